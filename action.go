@@ -0,0 +1,653 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/MiUPa/codex-notify/notify"
+)
+
+func runAction(args []string) error {
+	if len(args) == 0 {
+		return usageError(errors.New("action requires one of: open, approve, reject, reject-with-reason, choose, snooze, submit, mute-project, script"))
+	}
+
+	action := strings.ToLower(strings.TrimSpace(args[0]))
+	fs := flag.NewFlagSet("action", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	threadID := fs.String("thread-id", "", "thread id")
+	text := fs.String("text", "", "text payload for submit action")
+	preset := fs.String("preset", "", "named preset from config.toml for submit action")
+	cwd := fs.String("cwd", "", "project directory for mute-project and choose actions")
+	duration := fs.Duration("duration", defaultProjectMuteDuration, "mute duration for mute-project action")
+	expiresAt := fs.Int64("expires-at", 0, "unix time after which key-sending actions open the terminal instead")
+	script := fs.String("script", "", "named script from config.toml for script action")
+	event := fs.String("event", "", "hook event the script action was clicked from")
+	remember := fs.String("remember", "", "choose: answer with this action and remember it for the project")
+	forget := fs.Bool("forget", false, "choose: forget the answer remembered for the project")
+	latest := fs.Bool("latest", false, "act on the most recent pending approval instead of --thread-id")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	out := outFlags.output()
+
+	if *latest {
+		if *threadID != "" {
+			return usageError(errors.New("--latest and --thread-id cannot be combined"))
+		}
+		if !latestApprovalActions[action] {
+			return usageError(errors.New("--latest is only valid for open, approve, reject, reject-with-reason, choose, and snooze"))
+		}
+		pending, ok := latestPendingApproval(time.Now())
+		if !ok {
+			out.Println("no approval is pending")
+			return out.Result(commandResult{Command: "action", Status: "not-found", Action: action})
+		}
+		*threadID = pending.Thread
+		if *cwd == "" {
+			*cwd = pending.Cwd
+		}
+	}
+
+	if *preset != "" {
+		if action != "submit" {
+			return usageError(errors.New("--preset is only valid for the submit action"))
+		}
+		cfg, err := loadUserConfig()
+		if err != nil {
+			return configError(err)
+		}
+		p, ok := cfg.preset(*preset)
+		if !ok {
+			return configError(fmt.Errorf("unknown preset: %s", *preset))
+		}
+		*text = p.Text
+	}
+	if (*script != "" || *event != "") && action != "script" {
+		return usageError(errors.New("--script and --event are only valid for the script action"))
+	}
+	if action == "script" {
+		if err := runScriptAction(*script, *threadID, *cwd, *event); err != nil {
+			reportPermissionFailure("script "+*script, err)
+			return err
+		}
+		recordActionEvent(action, *threadID, *cwd, *script, "ok")
+		return out.Result(commandResult{Command: "action", Status: "ok", Action: action, Thread: *threadID})
+	}
+
+	if action == "snooze" {
+		return runSnoozeAction(out, *threadID)
+	}
+	if (*remember != "" || *forget) && action != "choose" {
+		return usageError(errors.New("--remember and --forget are only valid for the choose action"))
+	}
+	if action == "choose" {
+		chosen, forgot, err := resolveChooseAction(*threadID, *cwd, *remember, *forget)
+		if err != nil {
+			return err
+		}
+		if forgot {
+			out.Println("forgot the remembered choice")
+			return out.Result(commandResult{Command: "action", Status: "forgotten", Action: action, Thread: *threadID})
+		}
+		if chosen != "" {
+			action = chosen
+		}
+	}
+	if approvalAlreadyAnswered(action, *threadID, time.Now()) {
+		// A banner or popup that outlived its approval; the keys would
+		// answer whatever the session is doing now.
+		recordAudit(auditRecord{Action: action, Thread: *threadID, Result: auditWithheld, Detail: "approval already answered"})
+		if err := dispatchAction("open", *threadID, "", *cwd, *duration); err != nil {
+			reportPermissionFailure("open", err)
+			return err
+		}
+		recordActionEvent(action, *threadID, *cwd, "", "answered")
+		out.Printf("approval already answered; opened the terminal instead of sending %s\n", action)
+		return out.Result(commandResult{Command: "action", Status: "answered", Action: action, Thread: *threadID})
+	}
+	if approvalExpired(action, *expiresAt, time.Now()) {
+		// The approval Codex asked about is gone; typing now would answer
+		// whatever the session is doing instead.
+		recordAudit(auditRecord{Action: action, Thread: *threadID, Result: auditWithheld, Detail: "approval expired"})
+		if err := dispatchAction("open", *threadID, "", *cwd, *duration); err != nil {
+			reportPermissionFailure("open", err)
+			return err
+		}
+		recordActionEvent(action, *threadID, *cwd, "", "expired")
+		out.Printf("approval expired; opened the terminal instead of sending %s\n", action)
+		return out.Result(commandResult{Command: "action", Status: "expired", Action: action, Thread: *threadID})
+	}
+
+	// answered is the action that typed into the session: the choice a
+	// chooser resolved to, or "" when it only opened the terminal.
+	answered := action
+	var err error
+	if action == "choose" {
+		answered, err = runChooseAction(terminalBundleID(), *threadID)
+	} else {
+		err = dispatchAction(action, *threadID, *text, *cwd, *duration)
+	}
+	if err != nil {
+		reportPermissionFailure(action, err)
+		return err
+	}
+	clearPermissionProblem()
+	if approvalAnswerActions[answered] {
+		resolveWidgetApproval(*threadID)
+		defer settleApproval(*threadID, answeredLocal)()
+	}
+	// A chooser's answer is logged as that answer, so stats count it.
+	if action == "choose" && answered != "" {
+		recordActionEvent(answered, *threadID, *cwd, "choose", "ok")
+	} else {
+		recordActionEvent(action, *threadID, *cwd, "", "ok")
+	}
+	return out.Result(commandResult{Command: "action", Status: "ok", Action: action, Thread: *threadID})
+}
+
+func dispatchAction(action, threadID, text, cwd string, duration time.Duration) error {
+	bundleID := terminalBundleID()
+	switch action {
+	case "open":
+		return openTerminal(bundleID, threadID)
+	case "choose":
+		_, err := runChooseAction(bundleID, threadID)
+		return err
+	case "approve":
+		return sendActionKeys("approve", bundleID, approveKeySequence(), threadID)
+	case "reject":
+		return sendActionKeys("reject", bundleID, rejectKeySequence(), threadID)
+	case "reject-with-reason":
+		return runRejectWithReason(bundleID, threadID, text)
+	case "submit":
+		if strings.TrimSpace(text) == "" {
+			return usageError(errors.New("submit action requires --text or --preset"))
+		}
+		return sendActionKeys("submit", bundleID, []string{text, "enter"}, threadID)
+	case "mute-project":
+		if strings.TrimSpace(cwd) == "" {
+			return usageError(errors.New("mute-project action requires --cwd"))
+		}
+		if duration <= 0 {
+			return usageError(errors.New("mute-project action requires a positive --duration"))
+		}
+		return muteProject(cwd, duration)
+	default:
+		return usageError(fmt.Errorf("unknown action: %s", action))
+	}
+}
+
+func buildActionCommand(action, threadID string) string {
+	executable := appName
+	if path, err := os.Executable(); err == nil && strings.TrimSpace(path) != "" {
+		executable = path
+	}
+
+	parts := []string{
+		shellQuote(executable),
+		"action",
+		shellQuote(action),
+	}
+	if threadID != "" {
+		parts = append(parts, "--thread-id", shellQuote(threadID))
+	}
+	return strings.Join(parts, " ")
+}
+
+func buildSubmitActionCommand(text, threadID string) string {
+	executable := appName
+	if path, err := os.Executable(); err == nil && strings.TrimSpace(path) != "" {
+		executable = path
+	}
+
+	parts := []string{
+		shellQuote(executable),
+		"action",
+		"submit",
+		"--text",
+		shellQuote(text),
+	}
+	if threadID != "" {
+		parts = append(parts, "--thread-id", shellQuote(threadID))
+	}
+	return strings.Join(parts, " ")
+}
+
+func buildSubmitPresetCommand(name, threadID string) string {
+	executable := appName
+	if path, err := os.Executable(); err == nil && strings.TrimSpace(path) != "" {
+		executable = path
+	}
+
+	parts := []string{
+		shellQuote(executable),
+		"action",
+		"submit",
+		"--preset",
+		shellQuote(name),
+	}
+	if threadID != "" {
+		parts = append(parts, "--thread-id", shellQuote(threadID))
+	}
+	return strings.Join(parts, " ")
+}
+
+func buildMuteProjectCommand(cwd string, d time.Duration) string {
+	executable := appName
+	if path, err := os.Executable(); err == nil && strings.TrimSpace(path) != "" {
+		executable = path
+	}
+
+	parts := []string{
+		shellQuote(executable),
+		"action",
+		"mute-project",
+		"--cwd",
+		shellQuote(cwd),
+		"--duration",
+		shellQuote(d.String()),
+	}
+	return strings.Join(parts, " ")
+}
+
+func shellQuote(v string) string {
+	if v == "" {
+		return "''"
+	}
+	return "'" + strings.ReplaceAll(v, "'", `'"'"'`) + "'"
+}
+
+func terminalBundleID() string {
+	return currentSettings().TerminalBundleID
+}
+
+func approveKeySequence() []string {
+	return append([]string(nil), currentSettings().ApproveKeys...)
+}
+
+func rejectKeySequence() []string {
+	return append([]string(nil), currentSettings().RejectKeys...)
+}
+
+// openKeySequence is typed after Open activates the terminal. It is empty by
+// default; some terminals need a nudge (e.g. "enter") before Codex redraws.
+func openKeySequence() []string {
+	return append([]string(nil), currentSettings().OpenKeys...)
+}
+
+func keySequenceFromEnv(key, fallback string) []string {
+	return keySequenceFrom(getenv(key), fallback)
+}
+
+func keySequenceFrom(raw, fallback string) []string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		raw = fallback
+	}
+	parts := strings.Split(raw, ",")
+	out := []string{}
+	for _, part := range parts {
+		token := strings.TrimSpace(part)
+		if token != "" {
+			out = append(out, token)
+		}
+	}
+	if len(out) == 0 && fallback != "" {
+		out = append(out, strings.Split(fallback, ",")...)
+	}
+	return out
+}
+
+// keystrokesSupported reports whether actions that type into the terminal
+// (approve, reject, submit) can work on this machine.
+func keystrokesSupported() bool {
+	if hostOS == "linux" {
+		return linuxKeystrokesSupported()
+	}
+	return hostOS == "darwin" && !sandboxModeEnabled()
+}
+
+func activateApplication(bundleID string) error {
+	if hostOS == "linux" {
+		return activateLinuxTerminal()
+	}
+	if sandboxModeEnabled() {
+		return activateWithLaunchServices(bundleID)
+	}
+
+	path, ok := lookupCmd("osascript")
+	if !ok {
+		return errors.New("osascript not found")
+	}
+
+	script := fmt.Sprintf(`tell application id "%s" to activate`, escapeAppleScript(bundleID))
+	cmd := exec.Command(path, "-e", script)
+	if out, err := combinedOutputLogged(cmd); err != nil {
+		err = fmt.Errorf("activate app failed: %w (%s)", err, strings.TrimSpace(string(out)))
+		if isAppleEventsPermissionDenied(string(out)) {
+			return applePermissionError(err, string(out))
+		}
+		return err
+	}
+	return nil
+}
+
+func openTerminal(bundleID, threadID string) error {
+	seq := openKeySequence()
+	if len(seq) == 0 && len(revealKeySequence()) == 0 || !keystrokesSupported() {
+		return activateApplication(bundleID)
+	}
+	return sendActionKeys("open", bundleID, seq, threadID)
+}
+
+// sendActionKeys activates the terminal, sends the reveal sequence so the
+// prompt is on screen, then types seq. Whatever is typed for action goes to
+// the audit log with the app that was in front at the time.
+func sendActionKeys(action, bundleID string, seq []string, threadID string) error {
+	if err := activateApplication(bundleID); err != nil {
+		return err
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	reveal := revealKeySequence()
+	if len(reveal) == 0 && len(seq) == 0 {
+		return nil
+	}
+	frontmost := frontmostApp()
+	if len(reveal) > 0 {
+		if err := sendKeySequence(reveal, threadID); err != nil {
+			recordKeystrokes(action, bundleID, threadID, frontmost, reveal, err)
+			return err
+		}
+	}
+	var err error
+	if len(seq) > 0 {
+		err = sendKeySequence(seq, threadID)
+	}
+	recordKeystrokes(action, bundleID, threadID, frontmost, append(append([]string{}, reveal...), seq...), err)
+	return err
+}
+
+// runChooseAction asks which answer to send and sends it. It returns the
+// answer whose keys it typed, or "" when the choice went elsewhere: to the
+// terminal, a snooze, or the chooser popup, which runs the action itself.
+func runChooseAction(bundleID, threadID string) (string, error) {
+	if !keystrokesSupported() || hostOS != "darwin" {
+		// The choice dialog needs osascript.
+		return "", openTerminal(bundleID, threadID)
+	}
+	// The popup runs the chosen action itself.
+	if showChooserPopup(threadID) == nil {
+		return "", nil
+	}
+
+	choice, err := chooseApprovalAction(threadID)
+	if err != nil {
+		if errors.Is(err, errDialogCanceled) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	switch choice {
+	case "open":
+		return "", openTerminal(bundleID, threadID)
+	case "approve":
+		return choice, sendActionKeys("approve", bundleID, approveKeySequence(), threadID)
+	case "reject":
+		return choice, sendActionKeys("reject", bundleID, rejectKeySequence(), threadID)
+	case "timeout":
+		// display dialog has room for three buttons only, so a dialog left
+		// to time out snoozes instead of dropping the approval.
+		if snoozeInterval() > 0 {
+			if _, ok := snoozeApproval(threadID, time.Now()); ok {
+				startReminderProcess(threadID)
+			}
+		}
+		return "", nil
+	default:
+		return "", fmt.Errorf("unknown chosen action: %s", choice)
+	}
+}
+
+// runRejectWithReason asks for a reason first (unless one is given), then
+// rejects and sends the reason as a follow-up message so the agent knows why.
+// Canceling the prompt leaves the approval pending.
+func runRejectWithReason(bundleID, threadID, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		typed, err := promptRejectReason(threadID)
+		if err != nil {
+			if errors.Is(err, errDialogCanceled) {
+				return nil
+			}
+			return err
+		}
+		reason = typed
+	}
+
+	if err := sendActionKeys("reject-with-reason", bundleID, rejectKeySequence(), threadID); err != nil {
+		return err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil
+	}
+	// Give Codex a moment to leave the approval prompt before typing.
+	time.Sleep(300 * time.Millisecond)
+	keys := []string{reason, "enter"}
+	frontmost := frontmostApp()
+	err := sendKeySequence(keys, threadID)
+	recordKeystrokes("reject-with-reason", bundleID, threadID, frontmost, keys, err)
+	return err
+}
+
+func promptRejectReason(threadID string) (string, error) {
+	if hostOS != "darwin" {
+		return "", errKeystrokesUnsupported
+	}
+	if sandboxModeEnabled() {
+		return "", permissionError(errSandboxKeystrokes)
+	}
+	path, ok := lookupCmd("osascript")
+	if !ok {
+		return "", errors.New("osascript not found")
+	}
+
+	prompt := "拒否する理由を入力してください。"
+	if threadID != "" {
+		prompt = fmt.Sprintf("thread: %s\\n拒否する理由を入力してください。", threadID)
+	}
+
+	script := fmt.Sprintf(`try
+	set dialogResult to display dialog "%s" with title "Codex Notify" default answer "" buttons {"Cancel", "Reject"} default button "Reject" cancel button "Cancel" giving up after %d
+	if gave up of dialogResult then
+		return "__codex_notify_none__"
+	end if
+	return text returned of dialogResult
+on error number -128
+	return "__codex_notify_none__"
+end try`, escapeAppleScript(prompt), approvalActionTimeoutSeconds())
+
+	cmd := exec.Command(path)
+	// Keep the script (and any typed text echoed back) off the command line.
+	cmd.Stdin = strings.NewReader(script)
+	out, err := combinedOutputLogged(cmd)
+	if err != nil {
+		return "", fmt.Errorf("reject reason prompt failed: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+
+	reason := strings.TrimRight(string(out), "\r\n")
+	if reason == "__codex_notify_none__" {
+		return "", errDialogCanceled
+	}
+	return reason, nil
+}
+
+func chooseApprovalAction(threadID string) (string, error) {
+	path, ok := lookupCmd("osascript")
+	if !ok {
+		return "", errors.New("osascript not found")
+	}
+
+	prompt := "承認待ちです。実行する操作を選択してください。"
+	if threadID != "" {
+		prompt = fmt.Sprintf("thread: %s\\n承認待ちです。実行する操作を選択してください。", threadID)
+	}
+
+	script := fmt.Sprintf(`try
+	set dialogResult to display dialog "%s" with title "Codex Notify" buttons {"Open", "Approve", "Reject"} default button "Open" giving up after %d
+	if gave up of dialogResult then
+		return "timeout"
+	end if
+	set selectedButton to button returned of dialogResult
+	if selectedButton is "Open" then
+		return "open"
+	else if selectedButton is "Approve" then
+		return "approve"
+	else
+		return "reject"
+	end if
+on error number -128
+	return "none"
+end try`, escapeAppleScript(prompt), approvalActionTimeoutSeconds())
+
+	cmd := exec.Command(path, "-e", script)
+	out, err := combinedOutputLogged(cmd)
+	if err != nil {
+		return "", fmt.Errorf("choose action failed: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+
+	choice := strings.ToLower(strings.TrimSpace(string(out)))
+	if choice == "" || choice == "none" {
+		return "", errDialogCanceled
+	}
+	switch choice {
+	case "open", "approve", "reject", "timeout":
+		return choice, nil
+	default:
+		return "", fmt.Errorf("unknown choice from dialog: %s", choice)
+	}
+}
+
+func sendKeySequence(seq []string, threadID string) error {
+	if hostOS == "linux" && linuxKeystrokesSupported() {
+		return sendLinuxKeySequence(seq, threadID)
+	}
+	if hostOS != "darwin" {
+		return errKeystrokesUnsupported
+	}
+	if sandboxModeEnabled() {
+		return permissionError(errSandboxKeystrokes)
+	}
+
+	path, ok := lookupCmd("osascript")
+	if !ok {
+		return errors.New("osascript not found")
+	}
+
+	for _, token := range seq {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+
+		cmd := exec.Command(path, "-e", keyScript(token))
+		if out, err := combinedOutputLogged(cmd); err != nil {
+			if threadID != "" {
+				err = fmt.Errorf("send key for thread %s: %w (%s)", threadID, err, strings.TrimSpace(string(out)))
+			} else {
+				err = fmt.Errorf("send key: %w (%s)", err, strings.TrimSpace(string(out)))
+			}
+			if isAppleEventsPermissionDenied(string(out)) {
+				return applePermissionError(err, string(out))
+			}
+			return err
+		}
+		time.Sleep(80 * time.Millisecond)
+	}
+
+	return nil
+}
+
+// keyScript is the System Events command for one sequence token: a named
+// key, text to type, or either with modifiers such as "cmd+end" or "ctrl+l".
+func keyScript(token string) string {
+	key, modifiers := splitKeyModifiers(token)
+	using := ""
+	if len(modifiers) > 0 {
+		using = " using {" + strings.Join(modifiers, ", ") + "}"
+	}
+	if code, special := keyCodeForToken(key); special {
+		return fmt.Sprintf(`tell application "System Events" to key code %d%s`, code, using)
+	}
+	return fmt.Sprintf(`tell application "System Events" to keystroke "%s"%s`, escapeAppleScript(key), using)
+}
+
+// splitKeyModifiers splits "cmd+shift+end" into the key and AppleScript
+// modifier names. A token whose prefix is not all modifiers, like "c++", is
+// plain text.
+func splitKeyModifiers(token string) (string, []string) {
+	parts := strings.Split(token, "+")
+	if len(parts) < 2 || parts[len(parts)-1] == "" {
+		return token, nil
+	}
+	modifiers := []string{}
+	for _, part := range parts[:len(parts)-1] {
+		switch strings.ToLower(strings.TrimSpace(part)) {
+		case "cmd", "command":
+			modifiers = append(modifiers, "command down")
+		case "ctrl", "control":
+			modifiers = append(modifiers, "control down")
+		case "opt", "option", "alt":
+			modifiers = append(modifiers, "option down")
+		case "shift":
+			modifiers = append(modifiers, "shift down")
+		default:
+			return token, nil
+		}
+	}
+	return parts[len(parts)-1], modifiers
+}
+
+func keyCodeForToken(token string) (int, bool) {
+	switch strings.ToLower(strings.TrimSpace(token)) {
+	case "enter", "return":
+		return 36, true
+	case "tab":
+		return 48, true
+	case "esc", "escape":
+		return 53, true
+	case "space":
+		return 49, true
+	case "up":
+		return 126, true
+	case "down":
+		return 125, true
+	case "left":
+		return 123, true
+	case "right":
+		return 124, true
+	case "home":
+		return 115, true
+	case "end":
+		return 119, true
+	case "pageup":
+		return 116, true
+	case "pagedown":
+		return 121, true
+	default:
+		return 0, false
+	}
+}
+
+func escapeAppleScript(s string) string {
+	return notify.EscapeAppleScript(s)
+}
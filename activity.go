@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	defaultStatsWindow  = 7 * 24 * time.Hour
+	statsBusiestThreads = 5
+)
+
+// activityStats sums up the history for `codex-notify stats`: what Codex
+// sent, how approvals were answered, and which threads were busiest.
+type activityStats struct {
+	Since  time.Time      `json:"since"`
+	Events map[string]int `json:"events"`
+	// Approvals counts approval requests; Approved and Rejected the answers
+	// given through codex-notify, from the Mac or the phone. Answers typed
+	// in the terminal are not seen.
+	Approvals int `json:"approvals"`
+	Approved  int `json:"approved"`
+	Rejected  int `json:"rejected"`
+	// ApproveRatio is Approved over all answers, and AvgAnswerSeconds the
+	// mean wait from a request to its answer.
+	ApproveRatio     float64 `json:"approve_ratio"`
+	AvgAnswerSeconds float64 `json:"avg_answer_seconds"`
+	// AvgLatencyMS and P90LatencyMS summarize the desktop latency of sent
+	// events: from the hook starting to the backend taking the notification.
+	AvgLatencyMS float64          `json:"avg_latency_ms"`
+	P90LatencyMS float64          `json:"p90_latency_ms"`
+	Threads      []threadActivity `json:"busiest_threads"`
+}
+
+type threadActivity struct {
+	Thread    string `json:"thread_id"`
+	Cwd       string `json:"cwd,omitempty"`
+	Events    int    `json:"events"`
+	Approvals int    `json:"approvals"`
+}
+
+// summarizeActivity computes activityStats over entries, oldest first. An
+// answer is matched to the request before it on the same thread.
+func summarizeActivity(entries []historyEntry, since time.Time) activityStats {
+	stats := activityStats{Since: since, Events: map[string]int{}, Threads: []threadActivity{}}
+	threads := map[string]*threadActivity{}
+	requested := map[string]time.Time{}
+	var waited time.Duration
+	answered := 0
+	var latencies []float64
+	for _, e := range entries {
+		if e.Event == "action" {
+			action, _, _ := strings.Cut(e.Message, " ")
+			if e.Status != "ok" {
+				continue
+			}
+			switch action {
+			case "approve":
+				stats.Approved++
+			case "reject", "reject-with-reason":
+				stats.Rejected++
+			default:
+				continue
+			}
+			if at, ok := requested[e.Thread]; ok {
+				waited += e.Time.Sub(at)
+				answered++
+				delete(requested, e.Thread)
+			}
+			continue
+		}
+		stats.Events[e.Event]++
+		if e.LatencyMS > 0 {
+			latencies = append(latencies, e.LatencyMS)
+		}
+		if e.Thread == "" {
+			continue
+		}
+		t := threads[e.Thread]
+		if t == nil {
+			t = &threadActivity{Thread: e.Thread}
+			threads[e.Thread] = t
+		}
+		t.Events++
+		if e.Cwd != "" {
+			t.Cwd = e.Cwd
+		}
+		if e.Event == "approval-requested" {
+			stats.Approvals++
+			t.Approvals++
+			requested[e.Thread] = e.Time
+		}
+	}
+	if n := stats.Approved + stats.Rejected; n > 0 {
+		stats.ApproveRatio = float64(stats.Approved) / float64(n)
+	}
+	if answered > 0 {
+		stats.AvgAnswerSeconds = (waited / time.Duration(answered)).Seconds()
+	}
+	if len(latencies) > 0 {
+		sort.Float64s(latencies)
+		total := 0.0
+		for _, l := range latencies {
+			total += l
+		}
+		stats.AvgLatencyMS = total / float64(len(latencies))
+		// Nearest rank: the smallest latency at or above 90% of them.
+		stats.P90LatencyMS = latencies[(len(latencies)*9+9)/10-1]
+	}
+	for _, t := range threads {
+		stats.Threads = append(stats.Threads, *t)
+	}
+	sort.Slice(stats.Threads, func(i, j int) bool {
+		a, b := stats.Threads[i], stats.Threads[j]
+		if a.Events != b.Events {
+			return a.Events > b.Events
+		}
+		return a.Thread < b.Thread
+	})
+	if len(stats.Threads) > statsBusiestThreads {
+		stats.Threads = stats.Threads[:statsBusiestThreads]
+	}
+	return stats
+}
+
+func formatActivity(stats activityStats) string {
+	var b strings.Builder
+	if len(stats.Events) == 0 {
+		fmt.Fprintf(&b, "no events logged since %s\n", stats.Since.Local().Format("Jan 02 15:04"))
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Since %s\n", stats.Since.Local().Format("Jan 02 15:04"))
+
+	events := make([]string, 0, len(stats.Events))
+	for event := range stats.Events {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if stats.Events[events[i]] != stats.Events[events[j]] {
+			return stats.Events[events[i]] > stats.Events[events[j]]
+		}
+		return events[i] < events[j]
+	})
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EVENT\tCOUNT")
+	for _, event := range events {
+		fmt.Fprintf(w, "%s\t%d\n", event, stats.Events[event])
+	}
+	_ = w.Flush()
+
+	fmt.Fprintf(&b, "\nApprovals: %d requested, %d approved, %d rejected", stats.Approvals, stats.Approved, stats.Rejected)
+	if stats.Approved+stats.Rejected > 0 {
+		fmt.Fprintf(&b, " (%.0f%% approved)", 100*stats.ApproveRatio)
+	}
+	if stats.AvgAnswerSeconds > 0 {
+		wait := time.Duration(stats.AvgAnswerSeconds * float64(time.Second)).Round(time.Second)
+		fmt.Fprintf(&b, "; answered after %s on average", wait)
+	}
+	b.WriteString("\n")
+	if stats.AvgLatencyMS > 0 {
+		fmt.Fprintf(&b, "Desktop latency: %.0fms on average, %.0fms p90\n", stats.AvgLatencyMS, stats.P90LatencyMS)
+	}
+
+	if len(stats.Threads) > 0 {
+		b.WriteString("\n")
+		w = tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "THREAD\tPROJECT\tEVENTS\tAPPROVALS")
+		for _, t := range stats.Threads {
+			project := "-"
+			if t.Cwd != "" {
+				project = filepath.Base(t.Cwd)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", t.Thread, project, t.Events, t.Approvals)
+		}
+		_ = w.Flush()
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizeActivity(t *testing.T) {
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	entries := []historyEntry{
+		{Time: at(0), Event: "approval-requested", Thread: "t-1", Cwd: "/src/api", Status: "sent", LatencyMS: 30},
+		{Time: at(time.Minute), Event: "action", Thread: "t-1", Message: "approve", Status: "ok"},
+		{Time: at(2 * time.Minute), Event: "agent-turn-complete", Thread: "t-1", Cwd: "/src/api", Status: "sent", LatencyMS: 20},
+		{Time: at(3 * time.Minute), Event: "approval-requested", Thread: "t-2", Cwd: "/src/web", Status: "sent", LatencyMS: 250},
+		{Time: at(6 * time.Minute), Event: "action", Thread: "t-2", Message: "reject (from ntfy)", Status: "ok"},
+		{Time: at(7 * time.Minute), Event: "approval-requested", Thread: "t-1", Cwd: "/src/api", Status: "sent"},
+		// An answer to an approval that was already settled is not one.
+		{Time: at(8 * time.Minute), Event: "action", Thread: "t-1", Message: "approve", Status: "answered"},
+		{Time: at(9 * time.Minute), Event: "action", Thread: "t-1", Message: "open", Status: "ok"},
+	}
+
+	stats := summarizeActivity(entries, start)
+	if stats.Events["approval-requested"] != 3 || stats.Events["agent-turn-complete"] != 1 || len(stats.Events) != 2 {
+		t.Fatalf("events = %v", stats.Events)
+	}
+	if stats.Approvals != 3 || stats.Approved != 1 || stats.Rejected != 1 || stats.ApproveRatio != 0.5 {
+		t.Fatalf("approvals = %d requested, %d approved, %d rejected, ratio %v", stats.Approvals, stats.Approved, stats.Rejected, stats.ApproveRatio)
+	}
+	if stats.AvgAnswerSeconds != 120 {
+		t.Fatalf("average answer = %vs, want 120s", stats.AvgAnswerSeconds)
+	}
+	if stats.AvgLatencyMS != 100 || stats.P90LatencyMS != 250 {
+		t.Fatalf("latency = %vms average, %vms p90, want 100 and 250", stats.AvgLatencyMS, stats.P90LatencyMS)
+	}
+	if len(stats.Threads) != 2 || stats.Threads[0].Thread != "t-1" || stats.Threads[0].Events != 3 || stats.Threads[0].Approvals != 2 {
+		t.Fatalf("busiest threads = %+v", stats.Threads)
+	}
+
+	out := formatActivity(stats)
+	for _, s := range []string{"approval-requested   3", "3 requested, 1 approved, 1 rejected (50% approved); answered after 2m0s on average", "Desktop latency: 100ms on average, 250ms p90", "t-1     api"} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("stats output missing %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestSummarizeActivityCountsChosenAnswers(t *testing.T) {
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	entries := []historyEntry{
+		{Time: start, Event: "approval-requested", Thread: "t-1", Status: "sent"},
+		{Time: start.Add(time.Minute), Event: "action", Thread: "t-1", Message: "reject (choose)", Status: "ok"},
+		{Time: start.Add(2 * time.Minute), Event: "approval-requested", Thread: "t-2", Status: "sent"},
+		// A chooser that only opened the terminal answered nothing.
+		{Time: start.Add(3 * time.Minute), Event: "action", Thread: "t-2", Message: "choose", Status: "ok"},
+	}
+	stats := summarizeActivity(entries, start)
+	if stats.Rejected != 1 || stats.Approved != 0 || stats.AvgAnswerSeconds != 60 {
+		t.Fatalf("stats = %d approved, %d rejected, answered after %vs", stats.Approved, stats.Rejected, stats.AvgAnswerSeconds)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+const withdrawnDirName = "withdrawn"
+
+// Where an approval was answered. Each side withdraws what the others
+// still show, so the same prompt is never answered twice.
+const (
+	answeredLocal  = "local"  // a popup, notification, or `action` on this Mac
+	answeredRemote = "remote" // a reply from the phone
+	answeredCodex  = "codex"  // the thread moved on, typed in the terminal
+)
+
+// pendingApproval is an approval-requested event that nobody answered yet.
+// Nonce authenticates replies from the phone, which must echo it.
+type pendingApproval struct {
+	Thread string `json:"thread_id"`
+	Cwd    string `json:"cwd,omitempty"`
+	Nonce  string `json:"nonce"`
+	Since  int64  `json:"since"`
+	// Payload, Reminders, and RemindedAt are kept while reminders or
+	// snoozing are on: what to show again, how often it was, and when last.
+	Payload    map[string]any `json:"payload,omitempty"`
+	Reminders  int            `json:"reminders,omitempty"`
+	RemindedAt int64          `json:"reminded_at,omitempty"`
+	// SnoozedUntil is when a snoozed approval is shown again.
+	SnoozedUntil int64 `json:"snoozed_until,omitempty"`
+	// Local marks an approval only this machine showed, such as one
+	// simulated by `test --event`: it is never escalated to a sink, and
+	// settling it withdraws only the local popup.
+	Local bool `json:"local,omitempty"`
+}
+
+// trackApproval records a new pending approval for the payload's thread,
+// replacing an older one. Approvals without a thread are not tracked.
+func trackApproval(payload map[string]any, now time.Time) (pendingApproval, bool) {
+	return recordPendingApproval(payload, now, false)
+}
+
+// trackLocalApproval is trackApproval for an approval no sink or peer
+// hears of.
+func trackLocalApproval(payload map[string]any, now time.Time) (pendingApproval, bool) {
+	return recordPendingApproval(payload, now, true)
+}
+
+func recordPendingApproval(payload map[string]any, now time.Time, local bool) (pendingApproval, bool) {
+	thread := payloadThreadID(payload)
+	if thread == "" {
+		return pendingApproval{}, false
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return pendingApproval{}, false
+	}
+	pending := pendingApproval{Thread: thread, Cwd: payloadCwd(payload), Nonce: hex.EncodeToString(nonce), Since: now.Unix(), Local: local}
+	if remindInterval() > 0 || snoozeInterval() > 0 {
+		pending.Payload = payload
+	}
+	err := updateState(func(s *notifyState) {
+		prunePendingApprovals(s, now)
+		if s.PendingApprovals == nil {
+			s.PendingApprovals = map[string]pendingApproval{}
+		}
+		s.PendingApprovals[thread] = pending
+		delete(s.AnsweredApprovals, thread)
+	})
+	return pending, err == nil
+}
+
+func lookupPendingApproval(thread string) (pendingApproval, bool) {
+	state, err := loadState()
+	if err != nil {
+		return pendingApproval{}, false
+	}
+	pending, ok := state.PendingApprovals[thread]
+	return pending, ok && time.Since(time.Unix(pending.Since, 0)) < widgetApprovalWindow
+}
+
+// claimApproval removes the thread's pending approval and reports whether
+// it was still pending. With a non-empty nonce it must match, so a stale or
+// forged reply cannot answer a newer prompt.
+func claimApproval(thread, nonce string, now time.Time) (pendingApproval, bool) {
+	var claimed pendingApproval
+	found := false
+	_ = updateState(func(s *notifyState) {
+		prunePendingApprovals(s, now)
+		pending, ok := s.PendingApprovals[thread]
+		if !ok || nonce != "" && subtle.ConstantTimeCompare([]byte(nonce), []byte(pending.Nonce)) != 1 {
+			return
+		}
+		delete(s.PendingApprovals, thread)
+		if turn, ok := s.OpenTurns[thread]; ok {
+			turn.AwaitingApproval = false
+			s.OpenTurns[thread] = turn
+		}
+		if s.AnsweredApprovals == nil {
+			s.AnsweredApprovals = map[string]int64{}
+		}
+		s.AnsweredApprovals[thread] = now.Unix()
+		claimed, found = pending, true
+	})
+	return claimed, found
+}
+
+func prunePendingApprovals(s *notifyState, now time.Time) {
+	for thread, pending := range s.PendingApprovals {
+		if now.Sub(time.Unix(pending.Since, 0)) >= widgetApprovalWindow {
+			delete(s.PendingApprovals, thread)
+		}
+	}
+	for thread, answeredAt := range s.AnsweredApprovals {
+		if now.Sub(time.Unix(answeredAt, 0)) >= widgetApprovalWindow {
+			delete(s.AnsweredApprovals, thread)
+		}
+	}
+}
+
+// approvalAnswerActions are the actions that answer an approval prompt:
+// any keys typed into the session while one waits answer it.
+var approvalAnswerActions = keySendingActions
+
+// approvalAlreadyAnswered reports whether action would answer a thread's
+// approval that was settled since its notification went out: typed in the
+// terminal, answered from the phone, or clicked before.
+func approvalAlreadyAnswered(action, thread string, now time.Time) bool {
+	if !approvalAnswerActions[action] || thread == "" {
+		return false
+	}
+	state, err := loadState()
+	if err != nil {
+		return false
+	}
+	answeredAt, ok := state.AnsweredApprovals[thread]
+	return ok && now.Sub(time.Unix(answeredAt, 0)) < widgetApprovalWindow
+}
+
+// settleApproval marks the thread's approval answered and withdraws it
+// everywhere except where it was answered; a Local one was only ever shown
+// here. It is a no-op when nothing was pending, so every answer path can
+// call it.
+func settleApproval(thread, via string) func() {
+	if thread == "" {
+		return func() {}
+	}
+	pending, ok := claimApproval(thread, "", time.Now())
+	if !ok {
+		return func() {}
+	}
+	if pending.Local {
+		if via != answeredLocal {
+			withdrawLocalApproval(thread)
+		}
+		return func() {}
+	}
+	return withdrawApproval(thread, via)
+}
+
+// settleLocalApproval is settleApproval for an approval only this machine
+// showed, such as a simulated one: it withdraws the local popup and leaves
+// the remote sinks and the peer alone.
+func settleLocalApproval(thread string) {
+	if thread == "" {
+		return
+	}
+	if _, ok := claimApproval(thread, "", time.Now()); ok {
+		withdrawLocalApproval(thread)
+	}
+}
+
+// withdrawApproval closes the thread's approval wherever it is shown. The
+// returned function waits for the remote withdrawals.
+func withdrawApproval(thread, via string) func() {
+	if via != answeredLocal {
+		withdrawLocalApproval(thread)
+	}
+	return withdrawRemoteApproval(thread)
+}
+
+// approvalGroups are the local notification groups an approval may occupy,
+// depending on the approval UI.
+func approvalGroups(thread string) []string {
+	return []string{
+		notificationGroup("approval-native", thread),
+		notificationGroup("approval-requested", thread),
+		notificationGroup("approve", thread),
+		notificationGroup("reject", thread),
+	}
+}
+
+// withdrawLocalApproval closes the thread's approval popup and removes its
+// banners from Notification Center where the backend allows it.
+func withdrawLocalApproval(thread string) {
+	withdrawLocalGroups(approvalGroups(thread))
+}
+
+// withdrawLocalGroups closes the popups showing groups and removes their
+// banners where the backend allows it.
+func withdrawLocalGroups(groups []string) {
+	pruneWithdrawMarkers(time.Now())
+	terminalNotifier, hasTerminalNotifier := lookupCmd("terminal-notifier")
+	for _, group := range groups {
+		if path, err := withdrawMarkerPath(group); err == nil {
+			_ = writeFileAtomic(path, nil, privateFileMode)
+		}
+		if hostOS == "darwin" && hasTerminalNotifier {
+			_ = runLogged(exec.Command(terminalNotifier, "-remove", group))
+		}
+	}
+}
+
+// approvalWithdrawnEvent is the event of a queued withdrawal, so a retry
+// goes to withdrawalSinks.
+const approvalWithdrawnEvent = "approval-withdrawn"
+
+// withdrawRemoteApproval takes the approval back from the remote sinks that
+// support it, and from the peer, in the background; a withdrawal that fails
+// for the network is retried from the sink queue. ntfy pushes clear
+// themselves when a button is tapped, and their buttons stop working once
+// the nonce is spent. The returned function waits for the withdrawals.
+func withdrawRemoteApproval(thread string) func() {
+	cfg, err := loadUserConfig()
+	if _, capture := captureDir(); err != nil || capture || benchDryRun() {
+		return func() {}
+	}
+	payload := map[string]any{"type": approvalWithdrawnEvent, "thread-id": thread}
+	return publishRemoteSinks(withdrawalSinks(cfg, thread), payload)
+}
+
+// withdrawalSinks are the remote sinks that can take back the thread's
+// approval.
+func withdrawalSinks(cfg userConfig, thread string) []remoteSink {
+	sinks := []remoteSink{}
+	if cfg.Pushover.enabled() && cfg.Pushover.priorityFor("approval-requested") == pushoverEmergency {
+		sinks = append(sinks, remoteSink{Name: "pushover", Publish: func() error { return cancelPushoverEmergency(cfg.Pushover, approvalTag(thread)) }})
+	}
+	if cfg.Peer.enabled() && featureEnabled("peer") {
+		sinks = append(sinks, remoteSink{Name: "peer", Publish: func() error { return withdrawPeerApproval(cfg.Peer, thread) }})
+	}
+	return sinks
+}
+
+// approvalTag names an approval to remote services that can withdraw by tag.
+func approvalTag(thread string) string {
+	return appName + "-" + sanitizeID(thread)
+}
+
+// pruneWithdrawMarkers removes markers older than the pending-approval
+// window. By then no popup they were meant for is still open, and a new
+// popup for the group removes its marker itself.
+func pruneWithdrawMarkers(now time.Time) {
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		return
+	}
+	dir := filepath.Join(stateDir, withdrawnDirName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && now.Sub(info.ModTime()) >= widgetApprovalWindow {
+			_ = os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
+
+// withdrawMarkerPath is the file whose appearance tells the popup helper
+// showing identifier to close.
+func withdrawMarkerPath(identifier string) (string, error) {
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(stateDir, withdrawnDirName)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, sanitizeID(identifier)), nil
+}
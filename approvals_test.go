@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPendingApprovalLifecycle(t *testing.T) {
+	useTempHome(t)
+	now := time.Now()
+
+	pending, ok := trackApproval(map[string]any{"type": "approval-requested", "thread-id": "t1", "cwd": "/src/app"}, now)
+	if !ok || len(pending.Nonce) != 32 || pending.Cwd != "/src/app" {
+		t.Fatalf("trackApproval() = %+v, %v", pending, ok)
+	}
+	if _, ok := trackApproval(map[string]any{"type": "approval-requested"}, now); ok {
+		t.Fatal("tracked an approval without a thread")
+	}
+
+	// A reply with the wrong nonce leaves the approval pending.
+	if _, ok := claimApproval("t1", "forged", now); ok {
+		t.Fatal("claimed an approval with a forged nonce")
+	}
+	if _, ok := lookupPendingApproval("t1"); !ok {
+		t.Fatal("approval no longer pending after a forged claim")
+	}
+
+	settleApproval("t1", answeredCodex)()
+	if _, ok := lookupPendingApproval("t1"); ok {
+		t.Fatal("approval still pending after it was settled")
+	}
+	// The popup for the thread is told to close.
+	marker, err := withdrawMarkerPath(notificationGroup("approval-native", "t1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("withdraw marker missing: %v", err)
+	}
+}
+
+func TestPruneWithdrawMarkers(t *testing.T) {
+	useTempHome(t)
+
+	old, _ := withdrawMarkerPath(notificationGroup("approval-native", "old"))
+	fresh, _ := withdrawMarkerPath(notificationGroup("approval-native", "fresh"))
+	for _, path := range []string{old, fresh} {
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	if err := os.Chtimes(old, now.Add(-2*widgetApprovalWindow), now.Add(-2*widgetApprovalWindow)); err != nil {
+		t.Fatal(err)
+	}
+
+	pruneWithdrawMarkers(now)
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatalf("expired marker kept: %v", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Fatalf("fresh marker removed: %v", err)
+	}
+}
+
+func TestSettleApprovalLocalKeepsPopupMarkerOff(t *testing.T) {
+	useTempHome(t)
+
+	if _, ok := trackApproval(map[string]any{"type": "approval-requested", "thread-id": "t2"}, time.Now()); !ok {
+		t.Fatal("trackApproval failed")
+	}
+	settleApproval("t2", answeredLocal)()
+	marker, _ := withdrawMarkerPath(notificationGroup("approval-native", "t2"))
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatalf("answering locally left a withdraw marker: %v", err)
+	}
+}
+
+func TestHandleApprovalReplyRejectsStaleReplies(t *testing.T) {
+	useTempHome(t)
+	now := time.Now()
+	pending, _ := trackApproval(map[string]any{"type": "approval-requested", "thread-id": "t3"}, now)
+
+	for _, body := range []string{
+		"not json",
+		`{"action":"submit","thread_id":"t3","nonce":"` + pending.Nonce + `"}`,
+		`{"action":"approve","thread_id":"t3"}`,
+		`{"action":"approve","thread_id":"t3","nonce":"0000"}`,
+		`{"action":"approve","thread_id":"other","nonce":"` + pending.Nonce + `"}`,
+	} {
+		if err := handleApprovalReply(body, now); err == nil {
+			t.Errorf("handleApprovalReply(%s) succeeded, want it ignored", body)
+		}
+	}
+	if _, ok := lookupPendingApproval("t3"); !ok {
+		t.Fatal("an ignored reply consumed the pending approval")
+	}
+}
+
+func TestNtfyApprovalActions(t *testing.T) {
+	t.Setenv(ntfyTokenEnv, "")
+	actions := ntfyApprovalActions(ntfyConfig{Topic: "runs", ReplyTopic: "runs-reply", Token: "tk"}, pendingApproval{Thread: "t1", Nonce: "abc"})
+	if len(actions) != 2 || actions[0].Label != "Approve" || actions[1].Label != "Reject" {
+		t.Fatalf("actions = %+v", actions)
+	}
+	a := actions[0]
+	if a.URL != "https://ntfy.sh/runs-reply" || a.Method != "POST" || !a.Clear || a.Headers["Authorization"] != "Bearer tk" {
+		t.Fatalf("approve action = %+v", a)
+	}
+	var reply approvalReply
+	if err := json.Unmarshal([]byte(a.Body), &reply); err != nil || reply != (approvalReply{Action: "approve", Thread: "t1", Nonce: "abc"}) {
+		t.Fatalf("approve body = %s (%v)", a.Body, err)
+	}
+	if _, err := parseUserConfig([]byte("[ntfy]\ntopic = \"runs\"\nreply_topic = \"bad/topic\"\n")); err == nil || !strings.Contains(err.Error(), "reply_topic") {
+		t.Fatalf("parseUserConfig() error = %v, want a reply_topic error", err)
+	}
+}
+
+func TestNtfyRepliesNeedToken(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_FEATURES", "ntfy_replies=on")
+	t.Setenv(ntfyTokenEnv, "")
+	cfg := ntfyConfig{Topic: "runs", ReplyTopic: "runs-reply"}
+	if cfg.repliesEnabled() {
+		t.Fatal("replies enabled on a topic without a token")
+	}
+	cfg.Token = "tk"
+	if !cfg.repliesEnabled() {
+		t.Fatal("replies disabled with a token and the feature on")
+	}
+}
+
+func TestApprovalAlreadyAnswered(t *testing.T) {
+	useTempHome(t)
+	now := time.Now()
+
+	if approvalAlreadyAnswered("approve", "t3", now) {
+		t.Fatal("untracked thread counted as answered")
+	}
+	if _, ok := trackApproval(map[string]any{"type": "approval-requested", "thread-id": "t3"}, now); !ok {
+		t.Fatal("trackApproval failed")
+	}
+	if approvalAlreadyAnswered("approve", "t3", now) {
+		t.Fatal("pending approval counted as answered")
+	}
+	// Typed in the terminal: the next event settles it.
+	settleApproval("t3", answeredCodex)()
+	for _, action := range []string{"approve", "reject", "reject-with-reason", "choose", "submit"} {
+		if !approvalAlreadyAnswered(action, "t3", now) {
+			t.Errorf("late %s click would still send keys", action)
+		}
+	}
+	if approvalAlreadyAnswered("open", "t3", now) || approvalAlreadyAnswered("snooze", "t3", now) {
+		t.Error("non-answer actions were blocked")
+	}
+	if approvalAlreadyAnswered("approve", "t3", now.Add(widgetApprovalWindow)) {
+		t.Error("answered mark outlived the approval window")
+	}
+	// A new prompt on the thread can be answered again.
+	if _, ok := trackApproval(map[string]any{"type": "approval-requested", "thread-id": "t3"}, now); !ok {
+		t.Fatal("trackApproval failed")
+	}
+	if approvalAlreadyAnswered("approve", "t3", now) {
+		t.Fatal("new approval counted as answered")
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	auditFilename = "audit.jsonl"
+
+	auditSent     = "sent"
+	auditFailed   = "failed"
+	auditWithheld = "withheld"
+)
+
+// auditRecord is one line of audit.jsonl: keystrokes codex-notify typed
+// into a terminal, or an approval answer it refused to type. The log is
+// only appended to.
+type auditRecord struct {
+	Time   string   `json:"time"`
+	Action string   `json:"action"`
+	Thread string   `json:"thread_id,omitempty"`
+	Keys   []string `json:"keys,omitempty"`
+	// Target is the bundle id the keys were meant for and Frontmost the app
+	// that was in front when they were sent.
+	Target    string `json:"target,omitempty"`
+	Frontmost string `json:"frontmost,omitempty"`
+	Result    string `json:"result"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// frontmostApp is the bundle id of the app in front, or "" when System
+// Events cannot say. Tests replace it to put another app in front.
+var frontmostApp = func() string {
+	path, ok := lookupCmd("osascript")
+	if !ok || hostOS != "darwin" {
+		return ""
+	}
+	out, err := outputLogged(exec.Command(path, "-e", `tell application "System Events" to get bundle identifier of first application process whose frontmost is true`))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func auditPath() (string, error) {
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, auditFilename), nil
+}
+
+// recordAudit appends rec to the audit log. Like the other logs it is best
+// effort: a full disk must not stop an approval.
+func recordAudit(rec auditRecord) {
+	path, err := auditPath()
+	if err != nil {
+		return
+	}
+	if rec.Time == "" {
+		rec.Time = time.Now().UTC().Format(eventTimeLayout)
+	}
+	_ = appendJSONLine(path, rec)
+}
+
+// recordKeystrokes logs keys typed for action; err is what sending them
+// returned.
+func recordKeystrokes(action, bundleID, threadID, frontmost string, keys []string, err error) {
+	rec := auditRecord{Action: action, Thread: threadID, Keys: keys, Target: bundleID, Frontmost: frontmost, Result: auditSent}
+	if err != nil {
+		rec.Result, rec.Detail = auditFailed, err.Error()
+	}
+	recordAudit(rec)
+}
+
+func loadAudit() ([]auditRecord, error) {
+	path, err := auditPath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := readFileMaybe(path)
+	if err != nil {
+		return nil, err
+	}
+	var records []auditRecord
+	for _, line := range splitLines(content) {
+		var rec auditRecord
+		if json.Unmarshal([]byte(line), &rec) == nil && rec.Action != "" {
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+// auditFrontmost is the FRONTMOST column, marked when another app was in
+// front of the one the keys were meant for.
+func auditFrontmost(rec auditRecord) string {
+	switch {
+	case rec.Frontmost == "":
+		return "-"
+	case rec.Target != "" && rec.Frontmost != rec.Target:
+		return rec.Frontmost + " (!)"
+	}
+	return rec.Frontmost
+}
+
+func formatAudit(records []auditRecord, style timeStyle, now time.Time) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tACTION\tTHREAD\tKEYS\tTARGET\tFRONTMOST\tRESULT")
+	for _, rec := range records {
+		keys := strings.Join(rec.Keys, ",")
+		if keys == "" {
+			keys = "-"
+		}
+		thread, target := rec.Thread, rec.Target
+		if thread == "" {
+			thread = "-"
+		}
+		if target == "" {
+			target = "-"
+		}
+		result := rec.Result
+		if rec.Detail != "" {
+			result += ": " + rec.Detail
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", formatEventTime(rec.Time, style, now), rec.Action, thread, keys, target, auditFrontmost(rec), result)
+	}
+	_ = w.Flush()
+	return b.String()
+}
+
+type auditResult struct {
+	commandResult
+	Entries []auditRecord `json:"entries"`
+}
+
+// runAudit prints the audit log, newest last: `audit [-n 20] [--thread-id id]`.
+func runAudit(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	lines := fs.Int("n", 20, "number of entries to print, 0 for all")
+	threadID := fs.String("thread-id", "", "only entries for this thread")
+	utc := fs.Bool("utc", false, "print full UTC timestamps")
+	relative := fs.Bool("relative", false, "print times relative to now, e.g. 3m ago")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+	if *lines < 0 {
+		return usageError(errors.New("-n must not be negative"))
+	}
+	style := timeLocal
+	switch {
+	case *utc && *relative:
+		return usageError(errors.New("--utc and --relative cannot be combined"))
+	case *utc:
+		style = timeUTC
+	case *relative:
+		style = timeRelative
+	}
+	out := outFlags.output()
+
+	records, err := loadAudit()
+	if err != nil {
+		return err
+	}
+	entries := []auditRecord{}
+	for _, rec := range records {
+		if *threadID == "" || rec.Thread == *threadID {
+			entries = append(entries, rec)
+		}
+	}
+	if *lines > 0 && len(entries) > *lines {
+		entries = entries[len(entries)-*lines:]
+	}
+	if len(entries) == 0 {
+		out.Println("no keystrokes recorded")
+	} else {
+		out.Printf("%s", formatAudit(entries, style, time.Now()))
+	}
+	return out.Result(auditResult{
+		commandResult: commandResult{Command: "audit", Status: "ok", Thread: *threadID, Count: len(entries)},
+		Entries:       entries,
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditRecordsKeystrokes(t *testing.T) {
+	home, _ := useTempHome(t)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("TMUX", "")
+	t.Setenv("CODEX_NOTIFY_SANDBOX", "")
+	t.Setenv("CODEX_NOTIFY_REVEAL_KEYS", "")
+	t.Setenv("CODEX_NOTIFY_APPROVE_KEYS", "")
+	t.Setenv("CODEX_NOTIFY_TERMINAL_BUNDLE_ID", "com.apple.Terminal")
+	bin := t.TempDir()
+	writeFakeNotifier(t, bin, "osascript", filepath.Join(home, "ran.log"))
+	t.Setenv("PATH", bin)
+	stub(t, &frontmostApp, func() string { return "com.tinyspeck.slackmacgap" })
+
+	if _, ok := trackApproval(map[string]any{"type": "approval-requested", "thread-id": "t1"}, time.Now()); !ok {
+		t.Fatal("trackApproval failed")
+	}
+	if err := runAction([]string{"approve", "--thread-id", "t1", "--quiet"}); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	// The approval is settled now, so a second click must not type.
+	if err := runAction([]string{"approve", "--thread-id", "t1", "--quiet"}); err != nil {
+		t.Fatalf("approve again: %v", err)
+	}
+
+	records, err := loadAudit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("audit = %+v, want the keystrokes and the withheld answer", records)
+	}
+	sent := records[0]
+	if sent.Action != "approve" || sent.Thread != "t1" || strings.Join(sent.Keys, ",") != defaultApproveSeq || sent.Target != "com.apple.Terminal" || sent.Result != auditSent {
+		t.Fatalf("records[0] = %+v", sent)
+	}
+	if got := auditFrontmost(sent); got != "com.tinyspeck.slackmacgap (!)" {
+		t.Fatalf("auditFrontmost = %q, want the mismatch marked", got)
+	}
+	if withheld := records[1]; withheld.Result != auditWithheld || withheld.Detail != "approval already answered" || len(withheld.Keys) != 0 {
+		t.Fatalf("records[1] = %+v", withheld)
+	}
+
+	out := formatAudit(records, timeUTC, time.Now())
+	if !strings.Contains(out, "withheld: approval already answered") || !strings.Contains(out, "y,enter") {
+		t.Fatalf("audit output:\n%s", out)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MiUPa/codex-notify/notify"
+)
+
+// backendCapabilities declares what a notification backend can render. The
+// dispatcher degrades each request to fit, instead of backends guessing.
+type backendCapabilities struct {
+	// Click runs a command when the notification itself is clicked.
+	Click bool
+	// Actions shows extra choice buttons besides the click action.
+	Actions bool
+	// Reply accepts free text typed into the notification.
+	Reply bool
+	// Images shows an attached image.
+	Images bool
+	// Updates replaces an earlier notification with the same group in place.
+	Updates bool
+	// Removal can withdraw a delivered notification by group.
+	Removal bool
+	// Format is how the message body is rendered; plain by default.
+	Format messageFormat
+}
+
+type notifierBackend interface {
+	Name() string
+	Capabilities() backendCapabilities
+	// Available reports whether the backend can be used right now.
+	Available() bool
+	Send(req notificationRequest) error
+}
+
+// notificationBackends returns the backends to try, in order of preference.
+func notificationBackends() []notifierBackend {
+	if dir, ok := captureDir(); ok {
+		return []notifierBackend{captureBackend{Dir: dir}}
+	}
+	if hostOS == "linux" {
+		return []notifierBackend{notifySendBackend{}}
+	}
+	return []notifierBackend{popupBackend{}, terminalNotifierBackend{}, osascriptBackend{}}
+}
+
+// firstAvailableBackend returns the backend sendNotification tries first, or
+// nil when none is usable.
+func firstAvailableBackend() notifierBackend {
+	for _, backend := range notificationBackends() {
+		if backend.Available() {
+			return backend
+		}
+	}
+	return nil
+}
+
+// adaptForBackend drops the parts of req that the backend cannot show and
+// renders the message in the backend's format.
+func adaptForBackend(req notificationRequest, caps backendCapabilities) notificationRequest {
+	req.Message = renderMessage(req.Message, caps.Format)
+	if !caps.Actions {
+		req.ExtraChoices = nil
+	}
+	if !caps.Click {
+		req.ExecuteOnClick = ""
+		req.ActivateBundleID = ""
+		req.PopupPrimaryLabel = ""
+	}
+	return req
+}
+
+func sendNotification(req notificationRequest) error {
+	if _, capture := captureDir(); !capture && hostOS != "darwin" && hostOS != "linux" {
+		return fmt.Errorf("unsupported OS: %s (macOS and Linux only)", hostOS)
+	}
+
+	if req.Title == "" {
+		req.Title = "Codex"
+	}
+	if req.Message == "" {
+		req.Message = "通知イベントを受信しました。"
+	}
+	if req.Group == "" {
+		req.Group = "codex-notify"
+	}
+	if benchDryRun() {
+		return nil
+	}
+
+	failures := []string{}
+	for _, backend := range notificationBackends() {
+		if !backend.Available() {
+			continue
+		}
+		if err := injectedFailure(backend.Name()); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", backend.Name(), err))
+			continue
+		}
+		if err := backend.Send(adaptForBackend(req, backend.Capabilities())); err != nil {
+			logDebugf("notify %s: %s failed: %v", req.Group, backend.Name(), err)
+			failures = append(failures, fmt.Sprintf("%s: %v", backend.Name(), err))
+			continue
+		}
+		recordDelivered(req.Group, backend.Name())
+		logInfof("notify %s: delivered by %s", req.Group, backend.Name())
+		return nil
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("all notifiers failed (%s)", strings.Join(failures, "; "))
+	}
+	if hostOS == "linux" {
+		return errors.New("no notifier available (notify-send not found)")
+	}
+	if sandboxModeEnabled() {
+		return errors.New("no notifier available in sandbox mode (popup helper and terminal-notifier not found)")
+	}
+	return errors.New("no notifier available (terminal-notifier and osascript not found)")
+}
+
+// popupBackend is the compiled Swift helper.
+type popupBackend struct{}
+
+func (popupBackend) Name() string { return "popup" }
+
+func (popupBackend) Capabilities() backendCapabilities {
+	return backendCapabilities{Click: true, Actions: true, Format: formatMarkdown}
+}
+
+func (popupBackend) Available() bool {
+	return hostOS == "darwin" && notificationUIStyle() == notificationUIPopup && !powerSaverActive()
+}
+
+func (popupBackend) Send(req notificationRequest) error {
+	return sendNativePopupNotification(req, req.Title, req.Message, req.Group)
+}
+
+type terminalNotifierBackend struct{}
+
+func (terminalNotifierBackend) Name() string { return "terminal-notifier" }
+
+func (terminalNotifierBackend) Capabilities() backendCapabilities {
+	return backendCapabilities{Click: true, Images: true, Updates: true, Removal: true}
+}
+
+func (terminalNotifierBackend) Available() bool {
+	_, ok := lookupCmd("terminal-notifier")
+	return ok
+}
+
+func (terminalNotifierBackend) Send(req notificationRequest) error {
+	path, ok := lookupCmd("terminal-notifier")
+	if !ok {
+		return errors.New("terminal-notifier not found")
+	}
+
+	n := notify.Notification{
+		Title:          req.Title,
+		Message:        req.Message,
+		Group:          req.Group,
+		ExecuteOnClick: req.ExecuteOnClick,
+		Activate:       req.ActivateBundleID,
+		Sound:          backendSound(req.Sound),
+	}
+	if iconIsFile(req.Icon) {
+		n.Image = req.Icon
+	}
+	if err := notify.TerminalNotifier(path, n, notify.Options{PrivateArgv: privateArgvEnabled(), Trace: logExec}); err != nil {
+		return err
+	}
+	playFileSound(req.Sound)
+	return nil
+}
+
+// osascriptBackend is the `display notification` fallback. It cannot run a
+// command on click, and is never used in sandbox mode.
+type osascriptBackend struct{}
+
+func (osascriptBackend) Name() string { return "osascript" }
+
+func (osascriptBackend) Capabilities() backendCapabilities {
+	return backendCapabilities{}
+}
+
+func (osascriptBackend) Available() bool {
+	if sandboxModeEnabled() {
+		return false
+	}
+	_, ok := lookupCmd("osascript")
+	return ok
+}
+
+func (osascriptBackend) Send(req notificationRequest) error {
+	path, ok := lookupCmd("osascript")
+	if !ok {
+		return errors.New("osascript not found")
+	}
+
+	n := notify.Notification{Title: req.Title, Message: req.Message, Sound: backendSound(req.Sound)}
+	if err := notify.Osascript(path, n, notify.Options{PrivateArgv: privateArgvEnabled(), Trace: logExec}); err != nil {
+		return err
+	}
+	playFileSound(req.Sound)
+	return nil
+}
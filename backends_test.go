@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestAdaptForBackend(t *testing.T) {
+	req := notificationRequest{
+		Title:             "Codex: Turn Complete",
+		Message:           "done",
+		ExecuteOnClick:    "codex-notify action open",
+		PopupPrimaryLabel: "Open",
+		ExtraChoices:      []approvalChoice{{Label: "Mute project 1h", Command: "codex-notify action mute-project"}},
+	}
+
+	popup := adaptForBackend(req, popupBackend{}.Capabilities())
+	if len(popup.ExtraChoices) != 1 || popup.ExecuteOnClick == "" {
+		t.Fatalf("popup adaptation dropped content: %+v", popup)
+	}
+
+	tn := adaptForBackend(req, terminalNotifierBackend{}.Capabilities())
+	if len(tn.ExtraChoices) != 0 {
+		t.Fatalf("terminal-notifier adaptation kept buttons: %+v", tn.ExtraChoices)
+	}
+	if tn.ExecuteOnClick != req.ExecuteOnClick {
+		t.Fatalf("terminal-notifier adaptation ExecuteOnClick = %q, want %q", tn.ExecuteOnClick, req.ExecuteOnClick)
+	}
+
+	plain := adaptForBackend(req, osascriptBackend{}.Capabilities())
+	if plain.ExecuteOnClick != "" || len(plain.ExtraChoices) != 0 {
+		t.Fatalf("osascript adaptation kept click actions: %+v", plain)
+	}
+	if plain.Title != req.Title || plain.Message != req.Message {
+		t.Fatalf("osascript adaptation changed text: %+v", plain)
+	}
+}
+
+func TestOsascriptBackendUnavailableInSandbox(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_SANDBOX", "1")
+	if (osascriptBackend{}).Available() {
+		t.Fatalf("osascriptBackend.Available() in sandbox mode = true, want false")
+	}
+}
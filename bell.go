@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	terminalBellOff    = "off"
+	terminalBellBell   = "bell"
+	terminalBellOSC777 = "osc777"
+)
+
+// terminalTTYPath is where the bell is written: the hook's controlling
+// terminal, which is the one Codex runs in.
+var terminalTTYPath = "/dev/tty"
+
+// terminalBellMode is CODEX_NOTIFY_TERMINAL_BELL: off, bell (BEL only), or
+// osc777 (an OSC 777 notification followed by BEL).
+func terminalBellMode() string {
+	switch v := strings.TrimSpace(strings.ToLower(getenv("CODEX_NOTIFY_TERMINAL_BELL"))); v {
+	case terminalBellBell, "1", "true", "yes", "on":
+		return terminalBellBell
+	case terminalBellOSC777:
+		return terminalBellOSC777
+	default:
+		return terminalBellOff
+	}
+}
+
+// terminalBellSequence is what the terminal receives for payload. Inside
+// tmux the OSC is wrapped for passthrough (it needs allow-passthrough on),
+// while the BEL stays outside so tmux flags the window as well.
+func terminalBellSequence(mode string, payload map[string]any, inTmux bool) string {
+	if mode == terminalBellOff {
+		return ""
+	}
+	seq := ""
+	if mode == terminalBellOSC777 {
+		title, message := phoneText(payload)
+		osc := "\x1b]777;notify;" + oscField(title, true) + ";" + oscField(message, false) + "\x07"
+		if inTmux {
+			osc = "\x1bPtmux;" + strings.ReplaceAll(osc, "\x1b", "\x1b\x1b") + "\x1b\\"
+		}
+		seq = osc
+	}
+	return seq + "\a"
+}
+
+// oscField keeps text from ending the sequence early: control characters
+// become spaces, and in the title so does the ';' that separates fields.
+func oscField(s string, title bool) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || title && r == ';' {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// ringTerminalBell rings the Codex terminal for approvals alongside the
+// desktop notification, so the dock bounce or tab highlight still draws
+// attention when banners go unnoticed. Without a terminal it does nothing.
+func ringTerminalBell(payload map[string]any) {
+	mode := terminalBellMode()
+	if mode == terminalBellOff || payloadEventName(payload) != "approval-requested" || benchDryRun() {
+		return
+	}
+	tty, err := os.OpenFile(terminalTTYPath, os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	defer tty.Close()
+	_, _ = tty.WriteString(terminalBellSequence(mode, payload, getenv("TMUX") != ""))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTerminalBellSequence(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "0")
+	payload := map[string]any{"type": "approval-requested", "message": "Run `make`;\nthen deploy?"}
+
+	if got := terminalBellSequence(terminalBellOff, payload, false); got != "" {
+		t.Fatalf("off = %q", got)
+	}
+	if got := terminalBellSequence(terminalBellBell, payload, false); got != "\a" {
+		t.Fatalf("bell = %q", got)
+	}
+	want := "\x1b]777;notify;Codex: Approval Requested;Run make; then deploy?\x07\a"
+	if got := terminalBellSequence(terminalBellOSC777, payload, false); got != want {
+		t.Fatalf("osc777 = %q, want %q", got, want)
+	}
+	want = "\x1bPtmux;\x1b\x1b]777;notify;Codex: Approval Requested;Run make; then deploy?\x07\x1b\\\a"
+	if got := terminalBellSequence(terminalBellOSC777, payload, true); got != want {
+		t.Fatalf("osc777 in tmux = %q, want %q", got, want)
+	}
+}
+
+func TestRingTerminalBellOnlyForApprovals(t *testing.T) {
+	tty := filepath.Join(t.TempDir(), "tty")
+	if err := os.WriteFile(tty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stub(t, &terminalTTYPath, tty)
+	t.Setenv("CODEX_NOTIFY_TERMINAL_BELL", "bell")
+	t.Setenv("TMUX", "")
+
+	ringTerminalBell(map[string]any{"type": "agent-turn-complete"})
+	ringTerminalBell(map[string]any{"type": "approval-requested"})
+	if got, _ := os.ReadFile(tty); string(got) != "\a" {
+		t.Fatalf("tty got %q, want one BEL", got)
+	}
+	if daemonForwardingEnabled() {
+		t.Fatal("hook would forward to the daemon, which has no terminal to ring")
+	}
+}
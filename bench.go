@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// benchDryRunEnv makes hook run its whole decision path but skip the
+// notifier, so `bench hook` measures codex-notify itself.
+const benchDryRunEnv = "CODEX_NOTIFY_BENCH_DRY_RUN"
+
+func benchDryRun() bool {
+	return getenv(benchDryRunEnv) == "1"
+}
+
+type benchResult struct {
+	Command  string  `json:"command"`
+	Fixture  string  `json:"fixture"`
+	Runs     int     `json:"runs"`
+	MinMS    float64 `json:"min_ms"`
+	MedianMS float64 `json:"median_ms"`
+	P95MS    float64 `json:"p95_ms"`
+	MaxMS    float64 `json:"max_ms"`
+}
+
+func runBench(args []string) error {
+	if len(args) == 0 || args[0] != "hook" {
+		return usageError(errors.New("bench requires: hook"))
+	}
+
+	fs := flag.NewFlagSet("bench hook", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	runs := fs.Int("n", 20, "number of hook invocations")
+	fixture := fs.String("fixture", "turn-complete", "payload fixture to deliver")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	out := outFlags.output()
+	if *runs < 1 {
+		return usageError(errors.New("-n must be at least 1"))
+	}
+
+	content, err := payloadFixture(*fixture)
+	if err != nil {
+		return usageError(err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(content, &payload); err != nil {
+		return fmt.Errorf("parse fixture: %w", err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("resolve executable: %w", err)
+	}
+	env, cleanup, err := benchHookEnv()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	durations := make([]time.Duration, 0, *runs)
+	for i := 0; i < *runs; i++ {
+		// A fresh turn id per run keeps deduplication from cutting the
+		// hook short after the first invocation.
+		payload["turn-id"] = fmt.Sprintf("bench-%d-%d", os.Getpid(), i)
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("encode payload: %w", err)
+		}
+
+		cmd := exec.Command(executable, "hook", "--quiet", "--payload-file", "-")
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(string(raw))
+		start := time.Now()
+		output, err := cmd.CombinedOutput()
+		elapsed := time.Since(start)
+		if err != nil {
+			return fmt.Errorf("hook run %d failed: %w (%s)", i+1, err, strings.TrimSpace(string(output)))
+		}
+		durations = append(durations, elapsed)
+	}
+
+	result := summarizeBench(*fixture, durations)
+	out.Printf("hook %s: %d runs, min %.1fms, median %.1fms, p95 %.1fms, max %.1fms\n",
+		result.Fixture, result.Runs, result.MinMS, result.MedianMS, result.P95MS, result.MaxMS)
+	return out.Result(result)
+}
+
+// benchHookEnv returns the environment for benchmarked hook processes: the
+// caller's settings and config file, but a scratch home so their events,
+// state, and mutes never touch the real runtime dir.
+func benchHookEnv() ([]string, func(), error) {
+	scratch, err := os.MkdirTemp("", "codex-notify-bench-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("create bench dir: %w", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(scratch) }
+
+	env := []string{}
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		switch key {
+		case "HOME", "XDG_CACHE_HOME", "TMPDIR", "TMUX", "CODEX_NOTIFY_CONFIG_FILE", privateRelayEnv:
+			continue
+		}
+		env = append(env, kv)
+	}
+	if cfgPath, err := userConfigPath(); err == nil {
+		env = append(env, "CODEX_NOTIFY_CONFIG_FILE="+cfgPath)
+	}
+	env = append(env,
+		"HOME="+scratch,
+		"XDG_CACHE_HOME="+scratch,
+		"TMPDIR="+scratch,
+		benchDryRunEnv+"=1",
+	)
+	return env, cleanup, nil
+}
+
+func summarizeBench(fixture string, durations []time.Duration) benchResult {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	p95 := (len(sorted)*95 + 99) / 100
+	return benchResult{
+		Command:  "bench",
+		Fixture:  fixture,
+		Runs:     len(sorted),
+		MinMS:    ms(sorted[0]),
+		MedianMS: ms(sorted[len(sorted)/2]),
+		P95MS:    ms(sorted[p95-1]),
+		MaxMS:    ms(sorted[len(sorted)-1]),
+	}
+}
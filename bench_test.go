@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizeBench(t *testing.T) {
+	durations := []time.Duration{}
+	for i := 20; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+
+	got := summarizeBench("turn-complete", durations)
+	want := benchResult{Command: "bench", Fixture: "turn-complete", Runs: 20, MinMS: 1, MedianMS: 11, P95MS: 19, MaxMS: 20}
+	if got != want {
+		t.Fatalf("summarizeBench() = %+v, want %+v", got, want)
+	}
+
+	if one := summarizeBench("x", []time.Duration{3 * time.Millisecond}); one.P95MS != 3 || one.MedianMS != 3 {
+		t.Fatalf("summarizeBench(one run) = %+v, want every stat 3ms", one)
+	}
+}
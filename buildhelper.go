@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// runBuildHelper installs the popup helper now instead of on the first
+// popup, so compile errors show up here rather than as a silent fallback.
+func runBuildHelper(args []string) error {
+	fs := flag.NewFlagSet("build-helper", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	out := outFlags.output()
+
+	path, elapsed, err := prewarmHelper()
+	if err != nil {
+		return err
+	}
+	out.Printf("popup helper ready: %s (%s)\n", path, elapsed)
+	return out.Result(commandResult{Command: "build-helper", Status: "ready", Path: path})
+}
+
+// prewarmHelper builds or verifies the popup helper and reports how long it
+// took.
+func prewarmHelper() (string, time.Duration, error) {
+	if hostOS != "darwin" {
+		return "", 0, usageError(errors.New("the popup helper is only used on macOS"))
+	}
+	started := time.Now()
+	path, err := ensureApprovalActionHelper()
+	if err != nil {
+		return "", 0, backendError(fmt.Errorf("build popup helper: %w", err))
+	}
+	return path, time.Since(started).Round(time.Millisecond), nil
+}
@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+func TestPrewarmHelperOnlyOnMacOS(t *testing.T) {
+	useHostOS(t, "linux")
+
+	_, _, err := prewarmHelper()
+	if err == nil {
+		t.Fatal("prewarmHelper() succeeded on linux")
+	}
+	if got := exitCodeFor(err); got != exitUsage {
+		t.Fatalf("exit code = %d, want %d", got, exitUsage)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// backendEnv forces a notification backend. Only capture:<dir> is
+	// supported: it writes notifications to files instead of showing them.
+	backendEnv    = "CODEX_NOTIFY_BACKEND"
+	capturePrefix = "capture:"
+)
+
+// captureDir returns the directory of CODEX_NOTIFY_BACKEND=capture:<dir>.
+func captureDir() (string, bool) {
+	v := strings.TrimSpace(getenv(backendEnv))
+	if !strings.HasPrefix(v, capturePrefix) {
+		return "", false
+	}
+	dir, err := expandUserPath(strings.TrimSpace(strings.TrimPrefix(v, capturePrefix)))
+	if err != nil || dir == "" {
+		return "", false
+	}
+	return dir, true
+}
+
+// checkBackendEnv rejects a CODEX_NOTIFY_BACKEND other than capture:<dir>,
+// which would otherwise be ignored and post real notifications.
+func checkBackendEnv() error {
+	v := strings.TrimSpace(getenv(backendEnv))
+	if v == "" {
+		return nil
+	}
+	if _, ok := captureDir(); !ok {
+		return usageError(fmt.Errorf("%s=%q: only %s<dir> is supported", backendEnv, v, capturePrefix))
+	}
+	return nil
+}
+
+// capturedNotification is one file written by the capture backend: what
+// the first real backend would have been asked to show.
+type capturedNotification struct {
+	Title          string           `json:"title"`
+	Message        string           `json:"message"`
+	Group          string           `json:"group"`
+	ExecuteOnClick string           `json:"execute_on_click,omitempty"`
+	Activate       string           `json:"activate,omitempty"`
+	PrimaryLabel   string           `json:"primary_label,omitempty"`
+	Choices        []approvalChoice `json:"choices,omitempty"`
+	AccentColor    string           `json:"accent_color,omitempty"`
+	Icon           string           `json:"icon,omitempty"`
+	Sound          string           `json:"sound,omitempty"`
+	Time           string           `json:"time"`
+}
+
+// captureBackend renders like the popup, which shows the most, so a test
+// sees every choice and command a real notification could offer.
+type captureBackend struct {
+	Dir string
+}
+
+func (captureBackend) Name() string { return "capture" }
+
+func (captureBackend) Capabilities() backendCapabilities {
+	return backendCapabilities{Click: true, Actions: true, Format: formatMarkdown}
+}
+
+func (captureBackend) Available() bool { return true }
+
+// Send writes the notification to <unix-nanos>-<group>.json, so a
+// directory listing is in delivery order.
+func (b captureBackend) Send(req notificationRequest) error {
+	if err := os.MkdirAll(b.Dir, 0o700); err != nil {
+		return err
+	}
+	now := time.Now()
+	data, err := json.MarshalIndent(capturedNotification{
+		Title:          req.Title,
+		Message:        req.Message,
+		Group:          req.Group,
+		ExecuteOnClick: req.ExecuteOnClick,
+		Activate:       req.ActivateBundleID,
+		PrimaryLabel:   req.PopupPrimaryLabel,
+		Choices:        req.ExtraChoices,
+		AccentColor:    req.AccentColor,
+		Icon:           req.Icon,
+		Sound:          req.Sound,
+		Time:           now.UTC().Format(time.RFC3339Nano),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	for attempt := 0; attempt < 100; attempt++ {
+		name := fmt.Sprintf("%d-%s.json", now.UnixNano()+int64(attempt), sanitizeID(req.Group))
+		f, err := os.OpenFile(filepath.Join(b.Dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, privateFileMode)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	return errors.New("capture: could not pick a file name in " + b.Dir)
+}
+
+// captureApproval records the approval popup as one notification whose
+// choices are the popup's buttons.
+func captureApproval(dir string, payload map[string]any) error {
+	return captureBackend{Dir: dir}.Send(buildNativeApprovalContent(payload))
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readCaptured(t *testing.T, dir string) []capturedNotification {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	var captured []capturedNotification
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n capturedNotification
+		if err := json.Unmarshal(data, &n); err != nil {
+			t.Fatalf("%s: %v", entry.Name(), err)
+		}
+		captured = append(captured, n)
+	}
+	return captured
+}
+
+func TestCaptureBackendRecordsHookPipeline(t *testing.T) {
+	_, dir := useTempHome(t)
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "0")
+
+	if _, err := deliverHookPayload(map[string]any{"type": "agent-turn-complete", "thread-id": "t1", "last-assistant-message": "All **done**"}); err != nil {
+		t.Fatalf("turn complete: %v", err)
+	}
+	if _, err := deliverHookPayload(map[string]any{"type": "approval-requested", "thread-id": "t1", "message": "Run make?"}); err != nil {
+		t.Fatalf("approval: %v", err)
+	}
+
+	captured := readCaptured(t, dir)
+	if len(captured) != 2 {
+		t.Fatalf("captured %d notifications, want 2: %+v", len(captured), captured)
+	}
+	turn, approval := captured[0], captured[1]
+	if !strings.Contains(turn.Message, "All **done**") || turn.Group != notificationGroup("agent-turn-complete", "t1") || turn.ExecuteOnClick == "" {
+		t.Fatalf("turn notification = %+v", turn)
+	}
+	if approval.Group != notificationGroup("approval-native", "t1") || len(approval.Choices) < 3 || approval.Choices[1].Label != "Approve" {
+		t.Fatalf("approval notification = %+v", approval)
+	}
+}
+
+func TestCaptureBackendWorksOnAnyOS(t *testing.T) {
+	useHostOS(t, "windows")
+	dir := t.TempDir()
+	t.Setenv(backendEnv, "capture:"+dir)
+
+	if err := sendNotification(notificationRequest{Title: "T", Message: "M"}); err != nil {
+		t.Fatalf("sendNotification() error = %v", err)
+	}
+	if captured := readCaptured(t, dir); len(captured) != 1 || captured[0].Title != "T" || captured[0].Group != "codex-notify" {
+		t.Fatalf("captured = %+v", captured)
+	}
+
+	t.Setenv(backendEnv, "capture:")
+	if _, ok := captureDir(); ok {
+		t.Fatal("capture: without a directory enabled capture mode")
+	}
+}
+
+func TestCaptureBackendSkipsRemoteSinks(t *testing.T) {
+	home, dir := useTempHome(t)
+
+	posted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted = true
+	}))
+	defer server.Close()
+	cfgPath := filepath.Join(home, "config.toml")
+	if err := os.WriteFile(cfgPath, []byte("[webhooks.probe]\nurl = \""+server.URL+"\"\nevents = [\"approval-requested\"]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", cfgPath)
+
+	if _, err := deliverHookPayload(map[string]any{"type": "approval-requested", "thread-id": "t1", "message": "Run make?"}); err != nil {
+		t.Fatalf("approval: %v", err)
+	}
+	if posted {
+		t.Fatal("capture mode posted to a remote sink")
+	}
+	if captured := readCaptured(t, dir); len(captured) != 1 {
+		t.Fatalf("captured %d notifications, want 1", len(captured))
+	}
+}
+
+func TestCheckBackendEnv(t *testing.T) {
+	t.Setenv(backendEnv, "")
+	if err := checkBackendEnv(); err != nil {
+		t.Fatalf("unset: %v", err)
+	}
+	t.Setenv(backendEnv, "capture:"+t.TempDir())
+	if err := checkBackendEnv(); err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	t.Setenv(backendEnv, "popup")
+	if err := checkBackendEnv(); exitCodeFor(err) != exitUsage || !strings.Contains(err.Error(), "capture:<dir>") {
+		t.Fatalf("popup: error = %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	chooserPopup  = "popup"
+	chooserDialog = "dialog"
+)
+
+// rememberableChoices are the chooser answers its remember box can keep:
+// the ones that need no typing or later decision.
+var rememberableChoices = []string{"open", "approve", "reject"}
+
+// chooserStyle is CODEX_NOTIFY_CHOOSER: "popup" (default) answers `action
+// choose` with the popup helper, which does not take focus; "dialog" keeps
+// the AppleScript dialog.
+func chooserStyle() string {
+	if strings.TrimSpace(strings.ToLower(getenv("CODEX_NOTIFY_CHOOSER"))) == chooserDialog {
+		return chooserDialog
+	}
+	return chooserPopup
+}
+
+// showChooserPopup shows thread's approval in the popup helper, with the
+// payload the hook kept when there is one.
+func showChooserPopup(thread string) error {
+	if chooserStyle() == chooserDialog || hostOS != "darwin" {
+		return errors.New("chooser popup is off")
+	}
+	payload := map[string]any{"type": "approval-requested", "thread-id": thread}
+	if pending, ok := lookupPendingApproval(thread); ok {
+		if pending.Payload != nil {
+			payload = pending.Payload
+		} else if pending.Cwd != "" {
+			payload["cwd"] = pending.Cwd
+		}
+	}
+	return startApprovalPopup(payload, true)
+}
+
+// withRememberCommands gives the rememberable choices a command that also
+// remembers the answer for cwd's project, and returns the remember box
+// label. Without a project there is nothing to remember it for.
+func withRememberCommands(choices []approvalChoice, thread, cwd string) ([]approvalChoice, string) {
+	project := normalizeProjectPath(cwd)
+	if project == "" || thread == "" {
+		return choices, ""
+	}
+	out := make([]approvalChoice, len(choices))
+	for i, choice := range choices {
+		out[i] = choice
+		for _, action := range rememberableChoices {
+			prefix := buildActionCommand(action, thread)
+			if !strings.HasPrefix(choice.Command, prefix) {
+				continue
+			}
+			// Keep whatever followed, such as --expires-at.
+			out[i].RememberCommand = buildActionCommand("choose", thread) + " --remember " + action + strings.TrimPrefix(choice.Command, prefix)
+			break
+		}
+	}
+	return out, fmt.Sprintf("Remember for %s", filepath.Base(project))
+}
+
+// chooseProject is the project `action choose` remembers answers for: the
+// one given with --cwd, or else the pending approval's.
+func chooseProject(thread, cwd string) string {
+	if cwd == "" {
+		if pending, ok := lookupPendingApproval(thread); ok {
+			cwd = pending.Cwd
+		}
+	}
+	return normalizeProjectPath(cwd)
+}
+
+// rememberedChoice is the answer remembered for project, if any.
+func rememberedChoice(project string) string {
+	if project == "" {
+		return ""
+	}
+	state, err := loadState()
+	if err != nil {
+		return ""
+	}
+	return state.RememberedChoices[project]
+}
+
+// rememberChoice keeps choice as project's answer; an empty choice forgets
+// it.
+func rememberChoice(project, choice string) error {
+	return updateState(func(s *notifyState) {
+		if choice == "" {
+			delete(s.RememberedChoices, project)
+			return
+		}
+		if s.RememberedChoices == nil {
+			s.RememberedChoices = map[string]string{}
+		}
+		s.RememberedChoices[project] = choice
+	})
+}
+
+// resolveChooseAction applies choose's --remember and --forget and any
+// remembered answer, and returns the action to run instead of asking, or
+// "" to ask. forgot reports that --forget cleared the answer.
+func resolveChooseAction(thread, cwd, remember string, forget bool) (action string, forgot bool, err error) {
+	project := chooseProject(thread, cwd)
+	switch {
+	case forget:
+		if project == "" {
+			return "", false, usageError(errors.New("no project to forget the choice for; pass --cwd"))
+		}
+		return "", true, rememberChoice(project, "")
+	case remember != "":
+		if !containsString(rememberableChoices, remember) {
+			return "", false, usageError(fmt.Errorf("--remember must be one of %s", strings.Join(rememberableChoices, ", ")))
+		}
+		// A lost project costs the memory, not the answer.
+		if project != "" {
+			_ = rememberChoice(project, remember)
+		}
+		return remember, false, nil
+	default:
+		return rememberedChoice(project), false, nil
+	}
+}
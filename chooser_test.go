@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithRememberCommands(t *testing.T) {
+	choices := defaultApprovalChoices("t1")
+	deadline := time.Now().Add(time.Minute)
+	choices = expiringChoices(choices, "t1", deadline, time.Now())
+
+	got, label := withRememberCommands(choices, "t1", "/src/acme-web/")
+	if label != "Remember for acme-web" {
+		t.Fatalf("label = %q", label)
+	}
+	for _, choice := range got {
+		remembers := choice.RememberCommand != ""
+		if want := choice.Label == "Open" || choice.Label == "Approve" || choice.Label == "Reject"; remembers != want {
+			t.Errorf("%s: remember command %q", choice.Label, choice.RememberCommand)
+		}
+	}
+	if want := buildActionCommand("choose", "t1") + " --remember approve --expires-at "; !strings.HasPrefix(got[1].RememberCommand, want) {
+		t.Fatalf("approve remember command = %q", got[1].RememberCommand)
+	}
+
+	if _, label := withRememberCommands(choices, "t1", ""); label != "" {
+		t.Fatalf("label without a project = %q", label)
+	}
+}
+
+func TestResolveChooseAction(t *testing.T) {
+	useTempHome(t)
+	if _, ok := trackApproval(map[string]any{"type": "approval-requested", "thread-id": "t1", "cwd": "/src/app"}, time.Now()); !ok {
+		t.Fatal("trackApproval failed")
+	}
+
+	if action, _, err := resolveChooseAction("t1", "", "", false); err != nil || action != "" {
+		t.Fatalf("nothing remembered = %q, %v", action, err)
+	}
+	if _, _, err := resolveChooseAction("t1", "", "reject-with-reason", false); exitCodeFor(err) != exitUsage {
+		t.Fatalf("remembering reject-with-reason = %v", err)
+	}
+	if action, _, err := resolveChooseAction("t1", "", "approve", false); err != nil || action != "approve" {
+		t.Fatalf("--remember approve = %q, %v", action, err)
+	}
+	// The answer holds for the project, whichever thread asks.
+	if action, _, _ := resolveChooseAction("t2", "/src/app", "", false); action != "approve" {
+		t.Fatalf("remembered = %q, want approve", action)
+	}
+	if _, forgot, err := resolveChooseAction("t1", "", "", true); err != nil || !forgot {
+		t.Fatalf("--forget = %v, %v", forgot, err)
+	}
+	if action, _, _ := resolveChooseAction("t1", "", "", false); action != "" {
+		t.Fatalf("after --forget = %q", action)
+	}
+
+	if err := runAction([]string{"open", "--remember", "open", "--quiet"}); exitCodeFor(err) != exitUsage {
+		t.Fatalf("--remember on open = %v", err)
+	}
+}
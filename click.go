@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+)
+
+const clickActionNone = "none"
+
+// clickActions are the values of [click] entries that map onto
+// `codex-notify action <name>`; script:<name> runs a [scripts] entry and
+// anything else is run as a shell command.
+var clickActions = map[string]string{
+	"open":   "Open",
+	"choose": "Choose",
+}
+
+// clickAction returns the configured click behavior for event, falling back
+// to the "default" entry.
+func (c userConfig) clickAction(event string) (string, bool) {
+	if action, ok := c.Click[event]; ok {
+		return action, true
+	}
+	action, ok := c.Click["default"]
+	return action, ok
+}
+
+// buildClickCommand turns a configured click action into the command run
+// when the notification is clicked. Shell commands may use {cwd} and
+// {thread_id}, which are substituted shell-quoted.
+func buildClickCommand(action string, payload map[string]any) string {
+	threadID := payloadThreadID(payload)
+	name := strings.ToLower(action)
+	if name == clickActionNone {
+		return ""
+	}
+	if _, ok := clickActions[name]; ok {
+		return buildActionCommand(name, threadID)
+	}
+	if script, ok := strings.CutPrefix(action, clickScriptPrefix); ok {
+		return buildScriptActionCommand(script, payload)
+	}
+
+	replacer := strings.NewReplacer(
+		"{cwd}", shellQuote(payloadCwd(payload)),
+		"{thread_id}", shellQuote(threadID),
+	)
+	return replacer.Replace(action)
+}
+
+func clickActionLabel(action string) string {
+	name := strings.ToLower(action)
+	if name == clickActionNone {
+		return "Close"
+	}
+	if label, ok := clickActions[name]; ok {
+		return label
+	}
+	if strings.HasPrefix(name, clickScriptPrefix) {
+		return "Run"
+	}
+	return "Open"
+}
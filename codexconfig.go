@@ -0,0 +1,288 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	managedBlockBegin = "# BEGIN codex-notify"
+	managedBlockEnd   = "# END codex-notify"
+
+	// Codex's own terminal notifications duplicate every desktop banner once
+	// codex-notify is installed, so the managed setting turns them off.
+	managedTUINotificationsLine = "notifications = false"
+)
+
+// sectionBounds returns the index of the `[name]` header and the exclusive end
+// of that table. header is -1 when the table does not exist.
+func sectionBounds(lines []string, name string) (header, end int) {
+	header = -1
+	for i, line := range lines {
+		if !isTableHeader(line) {
+			continue
+		}
+		if header >= 0 {
+			return header, i
+		}
+		if tableHeaderName(line) == name {
+			header = i
+		}
+	}
+	if header < 0 {
+		return -1, len(lines)
+	}
+	return header, len(lines)
+}
+
+func isTableHeader(line string) bool {
+	trimmed := stripTOMLComment(strings.TrimSpace(line))
+	return strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]")
+}
+
+func tableHeaderName(line string) string {
+	trimmed := stripTOMLComment(strings.TrimSpace(line))
+	trimmed = strings.TrimPrefix(strings.TrimPrefix(trimmed, "["), "[")
+	trimmed = strings.TrimSuffix(strings.TrimSuffix(trimmed, "]"), "]")
+	return strings.TrimSpace(trimmed)
+}
+
+func stripTOMLComment(line string) string {
+	if i := indexOutsideQuotes(line, '#'); i >= 0 {
+		return strings.TrimSpace(line[:i])
+	}
+	return line
+}
+
+// indexOutsideQuotes returns the index of the first target byte that is not
+// inside a basic or literal TOML string, or -1.
+func indexOutsideQuotes(s string, target byte) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == target:
+			return i
+		}
+	}
+	return -1
+}
+
+// managedBlockBounds finds the first codex-notify managed block in
+// lines[from:to]. begin and end are the marker line indexes, or -1.
+func managedBlockBounds(lines []string, from, to int) (begin, end int) {
+	begin = -1
+	for i := from; i < to && i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case trimmed == managedBlockBegin && begin < 0:
+			begin = i
+		case trimmed == managedBlockEnd && begin >= 0:
+			return begin, i
+		}
+	}
+	return -1, -1
+}
+
+func managedBlock(body ...string) []string {
+	block := []string{managedBlockBegin}
+	block = append(block, body...)
+	return append(block, managedBlockEnd)
+}
+
+func sameLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if strings.TrimSpace(a[i]) != strings.TrimSpace(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func joinConfigLines(lines []string) []byte {
+	joined := strings.Join(lines, "\n")
+	if strings.TrimSpace(joined) == "" {
+		return []byte{}
+	}
+	return []byte(joined + "\n")
+}
+
+func firstTableHeaderIndex(lines []string) int {
+	for i, line := range lines {
+		if isTableHeader(line) {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// setManagedNotifyBlock ensures the root-level managed block contains the
+// codex-notify notify line. A legacy unmanaged codex-notify line is moved into
+// the block; any other root notify value is only replaced when replace is set.
+func setManagedNotifyBlock(content []byte, replace bool) ([]byte, bool, error) {
+	lines := splitLines(content)
+	block := managedBlock(defaultNotifyLine)
+	rootEnd := firstTableHeaderIndex(lines)
+
+	if begin, end := managedBlockBounds(lines, 0, rootEnd); begin >= 0 {
+		if sameLines(lines[begin:end+1], block) || !replace && blockChainsNotify(lines[begin:end+1]) {
+			return content, false, nil
+		}
+		out := append([]string{}, lines[:begin]...)
+		out = append(out, block...)
+		out = append(out, lines[end+1:]...)
+		return joinConfigLines(out), true, nil
+	}
+
+	for i := 0; i < rootEnd; i++ {
+		line := lines[i]
+		// Only match notify at root level (no indentation)
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if !isRootNotifyLine(trimmed) {
+			continue
+		}
+		if !isCodexNotifyHookLine(trimmed) && !replace {
+			return nil, false, errors.New("existing notify config found; rerun with --replace to update it")
+		}
+		out := append([]string{}, lines[:i]...)
+		out = append(out, block...)
+		out = append(out, lines[i+1:]...)
+		return joinConfigLines(out), true, nil
+	}
+
+	// Add the block at root level, before the first section.
+	out := append([]string{}, lines[:rootEnd]...)
+	if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" {
+		out = append(out, "")
+	}
+	out = append(out, block...)
+	if rootEnd < len(lines) {
+		out = append(out, "")
+		out = append(out, lines[rootEnd:]...)
+	}
+	return joinConfigLines(out), true, nil
+}
+
+// blockChainsNotify reports whether a managed block holds a codex-notify
+// line chaining to another notify command; init keeps it unless replacing.
+func blockChainsNotify(block []string) bool {
+	for _, line := range block {
+		trimmed := strings.TrimSpace(line)
+		if !isCodexNotifyHookLine(trimmed) {
+			continue
+		}
+		if argv, err := notifyArgv(trimmed); err == nil && len(hookChain(argv)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasManagedNotifyBlock reports whether the root-level managed block exists.
+func hasManagedNotifyBlock(content []byte) bool {
+	lines := splitLines(content)
+	begin, _ := managedBlockBounds(lines, 0, firstTableHeaderIndex(lines))
+	return begin >= 0
+}
+
+// setManagedTUINotifications writes `notifications = false` under [tui]
+// inside a managed block. An existing user-owned notifications key is only
+// replaced when replace is set.
+func setManagedTUINotifications(content []byte, replace bool) ([]byte, bool, error) {
+	lines := splitLines(content)
+	block := managedBlock(managedTUINotificationsLine)
+
+	header, end := sectionBounds(lines, "tui")
+	if header < 0 {
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "[tui]")
+		lines = append(lines, block...)
+		return joinConfigLines(lines), true, nil
+	}
+
+	if begin, blockEnd := managedBlockBounds(lines, header+1, end); begin >= 0 {
+		if sameLines(lines[begin:blockEnd+1], block) {
+			return content, false, nil
+		}
+		out := append([]string{}, lines[:begin]...)
+		out = append(out, block...)
+		out = append(out, lines[blockEnd+1:]...)
+		return joinConfigLines(out), true, nil
+	}
+
+	for i := header + 1; i < end; i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(trimmed, "notifications") {
+			continue
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(trimmed, "notifications"))
+		if !strings.HasPrefix(rest, "=") {
+			continue
+		}
+		if !replace {
+			return nil, false, errors.New("existing [tui] notifications setting found; rerun with --replace to let codex-notify manage it")
+		}
+		out := append([]string{}, lines[:i]...)
+		out = append(out, block...)
+		out = append(out, lines[i+1:]...)
+		return joinConfigLines(out), true, nil
+	}
+
+	out := append([]string{}, lines[:header+1]...)
+	out = append(out, block...)
+	out = append(out, lines[header+1:]...)
+	return joinConfigLines(out), true, nil
+}
+
+// removeManagedBlocks drops every codex-notify managed block, markers included.
+func removeManagedBlocks(content []byte) ([]byte, bool) {
+	lines := splitLines(content)
+	out := make([]string, 0, len(lines))
+	removed := false
+
+	for i := 0; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == managedBlockBegin {
+			if _, end := managedBlockBounds(lines, i, len(lines)); end >= 0 {
+				i = end
+				removed = true
+				continue
+			}
+		}
+		out = append(out, lines[i])
+	}
+	return joinConfigLines(out), removed
+}
+
+func isRootNotifyLine(trimmedLine string) bool {
+	return rootNotifyLineRE.MatchString(trimmedLine)
+}
+
+func isCodexNotifyHookLine(trimmedLine string) bool {
+	if !isRootNotifyLine(trimmedLine) {
+		return false
+	}
+
+	parts := strings.SplitN(trimmedLine, "=", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	rhs := strings.TrimSpace(parts[1])
+	return codexHookArrayRE.MatchString(rhs)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetManagedTUINotifications(t *testing.T) {
+	t.Run("adds tui table when missing", func(t *testing.T) {
+		in := []byte(defaultNotifyLine + "\n\n[profiles.work]\nmodel = \"gpt-5\"\n")
+		got, changed, err := setManagedTUINotifications(in, false)
+		if err != nil || !changed {
+			t.Fatalf("setManagedTUINotifications() = changed %v, err %v", changed, err)
+		}
+		want := defaultNotifyLine + "\n\n[profiles.work]\nmodel = \"gpt-5\"\n\n[tui]\n" +
+			managedBlockBegin + "\n" + managedTUINotificationsLine + "\n" + managedBlockEnd + "\n"
+		if string(got) != want {
+			t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+		}
+
+		again, changed, err := setManagedTUINotifications(got, false)
+		if err != nil || changed || string(again) != string(got) {
+			t.Fatalf("second run should be a no-op: changed %v, err %v\n%s", changed, err, again)
+		}
+	})
+
+	t.Run("inserts block under existing tui table", func(t *testing.T) {
+		in := []byte("[tui]\ntheme = \"dark\"\n\n[history]\npersistence = \"none\"\n")
+		got, changed, err := setManagedTUINotifications(in, false)
+		if err != nil || !changed {
+			t.Fatalf("setManagedTUINotifications() = changed %v, err %v", changed, err)
+		}
+		if !strings.HasPrefix(string(got), "[tui]\n"+managedBlockBegin+"\n") {
+			t.Fatalf("block not placed directly under [tui]:\n%s", got)
+		}
+		if !strings.Contains(string(got), "theme = \"dark\"\n\n[history]") {
+			t.Fatalf("user lines were modified:\n%s", got)
+		}
+	})
+
+	t.Run("refuses user owned key without replace", func(t *testing.T) {
+		in := []byte("[tui]\nnotifications = true\n")
+		if _, _, err := setManagedTUINotifications(in, false); err == nil {
+			t.Fatalf("expected error for existing notifications key")
+		}
+
+		got, changed, err := setManagedTUINotifications(in, true)
+		if err != nil || !changed {
+			t.Fatalf("replace: changed %v, err %v", changed, err)
+		}
+		if strings.Contains(string(got), "notifications = true") {
+			t.Fatalf("user key should be replaced:\n%s", got)
+		}
+	})
+}
+
+func TestRemoveManagedBlocks(t *testing.T) {
+	in := []byte(defaultNotifyLine + "\n\n[tui]\ntheme = \"dark\"\n" + managedBlockBegin + "\n" + managedTUINotificationsLine + "\n" + managedBlockEnd + "\n")
+	got, removed := removeManagedBlocks(in)
+	if !removed {
+		t.Fatalf("removeManagedBlocks() removed = false")
+	}
+	want := defaultNotifyLine + "\n\n[tui]\ntheme = \"dark\"\n"
+	if string(got) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSetManagedNotifyBlock(t *testing.T) {
+	wantBlock := managedBlockBegin + "\n" + defaultNotifyLine + "\n" + managedBlockEnd + "\n"
+
+	t.Run("empty config", func(t *testing.T) {
+		got, changed, err := setManagedNotifyBlock(nil, false)
+		if err != nil || !changed || string(got) != wantBlock {
+			t.Fatalf("got %q, changed %v, err %v", got, changed, err)
+		}
+	})
+
+	t.Run("inserts before first section", func(t *testing.T) {
+		in := []byte("model = \"gpt-5\"\n[tui]\ntheme = \"dark\"\n")
+		got, _, err := setManagedNotifyBlock(in, false)
+		if err != nil {
+			t.Fatalf("setManagedNotifyBlock() error = %v", err)
+		}
+		want := "model = \"gpt-5\"\n\n" + wantBlock + "\n[tui]\ntheme = \"dark\"\n"
+		if string(got) != want {
+			t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+		}
+
+		again, changed, err := setManagedNotifyBlock(got, false)
+		if err != nil || changed || string(again) != string(got) {
+			t.Fatalf("second run should be a no-op: changed %v, err %v", changed, err)
+		}
+	})
+
+	t.Run("migrates legacy line", func(t *testing.T) {
+		in := []byte("# my settings\n" + `notify = ["/opt/homebrew/bin/codex-notify", "hook"]` + "\nmodel = \"o3\"\n")
+		got, changed, err := setManagedNotifyBlock(in, false)
+		if err != nil || !changed {
+			t.Fatalf("changed %v, err %v", changed, err)
+		}
+		want := "# my settings\n" + wantBlock + "model = \"o3\"\n"
+		if string(got) != want {
+			t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("foreign notify requires replace", func(t *testing.T) {
+		in := []byte(`notify = ["my-notifier"]` + "\n")
+		if _, _, err := setManagedNotifyBlock(in, false); err == nil {
+			t.Fatalf("expected error without replace")
+		}
+		got, _, err := setManagedNotifyBlock(in, true)
+		if err != nil || string(got) != wantBlock {
+			t.Fatalf("got %q, err %v", got, err)
+		}
+	})
+
+	t.Run("ignores notify inside sections", func(t *testing.T) {
+		in := []byte("[profiles.x]\nnotify = [\"other\"]\n")
+		got, _, err := setManagedNotifyBlock(in, false)
+		if err != nil {
+			t.Fatalf("setManagedNotifyBlock() error = %v", err)
+		}
+		if !strings.HasPrefix(string(got), wantBlock+"\n[profiles.x]\nnotify = [\"other\"]") {
+			t.Fatalf("got:\n%s", got)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var codexVersionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?([-.+][0-9A-Za-z.-]+)?`)
+
+// readCodexVersion runs `codex --version`. Tests hand back version strings
+// without a codex install.
+var readCodexVersion = func() (string, error) {
+	path, ok := lookupCmd("codex")
+	if !ok {
+		return "", errors.New("codex not found on PATH")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	out, err := outputLogged(exec.CommandContext(ctx, path, "--version"))
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// parseCodexVersion extracts the version number from `codex --version`
+// output such as "codex-cli 0.46.0".
+func parseCodexVersion(output string) (string, bool) {
+	for _, field := range strings.Fields(output) {
+		if v := codexVersionPattern.FindString(field); v != "" && strings.HasPrefix(strings.TrimPrefix(field, "v"), v) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// codexHookStyle is how an installed Codex hands events to codex-notify.
+type codexHookStyle string
+
+const (
+	// codexHookNotify is the top-level `notify` command in config.toml,
+	// which every Rust Codex CLI reads and init configures.
+	codexHookNotify codexHookStyle = "notify"
+	// codexHookNone is the legacy TypeScript CLI (versions 0.1.YYMMDDhhmm),
+	// which reads config.json and runs no notify command.
+	codexHookNone codexHookStyle = "none"
+)
+
+// legacyCodexPatch is the smallest patch number of the TypeScript CLI's
+// date-stamped versions, such as 0.1.2505172129.
+const legacyCodexPatch = 2504000000
+
+// hookStyleForVersion picks the hook style a Codex version supports.
+func hookStyleForVersion(version string) codexHookStyle {
+	core, _, _ := strings.Cut(version, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) == 3 && parts[0] == "0" && parts[1] == "1" {
+		if patch, err := strconv.ParseInt(parts[2], 10, 64); err == nil && patch >= legacyCodexPatch {
+			return codexHookNone
+		}
+	}
+	return codexHookNotify
+}
+
+// detectCodexHookStyle asks the installed Codex for its version and the
+// hook style that goes with it. Without a readable version it assumes the
+// notify hook, which every current release reads.
+func detectCodexHookStyle() (version string, style codexHookStyle, err error) {
+	out, err := readCodexVersion()
+	if err != nil {
+		return "", codexHookNotify, err
+	}
+	version, ok := parseCodexVersion(out)
+	if !ok {
+		return "", codexHookNotify, errors.New("could not read a version from `codex --version`")
+	}
+	return version, hookStyleForVersion(version), nil
+}
+
+// legacyCodexAdvice explains what to do about a Codex without a hook.
+func legacyCodexAdvice(version string) string {
+	return fmt.Sprintf("codex %s is the legacy TypeScript CLI, which runs no notify hook; upgrade with `npm install -g @openai/codex@latest` or `brew install codex`", version)
+}
+
+// addCodexDoctorCheck reports the installed Codex version and the hook
+// style init configures for it.
+func addCodexDoctorCheck(report *doctorReport) {
+	version, style, err := detectCodexHookStyle()
+	switch {
+	case err != nil:
+		report.add(doctorCodex, checkWarn, "codex", err.Error(), false)
+	case style == codexHookNone:
+		report.add(doctorCodex, checkFail, "codex", legacyCodexAdvice(version), true)
+	default:
+		report.add(doctorCodex, checkOK, "codex", version+" ("+string(style)+" hook)", false)
+	}
+}
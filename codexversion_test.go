@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCodexVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+		ok     bool
+	}{
+		{output: "codex-cli 0.46.0\n", want: "0.46.0", ok: true},
+		{output: "codex-cli v0.47.0-alpha.2", want: "0.47.0-alpha.2", ok: true},
+		{output: "codex 1.2", want: "1.2", ok: true},
+		{output: "codex-cli\n", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := parseCodexVersion(tt.output)
+		if got != tt.want || ok != tt.ok {
+			t.Fatalf("parseCodexVersion(%q) = %q, %v, want %q, %v", tt.output, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHookStyleForVersion(t *testing.T) {
+	for version, want := range map[string]codexHookStyle{
+		"0.46.0":         codexHookNotify,
+		"0.47.0-alpha.2": codexHookNotify,
+		"0.1.0":          codexHookNotify,
+		"1.2":            codexHookNotify,
+		"0.1.2505172129": codexHookNone,
+	} {
+		if got := hookStyleForVersion(version); got != want {
+			t.Errorf("hookStyleForVersion(%q) = %q, want %q", version, got, want)
+		}
+	}
+}
+
+func TestCodexDoctorCheckFlagsLegacyCLI(t *testing.T) {
+	stub(t, &readCodexVersion, func() (string, error) { return "0.1.2505172129\n", nil })
+
+	var report doctorReport
+	addCodexDoctorCheck(&report)
+	if report.Problems != 1 || !strings.Contains(report.Checks[0].Detail, "legacy TypeScript CLI") {
+		t.Fatalf("report = %+v, want the legacy CLI flagged", report)
+	}
+}
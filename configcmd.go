@@ -0,0 +1,578 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const configExportFormat = "codex-notify-config"
+
+// configExport is the portable file written by `config export`. It carries
+// codex-notify's own config files and the CODEX_NOTIFY_* environment, but
+// never anything that looks like a credential.
+type configExport struct {
+	Format      string            `json:"format"`
+	Version     int               `json:"version"`
+	ConfigTOML  string            `json:"config_toml,omitempty"`
+	Settings    *popupSettings    `json:"settings,omitempty"`
+	Environment map[string]string `json:"environment,omitempty"`
+}
+
+// exportedEnvExcluded are CODEX_NOTIFY_* variables that describe this
+// machine or this process rather than the user's setup.
+var exportedEnvExcluded = map[string]bool{
+	privateRelayEnv:            true,
+	"CODEX_NOTIFY_CONFIG_FILE": true,
+}
+
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		return usageError(errors.New("config requires one of: get, set, unset, list, export, import"))
+	}
+	switch args[0] {
+	case "get":
+		return runConfigGet(args[1:])
+	case "set":
+		return runConfigSet(args[1:])
+	case "unset":
+		return runConfigUnset(args[1:])
+	case "list":
+		return runConfigList(args[1:])
+	case "export":
+		return runConfigExport(args[1:])
+	case "import":
+		return runConfigImport(args[1:])
+	default:
+		return usageError(fmt.Errorf("unknown config command: %s", args[0]))
+	}
+}
+
+func settingSpecFor(key string) (settingSpec, error) {
+	spec, ok := userConfigSettings[key]
+	if !ok {
+		return settingSpec{}, usageError(fmt.Errorf("unknown setting %q (see config list)", key))
+	}
+	return spec, nil
+}
+
+// configSetting is one setting in `config get` and `config list` JSON.
+// Source is "env", "config", or "default"; a default has no value.
+type configSetting struct {
+	Key    string  `json:"key"`
+	Env    string  `json:"env"`
+	Value  *string `json:"value"`
+	Source string  `json:"source"`
+}
+
+type configSettingsResult struct {
+	commandResult
+	Settings []configSetting `json:"settings"`
+}
+
+func effectiveSetting(cfg userConfig, key string) configSetting {
+	env := userConfigSettings[key].Env
+	s := configSetting{Key: key, Env: env, Source: "default"}
+	if value, ok := lookupEnv(env); ok {
+		s.Value, s.Source = &value, "env"
+	} else if value, ok := cfg.Settings[env]; ok {
+		s.Value, s.Source = &value, "config"
+	}
+	return s
+}
+
+// runConfigGet prints the effective value of a setting: the environment
+// variable when set, otherwise the config file.
+func runConfigGet(args []string) error {
+	fs := flag.NewFlagSet("config get", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	outFlags := addOutputFlags(fs)
+	// The setting name may come before or after the flags.
+	var positional []string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		positional, args = args[:1], args[1:]
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	positional = append(positional, fs.Args()...)
+	if len(positional) != 1 {
+		return usageError(errors.New("config get requires one setting name"))
+	}
+	out := outFlags.output()
+	if _, err := settingSpecFor(positional[0]); err != nil {
+		return err
+	}
+	cfg := userConfig{}
+	if _, inEnv := lookupEnv(userConfigSettings[positional[0]].Env); !inEnv {
+		var err error
+		if cfg, err = loadUserConfig(); err != nil {
+			return configError(err)
+		}
+	}
+	setting := effectiveSetting(cfg, positional[0])
+	if setting.Value == nil {
+		return fmt.Errorf("%s is not set", positional[0])
+	}
+	out.Println(*setting.Value)
+	return out.Result(configSettingsResult{
+		commandResult: commandResult{Command: "config get", Status: "ok", Source: setting.Source},
+		Settings:      []configSetting{setting},
+	})
+}
+
+func runConfigSet(args []string) error {
+	if len(args) != 2 {
+		return usageError(errors.New("config set requires a setting name and a value"))
+	}
+	spec, err := settingSpecFor(args[0])
+	if err != nil {
+		return err
+	}
+	value, err := spec.parseArg(args[1])
+	if err == nil {
+		_, err = spec.envValue(value)
+	}
+	if err != nil {
+		return usageError(fmt.Errorf("%s %w", args[0], err))
+	}
+	return editUserConfigSetting(args[0], tomlLiteral(value))
+}
+
+func runConfigUnset(args []string) error {
+	if len(args) != 1 {
+		return usageError(errors.New("config unset requires one setting name"))
+	}
+	if _, err := settingSpecFor(args[0]); err != nil {
+		return err
+	}
+	return editUserConfigSetting(args[0], "")
+}
+
+// editUserConfigSetting rewrites one top-level key in config.toml, leaving
+// comments, tables, and every other line as they are. An empty literal
+// removes the key.
+func editUserConfigSetting(key, literal string) error {
+	return editUserConfigKey(key, literal, func(content []byte) []byte {
+		return setTopLevelTOMLKey(content, key, literal)
+	})
+}
+
+// editUserConfigKey applies edit to config.toml and writes the result when
+// it changed and still parses. key and literal are only for the messages.
+func editUserConfigKey(key, literal string, edit func([]byte) []byte) error {
+	cfgPath, err := userConfigPath()
+	if err != nil {
+		return configError(err)
+	}
+	existing, err := readFileMaybe(cfgPath)
+	if err != nil {
+		return configError(err)
+	}
+	if _, err := parseUserConfig(existing); err != nil {
+		return configError(fmt.Errorf("parse %s: %w", cfgPath, err))
+	}
+
+	content := edit(existing)
+	if string(content) == string(existing) {
+		fmt.Fprintf(os.Stdout, "%s unchanged in %s\n", key, cfgPath)
+		return nil
+	}
+	if _, err := parseUserConfig(content); err != nil {
+		return configError(fmt.Errorf("updated config would not parse: %w", err))
+	}
+	if err := os.MkdirAll(filepath.Dir(cfgPath), 0o755); err != nil {
+		return configError(fmt.Errorf("create config dir: %w", err))
+	}
+	if err := writeConfigChecked(cfgPath, existing, content); err != nil {
+		return configError(fmt.Errorf("write %s: %w", cfgPath, err))
+	}
+	resetFeatureCache()
+
+	if literal == "" {
+		fmt.Fprintf(os.Stdout, "removed %s from %s\n", key, cfgPath)
+	} else {
+		fmt.Fprintf(os.Stdout, "set %s = %s in %s\n", key, literal, cfgPath)
+	}
+	if env := userConfigSettings[key].Env; env != "" && getenv(env) != "" {
+		fmt.Fprintf(os.Stdout, "note: %s is set in the environment and overrides the file\n", env)
+	}
+	return nil
+}
+
+// setTopLevelTOMLKey replaces, adds, or (with an empty literal) removes a
+// top-level key. New keys go before the first table header.
+func setTopLevelTOMLKey(content []byte, key, literal string) []byte {
+	lines := splitLines(content)
+	insertAt := len(lines)
+	for i, raw := range lines {
+		line := strings.TrimSpace(stripTOMLComment(strings.TrimSpace(raw)))
+		if strings.HasPrefix(line, "[") {
+			insertAt = i
+			break
+		}
+		eq := indexOutsideQuotes(line, '=')
+		if eq < 0 {
+			continue
+		}
+		if name, err := parseTOMLKey(strings.TrimSpace(line[:eq])); err != nil || name != key {
+			continue
+		}
+		if literal == "" {
+			lines = append(lines[:i], lines[i+1:]...)
+		} else {
+			lines[i] = key + " = " + literal
+		}
+		return joinTOMLLines(lines)
+	}
+	if literal == "" {
+		return content
+	}
+
+	added := []string{key + " = " + literal}
+	if insertAt < len(lines) {
+		added = append(added, "")
+	}
+	lines = append(lines[:insertAt], append(added, lines[insertAt:]...)...)
+	return joinTOMLLines(lines)
+}
+
+// setTableTOMLKey is setTopLevelTOMLKey for a key in [table], also found
+// when written as a dotted top-level key. A new key goes at the end of the
+// table, which is appended to the file when missing.
+func setTableTOMLKey(content []byte, table, key, literal string) []byte {
+	lines := splitLines(content)
+	current, hasTable, tableEnd := "", false, -1
+	for i, raw := range lines {
+		line := strings.TrimSpace(stripTOMLComment(strings.TrimSpace(raw)))
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current, _ = parseTOMLKey(strings.TrimSpace(line[1 : len(line)-1]))
+			if current == table {
+				hasTable, tableEnd = true, i
+			}
+			continue
+		}
+		if current == table && line != "" {
+			tableEnd = i
+		}
+		eq := indexOutsideQuotes(line, '=')
+		if eq < 0 {
+			continue
+		}
+		name, err := parseTOMLKey(strings.TrimSpace(line[:eq]))
+		if err != nil {
+			continue
+		}
+		written := key
+		if current == "" {
+			written = table + "." + key
+		}
+		if current != "" && current != table || name != written {
+			continue
+		}
+		if literal == "" {
+			lines = append(lines[:i], lines[i+1:]...)
+		} else {
+			lines[i] = written + " = " + literal
+		}
+		return joinTOMLLines(lines)
+	}
+	if literal == "" {
+		return content
+	}
+	if !hasTable {
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+			lines = append(lines, "")
+		}
+		return joinTOMLLines(append(lines, "["+table+"]", key+" = "+literal))
+	}
+	lines = append(lines[:tableEnd+1], append([]string{key + " = " + literal}, lines[tableEnd+1:]...)...)
+	return joinTOMLLines(lines)
+}
+
+func joinTOMLLines(lines []string) []byte {
+	return []byte(strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n")
+}
+
+// runConfigList prints every supported setting with its value and where the
+// value comes from.
+func runConfigList(args []string) error {
+	fs := flag.NewFlagSet("config list", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usageError(errors.New("config list takes no arguments"))
+	}
+	out := outFlags.output()
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return configError(err)
+	}
+
+	keys := make([]string, 0, len(userConfigSettings))
+	for key := range userConfigSettings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := configSettingsResult{commandResult: commandResult{Command: "config list", Status: "ok"}}
+	for _, key := range keys {
+		s := effectiveSetting(cfg, key)
+		result.Settings = append(result.Settings, s)
+		switch s.Source {
+		case "env":
+			out.Printf("%s = %s (env %s)\n", key, *s.Value, s.Env)
+		case "config":
+			out.Printf("%s = %s (config)\n", key, *s.Value)
+		default:
+			out.Printf("%s (default)\n", key)
+		}
+	}
+	result.Count = len(result.Settings)
+	return out.Result(result)
+}
+
+func runConfigExport(args []string) error {
+	fs := flag.NewFlagSet("config export", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	output := fs.String("output", "", "write the export to a file instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	export, err := buildConfigExport(os.Environ())
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode export: %w", err)
+	}
+	content = append(content, '\n')
+
+	if *output == "" {
+		_, err := os.Stdout.Write(content)
+		return err
+	}
+	if err := writeFileAtomic(*output, content, privateFileMode); err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+	fmt.Fprintf(os.Stdout, "exported codex-notify config to %s\n", *output)
+	return nil
+}
+
+func buildConfigExport(environ []string) (configExport, error) {
+	export := configExport{Format: configExportFormat, Version: 1}
+
+	cfgPath, err := userConfigPath()
+	if err != nil {
+		return configExport{}, configError(err)
+	}
+	cfg, err := readFileMaybe(cfgPath)
+	if err != nil {
+		return configExport{}, configError(err)
+	}
+	if _, err := parseUserConfig(cfg); err != nil {
+		return configExport{}, configError(fmt.Errorf("parse %s: %w", cfgPath, err))
+	}
+	export.ConfigTOML = redactConfigSecrets(string(cfg))
+
+	settings, err := readPopupSettings()
+	if err != nil {
+		return configExport{}, configError(err)
+	}
+	if settings != (popupSettings{}) {
+		export.Settings = &settings
+	}
+
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, "CODEX_NOTIFY_") || exportedEnvExcluded[key] || looksSecret(key) {
+			continue
+		}
+		if export.Environment == nil {
+			export.Environment = map[string]string{}
+		}
+		export.Environment[key] = value
+	}
+	return export, nil
+}
+
+// configSecretKeys are the fully qualified config.toml keys that hold
+// secrets, with the variable that can supply each one instead.
+var configSecretKeys = map[string]string{
+	"ntfy.token":            ntfyTokenEnv,
+	"slack.webhook_url":     slackWebhookEnv,
+	"pagerduty.routing_key": pagerDutyKeyEnv,
+	"oncall.url":            onCallURLEnv,
+	"pushover.token":        pushoverTokenEnv,
+	"pushover.user":         pushoverUserEnv,
+	"bark.device_key":       barkKeyEnv,
+	"email.password":        smtpPasswordEnv,
+	"peer.token":            peerTokenEnv,
+}
+
+// isConfigSecret reports whether a fully qualified config.toml key holds a
+// secret. Webhook URLs and headers count: both often embed API keys.
+func isConfigSecret(key string) bool {
+	if _, ok := configSecretKeys[key]; ok {
+		return true
+	}
+	parts := strings.Split(key, ".")
+	return parts[0] == "webhooks" && (len(parts) == 3 && (parts[2] == "url" || parts[2] == "secret") || len(parts) == 4 && parts[2] == "headers")
+}
+
+// redactConfigSecrets comments out the secrets config.toml can hold, which
+// would otherwise travel with the export.
+func redactConfigSecrets(content string) string {
+	lines := strings.Split(content, "\n")
+	table := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			if end := strings.Index(trimmed, "]"); end > 0 {
+				table = strings.TrimSpace(trimmed[1:end])
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		full := key
+		if table != "" {
+			full = table + "." + key
+		}
+		// A secret: reference names a Keychain item and is safe to share.
+		if isSecretRef(strings.Trim(strings.TrimSpace(value), `"'`)) {
+			continue
+		}
+		if normalized, err := parseTOMLKey(full); err == nil && isConfigSecret(normalized) {
+			hint := "set it again"
+			if env := configSecretKeys[normalized]; env != "" {
+				hint += " or use " + env
+			}
+			lines[i] = "# " + key + " = (not exported; " + hint + ")"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func looksSecret(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, marker := range []string{"TOKEN", "SECRET", "PASSWORD", "CREDENTIAL", "WEBHOOK"} {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func runConfigImport(args []string) error {
+	fs := flag.NewFlagSet("config import", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError(errors.New("config import requires one export file (or - for stdin)"))
+	}
+
+	var (
+		content []byte
+		err     error
+	)
+	if path := fs.Arg(0); path == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("read export: %w", err)
+	}
+
+	var export configExport
+	if err := json.Unmarshal(content, &export); err != nil {
+		return configError(fmt.Errorf("parse export: %w", err))
+	}
+	if export.Format != configExportFormat || export.Version != 1 {
+		return configError(fmt.Errorf("unsupported export format %q version %d", export.Format, export.Version))
+	}
+	if _, err := parseUserConfig([]byte(export.ConfigTOML)); err != nil {
+		return configError(fmt.Errorf("exported config.toml: %w", err))
+	}
+
+	cfgPath, err := userConfigPath()
+	if err != nil {
+		return configError(err)
+	}
+	if err := replaceWithBackup(cfgPath, []byte(export.ConfigTOML)); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "imported %s\n", cfgPath)
+
+	if export.Settings != nil {
+		settingsPath, err := popupSettingsPath()
+		if err != nil {
+			return configError(err)
+		}
+		settings, err := json.MarshalIndent(export.Settings, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode settings: %w", err)
+		}
+		if err := replaceWithBackup(settingsPath, append(settings, '\n')); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "imported %s\n", settingsPath)
+	}
+
+	if len(export.Environment) > 0 {
+		keys := make([]string, 0, len(export.Environment))
+		for key := range export.Environment {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fmt.Fprintln(os.Stdout, "add these to your shell profile:")
+		for _, key := range keys {
+			fmt.Fprintf(os.Stdout, "export %s=%s\n", key, shellQuote(export.Environment[key]))
+		}
+	}
+	return nil
+}
+
+// replaceWithBackup writes content to path, keeping a timestamped backup of
+// a different existing file.
+func replaceWithBackup(path string, content []byte) error {
+	existing, err := readFileMaybe(path)
+	if err != nil {
+		return err
+	}
+	if string(existing) == string(content) {
+		return nil
+	}
+	if len(existing) > 0 {
+		if _, err := createBackup(path, existing); err != nil {
+			return err
+		}
+	}
+	if len(content) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove %s: %w", path, err)
+		}
+		return nil
+	}
+	if err := writeFileAtomic(path, content, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigExportImportRoundTrip(t *testing.T) {
+	src := useTempUserConfigDir(t)
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", "")
+	writePopupSettingsForTest(t, src, `{"popup_timeout_seconds": 30}`)
+	cfgPath := filepath.Join(src, appName, userConfigFilename)
+	if err := os.WriteFile(cfgPath, []byte("[presets]\ntests = \"run the tests\"\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	export, err := buildConfigExport([]string{
+		"CODEX_NOTIFY_APPROVE_KEYS=y,enter",
+		"CODEX_NOTIFY_SLACK_WEBHOOK=https://hooks.example/secret",
+		"CODEX_NOTIFY_GITHUB_TOKEN=abc",
+		privateRelayEnv + "=1",
+		"HOME=/Users/dev",
+	})
+	if err != nil {
+		t.Fatalf("buildConfigExport() error = %v", err)
+	}
+	if export.Settings == nil || export.Settings.PopupTimeoutSeconds != 30 {
+		t.Fatalf("Settings = %+v, want popup timeout 30", export.Settings)
+	}
+	if len(export.Environment) != 1 || export.Environment["CODEX_NOTIFY_APPROVE_KEYS"] != "y,enter" {
+		t.Fatalf("Environment = %v, want only CODEX_NOTIFY_APPROVE_KEYS", export.Environment)
+	}
+
+	content, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	exportPath := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(exportPath, content, 0o600); err != nil {
+		t.Fatalf("write export: %v", err)
+	}
+
+	dst := useTempUserConfigDir(t)
+	if err := runConfigImport([]string{exportPath}); err != nil {
+		t.Fatalf("runConfigImport() error = %v", err)
+	}
+	cfg, err := loadUserConfig()
+	if err != nil {
+		t.Fatalf("loadUserConfig() error = %v", err)
+	}
+	if p, ok := cfg.preset("tests"); !ok || p.Text != "run the tests" {
+		t.Fatalf("imported presets = %+v", cfg.Presets)
+	}
+	settings, err := os.ReadFile(filepath.Join(dst, appName, popupSettingsFilename))
+	if err != nil {
+		t.Fatalf("read imported settings: %v", err)
+	}
+	var got popupSettings
+	if err := json.Unmarshal(settings, &got); err != nil || got.PopupTimeoutSeconds != 30 {
+		t.Fatalf("imported settings = %s (%v)", settings, err)
+	}
+}
+
+func TestConfigImportRejectsForeignFiles(t *testing.T) {
+	useTempUserConfigDir(t)
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", "")
+
+	path := filepath.Join(t.TempDir(), "other.json")
+	if err := os.WriteFile(path, []byte(`{"format": "something-else", "version": 1}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	err := runConfigImport([]string{path})
+	if exitCodeFor(err) != exitConfig {
+		t.Fatalf("runConfigImport() error = %v, want a config error", err)
+	}
+}
+
+func TestSetTopLevelTOMLKey(t *testing.T) {
+	content := []byte("# settings\nnotification_ui = \"popup\" # default\n\n[presets]\ntests = \"run the tests\"\n")
+
+	replaced := string(setTopLevelTOMLKey(content, "notification_ui", `"system"`))
+	if want := "# settings\nnotification_ui = \"system\"\n\n[presets]\ntests = \"run the tests\"\n"; replaced != want {
+		t.Fatalf("replace = %q, want %q", replaced, want)
+	}
+
+	added := string(setTopLevelTOMLKey(content, "approve_keys", `["1", "enter"]`))
+	if want := "# settings\nnotification_ui = \"popup\" # default\n\napprove_keys = [\"1\", \"enter\"]\n\n[presets]\ntests = \"run the tests\"\n"; added != want {
+		t.Fatalf("add = %q, want %q", added, want)
+	}
+
+	removed := string(setTopLevelTOMLKey(content, "notification_ui", ""))
+	if want := "# settings\n\n[presets]\ntests = \"run the tests\"\n"; removed != want {
+		t.Fatalf("remove = %q, want %q", removed, want)
+	}
+	if got := setTopLevelTOMLKey(content, "sandbox", ""); string(got) != string(content) {
+		t.Fatalf("removing a missing key changed the file: %q", got)
+	}
+}
+
+func TestConfigSetValidatesAndWrites(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "codex-notify", "config.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", cfgPath)
+	t.Setenv("CODEX_NOTIFY_APPROVE_KEYS", "")
+
+	for _, args := range [][]string{
+		{"notification_ui", "banner"},
+		{"popup_timeout_seconds", "1"},
+		{"sandbox", "maybe"},
+		{"unknown_key", "1"},
+	} {
+		if err := runConfigSet(args); exitCodeFor(err) != exitUsage {
+			t.Fatalf("runConfigSet(%q) error = %v, want usage error", args, err)
+		}
+	}
+	if _, err := os.Stat(cfgPath); !os.IsNotExist(err) {
+		t.Fatalf("invalid set created %s", cfgPath)
+	}
+
+	if err := runConfigSet([]string{"approve_keys", "1, enter"}); err != nil {
+		t.Fatalf("runConfigSet(approve_keys) error = %v", err)
+	}
+	if err := runConfigSet([]string{"sandbox", "yes"}); err != nil {
+		t.Fatalf("runConfigSet(sandbox) error = %v", err)
+	}
+	cfg, err := loadUserConfig()
+	if err != nil {
+		t.Fatalf("loadUserConfig() error = %v", err)
+	}
+	if got := cfg.Settings["CODEX_NOTIFY_APPROVE_KEYS"]; got != "1,enter" {
+		t.Fatalf("approve_keys = %q, want 1,enter", got)
+	}
+	if got := cfg.Settings["CODEX_NOTIFY_SANDBOX"]; got != "true" {
+		t.Fatalf("sandbox = %q, want true", got)
+	}
+
+	if err := runConfigUnset([]string{"sandbox"}); err != nil {
+		t.Fatalf("runConfigUnset() error = %v", err)
+	}
+	if cfg, _ := loadUserConfig(); cfg.Settings["CODEX_NOTIFY_SANDBOX"] != "" {
+		t.Fatalf("sandbox still set after unset: %+v", cfg.Settings)
+	}
+}
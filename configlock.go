@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const configLockTimeout = 5 * time.Second
+
+var errConfigChanged = errors.New("config changed while codex-notify was editing it (was Codex rewriting it?); nothing was written, rerun the command")
+
+// withConfigLock runs fn while holding an exclusive lock on a lock file
+// next to cfgPath, so concurrent init/uninstall runs cannot interleave their
+// read-modify-write cycles. The lock file is separate because writes
+// replace cfgPath itself, and a lock on the replaced file would guard
+// nothing. Codex itself does not take the lock, which is why writes also go
+// through writeConfigChecked.
+func withConfigLock(cfgPath string, fn func() (commandResult, error)) (commandResult, error) {
+	return withConfigLockTimeout(cfgPath, configLockTimeout, fn)
+}
+
+// withConfigLockTimeout is withConfigLock giving up after timeout.
+func withConfigLockTimeout(cfgPath string, timeout time.Duration, fn func() (commandResult, error)) (commandResult, error) {
+	if err := os.MkdirAll(filepath.Dir(cfgPath), 0o755); err != nil {
+		return commandResult{}, configError(fmt.Errorf("lock config: %w", err))
+	}
+	lock, err := os.OpenFile(configLockPath(cfgPath), os.O_CREATE|os.O_RDWR, privateFileMode)
+	if err != nil {
+		return commandResult{}, configError(fmt.Errorf("lock config: %w", err))
+	}
+	defer lock.Close()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return commandResult{}, configError(fmt.Errorf("lock config: %w", err))
+		}
+		if time.Now().After(deadline) {
+			return commandResult{}, configError(fmt.Errorf("another codex-notify process is editing %s; try again", cfgPath))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	return fn()
+}
+
+// configLockPath is the lock file for cfgPath: a hidden sibling, such as
+// .config.toml.codex-notify.lock. It is left in place, since removing it
+// while another process waits on it would let both in.
+func configLockPath(cfgPath string) string {
+	return filepath.Join(filepath.Dir(cfgPath), "."+filepath.Base(cfgPath)+"."+appName+".lock")
+}
+
+// writeConfigChecked replaces cfgPath with content only if the file still
+// holds original, comparing hashes right before the write. The existing file
+// mode is preserved.
+func writeConfigChecked(cfgPath string, original, content []byte) error {
+	current, err := readFileMaybe(cfgPath)
+	if err != nil {
+		return err
+	}
+	if sha256.Sum256(current) != sha256.Sum256(original) {
+		return errConfigChanged
+	}
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(cfgPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+	return writeFileAtomic(cfgPath, content, mode)
+}
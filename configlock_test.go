@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteConfigCheckedDetectsConcurrentChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	original := []byte("model = \"o3\"\n")
+	if err := os.WriteFile(path, original, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	// Someone else rewrites the file after it was read.
+	if err := os.WriteFile(path, []byte("model = \"gpt-5\"\n"), 0o600); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	err := writeConfigChecked(path, original, []byte("updated\n"))
+	if !errors.Is(err, errConfigChanged) {
+		t.Fatalf("writeConfigChecked() error = %v, want errConfigChanged", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "model = \"gpt-5\"\n" {
+		t.Fatalf("config was overwritten: %q", got)
+	}
+}
+
+func TestWriteConfigCheckedPreservesMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	original := []byte("model = \"o3\"\n")
+	if err := os.WriteFile(path, original, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := writeConfigChecked(path, original, []byte("updated\n")); err != nil {
+		t.Fatalf("writeConfigChecked() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if got := info.Mode().Perm(); got != 0o600 {
+		t.Fatalf("mode = %o, want 600", got)
+	}
+
+	created := filepath.Join(t.TempDir(), "new.toml")
+	if err := writeConfigChecked(created, nil, []byte("x\n")); err != nil {
+		t.Fatalf("writeConfigChecked(new file) error = %v", err)
+	}
+}
+
+func TestWithConfigLockUsesSiblingFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "codex")
+	path := filepath.Join(dir, "config.toml")
+
+	// The directory is created first, so a fresh install is locked too.
+	inner := false
+	if _, err := withConfigLock(path, func() (commandResult, error) {
+		if _, err := os.Stat(configLockPath(path)); err != nil {
+			t.Fatalf("lock file missing while held: %v", err)
+		}
+		// A second run waits for the first and gives up with a message.
+		_, err := withConfigLockTimeout(path, 100*time.Millisecond, func() (commandResult, error) {
+			inner = true
+			return commandResult{}, nil
+		})
+		if exitCodeFor(err) != exitConfig || !strings.Contains(err.Error(), "another codex-notify process") {
+			t.Fatalf("nested lock error = %v, want a config error", err)
+		}
+		return commandResult{}, writeConfigChecked(path, nil, []byte("model = \"o3\"\n"))
+	}); err != nil {
+		t.Fatalf("withConfigLock() error = %v", err)
+	}
+	if inner {
+		t.Fatalf("second holder ran while the lock was held")
+	}
+	if got, _ := os.ReadFile(path); string(got) != "model = \"o3\"\n" {
+		t.Fatalf("config = %q", got)
+	}
+}
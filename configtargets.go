@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolveConfigTargets returns the Codex config files a command operates on:
+// the --config flag when given, else codex_configs from the codex-notify
+// config, else the default Codex config.
+func resolveConfigTargets(configFlag string) ([]string, error) {
+	if configFlag != "" {
+		return []string{configFlag}, nil
+	}
+
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.CodexConfigs) == 0 {
+		path, err := resolveConfigPath("")
+		if err != nil {
+			return nil, err
+		}
+		return []string{path}, nil
+	}
+
+	paths := make([]string, 0, len(cfg.CodexConfigs))
+	seen := map[string]bool{}
+	for _, entry := range cfg.CodexConfigs {
+		path, err := expandCodexConfigEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// expandCodexConfigEntry expands ~ and environment variables in a
+// codex_configs entry. An entry that does not end in .toml is a CODEX_HOME
+// directory.
+func expandCodexConfigEntry(entry string) (string, error) {
+	path := os.ExpandEnv(strings.TrimSpace(entry))
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("resolve home: %w", err)
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	if path == "" {
+		return "", fmt.Errorf("codex_configs entry %q expands to an empty path", entry)
+	}
+	if !strings.HasSuffix(path, ".toml") {
+		path = filepath.Join(path, "config.toml")
+	}
+	return filepath.Clean(path), nil
+}
+
+// runForConfigTargets runs fn for every target. A single target behaves
+// exactly like the command always has; with several, the JSON result lists
+// each target and a failure on one does not stop the others.
+func runForConfigTargets(command string, paths []string, out commandOutput, fn func(path string) (commandResult, error)) error {
+	if len(paths) == 1 {
+		result, err := fn(paths[0])
+		if err != nil {
+			return err
+		}
+		return out.Result(result)
+	}
+
+	summary := commandResult{Command: command, Status: "ok"}
+	var failures []error
+	for _, path := range paths {
+		result, err := fn(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", path, err)
+			result = commandResult{Command: command, Status: "failed", Config: path, Error: err.Error()}
+			failures = append(failures, err)
+		}
+		summary.Targets = append(summary.Targets, result)
+	}
+
+	if len(failures) > 0 {
+		summary.Status = "failed"
+	}
+	if err := out.Result(summary); err != nil {
+		return err
+	}
+
+	switch {
+	case len(failures) == 0:
+		return nil
+	case len(failures) < len(paths):
+		return partialError(fmt.Errorf("%d of %d config targets failed", len(failures), len(paths)))
+	default:
+		// Keep the classification (config, permission, ...) of the first failure.
+		return withExitCode(exitCodeFor(failures[0]), errors.New("all config targets failed"))
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveConfigTargetsFromUserConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("WORK_CODEX_HOME", filepath.Join(home, "work-codex"))
+
+	cfgPath := filepath.Join(t.TempDir(), "config.toml")
+	content := `codex_configs = ["~/.codex/config.toml", "$WORK_CODEX_HOME", '~/.codex/config.toml',]` + "\n"
+	if err := os.WriteFile(cfgPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", cfgPath)
+
+	got, err := resolveConfigTargets("")
+	if err != nil {
+		t.Fatalf("resolveConfigTargets() error = %v", err)
+	}
+	want := []string{
+		filepath.Join(home, ".codex", "config.toml"),
+		filepath.Join(home, "work-codex", "config.toml"),
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("resolveConfigTargets() = %v, want %v", got, want)
+	}
+
+	explicit, err := resolveConfigTargets("/tmp/only.toml")
+	if err != nil || len(explicit) != 1 || explicit[0] != "/tmp/only.toml" {
+		t.Fatalf("resolveConfigTargets(flag) = %v, %v", explicit, err)
+	}
+}
+
+func TestResolveConfigPathUsesCodexHome(t *testing.T) {
+	t.Setenv("CODEX_HOME", "/opt/codex-home")
+	got, err := resolveConfigPath("")
+	if err != nil || got != filepath.Join("/opt/codex-home", "config.toml") {
+		t.Fatalf("resolveConfigPath() = %q, %v", got, err)
+	}
+}
+
+func TestRunForConfigTargetsReportsPartialFailure(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good", "config.toml")
+	blocker := filepath.Join(dir, "blocker")
+	if err := os.WriteFile(blocker, []byte("not a dir"), 0o600); err != nil {
+		t.Fatalf("write blocker: %v", err)
+	}
+	bad := filepath.Join(blocker, "config.toml")
+
+	out := commandOutput{mode: outputQuiet, w: os.Stdout}
+	err := runForConfigTargets("init", []string{good, bad}, out, func(path string) (commandResult, error) {
+		return initCodexConfig(path, false, false, false, out)
+	})
+	if got := exitCodeFor(err); got != exitPartial {
+		t.Fatalf("exitCodeFor() = %d (%v), want %d", got, err, exitPartial)
+	}
+	content, err := os.ReadFile(good)
+	if err != nil {
+		t.Fatalf("read good config: %v", err)
+	}
+	if ok, _ := configHasCodexNotify(content); !ok {
+		t.Fatalf("good target was not initialized: %s", content)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// codexPIDEnv carries the pid of the Codex process that ran the hook
+	// to a relayed hook and to the daemon. hook sets it to its parent.
+	codexPIDEnv = "CODEX_NOTIFY_CODEX_PID"
+
+	codexExitPollInterval = 5 * time.Second
+)
+
+// processAlive reports whether pid is still running. Tests end a Codex
+// process through it without starting one.
+var processAlive = func(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// turnEnded reports whether event closes a turn, so Codex exiting after it
+// is a normal quit.
+func turnEnded(event string) bool {
+	return event == "agent-turn-complete" || event == "agent-error"
+}
+
+// noteCodexPID records the hook's parent, the Codex process, unless a
+// relaying hook already did.
+func noteCodexPID() {
+	if getenv(codexPIDEnv) == "" && os.Getppid() > 1 {
+		_ = os.Setenv(codexPIDEnv, strconv.Itoa(os.Getppid()))
+	}
+}
+
+// codexPID is the Codex process behind payload: a pid in the payload, or
+// the one the hook noted.
+func codexPID(payload map[string]any) int {
+	if pid := payloadCodexPID(payload); pid > 0 {
+		return pid
+	}
+	if n, err := strconv.Atoi(getenv(codexPIDEnv)); err == nil && n > 1 {
+		return n
+	}
+	return 0
+}
+
+func payloadCodexPID(payload map[string]any) int {
+	for _, key := range []string{"pid", "codex-pid", "codex_pid"} {
+		switch v := payload[key].(type) {
+		case float64:
+			if v > 1 {
+				return int(v)
+			}
+		case string:
+			if n, err := strconv.Atoi(v); err == nil && n > 1 {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// watchedCodex is the latest hook a Codex process ran, with the variables
+// it was forwarded with, so its exit is reported the way it would have been.
+type watchedCodex struct {
+	Thread string
+	Cwd    string
+	Event  string
+	Env    map[string]string
+}
+
+// codexExitWatcher lets the daemon notice a Codex process that dies in the
+// middle of a turn, which no hook reports. wrap sees the exit itself, so
+// its sessions are not watched.
+type codexExitWatcher struct {
+	mu    sync.Mutex
+	procs map[int]watchedCodex
+}
+
+var codexExits = &codexExitWatcher{procs: map[int]watchedCodex{}}
+
+// observe records a hook forwarded with env.
+func (w *codexExitWatcher) observe(payload map[string]any, env map[string]string) {
+	if env[wrapSessionEnv] != "" {
+		return
+	}
+	pid := payloadCodexPID(payload)
+	if n, err := strconv.Atoi(env[codexPIDEnv]); pid == 0 && err == nil && n > 1 {
+		pid = n
+	}
+	if pid == 0 {
+		return
+	}
+	w.mu.Lock()
+	w.procs[pid] = watchedCodex{Thread: payloadThreadID(payload), Cwd: payloadCwd(payload), Event: payloadEventName(payload), Env: env}
+	w.mu.Unlock()
+}
+
+// check forgets processes that are gone and notifies about those that left
+// a turn open. It returns how many it notified about.
+func (w *codexExitWatcher) check() int {
+	var gone, crashed []watchedCodex
+	w.mu.Lock()
+	for pid, proc := range w.procs {
+		if processAlive(pid) {
+			continue
+		}
+		delete(w.procs, pid)
+		gone = append(gone, proc)
+		if !turnEnded(proc.Event) {
+			crashed = append(crashed, proc)
+		}
+	}
+	w.mu.Unlock()
+	// A turn whose Codex is gone will not finish.
+	for _, proc := range gone {
+		forgetOpenTurn(proc.Thread)
+	}
+
+	for _, proc := range crashed {
+		const title = "Codex: Exited Unexpectedly"
+		message := "agent exited unexpectedly without finishing its turn"
+		var status string
+		asHook(proc.Env, func() {
+			status = notifyLifecycle(notificationGroup("exit", proc.Thread), proc.Cwd, title, message)
+		})
+		if path, err := eventsPath(); err == nil {
+			_ = appendEvent(path, &eventRecord{
+				Event:   "codex-exit",
+				Thread:  proc.Thread,
+				Cwd:     proc.Cwd,
+				Title:   title,
+				Message: message,
+				Status:  status,
+			}, time.Now())
+		}
+	}
+	return len(crashed)
+}
+
+// startCodexExitWatcher checks the watched Codex processes for the
+// daemon's lifetime.
+func startCodexExitWatcher() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(codexExitPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				inDaemonWork(func() { codexExits.check() })
+			}
+		}
+	}()
+	return cancel
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCodexExitWatcher(t *testing.T) {
+	useTempHome(t)
+	dir := t.TempDir()
+	t.Setenv(backendEnv, "capture:"+filepath.Join(dir, "daemon"))
+	alive := map[int]bool{101: true, 102: true}
+	stub(t, &processAlive, func(pid int) bool { return alive[pid] })
+
+	w := &codexExitWatcher{procs: map[int]watchedCodex{}}
+	// The exit is reported with the variables the hook was forwarded with,
+	// not the daemon's.
+	env := map[string]string{backendEnv: "capture:" + dir}
+	w.observe(map[string]any{"type": "approval-requested", "thread-id": "t1"}, map[string]string{backendEnv: "capture:" + dir, codexPIDEnv: "101"})
+	w.observe(map[string]any{"type": "agent-turn-complete", "thread-id": "t2", "pid": float64(102)}, env)
+	w.observe(map[string]any{"type": "approval-requested", "thread-id": "t3", "pid": float64(103)}, map[string]string{wrapSessionEnv: "wrap-1"})
+
+	if n := w.check(); n != 0 || len(w.procs) != 2 {
+		t.Fatalf("check with both alive = %d, watching %+v", n, w.procs)
+	}
+	alive = map[int]bool{}
+	if n := w.check(); n != 1 || len(w.procs) != 0 {
+		t.Fatalf("check after both exited = %d, watching %+v", n, w.procs)
+	}
+	captured := readCaptured(t, dir)
+	if len(captured) != 1 || captured[0].Title != "Codex: Exited Unexpectedly" || captured[0].Group != notificationGroup("exit", "t1") {
+		t.Fatalf("captured = %+v", captured)
+	}
+}
+
+func TestCodexPID(t *testing.T) {
+	t.Setenv(codexPIDEnv, "4242")
+	if got := codexPID(map[string]any{"codex-pid": "77"}); got != 77 {
+		t.Fatalf("codexPID(payload) = %d, want 77", got)
+	}
+	if got := codexPID(map[string]any{}); got != 4242 {
+		t.Fatalf("codexPID(env) = %d, want 4242", got)
+	}
+}
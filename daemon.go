@@ -0,0 +1,515 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const daemonSocketName = "daemon.sock"
+
+// daemonRequest is one forwarded hook invocation. Env carries the client's
+// CODEX_NOTIFY_* and tmux variables, so the daemon decides exactly as an
+// in-process hook would. Both sides check what they receive against the
+// daemon_request and daemon_response schema definitions.
+type daemonRequest struct {
+	Schema  int               `json:"schema,omitempty"`
+	Payload string            `json:"payload"`
+	Env     map[string]string `json:"env,omitempty"`
+	// Reload asks the daemon to re-read config.toml; Payload is then empty.
+	Reload bool `json:"reload,omitempty"`
+}
+
+type daemonResponse struct {
+	Schema   int            `json:"schema,omitempty"`
+	Result   *commandResult `json:"result,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	ExitCode int            `json:"exit_code,omitempty"`
+}
+
+func daemonSocketPath() (string, error) {
+	dir, err := runtimeStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, daemonSocketName), nil
+}
+
+// daemonListening reports whether a daemon answers on the default socket.
+func daemonListening() bool {
+	path, err := daemonSocketPath()
+	if err != nil {
+		return false
+	}
+	conn, err := net.DialTimeout("unix", path, 200*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// daemonForwardingEnabled is on by default: a running daemon is used, and
+// without one hook quietly runs in-process. CODEX_NOTIFY_DAEMON=0 opts out.
+// Capture mode stays in-process, where the caller's CODEX_NOTIFY_BACKEND
+// applies, and so does the terminal bell, which needs the caller's tty.
+func daemonForwardingEnabled() bool {
+	if _, capture := captureDir(); capture {
+		return false
+	}
+	if terminalBellMode() != terminalBellOff || failuresInjected() {
+		return false
+	}
+	if !featureEnabled("daemon") {
+		return false
+	}
+	switch strings.TrimSpace(strings.ToLower(getenv("CODEX_NOTIFY_DAEMON"))) {
+	case "0", "false", "no", "off":
+		return false
+	default:
+		return !benchDryRun()
+	}
+}
+
+func runDaemon(args []string) error {
+	if len(args) > 0 && args[0] == "reload" {
+		return runDaemonReload(args[1:])
+	}
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	socket := fs.String("socket", "", "unix socket path (default: daemon.sock in the runtime state dir)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	path := *socket
+	if path == "" {
+		var err error
+		if path, err = daemonSocketPath(); err != nil {
+			return err
+		}
+	}
+	ln, err := listenDaemonSocket(path)
+	if err != nil {
+		return err
+	}
+
+	if hostOS == "darwin" {
+		warmPopup = &warmHelper{}
+		defer warmPopup.stop()
+	}
+	listeners := &daemonListeners{}
+	listeners.restart()
+	defer listeners.close()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		_ = ln.Close()
+	}()
+	reloads := make(chan os.Signal, 1)
+	signal.Notify(reloads, syscall.SIGHUP)
+	go func() {
+		for range reloads {
+			if err := reloadDaemonConfig(listeners); err != nil {
+				logErrorf("reload: %v", err)
+			}
+		}
+	}()
+	stopConfigWatch := watchUserConfig(func() {
+		if err := reloadDaemonConfig(listeners); err != nil {
+			logErrorf("reload: %v", err)
+		}
+	})
+	defer stopConfigWatch()
+
+	stopDrainer := startSinkQueueDrainer()
+	defer stopDrainer()
+	stopHeldWatcher := startHeldEventWatcher()
+	defer stopHeldWatcher()
+	stopExitWatcher := startCodexExitWatcher()
+	defer stopExitWatcher()
+	go inDaemonWork(func() { offerStaleSessions(time.Now()) })
+
+	logInfof("daemon listening on %s", path)
+	err = serveDaemon(ln, daemonHandler(listeners))
+	_ = os.Remove(path)
+	return err
+}
+
+// daemonListeners are the parts of the daemon that config.toml sets up
+// once: the ntfy reply stream, the peer listener, and the hotkey agent.
+// A reload stops and starts them so changed topics, ports, and bindings
+// take effect.
+type daemonListeners struct {
+	mu   sync.Mutex
+	stop []func()
+}
+
+func (l *daemonListeners) restart() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopLocked()
+
+	if cfg, err := loadUserConfig(); err == nil && cfg.Ntfy.enabled() && cfg.Ntfy.repliesEnabled() {
+		l.stop = append(l.stop, startNtfyReplyListener(cfg.Ntfy))
+		logInfof("daemon answering approvals from ntfy topic %s", cfg.Ntfy.ReplyTopic)
+	}
+	if cfg, err := loadUserConfig(); err == nil && cfg.Peer.listening() && featureEnabled("peer") {
+		if stop, err := startPeerListener(cfg.Peer); err != nil {
+			logErrorf("peer: %v", err)
+		} else {
+			l.stop = append(l.stop, stop)
+			logInfof("daemon accepting the peer on %s", cfg.Peer.Listen)
+		}
+	}
+	l.stop = append(l.stop, startHotkeyAgent())
+}
+
+func (l *daemonListeners) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopLocked()
+}
+
+func (l *daemonListeners) stopLocked() {
+	for _, stop := range l.stop {
+		stop()
+	}
+	l.stop = nil
+}
+
+// reloadDaemonConfig re-reads config.toml: its settings replace the ones
+// exported at startup, and the listeners restart with the new tables.
+// Forwarded hooks carry their shell's settings and need no reload. A
+// config that does not parse leaves the daemon as it was.
+func reloadDaemonConfig(listeners *daemonListeners) error {
+	var err error
+	inDaemonWork(func() {
+		resetFeatureCache()
+		err = applyUserConfigSettings()
+	})
+	if err != nil {
+		return configError(fmt.Errorf("config.toml: %w", err))
+	}
+	// Listeners are restarted outside daemonWork: stopping the peer
+	// listener waits for its handlers, which take daemonWork themselves.
+	listeners.restart()
+	logInfof("daemon reloaded config.toml")
+	return nil
+}
+
+// configWatchInterval is how often the daemon looks for config.toml edits.
+const configWatchInterval = 2 * time.Second
+
+// watchUserConfig calls reload whenever config.toml's modification time
+// or size changes, until the returned function is called.
+func watchUserConfig(reload func()) func() {
+	stamp := func() string {
+		path, err := userConfigPath()
+		if err != nil {
+			return ""
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "missing"
+		}
+		return fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+	}
+	last := stamp()
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(configWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if current := stamp(); current != last {
+					last = current
+					reload()
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// runDaemonReload asks the running daemon to re-read config.toml, as
+// SIGHUP does.
+func runDaemonReload(args []string) error {
+	fs := flag.NewFlagSet("daemon reload", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	socket := fs.String("socket", "", "unix socket path (default: daemon.sock in the runtime state dir)")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+	out := outFlags.output()
+
+	path := *socket
+	if path == "" {
+		var err error
+		if path, err = daemonSocketPath(); err != nil {
+			return err
+		}
+	}
+	data, _, err := exchangeDaemonRequest(path, daemonRequest{Schema: schemaVersion, Reload: true})
+	if err != nil {
+		return fmt.Errorf("no daemon answered on %s", path)
+	}
+	resp, err := decodeDaemonResponse(data)
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return withExitCode(resp.ExitCode, errors.New(resp.Error))
+	}
+	out.Printf("daemon reloaded config.toml\n")
+	return out.Result(commandResult{Command: "daemon", Status: "reloaded", Path: path})
+}
+
+// listenDaemonSocket binds path, replacing a stale socket left by a daemon
+// that did not shut down cleanly but refusing to steal a live one.
+func listenDaemonSocket(path string) (net.Listener, error) {
+	if conn, err := net.DialTimeout("unix", path, 200*time.Millisecond); err == nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, privateFileMode); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("restrict socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+// daemonHandlerFunc handles one request and answers it with reply, which
+// may come before the handler is done: only the first reply is sent.
+type daemonHandlerFunc func(req daemonRequest, reply func(daemonResponse))
+
+// serveDaemon answers requests until ln is closed.
+func serveDaemon(ln net.Listener, handle daemonHandlerFunc) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("accept: %w", err)
+		}
+		go func() {
+			defer conn.Close()
+			_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+			var raw json.RawMessage
+			if err := json.NewDecoder(conn).Decode(&raw); err != nil {
+				writeDaemonResponse(conn, daemonResponse{Error: fmt.Sprintf("decode request: %v", err), ExitCode: exitUsage})
+				return
+			}
+			req, err := validateDaemonRequest(raw)
+			if err != nil {
+				writeDaemonResponse(conn, daemonResponse{Error: fmt.Sprintf("request %v", err), ExitCode: exitUsage})
+				return
+			}
+			replied := false
+			handle(req, func(resp daemonResponse) {
+				if !replied {
+					replied = true
+					writeDaemonResponse(conn, resp)
+				}
+			})
+		}()
+	}
+}
+
+// writeDaemonResponse sends resp, or an error in its place when resp does
+// not match the schema, so clients never see an unchecked answer.
+func writeDaemonResponse(w io.Writer, resp daemonResponse) {
+	resp.Schema = schemaVersion
+	data, err := json.Marshal(resp)
+	if err == nil {
+		err = validateSchema("daemon_response", data)
+	}
+	if err != nil {
+		logErrorf("daemon response %v", err)
+		data, _ = json.Marshal(daemonResponse{Schema: schemaVersion, Error: fmt.Sprintf("daemon response %v", err), ExitCode: exitFailure})
+	}
+	_, _ = w.Write(append(data, '\n'))
+}
+
+// daemonHandler answers reload requests itself and hands hooks to
+// handleDaemonRequest.
+func daemonHandler(listeners *daemonListeners) daemonHandlerFunc {
+	return func(req daemonRequest, reply func(daemonResponse)) {
+		if !req.Reload {
+			handleDaemonRequest(req, reply)
+			return
+		}
+		if err := reloadDaemonConfig(listeners); err != nil {
+			reply(daemonResponse{Error: err.Error(), ExitCode: exitCodeFor(err)})
+			return
+		}
+		reply(daemonResponse{Result: &commandResult{Command: "daemon", Status: "reloaded"}})
+	}
+}
+
+// handleDaemonRequest delivers a forwarded hook with the client's
+// variables, as an in-process hook in the client's shell would. It replies
+// once the desktop notification is out and then waits for the remote sinks,
+// still with the hook's variables, so a slow sink never holds up the client.
+func handleDaemonRequest(req daemonRequest, reply func(daemonResponse)) {
+	payload := map[string]any{}
+	if strings.TrimSpace(req.Payload) != "" {
+		if err := json.Unmarshal([]byte(req.Payload), &payload); err != nil {
+			reply(daemonResponse{Error: fmt.Sprintf("parse payload json: %v", err), ExitCode: exitUsage})
+			return
+		}
+	}
+	withHookEnv(req.Env, func() {
+		codexExits.observe(payload, req.Env)
+		var waits []func()
+		result, err := deliverPayload(payload, hookDelivery{later: func(w []func()) { waits = w }})
+		if err != nil {
+			reply(daemonResponse{Error: err.Error(), ExitCode: exitCodeFor(err)})
+		} else {
+			reply(daemonResponse{Result: &result})
+		}
+		for _, wait := range waits {
+			wait()
+		}
+	})
+}
+
+// isForwardedEnv reports whether a variable travels with forwarded hooks.
+func isForwardedEnv(key string) bool {
+	if key == privateRelayEnv {
+		return false
+	}
+	return strings.HasPrefix(key, "CODEX_NOTIFY_") || key == "TMUX" || key == "TMUX_PANE"
+}
+
+func forwardedEnv() map[string]string {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok && isForwardedEnv(key) {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// forwardHookToDaemon hands the payload to a running daemon. forwarded is
+// false when no daemon answered, and the caller handles the hook itself.
+func forwardHookToDaemon(raw string) (result commandResult, forwarded bool, err error) {
+	if !daemonForwardingEnabled() {
+		return commandResult{}, false, nil
+	}
+	path, err := daemonSocketPath()
+	if err != nil {
+		return commandResult{}, false, nil
+	}
+	return forwardHookToSocket(path, raw)
+}
+
+func forwardHookToSocket(path, raw string) (commandResult, bool, error) {
+	data, sent, err := exchangeDaemonRequest(path, daemonRequest{Schema: schemaVersion, Payload: raw, Env: forwardedEnv()})
+	if !sent {
+		return commandResult{}, false, nil
+	}
+	if err != nil {
+		// The daemon has the hook and may still deliver it. Handling it
+		// here too would show it twice: approvals have no turn ID, so the
+		// duplicate check cannot catch the second one.
+		logErrorf("daemon did not answer the hook: %v", err)
+		return commandResult{Command: "hook", Status: "forwarded"}, true, nil
+	}
+	resp, err := decodeDaemonResponse(data)
+	if err != nil {
+		return commandResult{}, true, err
+	}
+	if resp.Error != "" {
+		return commandResult{}, true, withExitCode(resp.ExitCode, errors.New(resp.Error))
+	}
+	if resp.Result == nil {
+		return commandResult{}, true, errors.New("daemon returned no result")
+	}
+	return *resp.Result, true, nil
+}
+
+// exchangeDaemonRequest sends req to the daemon on path and reads one
+// response document. sent is false when no daemon took the request; with
+// sent true, err means the request went out but no answer came back.
+func exchangeDaemonRequest(path string, req daemonRequest) (data json.RawMessage, sent bool, err error) {
+	conn, err := net.DialTimeout("unix", path, 200*time.Millisecond)
+	if err != nil {
+		return nil, false, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, false, err
+	}
+	if err := json.NewDecoder(conn).Decode(&data); err != nil {
+		return nil, true, fmt.Errorf("read daemon response: %w", err)
+	}
+	return data, true, nil
+}
+
+func decodeDaemonResponse(data json.RawMessage) (daemonResponse, error) {
+	var resp daemonResponse
+	if err := validateSchema("daemon_response", data); err != nil {
+		return resp, fmt.Errorf("daemon response %w", err)
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, fmt.Errorf("decode daemon response: %w", err)
+	}
+	return resp, nil
+}
+
+func addDaemonDoctorCheck(report *doctorReport) {
+	path, err := daemonSocketPath()
+	if err != nil {
+		return
+	}
+	if !daemonForwardingEnabled() {
+		report.add(doctorDaemon, checkOK, "daemon", "forwarding off (CODEX_NOTIFY_DAEMON=0)", false)
+		return
+	}
+	conn, err := net.DialTimeout("unix", path, 200*time.Millisecond)
+	if err != nil {
+		report.add(doctorDaemon, checkOK, "daemon", "not running (hook runs in-process)", false)
+		return
+	}
+	_ = conn.Close()
+	report.add(doctorDaemon, checkOK, "daemon", "running on "+path, false)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func startTestDaemon(t *testing.T, handle daemonHandlerFunc) string {
+	t.Helper()
+
+	// Unix socket paths are short on macOS; t.TempDir() can exceed them.
+	dir, err := os.MkdirTemp("", "cn-daemon")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, daemonSocketName)
+
+	ln, err := listenDaemonSocket(path)
+	if err != nil {
+		t.Fatalf("listenDaemonSocket() error = %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- serveDaemon(ln, handle) }()
+	t.Cleanup(func() {
+		ln.Close()
+		if err := <-done; err != nil {
+			t.Errorf("serveDaemon() error = %v", err)
+		}
+	})
+	return path
+}
+
+func TestForwardHookToSocket(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_TMUX_SUPPRESS", "1")
+
+	var got daemonRequest
+	path := startTestDaemon(t, func(req daemonRequest, reply func(daemonResponse)) {
+		got = req
+		reply(daemonResponse{Result: &commandResult{Command: "hook", Status: "sent", Count: 1}})
+	})
+
+	result, forwarded, err := forwardHookToSocket(path, `{"type":"agent-turn-complete"}`)
+	if err != nil || !forwarded {
+		t.Fatalf("forwardHookToSocket() = %v, %v, want forwarded without error", forwarded, err)
+	}
+	if result.Status != "sent" || result.Count != 1 {
+		t.Fatalf("result = %+v, want sent/1", result)
+	}
+	if got.Payload != `{"type":"agent-turn-complete"}` || got.Env["CODEX_NOTIFY_TMUX_SUPPRESS"] != "1" {
+		t.Fatalf("daemon received %+v, want the payload and CODEX_NOTIFY_* env", got)
+	}
+	if _, ok := got.Env[privateRelayEnv]; ok {
+		t.Fatalf("daemon received %s, want it withheld", privateRelayEnv)
+	}
+
+	if _, err := listenDaemonSocket(path); err == nil {
+		t.Fatalf("listenDaemonSocket() on a live socket error = nil, want error")
+	}
+}
+
+func TestForwardHookToSocketKeepsExitCode(t *testing.T) {
+	path := startTestDaemon(t, func(_ daemonRequest, reply func(daemonResponse)) {
+		reply(daemonResponse{Error: "no notifier available", ExitCode: exitBackend})
+	})
+
+	_, forwarded, err := forwardHookToSocket(path, `{}`)
+	if !forwarded || exitCodeFor(err) != exitBackend {
+		t.Fatalf("forwardHookToSocket() = %v, %v, want forwarded backend error", forwarded, err)
+	}
+}
+
+func TestForwardHookNotAnsweredIsNotRetried(t *testing.T) {
+	// The daemon took the hook but never answered, as when it is still
+	// delivering at the client's deadline.
+	path := startTestDaemon(t, func(daemonRequest, func(daemonResponse)) {})
+
+	result, forwarded, err := forwardHookToSocket(path, `{"type":"approval-requested","thread-id":"t1"}`)
+	if !forwarded || err != nil || result.Status != "forwarded" {
+		t.Fatalf("forwardHookToSocket() = %+v, %v, %v; want it left to the daemon", result, forwarded, err)
+	}
+}
+
+func TestDaemonAnswersBeforeRemoteSinks(t *testing.T) {
+	home, _ := useTempHome(t)
+	// Remote sinks stay quiet in capture mode.
+	t.Setenv(backendEnv, "")
+	t.Setenv(ntfyTokenEnv, "")
+	t.Setenv("CODEX_NOTIFY_NOTIFICATION_UI", notificationUISystem)
+	t.Setenv("CODEX_NOTIFY_SANDBOX", "0")
+	bin := t.TempDir()
+	writeFakeNotifier(t, bin, "terminal-notifier", filepath.Join(home, "ran.log"))
+	t.Setenv("PATH", bin)
+	release, published := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		close(published)
+	}))
+	defer server.Close()
+	config := filepath.Join(home, "config.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", config)
+	if err := os.WriteFile(config, []byte("[ntfy]\ntopic = \"runs\"\nserver = \""+server.URL+"\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	path := startTestDaemon(t, daemonHandler(&daemonListeners{}))
+	start := time.Now()
+	result, forwarded, err := forwardHookToSocket(path, `{"type":"approval-requested","thread-id":"t1"}`)
+	elapsed := time.Since(start)
+	close(release)
+	if !forwarded || err != nil || result.Status != "sent" {
+		t.Fatalf("forwardHookToSocket() = %+v, %v, %v; want sent", result, forwarded, err)
+	}
+	// ntfy holds the request until released, so an answer that waited
+	// for it would take the whole HTTP timeout.
+	if elapsed > 2*time.Second {
+		t.Fatalf("the daemon answered after %s, want before ntfy did", elapsed)
+	}
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the daemon never published to ntfy")
+	}
+}
+
+func TestForwardHookWithoutDaemonFallsBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), daemonSocketName)
+	if _, forwarded, err := forwardHookToSocket(path, `{}`); forwarded || err != nil {
+		t.Fatalf("forwardHookToSocket(no daemon) = %v, %v, want not forwarded", forwarded, err)
+	}
+
+	// A stale socket file from a crashed daemon is replaced.
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+	ln, err = listenDaemonSocket(path)
+	if err != nil {
+		t.Fatalf("listenDaemonSocket(stale) error = %v", err)
+	}
+	ln.Close()
+}
+
+func TestWithHookEnvLeavesProcessEnvAlone(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_SANDBOX", "1")
+	t.Setenv("CODEX_NOTIFY_OPEN_KEYS", "")
+	os.Unsetenv("CODEX_NOTIFY_OPEN_KEYS")
+
+	withHookEnv(map[string]string{"CODEX_NOTIFY_OPEN_KEYS": "enter", "HOME": "/elsewhere"}, func() {
+		if _, ok := lookupEnv("CODEX_NOTIFY_SANDBOX"); ok {
+			t.Errorf("the daemon's CODEX_NOTIFY_SANDBOX leaked into the hook")
+		}
+		if getenv("CODEX_NOTIFY_OPEN_KEYS") != "enter" {
+			t.Errorf("CODEX_NOTIFY_OPEN_KEYS = %q, want enter", getenv("CODEX_NOTIFY_OPEN_KEYS"))
+		}
+		if getenv("HOME") == "/elsewhere" {
+			t.Errorf("HOME was forwarded")
+		}
+		if os.Getenv("CODEX_NOTIFY_SANDBOX") != "1" || os.Getenv("CODEX_NOTIFY_OPEN_KEYS") != "" {
+			t.Errorf("the process environment changed while handling the hook")
+		}
+	})
+	if getenv("CODEX_NOTIFY_SANDBOX") != "1" {
+		t.Fatalf("CODEX_NOTIFY_SANDBOX after the hook = %q, want the daemon's", getenv("CODEX_NOTIFY_SANDBOX"))
+	}
+	if _, ok := lookupEnv("CODEX_NOTIFY_OPEN_KEYS"); ok {
+		t.Fatalf("CODEX_NOTIFY_OPEN_KEYS outlived the hook")
+	}
+}
+
+func TestConcurrentHooksKeepTheirOwnEnv(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(project string) {
+			defer wg.Done()
+			withHookEnv(map[string]string{"CODEX_NOTIFY_BACKEND": "capture:/tmp/" + project}, func() {
+				time.Sleep(time.Millisecond)
+				if got := getenv("CODEX_NOTIFY_BACKEND"); got != "capture:/tmp/"+project {
+					t.Errorf("hook for %s saw backend %q", project, got)
+				}
+			})
+		}(fmt.Sprintf("p%d", i))
+	}
+	wg.Wait()
+}
+
+func TestDaemonReloadReappliesConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", configPath)
+	t.Setenv("CODEX_NOTIFY_TERMINAL_BUNDLE_ID", "")
+	os.Unsetenv("CODEX_NOTIFY_TERMINAL_BUNDLE_ID")
+	t.Cleanup(func() { configExportedEnv = map[string]string{} })
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	write("terminal_bundle_id = \"com.googlecode.iterm2\"\n")
+	if err := applyUserConfigSettings(); err != nil {
+		t.Fatalf("applyUserConfigSettings() error = %v", err)
+	}
+
+	path := startTestDaemon(t, daemonHandler(&daemonListeners{}))
+	write("terminal_bundle_id = \"com.mitchellh.ghostty\"\n")
+	if err := runDaemonReload([]string{"--socket", path, "--quiet"}); err != nil {
+		t.Fatalf("daemon reload error = %v", err)
+	}
+	if got := terminalBundleID(); got != "com.mitchellh.ghostty" {
+		t.Fatalf("terminalBundleID() after reload = %q, want the new value", got)
+	}
+
+	// A broken file keeps the running settings.
+	write("terminal_bundle_id = \n")
+	if err := runDaemonReload([]string{"--socket", path, "--quiet"}); exitCodeFor(err) != exitConfig {
+		t.Fatalf("daemon reload (broken config) = %v (exit %d), want a config error", err, exitCodeFor(err))
+	}
+	if got := os.Getenv("CODEX_NOTIFY_TERMINAL_BUNDLE_ID"); got != "com.mitchellh.ghostty" {
+		t.Fatalf("CODEX_NOTIFY_TERMINAL_BUNDLE_ID = %q, want it kept", got)
+	}
+
+	// A setting dropped from the file is taken back.
+	write("")
+	if err := runDaemonReload([]string{"--socket", path, "--quiet"}); err != nil {
+		t.Fatalf("daemon reload error = %v", err)
+	}
+	if _, ok := os.LookupEnv("CODEX_NOTIFY_TERMINAL_BUNDLE_ID"); ok {
+		t.Fatalf("CODEX_NOTIFY_TERMINAL_BUNDLE_ID still set after the file dropped it")
+	}
+
+	if err := runDaemonReload([]string{"--socket", filepath.Join(t.TempDir(), daemonSocketName)}); err == nil {
+		t.Fatalf("daemon reload without a daemon error = nil")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+)
+
+// stopPopupHelpers terminates every running popup helper and reports
+// whether any was running. Tests replace it so pkill never reaches a real
+// helper.
+var stopPopupHelpers = func() bool {
+	pkill, ok := lookupCmd("pkill")
+	if !ok || hostOS != "darwin" {
+		return false
+	}
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		return false
+	}
+	// pkill exits 1 when nothing matched. The anchor spares the daemon's
+	// hotkey agent, which runs the same binary with --hotkeys.
+	pattern := regexp.QuoteMeta(filepath.Join(stateDir, helperBinaryName)) + "$"
+	return runLogged(exec.Command(pkill, "-TERM", "-f", "--", pattern)) == nil
+}
+
+// runDismiss removes notifications that are still showing:
+// `dismiss --thread-id id` for one thread, `dismiss --all` for everything
+// codex-notify delivered.
+func runDismiss(args []string) error {
+	fs := flag.NewFlagSet("dismiss", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	threadID := fs.String("thread-id", "", "dismiss this thread's notifications")
+	all := fs.Bool("all", false, "dismiss every notification and close all popups")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+	if (*threadID == "") != *all {
+		return usageError(errors.New("dismiss requires one of --thread-id id or --all"))
+	}
+	out := outFlags.output()
+
+	var groups []string
+	delivered := deliveredReceipts()
+	if *all {
+		for _, r := range delivered {
+			groups = append(groups, r.ID)
+		}
+	} else {
+		var err error
+		if groups, err = threadGroups(*threadID); err != nil {
+			return err
+		}
+	}
+	withdrawLocalGroups(groups)
+	recordNoiseDismissed(*threadID)
+	stopped := *all && stopPopupHelpers()
+	// A popup that closes logs it itself, unless it was just stopped;
+	// removed banners leave no trace.
+	for _, r := range delivered {
+		if (*all || r.Backend != "popup") && containsString(groups, r.ID) {
+			_ = appendReceipt(deliveryReceipt{ID: r.ID, Backend: r.Backend, Status: receiptDismissed})
+		}
+	}
+
+	if stopped {
+		// A stopped approval popup cannot release its lock itself.
+		if lockPath, err := approvalInteractionLockPath(); err == nil {
+			clearApprovalInteractionLock(lockPath)
+		}
+	}
+
+	switch {
+	case *all && stopped:
+		out.Printf("dismissed %d notification groups and closed the open popups\n", len(groups))
+	case *all:
+		out.Printf("dismissed %d notification groups\n", len(groups))
+	default:
+		out.Printf("dismissed %d notification groups for thread %s\n", len(groups), *threadID)
+	}
+	if hostOS == "linux" {
+		out.Println("notify-send notifications cannot be removed; they close on their own")
+	}
+	return out.Result(commandResult{Command: "dismiss", Status: "dismissed", Thread: *threadID, Count: len(groups)})
+}
+
+// threadGroups are the notification groups thread may occupy: its approval
+// groups and one per event it logged.
+func threadGroups(thread string) ([]string, error) {
+	groups := approvalGroups(thread)
+	entries, err := threadTimeline(thread)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.Source != "event" {
+			continue
+		}
+		if group := notificationGroup(e.Kind, thread); !containsString(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+// deliveredReceipts are the last receipts of groups that were delivered
+// and have not been closed since, so they may still be on screen.
+func deliveredReceipts() []deliveryReceipt {
+	path, err := receiptsPath()
+	if err != nil {
+		return nil
+	}
+	content, err := readFileMaybe(path)
+	if err != nil {
+		return nil
+	}
+	var order []string
+	last := map[string]deliveryReceipt{}
+	for _, line := range splitLines(content) {
+		var r deliveryReceipt
+		if json.Unmarshal([]byte(line), &r) != nil || r.ID == "" {
+			continue
+		}
+		if _, seen := last[r.ID]; !seen {
+			order = append(order, r.ID)
+		}
+		last[r.ID] = r
+	}
+	var delivered []deliveryReceipt
+	for _, id := range order {
+		if last[id].Status == receiptDelivered {
+			delivered = append(delivered, last[id])
+		}
+	}
+	return delivered
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDismiss(t *testing.T) {
+	useTempHome(t)
+	t.Setenv("PATH", "")
+	stopped := false
+	stub(t, &stopPopupHelpers, func() bool { stopped = true; return true })
+
+	if err := runDismiss([]string{"--quiet"}); exitCodeFor(err) != exitUsage {
+		t.Fatalf("dismiss without a target = %v", err)
+	}
+	if err := runDismiss([]string{"--all", "--thread-id", "t1", "--quiet"}); exitCodeFor(err) != exitUsage {
+		t.Fatalf("dismiss with both targets = %v", err)
+	}
+
+	turn := notificationGroup("agent-turn-complete", "t1")
+	other := notificationGroup("agent-turn-complete", "t2")
+	recordDelivered(turn, "terminal-notifier")
+	recordDelivered(notificationGroup("approval-native", "t1"), "popup")
+	recordDelivered(other, "terminal-notifier")
+	recordHookEvent(map[string]any{"type": "agent-turn-complete", "thread-id": "t1"}, "sent")
+
+	if err := runDismiss([]string{"--thread-id", "t1", "--quiet"}); err != nil {
+		t.Fatalf("dismiss --thread-id: %v", err)
+	}
+	if stopped {
+		t.Fatal("dismissing one thread stopped every popup")
+	}
+	for _, group := range []string{turn, notificationGroup("approval-native", "t1")} {
+		marker, _ := withdrawMarkerPath(group)
+		if _, err := os.Stat(marker); err != nil {
+			t.Errorf("%s not withdrawn: %v", group, err)
+		}
+	}
+	// The popup logs its own close; the banner is marked dismissed.
+	if got := deliveredReceipts(); len(got) != 2 || got[0].ID != notificationGroup("approval-native", "t1") || got[1].ID != other {
+		t.Fatalf("delivered after dismissing t1 = %+v", got)
+	}
+
+	if err := runDismiss([]string{"--all", "--quiet"}); err != nil {
+		t.Fatalf("dismiss --all: %v", err)
+	}
+	if !stopped {
+		t.Fatal("dismiss --all left popups running")
+	}
+	if got := deliveredReceipts(); len(got) != 0 {
+		t.Fatalf("delivered after dismiss --all = %+v", got)
+	}
+}
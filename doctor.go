@@ -0,0 +1,395 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// doctorCheck is one line of doctor output. Problem marks checks that count
+// toward the doctor failure, which is not the same as status: a missing
+// terminal-notifier is a warning but not a problem.
+type doctorCheck struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Status   string `json:"status"`
+	Detail   string `json:"detail"`
+	Problem  bool   `json:"problem"`
+}
+
+type doctorReport struct {
+	Command  string   `json:"command"`
+	Status   string   `json:"status"`
+	Config   string   `json:"config"`
+	Configs  []string `json:"configs,omitempty"`
+	Problems int      `json:"problems"`
+	// Failed lists the categories with problems, in report order.
+	Failed []string      `json:"failed,omitempty"`
+	Checks []doctorCheck `json:"checks"`
+	// Preview is filled by `doctor --preview`.
+	Preview []notificationPreview `json:"preview,omitempty"`
+	// only holds the categories `doctor --check` selected; the probes of
+	// the others do not run.
+	only map[string]bool
+}
+
+// add reports one check in category, unless --check left the category
+// out.
+func (r *doctorReport) add(category, status, name, detail string, problem bool) {
+	if !r.wants(category) {
+		return
+	}
+	r.Checks = append(r.Checks, doctorCheck{Name: name, Category: category, Status: status, Detail: detail, Problem: problem})
+	if problem {
+		r.Problems++
+		if !containsString(r.Failed, category) {
+			r.Failed = append(r.Failed, category)
+		}
+	}
+}
+
+func doctorStatusLabel(status string) string {
+	switch status {
+	case checkOK:
+		return " OK "
+	case checkWarn:
+		return "WARN"
+	default:
+		return "FAIL"
+	}
+}
+
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	config := fs.String("config", "", "path to Codex config.toml")
+	preview := fs.Bool("preview", false, "also show how each bundled event fixture would be notified")
+	fix := fs.Bool("fix", false, "build or repair the popup helper before checking")
+	checks := fs.String("check", "", "only run these check categories, comma-separated (platform, permissions, helper, codex, daemon, sinks, settings)")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	only, err := parseDoctorCategories(*checks)
+	if err != nil {
+		return usageError(err)
+	}
+	out := outFlags.output()
+
+	cfgPaths, err := resolveConfigTargets(*config)
+	if err != nil {
+		return configError(err)
+	}
+
+	report := doctorReport{Command: "doctor", Config: cfgPaths[0], only: only}
+	if len(cfgPaths) > 1 {
+		report.Configs = cfgPaths
+	}
+
+	// Each category's probes run only when --check selected it, so asking
+	// for one never raises another's permission prompt or network call.
+	userCfg, userCfgPath, hasUserCfg := addUserConfigDoctorCheck(&report)
+	if report.wants(doctorPlatform) {
+		addPlatformDoctorChecks(&report)
+	}
+	if report.wants(doctorPermissions) {
+		addPrivacyDoctorCheck(&report)
+		addPermissionDoctorCheck(&report)
+	}
+	if report.wants(doctorHelper) {
+		addPopupHelperDoctorChecks(&report, *fix)
+	}
+	if report.wants(doctorCodex) {
+		addCodexDoctorCheck(&report)
+		if hasUserCfg {
+			addChainDoctorCheck(&report, userCfg)
+		}
+		if err := addCodexConfigDoctorChecks(&report, cfgPaths); err != nil {
+			return err
+		}
+	}
+	if report.wants(doctorDaemon) {
+		addDaemonDoctorCheck(&report)
+		addLockQueueDoctorCheck(&report)
+		addHotkeyDoctorCheck(&report)
+	}
+	if report.wants(doctorSinks) && hasUserCfg {
+		addSinkDoctorChecks(&report, userCfg, userCfgPath)
+	}
+	if report.wants(doctorSettings) {
+		addSettingsDoctorChecks(&report, userCfg, hasUserCfg)
+	}
+
+	report.Status = "ok"
+	if report.Problems > 0 {
+		report.Status = "problems"
+	}
+
+	out.Println("codex-notify doctor")
+	out.Println("-------------------")
+	for _, check := range report.Checks {
+		out.Printf("[%s] %s: %s\n", doctorStatusLabel(check.Status), check.Name, check.Detail)
+	}
+	if *preview {
+		previews, err := previewNotifications()
+		if err != nil {
+			return err
+		}
+		report.Preview = previews
+		printNotificationPreviews(out, previews)
+	}
+	if err := out.Result(report); err != nil {
+		return err
+	}
+
+	if report.Problems > 0 {
+		return doctorError(report)
+	}
+
+	if len(report.Checks) == 0 {
+		out.Printf("no %s checks apply on this machine\n", strings.Join(report.selected(), ", "))
+		return nil
+	}
+	out.Println("all checks passed")
+	return nil
+}
+
+// addUserConfigDoctorCheck loads config.toml for the checks that read it.
+// It reports false when there is none, or it does not load.
+func addUserConfigDoctorCheck(report *doctorReport) (userConfig, string, bool) {
+	path, err := userConfigPath()
+	if err != nil {
+		return userConfig{}, "", false
+	}
+	if _, err := os.Stat(path); err != nil {
+		return userConfig{}, "", false
+	}
+	cfg, err := loadUserConfig()
+	if err != nil {
+		report.add(doctorSettings, checkFail, "user config", err.Error(), true)
+		return userConfig{}, "", false
+	}
+	report.add(doctorSettings, checkOK, "user config", fmt.Sprintf("%s (%d presets)", path, len(cfg.Presets)), false)
+	return cfg, path, true
+}
+
+// addPlatformDoctorChecks reports the OS, the notifiers it offers, and
+// the runtime dir. On macOS it asks System Events whether Apple Events
+// are blocked.
+func addPlatformDoctorChecks(report *doctorReport) {
+	switch hostOS {
+	case "darwin":
+		report.add(doctorPlatform, checkOK, "OS", "darwin", false)
+		addTerminalDoctorCheck(report)
+
+		terminalNotifierPath, terminalNotifierOK := lookupCmd("terminal-notifier")
+		if terminalNotifierOK {
+			report.add(doctorPlatform, checkOK, "terminal-notifier", terminalNotifierPath, false)
+		} else {
+			report.add(doctorPlatform, checkWarn, "terminal-notifier", "not found (will use osascript fallback)", false)
+		}
+
+		osascriptPath, osascriptOK := lookupCmd("osascript")
+		switch {
+		case sandboxModeEnabled():
+			report.add(doctorPlatform, checkOK, "sandbox mode", "on (no osascript/System Events; approve/reject keys disabled)", false)
+			if !terminalNotifierOK && notificationUIStyle() != notificationUIPopup {
+				report.add(doctorPlatform, checkFail, "notifier", "sandbox mode needs terminal-notifier or the popup UI", true)
+			}
+		case !osascriptOK:
+			report.add(doctorPlatform, checkFail, "osascript", "not found; set CODEX_NOTIFY_SANDBOX=1 to run without it", true)
+		default:
+			report.add(doctorPlatform, checkOK, "osascript", osascriptPath, false)
+			if sandboxModeRequired(probeSystemEvents(osascriptPath)) {
+				report.add(doctorPlatform, checkWarn, "sandbox mode", "System Events is blocked by privacy policy; set CODEX_NOTIFY_SANDBOX=1", false)
+			}
+		}
+	case "linux":
+		report.add(doctorPlatform, checkOK, "OS", "linux (notify-send; approve/reject keys unavailable)", false)
+		addLinuxDoctorChecks(report)
+	default:
+		report.add(doctorPlatform, checkFail, "OS", fmt.Sprintf("expected darwin or linux, got %s", hostOS), true)
+	}
+
+	if err := checkBackendEnv(); err != nil {
+		report.add(doctorPlatform, checkFail, "backends", err.Error(), true)
+	}
+	available := []string{}
+	for _, backend := range notificationBackends() {
+		if backend.Available() {
+			available = append(available, backend.Name())
+		}
+	}
+	if len(available) > 0 {
+		report.add(doctorPlatform, checkOK, "backends", strings.Join(available, " → "), false)
+	} else {
+		report.add(doctorPlatform, checkFail, "backends", "no notification backend available", true)
+	}
+
+	switch powerSaverMode() {
+	case powerSaverOn:
+		report.add(doctorPlatform, checkOK, "power saver", "on (popup helper is skipped)", false)
+	case powerSaverAuto:
+		if powerSaverActive() {
+			report.add(doctorPlatform, checkOK, "power saver", "auto (active: on battery or Low Power Mode)", false)
+		} else {
+			report.add(doctorPlatform, checkOK, "power saver", "auto (inactive: on AC power)", false)
+		}
+	}
+
+	if stateDir, err := runtimeStateDir(); err == nil {
+		report.add(doctorPlatform, checkOK, "runtime dir", stateDir, false)
+	} else {
+		report.add(doctorPlatform, checkFail, "runtime dir", err.Error(), true)
+	}
+}
+
+// addPrivacyDoctorCheck reports whether payloads stay out of argv.
+func addPrivacyDoctorCheck(report *doctorReport) {
+	if privateArgvEnabled() {
+		report.add(doctorPermissions, checkOK, "payload privacy", "private argv mode on (payload relayed over stdin)", false)
+	} else {
+		report.add(doctorPermissions, checkWarn, "payload privacy", "Codex passes payloads as argv (visible in ps); set CODEX_NOTIFY_PRIVATE_ARGV=1 or deliver payloads via stdin/--payload-file", false)
+	}
+}
+
+// addPopupHelperDoctorChecks checks the popup helper and its build, first
+// building it when fix is set.
+func addPopupHelperDoctorChecks(report *doctorReport, fix bool) {
+	if hostOS == "darwin" && notificationUIStyle() == notificationUIPopup {
+		swiftcPath, swiftcOK := lookupCmd("swiftc")
+		if swiftcOK {
+			report.add(doctorHelper, checkOK, "swiftc", swiftcPath, false)
+		} else {
+			report.add(doctorHelper, checkWarn, "swiftc", "not found (popup UI will fall back to system notifications)", false)
+		}
+		if fix {
+			if _, elapsed, err := prewarmHelper(); err != nil {
+				report.add(doctorHelper, checkFail, "helper build", err.Error(), true)
+			} else {
+				report.add(doctorHelper, checkOK, "helper build", fmt.Sprintf("built in %s", elapsed), false)
+			}
+		}
+		addHelperDoctorCheck(report)
+	}
+}
+
+// addCodexConfigDoctorChecks checks the notify line of each Codex config.
+func addCodexConfigDoctorChecks(report *doctorReport, cfgPaths []string) error {
+	for _, cfgPath := range cfgPaths {
+		cfg, err := readFileMaybe(cfgPath)
+		if err != nil {
+			return configError(err)
+		}
+		if len(cfg) == 0 {
+			report.add(doctorCodex, checkWarn, "config", fmt.Sprintf("not found at %s", cfgPath), true)
+			continue
+		}
+		ok, err := configHasCodexNotify(cfg)
+		if err != nil {
+			return configError(err)
+		}
+		if ok && hasManagedNotifyBlock(cfg) {
+			report.add(doctorCodex, checkOK, "config", fmt.Sprintf("notify hook is configured (%s)", cfgPath), false)
+		} else if ok {
+			report.add(doctorCodex, checkWarn, "config", fmt.Sprintf("notify hook is configured outside the managed block; rerun init to migrate (%s)", cfgPath), false)
+		} else {
+			report.add(doctorCodex, checkWarn, "config", fmt.Sprintf("notify hook not configured (%s)", cfgPath), true)
+		}
+		addNotifyConflictChecks(report, cfgPath, cfg)
+	}
+	return nil
+}
+
+// addSinkDoctorChecks describes each configured remote sink and the
+// secrets config.toml refers to.
+func addSinkDoctorChecks(report *doctorReport, userCfg userConfig, path string) {
+	if content, err := os.ReadFile(path); err == nil {
+		addSecretDoctorChecks(report, content)
+	}
+	if userCfg.Ntfy.enabled() {
+		events := userCfg.Ntfy.Events
+		if events == nil {
+			events = defaultNtfyEvents
+		}
+		detail := fmt.Sprintf("%s/%s (%s)", userCfg.Ntfy.server(), userCfg.Ntfy.Topic, strings.Join(events, ", "))
+		status := checkOK
+		switch {
+		case userCfg.Ntfy.ReplyTopic == "":
+		case !featureEnabled("ntfy_replies"):
+			detail += "; reply_topic ignored (enable it with `" + appName + " features enable ntfy_replies`)"
+		case userCfg.Ntfy.token() == "":
+			status = checkWarn
+			detail += "; reply_topic ignored without a token (set ntfy.token or " + ntfyTokenEnv + ", with access control on the topic)"
+		default:
+			detail += "; replies on " + userCfg.Ntfy.ReplyTopic + " (needs the daemon)"
+		}
+		report.add(doctorSinks, status, "ntfy", detail, false)
+	}
+	if userCfg.Slack.enabled() {
+		events := userCfg.Slack.Events
+		if events == nil {
+			events = defaultSlackEvents
+		}
+		report.add(doctorSinks, checkOK, "slack", "webhook configured ("+strings.Join(events, ", ")+")", false)
+	}
+	addPhoneDoctorChecks(report, userCfg)
+	addIncidentDoctorChecks(report, userCfg)
+	addEmailDoctorCheck(report, userCfg.Email)
+	addPeerDoctorCheck(report, userCfg.Peer)
+	for _, hook := range userCfg.Webhooks {
+		events := "all events"
+		if hook.Events != nil {
+			events = strings.Join(hook.Events, ", ")
+		}
+		security, err := webhookSecurityDetail(hook)
+		if err != nil {
+			report.add(doctorSinks, checkFail, "webhook "+hook.Name, err.Error(), true)
+			continue
+		}
+		if security != "" {
+			events += "; " + security
+		}
+		report.add(doctorSinks, checkOK, "webhook "+hook.Name, fmt.Sprintf("%s %s (%s)", hook.method(), hook.host(), events), false)
+	}
+	addSinkQueueDoctorCheck(report, time.Now())
+}
+
+// addSettingsDoctorChecks describes the settings that shape what is
+// shown; the ones from config.toml only when it loaded.
+func addSettingsDoctorChecks(report *doctorReport, userCfg userConfig, hasUserCfg bool) {
+	addMuteDoctorCheck(report, time.Now())
+	addNoiseDoctorCheck(report)
+	switch terminalBellMode() {
+	case terminalBellBell:
+		report.add(doctorSettings, checkOK, "terminal bell", "BEL on approvals (hook runs without the daemon)", false)
+	case terminalBellOSC777:
+		report.add(doctorSettings, checkOK, "terminal bell", "OSC 777 and BEL on approvals (hook runs without the daemon)", false)
+	}
+	addIdleDoctorCheck(report)
+	addThrottleDoctorCheck(report)
+	addSoundThemeDoctorCheck(report)
+	if !hasUserCfg {
+		return
+	}
+	if toggles := userCfg.describeEventToggles(); toggles != "" {
+		report.add(doctorSettings, checkOK, "events", toggles, false)
+	}
+	addFeatureDoctorChecks(report, userCfg)
+	for _, id := range userCfg.Identities {
+		report.add(doctorSettings, checkOK, "identity "+id.Name, id.describe(), false)
+	}
+	addRuleDoctorChecks(report, userCfg)
+	addScreenShareDoctorCheck(report, userCfg)
+	addRemindDoctorCheck(report, userCfg)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The doctor check categories. Each check names its category where it is
+// added, and runDoctor skips the probes of categories --check left out.
+const (
+	doctorPlatform    = "platform"
+	doctorPermissions = "permissions"
+	doctorHelper      = "helper"
+	doctorCodex       = "codex"
+	doctorDaemon      = "daemon"
+	doctorSinks       = "sinks"
+	doctorSettings    = "settings"
+)
+
+// doctorCategory groups doctor checks for `doctor --check` and gives each
+// group its own exit code, so provisioning can assert only what it needs.
+type doctorCategory struct {
+	Name string
+	Exit int
+}
+
+// doctorCategories are in report order.
+var doctorCategories = []doctorCategory{
+	{Name: doctorPlatform, Exit: exitDoctorPlatform},
+	{Name: doctorPermissions, Exit: exitDoctorPermissions},
+	{Name: doctorHelper, Exit: exitDoctorHelper},
+	{Name: doctorCodex, Exit: exitDoctorCodex},
+	{Name: doctorDaemon, Exit: exitDoctorDaemon},
+	{Name: doctorSinks, Exit: exitDoctorSinks},
+	{Name: doctorSettings, Exit: exitDoctorSettings},
+}
+
+func doctorCategoryNames() []string {
+	names := make([]string, 0, len(doctorCategories))
+	for _, c := range doctorCategories {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// parseDoctorCategories reads --check: comma-separated category names, or
+// nothing for every category.
+func parseDoctorCategories(raw string) (map[string]bool, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	names := doctorCategoryNames()
+	only := map[string]bool{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !containsString(names, name) {
+			return nil, fmt.Errorf("unknown doctor check %q (want %s)", name, strings.Join(names, ", "))
+		}
+		only[name] = true
+	}
+	return only, nil
+}
+
+// wants reports whether the category's checks run: every category does
+// without --check.
+func (r doctorReport) wants(category string) bool {
+	return r.only == nil || r.only[category]
+}
+
+// selected lists the categories `doctor --check` asked for, in report
+// order.
+func (r doctorReport) selected() []string {
+	var names []string
+	for _, name := range doctorCategoryNames() {
+		if r.only[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// doctorError is doctor's failure: the exit code of the category the
+// problems are in, or exitFailure when they are in several.
+func doctorError(report doctorReport) error {
+	err := fmt.Errorf("doctor found %d issue(s) in %s", report.Problems, strings.Join(report.Failed, ", "))
+	if len(report.Failed) != 1 {
+		return err
+	}
+	for _, c := range doctorCategories {
+		if c.Name == report.Failed[0] {
+			return withExitCode(c.Exit, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoctorReportKeepsSelectedCategories(t *testing.T) {
+	only, err := parseDoctorCategories("Helper, permissions")
+	if err != nil {
+		t.Fatalf("parseDoctorCategories() error = %v", err)
+	}
+	report := doctorReport{only: only}
+	report.add(doctorPlatform, checkFail, "backends", "no notification backend available", true)
+	report.add(doctorHelper, checkFail, "popup helper", "signature mismatch", true)
+	report.add(doctorPermissions, checkOK, "permission automation", "granted", false)
+
+	if len(report.Checks) != 2 || report.Problems != 1 || strings.Join(report.Failed, ",") != "helper" {
+		t.Fatalf("report = %+v, want only the helper and permission checks", report)
+	}
+	if got := strings.Join(report.selected(), ","); got != "permissions,helper" {
+		t.Fatalf("selected() = %q", got)
+	}
+	if err := doctorError(report); exitCodeFor(err) != exitDoctorHelper || !strings.Contains(err.Error(), "1 issue(s) in helper") {
+		t.Fatalf("doctorError() = %v (exit %d), want the helper exit code", err, exitCodeFor(err))
+	}
+
+	if _, err := parseDoctorCategories("helper,disk"); err == nil || !strings.Contains(err.Error(), `unknown doctor check "disk"`) {
+		t.Fatalf("parseDoctorCategories(disk) error = %v", err)
+	}
+}
+
+func TestDoctorErrorAcrossCategoriesIsGeneric(t *testing.T) {
+	var report doctorReport
+	report.add(doctorPlatform, checkFail, "backends", "no notification backend available", true)
+	report.add(doctorCodex, checkWarn, "config", "notify hook not configured", true)
+	if err := doctorError(report); exitCodeFor(err) != exitFailure || !strings.Contains(err.Error(), "in platform, codex") {
+		t.Fatalf("doctorError() = %v (exit %d), want exit 1 naming both", err, exitCodeFor(err))
+	}
+}
+
+func TestDoctorCheckFlag(t *testing.T) {
+	home, _ := useTempHome(t)
+	t.Setenv("CODEX_HOME", filepath.Join(home, ".codex"))
+
+	if err := runDoctor([]string{"--check", "daemon", "--quiet"}); err != nil {
+		t.Fatalf("doctor --check daemon error = %v", err)
+	}
+	// No Codex config exists, which only the codex category reports.
+	if err := runDoctor([]string{"--check", "codex", "--quiet"}); exitCodeFor(err) != exitDoctorCodex {
+		t.Fatalf("doctor --check codex = %v (exit %d), want exit %d", err, exitCodeFor(err), exitDoctorCodex)
+	}
+	if err := runDoctor([]string{"--check", "disk"}); exitCodeFor(err) != exitUsage {
+		t.Fatalf("doctor --check disk = %v, want a usage error", err)
+	}
+}
+
+func TestDoctorCheckRunsOnlySelectedProbes(t *testing.T) {
+	home, _ := useTempHome(t)
+	t.Setenv("CODEX_HOME", filepath.Join(home, ".codex"))
+	bin := filepath.Join(home, "bin")
+	if err := os.MkdirAll(bin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "osascript"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	var probed []string
+	stub(t, &probeSystemEvents, func(string) (string, error) {
+		probed = append(probed, "osascript")
+		return "", nil
+	})
+	stub(t, &readCodexVersion, func() (string, error) {
+		probed = append(probed, "codex")
+		return "codex-cli 0.50.0", nil
+	})
+
+	if err := runDoctor([]string{"--check", "daemon", "--quiet"}); err != nil {
+		t.Fatalf("doctor --check daemon error = %v", err)
+	}
+	if len(probed) != 0 {
+		t.Fatalf("doctor --check daemon ran %v", probed)
+	}
+	_ = runDoctor([]string{"--quiet"})
+	if strings.Join(probed, ",") != "osascript,codex" {
+		t.Fatalf("doctor ran %v, want the System Events and codex probes", probed)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	smtpPasswordEnv = "CODEX_NOTIFY_SMTP_PASSWORD"
+	smtpTimeout     = 10 * time.Second
+)
+
+// emailSecurityModes are how the SMTP connection is protected: STARTTLS on
+// the submission port, TLS from the first byte, or a plain local relay.
+var emailSecurityModes = []string{"starttls", "tls", "none"}
+
+// emailConfig is the [email] table of config.toml. Like the incident sinks
+// it only sends agent-error unless events says otherwise, so a failed
+// overnight run is waiting in the inbox.
+type emailConfig struct {
+	Host     string
+	Port     int64
+	Security string
+	Username string
+	// Password is a credential; CODEX_NOTIFY_SMTP_PASSWORD overrides it.
+	Password string
+	From     string
+	To       []string
+	Events   []string
+}
+
+func (c emailConfig) configured() bool {
+	return c.Host != "" || c.From != "" || len(c.To) > 0
+}
+
+func (c emailConfig) enabled() bool {
+	return c.Host != "" && c.From != "" && len(c.To) > 0
+}
+
+func (c emailConfig) wants(event string) bool {
+	return incidentWants(c.Events, event)
+}
+
+func (c emailConfig) password() string {
+	if v := getenv(smtpPasswordEnv); v != "" {
+		return secretValue(v)
+	}
+	return secretValue(c.Password)
+}
+
+func (c emailConfig) security() string {
+	if c.Security == "" {
+		return "starttls"
+	}
+	return c.Security
+}
+
+func (c emailConfig) port() int64 {
+	if c.Port != 0 {
+		return c.Port
+	}
+	switch c.security() {
+	case "tls":
+		return 465
+	case "none":
+		return 25
+	}
+	return 587
+}
+
+func (c emailConfig) addr() string {
+	return net.JoinHostPort(c.Host, strconv.FormatInt(c.port(), 10))
+}
+
+// set parses one `email.<key>` entry.
+func (c *emailConfig) set(key string, value any) error {
+	switch key {
+	case "events":
+		events, err := parseEventList("email.events", value)
+		if err != nil {
+			return err
+		}
+		c.Events = events
+		return nil
+	case "port":
+		n, ok := value.(int64)
+		if !ok || n < 1 || n > 65535 {
+			return errors.New("email.port must be a port number")
+		}
+		c.Port = n
+		return nil
+	case "to":
+		var list []any
+		switch v := value.(type) {
+		case string:
+			list = []any{v}
+		case []any:
+			list = v
+		default:
+			return errors.New("email.to must be an address or an array of addresses")
+		}
+		c.To = nil
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return errors.New("email.to entries must be strings")
+			}
+			if _, err := mail.ParseAddress(s); err != nil {
+				return fmt.Errorf("email.to: %q is not an email address", s)
+			}
+			c.To = append(c.To, strings.TrimSpace(s))
+		}
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("email.%s must be a string", key)
+	}
+	switch key {
+	case "host":
+		c.Host = strings.TrimSpace(s)
+	case "security":
+		if !containsString(emailSecurityModes, s) {
+			return fmt.Errorf("email.security must be one of %s", strings.Join(emailSecurityModes, ", "))
+		}
+		c.Security = s
+	case "username":
+		c.Username = s
+	case "password":
+		c.Password = s
+	case "from":
+		if _, err := mail.ParseAddress(s); err != nil {
+			return fmt.Errorf("email.from: %q is not an email address", s)
+		}
+		c.From = strings.TrimSpace(s)
+	default:
+		return unknownSetting("email." + key)
+	}
+	return nil
+}
+
+// validateEmail catches a half-written [email] table, which would
+// otherwise stay silent until the run it was meant for fails.
+func validateEmail(c emailConfig) error {
+	if c.configured() && !c.enabled() {
+		return errors.New("email: host, from, and to are all required")
+	}
+	return nil
+}
+
+// buildEmailMessage returns the RFC 5322 message for an event: the
+// incident summary as the subject and the plain-text message, followed by
+// where it happened, as a quoted-printable body.
+func buildEmailMessage(cfg emailConfig, payload map[string]any, now time.Time) []byte {
+	title, message := incidentSummary(payload)
+	subject := strings.Join(strings.Fields(title), " ")
+
+	var body bytes.Buffer
+	body.WriteString(message)
+	body.WriteString("\n\n")
+	details := [][2]string{{"Event", payloadEventName(payload)}, {"Directory", payloadCwd(payload)}, {"Thread", payloadThreadID(payload)}}
+	if host, err := os.Hostname(); err == nil {
+		details = append(details, [2]string{"Host", host})
+	}
+	for _, d := range details {
+		if d[1] != "" {
+			fmt.Fprintf(&body, "%s: %s\n", d[0], d[1])
+		}
+	}
+
+	var msg bytes.Buffer
+	for _, h := range [][2]string{
+		{"From", cfg.From},
+		{"To", strings.Join(cfg.To, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+		{"X-Codex-Notify-Event", payloadEventName(payload)},
+	} {
+		fmt.Fprintf(&msg, "%s: %s\r\n", h[0], h[1])
+	}
+	msg.WriteString("\r\n")
+	qp := quotedprintable.NewWriter(&msg)
+	_, _ = qp.Write([]byte(strings.ReplaceAll(body.String(), "\n", "\r\n")))
+	_ = qp.Close()
+	return msg.Bytes()
+}
+
+// sendEmail delivers msg through the configured SMTP server. Connection
+// failures and 4xx replies are transient; the server asked to try later.
+func sendEmail(cfg emailConfig, msg []byte) error {
+	err := smtpSend(cfg, msg)
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code/100 == 4 {
+		return transient(err)
+	}
+	return err
+}
+
+// smtpSend runs one SMTP session. Plain-text auth is refused by net/smtp
+// over an unencrypted connection to anything but localhost, so
+// security = "none" only suits a local relay.
+func smtpSend(cfg emailConfig, msg []byte) error {
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+	var conn net.Conn
+	var err error
+	if cfg.security() == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", cfg.addr(), tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", cfg.addr())
+	}
+	if err != nil {
+		return transient(fmt.Errorf("connect to %s: %w", cfg.addr(), err))
+	}
+	_ = conn.SetDeadline(time.Now().Add(smtpTimeout))
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if cfg.security() == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not offer STARTTLS; set email.security", cfg.Host)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.password(), cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP login failed: %w", err)
+		}
+	}
+	from, _ := mail.ParseAddress(cfg.From)
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range cfg.To {
+		addr, _ := mail.ParseAddress(to)
+		if err := client.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// addEmailDoctorCheck reports the configured email sink.
+func addEmailDoctorCheck(report *doctorReport, cfg emailConfig) {
+	if !cfg.enabled() {
+		return
+	}
+	events := cfg.Events
+	if events == nil {
+		events = defaultIncidentEvents
+	}
+	report.add(doctorSinks, checkOK, "email", fmt.Sprintf("%s (%s) to %s (%s)", cfg.addr(), cfg.security(), strings.Join(cfg.To, ", "), strings.Join(events, ", ")), false)
+}
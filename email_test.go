@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildEmailMessage(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "0")
+	cfg := emailConfig{From: "codex-notify <me@example.com>", To: []string{"me@example.com", "ops@example.com"}}
+	raw := buildEmailMessage(cfg, map[string]any{
+		"type":      "agent-error",
+		"thread-id": "t-1",
+		"cwd":       "/src/batch",
+		"message":   "stream disconnected **before** completion",
+	}, time.Date(2026, 3, 1, 2, 30, 0, 0, time.UTC))
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if got := msg.Header.Get("To"); got != "me@example.com, ops@example.com" {
+		t.Fatalf("To = %q", got)
+	}
+	if got := msg.Header.Get("Subject"); !strings.HasSuffix(got, "Codex: Error") {
+		t.Fatalf("Subject = %q", got)
+	}
+	if got := msg.Header.Get("Date"); got != "Sun, 01 Mar 2026 02:30:00 +0000" {
+		t.Fatalf("Date = %q", got)
+	}
+	body := string(raw[strings.Index(string(raw), "\r\n\r\n"):])
+	for _, want := range []string{"stream disconnected before completion", "Directory: /src/batch", "Thread: t-1"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestEmailConfig(t *testing.T) {
+	cfg, err := parseUserConfig([]byte("[email]\nhost = \"smtp.example.com\"\nsecurity = \"tls\"\nfrom = \"me@example.com\"\nto = \"me@example.com\"\n"))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	if !cfg.Email.enabled() || cfg.Email.addr() != "smtp.example.com:465" || len(cfg.Email.To) != 1 {
+		t.Fatalf("email = %+v", cfg.Email)
+	}
+	if !cfg.Email.wants("agent-error") || cfg.Email.wants("agent-turn-complete") {
+		t.Fatalf("email events: %+v", cfg.Email.Events)
+	}
+
+	for _, bad := range []string{
+		"[email]\nhost = \"smtp.example.com\"\n",
+		"[email]\nhost = \"h\"\nfrom = \"not an address\"\nto = \"me@example.com\"\n",
+		"[email]\nsecurity = \"ssl\"\n",
+		"[email]\nport = 70000\n",
+		"[email]\nto = [1]\n",
+	} {
+		if _, err := parseUserConfig([]byte(bad)); err == nil {
+			t.Errorf("parseUserConfig(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestSendEmail(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	got := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 test ESMTP\r\n")
+		var lines []string
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case inData && line == ".":
+				inData = false
+				fmt.Fprint(conn, "250 queued\r\n")
+			case inData:
+			case strings.HasPrefix(line, "EHLO"):
+				fmt.Fprint(conn, "250 test\r\n")
+			case line == "DATA":
+				inData = true
+				fmt.Fprint(conn, "354 go ahead\r\n")
+			case line == "QUIT":
+				fmt.Fprint(conn, "221 bye\r\n")
+				got <- lines
+				return
+			default:
+				fmt.Fprint(conn, "250 ok\r\n")
+			}
+		}
+		got <- lines
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	cfg, err := parseUserConfig([]byte(fmt.Sprintf("[email]\nhost = %q\nport = %s\nsecurity = \"none\"\nfrom = \"codex-notify <me@example.com>\"\nto = [\"ops@example.com\"]\n", host, port)))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	sinks := remoteSinks(cfg, map[string]any{"type": "agent-error", "message": "boom"})
+	if len(sinks) != 1 || sinks[0].Name != "email" {
+		t.Fatalf("remoteSinks() = %+v", sinks)
+	}
+	if err := sinks[0].Publish(); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	transcript := strings.Join(<-got, "\n")
+	for _, want := range []string{"MAIL FROM:<me@example.com>", "RCPT TO:<ops@example.com>", "boom"} {
+		if !strings.Contains(transcript, want) {
+			t.Errorf("transcript missing %q:\n%s", want, transcript)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// The daemon answers hooks forwarded from other shells, each with its own
+// CODEX_NOTIFY_* and tmux variables. Code reads them through getenv and
+// lookupEnv instead of os.Getenv, and the daemon hands each hook's
+// variables to them with withHookEnv, so its own environment never changes
+// and one hook never sees another's project, backend, or mute settings.
+
+var (
+	// daemonWork serializes the daemon: one forwarded hook or one round of
+	// background work at a time. Background work runs with no hook
+	// environment, so it sees the daemon's own variables.
+	daemonWork sync.Mutex
+	// activeHookEnv holds the forwarded variables of the hook being
+	// handled, or nil outside one.
+	activeHookEnv atomic.Pointer[map[string]string]
+)
+
+// withHookEnv handles a hook forwarded with env: fn, and every goroutine
+// it waits for, read env's forwarded variables in place of the daemon's.
+func withHookEnv(env map[string]string, fn func()) {
+	daemonWork.Lock()
+	defer daemonWork.Unlock()
+	asHook(env, fn)
+}
+
+// asHook is withHookEnv for a caller that already holds daemonWork, such
+// as the exit watcher reporting on the hook that last ran a Codex process.
+func asHook(env map[string]string, fn func()) {
+	forwarded := map[string]string{}
+	for key, value := range env {
+		if isForwardedEnv(key) {
+			forwarded[key] = value
+		}
+	}
+	previous := activeHookEnv.Swap(&forwarded)
+	defer activeHookEnv.Store(previous)
+	fn()
+}
+
+// inDaemonWork runs a round of the daemon's background work between
+// forwarded hooks.
+func inDaemonWork(fn func()) {
+	daemonWork.Lock()
+	defer daemonWork.Unlock()
+	fn()
+}
+
+// lookupEnv is os.LookupEnv, except that while the daemon handles a
+// forwarded hook the forwarded variables are the hook's.
+func lookupEnv(key string) (string, bool) {
+	if env := activeHookEnv.Load(); env != nil && isForwardedEnv(key) {
+		value, ok := (*env)[key]
+		return value, ok
+	}
+	return os.LookupEnv(key)
+}
+
+func getenv(key string) string {
+	value, _ := lookupEnv(key)
+	return value
+}
+
+// hookEnviron is os.Environ as getenv sees it, for child processes that
+// read codex-notify settings themselves.
+func hookEnviron() []string {
+	env := activeHookEnv.Load()
+	if env == nil {
+		return os.Environ()
+	}
+	var out []string
+	for _, kv := range os.Environ() {
+		if key, _, _ := strings.Cut(kv, "="); !isForwardedEnv(key) {
+			out = append(out, kv)
+		}
+	}
+	for key, value := range *env {
+		out = append(out, key+"="+value)
+	}
+	return out
+}
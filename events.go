@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	eventsFilename = "events.jsonl"
+
+	// eventTimeLayout is RFC 3339 in UTC with a fixed nine-digit fraction, so
+	// event times sort the same as strings and as times.
+	eventTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+)
+
+// eventRecord is one line of the NDJSON event log written by hook.
+type eventRecord struct {
+	Time   string `json:"time"`
+	Event  string `json:"event"`
+	Thread string `json:"thread_id,omitempty"`
+	// Session links events to a `codex-notify wrap` run.
+	Session string `json:"session,omitempty"`
+	Cwd     string `json:"cwd,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message,omitempty"`
+	Status  string `json:"status"`
+	// LatencyMS is how long a sent event took from the hook starting to
+	// the desktop backend taking the notification.
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+}
+
+func eventsPath() (string, error) {
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, eventsFilename), nil
+}
+
+// appendJSONLine appends v to an NDJSON log such as events.jsonl,
+// receipts.jsonl, or audit.jsonl. The log is rotated like the log file, so
+// readers that scan it whole stay fast.
+func appendJSONLine(path string, v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", filepath.Base(path), err)
+	}
+	rotateLogFile(path)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, privateFileMode)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", filepath.Base(path), err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// recordHookEvent appends the outcome of one hook invocation to the event
+// log. Like receipts, the log is best effort and never fails the hook.
+func recordHookEvent(payload map[string]any, status string) {
+	recordHookOutcome(payload, status, 0)
+}
+
+// recordHookSent logs a sent event with the time since the hook started
+// on it, which `stats` reports as the desktop latency.
+func recordHookSent(payload map[string]any, started time.Time) {
+	recordHookOutcome(payload, "sent", time.Since(started))
+}
+
+func recordHookOutcome(payload map[string]any, status string, latency time.Duration) {
+	path, err := eventsPath()
+	if err != nil {
+		return
+	}
+	title, message := renderPayloadMessage(payload)
+	event := payloadEventName(payload)
+	if event == "" {
+		event = "unknown"
+	}
+	now := time.Now()
+	rec := eventRecord{
+		Event:     event,
+		Thread:    payloadThreadID(payload),
+		Session:   getenv(wrapSessionEnv),
+		Cwd:       payloadCwd(payload),
+		Title:     title,
+		Message:   message,
+		Status:    status,
+		LatencyMS: float64(latency.Microseconds()) / 1000,
+	}
+	_ = appendEvent(path, &rec, now)
+	recordWidgetEvent(rec, now)
+	logInfof("hook %s thread=%s: %s", event, rec.Thread, status)
+}
+
+// appendEvent stamps rec with a UTC time later than every event already in
+// the log, even if the wall clock stepped back, and appends it.
+func appendEvent(path string, rec *eventRecord, now time.Time) error {
+	if unlock, err := lockState(); err == nil {
+		defer unlock()
+	}
+	stamp := now.UTC()
+	if last, ok := lastEventTime(path); ok && !stamp.After(last) {
+		stamp = last.Add(time.Nanosecond)
+	}
+	rec.Time = stamp.Format(eventTimeLayout)
+	return appendJSONLine(path, rec)
+}
+
+// lastEventTime reads the time of the last complete line in the log.
+func lastEventTime(path string) (time.Time, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	const window = 64 * 1024
+	start := max(info.Size()-window, 0)
+	buf := make([]byte, info.Size()-start)
+	if _, err := f.ReadAt(buf, start); err != nil && err != io.EOF {
+		return time.Time{}, false
+	}
+	lines := splitLines(buf)
+	for i := len(lines) - 1; i >= 0; i-- {
+		var rec eventRecord
+		if json.Unmarshal([]byte(lines[i]), &rec) != nil {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339Nano, rec.Time); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func runTail(args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	lines := fs.Int("n", 10, "number of past events to print before following")
+	follow := fs.Bool("follow", true, "keep waiting for new events")
+	raw := fs.Bool("raw", false, "print raw NDJSON lines")
+	noColor := fs.Bool("no-color", false, "disable colors")
+	utc := fs.Bool("utc", false, "print full UTC timestamps")
+	relative := fs.Bool("relative", false, "print times relative to now, e.g. 3m ago")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	style := timeLocal
+	switch {
+	case *utc && *relative:
+		return usageError(errors.New("--utc and --relative cannot be combined"))
+	case *utc:
+		style = timeUTC
+	case *relative:
+		style = timeRelative
+	}
+
+	path, err := eventsPath()
+	if err != nil {
+		return err
+	}
+
+	color := !*noColor && !*raw && getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	printLine := func(line string) {
+		if *raw {
+			fmt.Fprintln(os.Stdout, line)
+			return
+		}
+		var rec eventRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return
+		}
+		fmt.Fprintln(os.Stdout, formatEventRecord(rec, style, color))
+	}
+
+	offset, err := printLastLines(path, *lines, printLine)
+	if err != nil {
+		return err
+	}
+	if !*follow {
+		return nil
+	}
+
+	for {
+		time.Sleep(500 * time.Millisecond)
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if info.Size() < offset {
+			// The log was truncated or replaced; start over from the top.
+			offset = 0
+		}
+		if info.Size() == offset {
+			continue
+		}
+		offset, err = printFrom(path, offset, printLine)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// printLastLines prints the last n complete lines of path and returns the
+// offset just past them. A missing file is treated as empty.
+func printLastLines(path string, n int, printLine func(string)) (int64, error) {
+	content, err := readFileMaybe(path)
+	if err != nil {
+		return 0, err
+	}
+	end := int64(len(content))
+	if i := strings.LastIndexByte(string(content), '\n'); i >= 0 {
+		end = int64(i + 1)
+	} else {
+		end = 0
+	}
+
+	all := splitLines(content[:end])
+	if n >= 0 && len(all) > n {
+		all = all[len(all)-n:]
+	}
+	for _, line := range all {
+		if strings.TrimSpace(line) != "" {
+			printLine(line)
+		}
+	}
+	return end, nil
+}
+
+func printFrom(path string, offset int64, printLine func(string)) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return offset, nil
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return offset, fmt.Errorf("seek %s: %w", path, err)
+	}
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// Leave partial lines for the next poll.
+			return offset, nil
+		}
+		offset += int64(len(line))
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			printLine(trimmed)
+		}
+	}
+}
+
+const (
+	ansiReset  = "\033[0m"
+	ansiDim    = "\033[2m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+)
+
+// timeStyle selects how event times are shown. The log itself is always UTC.
+type timeStyle int
+
+const (
+	timeLocal timeStyle = iota
+	timeUTC
+	timeRelative
+)
+
+// formatEventTime renders a logged time in the local time zone (with the
+// date when it is not today), as full UTC for scripts, or relative to now.
+// Unparseable values are returned as is.
+func formatEventTime(value string, style timeStyle, now time.Time) string {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return value
+	}
+	switch style {
+	case timeUTC:
+		return t.UTC().Format(time.RFC3339)
+	case timeRelative:
+		return relativeTime(t, now)
+	}
+	local, today := t.In(now.Location()), now
+	if local.Year() == today.Year() && local.YearDay() == today.YearDay() {
+		return local.Format("15:04:05")
+	}
+	return local.Format("Jan 02 15:04:05")
+}
+
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < 10*time.Second:
+		// Clock skew can put an event slightly in the future.
+		return "just now"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d/time.Second))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+	}
+}
+
+func formatEventRecord(rec eventRecord, style timeStyle, color bool) string {
+	ts := formatEventTime(rec.Time, style, time.Now())
+
+	paint := func(code, s string) string {
+		if !color || s == "" {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	eventColor := ansiCyan
+	switch rec.Event {
+	case "approval-requested":
+		eventColor = ansiYellow
+	case "agent-error":
+		eventColor = ansiRed
+	case "agent-turn-complete":
+		eventColor = ansiGreen
+	}
+
+	parts := []string{paint(ansiDim, ts), paint(eventColor, fmt.Sprintf("%-20s", rec.Event))}
+	if rec.Cwd != "" {
+		parts = append(parts, "["+filepath.Base(rec.Cwd)+"]")
+	}
+	if rec.Thread != "" {
+		parts = append(parts, paint(ansiDim, "thread "+rec.Thread))
+	}
+	if rec.Status != "" && rec.Status != "sent" {
+		parts = append(parts, paint(ansiDim, "("+rec.Status+")"))
+	}
+	if rec.Message != "" {
+		parts = append(parts, rec.Message)
+	}
+	return strings.Join(parts, "  ")
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFormatEventRecord(t *testing.T) {
+	rec := eventRecord{
+		Time:    "not-a-time",
+		Event:   "approval-requested",
+		Thread:  "t1",
+		Cwd:     "/src/app",
+		Message: "run rm?",
+		Status:  "muted",
+	}
+	got := formatEventRecord(rec, timeLocal, false)
+	want := "not-a-time  approval-requested    [app]  thread t1  (muted)  run rm?"
+	if got != want {
+		t.Fatalf("formatEventRecord() = %q, want %q", got, want)
+	}
+}
+
+func TestPrintLastLinesAndFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), eventsFilename)
+	if err := os.WriteFile(path, []byte("a\nb\nc\npartial"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var got []string
+	collect := func(line string) { got = append(got, line) }
+
+	offset, err := printLastLines(path, 2, collect)
+	if err != nil {
+		t.Fatalf("printLastLines() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != "b" || got[1] != "c" {
+		t.Fatalf("printLastLines() printed %v, want [b c]", got)
+	}
+	if offset != int64(len("a\nb\nc\n")) {
+		t.Fatalf("offset = %d, want end of last complete line", offset)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	_, _ = f.WriteString(" line\nd\n")
+	_ = f.Close()
+
+	got = nil
+	if _, err := printFrom(path, offset, collect); err != nil {
+		t.Fatalf("printFrom() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != "partial line" || got[1] != "d" {
+		t.Fatalf("printFrom() printed %v, want [partial line d]", got)
+	}
+}
+
+func TestFormatEventTime(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, loc)
+	stamp := now.Add(-3 * time.Minute).UTC().Format(eventTimeLayout)
+
+	tests := []struct {
+		name  string
+		value string
+		style timeStyle
+		want  string
+	}{
+		{"local today", stamp, timeLocal, "11:57:00"},
+		{"local earlier day", "2026-03-08T01:02:03Z", timeLocal, "Mar 08 10:02:03"},
+		{"utc", stamp, timeUTC, "2026-03-10T02:57:00Z"},
+		{"relative", stamp, timeRelative, "3m ago"},
+		{"relative days", "2026-03-08T01:02:03Z", timeRelative, "2d ago"},
+		{"future is just now", now.Add(time.Second).Format(time.RFC3339), timeRelative, "just now"},
+		{"legacy seconds", "2026-03-10T02:59:30Z", timeRelative, "30s ago"},
+		{"unparseable", "not-a-time", timeUTC, "not-a-time"},
+	}
+	for _, tt := range tests {
+		if got := formatEventTime(tt.value, tt.style, now); got != tt.want {
+			t.Errorf("%s: formatEventTime(%q) = %q, want %q", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestAppendEventKeepsTimesMonotonic(t *testing.T) {
+	useTempHome(t)
+	path := filepath.Join(t.TempDir(), eventsFilename)
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	first := eventRecord{Event: "a"}
+	if err := appendEvent(path, &first, now); err != nil {
+		t.Fatalf("appendEvent: %v", err)
+	}
+	// The clock stepped back a minute.
+	second := eventRecord{Event: "b"}
+	if err := appendEvent(path, &second, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("appendEvent: %v", err)
+	}
+
+	if first.Time != "2026-03-10T12:00:00.000000000Z" {
+		t.Fatalf("first time = %q", first.Time)
+	}
+	if second.Time <= first.Time {
+		t.Fatalf("second time %q does not sort after %q", second.Time, first.Time)
+	}
+}
+
+func TestEventLogIsRotated(t *testing.T) {
+	useTempHome(t)
+	path, err := eventsPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, logMaxSize), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	recordHookEvent(map[string]any{"type": "agent-turn-complete", "thread-id": "t1"}, "sent")
+	if info, err := os.Stat(path + ".1"); err != nil || info.Size() != logMaxSize {
+		t.Fatalf("rotated log = %v, %v; want the full one moved aside", info, err)
+	}
+	content, err := os.ReadFile(path)
+	if lines := splitLines(content); err != nil || len(lines) != 1 {
+		t.Fatalf("events.jsonl has %d lines after rotation, want the new event alone", len(lines))
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// disabledEventsEnv lists the events that get no desktop notification,
+// comma-separated. When set it replaces the [events] table.
+const disabledEventsEnv = "CODEX_NOTIFY_DISABLED_EVENTS"
+
+// eventEnabled reports whether event gets a desktop notification. The
+// [events] table maps event names (or "default") to true or false, so
+// `default = false` with a few events turned back on is an allowlist.
+func (c userConfig) eventEnabled(event string) bool {
+	if raw, set := lookupEnv(disabledEventsEnv); set {
+		for _, name := range strings.Split(raw, ",") {
+			if strings.TrimSpace(name) == event {
+				return false
+			}
+		}
+		return true
+	}
+	if enabled, ok := c.Events[event]; ok {
+		return enabled
+	}
+	if enabled, ok := c.Events["default"]; ok {
+		return enabled
+	}
+	return true
+}
+
+// describeEventToggles summarizes the toggles for doctor, or "" when every
+// event is on.
+func (c userConfig) describeEventToggles() string {
+	if raw, set := lookupEnv(disabledEventsEnv); set {
+		if strings.TrimSpace(raw) == "" {
+			return ""
+		}
+		return "off: " + raw + " (" + disabledEventsEnv + ")"
+	}
+	var on, off []string
+	for event, enabled := range c.Events {
+		if event == "default" {
+			continue
+		}
+		if enabled {
+			on = append(on, event)
+		} else {
+			off = append(off, event)
+		}
+	}
+	sort.Strings(on)
+	sort.Strings(off)
+	if enabled, ok := c.Events["default"]; ok && !enabled {
+		if len(on) == 0 {
+			return "all events off"
+		}
+		return "only " + strings.Join(on, ", ")
+	}
+	if len(off) == 0 {
+		return ""
+	}
+	return "off: " + strings.Join(off, ", ")
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEventEnabled(t *testing.T) {
+	t.Setenv(disabledEventsEnv, "")
+	os.Unsetenv(disabledEventsEnv)
+	cfg, err := parseUserConfig([]byte("[events]\ndefault = false\napproval-requested = true\nagent-error = true\n"))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	for event, want := range map[string]bool{"approval-requested": true, "agent-error": true, "agent-turn-complete": false} {
+		if got := cfg.eventEnabled(event); got != want {
+			t.Errorf("eventEnabled(%q) = %v, want %v", event, got, want)
+		}
+	}
+	if got := cfg.describeEventToggles(); got != "only agent-error, approval-requested" {
+		t.Errorf("describeEventToggles() = %q", got)
+	}
+
+	// The variable replaces the table.
+	t.Setenv(disabledEventsEnv, "agent-turn-complete, agent-error")
+	if cfg.eventEnabled("agent-error") || !cfg.eventEnabled("task-started") {
+		t.Error("CODEX_NOTIFY_DISABLED_EVENTS not honored")
+	}
+
+	if _, err := parseUserConfig([]byte("[events]\nagent-error = \"no\"\n")); err == nil {
+		t.Error("parseUserConfig() accepted a non-boolean toggle")
+	}
+}
+
+func TestDisabledEventSendsNothing(t *testing.T) {
+	_, dir := useTempHome(t)
+	t.Setenv(disabledEventsEnv, "agent-turn-complete")
+
+	result, err := deliverHookPayload(map[string]any{"type": "agent-turn-complete", "thread-id": "t1"})
+	if err != nil || result.Status != "disabled" {
+		t.Fatalf("deliverHookPayload() = %+v, %v", result, err)
+	}
+	if reqs, err := buildHookNotifications(map[string]any{"type": "agent-turn-complete"}); err != nil || len(reqs) != 0 {
+		t.Fatalf("buildHookNotifications() = %+v, %v, want none", reqs, err)
+	}
+	if _, err := deliverHookPayload(map[string]any{"type": "agent-error", "thread-id": "t1"}); err != nil {
+		t.Fatal(err)
+	}
+	if captured := readCaptured(t, dir); len(captured) != 1 {
+		t.Fatalf("captured = %+v, want only the error", captured)
+	}
+}
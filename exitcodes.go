@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"strings"
+)
+
+// Exit codes are part of the CLI contract (see README "Exit codes"); scripts
+// depend on them, so existing values must never be renumbered.
+const (
+	exitOK         = 0
+	exitFailure    = 1 // unclassified failure, or doctor checks failed
+	exitUsage      = 2 // unknown command, flag, or missing argument
+	exitConfig     = 3 // Codex config / codex-notify settings cannot be read, parsed, or updated
+	exitPermission = 4 // filesystem or macOS privacy (Accessibility/Automation) permission denied
+	exitBackend    = 5 // no notifier could deliver the notification
+	exitPartial    = 6 // some notifications were delivered, others failed
+
+	// doctor exits with one of these when all its problems are in one
+	// category (see doctorCategories), and exitFailure otherwise.
+	exitDoctorPlatform    = 10
+	exitDoctorPermissions = 11
+	exitDoctorHelper      = 12
+	exitDoctorCodex       = 13
+	exitDoctorDaemon      = 14
+	exitDoctorSinks       = 15
+	exitDoctorSettings    = 16
+)
+
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+func usageError(err error) error      { return withExitCode(exitUsage, err) }
+func configError(err error) error     { return withExitCode(exitConfig, err) }
+func permissionError(err error) error { return withExitCode(exitPermission, err) }
+func backendError(err error) error    { return withExitCode(exitBackend, err) }
+func partialError(err error) error    { return withExitCode(exitPartial, err) }
+
+// exitCodeFor maps an error to its process exit code. Permission failures win
+// over the classification of the surrounding operation so that, for example,
+// an unwritable config.toml is reported as a permission problem.
+func exitCodeFor(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	if errors.Is(err, os.ErrPermission) {
+		return exitPermission
+	}
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	return exitFailure
+}
+
+// parseFlags parses args into fs and classifies failures as usage errors.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	return nil
+}
+
+// isAppleEventsPermissionDenied reports whether osascript output indicates a
+// missing Accessibility or Automation grant rather than a script error.
+func isAppleEventsPermissionDenied(output string) bool {
+	out := strings.ToLower(output)
+	for _, marker := range []string{"(-1743)", "(-25211)", "(-1719)", "not allowed assistive access", "not authorized to send apple events"} {
+		if strings.Contains(out, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, exitOK},
+		{"plain", errors.New("boom"), exitFailure},
+		{"usage", usageError(errors.New("unknown command: x")), exitUsage},
+		{"wrapped config", fmt.Errorf("init: %w", configError(errors.New("bad config"))), exitConfig},
+		{"backend", backendError(errors.New("no notifier")), exitBackend},
+		{"partial", partialError(errors.New("sent 1 of 2")), exitPartial},
+		{"permission beats config", configError(fmt.Errorf("write config: %w", os.ErrPermission)), exitPermission},
+	}
+	for _, tc := range cases {
+		if got := exitCodeFor(tc.err); got != tc.want {
+			t.Errorf("%s: exitCodeFor() = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestIsAppleEventsPermissionDenied(t *testing.T) {
+	if !isAppleEventsPermissionDenied("execution error: osascript is not allowed assistive access. (-25211)") {
+		t.Fatalf("expected assistive access error to be a permission denial")
+	}
+	if isAppleEventsPermissionDenied("execution error: Can't get application id \"x\". (-1728)") {
+		t.Fatalf("expected generic script error not to be a permission denial")
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// approvalDeadlineKeys name an absolute deadline, as RFC 3339 or unix
+// seconds; approvalTimeoutKeys name seconds left from when the hook runs.
+var (
+	approvalDeadlineKeys = []string{"expires-at", "expires_at", "deadline", "approval-deadline", "approval_deadline"}
+	approvalTimeoutKeys  = []string{"timeout-seconds", "timeout_seconds", "approval-timeout-seconds", "approval_timeout_seconds"}
+)
+
+// keySendingActions are the actions that type into the session. Once an
+// approval has expired they would answer whatever Codex is doing now.
+var keySendingActions = map[string]bool{
+	"approve":            true,
+	"reject":             true,
+	"reject-with-reason": true,
+	"choose":             true,
+	"submit":             true,
+}
+
+// payloadApprovalDeadline returns when the approval stops waiting, if the
+// payload says so.
+func payloadApprovalDeadline(payload map[string]any, now time.Time) (time.Time, bool) {
+	for _, key := range approvalDeadlineKeys {
+		switch v := payload[key].(type) {
+		case string:
+			v = strings.TrimSpace(v)
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				return t, true
+			}
+			if n, err := strconv.ParseFloat(v, 64); err == nil && n > 0 {
+				return unixSeconds(n), true
+			}
+		case float64:
+			if v > 0 {
+				return unixSeconds(v), true
+			}
+		}
+	}
+	for _, key := range approvalTimeoutKeys {
+		var seconds float64
+		switch v := payload[key].(type) {
+		case string:
+			n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				continue
+			}
+			seconds = n
+		case float64:
+			seconds = v
+		default:
+			continue
+		}
+		if seconds > 0 {
+			return now.Add(time.Duration(seconds * float64(time.Second))), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func unixSeconds(v float64) time.Time {
+	sec, frac := math.Modf(v)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// formatCountdown renders time left the way a notification has room for:
+// "45s", "2m30s", "1h05m".
+func formatCountdown(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		m := int(d / time.Minute)
+		s := int((d % time.Minute) / time.Second)
+		if s == 0 {
+			return fmt.Sprintf("%dm", m)
+		}
+		return fmt.Sprintf("%dm%02ds", m, s)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d/time.Hour), int((d%time.Hour)/time.Minute))
+	}
+}
+
+// approvalExpiryLine is the line appended to an approval message that has
+// a deadline.
+func approvalExpiryLine(text notificationStrings, deadline, now time.Time) string {
+	if !now.Before(deadline) {
+		return text.Expired
+	}
+	return fmt.Sprintf(text.ExpiresIn, formatCountdown(deadline.Sub(now)))
+}
+
+// expiringChoices makes every codex-notify action in choices carry the
+// deadline, so a click that arrives late opens the terminal instead of
+// sending keys. After the deadline only Open is offered.
+func expiringChoices(choices []approvalChoice, threadID string, deadline, now time.Time) []approvalChoice {
+	if !now.Before(deadline) {
+		return []approvalChoice{{Label: "Open", Command: buildActionCommand("open", threadID)}}
+	}
+	out := make([]approvalChoice, len(choices))
+	for i, choice := range choices {
+		out[i] = approvalChoice{Label: choice.Label, Command: withExpiresAt(choice.Command, deadline)}
+	}
+	return out
+}
+
+// withExpiresAt appends --expires-at to a command built by
+// buildActionCommand and friends; anything else is returned unchanged.
+func withExpiresAt(command string, deadline time.Time) string {
+	if !strings.HasPrefix(command, actionCommandPrefix()) {
+		return command
+	}
+	return fmt.Sprintf("%s --expires-at %d", command, deadline.Unix())
+}
+
+func actionCommandPrefix() string {
+	executable := appName
+	if path, err := os.Executable(); err == nil && strings.TrimSpace(path) != "" {
+		executable = path
+	}
+	return shellQuote(executable) + " action "
+}
+
+// capTimeoutToDeadline keeps a popup from outliving its approval.
+func capTimeoutToDeadline(timeoutSeconds int, deadline, now time.Time) int {
+	left := int(math.Ceil(deadline.Sub(now).Seconds()))
+	if left < 1 {
+		left = 1
+	}
+	if left < timeoutSeconds {
+		return left
+	}
+	return timeoutSeconds
+}
+
+// approvalExpired reports whether an action carrying --expires-at arrived
+// too late to send keys.
+func approvalExpired(action string, expiresAt int64, now time.Time) bool {
+	return expiresAt > 0 && keySendingActions[action] && !now.Before(time.Unix(expiresAt, 0))
+}
+
+// withExpiryCountdown has the popup for payload count its expiry line down
+// to the deadline, in the language renderPayloadMessage wrote it in.
+func withExpiryCountdown(req *helperRequest, payload map[string]any) {
+	deadline, ok := payloadApprovalDeadline(payload, time.Now())
+	if !ok {
+		return
+	}
+	text := stringsForText(payloadPreviewMessage(payload))
+	req.ExpiresAt = deadline.Unix()
+	req.ExpiresInFormat = text.ExpiresIn
+	req.ExpiredText = text.Expired
+}
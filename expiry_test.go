@@ -0,0 +1,169 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPayloadApprovalDeadline(t *testing.T) {
+	now := time.Unix(1_000, 0)
+	tests := []struct {
+		name    string
+		payload map[string]any
+		want    time.Time
+		ok      bool
+	}{
+		{"none", map[string]any{}, time.Time{}, false},
+		{"rfc3339", map[string]any{"expires-at": "1970-01-01T00:20:00Z"}, time.Unix(1_200, 0), true},
+		{"unix number", map[string]any{"deadline": float64(1_300)}, time.Unix(1_300, 0), true},
+		{"unix string", map[string]any{"expires_at": "1400"}, time.Unix(1_400, 0), true},
+		{"timeout", map[string]any{"timeout-seconds": float64(90)}, time.Unix(1_090, 0), true},
+		{"timeout string", map[string]any{"approval_timeout_seconds": "30"}, time.Unix(1_030, 0), true},
+		{"deadline wins", map[string]any{"deadline": float64(1_300), "timeout-seconds": float64(90)}, time.Unix(1_300, 0), true},
+		{"garbage", map[string]any{"deadline": "soon", "timeout-seconds": float64(-5)}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := payloadApprovalDeadline(tt.payload, now)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("%s: payloadApprovalDeadline() = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormatCountdown(t *testing.T) {
+	tests := map[time.Duration]string{
+		45 * time.Second:               "45s",
+		2 * time.Minute:                "2m",
+		2*time.Minute + 30*time.Second: "2m30s",
+		65 * time.Minute:               "1h05m",
+	}
+	for d, want := range tests {
+		if got := formatCountdown(d); got != want {
+			t.Errorf("formatCountdown(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestExpiringChoices(t *testing.T) {
+	now := time.Unix(1_000, 0)
+	deadline := time.Unix(1_120, 0)
+	choices := append(defaultApprovalChoices("t1"), approvalChoice{Label: "Custom", Command: "echo hi"})
+
+	live := expiringChoices(choices, "t1", deadline, now)
+	if len(live) != len(choices) {
+		t.Fatalf("len(live) = %d, want %d", len(live), len(choices))
+	}
+	for _, choice := range live[:4] {
+		if !strings.HasSuffix(choice.Command, " --expires-at 1120") {
+			t.Errorf("%s command = %q, want --expires-at suffix", choice.Label, choice.Command)
+		}
+	}
+	if live[4].Command != "echo hi" {
+		t.Errorf("user command rewritten to %q", live[4].Command)
+	}
+
+	expired := expiringChoices(choices, "t1", deadline, deadline)
+	if len(expired) != 1 || expired[0].Label != "Open" {
+		t.Fatalf("expired choices = %+v, want only Open", expired)
+	}
+}
+
+func TestBuildHookNotificationsExpiredApproval(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_LANGUAGE", "en")
+	t.Setenv("CODEX_NOTIFY_APPROVAL_UI", "multi")
+	payload := map[string]any{
+		"type":       "approval-requested",
+		"thread-id":  "t1",
+		"message":    "Run rm -rf build?",
+		"expires-at": time.Now().Add(-time.Minute).Format(time.RFC3339),
+	}
+
+	requests, err := buildHookNotifications(payload)
+	if err != nil {
+		t.Fatalf("buildHookNotifications() error = %v", err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("len(requests) = %d, want 1 (no approve/reject after expiry)", len(requests))
+	}
+	if !strings.Contains(requests[0].ExecuteOnClick, "action 'open'") {
+		t.Fatalf("ExecuteOnClick = %q, want open", requests[0].ExecuteOnClick)
+	}
+	if !strings.HasSuffix(requests[0].Message, englishStrings.Expired) {
+		t.Fatalf("Message = %q, want expired note", requests[0].Message)
+	}
+}
+
+func TestBuildHookNotificationsApprovalCountdown(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_LANGUAGE", "en")
+	payload := map[string]any{
+		"type":            "approval-requested",
+		"thread-id":       "t1",
+		"message":         "Run rm -rf build?",
+		"timeout-seconds": float64(150),
+	}
+
+	requests, err := buildHookNotifications(payload)
+	if err != nil {
+		t.Fatalf("buildHookNotifications() error = %v", err)
+	}
+	if !strings.Contains(requests[0].Message, "Expires in 2m") {
+		t.Fatalf("Message = %q, want countdown", requests[0].Message)
+	}
+	if !strings.Contains(requests[0].ExecuteOnClick, "action 'choose'") || !strings.Contains(requests[0].ExecuteOnClick, "--expires-at ") {
+		t.Fatalf("ExecuteOnClick = %q, want choose with --expires-at", requests[0].ExecuteOnClick)
+	}
+}
+
+func TestWithExpiryCountdown(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_LANGUAGE", "en")
+	payload := map[string]any{
+		"type":       "approval-requested",
+		"message":    "Run rm -rf build?",
+		"expires-at": float64(2_000_000_000),
+	}
+
+	var req helperRequest
+	withExpiryCountdown(&req, payload)
+	if req.ExpiresAt != 2_000_000_000 {
+		t.Fatalf("ExpiresAt = %d, want the payload's deadline", req.ExpiresAt)
+	}
+	if req.ExpiresInFormat != englishStrings.ExpiresIn || req.ExpiredText != englishStrings.Expired {
+		t.Fatalf("countdown text = %q / %q, want the English strings", req.ExpiresInFormat, req.ExpiredText)
+	}
+
+	req = helperRequest{}
+	withExpiryCountdown(&req, map[string]any{"type": "approval-requested"})
+	if req.ExpiresAt != 0 || req.ExpiresInFormat != "" {
+		t.Fatalf("request without a deadline = %+v, want no countdown", req)
+	}
+}
+
+func TestApprovalExpired(t *testing.T) {
+	now := time.Unix(1_000, 0)
+	if approvalExpired("approve", 0, now) {
+		t.Error("action without --expires-at expired")
+	}
+	if approvalExpired("approve", 1_001, now) {
+		t.Error("approve before the deadline expired")
+	}
+	if !approvalExpired("approve", 1_000, now) {
+		t.Error("approve at the deadline not expired")
+	}
+	if approvalExpired("open", 500, now) {
+		t.Error("open should never expire")
+	}
+}
+
+func TestCapTimeoutToDeadline(t *testing.T) {
+	now := time.Unix(1_000, 0)
+	if got := capTimeoutToDeadline(120, time.Unix(1_030, 0), now); got != 30 {
+		t.Errorf("capTimeoutToDeadline(near) = %d, want 30", got)
+	}
+	if got := capTimeoutToDeadline(120, time.Unix(2_000, 0), now); got != 120 {
+		t.Errorf("capTimeoutToDeadline(far) = %d, want 120", got)
+	}
+	if got := capTimeoutToDeadline(120, time.Unix(900, 0), now); got != 1 {
+		t.Errorf("capTimeoutToDeadline(past) = %d, want 1", got)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// failBackendEnv is a testing aid: it makes the named desktop backends and
+// remote sinks fail without running them, so the fallback chain, the sink
+// retry queue, and the exit codes can be exercised on any machine. It is a
+// comma-separated list of names as doctor and sendNotification report
+// them ("popup", "terminal-notifier", "ntfy", "webhook deploy", ...), or
+// "all". A ":transient" suffix makes the failure one the retry queue keeps.
+const failBackendEnv = "CODEX_NOTIFY_FAIL_BACKEND"
+
+// injectedFailureError is the error a failed backend reports.
+type injectedFailureError struct{ name string }
+
+func (e injectedFailureError) Error() string {
+	return fmt.Sprintf("injected failure for %s (%s)", e.name, failBackendEnv)
+}
+
+// injectedFailure returns the error name should fail with, or nil.
+func injectedFailure(name string) error {
+	for _, entry := range strings.Split(getenv(failBackendEnv), ",") {
+		entry = strings.TrimSpace(entry)
+		target, kind, _ := strings.Cut(entry, ":")
+		target = strings.TrimSpace(target)
+		if target == "" || (target != "all" && !strings.EqualFold(target, name)) {
+			continue
+		}
+		if strings.TrimSpace(kind) == "transient" {
+			return transient(injectedFailureError{name: name})
+		}
+		return injectedFailureError{name: name}
+	}
+	return nil
+}
+
+// failuresInjected reports whether any failure is configured. The daemon
+// does not share the variable, so hooks that set it are not forwarded.
+func failuresInjected() bool {
+	return strings.TrimSpace(getenv(failBackendEnv)) != ""
+}
+
+// applyFailBackendFlag exports --fail-backend for the rest of the process
+// and the helpers it starts.
+func applyFailBackendFlag(value string) {
+	if value = strings.TrimSpace(value); value != "" {
+		_ = os.Setenv(failBackendEnv, value)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInjectedFailure(t *testing.T) {
+	t.Setenv(failBackendEnv, "popup, webhook deploy:transient")
+	if err := injectedFailure("popup"); err == nil || isTransient(err) {
+		t.Fatalf("popup = %v, want a permanent failure", err)
+	}
+	if err := injectedFailure("webhook deploy"); !isTransient(err) {
+		t.Fatalf("webhook deploy = %v, want a transient failure", err)
+	}
+	if err := injectedFailure("terminal-notifier"); err != nil {
+		t.Fatalf("terminal-notifier = %v", err)
+	}
+	t.Setenv(failBackendEnv, "all")
+	if injectedFailure("osascript") == nil {
+		t.Fatal("all did not fail osascript")
+	}
+}
+
+func writeFakeNotifier(t *testing.T, dir, name, log string) {
+	t.Helper()
+	script := "#!/bin/sh\necho " + name + " >> " + log + "\n"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFailBackendFallsBack(t *testing.T) {
+	home, _ := useTempHome(t)
+	// Remote sinks stay quiet in capture mode.
+	t.Setenv(backendEnv, "")
+	t.Setenv("CODEX_NOTIFY_NOTIFICATION_UI", notificationUISystem)
+	t.Setenv("CODEX_NOTIFY_SANDBOX", "0")
+	t.Setenv(failBackendEnv, "")
+	bin := t.TempDir()
+	log := filepath.Join(home, "ran.log")
+	writeFakeNotifier(t, bin, "terminal-notifier", log)
+	writeFakeNotifier(t, bin, "osascript", log)
+	t.Setenv("PATH", bin)
+
+	if err := runTest([]string{"--fail-backend", "terminal-notifier", "--quiet"}); err != nil {
+		t.Fatalf("test with terminal-notifier failing: %v", err)
+	}
+	if got, _ := os.ReadFile(log); string(got) != "osascript\n" {
+		t.Fatalf("ran %q, want only the osascript fallback", got)
+	}
+
+	err := runTest([]string{"--fail-backend", "terminal-notifier,osascript", "--quiet"})
+	if exitCodeFor(err) != exitBackend || !strings.Contains(err.Error(), "injected failure for osascript") {
+		t.Fatalf("test with every backend failing = %v", err)
+	}
+}
+
+func TestFailBackendQueuesTransientSinkFailures(t *testing.T) {
+	home, _ := useTempHome(t)
+	// Remote sinks stay quiet in capture mode.
+	t.Setenv(backendEnv, "")
+	t.Setenv(ntfyTokenEnv, "")
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(home, "config.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", path)
+	if err := os.WriteFile(path, []byte("[ntfy]\ntopic = \"runs\"\nserver = \""+server.URL+"\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadUserConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(failBackendEnv, "ntfy:transient")
+	startRemoteSinks(map[string]any{"type": "agent-turn-complete", "thread-id": "t1"})()
+	state, _ := loadState()
+	if len(state.SinkQueue) != 1 || hits.Load() != 0 {
+		t.Fatalf("queue = %+v, hits = %d; want one queued delivery and no request", state.SinkQueue, hits.Load())
+	}
+
+	t.Setenv(failBackendEnv, "")
+	if n := drainSinkQueue(cfg, time.Now().Add(time.Hour)); n != 1 || hits.Load() != 1 {
+		t.Fatalf("drain delivered %d with %d requests, want 1", n, hits.Load())
+	}
+
+	// A permanent failure is not queued.
+	t.Setenv(failBackendEnv, "ntfy")
+	startRemoteSinks(map[string]any{"type": "agent-turn-complete", "thread-id": "t1"})()
+	if state, _ := loadState(); len(state.SinkQueue) != 0 {
+		t.Fatalf("permanent failure queued %+v", state.SinkQueue)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Feature stages, from least to most settled. Stable features are on by
+// default; the others ship dark until a user opts in.
+const (
+	stageExperimental = "experimental"
+	stageBeta         = "beta"
+	stageStable       = "stable"
+)
+
+var featureStages = []string{stageExperimental, stageBeta, stageStable}
+
+// featuresEnv overrides [features] per name: "ntfy_replies=on,daemon=off".
+const featuresEnv = "CODEX_NOTIFY_FEATURES"
+
+// feature is one subsystem that can be switched per user.
+type feature struct {
+	Name    string
+	Stage   string
+	Summary string
+}
+
+// features lists every flag. Promoting a feature is a one-line change to
+// its Stage; users who opted in at an earlier stage stay opted in.
+var features = []feature{
+	{Name: "daemon", Stage: stageStable, Summary: "hook forwards events to a running codex-notify daemon"},
+	{Name: "native_popup", Stage: stageStable, Summary: "approval popups from the Swift helper"},
+	{Name: "lock_queue", Stage: stageStable, Summary: "hold desktop notifications while the screen is locked"},
+	{Name: "ntfy_replies", Stage: stageBeta, Summary: "Approve/Reject buttons on ntfy approval pushes (reply_topic)"},
+	{Name: "peer", Stage: stageBeta, Summary: "mirror notifications and approvals to a paired machine ([peer])"},
+}
+
+func lookupFeature(name string) (feature, bool) {
+	for _, f := range features {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return feature{}, false
+}
+
+// parseFeatureSetting checks a [features] value: on, off, or a stage name,
+// which turns the feature on once it has reached that stage. Unknown names
+// are accepted so a config outlives a retired flag; doctor points them out.
+func parseFeatureSetting(name string, value any) (string, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return "on", nil
+		}
+		return "off", nil
+	case string:
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "on" || v == "off" || containsString(featureStages, v) {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("features.%s must be on, off, or one of %s", name, strings.Join(featureStages, ", "))
+}
+
+func stageIndex(stage string) int {
+	for i, s := range featureStages {
+		if s == stage {
+			return i
+		}
+	}
+	return len(featureStages)
+}
+
+// featureState resolves a feature: CODEX_NOTIFY_FEATURES first, then the
+// config file, then the stage default. It returns the setting that decided
+// and where it came from.
+func (c userConfig) featureState(f feature) (enabled bool, setting, source string) {
+	setting, source = "", "default"
+	for _, item := range strings.Split(getenv(featuresEnv), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || strings.TrimSpace(name) != f.Name {
+			continue
+		}
+		if v, err := parseFeatureSetting(f.Name, value); err == nil {
+			setting, source = v, "env "+featuresEnv
+		}
+	}
+	if setting == "" {
+		if v, ok := c.Features[f.Name]; ok {
+			setting, source = v, "config"
+		}
+	}
+	switch setting {
+	case "":
+		return f.Stage == stageStable, "", source
+	case "on":
+		return true, setting, source
+	case "off":
+		return false, setting, source
+	}
+	return stageIndex(f.Stage) >= stageIndex(setting), setting, source
+}
+
+var featureCache struct {
+	sync.Mutex
+	key     string
+	enabled map[string]bool
+}
+
+// featureEnabled reports whether the named feature is on. A broken config
+// falls back to the env and stage defaults.
+//
+// Every feature is resolved on the first call and reused for the rest of
+// the invocation, or until CODEX_NOTIFY_FEATURES or the config file path
+// differs, as for a hook forwarded to the daemon. A config edit made by
+// this process or a daemon reload calls resetFeatureCache.
+func featureEnabled(name string) bool {
+	path, _ := userConfigPath()
+	key := getenv(featuresEnv) + "\x00" + path
+	featureCache.Lock()
+	defer featureCache.Unlock()
+	if featureCache.enabled == nil || featureCache.key != key {
+		cfg, _ := loadUserConfig()
+		featureCache.enabled = map[string]bool{}
+		for _, f := range features {
+			featureCache.enabled[f.Name], _, _ = cfg.featureState(f)
+		}
+		featureCache.key = key
+	}
+	return featureCache.enabled[name]
+}
+
+// resetFeatureCache makes the next featureEnabled read config.toml again.
+func resetFeatureCache() {
+	featureCache.Lock()
+	featureCache.enabled = nil
+	featureCache.Unlock()
+}
+
+// addFeatureDoctorChecks lists the features switched away from their
+// default and flags names no build knows.
+func addFeatureDoctorChecks(report *doctorReport, cfg userConfig) {
+	for _, f := range features {
+		enabled, setting, source := cfg.featureState(f)
+		if setting == "" {
+			continue
+		}
+		state := "off"
+		if enabled {
+			state = "on"
+		}
+		report.add(doctorSettings, checkOK, "feature "+f.Name, fmt.Sprintf("%s (%s, %s: %s)", state, f.Stage, source, setting), false)
+	}
+	for name := range cfg.Features {
+		if _, ok := lookupFeature(name); !ok {
+			report.add(doctorSettings, checkWarn, "feature "+name, "unknown feature; remove it from [features]", false)
+		}
+	}
+}
+
+// featureStatus is one feature in `features list --json`.
+type featureStatus struct {
+	Name    string `json:"name"`
+	Stage   string `json:"stage"`
+	Enabled bool   `json:"enabled"`
+	Source  string `json:"source"`
+	Setting string `json:"setting,omitempty"`
+	Summary string `json:"summary"`
+}
+
+type featuresResult struct {
+	commandResult
+	Features []featureStatus `json:"features"`
+}
+
+func runFeatures(args []string) error {
+	if len(args) == 0 {
+		return usageError(errors.New("features requires one of: list, enable, disable, reset"))
+	}
+	command, args := args[0], args[1:]
+	fs := flag.NewFlagSet("features "+command, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	stage := fs.String("stage", "", "enable only once the feature reaches this stage")
+	outFlags := addOutputFlags(fs)
+	// The feature name may come before or after the flags.
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	rest := fs.Args()
+	if name == "" && len(rest) > 0 {
+		name, rest = rest[0], rest[1:]
+	}
+
+	if command == "list" {
+		if name != "" {
+			return usageError(errors.New("features list takes no arguments"))
+		}
+		out := outFlags.output()
+		cfg, err := loadUserConfig()
+		if err != nil {
+			return configError(err)
+		}
+		result := featuresResult{commandResult: commandResult{Command: "features list", Status: "ok", Count: len(features)}}
+		for _, f := range features {
+			enabled, setting, source := cfg.featureState(f)
+			result.Features = append(result.Features, featureStatus{
+				Name: f.Name, Stage: f.Stage, Enabled: enabled, Source: source, Setting: setting, Summary: f.Summary,
+			})
+			state := "off"
+			if enabled {
+				state = "on"
+			}
+			if setting != "" {
+				source += ": " + setting
+			}
+			out.Printf("%-14s %-13s %-4s (%s)  %s\n", f.Name, f.Stage, state, source, f.Summary)
+		}
+		return out.Result(result)
+	}
+
+	if name == "" || len(rest) > 0 {
+		return usageError(fmt.Errorf("features %s requires one feature name", command))
+	}
+	var literal string
+	switch command {
+	case "enable":
+		literal = `"on"`
+		if *stage != "" {
+			literal = `"` + *stage + `"`
+		}
+	case "disable":
+		literal = `"off"`
+	case "reset":
+		literal = ""
+	default:
+		return usageError(fmt.Errorf("unknown features command: %s", command))
+	}
+	if *stage != "" && command != "enable" {
+		return usageError(errors.New("--stage is only valid for enable"))
+	}
+	if _, ok := lookupFeature(name); !ok && literal != "" {
+		return usageError(fmt.Errorf("unknown feature %q (see `%s features list`)", name, appName))
+	}
+	if _, err := parseFeatureSetting(name, strings.Trim(literal, `"`)); err != nil && literal != "" {
+		return usageError(err)
+	}
+	return editUserConfigKey("features."+name, literal, func(content []byte) []byte {
+		return setTableTOMLKey(content, "features", name, literal)
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFeatureState(t *testing.T) {
+	t.Setenv(featuresEnv, "")
+	beta := feature{Name: "ntfy_replies", Stage: stageBeta}
+	experimental := feature{Name: "ntfy_replies", Stage: stageExperimental}
+	tests := []struct {
+		f       feature
+		setting string
+		want    bool
+	}{
+		{beta, "", false},
+		{feature{Name: "daemon", Stage: stageStable}, "", true},
+		{beta, "on", true},
+		{beta, "beta", true},
+		// Opted in at beta: stays dark while the feature is experimental.
+		{experimental, "beta", false},
+		{experimental, "experimental", true},
+		{feature{Name: "daemon", Stage: stageStable}, "off", false},
+	}
+	for _, tt := range tests {
+		cfg := userConfig{Features: map[string]string{}}
+		if tt.setting != "" {
+			cfg.Features[tt.f.Name] = tt.setting
+		}
+		if got, _, _ := cfg.featureState(tt.f); got != tt.want {
+			t.Errorf("featureState(%+v) with %q = %v, want %v", tt.f, tt.setting, got, tt.want)
+		}
+	}
+
+	t.Setenv(featuresEnv, "daemon=off, ntfy_replies=on")
+	cfg := userConfig{Features: map[string]string{"ntfy_replies": "off"}}
+	if on, _, source := cfg.featureState(beta); !on || source != "env "+featuresEnv {
+		t.Errorf("env override = %v from %s", on, source)
+	}
+
+	if _, err := parseUserConfig([]byte("[features]\nnative_popup = \"sometimes\"\n")); err == nil {
+		t.Error("parseUserConfig() accepted an invalid feature setting")
+	}
+	if _, err := parseUserConfig([]byte("features.retired = true\n")); err != nil {
+		t.Errorf("parseUserConfig() rejected an unknown feature: %v", err)
+	}
+}
+
+func TestSetTableTOMLKey(t *testing.T) {
+	content := []byte("notification_ui = \"popup\"\n\n[features]\ndaemon = \"off\"\n\n[presets]\ntests = \"run\"\n")
+	if got, want := string(setTableTOMLKey(content, "features", "native_popup", `"on"`)), "notification_ui = \"popup\"\n\n[features]\ndaemon = \"off\"\nnative_popup = \"on\"\n\n[presets]\ntests = \"run\"\n"; got != want {
+		t.Fatalf("add = %q, want %q", got, want)
+	}
+	if got, want := string(setTableTOMLKey(content, "features", "daemon", "")), "notification_ui = \"popup\"\n\n[features]\n\n[presets]\ntests = \"run\"\n"; got != want {
+		t.Fatalf("remove = %q, want %q", got, want)
+	}
+	dotted := []byte("features.daemon = \"off\"\n")
+	if got, want := string(setTableTOMLKey(dotted, "features", "daemon", `"on"`)), "features.daemon = \"on\"\n"; got != want {
+		t.Fatalf("dotted = %q, want %q", got, want)
+	}
+	if got, want := string(setTableTOMLKey([]byte("sandbox = true\n"), "features", "daemon", `"on"`)), "sandbox = true\n\n[features]\ndaemon = \"on\"\n"; got != want {
+		t.Fatalf("new table = %q, want %q", got, want)
+	}
+}
+
+func TestFeaturesCommand(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", cfgPath)
+	t.Setenv(featuresEnv, "")
+
+	if featureEnabled("ntfy_replies") {
+		t.Fatal("beta feature on by default")
+	}
+	if err := runFeatures([]string{"enable", "ntfy_replies", "--stage", "beta"}); err != nil {
+		t.Fatalf("features enable: %v", err)
+	}
+	if !featureEnabled("ntfy_replies") {
+		t.Fatal("ntfy_replies still off after enable")
+	}
+	if err := runFeatures([]string{"disable", "daemon"}); err != nil {
+		t.Fatalf("features disable: %v", err)
+	}
+	if featureEnabled("daemon") || daemonForwardingEnabled() {
+		t.Fatal("daemon still on after disable")
+	}
+	if err := runFeatures([]string{"reset", "daemon"}); err != nil {
+		t.Fatalf("features reset: %v", err)
+	}
+	content, _ := os.ReadFile(cfgPath)
+	if want := "[features]\nntfy_replies = \"beta\"\n"; string(content) != want {
+		t.Fatalf("config = %q, want %q", content, want)
+	}
+	if err := runFeatures([]string{"enable", "turbo"}); exitCodeFor(err) != exitUsage {
+		t.Fatalf("unknown feature: %v", err)
+	}
+	if err := runFeatures([]string{"enable", "daemon", "--stage", "gamma"}); exitCodeFor(err) != exitUsage {
+		t.Fatalf("unknown stage: %v", err)
+	}
+}
+
+func TestFeatureEnabledResolvesOnce(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", cfgPath)
+	t.Setenv(featuresEnv, "")
+	if err := os.WriteFile(cfgPath, []byte("[features]\npeer = \"on\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if !featureEnabled("peer") {
+		t.Fatal("peer off with features.peer = on")
+	}
+
+	if err := os.WriteFile(cfgPath, []byte("[features]\npeer = \"off\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if !featureEnabled("peer") {
+		t.Fatal("config.toml was read again within the invocation")
+	}
+	t.Setenv(featuresEnv, "peer=off")
+	if featureEnabled("peer") {
+		t.Fatal("a different CODEX_NOTIFY_FEATURES reused the cached features")
+	}
+	t.Setenv(featuresEnv, "")
+	resetFeatureCache()
+	if featureEnabled("peer") {
+		t.Fatal("reset did not pick up the edited file")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// helperEnvName records the macOS version and Swift toolchain the helper
+// was installed under. Helpers built before a major macOS update sometimes
+// stop posting notifications, so a new major version or toolchain triggers
+// a rebuild even when the helper source is unchanged.
+const helperEnvName = "approval_action_notifier.env"
+
+// helperBuildEnv is what helperEnvName records. Toolchain is empty for the
+// prebuilt helper, which does not depend on the local Swift install.
+type helperBuildEnv struct {
+	OS        string
+	Toolchain string
+}
+
+func (e helperBuildEnv) String() string {
+	return fmt.Sprintf("os %s\ntoolchain %s\n", e.OS, e.Toolchain)
+}
+
+func parseHelperBuildEnv(content string) (helperBuildEnv, bool) {
+	var env helperBuildEnv
+	found := false
+	for _, line := range strings.Split(content, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "os":
+			env.OS, found = value, true
+		case "toolchain":
+			env.Toolchain = value
+		}
+	}
+	return env, found
+}
+
+// currentHelperBuildEnv is the macOS and Swift toolchain the helper would be
+// built with now; tests fake an upgrade through it. It only reads files, so
+// it is cheap enough to run before every popup.
+var currentHelperBuildEnv = func() helperBuildEnv {
+	return helperBuildEnv{OS: macOSVersion(), Toolchain: swiftToolchainStamp()}
+}
+
+var plistStringPattern = regexp.MustCompile(`<key>(ProductVersion|ProductBuildVersion)</key>\s*<string>([^<]*)</string>`)
+
+// macOSVersion is the version and build from SystemVersion.plist, e.g.
+// "15.1 (24B83)", or "" when it cannot be read.
+func macOSVersion() string {
+	content, err := os.ReadFile("/System/Library/CoreServices/SystemVersion.plist")
+	if err != nil {
+		return ""
+	}
+	values := map[string]string{}
+	for _, m := range plistStringPattern.FindAllStringSubmatch(string(content), -1) {
+		values[m[1]] = m[2]
+	}
+	if values["ProductVersion"] == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s (%s)", values["ProductVersion"], values["ProductBuildVersion"])
+}
+
+// swiftToolchainStamp identifies the swiftc that xcrun would pick, by path
+// and modification time, without running it: `swiftc --version` takes
+// longer than the rest of a hook.
+func swiftToolchainStamp() string {
+	devDir := strings.TrimSpace(getenv("DEVELOPER_DIR"))
+	if devDir == "" {
+		devDir, _ = os.Readlink("/var/db/xcode_select_link")
+	}
+	if devDir == "" {
+		devDir = "/Library/Developer/CommandLineTools"
+	}
+	for _, path := range []string{
+		filepath.Join(devDir, "Toolchains", "XcodeDefault.xctoolchain", "usr", "bin", "swiftc"),
+		filepath.Join(devDir, "usr", "bin", "swiftc"),
+	} {
+		if info, err := os.Stat(path); err == nil {
+			return fmt.Sprintf("%s@%d", path, info.ModTime().Unix())
+		}
+	}
+	return ""
+}
+
+// helperEnvChange explains why the recorded environment no longer matches,
+// or returns "" when the helper can stay. A value that cannot be read now
+// is not a change: losing swiftc does not make a working helper stale.
+func helperEnvChange(recorded string, current helperBuildEnv) string {
+	was, ok := parseHelperBuildEnv(recorded)
+	if !ok {
+		return "no recorded build environment"
+	}
+	if current.OS != "" && macOSMajor(was.OS) != macOSMajor(current.OS) {
+		return fmt.Sprintf("macOS changed from %s to %s", orUnknown(was.OS), current.OS)
+	}
+	if was.Toolchain != "" && current.Toolchain != "" && was.Toolchain != current.Toolchain {
+		return "the Swift toolchain changed"
+	}
+	return ""
+}
+
+// macOSMajor is the major version of a recorded macOS version, "14" for
+// "14.6 (23G80)". Point releases keep the helper working, so only a
+// change here calls for a rebuild.
+func macOSMajor(version string) string {
+	major, _, _ := strings.Cut(version, ".")
+	major, _, _ = strings.Cut(major, " ")
+	return major
+}
+
+func orUnknown(v string) string {
+	if v == "" {
+		return "unknown"
+	}
+	return v
+}
+
+func writeHelperBuildEnv(helperDir string, env helperBuildEnv) error {
+	if err := writeFileAtomic(filepath.Join(helperDir, helperEnvName), []byte(env.String()), privateFileMode); err != nil {
+		return fmt.Errorf("write helper build environment: %w", err)
+	}
+	return nil
+}
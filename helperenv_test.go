@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func useHelperBuildEnv(t *testing.T, env *helperBuildEnv) {
+	t.Helper()
+	stub(t, &currentHelperBuildEnv, func() helperBuildEnv { return *env })
+}
+
+func TestHelperEnvChange(t *testing.T) {
+	recorded := helperBuildEnv{OS: "14.6 (23G80)", Toolchain: "/swiftc@1"}.String()
+	tests := []struct {
+		recorded string
+		current  helperBuildEnv
+		want     string
+	}{
+		{recorded, helperBuildEnv{OS: "14.6 (23G80)", Toolchain: "/swiftc@1"}, ""},
+		{recorded, helperBuildEnv{OS: "15.0 (24A335)", Toolchain: "/swiftc@1"}, "macOS changed from 14.6 (23G80) to 15.0 (24A335)"},
+		// A point release keeps the helper.
+		{recorded, helperBuildEnv{OS: "14.7 (23H124)", Toolchain: "/swiftc@1"}, ""},
+		{recorded, helperBuildEnv{OS: "14.6 (23G80)", Toolchain: "/swiftc@2"}, "the Swift toolchain changed"},
+		// Losing swiftc, or a prebuilt helper, is not a reason to rebuild.
+		{recorded, helperBuildEnv{OS: "14.6 (23G80)"}, ""},
+		{helperBuildEnv{OS: "14.6 (23G80)"}.String(), helperBuildEnv{OS: "14.6 (23G80)", Toolchain: "/swiftc@2"}, ""},
+		{"", helperBuildEnv{OS: "14.6 (23G80)"}, "no recorded build environment"},
+	}
+	for _, tt := range tests {
+		if got := helperEnvChange(tt.recorded, tt.current); got != tt.want {
+			t.Fatalf("helperEnvChange(%q, %+v) = %q, want %q", tt.recorded, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestEnsureHelperRebuildsAfterOSUpgrade(t *testing.T) {
+	useFakeCodesign(t)
+	useTempHome(t)
+	t.Setenv("PATH", t.TempDir())
+	probes := 0
+	usePrebuiltHelper(t, []byte("helper"), approvalActionNotifierHash(), func(string) error {
+		probes++
+		return nil
+	})
+	env := helperBuildEnv{OS: "14.6 (23G80)"}
+	useHelperBuildEnv(t, &env)
+
+	path, err := ensureApprovalActionHelper()
+	if err != nil {
+		t.Fatalf("ensureApprovalActionHelper() error = %v", err)
+	}
+	if _, err := ensureApprovalActionHelper(); err != nil || probes != 1 {
+		t.Fatalf("second call: err = %v, probes = %d, want the installed helper reused", err, probes)
+	}
+
+	env.OS = "15.0 (24A335)"
+	if _, err := ensureApprovalActionHelper(); err != nil || probes != 2 {
+		t.Fatalf("after upgrade: err = %v, probes = %d, want a reinstall", err, probes)
+	}
+	recorded, _ := os.ReadFile(filepath.Join(filepath.Dir(path), helperEnvName))
+	if !strings.Contains(string(recorded), "os 15.0 (24A335)") {
+		t.Fatalf("recorded env = %q", recorded)
+	}
+
+	// With nothing to rebuild from, the verified helper keeps working.
+	usePrebuiltHelper(t, nil, "", func(string) error { return nil })
+	env.OS = "16.0 (25A354)"
+	got, err := ensureApprovalActionHelper()
+	if err != nil || got != path {
+		t.Fatalf("failed rebuild: path = %q, err = %v, want the existing helper", got, err)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// The popup helper's Swift source is only read to build or hash the
+// helper, which most invocations never do, so it stays in the embedded file
+// system until then instead of being copied into a string at startup.
+//
+//go:embed internal/swift/approval_action_notifier.swift
+var helperSourceFS embed.FS
+
+const helperSourceFile = "internal/swift/approval_action_notifier.swift"
+
+var approvalActionNotifierSource = sync.OnceValue(func() string {
+	data, err := helperSourceFS.ReadFile(helperSourceFile)
+	if err != nil {
+		// The file is embedded at build time; it cannot be missing.
+		panic(err)
+	}
+	return string(data)
+})
+
+func ensureApprovalActionHelper() (string, error) {
+	helperDir, err := runtimeStateDir()
+	if err != nil {
+		return "", err
+	}
+
+	binaryPath := filepath.Join(helperDir, helperBinaryName)
+	hashPath := filepath.Join(helperDir, helperHashName)
+
+	expectedHash := approvalActionNotifierHash()
+	currentHash, _ := os.ReadFile(hashPath)
+	if strings.TrimSpace(string(currentHash)) == expectedHash {
+		if info, err := os.Stat(binaryPath); err == nil && info.Mode().IsRegular() {
+			err := verifyHelper(binaryPath)
+			if err == nil {
+				env := currentHelperBuildEnv()
+				recorded, _ := os.ReadFile(filepath.Join(helperDir, helperEnvName))
+				change := helperEnvChange(string(recorded), env)
+				if change == "" {
+					return binaryPath, nil
+				}
+				logErrorf("popup helper is stale (%s); rebuilding", change)
+				path, err := installApprovalActionHelper(helperDir, expectedHash, env)
+				if err != nil {
+					// The old helper passed verification, so keep using it, and
+					// record the environment so every popup does not retry.
+					logErrorf("popup helper rebuild failed (%v); keeping the existing helper", err)
+					_ = writeHelperBuildEnv(helperDir, helperBuildEnv{OS: env.OS})
+					return binaryPath, nil
+				}
+				return path, nil
+			}
+			logErrorf("popup helper failed verification (%v); reinstalling", err)
+		}
+	}
+	return installApprovalActionHelper(helperDir, expectedHash, currentHelperBuildEnv())
+}
+
+// installApprovalActionHelper installs the prebuilt helper or compiles it,
+// and records the source hash and the environment it was built under.
+func installApprovalActionHelper(helperDir, expectedHash string, env helperBuildEnv) (string, error) {
+	sourcePath := filepath.Join(helperDir, helperSourceFilename)
+	binaryPath := filepath.Join(helperDir, helperBinaryName)
+	hashPath := filepath.Join(helperDir, helperHashName)
+
+	// Release builds carry a prebuilt helper; swiftc is only needed when it
+	// is missing, stale, or cannot run on this Mac.
+	if err := installPrebuiltHelper(binaryPath, expectedHash); err == nil {
+		if err := sealHelper(binaryPath); err != nil {
+			return "", err
+		}
+		if err := writeFileAtomic(hashPath, []byte(expectedHash+"\n"), privateFileMode); err != nil {
+			return "", fmt.Errorf("write helper hash: %w", err)
+		}
+		if err := writeHelperBuildEnv(helperDir, helperBuildEnv{OS: env.OS}); err != nil {
+			return "", err
+		}
+		return binaryPath, nil
+	}
+
+	swiftcPath, ok := lookupCmd("swiftc")
+	if !ok {
+		return "", errors.New("swiftc not found")
+	}
+
+	if err := writeFileAtomic(sourcePath, []byte(approvalActionNotifierSource()), privateFileMode); err != nil {
+		return "", fmt.Errorf("write helper source: %w", err)
+	}
+
+	tmpBinaryPath := binaryPath + ".tmp"
+	_ = os.Remove(tmpBinaryPath)
+
+	moduleCachePath := filepath.Join(helperDir, "swift-module-cache")
+	if err := os.MkdirAll(moduleCachePath, privateDirMode); err != nil {
+		return "", fmt.Errorf("create swift module cache dir: %w", err)
+	}
+
+	compileCmd := exec.Command(
+		swiftcPath,
+		"-O",
+		"-suppress-warnings",
+		"-module-cache-path",
+		moduleCachePath,
+		sourcePath,
+		"-o",
+		tmpBinaryPath,
+	)
+	if out, err := combinedOutputLogged(compileCmd); err != nil {
+		_ = os.Remove(tmpBinaryPath)
+		return "", fmt.Errorf("compile helper failed: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+
+	if err := os.Chmod(tmpBinaryPath, 0o700); err != nil {
+		_ = os.Remove(tmpBinaryPath)
+		return "", fmt.Errorf("chmod helper: %w", err)
+	}
+	if err := os.Rename(tmpBinaryPath, binaryPath); err != nil {
+		_ = os.Remove(tmpBinaryPath)
+		return "", fmt.Errorf("install helper: %w", err)
+	}
+	if err := sealHelper(binaryPath); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(hashPath, []byte(expectedHash+"\n"), privateFileMode); err != nil {
+		return "", fmt.Errorf("write helper hash: %w", err)
+	}
+	if err := writeHelperBuildEnv(helperDir, env); err != nil {
+		return "", err
+	}
+
+	return binaryPath, nil
+}
+
+// approvalActionNotifierHash hashes the embedded helper source once per
+// process, and only when a popup actually needs the helper.
+var approvalActionNotifierHash = sync.OnceValue(func() string {
+	return helperSourceHash(approvalActionNotifierSource())
+})
+
+func helperSourceHash(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// helperDigestName records the SHA-256 of the signed helper binary, so a
+// binary swapped or patched in the cache dir is caught before it runs.
+const helperDigestName = "approval_action_notifier.binary.json"
+
+// helperSeal is what sealHelper records about the helper it signed: its
+// digest, and the file identity verifyHelper compares before each launch
+// to skip re-hashing and codesign while the binary is untouched.
+type helperSeal struct {
+	Path    string `json:"path"`
+	SHA256  string `json:"sha256"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"`
+	Inode   uint64 `json:"inode"`
+}
+
+// helperDigestPath keeps the seal in the user's cache dir even when the
+// helper itself had to go to the shared temp dir, where anyone could
+// rewrite a digest stored next to it.
+func helperDigestPath(binaryPath string) string {
+	if cacheDir, err := os.UserCacheDir(); err == nil && strings.TrimSpace(cacheDir) != "" {
+		dir := filepath.Join(cacheDir, appName)
+		if err := os.MkdirAll(dir, 0o700); err == nil {
+			return filepath.Join(dir, helperDigestName)
+		}
+	}
+	return filepath.Join(filepath.Dir(binaryPath), helperDigestName)
+}
+
+func statHelper(path string) (size, modTime int64, inode uint64, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		inode = uint64(st.Ino)
+	}
+	return info.Size(), info.ModTime().UnixNano(), inode, nil
+}
+
+// signHelper ad-hoc signs the helper in place.
+var signHelper = func(path string) error {
+	codesign, ok := lookupCmd("codesign")
+	if !ok {
+		return errors.New("codesign not found")
+	}
+	if out, err := combinedOutputLogged(exec.Command(codesign, "--force", "--sign", "-", path)); err != nil {
+		return fmt.Errorf("codesign failed: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// verifyHelperSignature checks the helper's code signature.
+var verifyHelperSignature = func(path string) error {
+	codesign, ok := lookupCmd("codesign")
+	if !ok {
+		return errors.New("codesign not found")
+	}
+	if out, err := combinedOutputLogged(exec.Command(codesign, "--verify", "--strict", path)); err != nil {
+		return fmt.Errorf("invalid signature: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// sealHelper signs a freshly installed helper and records its seal. The
+// digest is taken after signing, because codesign rewrites the binary.
+func sealHelper(binaryPath string) error {
+	if err := signHelper(binaryPath); err != nil {
+		return fmt.Errorf("sign helper: %w", err)
+	}
+	digest, err := fileSHA256(binaryPath)
+	if err != nil {
+		return fmt.Errorf("hash helper: %w", err)
+	}
+	size, modTime, inode, err := statHelper(binaryPath)
+	if err != nil {
+		return fmt.Errorf("stat helper: %w", err)
+	}
+	data, err := json.Marshal(helperSeal{Path: binaryPath, SHA256: digest, Size: size, ModTime: modTime, Inode: inode})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(helperDigestPath(binaryPath), append(data, '\n'), privateFileMode); err != nil {
+		return fmt.Errorf("write helper digest: %w", err)
+	}
+	return nil
+}
+
+// verifyHelper runs before every launch. While the binary is the file
+// sealHelper signed, unchanged in size, time, and inode, that is all it
+// checks; anything else gets verifyHelperFully.
+func verifyHelper(binaryPath string) error {
+	seal, err := readHelperSeal(binaryPath)
+	if err != nil {
+		return err
+	}
+	if size, modTime, inode, err := statHelper(binaryPath); err == nil && size == seal.Size && modTime == seal.ModTime && inode == seal.Inode {
+		return nil
+	}
+	return verifyHelperFully(binaryPath)
+}
+
+// verifyHelperFully checks the helper against its recorded digest and its
+// code signature.
+func verifyHelperFully(binaryPath string) error {
+	seal, err := readHelperSeal(binaryPath)
+	if err != nil {
+		return err
+	}
+	digest, err := fileSHA256(binaryPath)
+	if err != nil {
+		return err
+	}
+	if digest != seal.SHA256 {
+		return errors.New("SHA-256 does not match the recorded digest")
+	}
+	return verifyHelperSignature(binaryPath)
+}
+
+func readHelperSeal(binaryPath string) (helperSeal, error) {
+	var seal helperSeal
+	data, err := os.ReadFile(helperDigestPath(binaryPath))
+	if err != nil || json.Unmarshal(data, &seal) != nil || seal.Path != binaryPath {
+		return seal, errors.New("no recorded digest")
+	}
+	return seal, nil
+}
+
+func addHelperDoctorCheck(report *doctorReport) {
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		return
+	}
+	binaryPath := filepath.Join(stateDir, helperBinaryName)
+	if _, err := os.Stat(binaryPath); errors.Is(err, os.ErrNotExist) {
+		report.add(doctorHelper, checkOK, "popup helper", "not built yet (installed on first popup)", false)
+		return
+	}
+	currentHash, _ := os.ReadFile(filepath.Join(stateDir, helperHashName))
+	if strings.TrimSpace(string(currentHash)) != approvalActionNotifierHash() {
+		report.add(doctorHelper, checkOK, "popup helper", "outdated (rebuilt on next popup)", false)
+		return
+	}
+	if err := verifyHelperFully(binaryPath); err != nil {
+		report.add(doctorHelper, checkFail, "popup helper", fmt.Sprintf("%s failed verification: %v (it is rebuilt before the next popup)", binaryPath, err), true)
+		return
+	}
+	recorded, _ := os.ReadFile(filepath.Join(stateDir, helperEnvName))
+	if change := helperEnvChange(string(recorded), currentHelperBuildEnv()); change != "" {
+		report.add(doctorHelper, checkWarn, "popup helper", fmt.Sprintf("stale: %s (rebuilt on next popup)", change), false)
+		return
+	}
+	report.add(doctorHelper, checkOK, "popup helper", binaryPath+" (signed, digest verified)", false)
+}
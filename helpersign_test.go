@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func useFakeCodesign(t *testing.T) {
+	t.Helper()
+
+	prevSign, prevVerify := signHelper, verifyHelperSignature
+	signHelper = func(path string) error {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.WriteString("signature")
+		return err
+	}
+	verifyHelperSignature = func(string) error { return nil }
+	t.Cleanup(func() {
+		signHelper, verifyHelperSignature = prevSign, prevVerify
+	})
+}
+
+func TestSealAndVerifyHelper(t *testing.T) {
+	useFakeCodesign(t)
+	useTempHome(t)
+
+	path := filepath.Join(t.TempDir(), helperBinaryName)
+	if err := os.WriteFile(path, []byte("helper"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyHelper(path); err == nil {
+		t.Fatal("expected an unsealed helper to fail verification")
+	}
+	if err := sealHelper(path); err != nil {
+		t.Fatalf("sealHelper: %v", err)
+	}
+	if err := verifyHelper(path); err != nil {
+		t.Fatalf("verifyHelper after seal: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), helperDigestName)); err == nil {
+		t.Fatal("digest stored next to the helper, want the user cache dir")
+	}
+
+	// An untouched helper is not re-verified: codesign is not run.
+	verifyHelperSignature = func(string) error { return errors.New("codesign ran") }
+	if err := verifyHelper(path); err != nil {
+		t.Fatalf("verifyHelper(untouched) = %v, want the sealed file accepted without codesign", err)
+	}
+	verifyHelperSignature = func(string) error { return nil }
+
+	if err := os.WriteFile(path, []byte("tampered"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	err := verifyHelper(path)
+	if err == nil || !strings.Contains(err.Error(), "SHA-256") {
+		t.Fatalf("verifyHelper after tampering = %v, want digest mismatch", err)
+	}
+}
+
+func TestHelperDoctorCheckFlagsTamperedHelper(t *testing.T) {
+	useFakeCodesign(t)
+	useTempHome(t)
+
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(stateDir, helperBinaryName)
+	if err := os.WriteFile(path, []byte("helper"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stateDir, helperHashName), []byte(approvalActionNotifierHash()+"\n"), privateFileMode); err != nil {
+		t.Fatal(err)
+	}
+	if err := sealHelper(path); err != nil {
+		t.Fatal(err)
+	}
+
+	var report doctorReport
+	addHelperDoctorCheck(&report)
+	if report.Problems != 0 {
+		t.Fatalf("sealed helper reported as a problem: %+v", report.Checks)
+	}
+
+	if err := os.WriteFile(path, []byte("tampered"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	report = doctorReport{}
+	addHelperDoctorCheck(&report)
+	if report.Problems != 1 || report.Checks[0].Status != checkFail {
+		t.Fatalf("tampered helper not flagged: %+v", report.Checks)
+	}
+}
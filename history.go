@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const defaultHistoryWindow = 24 * time.Hour
+
+// historyEntry is one event in `codex-notify history`: the event log line,
+// and for a notification what became of it according to the receipts.
+type historyEntry struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Thread  string    `json:"thread_id,omitempty"`
+	Cwd     string    `json:"cwd,omitempty"`
+	Title   string    `json:"title,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Status  string    `json:"status"`
+	// Delivery is the last receipt status for the notification, such as
+	// delivered, clicked, expired, or dismissed, and Backend what showed it.
+	Delivery string `json:"delivery,omitempty"`
+	Backend  string `json:"backend,omitempty"`
+	// LatencyMS is the desktop latency of a sent event.
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+}
+
+type historyResult struct {
+	commandResult
+	Entries []historyEntry `json:"entries"`
+}
+
+// parseSince reads --since: a duration back from now (30m, 2h, 3d) or an
+// RFC 3339 time.
+func parseSince(raw string, now time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.Add(-time.Duration(n) * 24 * time.Hour), nil
+		}
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (use e.g. 30m, 2h, 3d, or an RFC 3339 time)", raw)
+}
+
+// historyGroups are the notification groups an event may have shown under.
+func historyGroups(rec eventRecord) []string {
+	switch {
+	case rec.Event == "approval-requested":
+		return approvalGroups(rec.Thread)
+	case strings.HasPrefix(rec.Event, "wrap-"):
+		return []string{notificationGroup("wrap", rec.Session)}
+	case rec.Event == "codex-exit":
+		return []string{notificationGroup("exit", rec.Thread)}
+	case rec.Event == "action":
+		return nil
+	}
+	return []string{notificationGroup(rec.Event, rec.Thread)}
+}
+
+// loadHistory returns the events logged since since, oldest first. A
+// receipt belongs to the latest event before it that used its group.
+func loadHistory(since time.Time) ([]historyEntry, error) {
+	path, err := eventsPath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := readFileMaybe(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []historyEntry
+	var groups [][]string
+	for _, line := range splitLines(content) {
+		var rec eventRecord
+		if json.Unmarshal([]byte(line), &rec) != nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, rec.Time)
+		if err != nil || t.Before(since) {
+			continue
+		}
+		entries = append(entries, historyEntry{
+			Time:      t,
+			Event:     rec.Event,
+			Thread:    rec.Thread,
+			Cwd:       rec.Cwd,
+			Title:     rec.Title,
+			Message:   rec.Message,
+			Status:    rec.Status,
+			LatencyMS: rec.LatencyMS,
+		})
+		groups = append(groups, historyGroups(rec))
+	}
+	if len(entries) == 0 {
+		return entries, nil
+	}
+
+	receiptsFile, err := receiptsPath()
+	if err != nil {
+		return entries, nil
+	}
+	content, err = readFileMaybe(receiptsFile)
+	if err != nil {
+		return nil, err
+	}
+	var receipts []deliveryReceipt
+	var receiptTimes []time.Time
+	for _, line := range splitLines(content) {
+		var r deliveryReceipt
+		if json.Unmarshal([]byte(line), &r) != nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, r.Time)
+		if err != nil || t.Before(entries[0].Time) {
+			continue
+		}
+		receipts = append(receipts, r)
+		receiptTimes = append(receiptTimes, t)
+	}
+	order := make([]int, len(receipts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return receiptTimes[order[a]].Before(receiptTimes[order[b]]) })
+
+	latest := map[string]int{}
+	next := 0
+	for _, i := range order {
+		for next < len(entries) && !entries[next].Time.After(receiptTimes[i]) {
+			for _, group := range groups[next] {
+				latest[group] = next
+			}
+			next++
+		}
+		if idx, ok := latest[receipts[i].ID]; ok {
+			entries[idx].Delivery = receipts[i].Status
+			if receipts[i].Backend != "" {
+				entries[idx].Backend = receipts[i].Backend
+			}
+		}
+	}
+	return entries, nil
+}
+
+// historyOutcome is the STATUS column: what the hook did, then what became
+// of the notification.
+func historyOutcome(e historyEntry) string {
+	outcome := e.Status
+	if e.Delivery != "" && e.Delivery != receiptDelivered {
+		outcome += ", " + e.Delivery
+	}
+	return outcome
+}
+
+func formatHistory(entries []historyEntry, style timeStyle, now time.Time) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tEVENT\tPROJECT\tSTATUS\tMESSAGE")
+	for _, e := range entries {
+		project := "-"
+		if e.Cwd != "" {
+			project = filepath.Base(e.Cwd)
+		}
+		message := e.Message
+		if message == "" {
+			message = e.Title
+		}
+		ts := formatEventTime(e.Time.Format(time.RFC3339Nano), style, now)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", ts, e.Event, project, historyOutcome(e), message)
+	}
+	_ = w.Flush()
+	return b.String()
+}
+
+// runHistory prints what was notified, held back, and answered recently:
+// `history [--since 1h] [--json]`.
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	since := fs.String("since", "", "show events since a duration ago (30m, 2h, 3d) or an RFC 3339 time (default 24h)")
+	utc := fs.Bool("utc", false, "print full UTC timestamps")
+	relative := fs.Bool("relative", false, "print times relative to now, e.g. 3m ago")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+	style := timeLocal
+	switch {
+	case *utc && *relative:
+		return usageError(errors.New("--utc and --relative cannot be combined"))
+	case *utc:
+		style = timeUTC
+	case *relative:
+		style = timeRelative
+	}
+	out := outFlags.output()
+
+	now := time.Now()
+	from := now.Add(-defaultHistoryWindow)
+	if *since != "" {
+		var err error
+		if from, err = parseSince(*since, now); err != nil {
+			return usageError(err)
+		}
+	}
+	entries, err := loadHistory(from)
+	if err != nil {
+		return err
+	}
+	if entries == nil {
+		entries = []historyEntry{}
+	}
+	if len(entries) == 0 {
+		out.Printf("nothing recorded since %s\n", from.Local().Format("Jan 02 15:04"))
+	} else {
+		out.Printf("%s", formatHistory(entries, style, now))
+	}
+	return out.Result(historyResult{
+		commandResult: commandResult{Command: "history", Status: "ok", Count: len(entries)},
+		Entries:       entries,
+	})
+}
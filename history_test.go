@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadHistory(t *testing.T) {
+	useTempHome(t)
+	path, err := eventsPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	events := []struct {
+		at  time.Duration
+		rec eventRecord
+	}{
+		{0, eventRecord{Event: "agent-turn-complete", Thread: "old", Status: "sent"}},
+		{time.Hour, eventRecord{Event: "approval-requested", Thread: "t-1", Cwd: "/src/api", Message: "Run make?", Status: "sent"}},
+		{time.Hour + time.Minute, eventRecord{Event: "agent-turn-complete", Thread: "t-1", Cwd: "/src/api", Message: "Done", Status: "sent"}},
+		{time.Hour + 2*time.Minute, eventRecord{Event: "agent-turn-complete", Thread: "t-2", Cwd: "/src/web", Message: "Quiet", Status: "muted"}},
+		{time.Hour + 3*time.Minute, eventRecord{Event: "agent-turn-complete", Thread: "t-1", Cwd: "/src/api", Message: "Again", Status: "sent"}},
+	}
+	for _, e := range events {
+		if err := appendEvent(path, &e.rec, start.Add(e.at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	at := func(d time.Duration) string { return start.Add(d).Format(time.RFC3339) }
+	for _, r := range []deliveryReceipt{
+		{Time: at(time.Hour + 10*time.Second), ID: notificationGroup("approval-native", "t-1"), Backend: "popup", Status: receiptDelivered},
+		{Time: at(time.Hour + 70*time.Second), ID: notificationGroup("agent-turn-complete", "t-1"), Backend: "terminal-notifier", Status: receiptDelivered},
+		{Time: at(time.Hour + 2*time.Minute), ID: notificationGroup("approval-native", "t-1"), Status: receiptClicked, Choice: "Approve"},
+		{Time: at(time.Hour + 4*time.Minute), ID: notificationGroup("agent-turn-complete", "t-1"), Status: receiptExpired},
+	} {
+		if err := appendReceipt(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := loadHistory(start.Add(30 * time.Minute))
+	if err != nil {
+		t.Fatalf("loadHistory() error = %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Message+":"+historyOutcome(e))
+	}
+	// The expiry comes after the second turn-complete replaced the first in
+	// its group, so it belongs to the second.
+	want := "Run make?:sent, clicked,Done:sent,Quiet:muted,Again:sent, expired"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("history = %v, want %s", got, want)
+	}
+	if entries[1].Backend != "terminal-notifier" || entries[1].Delivery != receiptDelivered {
+		t.Fatalf("entries[1] = %+v, want delivered by terminal-notifier", entries[1])
+	}
+
+	out := formatHistory(entries, timeUTC, start)
+	for _, s := range []string{"TIME", "PROJECT", "api", "web", "sent, clicked"} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("history output missing %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for raw, want := range map[string]time.Time{
+		"90m":                  now.Add(-90 * time.Minute),
+		"2d":                   now.Add(-48 * time.Hour),
+		"2026-03-10T09:00:00Z": time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC),
+	} {
+		got, err := parseSince(raw, now)
+		if err != nil || !got.Equal(want) {
+			t.Fatalf("parseSince(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"", "-1h", "0d", "yesterday"} {
+		if _, err := parseSince(raw, now); err == nil {
+			t.Fatalf("parseSince(%q) error = nil, want an error", raw)
+		}
+	}
+}
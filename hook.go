@@ -0,0 +1,594 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	codexpayload "github.com/MiUPa/codex-notify/payload"
+)
+
+func runTest(args []string) error {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	event := fs.String("event", "", "simulate this hook event, e.g. approval-requested")
+	threadID := fs.String("thread-id", "codex-notify-test", "thread id of the simulated event")
+	messageFlag := fs.String("message", "", "message of the simulated event")
+	options := fs.String("options", "", "comma-separated approval options of the simulated event, e.g. Yes,No")
+	cwd := fs.String("cwd", "", "working directory of the simulated event (default: current directory)")
+	// Hidden: see failBackendEnv.
+	failBackend := fs.String("fail-backend", "", "")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	applyFailBackendFlag(*failBackend)
+	out := outFlags.output()
+	if err := checkBackendEnv(); err != nil {
+		return err
+	}
+
+	message := "Codex通知テスト"
+	if fs.NArg() > 0 {
+		message = strings.Join(fs.Args(), " ")
+	}
+	if *event == "" {
+		var eventOnly []string
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "thread-id", "message", "options", "cwd":
+				eventOnly = append(eventOnly, "--"+f.Name)
+			}
+		})
+		if len(eventOnly) > 0 {
+			return usageError(fmt.Errorf("%s only applies with --event", strings.Join(eventOnly, ", ")))
+		}
+	} else {
+		if *messageFlag != "" {
+			message = *messageFlag
+		} else if fs.NArg() == 0 {
+			message = ""
+		}
+		if *cwd == "" {
+			*cwd, _ = os.Getwd()
+		}
+		payload := testEventPayload(*event, *threadID, message, *options, *cwd, time.Now())
+		delivered, err := deliverPayload(payload, hookDelivery{test: true})
+		if err != nil {
+			return err
+		}
+		switch delivered.Status {
+		case "sent":
+			out.Printf("simulated %s sent for thread %s\n", payloadEventName(payload), *threadID)
+		case "disabled":
+			out.Printf("%s notifications are turned off in config.toml\n", payloadEventName(payload))
+		default:
+			out.Printf("simulated %s not shown: %s\n", payloadEventName(payload), delivered.Status)
+		}
+		return out.Result(commandResult{Command: "test", Status: delivered.Status, Thread: *threadID, Count: delivered.Count})
+	}
+	if err := sendNotification(notificationRequest{
+		Title:             "Codex Notify",
+		Message:           message,
+		Group:             "codex-notify-test",
+		ExecuteOnClick:    buildActionCommand("open", ""),
+		PopupPrimaryLabel: "Open",
+	}); err != nil {
+		return backendError(err)
+	}
+	out.Println("test notification sent")
+	return out.Result(commandResult{Command: "test", Status: "sent", Count: 1})
+}
+
+// testEventPayload builds the payload Codex would send for event, with a
+// turn id of its own so each run is a new turn.
+func testEventPayload(event, threadID, message, options, cwd string, now time.Time) map[string]any {
+	payload := map[string]any{
+		"type":      event,
+		"thread-id": threadID,
+		"turn-id":   fmt.Sprintf("test-%d", now.UnixNano()),
+	}
+	if cwd != "" {
+		payload["cwd"] = cwd
+	}
+	if message != "" {
+		key := "last-assistant-message"
+		if payloadEventName(payload) == "approval-requested" {
+			key = "message"
+		}
+		payload[key] = message
+	}
+	var choices []any
+	for _, option := range strings.Split(options, ",") {
+		if option = strings.TrimSpace(option); option != "" {
+			choices = append(choices, option)
+		}
+	}
+	if len(choices) > 0 {
+		payload["options"] = choices
+	}
+	return payload
+}
+
+func runHook(args []string) error {
+	fs := flag.NewFlagSet("hook", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	payloadFile := fs.String("payload-file", "", "read payload JSON from file (- for stdin)")
+	payloadFD := fs.Int("payload-fd", -1, "read payload JSON from an inherited file descriptor")
+	// Hidden: older `init --chain` wrote it; chain in config.toml is the
+	// setting now.
+	then := fs.String("then", "", "")
+	recordDir := fs.String("record", "", "also save each payload as a timestamped JSON file in this directory")
+	// Hidden: see failBackendEnv.
+	failBackend := fs.String("fail-backend", "", "")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	applyFailBackendFlag(*failBackend)
+	noteCodexPID()
+	out := outFlags.output()
+	chained, err := parseChainedCommand(*then)
+	if err != nil {
+		return usageError(err)
+	}
+
+	payloadRaw, err := resolveHookPayload(fs.Args(), *payloadFile, *payloadFD)
+	if err != nil {
+		return usageError(err)
+	}
+
+	if privateArgvEnabled() && *payloadFile == "" && *payloadFD < 0 && fs.NArg() > 0 && getenv(privateRelayEnv) == "" {
+		flagArgs := args[:len(args)-fs.NArg()]
+		return relayHookPayloadPrivately(payloadRaw, flagArgs)
+	}
+	// The relayed hook above records and chains on its own.
+	if *recordDir != "" {
+		if _, err := recordPayload(*recordDir, payloadRaw, time.Now()); err != nil {
+			logErrorf("record payload: %v", err)
+		}
+	}
+	if len(chained) == 0 {
+		if cfg, err := loadUserConfig(); err == nil {
+			chained = cfg.Chain
+		}
+	}
+	defer startChainedNotify(chained, payloadRaw)
+	if err := checkBackendEnv(); err != nil {
+		return err
+	}
+
+	if result, forwarded, err := forwardHookToDaemon(payloadRaw); forwarded {
+		if err != nil {
+			return err
+		}
+		return out.Result(result)
+	}
+
+	payload, err := codexpayload.Parse([]byte(payloadRaw))
+	if err != nil {
+		return usageError(err)
+	}
+	result, err := deliverHookPayload(payload)
+	if err != nil {
+		return err
+	}
+	if result.Status == "sent" && payloadEventName(payload) == "approval-requested" && remindInterval() > 0 {
+		// No daemon is watching, so a background process keeps time.
+		startReminderProcess(result.Thread)
+	}
+	return out.Result(result)
+}
+
+// hookDelivery is how much of a live hook deliverPayload acts out.
+type hookDelivery struct {
+	// replay shows a payload again without acting for it: its approval
+	// is not tracked, nor an earlier one settled, its buttons only open
+	// the terminal, and remote sinks are skipped.
+	replay bool
+	// test shows an event simulated by `test --event` as if every gate
+	// had let it through, except a disabled event. Its approval is
+	// tracked so the buttons work; remote sinks never hear of it, so
+	// settling one withdraws only the local popup.
+	test bool
+	// later, when set, is handed the waits for remote sinks and
+	// withdrawals in place of deliverPayload running them before it
+	// returns, so the daemon can answer the hook first.
+	later func(waits []func())
+}
+
+// deliverHookPayload runs the hook decisions for one parsed payload and
+// sends its notifications. The daemon calls it for forwarded payloads.
+func deliverHookPayload(payload map[string]any) (commandResult, error) {
+	return deliverPayload(payload, hookDelivery{})
+}
+
+func deliverPayload(payload map[string]any, d hookDelivery) (commandResult, error) {
+	started := time.Now()
+	threadID := payloadThreadID(payload)
+	var waits []func()
+	defer func() {
+		if d.later != nil {
+			d.later(waits)
+			return
+		}
+		for _, wait := range waits {
+			wait()
+		}
+	}()
+	if !d.replay {
+		if payloadEventName(payload) == "approval-requested" {
+			if d.test {
+				trackLocalApproval(payload, time.Now())
+			} else {
+				trackApproval(payload, time.Now())
+			}
+		} else {
+			// Any later event from the thread means the approval was
+			// answered in the terminal. The remote withdrawals finish
+			// alongside the notifications.
+			if d.test {
+				settleLocalApproval(threadID)
+			} else {
+				waits = append(waits, settleApproval(threadID, answeredCodex))
+			}
+		}
+		trackTurn(payload, time.Now())
+	}
+	// live is a hook for something Codex did, not a test or a replay:
+	// the gates below apply, remote sinks hear of it, and the background
+	// work a hook does on the side runs.
+	live := !d.replay && !d.test
+
+	if live && isApprovalInteractionLockActive() {
+		recordHookEvent(payload, "suppressed")
+		return commandResult{Command: "hook", Status: "suppressed", Thread: threadID}, nil
+	}
+
+	if state, err := loadState(); live && err == nil && (isProjectMuted(state, payloadCwd(payload), time.Now()) || isGloballyMuted(state, time.Now())) {
+		recordHookEvent(payload, "muted")
+		return commandResult{Command: "hook", Status: "muted", Thread: threadID}, nil
+	}
+
+	// A state failure must not cost the notification, so only a successful
+	// claim by someone else suppresses it.
+	if first, err := claimEvent(eventDedupKey(payload), time.Now()); live && err == nil && !first {
+		recordHookEvent(payload, "duplicate")
+		return commandResult{Command: "hook", Status: "duplicate", Thread: threadID}, nil
+	}
+
+	// Remote sinks reach you away from the desk, so the checks below, which
+	// only ask whether a desktop notification is worth showing, skip them.
+	if live {
+		waits = append(waits, startRemoteSinks(payload))
+	}
+
+	if live && tmuxSessionWatched(time.Now()) {
+		recordHookEvent(payload, "watching")
+		return commandResult{Command: "hook", Status: "watching", Thread: threadID}, nil
+	}
+	if live && userRecentlyActive(payload) {
+		recordHookEvent(payload, "active")
+		return commandResult{Command: "hook", Status: "active", Thread: threadID}, nil
+	}
+
+	if desktop, rule := routesToDesktop(payload, time.Now()); live && !desktop {
+		recordHookEvent(payload, "routed")
+		return commandResult{Command: "hook", Status: "routed", Thread: threadID, Rule: rule}, nil
+	}
+	if cfg, _ := loadUserConfig(); !cfg.eventEnabled(payloadEventName(payload)) {
+		recordHookEvent(payload, "disabled")
+		return commandResult{Command: "hook", Status: "disabled", Thread: threadID}, nil
+	}
+	if live && screenShareSuppressActive(time.Now()) {
+		recordHookEvent(payload, "sharing")
+		return commandResult{Command: "hook", Status: "sharing", Thread: threadID}, nil
+	}
+	if live && screenLockQueueActive() {
+		if err := queueLockedEvent(payload, time.Now()); err == nil {
+			recordHookEvent(payload, "queued")
+			return commandResult{Command: "hook", Status: "queued", Thread: threadID}, nil
+		}
+	}
+	if live {
+		flushLockQueue()
+	}
+	if state, err := loadState(); live && err == nil && digestOnly(state, payload) {
+		if err := queueDigestEvent(payload, time.Now()); err == nil {
+			recordNoiseShown(payload, time.Now())
+			recordHookEvent(payload, "digest")
+			flushNoiseDigest(time.Now())
+			return commandResult{Command: "hook", Status: "digest", Thread: threadID}, nil
+		}
+	}
+	if live {
+		flushNoiseDigest(time.Now())
+		remindPendingApprovals(time.Now())
+	}
+	ringTerminalBell(payload)
+
+	if !d.replay && shouldUseNativeApprovalNotification(payload) {
+		if err := sendNativeApprovalNotification(payload); err == nil {
+			recordNoiseShown(payload, time.Now())
+			recordHookSent(payload, started)
+			return commandResult{Command: "hook", Status: "sent", Thread: threadID, Count: 1}, nil
+		}
+	}
+
+	requests, err := buildHookNotifications(payload)
+	if err != nil {
+		return commandResult{}, err
+	}
+	if d.replay {
+		requests = openOnly(requests, threadID)
+	}
+	requests, status := throttleNotifications(payload, requests, time.Now())
+	if len(requests) == 0 && status != "" {
+		recordHookEvent(payload, status)
+		return commandResult{Command: "hook", Status: status, Thread: threadID}, nil
+	}
+
+	for i, req := range requests {
+		if err := sendNotification(req); err != nil {
+			recordHookEvent(payload, "failed")
+			if i > 0 {
+				return commandResult{}, partialError(fmt.Errorf("sent %d of %d notifications: %w", i, len(requests), err))
+			}
+			return commandResult{}, backendError(err)
+		}
+		recordNotificationSent(req, time.Now())
+	}
+	recordNoiseShown(payload, time.Now())
+	recordHookSent(payload, started)
+	return commandResult{Command: "hook", Status: "sent", Thread: threadID, Count: len(requests)}, nil
+}
+
+// openOnly keeps the first notification with only its Open click, for a
+// payload whose buttons must answer nothing.
+func openOnly(requests []notificationRequest, threadID string) []notificationRequest {
+	if len(requests) == 0 {
+		return requests
+	}
+	req := requests[0]
+	req.ExecuteOnClick = buildActionCommand("open", threadID)
+	req.PopupPrimaryLabel = ""
+	req.ExtraChoices = nil
+	return []notificationRequest{req}
+}
+
+// resolveHookPayload reads the payload from, in order of precedence,
+// --payload-file, --payload-fd, the first positional argument, or stdin.
+func resolveHookPayload(args []string, payloadFile string, payloadFD int) (string, error) {
+	if payloadFile != "" && payloadFile != "-" {
+		b, err := os.ReadFile(payloadFile)
+		if err != nil {
+			return "", fmt.Errorf("read payload file: %w", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	if payloadFD >= 0 {
+		f := os.NewFile(uintptr(payloadFD), "payload-fd")
+		if f == nil {
+			return "", fmt.Errorf("invalid payload fd: %d", payloadFD)
+		}
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return "", fmt.Errorf("read payload fd %d: %w", payloadFD, err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	if payloadFile == "" && len(args) > 0 {
+		return args[0], nil
+	}
+
+	stdinInfo, err := os.Stdin.Stat()
+	if err != nil {
+		return "", fmt.Errorf("read stdin stat: %w", err)
+	}
+	if (stdinInfo.Mode() & os.ModeCharDevice) != 0 {
+		return "", nil
+	}
+
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("read stdin: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func buildHookNotifications(payload map[string]any) ([]notificationRequest, error) {
+	eventName := payloadEventName(payload)
+	threadID := payloadThreadID(payload)
+	// A broken user config must not cost the notification itself.
+	userCfg, _ := loadUserConfig()
+	if !userCfg.eventEnabled(eventName) {
+		return nil, nil
+	}
+	title, message := renderPayloadMessage(payload)
+
+	base := notificationRequest{
+		Title:          title,
+		Message:        message,
+		Group:          notificationGroup(eventName, threadID),
+		ExecuteOnClick: buildActionCommand("open", threadID),
+	}
+	project, hasProject := projectIdentityForCwd(payloadCwd(payload))
+	if hasProject {
+		base.Message = project.prefix(base.Message)
+		base.AccentColor = project.Color
+	}
+	if identity, ok := userCfg.identityFor(payload); ok {
+		identity.apply(&base)
+	}
+	if base.Sound == "" {
+		base.Sound = themeSoundFor(eventName)
+	}
+	if eventName == "agent-turn-complete" && keystrokesSupported() {
+		for _, preset := range userCfg.Presets {
+			base.ExtraChoices = append(base.ExtraChoices, approvalChoice{
+				Label:   preset.Name,
+				Command: buildSubmitPresetCommand(preset.Name, threadID),
+			})
+		}
+	}
+	if cwd := payloadCwd(payload); eventName == "agent-turn-complete" && cwd != "" {
+		base.ExtraChoices = append(base.ExtraChoices, approvalChoice{
+			Label:   "Mute project 1h",
+			Command: buildMuteProjectCommand(cwd, defaultProjectMuteDuration),
+		})
+	}
+
+	requests := []notificationRequest{base}
+	now := time.Now()
+	deadline, hasDeadline := payloadApprovalDeadline(payload, now)
+	// An approval that already expired keeps only the Open click.
+	expired := hasDeadline && !now.Before(deadline)
+	if eventName == "approval-requested" && approvalActionsEnabled() && !expired {
+		if approvalUIStyle() == approvalUIMulti {
+			text := stringsForText(payloadPreviewMessage(payload))
+			requests = append(requests,
+				notificationRequest{
+					Title:             text.ApproveTitle,
+					Message:           text.ClickToApprove,
+					Group:             notificationGroup("approve", threadID),
+					ExecuteOnClick:    buildActionCommand("approve", threadID),
+					PopupPrimaryLabel: "Approve",
+					AccentColor:       base.AccentColor,
+					Icon:              base.Icon,
+				},
+				notificationRequest{
+					Title:             text.RejectTitle,
+					Message:           text.ClickToReject,
+					Group:             notificationGroup("reject", threadID),
+					ExecuteOnClick:    buildActionCommand("reject", threadID),
+					PopupPrimaryLabel: "Reject",
+					AccentColor:       base.AccentColor,
+					Icon:              base.Icon,
+				},
+			)
+		} else {
+			requests[0].ExecuteOnClick = buildActionCommand("choose", threadID)
+		}
+	}
+	if action, ok := userCfg.clickAction(eventName); ok {
+		requests[0].ExecuteOnClick = buildClickCommand(action, payload)
+		requests[0].PopupPrimaryLabel = clickActionLabel(action)
+	}
+	if eventName == "approval-requested" && hasDeadline {
+		for i := range requests {
+			requests[i].ExecuteOnClick = withExpiresAt(requests[i].ExecuteOnClick, deadline)
+		}
+	}
+
+	return requests, nil
+}
+
+func renderPayloadMessage(payload map[string]any) (string, string) {
+	event := payloadEventName(payload)
+	preview := payloadPreviewMessage(payload)
+	text := stringsForText(preview)
+
+	switch event {
+	case "agent-turn-complete":
+		if preview == "" {
+			preview = text.WaitingForInput
+		}
+		return turnTitle(text, classifyTurn(payload)), preview
+	case "approval-requested":
+		if preview == "" {
+			preview = text.WaitingForApproval
+		}
+		now := time.Now()
+		if deadline, ok := payloadApprovalDeadline(payload, now); ok {
+			preview += "\n" + approvalExpiryLine(text, deadline, now)
+		}
+		return text.ApprovalTitle, preview
+	case "agent-error":
+		if preview == "" {
+			preview = text.ErrorReceived
+		}
+		return text.ErrorTitle, preview
+	default:
+		if event == "" {
+			if preview == "" {
+				preview = text.EventReceived
+			}
+			return "Codex", preview
+		}
+		if preview != "" {
+			return "Codex", fmt.Sprintf("%s: %s", event, preview)
+		}
+		return "Codex", fmt.Sprintf(text.EventFormat, event)
+	}
+}
+
+func payloadEventName(payload map[string]any) string {
+	return codexpayload.Payload(payload).Event()
+}
+
+func payloadThreadID(payload map[string]any) string {
+	return codexpayload.Payload(payload).ThreadID()
+}
+
+func payloadTurnID(payload map[string]any) string {
+	return codexpayload.Payload(payload).TurnID()
+}
+
+func payloadCwd(payload map[string]any) string {
+	return codexpayload.Payload(payload).Cwd()
+}
+
+func payloadPreviewMessage(payload map[string]any) string {
+	return codexpayload.Payload(payload).Preview()
+}
+
+func getString(payload map[string]any, key string) string {
+	return codexpayload.Payload(payload).String(key)
+}
+
+func getStringAny(payload map[string]any, keys ...string) string {
+	return codexpayload.Payload(payload).String(keys...)
+}
+
+func getStringSliceAny(payload map[string]any, keys ...string) []string {
+	return codexpayload.Payload(payload).Strings(keys...)
+}
+
+func notificationGroup(kind, threadID string) string {
+	kind = sanitizeID(kind)
+	if kind == "" {
+		kind = "event"
+	}
+	if threadID == "" {
+		return "codex-notify-" + kind
+	}
+	return fmt.Sprintf("codex-notify-%s-%s", kind, sanitizeID(threadID))
+}
+
+func sanitizeID(v string) string {
+	if v == "" {
+		return ""
+	}
+	var b strings.Builder
+	for _, r := range v {
+		switch {
+		case r >= 'a' && r <= 'z':
+			b.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '.' || r == '_' || r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// hotkeyArg starts the popup helper as the hotkey agent instead of a popup.
+const hotkeyArg = "--hotkeys"
+
+// hotkeyActions are the actions a global hotkey can bind, in the order
+// doctor lists them. Each reads CODEX_NOTIFY_HOTKEY_<ACTION>.
+var hotkeyActions = []string{"approve", "reject", "open"}
+
+// Carbon modifier masks, as RegisterEventHotKey takes them.
+const (
+	hotkeyCmd   = 1 << 8
+	hotkeyShift = 1 << 9
+	hotkeyOpt   = 1 << 11
+	hotkeyCtrl  = 1 << 12
+)
+
+// hotkeyKeyCodes are the ANSI virtual key codes of the letter, digit, and
+// function keys; keyCodeForToken has the named ones.
+var hotkeyKeyCodes = map[string]int{
+	"a": 0, "s": 1, "d": 2, "f": 3, "h": 4, "g": 5, "z": 6, "x": 7, "c": 8, "v": 9,
+	"b": 11, "q": 12, "w": 13, "e": 14, "r": 15, "y": 16, "t": 17, "o": 31, "u": 32,
+	"i": 34, "p": 35, "l": 37, "j": 38, "k": 40, "n": 45, "m": 46,
+	"1": 18, "2": 19, "3": 20, "4": 21, "6": 22, "5": 23, "9": 25, "7": 26, "8": 28, "0": 29,
+	"f1": 122, "f2": 120, "f3": 99, "f4": 118, "f5": 96, "f6": 97,
+	"f7": 98, "f8": 100, "f9": 101, "f10": 109, "f11": 103, "f12": 111,
+}
+
+// hotkeyBinding is one hotkey the agent registers. Keep field names in sync
+// with HotkeyRequest in approval_action_notifier.swift.
+type hotkeyBinding struct {
+	Action    string `json:"action"`
+	Spec      string `json:"spec"`
+	KeyCode   int    `json:"key_code"`
+	Modifiers int    `json:"modifiers"`
+	Command   string `json:"command"`
+}
+
+type hotkeyRequest struct {
+	Hotkeys []hotkeyBinding `json:"hotkeys"`
+}
+
+func hotkeyEnv(action string) string {
+	return "CODEX_NOTIFY_HOTKEY_" + strings.ToUpper(action)
+}
+
+// parseHotkey reads a spec such as "ctrl+opt+cmd+a". It needs cmd, ctrl, or
+// opt: anything less would take the key away from every app.
+func parseHotkey(spec string) (keyCode, modifiers int, err error) {
+	key, names := splitKeyModifiers(strings.ToLower(strings.TrimSpace(spec)))
+	for _, name := range names {
+		switch name {
+		case "command down":
+			modifiers |= hotkeyCmd
+		case "shift down":
+			modifiers |= hotkeyShift
+		case "option down":
+			modifiers |= hotkeyOpt
+		case "control down":
+			modifiers |= hotkeyCtrl
+		}
+	}
+	if modifiers&^hotkeyShift == 0 {
+		return 0, 0, fmt.Errorf("hotkey %q needs cmd, ctrl, or opt", spec)
+	}
+	if code, ok := keyCodeForToken(key); ok {
+		return code, modifiers, nil
+	}
+	if code, ok := hotkeyKeyCodes[key]; ok {
+		return code, modifiers, nil
+	}
+	return 0, 0, fmt.Errorf("hotkey %q: unknown key %q", spec, key)
+}
+
+// configuredHotkeys are the valid hotkeys set in the environment, and an
+// error for each one that is not.
+func configuredHotkeys() ([]hotkeyBinding, []error) {
+	var bindings []hotkeyBinding
+	var errs []error
+	seen := map[string]string{}
+	for _, action := range hotkeyActions {
+		spec := strings.TrimSpace(getenv(hotkeyEnv(action)))
+		if spec == "" {
+			continue
+		}
+		code, modifiers, err := parseHotkey(spec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", action, err))
+			continue
+		}
+		key := fmt.Sprintf("%d/%d", code, modifiers)
+		if other, dup := seen[key]; dup {
+			errs = append(errs, fmt.Errorf("%s: hotkey %q is already bound to %s", action, spec, other))
+			continue
+		}
+		seen[key] = action
+		bindings = append(bindings, hotkeyBinding{
+			Action:    action,
+			Spec:      spec,
+			KeyCode:   code,
+			Modifiers: modifiers,
+			Command:   buildActionCommand(action, "") + " --latest",
+		})
+	}
+	return bindings, errs
+}
+
+// latestPendingApproval is the approval that was requested last and is
+// still unanswered, which is what `action --latest` and the hotkeys act on.
+func latestPendingApproval(now time.Time) (pendingApproval, bool) {
+	state, err := loadState()
+	if err != nil {
+		return pendingApproval{}, false
+	}
+	var pending []pendingApproval
+	for _, p := range state.PendingApprovals {
+		if now.Sub(time.Unix(p.Since, 0)) < widgetApprovalWindow {
+			pending = append(pending, p)
+		}
+	}
+	if len(pending) == 0 {
+		return pendingApproval{}, false
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Since != pending[j].Since {
+			return pending[i].Since > pending[j].Since
+		}
+		return pending[i].Thread < pending[j].Thread
+	})
+	return pending[0], true
+}
+
+// latestApprovalActions are the actions `--latest` may pick a thread for.
+var latestApprovalActions = map[string]bool{
+	"open":               true,
+	"approve":            true,
+	"reject":             true,
+	"reject-with-reason": true,
+	"choose":             true,
+	"snooze":             true,
+}
+
+// startHotkeyAgent runs the popup helper as the hotkey agent for as long as
+// the daemon runs. It is a no-op without hotkeys or off macOS.
+func startHotkeyAgent() func() {
+	bindings, errs := configuredHotkeys()
+	for _, err := range errs {
+		logErrorf("daemon: hotkey %v", err)
+	}
+	if len(bindings) == 0 || hostOS != "darwin" {
+		return func() {}
+	}
+	helperPath, err := ensureApprovalActionHelper()
+	if err != nil {
+		logErrorf("daemon: hotkeys disabled: %v", err)
+		return func() {}
+	}
+	body, err := json.Marshal(hotkeyRequest{Hotkeys: bindings})
+	if err != nil {
+		return func() {}
+	}
+	cmd := exec.Command(helperPath, hotkeyArg)
+	cmd.Stderr = os.Stderr
+	err = startWithStdin(cmd, body)
+	logExecStart(cmd, err)
+	if err != nil {
+		logErrorf("daemon: hotkeys disabled: %v", err)
+		return func() {}
+	}
+	var specs []string
+	for _, b := range bindings {
+		specs = append(specs, b.Spec+" "+b.Action)
+	}
+	logInfof("daemon hotkeys: %s", strings.Join(specs, ", "))
+	return func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}
+}
+
+func addHotkeyDoctorCheck(report *doctorReport) {
+	bindings, errs := configuredHotkeys()
+	if len(bindings) == 0 && len(errs) == 0 {
+		return
+	}
+	if len(errs) > 0 {
+		report.add(doctorDaemon, checkWarn, "hotkeys", errors.Join(errs...).Error(), true)
+		return
+	}
+	var specs []string
+	for _, b := range bindings {
+		specs = append(specs, b.Spec+" "+b.Action)
+	}
+	detail := strings.Join(specs, ", ")
+	if !daemonListening() {
+		report.add(doctorDaemon, checkWarn, "hotkeys", detail+" (registered only while `codex-notify daemon` runs)", false)
+		return
+	}
+	report.add(doctorDaemon, checkOK, "hotkeys", detail, false)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHotkey(t *testing.T) {
+	for spec, want := range map[string][2]int{
+		"ctrl+opt+cmd+a": {0, hotkeyCtrl | hotkeyOpt | hotkeyCmd},
+		"Cmd+Shift+R":    {15, hotkeyCmd | hotkeyShift},
+		"ctrl+f5":        {96, hotkeyCtrl},
+		"opt+return":     {36, hotkeyOpt},
+	} {
+		code, modifiers, err := parseHotkey(spec)
+		if err != nil || code != want[0] || modifiers != want[1] {
+			t.Fatalf("parseHotkey(%q) = %d, %#x, %v; want %d, %#x", spec, code, modifiers, err, want[0], want[1])
+		}
+	}
+	for _, spec := range []string{"a", "shift+a", "cmd+", "cmd+é", "hyper+a"} {
+		if _, _, err := parseHotkey(spec); err == nil {
+			t.Fatalf("parseHotkey(%q) error = nil, want an error", spec)
+		}
+	}
+}
+
+func TestConfiguredHotkeys(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_HOTKEY_APPROVE", "ctrl+opt+cmd+a")
+	t.Setenv("CODEX_NOTIFY_HOTKEY_REJECT", "cmd+opt+ctrl+A")
+	t.Setenv("CODEX_NOTIFY_HOTKEY_OPEN", "")
+
+	bindings, errs := configuredHotkeys()
+	if len(bindings) != 1 || bindings[0].Action != "approve" || !strings.HasSuffix(bindings[0].Command, " action 'approve' --latest") {
+		t.Fatalf("bindings = %+v", bindings)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "already bound to approve") {
+		t.Fatalf("errs = %v, want the duplicate reject hotkey reported", errs)
+	}
+}
+
+func TestLatestPendingApproval(t *testing.T) {
+	home, _ := useTempHome(t)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	if err := runAction([]string{"approve", "--latest", "--quiet"}); err != nil {
+		t.Fatalf("approve --latest without a pending approval: %v", err)
+	}
+	if err := runAction([]string{"submit", "--latest", "--quiet"}); exitCodeFor(err) != exitUsage {
+		t.Fatalf("submit --latest: err = %v, want a usage error", err)
+	}
+
+	now := time.Now()
+	trackApproval(map[string]any{"type": "approval-requested", "thread-id": "older", "cwd": "/src/api"}, now.Add(-time.Minute))
+	trackApproval(map[string]any{"type": "approval-requested", "thread-id": "newer", "cwd": "/src/web"}, now)
+	pending, ok := latestPendingApproval(now)
+	if !ok || pending.Thread != "newer" || pending.Cwd != "/src/web" {
+		t.Fatalf("latestPendingApproval = %+v, %v; want thread newer", pending, ok)
+	}
+	if _, found := claimApproval("newer", "", now); !found {
+		t.Fatal("claimApproval(newer) found nothing")
+	}
+	if pending, _ := latestPendingApproval(now); pending.Thread != "older" {
+		t.Fatalf("after answering newer, latest = %q, want older", pending.Thread)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// notificationIdentity is one [identities.<name>] table: how notifications
+// look for runs of a Codex profile or model, so a long review run and a
+// quick local one are told apart at a glance. Identities are tried in file
+// order and the first whose matchers all hold applies.
+type notificationIdentity struct {
+	Name string
+	// Profile and Model are globs on the payload's profile and model
+	// fields; an empty one matches anything.
+	Profile string
+	Model   string
+	// TitlePrefix goes in front of the notification title.
+	TitlePrefix string
+	// Icon is an image file, or an SF Symbol (popup) or icon theme name
+	// (notify-send).
+	Icon string
+	// Sound is a system sound name such as "Glass".
+	Sound string
+}
+
+// setIdentity parses one `identities.<name>.<key>` entry.
+func (cfg *userConfig) setIdentity(key string, value any) error {
+	return setNamedTable("identities", key, value, &cfg.Identities, func(name string) notificationIdentity { return notificationIdentity{Name: name} })
+}
+
+func (id notificationIdentity) tableName() string {
+	return id.Name
+}
+
+func (id *notificationIdentity) set(key string, value any) error {
+	prefix := "identities." + id.Name + "."
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%s%s must be a string", prefix, key)
+	}
+	s = strings.TrimSpace(s)
+	switch key {
+	case "profile", "model":
+		if _, err := path.Match(s, ""); err != nil {
+			return fmt.Errorf("%s%s: %w", prefix, key, err)
+		}
+		if key == "profile" {
+			id.Profile = s
+		} else {
+			id.Model = s
+		}
+	case "title_prefix":
+		id.TitlePrefix = s
+	case "icon":
+		if strings.HasPrefix(s, "~") || strings.Contains(s, "/") {
+			expanded, err := expandUserPath(s)
+			if err != nil {
+				return fmt.Errorf("%sicon: %w", prefix, err)
+			}
+			s = expanded
+		}
+		id.Icon = s
+	case "sound":
+		id.Sound = s
+	default:
+		return unknownSetting(prefix + key)
+	}
+	return nil
+}
+
+func payloadProfile(payload map[string]any) string {
+	return getStringAny(payload, "profile", "config-profile", "config_profile")
+}
+
+func payloadModel(payload map[string]any) string {
+	return getStringAny(payload, "model", "model-name", "model_name")
+}
+
+func (id notificationIdentity) matches(payload map[string]any) bool {
+	for _, m := range []struct{ glob, value string }{
+		{id.Profile, payloadProfile(payload)},
+		{id.Model, payloadModel(payload)},
+	} {
+		if m.glob == "" {
+			continue
+		}
+		if ok, _ := path.Match(m.glob, m.value); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// identityFor returns the first identity matching payload.
+func (c userConfig) identityFor(payload map[string]any) (notificationIdentity, bool) {
+	for _, id := range c.Identities {
+		if id.matches(payload) {
+			return id, true
+		}
+	}
+	return notificationIdentity{}, false
+}
+
+// apply dresses req in the identity.
+func (id notificationIdentity) apply(req *notificationRequest) {
+	if id.TitlePrefix != "" {
+		req.Title = id.TitlePrefix + " " + req.Title
+	}
+	req.Icon = id.Icon
+	req.Sound = id.Sound
+}
+
+// iconIsFile distinguishes an image path from a symbol or theme name.
+func iconIsFile(icon string) bool {
+	return strings.HasPrefix(icon, "/")
+}
+
+// describe summarizes the identity for doctor.
+func (id notificationIdentity) describe() string {
+	var match, style []string
+	if id.Profile != "" {
+		match = append(match, "profile "+id.Profile)
+	}
+	if id.Model != "" {
+		match = append(match, "model "+id.Model)
+	}
+	if len(match) == 0 {
+		match = append(match, "every run")
+	}
+	if id.TitlePrefix != "" {
+		style = append(style, fmt.Sprintf("title %q", id.TitlePrefix))
+	}
+	if id.Icon != "" {
+		style = append(style, "icon "+id.Icon)
+	}
+	if id.Sound != "" {
+		style = append(style, "sound "+id.Sound)
+	}
+	if len(style) == 0 {
+		style = append(style, "no changes")
+	}
+	return strings.Join(match, ", ") + ": " + strings.Join(style, ", ")
+}
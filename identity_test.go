@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIdentityForPayload(t *testing.T) {
+	cfg, err := parseUserConfig([]byte(`
+[identities.review]
+profile = "review*"
+model = "gpt-5-high"
+title_prefix = "[review]"
+icon = "magnifyingglass"
+sound = "Glass"
+[identities.local]
+model = "gpt-oss*"
+title_prefix = "[local]"
+`))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	tests := []struct {
+		payload map[string]any
+		want    string
+	}{
+		{map[string]any{"profile": "review-deep", "model": "gpt-5-high"}, "review"},
+		{map[string]any{"profile": "review-deep", "model": "gpt-5"}, ""},
+		{map[string]any{"model": "gpt-oss:20b"}, "local"},
+		{map[string]any{}, ""},
+	}
+	for _, tt := range tests {
+		id, _ := cfg.identityFor(tt.payload)
+		if id.Name != tt.want {
+			t.Errorf("identityFor(%v) = %q, want %q", tt.payload, id.Name, tt.want)
+		}
+	}
+	if got := cfg.Identities[0].describe(); got != `profile review*, model gpt-5-high: title "[review]", icon magnifyingglass, sound Glass` {
+		t.Errorf("describe() = %q", got)
+	}
+	if _, err := parseUserConfig([]byte("[identities.x]\ncolor = \"red\"\n")); err == nil {
+		t.Error("parseUserConfig() accepted an unknown identity key")
+	}
+}
+
+func TestIdentityStylesNotifications(t *testing.T) {
+	home, _ := useTempHome(t)
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "0")
+	path := filepath.Join(home, "config.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", path)
+	if err := os.WriteFile(path, []byte("[identities.review]\nmodel = \"gpt-5*\"\ntitle_prefix = \"[review]\"\nicon = \"~/icons/review.png\"\nsound = \"Glass\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs, err := buildHookNotifications(map[string]any{"type": "agent-turn-complete", "model": "gpt-5-high"})
+	if err != nil {
+		t.Fatalf("buildHookNotifications() error = %v", err)
+	}
+	req := reqs[0]
+	if !strings.HasPrefix(req.Title, "[review] ") || req.Icon != filepath.Join(home, "icons", "review.png") || req.Sound != "Glass" {
+		t.Fatalf("request = %+v", req)
+	}
+	if got := buildNativeApprovalContent(map[string]any{"type": "approval-requested", "model": "gpt-5"}); !strings.HasPrefix(got.Title, "[review] ") || got.Sound != "Glass" {
+		t.Fatalf("approval popup = %+v", got)
+	}
+	if reqs, _ := buildHookNotifications(map[string]any{"type": "agent-turn-complete", "model": "o3"}); reqs[0].Sound != "" || strings.HasPrefix(reqs[0].Title, "[review]") {
+		t.Fatalf("unmatched run styled: %+v", reqs[0])
+	}
+	args := strings.Join(notifySendIdentityArgs(req), " ")
+	if args != "--icon="+req.Icon+" --hint=string:sound-name:Glass" {
+		t.Fatalf("notify-send args = %q", args)
+	}
+}
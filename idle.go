@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// idleThreshold is CODEX_NOTIFY_IDLE_SECONDS: notify only once the user
+// has been away from keyboard and mouse that long. Zero turns it off.
+func idleThreshold() time.Duration {
+	raw := strings.TrimSpace(getenv("CODEX_NOTIFY_IDLE_SECONDS"))
+	if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return 0
+}
+
+// readHIDIdleTime returns the time since the last keyboard or mouse input,
+// as IOKit's HIDIdleTime reports it. Tests set the idle time through it.
+var readHIDIdleTime = func() (time.Duration, bool) {
+	if hostOS != "darwin" {
+		return 0, false
+	}
+	path, ok := lookupCmd("ioreg")
+	if !ok {
+		return 0, false
+	}
+	out, err := outputLogged(exec.Command(path, "-c", "IOHIDSystem", "-d", "4"))
+	if err != nil {
+		return 0, false
+	}
+	return parseHIDIdleTime(string(out))
+}
+
+// parseHIDIdleTime reads the first `"HIDIdleTime" = <ns>` line.
+func parseHIDIdleTime(out string) (time.Duration, bool) {
+	for _, line := range strings.Split(out, "\n") {
+		_, value, ok := strings.Cut(line, `"HIDIdleTime" =`)
+		if !ok {
+			continue
+		}
+		ns, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(ns), true
+	}
+	return 0, false
+}
+
+// userRecentlyActive reports whether the idle gate holds payload's
+// notification back: someone typing in the Codex terminal already sees its
+// prompt. HIDIdleTime counts input to any app, so the terminal must also be
+// frontmost. Approvals always go out, since one left unseen blocks the
+// turn, and an unknown idle time or frontmost app never suppresses.
+func userRecentlyActive(payload map[string]any) bool {
+	threshold := idleThreshold()
+	if threshold == 0 || payloadEventName(payload) == "approval-requested" {
+		return false
+	}
+	idle, ok := readHIDIdleTime()
+	if !ok || idle >= threshold {
+		return false
+	}
+	front := frontmostApp()
+	return front != "" && front == terminalBundleID()
+}
+
+func addIdleDoctorCheck(report *doctorReport) {
+	threshold := idleThreshold()
+	if threshold == 0 {
+		return
+	}
+	idle, ok := readHIDIdleTime()
+	if !ok {
+		report.add(doctorSettings, checkWarn, "idle gate", fmt.Sprintf("after %s idle, but HIDIdleTime is unavailable (notifications are never held back)", threshold), false)
+		return
+	}
+	report.add(doctorSettings, checkOK, "idle gate", fmt.Sprintf("after %s idle (idle now %s)", threshold, idle.Round(time.Second)), false)
+}
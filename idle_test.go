@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseHIDIdleTime(t *testing.T) {
+	out := "  | |   \"HIDIdleTime\" = 4521387500\n  | |   \"HIDParameters\" = {}\n"
+	if idle, ok := parseHIDIdleTime(out); !ok || idle != 4521387500*time.Nanosecond {
+		t.Fatalf("parseHIDIdleTime() = %v, %v", idle, ok)
+	}
+	if _, ok := parseHIDIdleTime("no idle here"); ok {
+		t.Fatal("parseHIDIdleTime() found a value in unrelated output")
+	}
+}
+
+func TestIdleGate(t *testing.T) {
+	useTempHome(t)
+	idle, known := 5*time.Second, true
+	stub(t, &readHIDIdleTime, func() (time.Duration, bool) { return idle, known })
+	front := terminalBundleID()
+	stub(t, &frontmostApp, func() string { return front })
+	turn := map[string]any{"type": "agent-turn-complete", "thread-id": "t1"}
+
+	t.Setenv("CODEX_NOTIFY_IDLE_SECONDS", "")
+	if userRecentlyActive(turn) {
+		t.Fatal("gate on without a threshold")
+	}
+	t.Setenv("CODEX_NOTIFY_IDLE_SECONDS", "60")
+	if result, err := deliverHookPayload(map[string]any{"type": "agent-turn-complete", "thread-id": "t1", "turn-id": "1"}); err != nil || result.Status != "active" {
+		t.Fatalf("deliverHookPayload() while active = %+v, %v", result, err)
+	}
+	if userRecentlyActive(map[string]any{"type": "approval-requested", "thread-id": "t1"}) {
+		t.Fatal("an approval was held back while active")
+	}
+	front = "com.google.Chrome"
+	if userRecentlyActive(turn) {
+		t.Fatal("typing in another app held a notification back")
+	}
+	front = ""
+	if userRecentlyActive(turn) {
+		t.Fatal("unknown frontmost app held a notification back")
+	}
+	front = terminalBundleID()
+	idle = 2 * time.Minute
+	if result, err := deliverHookPayload(map[string]any{"type": "agent-turn-complete", "thread-id": "t1", "turn-id": "2"}); err != nil || result.Status != "sent" {
+		t.Fatalf("deliverHookPayload() while idle = %+v, %v", result, err)
+	}
+	idle, known = 0, false
+	if userRecentlyActive(turn) {
+		t.Fatal("unknown idle time held a notification back")
+	}
+}
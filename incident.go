@@ -0,0 +1,276 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	defaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+	pagerDutyKeyEnv     = "CODEX_NOTIFY_PAGERDUTY_ROUTING_KEY"
+	onCallURLEnv        = "CODEX_NOTIFY_ONCALL_URL"
+)
+
+// defaultIncidentEvents only pages for failures; approvals and finished
+// turns can wait for the morning.
+var defaultIncidentEvents = []string{"agent-error"}
+
+var pagerDutySeverities = []string{"critical", "error", "warning", "info"}
+
+// pagerDutyConfig is the [pagerduty] table of config.toml: an Events API v2
+// integration. The sink is off until a routing key is set.
+type pagerDutyConfig struct {
+	// RoutingKey is the integration key; CODEX_NOTIFY_PAGERDUTY_ROUTING_KEY
+	// overrides it.
+	RoutingKey string
+	Severity   string
+	Events     []string
+	// URL is for EU accounts and test servers.
+	URL string
+}
+
+func (c pagerDutyConfig) routingKey() string {
+	if key := strings.TrimSpace(getenv(pagerDutyKeyEnv)); key != "" {
+		return secretValue(key)
+	}
+	return secretValue(c.RoutingKey)
+}
+
+func (c pagerDutyConfig) enabled() bool {
+	return c.routingKey() != ""
+}
+
+func (c pagerDutyConfig) wants(event string) bool {
+	return incidentWants(c.Events, event)
+}
+
+func (c pagerDutyConfig) severity() string {
+	if c.Severity == "" {
+		return "error"
+	}
+	return c.Severity
+}
+
+func (c pagerDutyConfig) url() string {
+	if c.URL == "" {
+		return defaultPagerDutyURL
+	}
+	return c.URL
+}
+
+// set parses one `pagerduty.<key>` entry.
+func (c *pagerDutyConfig) set(key string, value any) error {
+	if key == "events" {
+		events, err := parseEventList("pagerduty.events", value)
+		if err != nil {
+			return err
+		}
+		c.Events = events
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("pagerduty.%s must be a string", key)
+	}
+	s = strings.TrimSpace(s)
+	switch key {
+	case "routing_key":
+		c.RoutingKey = s
+	case "severity":
+		if !containsString(pagerDutySeverities, s) {
+			return fmt.Errorf("pagerduty.severity must be one of %s", strings.Join(pagerDutySeverities, ", "))
+		}
+		c.Severity = s
+	case "url":
+		if !strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://") {
+			return errors.New("pagerduty.url must be an http(s) URL")
+		}
+		c.URL = s
+	default:
+		return unknownSetting("pagerduty." + key)
+	}
+	return nil
+}
+
+// onCallConfig is the [oncall] table: a Grafana OnCall formatted-webhook
+// integration, whose URL is also its credential.
+type onCallConfig struct {
+	// URL is the integration URL; CODEX_NOTIFY_ONCALL_URL overrides it.
+	URL    string
+	Events []string
+}
+
+func (c onCallConfig) url() string {
+	if url := strings.TrimSpace(getenv(onCallURLEnv)); url != "" {
+		return secretValue(url)
+	}
+	return secretValue(c.URL)
+}
+
+func (c onCallConfig) enabled() bool {
+	return c.url() != ""
+}
+
+func (c onCallConfig) wants(event string) bool {
+	return incidentWants(c.Events, event)
+}
+
+// set parses one `oncall.<key>` entry.
+func (c *onCallConfig) set(key string, value any) error {
+	switch key {
+	case "events":
+		events, err := parseEventList("oncall.events", value)
+		if err != nil {
+			return err
+		}
+		c.Events = events
+	case "url":
+		s, ok := value.(string)
+		if !ok || !strings.HasPrefix(strings.TrimSpace(s), "https://") && !isSecretRef(s) {
+			return errors.New("oncall.url must be an https URL or a secret: reference")
+		}
+		c.URL = strings.TrimSpace(s)
+	default:
+		return unknownSetting("oncall." + key)
+	}
+	return nil
+}
+
+func incidentWants(events []string, event string) bool {
+	if events == nil {
+		events = defaultIncidentEvents
+	}
+	return containsString(events, event) || containsString(events, "all")
+}
+
+// incidentKey groups repeated errors of one Codex thread into one incident,
+// so a crash loop pages once.
+func incidentKey(payload map[string]any) string {
+	if thread := payloadThreadID(payload); thread != "" {
+		return appName + "-" + sanitizeID(thread)
+	}
+	if cwd := payloadCwd(payload); cwd != "" {
+		return appName + "-" + sanitizeID(cwd)
+	}
+	return ""
+}
+
+// incidentSummary is the one-line incident title: the notification title
+// and the plain-text message, with the project in front.
+func incidentSummary(payload map[string]any) (title, message string) {
+	title, message = renderPayloadMessage(payload)
+	message = renderMessage(message, formatPlain)
+	if project, ok := projectIdentityForCwd(payloadCwd(payload)); ok {
+		title = project.Name + ": " + title
+	}
+	return title, message
+}
+
+// pagerDutyEvent is an Events API v2 trigger.
+type pagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key,omitempty"`
+	Client      string           `json:"client"`
+	Payload     pagerDutyPayload `json:"payload"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Component     string            `json:"component,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+func buildPagerDutyEvent(cfg pagerDutyConfig, payload map[string]any) pagerDutyEvent {
+	title, message := incidentSummary(payload)
+	summary := title + ": " + message
+	// PagerDuty rejects summaries over 1024 characters.
+	if r := []rune(summary); len(r) > 1024 {
+		summary = string(r[:1021]) + "..."
+	}
+	source, err := os.Hostname()
+	if err != nil || source == "" {
+		source = appName
+	}
+
+	details := map[string]string{"message": message}
+	for key, value := range map[string]string{
+		"event":     payloadEventName(payload),
+		"cwd":       payloadCwd(payload),
+		"thread_id": payloadThreadID(payload),
+	} {
+		if value != "" {
+			details[key] = value
+		}
+	}
+	ev := pagerDutyEvent{
+		RoutingKey:  cfg.routingKey(),
+		EventAction: "trigger",
+		DedupKey:    incidentKey(payload),
+		Client:      appName,
+		Payload: pagerDutyPayload{
+			Summary:       summary,
+			Source:        source,
+			Severity:      cfg.severity(),
+			Class:         payloadEventName(payload),
+			CustomDetails: details,
+		},
+	}
+	if project, ok := projectIdentityForCwd(payloadCwd(payload)); ok {
+		ev.Payload.Component = project.Name
+	}
+	return ev
+}
+
+// onCallAlert is Grafana OnCall's formatted webhook body.
+type onCallAlert struct {
+	AlertUID string `json:"alert_uid,omitempty"`
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	State    string `json:"state"`
+}
+
+func buildOnCallAlert(payload map[string]any) onCallAlert {
+	title, message := incidentSummary(payload)
+	return onCallAlert{AlertUID: incidentKey(payload), Title: title, Message: message, State: "alerting"}
+}
+
+// postIncident sends body as JSON. The PagerDuty routing key and the
+// OnCall URL are credentials, so errors name the service only.
+func postIncident(service, url string, body any) error {
+	req, err := jsonRequest(url, body)
+	if err != nil {
+		return err
+	}
+	return publishWebhook(sinkHTTPClient, service, req)
+}
+
+func publishPagerDuty(cfg pagerDutyConfig, ev pagerDutyEvent) error {
+	return postIncident("PagerDuty", cfg.url(), ev)
+}
+
+func publishOnCall(cfg onCallConfig, alert onCallAlert) error {
+	return postIncident("Grafana OnCall", cfg.url(), alert)
+}
+
+// addIncidentDoctorChecks reports the configured incident sinks.
+func addIncidentDoctorChecks(report *doctorReport, cfg userConfig) {
+	events := func(list []string) string {
+		if list == nil {
+			list = defaultIncidentEvents
+		}
+		return strings.Join(list, ", ")
+	}
+	if cfg.PagerDuty.enabled() {
+		report.add(doctorSinks, checkOK, "pagerduty", fmt.Sprintf("severity %s (%s)", cfg.PagerDuty.severity(), events(cfg.PagerDuty.Events)), false)
+	}
+	if cfg.OnCall.enabled() {
+		report.add(doctorSinks, checkOK, "oncall", "webhook configured ("+events(cfg.OnCall.Events)+")", false)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBuildPagerDutyEvent(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "0")
+	t.Setenv(pagerDutyKeyEnv, "")
+	ev := buildPagerDutyEvent(pagerDutyConfig{RoutingKey: "R123"}, map[string]any{
+		"type":      "agent-error",
+		"thread-id": "t-1",
+		"cwd":       "/src/batch",
+		"message":   "stream disconnected **before** completion",
+	})
+
+	if ev.RoutingKey != "R123" || ev.EventAction != "trigger" || ev.DedupKey != "codex-notify-t-1" {
+		t.Fatalf("event = %+v", ev)
+	}
+	if ev.Payload.Severity != "error" || ev.Payload.Class != "agent-error" {
+		t.Fatalf("payload = %+v", ev.Payload)
+	}
+	if !strings.HasSuffix(ev.Payload.Summary, ": stream disconnected before completion") {
+		t.Fatalf("summary = %q", ev.Payload.Summary)
+	}
+	if ev.Payload.CustomDetails["cwd"] != "/src/batch" || ev.Payload.CustomDetails["thread_id"] != "t-1" {
+		t.Fatalf("custom details = %+v", ev.Payload.CustomDetails)
+	}
+}
+
+func TestIncidentSinksConfigAndPublish(t *testing.T) {
+	var gotPD pagerDutyEvent
+	var gotOnCall onCallAlert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		if r.URL.Path == "/pd" {
+			err = json.NewDecoder(r.Body).Decode(&gotPD)
+			w.WriteHeader(http.StatusAccepted)
+		} else {
+			err = json.NewDecoder(r.Body).Decode(&gotOnCall)
+		}
+		if err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv(pagerDutyKeyEnv, "")
+	t.Setenv(onCallURLEnv, "")
+	cfg, err := parseUserConfig([]byte("[pagerduty]\nrouting_key = \"R123\"\nseverity = \"critical\"\nurl = \"" + server.URL + "/pd\"\n[oncall]\nurl = \"https://oncall.example/integrations/v1/formatted_webhook/x/\"\n"))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	if !cfg.PagerDuty.wants("agent-error") || cfg.PagerDuty.wants("agent-turn-complete") || cfg.PagerDuty.wants("approval-requested") {
+		t.Fatalf("pagerduty events: %+v", cfg.PagerDuty)
+	}
+	if len(remoteSinks(cfg, map[string]any{"type": "agent-turn-complete"})) != 0 {
+		t.Fatal("incident sinks fired for a finished turn")
+	}
+
+	// The environment wins over the file, which keeps the URL out of it.
+	t.Setenv(onCallURLEnv, server.URL+"/oncall")
+	sinks := remoteSinks(cfg, map[string]any{"type": "agent-error", "message": "boom"})
+	if len(sinks) != 2 || sinks[0].Name != "pagerduty" || sinks[1].Name != "oncall" {
+		t.Fatalf("remoteSinks() = %+v", sinks)
+	}
+	for _, sink := range sinks {
+		if err := sink.Publish(); err != nil {
+			t.Fatalf("%s Publish() error = %v", sink.Name, err)
+		}
+	}
+	if gotPD.Payload.Severity != "critical" || !strings.Contains(gotPD.Payload.Summary, "boom") {
+		t.Fatalf("pagerduty got %+v", gotPD)
+	}
+	if gotOnCall.State != "alerting" || gotOnCall.Message != "boom" {
+		t.Fatalf("oncall got %+v", gotOnCall)
+	}
+
+	for _, bad := range []string{
+		"[pagerduty]\nseverity = \"page-me\"\n",
+		"[oncall]\nurl = \"http://insecure\"\n",
+		"[pagerduty]\nescalation = \"x\"\n",
+	} {
+		if _, err := parseUserConfig([]byte(bad)); err == nil {
+			t.Errorf("parseUserConfig(%q) succeeded, want an error", bad)
+		}
+	}
+}
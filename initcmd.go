@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+func runInit(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	replace := fs.Bool("replace", false, "replace existing notify setting")
+	chain := fs.Bool("chain", false, "keep an existing notify command and run it after codex-notify")
+	config := fs.String("config", "", "path to Codex config.toml")
+	manageTUI := fs.Bool("manage-tui-notifications", false, "turn off Codex TUI notifications in a managed block")
+	launchd := fs.Bool("launchd", false, "install a LaunchAgent that runs the daemon at login")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	out := outFlags.output()
+	if *chain && *replace {
+		return usageError(errors.New("--chain and --replace cannot be combined"))
+	}
+
+	if *launchd {
+		path, err := installLaunchAgent()
+		if err != nil {
+			return err
+		}
+		out.Printf("loaded LaunchAgent: %s\n", path)
+	}
+
+	paths, err := resolveConfigTargets(*config)
+	if err != nil {
+		return configError(err)
+	}
+	if created, err := scaffoldUserConfig(); err != nil {
+		out.Printf("warning: could not create codex-notify config: %v\n", err)
+	} else if created != "" {
+		out.Printf("created codex-notify config: %s\n", created)
+	}
+	if hostOS == "darwin" && notificationUIStyle() == notificationUIPopup {
+		if path, elapsed, err := prewarmHelper(); err != nil {
+			out.Printf("warning: %v (popups will fall back to system notifications)\n", err)
+		} else {
+			out.Printf("popup helper ready: %s (%s)\n", path, elapsed)
+		}
+	}
+	switch version, style, err := detectCodexHookStyle(); {
+	case err != nil:
+		out.Printf("note: %v; configuring the notify hook every current Codex reads\n", err)
+	case style == codexHookNone:
+		out.Printf("warning: %s\n", legacyCodexAdvice(version))
+	default:
+		out.Printf("codex %s: configuring the %s hook\n", version, style)
+	}
+	return runForConfigTargets("init", paths, out, func(cfgPath string) (commandResult, error) {
+		return withConfigLock(cfgPath, func() (commandResult, error) {
+			return initCodexConfig(cfgPath, *replace, *chain, *manageTUI, out)
+		})
+	})
+}
+
+func initCodexConfig(cfgPath string, replace, chain, manageTUI bool, out commandOutput) (commandResult, error) {
+	existing, err := readFileMaybe(cfgPath)
+	if err != nil {
+		return commandResult{}, configError(err)
+	}
+
+	if len(existing) == 0 {
+		if err := os.MkdirAll(filepath.Dir(cfgPath), 0o755); err != nil {
+			return commandResult{}, configError(fmt.Errorf("create config dir: %w", err))
+		}
+
+		content, _, _ := setManagedNotifyBlock(nil, false)
+		if manageTUI {
+			content, _, _ = setManagedTUINotifications(content, false)
+		}
+		if err := writeConfigChecked(cfgPath, existing, content); err != nil {
+			return commandResult{}, configError(fmt.Errorf("write config: %w", err))
+		}
+		out.Printf("created %s and configured notify hook\n", cfgPath)
+		return commandResult{Command: "init", Status: "created", Config: cfgPath}, nil
+	}
+
+	updated, chained := existing, []string(nil)
+	if chain {
+		if updated, chained, err = chainManagedNotifyBlock(existing); err != nil {
+			return commandResult{}, configError(err)
+		}
+	}
+	updated, changed, err := setManagedNotifyBlock(updated, replace)
+	if err != nil {
+		return commandResult{}, configError(err)
+	}
+	if len(chained) > 0 {
+		// The chain goes in first: a Codex config without its notifier
+		// and no chain to run it would drop it.
+		userCfgPath, set, err := setUserConfigChain(chained)
+		if err != nil {
+			return commandResult{}, configError(err)
+		}
+		if set {
+			out.Printf("set chain = %s in %s\n", strings.Join(chained, " "), userCfgPath)
+		}
+		changed = true
+	}
+	if manageTUI {
+		withTUI, tuiChanged, err := setManagedTUINotifications(updated, replace)
+		if err != nil {
+			return commandResult{}, configError(err)
+		}
+		updated = withTUI
+		changed = changed || tuiChanged
+	}
+
+	if !changed {
+		out.Printf("notify hook already configured in %s\n", cfgPath)
+		return commandResult{Command: "init", Status: "unchanged", Config: cfgPath}, nil
+	}
+
+	backupPath, err := createBackup(cfgPath, existing)
+	if err != nil {
+		return commandResult{}, configError(err)
+	}
+
+	if err := writeConfigChecked(cfgPath, existing, updated); err != nil {
+		return commandResult{}, configError(fmt.Errorf("update config: %w", err))
+	}
+
+	out.Printf("updated %s\n", cfgPath)
+	out.Printf("backup created: %s\n", backupPath)
+	return commandResult{Command: "init", Status: "updated", Config: cfgPath, Backup: backupPath}, nil
+}
+
+func runUninstall(args []string) error {
+	fs := flag.NewFlagSet("uninstall", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	restore := fs.Bool("restore-config", true, "restore latest config backup")
+	config := fs.String("config", "", "path to Codex config.toml")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	out := outFlags.output()
+
+	paths, err := resolveConfigTargets(*config)
+	if err != nil {
+		return configError(err)
+	}
+	if removed, err := removeLaunchAgent(); errors.Is(err, errForeignLaunchAgent) {
+		out.Printf("left LaunchAgent %s alone: %v\n", removed, err)
+	} else if err != nil {
+		return err
+	} else if removed != "" {
+		out.Printf("removed LaunchAgent: %s\n", removed)
+	}
+	return runForConfigTargets("uninstall", paths, out, func(cfgPath string) (commandResult, error) {
+		return withConfigLock(cfgPath, func() (commandResult, error) {
+			return uninstallCodexConfig(cfgPath, *restore, out)
+		})
+	})
+}
+
+func uninstallCodexConfig(cfgPath string, restore bool, out commandOutput) (commandResult, error) {
+	current, err := readFileMaybe(cfgPath)
+	if err != nil {
+		return commandResult{}, configError(err)
+	}
+	if len(current) == 0 {
+		out.Printf("config not found: %s\n", cfgPath)
+		return commandResult{Command: "uninstall", Status: "not-found", Config: cfgPath}, nil
+	}
+
+	if restore {
+		latest, err := findLatestBackup(cfgPath)
+		if err != nil {
+			return commandResult{}, configError(err)
+		}
+		backupContent, err := os.ReadFile(latest)
+		if err != nil {
+			return commandResult{}, configError(fmt.Errorf("read backup: %w", err))
+		}
+		if err := writeConfigChecked(cfgPath, current, backupContent); err != nil {
+			return commandResult{}, configError(fmt.Errorf("restore config: %w", err))
+		}
+		out.Printf("restored %s from %s\n", cfgPath, latest)
+		return commandResult{Command: "uninstall", Status: "restored", Config: cfgPath, Source: latest}, nil
+	}
+
+	updated, removed := removeCodexNotifyLine(current)
+	updated, removedBlocks := removeManagedBlocks(updated)
+	removed = removed || removedBlocks
+	if !removed {
+		out.Printf("no codex-notify line found in %s; nothing changed\n", cfgPath)
+		return commandResult{Command: "uninstall", Status: "unchanged", Config: cfgPath}, nil
+	}
+
+	backupPath, err := createBackup(cfgPath, current)
+	if err != nil {
+		return commandResult{}, configError(err)
+	}
+
+	if err := writeConfigChecked(cfgPath, current, updated); err != nil {
+		return commandResult{}, configError(fmt.Errorf("write config: %w", err))
+	}
+
+	out.Printf("removed codex-notify line from %s\n", cfgPath)
+	out.Printf("backup created: %s\n", backupPath)
+	return commandResult{Command: "uninstall", Status: "removed", Config: cfgPath, Backup: backupPath}, nil
+}
+
+func resolveConfigPath(configFlag string) (string, error) {
+	if configFlag != "" {
+		return configFlag, nil
+	}
+	if codexHome := strings.TrimSpace(getenv("CODEX_HOME")); codexHome != "" {
+		return filepath.Join(codexHome, "config.toml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve home: %w", err)
+	}
+	return filepath.Join(home, ".codex", "config.toml"), nil
+}
+
+func readFileMaybe(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err == nil {
+		return b, nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("read %s: %w", path, err)
+}
+
+func configHasCodexNotify(content []byte) (bool, error) {
+	lines := splitLines(content)
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if isCodexNotifyHookLine(trimmed) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func removeCodexNotifyLine(content []byte) ([]byte, bool) {
+	lines := splitLines(content)
+	out := make([]string, 0, len(lines))
+	removed := false
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if isCodexNotifyHookLine(trimmed) {
+			removed = true
+			continue
+		}
+		out = append(out, line)
+	}
+
+	joined := strings.Join(out, "\n")
+	if strings.TrimSpace(joined) == "" {
+		return []byte{}, removed
+	}
+	return []byte(joined + "\n"), removed
+}
+
+func splitLines(content []byte) []string {
+	normalized := bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	scanner := bufio.NewScanner(bytes.NewReader(normalized))
+	lines := []string{}
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+func createBackup(configPath string, content []byte) (string, error) {
+	timestamp := fmt.Sprintf("%d", time.Now().UnixNano())
+	backupPath := fmt.Sprintf("%s.bak.%s", configPath, timestamp)
+	if err := writeFileAtomic(backupPath, content, 0o644); err != nil {
+		return "", fmt.Errorf("write backup: %w", err)
+	}
+	return backupPath, nil
+}
+
+func findLatestBackup(configPath string) (string, error) {
+	pattern := regexp.QuoteMeta(configPath) + `\.bak\.\d+$`
+	re := regexp.MustCompile(pattern)
+
+	dir := filepath.Dir(configPath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("read config dir: %w", err)
+	}
+
+	backups := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if re.MatchString(path) {
+			backups = append(backups, path)
+		}
+	}
+
+	if len(backups) == 0 {
+		return "", errors.New("no backup found; cannot restore")
+	}
+
+	sort.Strings(backups)
+	return backups[len(backups)-1], nil
+}
+
+func writeFileAtomic(path string, content []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	cleanup := func() {
+		_ = os.Remove(tmpPath)
+	}
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		cleanup()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+
+	if err := tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		cleanup()
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		cleanup()
+		return fmt.Errorf("close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		cleanup()
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
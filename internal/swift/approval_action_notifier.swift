@@ -1,9 +1,12 @@
 import AppKit
+import Carbon
 import Foundation
 
 struct Choice {
     let label: String
     let command: String
+    // rememberCommand runs instead of command when the remember box is ticked.
+    let rememberCommand: String
 }
 
 struct Config {
@@ -13,7 +16,21 @@ struct Config {
     let timeoutSeconds: Int
     let dismissOnActivateBundleID: String
     let interactionLockFile: String
+    let receiptFile: String
+    let withdrawFile: String
+    let accentColor: NSColor
+    let icon: String
+    let sound: String
     let choices: [Choice]
+    let rememberLabel: String
+    // environment, when set, replaces the helper's own for choice commands:
+    // a helper serving the daemon runs them with the hook's variables.
+    let environment: [String: String]
+    // expiresAt, when set, is the approval's deadline. The message's last
+    // line is then the expiry line, which the popup counts down to it.
+    let expiresAt: Date?
+    let expiresInFormat: String
+    let expiredText: String
 }
 
 private struct PopupSettings: Codable {
@@ -59,60 +76,129 @@ private func savePopupTimeoutSetting(_ seconds: Int) {
     }
 }
 
-private func parseArgs(_ args: [String]) -> Config {
-    func value(_ key: String) -> String? {
-        guard let idx = args.firstIndex(of: key), idx + 1 < args.count else {
-            return nil
-        }
-        return args[idx + 1]
+// HelperRequest is the JSON document written to stdin by codex-notify.
+// Keep field names in sync with helperRequest in main.go.
+private struct HelperRequest: Decodable {
+    struct RequestChoice: Decodable {
+        let label: String?
+        let command: String?
+        let rememberCommand: String?
     }
 
-    func values(_ key: String) -> [String] {
-        var out: [String] = []
-        var idx = 0
-        while idx < args.count {
-            if args[idx] == key && idx + 1 < args.count {
-                out.append(args[idx + 1])
-                idx += 2
-                continue
+    let title: String?
+    let message: String?
+    let identifier: String?
+    let category: String?
+    let timeoutSeconds: Int?
+    let dismissOnActivateBundleId: String?
+    let interactionLockFile: String?
+    let receiptFile: String?
+    let accentColor: String?
+    let withdrawFile: String?
+    let icon: String?
+    let sound: String?
+    let choices: [RequestChoice]?
+    let rememberLabel: String?
+    let env: [String: String]?
+    let expiresAt: Int?
+    let expiresInFormat: String?
+    let expiredText: String?
+}
+
+private func colorFromHex(_ raw: String?) -> NSColor? {
+    guard var hex = raw?.trimmingCharacters(in: .whitespacesAndNewlines), !hex.isEmpty else {
+        return nil
+    }
+    if hex.hasPrefix("#") {
+        hex.removeFirst()
+    }
+    guard hex.count == 6, let value = UInt32(hex, radix: 16) else {
+        return nil
+    }
+    return NSColor(
+        srgbRed: CGFloat((value >> 16) & 0xFF) / 255,
+        green: CGFloat((value >> 8) & 0xFF) / 255,
+        blue: CGFloat(value & 0xFF) / 255,
+        alpha: 1
+    )
+}
+
+// markdownMessage renders inline Markdown (bold, italics, code spans, link
+// text) for the message label. AppKit ignores presentation intents, so they
+// are mapped to fonts here.
+private func markdownMessage(_ text: String, font: NSFont, color: NSColor) -> NSAttributedString? {
+    guard #available(macOS 12.0, *) else {
+        return nil
+    }
+    let options = AttributedString.MarkdownParsingOptions(interpretedSyntax: .inlineOnlyPreservingWhitespace)
+    guard let parsed = try? AttributedString(markdown: text, options: options) else {
+        return nil
+    }
+
+    let paragraph = NSMutableParagraphStyle()
+    paragraph.lineBreakMode = .byTruncatingTail
+    let result = NSMutableAttributedString()
+    for run in parsed.runs {
+        var runFont = font
+        if let intent = run.inlinePresentationIntent {
+            if intent.contains(.code) {
+                runFont = NSFont.monospacedSystemFont(ofSize: font.pointSize, weight: .regular)
+            }
+            if intent.contains(.stronglyEmphasized) {
+                runFont = NSFontManager.shared.convert(runFont, toHaveTrait: .boldFontMask)
+            }
+            if intent.contains(.emphasized) {
+                runFont = NSFontManager.shared.convert(runFont, toHaveTrait: .italicFontMask)
             }
-            idx += 1
         }
-        return out
+        result.append(NSAttributedString(
+            string: String(parsed[run.range].characters),
+            attributes: [.font: runFont, .foregroundColor: color, .paragraphStyle: paragraph]
+        ))
     }
+    return result
+}
 
-    let title = value("--title") ?? "Codex: Approval Requested"
-    let message = value("--message") ?? "承認待ちです。"
-    let identifier = value("--identifier") ?? ""
-    let dismissOnActivateBundleID = value("--dismiss-on-activate-bundle-id")?
-        .trimmingCharacters(in: .whitespacesAndNewlines) ?? ""
-    let interactionLockFile = value("--interaction-lock-file") ?? ""
+private func readRequest() -> Config {
+    decodeRequest(FileHandle.standardInput.readDataToEndOfFile())
+}
 
-    let timeoutRaw = value("--timeout-seconds") ?? "45"
-    let timeoutParsed = Int(timeoutRaw) ?? 45
-    let timeoutSeconds = max(5, min(300, timeoutParsed))
+private func decodeRequest(_ data: Data) -> Config {
+    let decoder = JSONDecoder()
+    decoder.keyDecodingStrategy = .convertFromSnakeCase
 
-    let labels = values("--choice-label")
-    let commands = values("--choice-cmd")
-    let count = min(labels.count, commands.count)
+    var request: HelperRequest?
+    do {
+        request = try decoder.decode(HelperRequest.self, from: data)
+    } catch {
+        fputs("failed to parse helper request: \(error)\n", stderr)
+    }
+
+    let title = request?.title ?? "Codex: Approval Requested"
+    let message = request?.message ?? "承認待ちです。"
+    let identifier = request?.identifier ?? ""
+    let dismissOnActivateBundleID = request?.dismissOnActivateBundleId?
+        .trimmingCharacters(in: .whitespacesAndNewlines) ?? ""
+    let interactionLockFile = request?.interactionLockFile ?? ""
+    let receiptFile = request?.receiptFile ?? ""
+    let timeoutSeconds = max(5, min(300, request?.timeoutSeconds ?? 45))
 
     var choices: [Choice] = []
-    if count > 0 {
-        for i in 0..<count {
-            let label = labels[i].trimmingCharacters(in: .whitespacesAndNewlines)
-            let command = commands[i].trimmingCharacters(in: .whitespacesAndNewlines)
-            if label.isEmpty || command.isEmpty {
-                continue
-            }
-            choices.append(Choice(label: label, command: command))
+    for choice in request?.choices ?? [] {
+        let label = (choice.label ?? "").trimmingCharacters(in: .whitespacesAndNewlines)
+        let command = (choice.command ?? "").trimmingCharacters(in: .whitespacesAndNewlines)
+        if label.isEmpty || command.isEmpty {
+            continue
         }
+        let rememberCommand = (choice.rememberCommand ?? "").trimmingCharacters(in: .whitespacesAndNewlines)
+        choices.append(Choice(label: label, command: command, rememberCommand: rememberCommand))
     }
 
     if choices.isEmpty {
         choices = [
-            Choice(label: "Open", command: ""),
-            Choice(label: "Approve", command: ""),
-            Choice(label: "Reject", command: "")
+            Choice(label: "Open", command: "", rememberCommand: ""),
+            Choice(label: "Approve", command: "", rememberCommand: ""),
+            Choice(label: "Reject", command: "", rememberCommand: "")
         ]
     }
 
@@ -123,10 +209,33 @@ private func parseArgs(_ args: [String]) -> Config {
         timeoutSeconds: timeoutSeconds,
         dismissOnActivateBundleID: dismissOnActivateBundleID,
         interactionLockFile: interactionLockFile,
-        choices: choices
+        receiptFile: receiptFile,
+        withdrawFile: request?.withdrawFile?.trimmingCharacters(in: .whitespacesAndNewlines) ?? "",
+        accentColor: colorFromHex(request?.accentColor) ?? NSColor.controlAccentColor,
+        icon: request?.icon?.trimmingCharacters(in: .whitespacesAndNewlines) ?? "",
+        sound: request?.sound?.trimmingCharacters(in: .whitespacesAndNewlines) ?? "",
+        choices: choices,
+        rememberLabel: request?.rememberLabel?.trimmingCharacters(in: .whitespacesAndNewlines) ?? "",
+        environment: request?.env ?? [:],
+        expiresAt: (request?.expiresAt ?? 0) > 0 ? Date(timeIntervalSince1970: TimeInterval(request?.expiresAt ?? 0)) : nil,
+        expiresInFormat: request?.expiresInFormat ?? "Expires in %s",
+        expiredText: request?.expiredText ?? "Expired — open the terminal to answer."
     )
 }
 
+// formatCountdown matches formatCountdown in expiry.go: "45s", "2m30s",
+// "1h05m".
+private func formatCountdown(_ seconds: Int) -> String {
+    if seconds < 60 {
+        return "\(seconds)s"
+    }
+    if seconds < 3600 {
+        let s = seconds % 60
+        return s == 0 ? "\(seconds / 60)m" : String(format: "%ldm%02lds", seconds / 60, s)
+    }
+    return String(format: "%ldh%02ldm", seconds / 3600, (seconds % 3600) / 60)
+}
+
 private func clearInteractionLock(_ path: String) {
     let trimmed = path.trimmingCharacters(in: .whitespacesAndNewlines)
     guard !trimmed.isEmpty else {
@@ -135,7 +244,43 @@ private func clearInteractionLock(_ path: String) {
     _ = try? FileManager.default.removeItem(atPath: trimmed)
 }
 
-private func runShell(_ command: String) {
+private func appendReceipt(_ path: String, identifier: String, status: String, choice: String) {
+    let trimmed = path.trimmingCharacters(in: .whitespacesAndNewlines)
+    guard !trimmed.isEmpty else {
+        return
+    }
+
+    var record: [String: String] = [
+        "time": ISO8601DateFormatter().string(from: Date()),
+        "id": identifier,
+        "backend": "popup",
+        "status": status
+    ]
+    if !choice.isEmpty {
+        record["choice"] = choice
+    }
+
+    guard var data = try? JSONSerialization.data(withJSONObject: record, options: [.sortedKeys]) else {
+        return
+    }
+    data.append(0x0A)
+
+    if !FileManager.default.fileExists(atPath: trimmed) {
+        FileManager.default.createFile(atPath: trimmed, contents: nil, attributes: [.posixPermissions: 0o600])
+    }
+    guard let handle = FileHandle(forWritingAtPath: trimmed) else {
+        return
+    }
+    handle.seekToEndOfFile()
+    handle.write(data)
+    handle.closeFile()
+}
+
+// servingPopups is set under --serve, where one process shows every popup
+// and a command must not hold up the others while it runs.
+private var servingPopups = false
+
+private func runShell(_ command: String, environment: [String: String] = [:]) {
     guard !command.isEmpty else {
         return
     }
@@ -143,6 +288,9 @@ private func runShell(_ command: String) {
     let process = Process()
     process.executableURL = URL(fileURLWithPath: "/bin/zsh")
     process.arguments = ["-lc", command]
+    if !environment.isEmpty {
+        process.environment = environment
+    }
 
     if let nullOut = FileHandle(forWritingAtPath: "/dev/null") {
         process.standardOutput = nullOut
@@ -151,7 +299,13 @@ private func runShell(_ command: String) {
 
     do {
         try process.run()
-        process.waitUntilExit()
+        if servingPopups {
+            DispatchQueue.global().async {
+                process.waitUntilExit()
+            }
+        } else {
+            process.waitUntilExit()
+        }
     } catch {
         fputs("failed to run action command: \(error)\n", stderr)
     }
@@ -170,6 +324,13 @@ private func choiceIntent(for label: String, index: Int, total: Int) -> ChoiceIn
     normalized = normalized.replacingOccurrences(of: "-", with: "")
     normalized = normalized.replacingOccurrences(of: "_", with: "")
 
+    if normalized.hasPrefix("mute") {
+        return .secondary
+    }
+    if normalized.hasPrefix("rejectwith") {
+        return .destructive
+    }
+
     switch normalized {
     case "open", "focus", "show", "view":
         return .neutral
@@ -392,12 +553,21 @@ final class PopupController: NSObject {
     private var panel: PopupPanel?
     private var timeoutTimer: Timer?
     private var progressTimer: Timer?
+    private var withdrawTimer: Timer?
     private var appActivationObserver: NSObjectProtocol?
     private var progressFill: NSView?
+    private var messageLabel: NSTextField?
+    private var renderedExpiryLine = ""
+    private var rememberCheckbox: NSButton?
     private var progressTrackWidth: CGFloat = 0
     private var openedAt = Date()
     private var isClosing = false
+    private var closeStatus = "dismissed"
+    private var closeChoice = ""
     private var timeoutSeconds: Int
+    // onClose, when set, runs once the popup is gone in place of ending
+    // the process, for a helper that serves more popups.
+    var onClose: (() -> Void)?
     private let fixedWidth: CGFloat = 392
     private let fixedHeight: CGFloat = 168
     private let horizontalPadding: CGFloat = 14
@@ -463,12 +633,12 @@ final class PopupController: NSObject {
         let tint = NSView(frame: root.bounds)
         tint.autoresizingMask = [.width, .height]
         tint.wantsLayer = true
-        tint.layer?.backgroundColor = NSColor.controlAccentColor.withAlphaComponent(0.08).cgColor
+        tint.layer?.backgroundColor = config.accentColor.withAlphaComponent(0.08).cgColor
         root.addSubview(tint)
 
         let accentBar = NSView(frame: NSRect(x: 0, y: 0, width: 4, height: panelHeight))
         accentBar.wantsLayer = true
-        accentBar.layer?.backgroundColor = NSColor.controlAccentColor.withAlphaComponent(0.85).cgColor
+        accentBar.layer?.backgroundColor = config.accentColor.withAlphaComponent(0.85).cgColor
         root.addSubview(accentBar)
 
         let headerHeight: CGFloat = 30
@@ -477,15 +647,20 @@ final class PopupController: NSObject {
         let iconBack = NSView(frame: NSRect(x: horizontalPadding, y: headerY + 7, width: 18, height: 18))
         iconBack.wantsLayer = true
         iconBack.layer?.cornerRadius = 9
-        iconBack.layer?.backgroundColor = NSColor.controlAccentColor.withAlphaComponent(0.2).cgColor
+        iconBack.layer?.backgroundColor = config.accentColor.withAlphaComponent(0.2).cgColor
         root.addSubview(iconBack)
 
         let iconView = NSImageView(frame: NSRect(x: horizontalPadding + 2, y: headerY + 9, width: 14, height: 14))
-        if #available(macOS 11.0, *) {
-            iconView.image = NSImage(systemSymbolName: "bolt.fill", accessibilityDescription: nil)
+        // The icon is an image file or an SF Symbol name.
+        if config.icon.hasPrefix("/"), let image = NSImage(contentsOfFile: config.icon) {
+            iconView.image = image
+            iconView.imageScaling = .scaleProportionallyUpOrDown
+        } else if #available(macOS 11.0, *) {
+            iconView.image = NSImage(systemSymbolName: config.icon, accessibilityDescription: nil)
+                ?? NSImage(systemSymbolName: "bolt.fill", accessibilityDescription: nil)
             iconView.symbolConfiguration = NSImage.SymbolConfiguration(pointSize: 10, weight: .medium)
         }
-        iconView.contentTintColor = NSColor.controlAccentColor
+        iconView.contentTintColor = config.accentColor
         root.addSubview(iconView)
 
         let trailingButtonsWidth: CGFloat = 96
@@ -541,8 +716,13 @@ final class PopupController: NSObject {
             messageCell.wraps = true
             messageCell.lineBreakMode = .byTruncatingTail
             messageCell.usesSingleLineMode = false
+            messageCell.truncatesLastVisibleLine = true
+        }
+        if let rendered = markdownMessage(config.message, font: NSFont.systemFont(ofSize: 12, weight: .regular), color: .secondaryLabelColor) {
+            messageLabel.attributedStringValue = rendered
         }
         root.addSubview(messageLabel)
+        self.messageLabel = messageLabel
 
         let progressHeight: CGFloat = 3
         let progressY = messageY - 9 - progressHeight
@@ -556,7 +736,7 @@ final class PopupController: NSObject {
         progressFill.autoresizingMask = [.height]
         progressFill.wantsLayer = true
         progressFill.layer?.cornerRadius = progressHeight / 2
-        progressFill.layer?.backgroundColor = NSColor.controlAccentColor.withAlphaComponent(0.95).cgColor
+        progressFill.layer?.backgroundColor = config.accentColor.withAlphaComponent(0.95).cgColor
         progressTrack.addSubview(progressFill)
         root.addSubview(progressTrack)
         self.progressFill = progressFill
@@ -565,11 +745,20 @@ final class PopupController: NSObject {
         let readMoreButton = NSButton(title: "Read more", target: self, action: #selector(showReadMore))
         readMoreButton.isBordered = false
         readMoreButton.font = NSFont.systemFont(ofSize: 10, weight: .semibold)
-        readMoreButton.contentTintColor = NSColor.controlAccentColor
+        readMoreButton.contentTintColor = config.accentColor
         readMoreButton.frame = NSRect(x: width - horizontalPadding - 66, y: progressY + progressHeight + 1, width: 66, height: 14)
         readMoreButton.alignment = .right
         root.addSubview(readMoreButton)
 
+        if !config.rememberLabel.isEmpty && config.choices.contains(where: { !$0.rememberCommand.isEmpty }) {
+            let checkbox = NSButton(checkboxWithTitle: config.rememberLabel, target: nil, action: nil)
+            checkbox.font = NSFont.systemFont(ofSize: 10, weight: .medium)
+            checkbox.frame = NSRect(x: horizontalPadding, y: progressY + progressHeight + 1, width: width - (horizontalPadding * 2) - 72, height: 14)
+            checkbox.state = .off
+            root.addSubview(checkbox)
+            self.rememberCheckbox = checkbox
+        }
+
         let availableButtonsTop = progressY - 8
         let availableButtonsBottom: CGFloat = 12
         let availableButtonsHeight = max(36, availableButtonsTop - availableButtonsBottom)
@@ -612,9 +801,15 @@ final class PopupController: NSObject {
         self.panel = panel
         openedAt = Date()
         startDismissOnActivateObserver()
+        startWithdrawWatch()
         panel.alphaValue = 1
         panel.setFrame(finalFrame, display: true)
         panel.orderFrontRegardless()
+        if config.sound.hasPrefix("/") {
+            NSSound(contentsOfFile: config.sound, byReference: true)?.play()
+        } else if !config.sound.isEmpty {
+            NSSound(named: NSSound.Name(config.sound))?.play()
+        }
         scheduleTimeoutCountdown()
     }
 
@@ -642,6 +837,23 @@ final class PopupController: NSObject {
         }
     }
 
+    // startWithdrawWatch closes the popup once codex-notify creates the
+    // withdraw file, because the approval was answered on another device.
+    private func startWithdrawWatch() {
+        let path = config.withdrawFile
+        guard !path.isEmpty, withdrawTimer == nil else {
+            return
+        }
+        withdrawTimer = Timer.scheduledTimer(withTimeInterval: 0.5, repeats: true) { [weak self] _ in
+            guard let self, FileManager.default.fileExists(atPath: path) else {
+                return
+            }
+            _ = try? FileManager.default.removeItem(atPath: path)
+            self.closeStatus = "withdrawn"
+            self.closePopup()
+        }
+    }
+
     private func stopDismissOnActivateObserver() {
         guard let appActivationObserver else {
             return
@@ -678,7 +890,7 @@ final class PopupController: NSObject {
                 continue
             }
             if choiceIntent(for: choice.label, index: index, total: config.choices.count) == .neutral {
-                runShell(command)
+                runShell(command, environment: config.environment)
                 return true
             }
         }
@@ -687,6 +899,8 @@ final class PopupController: NSObject {
     }
 
     @objc private func showReadMore() {
+        closeStatus = "clicked"
+        closeChoice = "Read more"
         if activateReadMoreTarget() {
             closePopup()
             return
@@ -704,6 +918,30 @@ final class PopupController: NSObject {
         var frame = fill.frame
         frame.size.width = progressTrackWidth * CGFloat(ratio)
         fill.frame = frame
+        updateExpiryLine()
+    }
+
+    // updateExpiryLine re-renders the message when the time left, in the
+    // units formatCountdown shows, has changed since the last tick.
+    private func updateExpiryLine() {
+        guard let deadline = config.expiresAt, let label = messageLabel else {
+            return
+        }
+        let left = Int(deadline.timeIntervalSinceNow.rounded())
+        let line = left > 0
+            ? config.expiresInFormat.replacingOccurrences(of: "%s", with: formatCountdown(left))
+            : config.expiredText
+        if line == renderedExpiryLine {
+            return
+        }
+        renderedExpiryLine = line
+        var lines = config.message.components(separatedBy: "\n")
+        lines[lines.count - 1] = line
+        let message = lines.joined(separator: "\n")
+        label.stringValue = message
+        if let rendered = markdownMessage(message, font: NSFont.systemFont(ofSize: 12, weight: .regular), color: .secondaryLabelColor) {
+            label.attributedStringValue = rendered
+        }
     }
 
     @objc private func showPopupMenu(_ sender: NSButton) {
@@ -743,7 +981,14 @@ final class PopupController: NSObject {
             closePopup()
             return
         }
-        runShell(config.choices[idx].command)
+        let choice = config.choices[idx]
+        closeStatus = "clicked"
+        closeChoice = choice.label
+        if rememberCheckbox?.state == .on && !choice.rememberCommand.isEmpty {
+            runShell(choice.rememberCommand, environment: config.environment)
+        } else {
+            runShell(choice.command, environment: config.environment)
+        }
         closePopup()
     }
 
@@ -766,7 +1011,7 @@ final class PopupController: NSObject {
         timeoutTimer = Timer.scheduledTimer(
             timeInterval: TimeInterval(timeoutSeconds),
             target: self,
-            selector: #selector(closePopup),
+            selector: #selector(timeoutExpired),
             userInfo: nil,
             repeats: false
         )
@@ -779,21 +1024,29 @@ final class PopupController: NSObject {
         )
     }
 
+    @objc private func timeoutExpired() {
+        closeStatus = "expired"
+        closePopup()
+    }
+
     @objc private func closePopup() {
         if isClosing {
             return
         }
         isClosing = true
+        appendReceipt(config.receiptFile, identifier: config.identifier, status: closeStatus, choice: closeChoice)
 
         timeoutTimer?.invalidate()
         timeoutTimer = nil
         progressTimer?.invalidate()
         progressTimer = nil
+        withdrawTimer?.invalidate()
+        withdrawTimer = nil
         stopDismissOnActivateObserver()
         releaseInteractionLock()
 
         guard let panel else {
-            NSApp.terminate(nil)
+            finish()
             return
         }
 
@@ -807,9 +1060,18 @@ final class PopupController: NSObject {
         }, completionHandler: {
             panel.orderOut(nil)
             self.panel = nil
-            NSApp.terminate(nil)
+            self.finish()
         })
     }
+
+    private func finish() {
+        if let onClose {
+            self.onClose = nil
+            onClose()
+            return
+        }
+        NSApp.terminate(nil)
+    }
 }
 
 final class AppDelegate: NSObject, NSApplicationDelegate {
@@ -845,7 +1107,147 @@ final class AppDelegate: NSObject, NSApplicationDelegate {
     }
 }
 
-let config = parseArgs(CommandLine.arguments)
+// `--probe` lets codex-notify check that a prebuilt helper runs on this Mac
+// before relying on it.
+if CommandLine.arguments.contains("--probe") {
+    print("ok")
+    exit(0)
+}
+
+// HotkeyRequest is the JSON document `codex-notify daemon` writes to stdin
+// with --hotkeys. Keep field names in sync with hotkeyBinding in hotkey.go.
+private struct HotkeyRequest: Decodable {
+    struct Binding: Decodable {
+        let action: String?
+        let keyCode: Int?
+        let modifiers: Int?
+        let command: String?
+    }
+
+    let hotkeys: [Binding]?
+}
+
+private var hotkeyCommands: [UInt32: String] = [:]
+
+// runHotkeyAgent registers the daemon's global hotkeys with Carbon, which
+// needs no Accessibility permission, and runs a binding's command when its
+// key is pressed. It runs until the daemon stops it.
+private func runHotkeyAgent() -> Never {
+    let data = FileHandle.standardInput.readDataToEndOfFile()
+    let decoder = JSONDecoder()
+    decoder.keyDecodingStrategy = .convertFromSnakeCase
+    guard let request = try? decoder.decode(HotkeyRequest.self, from: data) else {
+        fputs("failed to parse hotkey request\n", stderr)
+        exit(1)
+    }
+
+    var eventType = EventTypeSpec(eventClass: OSType(kEventClassKeyboard), eventKind: UInt32(kEventHotKeyPressed))
+    InstallEventHandler(GetApplicationEventTarget(), { _, event, _ in
+        var hotKeyID = EventHotKeyID()
+        GetEventParameter(
+            event,
+            EventParamName(kEventParamDirectObject),
+            EventParamType(typeEventHotKeyID),
+            nil,
+            MemoryLayout<EventHotKeyID>.size,
+            nil,
+            &hotKeyID
+        )
+        if let command = hotkeyCommands[hotKeyID.id] {
+            DispatchQueue.global().async {
+                runShell(command)
+            }
+        }
+        return noErr
+    }, 1, &eventType, nil, nil)
+
+    var refs: [EventHotKeyRef?] = []
+    for (index, binding) in (request.hotkeys ?? []).enumerated() {
+        guard let keyCode = binding.keyCode, let command = binding.command, !command.isEmpty else {
+            continue
+        }
+        let id = UInt32(index + 1)
+        var ref: EventHotKeyRef?
+        let status = RegisterEventHotKey(
+            UInt32(keyCode),
+            UInt32(binding.modifiers ?? 0),
+            EventHotKeyID(signature: OSType(0x434E_4854), id: id), // "CNHT"
+            GetApplicationEventTarget(),
+            0,
+            &ref
+        )
+        if status != noErr {
+            // Usually another app holds the same combination.
+            fputs("failed to register hotkey for \(binding.action ?? "action"): \(status)\n", stderr)
+            continue
+        }
+        hotkeyCommands[id] = command
+        refs.append(ref)
+    }
+    if refs.isEmpty {
+        exit(1)
+    }
+
+    let app = NSApplication.shared
+    app.setActivationPolicy(.accessory)
+    withExtendedLifetime(refs) {
+        app.run()
+    }
+    exit(0)
+}
+
+if CommandLine.arguments.contains("--hotkeys") {
+    runHotkeyAgent()
+}
+
+private var servedControllers: [PopupController] = []
+private var serveInputClosed = false
+
+// runPopupServer keeps one helper running for `codex-notify daemon`: each
+// line on stdin is a HelperRequest, shown as soon as it arrives, so a popup
+// costs no process launch. "ready" on stdout tells the daemon the helper
+// understands --serve. Once stdin closes, the helper exits after its last
+// popup.
+private func runPopupServer() -> Never {
+    servingPopups = true
+    let app = NSApplication.shared
+    app.setActivationPolicy(.accessory)
+    DispatchQueue.global().async {
+        while let line = readLine() {
+            guard !line.isEmpty else {
+                continue
+            }
+            let data = Data(line.utf8)
+            DispatchQueue.main.async {
+                let controller = PopupController(config: decodeRequest(data))
+                controller.onClose = { [weak controller] in
+                    servedControllers.removeAll { $0 === controller }
+                    if serveInputClosed && servedControllers.isEmpty {
+                        exit(0)
+                    }
+                }
+                servedControllers.append(controller)
+                controller.show()
+            }
+        }
+        DispatchQueue.main.async {
+            serveInputClosed = true
+            if servedControllers.isEmpty {
+                exit(0)
+            }
+        }
+    }
+    print("ready")
+    fflush(stdout)
+    app.run()
+    exit(0)
+}
+
+if CommandLine.arguments.contains("--serve") {
+    runPopupServer()
+}
+
+let config = readRequest()
 let previousFrontmostApp = NSWorkspace.shared.frontmostApplication
 let app = NSApplication.shared
 app.setActivationPolicy(.accessory)
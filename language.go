@@ -0,0 +1,148 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	languageAuto = "auto"
+	languageEn   = "en"
+	languageJa   = "ja"
+	// languageMixed keeps the original wording: English titles with
+	// Japanese fallback text.
+	languageMixed = "mixed"
+)
+
+// notificationStrings is the boilerplate codex-notify adds around Codex's
+// own text.
+type notificationStrings struct {
+	TurnCompleteTitle  string
+	SucceededTitle     string
+	NeedsInputTitle    string
+	TestsFailedTitle   string
+	BlockedTitle       string
+	FailedTitle        string
+	ApprovalTitle      string
+	ErrorTitle         string
+	ApproveTitle       string
+	RejectTitle        string
+	WaitingForInput    string
+	WaitingForApproval string
+	ErrorReceived      string
+	EventReceived      string
+	EventFormat        string
+	ClickToApprove     string
+	ClickToReject      string
+	ExpiresIn          string
+	Expired            string
+}
+
+var englishStrings = notificationStrings{
+	TurnCompleteTitle:  "Codex: Turn Complete",
+	SucceededTitle:     "Codex: ✅ Turn Complete",
+	NeedsInputTitle:    "Codex: ❓ Needs Input",
+	TestsFailedTitle:   "Codex: ❌ Tests Failed",
+	BlockedTitle:       "Codex: ⛔ Blocked",
+	FailedTitle:        "Codex: ❌ Turn Failed",
+	ApprovalTitle:      "Codex: Approval Requested",
+	ErrorTitle:         "Codex: Error",
+	ApproveTitle:       "Codex: Approve",
+	RejectTitle:        "Codex: Reject",
+	WaitingForInput:    "Waiting for your input.",
+	WaitingForApproval: "Waiting for approval.",
+	ErrorReceived:      "Received an error event.",
+	EventReceived:      "Received a notification event.",
+	EventFormat:        "Event: %s",
+	ClickToApprove:     "Click to send the approve keys",
+	ClickToReject:      "Click to send the reject keys",
+	ExpiresIn:          "Expires in %s",
+	Expired:            "Expired — open the terminal to answer.",
+}
+
+var japaneseStrings = notificationStrings{
+	TurnCompleteTitle:  "Codex: ターン完了",
+	SucceededTitle:     "Codex: ✅ ターン完了",
+	NeedsInputTitle:    "Codex: ❓ 入力が必要",
+	TestsFailedTitle:   "Codex: ❌ テスト失敗",
+	BlockedTitle:       "Codex: ⛔ ブロック中",
+	FailedTitle:        "Codex: ❌ ターン失敗",
+	ApprovalTitle:      "Codex: 承認リクエスト",
+	ErrorTitle:         "Codex: エラー",
+	ApproveTitle:       "Codex: 承認",
+	RejectTitle:        "Codex: 拒否",
+	WaitingForInput:    "入力待ちです。",
+	WaitingForApproval: "承認待ちです。",
+	ErrorReceived:      "エラーイベントを受信しました。",
+	EventReceived:      "通知イベントを受信しました。",
+	EventFormat:        "イベント: %s",
+	ClickToApprove:     "クリックで承認入力を送信",
+	ClickToReject:      "クリックで拒否入力を送信",
+	ExpiresIn:          "あと %s で期限切れ",
+	Expired:            "期限切れです。ターミナルを開いて回答してください。",
+}
+
+func notificationLanguageSetting() string {
+	switch v := strings.TrimSpace(strings.ToLower(getenv("CODEX_NOTIFY_LANGUAGE"))); v {
+	case languageEn, languageJa, languageMixed:
+		return v
+	default:
+		return languageAuto
+	}
+}
+
+// stringsForText picks the boilerplate for a notification whose Codex text
+// is text: the configured language, else the language of text, else the
+// mixed wording codex-notify has always used.
+func stringsForText(text string) notificationStrings {
+	lang := notificationLanguageSetting()
+	if lang == languageAuto {
+		lang = detectLanguage(text)
+	}
+	if lang == "" {
+		lang = languageMixed
+	}
+	switch lang {
+	case languageJa:
+		return japaneseStrings
+	case languageMixed:
+		mixed := japaneseStrings
+		mixed.TurnCompleteTitle = englishStrings.TurnCompleteTitle
+		mixed.SucceededTitle = englishStrings.SucceededTitle
+		mixed.NeedsInputTitle = englishStrings.NeedsInputTitle
+		mixed.TestsFailedTitle = englishStrings.TestsFailedTitle
+		mixed.BlockedTitle = englishStrings.BlockedTitle
+		mixed.FailedTitle = englishStrings.FailedTitle
+		mixed.ApprovalTitle = englishStrings.ApprovalTitle
+		mixed.ErrorTitle = englishStrings.ErrorTitle
+		mixed.ApproveTitle = englishStrings.ApproveTitle
+		mixed.RejectTitle = englishStrings.RejectTitle
+		return mixed
+	default:
+		return englishStrings
+	}
+}
+
+// detectLanguage reports "ja" for text with kana or mostly kanji, "en" for
+// text with Latin letters, and "" when text has no letters to go by.
+func detectLanguage(text string) string {
+	var kana, han, latin int
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			latin++
+		}
+	}
+	switch {
+	case kana > 0 || han > latin:
+		return languageJa
+	case latin > 0:
+		return languageEn
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"Added 4 tests for POST /login.": languageEn,
+		"入力待ちです。":                        languageJa,
+		"テストを追加しました (go test ./...)":     languageJa,
+		"修正完了":                           languageJa,
+		"12345 ...":                      "",
+		"":                               "",
+	}
+	for text, want := range tests {
+		if got := detectLanguage(text); got != want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestRenderPayloadMessageMatchesLanguage(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_LANGUAGE", "")
+	t.Setenv("CODEX_NOTIFY_TURN_OUTCOMES", "0")
+
+	tests := []struct {
+		name        string
+		setting     string
+		message     string
+		wantTitle   string
+		wantMessage string
+	}{
+		{"japanese text", "", "入力待ちです。", "Codex: ターン完了", "入力待ちです。"},
+		{"english text", "", "All tests pass.", "Codex: Turn Complete", "All tests pass."},
+		{"no text keeps old wording", "", "", "Codex: Turn Complete", "入力待ちです。"},
+		{"forced japanese", "ja", "All tests pass.", "Codex: ターン完了", "All tests pass."},
+		{"mixed keeps old wording", "mixed", "", "Codex: Turn Complete", "入力待ちです。"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CODEX_NOTIFY_LANGUAGE", tt.setting)
+			payload := map[string]any{"type": "agent-turn-complete"}
+			if tt.message != "" {
+				payload["last-assistant-message"] = tt.message
+			}
+			title, message := renderPayloadMessage(payload)
+			if title != tt.wantTitle || message != tt.wantMessage {
+				t.Fatalf("renderPayloadMessage() = %q, %q, want %q, %q", title, message, tt.wantTitle, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const launchAgentLabel = "com.github.miupa.codex-notify"
+
+func launchAgentPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve home dir: %w", err)
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", launchAgentLabel+".plist"), nil
+}
+
+// daemonExecutable prefers the codex-notify found on PATH when it is this
+// binary, so the agent keeps working after a Homebrew upgrade replaces the
+// versioned Cellar path.
+func daemonExecutable() (string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("resolve executable: %w", err)
+	}
+	if onPath, ok := lookupCmd(appName); ok {
+		a, errA := filepath.EvalSymlinks(onPath)
+		b, errB := filepath.EvalSymlinks(self)
+		if errA == nil && errB == nil && a == b {
+			return onPath, nil
+		}
+	}
+	return self, nil
+}
+
+// launchAgentPlist renders a LaunchAgent that starts `codex-notify daemon`
+// at login and restarts it if it exits. env becomes its
+// EnvironmentVariables, since launchd does not pass on the login shell's.
+func launchAgentPlist(executable, logPath string, env map[string]string) string {
+	escape := func(s string) string {
+		var b strings.Builder
+		_ = xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	var environment strings.Builder
+	if len(env) > 0 {
+		environment.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		keys := make([]string, 0, len(env))
+		for key := range env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&environment, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", escape(key), escape(env[key]))
+		}
+		environment.WriteString("\t</dict>\n")
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+		<string>daemon</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>ProcessType</key>
+	<string>Interactive</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+%s</dict>
+</plist>
+`, launchAgentLabel, escape(executable), escape(logPath), environment.String())
+}
+
+func launchctlDomain() string {
+	return fmt.Sprintf("gui/%d", os.Getuid())
+}
+
+// installLaunchAgent writes the plist and (re)loads it. It returns the plist
+// path.
+func installLaunchAgent() (string, error) {
+	if hostOS != "darwin" {
+		return "", usageError(errors.New("--launchd is only available on macOS"))
+	}
+	launchctl, ok := lookupCmd("launchctl")
+	if !ok {
+		return "", errors.New("launchctl not found")
+	}
+	path, err := launchAgentPath()
+	if err != nil {
+		return "", err
+	}
+	executable, err := daemonExecutable()
+	if err != nil {
+		return "", err
+	}
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		return "", err
+	}
+
+	content := launchAgentPlist(executable, filepath.Join(stateDir, "daemon.log"), nil)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("create LaunchAgents dir: %w", err)
+	}
+	if err := writeFileAtomic(path, []byte(content), 0o644); err != nil {
+		return "", fmt.Errorf("write %s: %w", path, err)
+	}
+
+	// bootout fails when the agent is not loaded yet, which is fine.
+	_ = runLogged(exec.Command(launchctl, "bootout", launchctlDomain()+"/"+launchAgentLabel))
+	if out, err := combinedOutputLogged(exec.Command(launchctl, "bootstrap", launchctlDomain(), path)); err != nil {
+		return "", fmt.Errorf("launchctl bootstrap failed: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+	return path, nil
+}
+
+// errForeignLaunchAgent reports a plist under codex-notify's label that
+// does not run `codex-notify daemon`, such as one the user wrote by hand.
+var errForeignLaunchAgent = errors.New("it does not run codex-notify daemon")
+
+// launchAgentProgramRE matches the ProgramArguments launchAgentPlist
+// writes: an executable followed by the daemon subcommand.
+var launchAgentProgramRE = regexp.MustCompile(`<key>ProgramArguments</key>\s*<array>\s*<string>([^<]+)</string>\s*<string>daemon</string>\s*</array>`)
+
+// ownLaunchAgent reports whether plist is one launchAgentPlist wrote:
+// codex-notify's label, running some codex-notify binary as the daemon.
+func ownLaunchAgent(plist []byte) bool {
+	if !strings.Contains(string(plist), "<string>"+launchAgentLabel+"</string>") {
+		return false
+	}
+	m := launchAgentProgramRE.FindSubmatch(plist)
+	return m != nil && filepath.Base(html.UnescapeString(string(m[1]))) == appName
+}
+
+// removeLaunchAgent unloads and deletes the agent. It returns the removed
+// plist path, or "" when no agent was installed. A plist that is not
+// codex-notify's is left loaded and in place, with errForeignLaunchAgent.
+func removeLaunchAgent() (string, error) {
+	path, err := launchAgentPath()
+	if err != nil {
+		return "", err
+	}
+	plist, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", path, err)
+	}
+	if !ownLaunchAgent(plist) {
+		return path, errForeignLaunchAgent
+	}
+	if launchctl, ok := lookupCmd("launchctl"); ok {
+		_ = runLogged(exec.Command(launchctl, "bootout", launchctlDomain()+"/"+launchAgentLabel))
+	}
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("remove %s: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLaunchAgentPlist(t *testing.T) {
+	plist := launchAgentPlist("/opt/homebrew/bin/codex-notify", "/Users/a&b/Library/Caches/codex-notify/daemon.log", nil)
+
+	for _, want := range []string{
+		"<string>" + launchAgentLabel + "</string>",
+		"<string>/opt/homebrew/bin/codex-notify</string>\n\t\t<string>daemon</string>",
+		"<key>RunAtLoad</key>\n\t<true/>",
+		"<key>KeepAlive</key>\n\t<true/>",
+		"<string>/Users/a&amp;b/Library/Caches/codex-notify/daemon.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Fatalf("launchAgentPlist() missing %q:\n%s", want, plist)
+		}
+	}
+}
+
+func TestRemoveLaunchAgent(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PATH", t.TempDir())
+
+	if removed, err := removeLaunchAgent(); err != nil || removed != "" {
+		t.Fatalf("removeLaunchAgent(none installed) = %q, %v, want no-op", removed, err)
+	}
+
+	path := filepath.Join(home, "Library", "LaunchAgents", launchAgentLabel+".plist")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(launchAgentPlist("codex-notify", "daemon.log", nil)), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if removed, err := removeLaunchAgent(); err != nil || removed != path {
+		t.Fatalf("removeLaunchAgent() = %q, %v, want %q", removed, err, path)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("plist still exists after removeLaunchAgent()")
+	}
+
+	// A plist of the user's own under the same label stays.
+	foreign := strings.Replace(launchAgentPlist("/usr/local/bin/my-notifier", "daemon.log", nil), "<string>daemon</string>", "<string>serve</string>", 1)
+	if err := os.WriteFile(path, []byte(foreign), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := removeLaunchAgent(); !errors.Is(err, errForeignLaunchAgent) {
+		t.Fatalf("removeLaunchAgent(foreign) error = %v, want errForeignLaunchAgent", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("foreign plist removed: %v", err)
+	}
+	if !ownLaunchAgent([]byte(launchAgentPlist("/opt/homebrew/bin/codex-notify", "daemon.log", map[string]string{"PATH": "/usr/bin"}))) {
+		t.Fatalf("ownLaunchAgent() = false for a plist launchAgentPlist wrote")
+	}
+}
+
+func TestServicePlistBakesInSettings(t *testing.T) {
+	home, _ := useTempHome(t)
+	t.Setenv("PATH", "/opt/homebrew/bin:/usr/bin")
+	t.Setenv("CODEX_NOTIFY_APPROVAL_UI", "multi")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", filepath.Join(home, "notify & co.toml"))
+
+	env := serviceEnv()
+	if env["CODEX_NOTIFY_APPROVAL_UI"] != "multi" || env["PATH"] != "/opt/homebrew/bin:/usr/bin" {
+		t.Fatalf("serviceEnv() = %v", env)
+	}
+	plist := launchAgentPlist("/opt/homebrew/bin/codex-notify", "daemon.log", env)
+	for _, want := range []string{
+		"<key>EnvironmentVariables</key>\n\t<dict>\n\t\t<key>CODEX_NOTIFY_APPROVAL_UI</key>\n\t\t<string>multi</string>\n",
+		"<string>" + home + "/notify &amp; co.toml</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Fatalf("plist missing %q:\n%s", want, plist)
+		}
+	}
+
+	brew := brewServiceBlock(map[string]string{"CODEX_NOTIFY_APPROVAL_UI": "multi", "PATH": `/a"#{b}`})
+	if !strings.Contains(brew, `run [opt_bin/"codex-notify", "daemon"]`) || !strings.Contains(brew, `environment_variables CODEX_NOTIFY_APPROVAL_UI: "multi", PATH: "/a\"\#{b}"`) {
+		t.Fatalf("brewServiceBlock() =\n%s", brew)
+	}
+
+	if err := runService([]string{"plist", "--quiet"}); err != nil {
+		t.Fatalf("service plist error = %v", err)
+	}
+	written, err := os.ReadFile(filepath.Join(home, "Library", "LaunchAgents", launchAgentLabel+".plist"))
+	if err != nil || !strings.Contains(string(written), "<key>EnvironmentVariables</key>") {
+		t.Fatalf("written plist = %s, %v", written, err)
+	}
+	if err := runService([]string{"unit"}); exitCodeFor(err) != exitUsage {
+		t.Fatalf("service unit = %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// linuxKeyTool picks the keystroke tool for the session: wtype under
+// Wayland, xdotool under X11. It returns "" when the tool is missing.
+func linuxKeyTool() (name, path string) {
+	name = "xdotool"
+	if getenv("WAYLAND_DISPLAY") != "" {
+		name = "wtype"
+	}
+	path, ok := lookupCmd(name)
+	if !ok {
+		return name, ""
+	}
+	return name, path
+}
+
+// linuxKeystrokesSupported reports whether keys can be typed into the
+// terminal: a keystroke tool is installed and the terminal's window class
+// is set, so activateLinuxTerminal can put the keys in the right window.
+func linuxKeystrokesSupported() bool {
+	if strings.TrimSpace(getenv("CODEX_NOTIFY_TERMINAL_WM_CLASS")) == "" {
+		return false
+	}
+	_, path := linuxKeyTool()
+	return path != ""
+}
+
+// linuxKeysyms maps the named keys of a sequence to X keysyms, which both
+// xdotool and wtype accept.
+var linuxKeysyms = map[string]string{
+	"enter":    "Return",
+	"return":   "Return",
+	"tab":      "Tab",
+	"esc":      "Escape",
+	"escape":   "Escape",
+	"space":    "space",
+	"up":       "Up",
+	"down":     "Down",
+	"left":     "Left",
+	"right":    "Right",
+	"home":     "Home",
+	"end":      "End",
+	"pageup":   "Page_Up",
+	"pagedown": "Page_Down",
+}
+
+// linuxModifiers maps splitKeyModifiers' AppleScript names to the
+// modifier names xdotool and wtype use; cmd becomes the Super key.
+var linuxModifiers = map[string]string{
+	"command down": "super",
+	"control down": "ctrl",
+	"option down":  "alt",
+	"shift down":   "shift",
+}
+
+// linuxKeyArgs is the command line for one sequence token: a named key,
+// text to type, or either with modifiers such as "ctrl+l".
+func linuxKeyArgs(tool, token string) []string {
+	key, appleModifiers := splitKeyModifiers(token)
+	modifiers := make([]string, 0, len(appleModifiers))
+	for _, m := range appleModifiers {
+		modifiers = append(modifiers, linuxModifiers[m])
+	}
+	keysym, named := linuxKeysyms[strings.ToLower(strings.TrimSpace(key))]
+
+	if tool == "wtype" {
+		var args []string
+		for _, m := range modifiers {
+			args = append(args, "-M", m)
+		}
+		if named {
+			args = append(args, "-k", keysym)
+		} else {
+			args = append(args, "--", key)
+		}
+		for _, m := range modifiers {
+			args = append(args, "-m", m)
+		}
+		return args
+	}
+
+	switch {
+	case named:
+		return []string{"key", "--clearmodifiers", strings.Join(append(modifiers, keysym), "+")}
+	case len(modifiers) > 0:
+		return []string{"key", "--clearmodifiers", strings.Join(append(modifiers, key), "+")}
+	default:
+		return []string{"type", "--clearmodifiers", "--delay", "0", "--", key}
+	}
+}
+
+// sendLinuxKeySequence types seq into the focused window, which
+// sendActionKeys has just made the terminal.
+func sendLinuxKeySequence(seq []string, threadID string) error {
+	tool, path := linuxKeyTool()
+	if path == "" {
+		return fmt.Errorf("%s not found (needed to send keys on linux)", tool)
+	}
+	if strings.TrimSpace(getenv("CODEX_NOTIFY_TERMINAL_WM_CLASS")) == "" {
+		return errors.New("set CODEX_NOTIFY_TERMINAL_WM_CLASS so keys go to the terminal window")
+	}
+	for _, token := range seq {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		if out, err := combinedOutputLogged(exec.Command(path, linuxKeyArgs(tool, token)...)); err != nil {
+			if threadID != "" {
+				return fmt.Errorf("send key for thread %s: %w (%s)", threadID, err, strings.TrimSpace(string(out)))
+			}
+			return fmt.Errorf("send key: %w (%s)", err, strings.TrimSpace(string(out)))
+		}
+		time.Sleep(80 * time.Millisecond)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// lockQueueLimit caps events held while locked; the oldest go first.
+	lockQueueLimit = 100
+	// lockQueuePollInterval is how often the daemon checks for an unlock
+	// while events are held.
+	lockQueuePollInterval = 5 * time.Second
+)
+
+// lockedEvent is a hook payload held back while the screen was locked.
+type lockedEvent struct {
+	Time    int64          `json:"time"`
+	Payload map[string]any `json:"payload"`
+}
+
+// readScreenLocked reports whether the console session is locked. Popups
+// and key injection cannot reach anyone then, so desktop notifications
+// wait for the unlock.
+var readScreenLocked = func() bool {
+	if hostOS != "darwin" {
+		return false
+	}
+	path, ok := lookupCmd("ioreg")
+	if !ok {
+		return false
+	}
+	out, err := outputLogged(exec.Command(path, "-n", "Root", "-d1"))
+	if err != nil {
+		return false
+	}
+	return parseIoregScreenLocked(string(out))
+}
+
+func parseIoregScreenLocked(out string) bool {
+	return strings.Contains(strings.ReplaceAll(out, " ", ""), `"CGSSessionScreenIsLocked"=Yes`)
+}
+
+func screenLockQueueActive() bool {
+	return featureEnabled("lock_queue") && readScreenLocked()
+}
+
+// queueLockedEvent holds payload until flushLockQueue.
+func queueLockedEvent(payload map[string]any, now time.Time) error {
+	return updateState(func(s *notifyState) {
+		s.LockQueue = append(s.LockQueue, lockedEvent{Time: now.Unix(), Payload: payload})
+		if over := len(s.LockQueue) - lockQueueLimit; over > 0 {
+			s.LockQueue = s.LockQueue[over:]
+		}
+	})
+}
+
+// flushLockQueue delivers what was held while the screen was locked:
+// approvals still waiting for an answer come back as themselves, and the
+// rest as one summary. It returns how many notifications it sent.
+func flushLockQueue() int {
+	if state, err := loadState(); err != nil || len(state.LockQueue) == 0 {
+		return 0
+	}
+	var held []lockedEvent
+	if err := updateState(func(s *notifyState) {
+		held, s.LockQueue = s.LockQueue, nil
+	}); err != nil {
+		return 0
+	}
+
+	var (
+		approvals []map[string]any
+		others    []map[string]any
+		seen      = map[string]bool{}
+	)
+	// Newest first, so a thread that asked twice is asked once.
+	for i := len(held) - 1; i >= 0; i-- {
+		payload := held[i].Payload
+		if payloadEventName(payload) != "approval-requested" {
+			others = append([]map[string]any{payload}, others...)
+			continue
+		}
+		thread := payloadThreadID(payload)
+		if seen[thread] {
+			continue
+		}
+		seen[thread] = true
+		// Answered in the terminal or from the phone in the meantime.
+		if _, pending := lookupPendingApproval(thread); !pending {
+			continue
+		}
+		approvals = append([]map[string]any{payload}, approvals...)
+	}
+
+	sent := 0
+	if len(others) == 1 {
+		approvals = append(others, approvals...)
+	} else if len(others) > 1 {
+		req := heldSummaryNotification(fmt.Sprintf("Codex Notify: %d events while locked", len(others)), others)
+		if sendNotification(req) == nil {
+			sent++
+		}
+	}
+	for _, payload := range approvals {
+		sent += redeliverNotification(payload)
+	}
+	return sent
+}
+
+// redeliverNotification shows payload again the way the hook showed it,
+// past the hook's checks, and returns how many notifications it sent.
+func redeliverNotification(payload map[string]any) int {
+	if shouldUseNativeApprovalNotification(payload) && sendNativeApprovalNotification(payload) == nil {
+		return 1
+	}
+	requests, err := buildHookNotifications(payload)
+	if err != nil {
+		return 0
+	}
+	sent := 0
+	for _, req := range requests {
+		if sendNotification(req) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// heldSummaryNotification folds held events into one notification: counts
+// per event in arrival order, then the projects involved.
+func heldSummaryNotification(title string, payloads []map[string]any) notificationRequest {
+	var (
+		events   []string
+		counts   = map[string]int{}
+		projects []string
+	)
+	for _, payload := range payloads {
+		event := payloadEventName(payload)
+		if counts[event] == 0 {
+			events = append(events, event)
+		}
+		counts[event]++
+		if name := projectName(payloadCwd(payload)); name != "" && !containsString(projects, name) {
+			projects = append(projects, name)
+		}
+	}
+	parts := make([]string, 0, len(events))
+	for _, event := range events {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[event], event))
+	}
+	message := strings.Join(parts, ", ")
+	if len(projects) > 0 {
+		message += " · " + strings.Join(projects, ", ")
+	}
+	last := payloads[len(payloads)-1]
+	return notificationRequest{
+		Title:             title,
+		Message:           message,
+		Group:             "codex-notify-locked",
+		ExecuteOnClick:    buildActionCommand("open", payloadThreadID(last)),
+		PopupPrimaryLabel: "Open",
+	}
+}
+
+// startHeldEventWatcher flushes events held for a locked screen soon after
+// the unlock, a digest once it is due, and approval reminders, for the
+// daemon's lifetime; without a daemon the next hook flushes them.
+func startHeldEventWatcher() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(lockQueuePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				inDaemonWork(func() {
+					flushNoiseDigest(now)
+					remindPendingApprovals(now)
+					state, err := loadState()
+					if err != nil || len(state.LockQueue) == 0 || readScreenLocked() {
+						return
+					}
+					flushLockQueue()
+				})
+			}
+		}
+	}()
+	return cancel
+}
+
+func addLockQueueDoctorCheck(report *doctorReport) {
+	state, err := loadState()
+	if err != nil || len(state.LockQueue) == 0 {
+		return
+	}
+	report.add(doctorDaemon, checkOK, "lock queue", fmt.Sprintf("%d events held until the screen unlocks (since %s)",
+		len(state.LockQueue), time.Unix(state.LockQueue[0].Time, 0).Local().Format("15:04")), false)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseIoregScreenLocked(t *testing.T) {
+	locked := `  |   "IOConsoleUsers" = ({"kCGSSessionOnConsoleKey"=Yes,"CGSSessionScreenIsLocked"=Yes,"kCGSSessionUserNameKey"="me"})`
+	if !parseIoregScreenLocked(locked) {
+		t.Fatal("locked session not detected")
+	}
+	if parseIoregScreenLocked(strings.Replace(locked, `"CGSSessionScreenIsLocked"=Yes,`, "", 1)) {
+		t.Fatal("unlocked session reported locked")
+	}
+}
+
+func TestLockQueue(t *testing.T) {
+	_, dir := useTempHome(t)
+	locked := true
+	stub(t, &readScreenLocked, func() bool { return locked })
+
+	held := []map[string]any{
+		{"type": "agent-turn-complete", "thread-id": "t1", "turn-id": "1", "cwd": "/src/app"},
+		{"type": "approval-requested", "thread-id": "t2", "cwd": "/src/api"},
+		{"type": "agent-error", "thread-id": "t3", "cwd": "/src/app"},
+		{"type": "approval-requested", "thread-id": "t4", "cwd": "/src/web"},
+		{"type": "agent-turn-complete", "thread-id": "t4", "turn-id": "2", "cwd": "/src/web"},
+	}
+	for _, payload := range held {
+		if result, err := deliverHookPayload(payload); err != nil || result.Status != "queued" {
+			t.Fatalf("deliverHookPayload() while locked = %+v, %v", result, err)
+		}
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("notifications captured while locked: %v", err)
+	}
+
+	locked = false
+	if result, err := deliverHookPayload(map[string]any{"type": "agent-turn-complete", "thread-id": "t5", "turn-id": "3"}); err != nil || result.Status != "sent" {
+		t.Fatalf("deliverHookPayload() after unlock = %+v, %v", result, err)
+	}
+	captured := readCaptured(t, dir)
+	if len(captured) != 3 {
+		t.Fatalf("captured %d notifications after unlock, want summary, t2 approval, and t5: %+v", len(captured), captured)
+	}
+	summary := captured[0]
+	// t4's approval was answered by its next turn, so only t2 is asked again.
+	if summary.Title != "Codex Notify: 3 events while locked" || summary.Message != "2 agent-turn-complete, 1 agent-error · app, web" {
+		t.Fatalf("summary = %q / %q", summary.Title, summary.Message)
+	}
+	if state, _ := loadState(); len(state.LockQueue) != 0 {
+		t.Fatalf("lock queue not emptied: %d left", len(state.LockQueue))
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	logLevelEnv = "CODEX_NOTIFY_LOG_LEVEL"
+	logFileEnv  = "CODEX_NOTIFY_LOG_FILE"
+	// verboseEnv is set by --verbose, so relayed hooks and reminder
+	// processes started on its behalf log the same way.
+	verboseEnv = "CODEX_NOTIFY_VERBOSE"
+
+	logFileName = "codex-notify.log"
+	// logMaxSize is when the log file is rotated; logKeep rotated files
+	// are kept beside it as codex-notify.log.1 and so on.
+	logMaxSize = 1 << 20
+	logKeep    = 3
+)
+
+// logLevel orders log lines from always shown to only on request.
+type logLevel int
+
+const (
+	logError logLevel = iota
+	logInfo
+	logDebug
+)
+
+var logLevelNames = map[logLevel]string{logError: "error", logInfo: "info", logDebug: "debug"}
+
+func parseLogLevel(raw string) (logLevel, bool) {
+	for level, name := range logLevelNames {
+		if strings.EqualFold(strings.TrimSpace(raw), name) {
+			return level, true
+		}
+	}
+	return logError, false
+}
+
+func verboseLogging() bool {
+	v := strings.TrimSpace(strings.ToLower(getenv(verboseEnv)))
+	return v == "1" || v == "true" || v == "yes" || v == "on"
+}
+
+// stderrLogLevel is the lowest level printed on stderr: errors, or
+// everything with --verbose.
+func stderrLogLevel() logLevel {
+	if verboseLogging() {
+		return logDebug
+	}
+	return logError
+}
+
+// fileLogLevel is CODEX_NOTIFY_LOG_LEVEL for the log file, info by default
+// and debug with --verbose.
+func fileLogLevel() logLevel {
+	if verboseLogging() {
+		return logDebug
+	}
+	if level, ok := parseLogLevel(getenv(logLevelEnv)); ok {
+		return level
+	}
+	return logInfo
+}
+
+// logFilePath is CODEX_NOTIFY_LOG_FILE: "on" for codex-notify.log in the
+// runtime state dir, or a path. It is off by default.
+func logFilePath() (string, bool) {
+	raw := strings.TrimSpace(getenv(logFileEnv))
+	switch strings.ToLower(raw) {
+	case "", "0", "false", "no", "off":
+		return "", false
+	case "1", "true", "yes", "on":
+		dir, err := runtimeStateDir()
+		if err != nil {
+			return "", false
+		}
+		return filepath.Join(dir, logFileName), true
+	}
+	path, err := expandUserPath(raw)
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+var logFileMu sync.Mutex
+
+// logf writes one line: to stderr as "codex-notify: ..." when level is
+// shown there, and with a timestamp, level, and pid to the log file when
+// one is configured.
+func logf(level logLevel, format string, args ...any) {
+	line := fmt.Sprintf(format, args...)
+	if level <= stderrLogLevel() {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", appName, line)
+	}
+	if level > fileLogLevel() {
+		return
+	}
+	path, ok := logFilePath()
+	if !ok {
+		return
+	}
+	entry := fmt.Sprintf("%s %-5s [%d] %s\n", time.Now().UTC().Format("2006-01-02T15:04:05.000Z"), logLevelNames[level], os.Getpid(), line)
+	logFileMu.Lock()
+	defer logFileMu.Unlock()
+	rotateLogFile(path)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, privateFileMode)
+	if err != nil {
+		return
+	}
+	_, _ = f.WriteString(entry)
+	_ = f.Close()
+}
+
+func logErrorf(format string, args ...any) { logf(logError, format, args...) }
+func logInfof(format string, args ...any)  { logf(logInfo, format, args...) }
+func logDebugf(format string, args ...any) { logf(logDebug, format, args...) }
+
+// rotateLogFile shifts path to path.1, path.1 to path.2, and so on once it
+// reaches logMaxSize, dropping the oldest.
+func rotateLogFile(path string) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() < logMaxSize {
+		return
+	}
+	_ = os.Remove(fmt.Sprintf("%s.%d", path, logKeep))
+	for i := logKeep - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+	}
+	_ = os.Rename(path, path+".1")
+}
+
+// logExec records a finished command at debug level. Only the program and
+// the number of arguments are logged, since the arguments may carry the
+// message or a script with it.
+func logExec(cmd *exec.Cmd, err error, elapsed time.Duration) {
+	if logDebug > stderrLogLevel() && logDebug > fileLogLevel() {
+		return
+	}
+	name := filepath.Base(cmd.Path)
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		logDebugf("exec %s (%d args): exit 0 in %s", name, len(cmd.Args)-1, elapsed.Round(time.Millisecond))
+	case errors.As(err, &exitErr):
+		logDebugf("exec %s (%d args): exit %d in %s", name, len(cmd.Args)-1, exitErr.ExitCode(), elapsed.Round(time.Millisecond))
+	default:
+		logDebugf("exec %s (%d args): %v", name, len(cmd.Args)-1, err)
+	}
+}
+
+// logExecStart records a command that keeps running on its own, like the
+// popup helper, which reports its outcome in the receipts instead.
+func logExecStart(cmd *exec.Cmd, err error) {
+	name := filepath.Base(cmd.Path)
+	if err != nil {
+		logDebugf("exec %s (%d args): start failed: %v", name, len(cmd.Args)-1, err)
+		return
+	}
+	logDebugf("exec %s (%d args): started as pid %d", name, len(cmd.Args)-1, cmd.Process.Pid)
+}
+
+func combinedOutputLogged(cmd *exec.Cmd) ([]byte, error) {
+	start := time.Now()
+	out, err := cmd.CombinedOutput()
+	logExec(cmd, err, time.Since(start))
+	return out, err
+}
+
+func outputLogged(cmd *exec.Cmd) ([]byte, error) {
+	start := time.Now()
+	out, err := cmd.Output()
+	logExec(cmd, err, time.Since(start))
+	return out, err
+}
+
+func runLogged(cmd *exec.Cmd) error {
+	start := time.Now()
+	err := cmd.Run()
+	logExec(cmd, err, time.Since(start))
+	return err
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	for raw, want := range map[string]logLevel{"error": logError, "INFO": logInfo, " debug ": logDebug} {
+		if got, ok := parseLogLevel(raw); !ok || got != want {
+			t.Fatalf("parseLogLevel(%q) = %v, %v; want %v", raw, got, ok, want)
+		}
+	}
+	if _, ok := parseLogLevel("trace"); ok {
+		t.Fatal("parseLogLevel(trace) accepted an unknown level")
+	}
+}
+
+func TestLogFileLevels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.log")
+	t.Setenv(logFileEnv, path)
+	t.Setenv(logLevelEnv, "")
+	t.Setenv(verboseEnv, "")
+
+	logInfof("hook %s: %s", "agent-turn-complete", "sent")
+	logDebugf("left out at info")
+	t.Setenv(logLevelEnv, "error")
+	logInfof("left out at error")
+	logErrorf("backend failed")
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	lines := splitLines(content)
+	if len(lines) != 2 {
+		t.Fatalf("log = %q, want the info and error lines only", content)
+	}
+	if !strings.Contains(lines[0], " info  [") || !strings.HasSuffix(lines[0], "hook agent-turn-complete: sent") {
+		t.Fatalf("first line = %q", lines[0])
+	}
+	if !strings.Contains(lines[1], " error [") || !strings.HasSuffix(lines[1], "backend failed") {
+		t.Fatalf("second line = %q", lines[1])
+	}
+}
+
+func TestLogFileOnUsesRuntimeStateDir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv(logFileEnv, "on")
+
+	dir, err := runtimeStateDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path, ok := logFilePath(); !ok || path != filepath.Join(dir, logFileName) {
+		t.Fatalf("logFilePath() = %q, %v", path, ok)
+	}
+	t.Setenv(logFileEnv, "off")
+	if _, ok := logFilePath(); ok {
+		t.Fatal("logFilePath() is set with CODEX_NOTIFY_LOG_FILE=off")
+	}
+}
+
+func TestRotateLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.log")
+	for i, content := range []string{"old", "older"} {
+		if err := os.WriteFile(path+"."+string(rune('1'+i)), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, make([]byte, logMaxSize), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rotateLogFile(path)
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("log file still in place after rotation: %v", err)
+	}
+	for suffix, want := range map[string]string{".2": "old", ".3": "older"} {
+		if got, err := os.ReadFile(path + suffix); err != nil || string(got) != want {
+			t.Fatalf("%s = %q, %v; want %q", suffix, got, err, want)
+		}
+	}
+	if info, err := os.Stat(path + ".1"); err != nil || info.Size() != logMaxSize {
+		t.Fatalf("rotated log = %v, %v", info, err)
+	}
+}
+
+func TestLogExecRecordsExitStatusWithoutArgs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.log")
+	t.Setenv(logFileEnv, path)
+	t.Setenv(logLevelEnv, "debug")
+	t.Setenv(verboseEnv, "")
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+	_, _ = combinedOutputLogged(exec.Command(sh, "-c", "exit 3", "secret message"))
+	logExec(exec.Command("/usr/local/bin/terminal-notifier", "-title", "t"), nil, 40*time.Millisecond)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	lines := splitLines(content)
+	if len(lines) != 2 {
+		t.Fatalf("log = %q, want two exec lines", content)
+	}
+	if !strings.Contains(lines[0], "exec sh (3 args): exit 3 in ") {
+		t.Fatalf("first line = %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "exec terminal-notifier (2 args): exit 0 in 40ms") {
+		t.Fatalf("second line = %q", lines[1])
+	}
+	if strings.Contains(string(content), "secret message") {
+		t.Fatalf("log leaked a command argument: %q", content)
+	}
+}
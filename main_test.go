@@ -3,19 +3,58 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
+// TestMain pins the tests to the macOS behavior, whatever the host; the
+// Linux paths opt in with useHostOS. Hooks never reach a daemon the
+// developer happens to be running.
+func TestMain(m *testing.M) {
+	hostOS = "darwin"
+	os.Setenv("CODEX_NOTIFY_DAEMON", "0")
+	os.Exit(m.Run())
+}
+
+// stub sets *target to value for the rest of the test, for the function
+// variables that stand between codex-notify and the host.
+func stub[T any](t *testing.T, target *T, value T) {
+	t.Helper()
+
+	prev := *target
+	*target = value
+	t.Cleanup(func() {
+		*target = prev
+	})
+}
+
+func useHostOS(t *testing.T, goos string) {
+	t.Helper()
+	stub(t, &hostOS, goos)
+}
+
+// useTempHome gives the test an empty home and cache dir, outside tmux,
+// and captures its notifications instead of showing them. It returns the
+// home dir and the capture dir for readCaptured.
+func useTempHome(t *testing.T) (home, captured string) {
+	t.Helper()
+
+	home = t.TempDir()
+	captured = filepath.Join(home, "captured")
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	t.Setenv("TMUX", "")
+	t.Setenv(backendEnv, "capture:"+captured)
+	return home, captured
+}
+
 func useTempUserConfigDir(t *testing.T) string {
 	t.Helper()
 
 	dir := t.TempDir()
-	prev := userConfigDir
-	userConfigDir = func() (string, error) {
+	stub(t, &userConfigDir, func() (string, error) {
 		return dir, nil
-	}
-	t.Cleanup(func() {
-		userConfigDir = prev
 	})
 	return dir
 }
@@ -32,6 +71,26 @@ func writePopupSettingsForTest(t *testing.T, configDir string, content string) {
 	}
 }
 
+func TestInferPopupLabelFromCommand(t *testing.T) {
+	for action, want := range map[string]string{
+		"approve":            "Approve",
+		"reject":             "Reject",
+		"reject-with-reason": "Reject with reason…",
+		"choose":             "Choose",
+		"open":               "Open",
+	} {
+		if got := inferPopupLabelFromCommand(buildActionCommand(action, "t1")); got != want {
+			t.Errorf("label for %s = %q, want %q", action, got, want)
+		}
+	}
+	if got := inferPopupLabelFromCommand(buildSubmitActionCommand("yes", "t1")); got != "Submit" {
+		t.Errorf("label for submit = %q, want Submit", got)
+	}
+	if got := inferPopupLabelFromCommand(""); got != "" {
+		t.Errorf("label for no command = %q, want none", got)
+	}
+}
+
 func TestPopupTimeoutSeconds(t *testing.T) {
 	t.Run("default", func(t *testing.T) {
 		useTempUserConfigDir(t)
@@ -159,3 +218,197 @@ func TestApprovalActionTimeoutSeconds(t *testing.T) {
 		}
 	})
 }
+
+func TestBuildHookNotificationsProjectMuteChoice(t *testing.T) {
+	payload := map[string]any{
+		"type":                   "agent-turn-complete",
+		"thread-id":              "t1",
+		"cwd":                    "/Users/me/src/app",
+		"last-assistant-message": "done",
+	}
+
+	requests, err := buildHookNotifications(payload)
+	if err != nil {
+		t.Fatalf("buildHookNotifications() error = %v", err)
+	}
+	choices := popupChoicesForRequest(requests[0])
+	if len(choices) != 2 {
+		t.Fatalf("len(choices) = %d, want 2", len(choices))
+	}
+	if choices[1].Label != "Mute project 1h" {
+		t.Fatalf("choices[1].Label = %q, want %q", choices[1].Label, "Mute project 1h")
+	}
+	if !strings.Contains(choices[1].Command, "mute-project --cwd '/Users/me/src/app' --duration '1h0m0s'") {
+		t.Fatalf("choices[1].Command = %q, missing mute-project args", choices[1].Command)
+	}
+}
+
+func TestIsProjectMuted(t *testing.T) {
+	now := time.Unix(1_000, 0)
+	state := notifyState{ProjectMutes: map[string]int64{
+		"/src/app":     2_000,
+		"/src/expired": 500,
+	}}
+
+	cases := []struct {
+		cwd  string
+		want bool
+	}{
+		{"/src/app", true},
+		{"/src/app/sub/dir", true},
+		{"/src/application", false},
+		{"/src/expired", false},
+		{"", false},
+	}
+	for _, tc := range cases {
+		if got := isProjectMuted(state, tc.cwd, now); got != tc.want {
+			t.Errorf("isProjectMuted(%q) = %v, want %v", tc.cwd, got, tc.want)
+		}
+	}
+}
+
+func TestResolveHookPayload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "payload.json")
+	if err := os.WriteFile(path, []byte(" {\"type\":\"from-file\"}\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	t.Run("file beats argv", func(t *testing.T) {
+		got, err := resolveHookPayload([]string{`{"type":"argv"}`}, path, -1)
+		if err != nil || got != `{"type":"from-file"}` {
+			t.Fatalf("resolveHookPayload() = %q, %v", got, err)
+		}
+	})
+
+	t.Run("fd", func(t *testing.T) {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		defer f.Close()
+
+		got, err := resolveHookPayload(nil, "", int(f.Fd()))
+		if err != nil || got != `{"type":"from-file"}` {
+			t.Fatalf("resolveHookPayload() = %q, %v", got, err)
+		}
+	})
+
+	t.Run("argv", func(t *testing.T) {
+		got, err := resolveHookPayload([]string{`{"type":"argv"}`}, "", -1)
+		if err != nil || got != `{"type":"argv"}` {
+			t.Fatalf("resolveHookPayload() = %q, %v", got, err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := resolveHookPayload(nil, filepath.Join(dir, "missing.json"), -1); err == nil {
+			t.Fatalf("expected error for missing payload file")
+		}
+	})
+}
+
+func TestOpenKeySequence(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_OPEN_KEYS", "")
+	if got := openKeySequence(); len(got) != 0 {
+		t.Fatalf("openKeySequence() default = %v, want empty", got)
+	}
+
+	t.Setenv("CODEX_NOTIFY_OPEN_KEYS", " /status , enter ")
+	got := openKeySequence()
+	if len(got) != 2 || got[0] != "/status" || got[1] != "enter" {
+		t.Fatalf("openKeySequence() = %v, want [/status enter]", got)
+	}
+}
+
+func TestApprovalChoicesIncludeRejectWithReason(t *testing.T) {
+	last := func(choices []approvalChoice) approvalChoice { return choices[len(choices)-1] }
+
+	defaults := defaultApprovalChoices("t1")
+	if got := last(defaults); got.Label != "Reject with reason…" || !strings.Contains(got.Command, "action 'reject-with-reason' --thread-id 't1'") {
+		t.Fatalf("last default choice = %+v, want reject-with-reason", got)
+	}
+
+	fromPayload := approvalChoicesFromPayload(map[string]any{"approval-options": []any{"Yes", "No"}}, "t1")
+	if len(fromPayload) != 3 || last(fromPayload).Label != "Reject with reason…" {
+		t.Fatalf("payload choices = %+v, want Yes, No, Reject with reason…", fromPayload)
+	}
+
+	noReject := approvalChoicesFromPayload(map[string]any{"approval-options": []any{"Run once", "Always", "Skip for now"}}, "t1")
+	for _, c := range noReject {
+		if c.Label == "Reject with reason…" {
+			t.Fatalf("choices without a reject option = %+v, want no reject-with-reason", noReject)
+		}
+	}
+}
+
+func TestClaimEventDeduplicatesTurns(t *testing.T) {
+	useTempHome(t)
+
+	payload := map[string]any{"type": "agent-turn-complete", "thread-id": "t1", "turn-id": "7"}
+	key := eventDedupKey(payload)
+	if key == "" {
+		t.Fatalf("eventDedupKey() = empty, want a key")
+	}
+
+	now := time.Unix(1700000000, 0)
+	if first, err := claimEvent(key, now); err != nil || !first {
+		t.Fatalf("first claimEvent() = %v, %v; want true, nil", first, err)
+	}
+	if first, err := claimEvent(key, now.Add(time.Minute)); err != nil || first {
+		t.Fatalf("second claimEvent() = %v, %v; want false, nil", first, err)
+	}
+	if first, err := claimEvent(key, now.Add(recentEventWindow+2*time.Minute)); err != nil || !first {
+		t.Fatalf("claimEvent() after the window = %v, %v; want true, nil", first, err)
+	}
+
+	if got := eventDedupKey(map[string]any{"type": "approval-requested", "thread-id": "t1"}); got != "" {
+		t.Fatalf("eventDedupKey() without turn id = %q, want empty", got)
+	}
+}
+
+func TestRunTestSimulatesEvents(t *testing.T) {
+	home, dir := useTempHome(t)
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "0")
+
+	if err := runTest([]string{"--event", "approval-requested", "--thread-id", "abc", "--message", "rm -rf?", "--options", "Yes,No", "--cwd", home, "--quiet"}); err != nil {
+		t.Fatalf("test --event approval-requested: %v", err)
+	}
+	if state, err := loadState(); err != nil || state.PendingApprovals["abc"].Cwd != home || !state.OpenTurns["abc"].AwaitingApproval {
+		t.Fatalf("state = %+v, %v; want the simulated approval tracked", state, err)
+	}
+	if err := runTest([]string{"--event", "agent-turn-complete", "--thread-id", "abc", "--message", "All done", "--quiet"}); err != nil {
+		t.Fatalf("test --event agent-turn-complete: %v", err)
+	}
+
+	captured := readCaptured(t, dir)
+	if len(captured) != 2 {
+		t.Fatalf("captured %d notifications, want 2: %+v", len(captured), captured)
+	}
+	approval, turn := captured[0], captured[1]
+	if approval.Group != notificationGroup("approval-native", "abc") || !strings.Contains(approval.Message, "rm -rf?") {
+		t.Fatalf("approval notification = %+v", approval)
+	}
+	if len(approval.Choices) < 2 || approval.Choices[0].Label != "Yes" || approval.Choices[0].Command != buildActionCommand("approve", "abc") || approval.Choices[1].Command != buildActionCommand("reject", "abc") {
+		t.Fatalf("approval choices = %+v, want Yes and No mapped to approve and reject", approval.Choices)
+	}
+	if turn.Group != notificationGroup("agent-turn-complete", "abc") || !strings.Contains(turn.Message, "All done") {
+		t.Fatalf("turn notification = %+v", turn)
+	}
+	if state, err := loadState(); err != nil || len(state.PendingApprovals) != 0 || len(state.OpenTurns) != 0 {
+		t.Fatalf("state = %+v, %v; want the approval settled and the turn closed", state, err)
+	}
+
+	t.Setenv(disabledEventsEnv, "approval-requested")
+	result, err := deliverPayload(testEventPayload("approval-requested", "abc", "rm -rf?", "", home, time.Now()), hookDelivery{test: true})
+	if err != nil || result.Status != "disabled" {
+		t.Fatalf("disabled test approval = %+v, %v; want status disabled", result, err)
+	}
+	if captured := readCaptured(t, dir); len(captured) != 2 {
+		t.Fatalf("captured %d notifications, want the disabled approval not shown", len(captured))
+	}
+
+	if err := runTest([]string{"--thread-id", "abc"}); exitCodeFor(err) != exitUsage {
+		t.Fatalf("test --thread-id without --event = %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// messageFormat is how a backend displays the message body. Codex writes
+// Markdown, so each backend gets a variant it can show instead of the raw
+// string.
+type messageFormat int
+
+const (
+	// formatPlain strips Markdown syntax, for banners that show literal text.
+	formatPlain messageFormat = iota
+	// formatMarkdown passes the message through for backends that render it.
+	formatMarkdown
+	// formatHTML converts inline Markdown to the small HTML subset that
+	// notification servers and chat webhooks accept (<b>, <i>, <a>).
+	formatHTML
+	// formatSlack converts inline Markdown to Slack's mrkdwn.
+	formatSlack
+)
+
+var (
+	mdFenceRE      = regexp.MustCompile("```[\\w+-]*")
+	mdCodeSpanRE   = regexp.MustCompile("`([^`]+)`")
+	mdImageRE      = regexp.MustCompile(`!\[([^\]]*)\]\([^)\s]*\)`)
+	mdLinkRE       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s"]+)\)`)
+	mdHeadingRE    = regexp.MustCompile(`(?m)^[ \t]*#{1,6}[ \t]+`)
+	mdBulletRE     = regexp.MustCompile(`(?m)^[ \t]*[-*+][ \t]+`)
+	mdStarBoldRE   = regexp.MustCompile(`\*\*([^*\s](?:[^*]*[^*\s])?)\*\*`)
+	mdUnderBoldRE  = regexp.MustCompile(`__([^_\s](?:[^_]*[^_\s])?)__`)
+	mdStarEmRE     = regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*]*[^*\s])?)\*($|[^\w*])`)
+	mdUnderEmRE    = regexp.MustCompile(`(^|[^\w_])_([^_\s](?:[^_]*[^_\s])?)_($|[^\w_])`)
+	mdPlaceholder  = regexp.MustCompile("\x00(\\d+)\x00")
+	htmlTextEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+)
+
+// renderMessage converts a Markdown message to format. Unbalanced or
+// unknown syntax is left as is.
+func renderMessage(message string, format messageFormat) string {
+	if format == formatMarkdown {
+		return message
+	}
+	escaped := format == formatHTML || format == formatSlack
+
+	// Code spans are set aside first so their contents are never treated
+	// as emphasis or links.
+	msg := mdFenceRE.ReplaceAllString(message, "")
+	spans := []string{}
+	msg = mdCodeSpanRE.ReplaceAllStringFunc(msg, func(m string) string {
+		spans = append(spans, mdCodeSpanRE.FindStringSubmatch(m)[1])
+		return fmt.Sprintf("\x00%d\x00", len(spans)-1)
+	})
+	if escaped {
+		msg = htmlTextEscape.Replace(msg)
+	}
+
+	link, bold, em := "$1", "$1", "${1}${2}${3}"
+	switch format {
+	case formatHTML:
+		link, bold, em = `<a href="$2">$1</a>`, "<b>$1</b>", "${1}<i>${2}</i>${3}"
+	case formatSlack:
+		link, bold, em = "<$2|$1>", "*$1*", "${1}_${2}_${3}"
+	}
+	msg = mdImageRE.ReplaceAllString(msg, "$1")
+	msg = mdLinkRE.ReplaceAllString(msg, link)
+	msg = mdHeadingRE.ReplaceAllString(msg, "")
+	msg = mdBulletRE.ReplaceAllString(msg, "• ")
+	// Emphasis goes first: Slack's bold is a single asterisk, which the
+	// emphasis pattern would otherwise pick up again.
+	msg = mdStarEmRE.ReplaceAllString(msg, em)
+	msg = mdUnderEmRE.ReplaceAllString(msg, em)
+	msg = mdStarBoldRE.ReplaceAllString(msg, bold)
+	msg = mdUnderBoldRE.ReplaceAllString(msg, bold)
+
+	return mdPlaceholder.ReplaceAllStringFunc(msg, func(m string) string {
+		i, err := strconv.Atoi(mdPlaceholder.FindStringSubmatch(m)[1])
+		if err != nil || i >= len(spans) {
+			return m
+		}
+		span := spans[i]
+		if escaped {
+			span = htmlTextEscape.Replace(span)
+		}
+		if format == formatSlack {
+			return "`" + span + "`"
+		}
+		return span
+	})
+}
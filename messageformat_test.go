@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestRenderMessage(t *testing.T) {
+	tests := []struct {
+		in    string
+		plain string
+		html  string
+	}{
+		{
+			in:    "Added **4 tests** for `POST /login` — see [the PR](https://example.com/pr?a=1&b=2).",
+			plain: "Added 4 tests for POST /login — see the PR.",
+			html:  `Added <b>4 tests</b> for POST /login — see <a href="https://example.com/pr?a=1&amp;b=2">the PR</a>.`,
+		},
+		{
+			in:    "## Summary\n- fixed *flaky* test\n- renamed `snake_case_var`",
+			plain: "Summary\n• fixed flaky test\n• renamed snake_case_var",
+			html:  "Summary\n• fixed <i>flaky</i> test\n• renamed snake_case_var",
+		},
+		{
+			in:    "compare `a<b` and 2 * 3 * 4 in file_name_test.go",
+			plain: "compare a<b and 2 * 3 * 4 in file_name_test.go",
+			html:  "compare a&lt;b and 2 * 3 * 4 in file_name_test.go",
+		},
+		{
+			in:    "```go fmt.Println() ``` and an unclosed **bold",
+			plain: " fmt.Println()  and an unclosed **bold",
+			html:  " fmt.Println()  and an unclosed **bold",
+		},
+	}
+	for _, tt := range tests {
+		if got := renderMessage(tt.in, formatPlain); got != tt.plain {
+			t.Errorf("renderMessage(%q, plain) = %q, want %q", tt.in, got, tt.plain)
+		}
+		if got := renderMessage(tt.in, formatHTML); got != tt.html {
+			t.Errorf("renderMessage(%q, html) = %q, want %q", tt.in, got, tt.html)
+		}
+		if got := renderMessage(tt.in, formatMarkdown); got != tt.in {
+			t.Errorf("renderMessage(%q, markdown) = %q, want it unchanged", tt.in, got)
+		}
+	}
+}
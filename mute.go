@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// globalMute reports whether every notification is silenced, and until
+// when; a zero time means paused until `resume`.
+func globalMute(state notifyState, now time.Time) (time.Time, bool) {
+	if state.Paused {
+		return time.Time{}, true
+	}
+	if state.MutedUntil > 0 && now.Unix() < state.MutedUntil {
+		return time.Unix(state.MutedUntil, 0), true
+	}
+	return time.Time{}, false
+}
+
+func isGloballyMuted(state notifyState, now time.Time) bool {
+	_, muted := globalMute(state, now)
+	return muted
+}
+
+// describeGlobalMute is the mute as doctor and the commands print it.
+func describeGlobalMute(until time.Time, now time.Time) string {
+	if until.IsZero() {
+		return fmt.Sprintf("paused (run `%s resume` to turn notifications back on)", appName)
+	}
+	return fmt.Sprintf("muted until %s (%s left)", until.Local().Format("15:04"), formatCountdown(until.Sub(now)))
+}
+
+// runMute silences everything hook would send, desktop and remote sinks
+// alike, for a while: `codex-notify mute 30m`.
+func runMute(args []string) error {
+	fs := flag.NewFlagSet("mute", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	outFlags := addOutputFlags(fs)
+	// The duration may come before or after the flags.
+	var raw string
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		raw, args = args[0], args[1:]
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	rest := fs.Args()
+	if raw == "" && len(rest) > 0 {
+		raw, rest = rest[0], rest[1:]
+	}
+	if raw == "" || len(rest) > 0 {
+		return usageError(errors.New("mute requires one duration, e.g. 30m or 2h"))
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return usageError(fmt.Errorf("invalid mute duration %q (use e.g. 30m or 2h)", raw))
+	}
+	out := outFlags.output()
+
+	now := time.Now()
+	until := now.Add(d)
+	if err := updateState(func(s *notifyState) {
+		// A timed mute replaces a pause.
+		s.Paused, s.MutedUntil = false, until.Unix()
+	}); err != nil {
+		return err
+	}
+	out.Println(describeGlobalMute(time.Unix(until.Unix(), 0), now))
+	return out.Result(commandResult{Command: "mute", Status: "muted"})
+}
+
+// runPause silences notifications until runResume.
+func runPause(args []string) error {
+	fs := flag.NewFlagSet("pause", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fmt.Errorf("unexpected argument: %s (use mute <duration> for a timed mute)", fs.Arg(0)))
+	}
+	out := outFlags.output()
+
+	if err := updateState(func(s *notifyState) {
+		s.Paused = true
+	}); err != nil {
+		return err
+	}
+	out.Println(describeGlobalMute(time.Time{}, time.Now()))
+	return out.Result(commandResult{Command: "pause", Status: "paused"})
+}
+
+// runResume lifts a pause or timed mute. Project mutes stay.
+func runResume(args []string) error {
+	fs := flag.NewFlagSet("resume", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+	out := outFlags.output()
+
+	wasMuted := false
+	if err := updateState(func(s *notifyState) {
+		_, wasMuted = globalMute(*s, time.Now())
+		s.Paused, s.MutedUntil = false, 0
+	}); err != nil {
+		return err
+	}
+	if !wasMuted {
+		out.Println("notifications were not muted")
+		return out.Result(commandResult{Command: "resume", Status: "unchanged"})
+	}
+	out.Println("notifications resumed")
+	return out.Result(commandResult{Command: "resume", Status: "resumed"})
+}
+
+func addMuteDoctorCheck(report *doctorReport, now time.Time) {
+	state, err := loadState()
+	if err != nil {
+		return
+	}
+	if until, muted := globalMute(state, now); muted {
+		report.add(doctorSettings, checkWarn, "mute", describeGlobalMute(until, now), false)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMutePauseResume(t *testing.T) {
+	_, dir := useTempHome(t)
+	payload := map[string]any{"type": "agent-turn-complete", "thread-id": "t1"}
+
+	if err := runMute([]string{"30m", "--quiet"}); err != nil {
+		t.Fatalf("mute: %v", err)
+	}
+	state, _ := loadState()
+	until, muted := globalMute(state, time.Now())
+	if !muted || until.Sub(time.Now()) < 29*time.Minute {
+		t.Fatalf("after mute: until = %v, muted = %v", until, muted)
+	}
+	if _, muted := globalMute(state, time.Now().Add(31*time.Minute)); muted {
+		t.Fatal("mute did not expire")
+	}
+	if result, err := deliverHookPayload(payload); err != nil || result.Status != "muted" {
+		t.Fatalf("deliverHookPayload() while muted = %+v, %v", result, err)
+	}
+
+	if err := runPause([]string{"--quiet"}); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	state, _ = loadState()
+	if _, muted := globalMute(state, time.Now().Add(48*time.Hour)); !muted {
+		t.Fatal("pause expired")
+	}
+
+	if err := runResume([]string{"--quiet"}); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if result, err := deliverHookPayload(payload); err != nil || result.Status != "sent" {
+		t.Fatalf("deliverHookPayload() after resume = %+v, %v", result, err)
+	}
+	if captured := readCaptured(t, dir); len(captured) != 1 {
+		t.Fatalf("captured %d notifications, want 1", len(captured))
+	}
+
+	for _, args := range [][]string{{}, {"soon"}, {"-5m"}, {"30m", "extra"}} {
+		if err := runMute(args); exitCodeFor(err) != exitUsage {
+			t.Errorf("mute %q: %v, want a usage error", args, err)
+		}
+	}
+}
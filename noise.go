@@ -0,0 +1,347 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// A class is downgraded once at least noiseMinShown of its
+	// notifications were shown and no more than one in ten led to an
+	// action.
+	noiseMinShown       = 10
+	noiseDowngradeScore = 0.9
+	// noiseActWindow is how long after a notification an action on its
+	// thread still counts as acting on it.
+	noiseActWindow = 24 * time.Hour
+	// noiseHalfLife is how fast old notifications stop counting, so a
+	// class that became useful again (or went quiet) is relearned.
+	noiseHalfLife = 7 * 24 * time.Hour
+	// noiseDigestInterval is how long digest-only events wait, from the
+	// oldest one, before they go out as one summary.
+	noiseDigestInterval = 30 * time.Minute
+)
+
+// noiseScore counts, per notification class, how many notifications were
+// shown and how many were acted on, as of Last. Both counts halve every
+// noiseHalfLife.
+type noiseScore struct {
+	Shown float64 `json:"shown"`
+	Acted float64 `json:"acted"`
+	Last  int64   `json:"last"`
+}
+
+// decayed is n as of now.
+func (n noiseScore) decayed(now time.Time) noiseScore {
+	if elapsed := now.Unix() - n.Last; n.Last > 0 && elapsed > 0 {
+		factor := math.Pow(0.5, float64(elapsed)/noiseHalfLife.Seconds())
+		n.Shown *= factor
+		n.Acted *= factor
+	}
+	n.Last = now.Unix()
+	return n
+}
+
+// decayedNoiseScores is every class's score as of now.
+func decayedNoiseScores(scores map[string]noiseScore, now time.Time) map[string]noiseScore {
+	out := make(map[string]noiseScore, len(scores))
+	for class, score := range scores {
+		out[class] = score.decayed(now)
+	}
+	return out
+}
+
+// score is the share of notifications nobody acted on.
+func (n noiseScore) score() float64 {
+	if n.Shown == 0 {
+		return 0
+	}
+	return 1 - math.Min(1, float64(n.Acted)/float64(n.Shown))
+}
+
+// downgraded compares the rounded count with noiseMinShown, so a class that
+// just reached it does not drop back out a second later as it decays.
+func (n noiseScore) downgraded() bool {
+	return math.Round(n.Shown) >= noiseMinShown && n.score() >= noiseDowngradeScore
+}
+
+// noiseThread links a thread to the class of its last notification, so an
+// action on the thread is credited to it once.
+type noiseThread struct {
+	Class string `json:"class"`
+	Time  int64  `json:"time"`
+}
+
+// adaptiveNotificationsEnabled is CODEX_NOTIFY_ADAPTIVE: move chronically
+// ignored classes to the digest. Scores are kept either way.
+func adaptiveNotificationsEnabled() bool {
+	v := strings.TrimSpace(strings.ToLower(getenv("CODEX_NOTIFY_ADAPTIVE")))
+	return v == "1" || v == "true" || v == "yes" || v == "on"
+}
+
+// noiseClass groups notifications by event and project:
+// "agent-turn-complete@app", or the bare event without a project.
+func noiseClass(payload map[string]any) string {
+	class := payloadEventName(payload)
+	if project := projectName(payloadCwd(payload)); project != "" {
+		class += "@" + project
+	}
+	return class
+}
+
+// recordNoiseShown counts a notification shown for payload's class.
+func recordNoiseShown(payload map[string]any, now time.Time) {
+	class := noiseClass(payload)
+	thread := payloadThreadID(payload)
+	_ = updateState(func(s *notifyState) {
+		if s.NoiseScores == nil {
+			s.NoiseScores = map[string]noiseScore{}
+		}
+		score := s.NoiseScores[class].decayed(now)
+		score.Shown++
+		s.NoiseScores[class] = score
+		if thread == "" {
+			return
+		}
+		for t, nt := range s.NoiseThreads {
+			if now.Sub(time.Unix(nt.Time, 0)) > noiseActWindow {
+				delete(s.NoiseThreads, t)
+			}
+		}
+		if s.NoiseThreads == nil {
+			s.NoiseThreads = map[string]noiseThread{}
+		}
+		s.NoiseThreads[thread] = noiseThread{Class: class, Time: now.Unix()}
+	})
+}
+
+// recordNoiseActed credits an action on thread to the class of its last
+// notification.
+func recordNoiseActed(thread string, now time.Time) {
+	if thread == "" {
+		return
+	}
+	if state, err := loadState(); err != nil || state.NoiseThreads[thread].Class == "" {
+		return
+	}
+	_ = updateState(func(s *notifyState) {
+		nt, ok := s.NoiseThreads[thread]
+		delete(s.NoiseThreads, thread)
+		if !ok || now.Sub(time.Unix(nt.Time, 0)) > noiseActWindow {
+			return
+		}
+		if score, ok := s.NoiseScores[nt.Class]; ok {
+			score = score.decayed(now)
+			score.Acted++
+			s.NoiseScores[nt.Class] = score
+		}
+	})
+}
+
+// recordNoiseDismissed settles thread's last notification as ignored: a
+// later action on the thread no longer counts as acting on it. An empty
+// thread settles every notification.
+func recordNoiseDismissed(thread string) {
+	_ = updateState(func(s *notifyState) {
+		if thread == "" {
+			s.NoiseThreads = nil
+			return
+		}
+		delete(s.NoiseThreads, thread)
+	})
+}
+
+// digestOnly reports whether payload's class has been downgraded. Approvals
+// block Codex, so they are never downgraded.
+func digestOnly(state notifyState, payload map[string]any) bool {
+	if !adaptiveNotificationsEnabled() || payloadEventName(payload) == "approval-requested" {
+		return false
+	}
+	return state.NoiseScores[noiseClass(payload)].decayed(time.Now()).downgraded()
+}
+
+func queueDigestEvent(payload map[string]any, now time.Time) error {
+	return updateState(func(s *notifyState) {
+		s.Digest = append(s.Digest, lockedEvent{Time: now.Unix(), Payload: payload})
+		if over := len(s.Digest) - lockQueueLimit; over > 0 {
+			s.Digest = s.Digest[over:]
+		}
+	})
+}
+
+// flushNoiseDigest sends the digest as one summary once its oldest event
+// has waited noiseDigestInterval. It reports whether it sent one.
+func flushNoiseDigest(now time.Time) bool {
+	state, err := loadState()
+	if err != nil || len(state.Digest) == 0 || now.Sub(time.Unix(state.Digest[0].Time, 0)) < noiseDigestInterval {
+		return false
+	}
+	var held []lockedEvent
+	if err := updateState(func(s *notifyState) {
+		held, s.Digest = s.Digest, nil
+	}); err != nil || len(held) == 0 {
+		return false
+	}
+	payloads := make([]map[string]any, 0, len(held))
+	for _, e := range held {
+		payloads = append(payloads, e.Payload)
+	}
+	title := fmt.Sprintf("Codex Notify: %d quiet events", len(payloads))
+	if len(payloads) == 1 {
+		title = "Codex Notify: 1 quiet event"
+	}
+	req := heldSummaryNotification(title, payloads)
+	req.Group = "codex-notify-digest"
+	return sendNotification(req) == nil
+}
+
+// statsResult is `stats --json`: the activity summary and the noise scores.
+type statsResult struct {
+	commandResult
+	Activity activityStats         `json:"activity"`
+	Noise    map[string]noiseScore `json:"noise"`
+}
+
+// runStats prints activity since --since and the learned noise scores,
+// noisiest first, or resets the scores: `stats reset [class]`.
+func runStats(args []string) error {
+	reset := len(args) > 0 && args[0] == "reset"
+	if reset {
+		args = args[1:]
+	}
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	since := fs.String("since", "", "summarize events since a duration ago (30m, 2h, 3d) or an RFC 3339 time (default 7d)")
+	outFlags := addOutputFlags(fs)
+	var class string
+	if reset && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		class, args = args[0], args[1:]
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+	if reset && *since != "" {
+		return usageError(errors.New("--since cannot be combined with reset"))
+	}
+	out := outFlags.output()
+
+	if reset {
+		found := false
+		if err := updateState(func(s *notifyState) {
+			if class == "" {
+				found = len(s.NoiseScores) > 0
+				s.NoiseScores, s.NoiseThreads = nil, nil
+				return
+			}
+			_, found = s.NoiseScores[class]
+			delete(s.NoiseScores, class)
+		}); err != nil {
+			return err
+		}
+		if class != "" && !found {
+			return usageError(fmt.Errorf("no scores for %s (see `%s stats`)", class, appName))
+		}
+		if class == "" {
+			class = "all classes"
+		}
+		out.Printf("reset noise scores for %s\n", class)
+		return out.Result(commandResult{Command: "stats", Status: "reset"})
+	}
+
+	now := time.Now()
+	from := now.Add(-defaultStatsWindow)
+	if *since != "" {
+		var err error
+		if from, err = parseSince(*since, now); err != nil {
+			return usageError(err)
+		}
+	}
+	entries, err := loadHistory(from)
+	if err != nil {
+		return err
+	}
+	activity := summarizeActivity(entries, from)
+	state, err := loadState()
+	if err != nil {
+		return err
+	}
+	noise := decayedNoiseScores(state.NoiseScores, now)
+	out.Printf("%s\n", formatActivity(activity))
+	if len(noise) == 0 {
+		out.Println("no notifications counted yet")
+	} else {
+		out.Printf("%s", formatNoiseScores(noise, adaptiveNotificationsEnabled()))
+	}
+	return out.Result(statsResult{
+		commandResult: commandResult{Command: "stats", Status: "ok", Count: len(state.NoiseScores)},
+		Activity:      activity,
+		Noise:         noise,
+	})
+}
+
+func formatNoiseScores(scores map[string]noiseScore, adaptive bool) string {
+	classes := make([]string, 0, len(scores))
+	for class := range scores {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		a, b := scores[classes[i]], scores[classes[j]]
+		if a.score() != b.score() {
+			return a.score() > b.score()
+		}
+		return classes[i] < classes[j]
+	})
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLASS\tSHOWN\tACTED\tNOISE\tLEVEL")
+	for _, class := range classes {
+		score := scores[class]
+		level := "normal"
+		switch {
+		case strings.HasPrefix(class, "approval-requested"):
+			level = "normal (approvals stay)"
+		case score.downgraded() && adaptive:
+			level = "digest"
+		case score.downgraded():
+			level = "normal (would be digest)"
+		case score.Shown < noiseMinShown:
+			level = "learning"
+		}
+		fmt.Fprintf(w, "%s\t%.0f\t%.0f\t%.0f%%\t%s\n", class, score.Shown, score.Acted, 100*score.score(), level)
+	}
+	_ = w.Flush()
+	return b.String()
+}
+
+func addNoiseDoctorCheck(report *doctorReport) {
+	if !adaptiveNotificationsEnabled() {
+		return
+	}
+	state, err := loadState()
+	if err != nil {
+		return
+	}
+	var downgraded []string
+	for class, score := range decayedNoiseScores(state.NoiseScores, time.Now()) {
+		if score.downgraded() && !strings.HasPrefix(class, "approval-requested") {
+			downgraded = append(downgraded, class)
+		}
+	}
+	sort.Strings(downgraded)
+	detail := "on, no classes downgraded"
+	if len(downgraded) > 0 {
+		detail = fmt.Sprintf("digest only: %s (%d held)", strings.Join(downgraded, ", "), len(state.Digest))
+	}
+	report.add(doctorSettings, checkOK, "adaptive level", detail, false)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNoiseScores(t *testing.T) {
+	_, dir := useTempHome(t)
+	t.Setenv("CODEX_NOTIFY_ADAPTIVE", "1")
+	now := time.Now()
+
+	turn := func(i int, cwd string) map[string]any {
+		return map[string]any{"type": "agent-turn-complete", "thread-id": fmt.Sprintf("t%d", i), "turn-id": "1", "cwd": cwd}
+	}
+	for i := 0; i < noiseMinShown; i++ {
+		recordNoiseShown(turn(i, "/src/docs"), now)
+		recordNoiseShown(turn(100+i, "/src/app"), now)
+		recordNoiseActed(fmt.Sprintf("t%d", 100+i), now)
+	}
+	// A second action on the same notification is not counted again.
+	recordNoiseActed("t100", now)
+	state, _ := loadState()
+	docs, app := state.NoiseScores["agent-turn-complete@docs"], state.NoiseScores["agent-turn-complete@app"]
+	if docs.Shown != noiseMinShown || docs.Acted != 0 || !docs.downgraded() {
+		t.Fatalf("docs = %+v", docs)
+	}
+	if app.Acted != noiseMinShown || app.downgraded() {
+		t.Fatalf("app = %+v", app)
+	}
+	if !digestOnly(state, turn(1, "/src/docs")) || digestOnly(state, map[string]any{"type": "approval-requested", "cwd": "/src/docs"}) {
+		t.Fatal("digestOnly() picked the wrong classes")
+	}
+	if !docs.decayed(now.Add(time.Minute)).downgraded() {
+		t.Fatal("docs left the digest a minute after reaching it")
+	}
+	weekLater := docs.decayed(now.Add(noiseHalfLife))
+	if math.Abs(weekLater.Shown-noiseMinShown/2) > 0.01 || weekLater.downgraded() {
+		t.Fatalf("docs a half-life later = %+v", weekLater)
+	}
+	recordNoiseShown(turn(200, "/src/app"), now)
+	recordNoiseDismissed("t200")
+	recordNoiseActed("t200", now)
+	if state, _ := loadState(); state.NoiseScores["agent-turn-complete@app"].Acted != noiseMinShown {
+		t.Fatal("an action after a dismissal was credited")
+	}
+	table := formatNoiseScores(state.NoiseScores, true)
+	if lines := strings.Split(strings.TrimSpace(table), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[1], "agent-turn-complete@docs") || !strings.HasSuffix(lines[1], "digest") {
+		t.Fatalf("stats table:\n%s", table)
+	}
+
+	if result, err := deliverHookPayload(turn(50, "/src/docs")); err != nil || result.Status != "digest" {
+		t.Fatalf("deliverHookPayload() for a noisy class = %+v, %v", result, err)
+	}
+	if flushNoiseDigest(now.Add(time.Minute)) {
+		t.Fatal("digest flushed before it was due")
+	}
+	// The power saver only picks a cheaper notifier; the digest still goes out.
+	t.Setenv("CODEX_NOTIFY_POWER_SAVER", "on")
+	if !flushNoiseDigest(now.Add(noiseDigestInterval + time.Minute)) {
+		t.Fatal("digest not flushed once due")
+	}
+	if captured := readCaptured(t, dir); len(captured) != 1 || captured[0].Title != "Codex Notify: 1 quiet event" {
+		t.Fatalf("captured = %+v", captured)
+	}
+
+	if err := runStats([]string{"reset", "agent-turn-complete@docs", "--quiet"}); err != nil {
+		t.Fatalf("stats reset class: %v", err)
+	}
+	if err := runStats([]string{"reset", "nope", "--quiet"}); exitCodeFor(err) != exitUsage {
+		t.Fatalf("stats reset unknown class: %v", err)
+	}
+	if err := runStats([]string{"reset", "--quiet"}); err != nil {
+		t.Fatalf("stats reset: %v", err)
+	}
+	if state, _ := loadState(); len(state.NoiseScores) != 0 {
+		t.Fatalf("scores left after reset: %+v", state.NoiseScores)
+	}
+}
@@ -0,0 +1,173 @@
+// Package notify posts desktop notifications the way codex-notify's plain
+// backends do: terminal-notifier, then osascript on macOS, and notify-send
+// on Linux. The popup helper, approval buttons, and everything driven by
+// codex-notify's configuration stay in the command.
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Notification is what Send shows. Fields a backend cannot render are
+// left out.
+type Notification struct {
+	Title   string
+	Message string
+	// Group identifies the notification; terminal-notifier replaces an
+	// earlier one from the same group in place.
+	Group string
+	// ExecuteOnClick is a shell command run when the notification is
+	// clicked (terminal-notifier only).
+	ExecuteOnClick string
+	// Activate is the bundle id of an app brought forward on click
+	// (terminal-notifier only).
+	Activate string
+	// Image is a file shown beside the message (terminal-notifier).
+	Image string
+	// Icon is a notify-send icon name or path.
+	Icon string
+	// Sound is a system sound name, or for notify-send also an absolute
+	// path to a sound file.
+	Sound string
+}
+
+// Options adjust how Send runs a backend.
+type Options struct {
+	// AppName is the application name notify-send reports.
+	AppName string
+	// PrivateArgv passes the message over stdin where the backend allows
+	// it, so it does not show up in `ps`.
+	PrivateArgv bool
+	// Trace, when set, is called after each notifier command exits, with
+	// its error and how long it ran.
+	Trace func(cmd *exec.Cmd, err error, elapsed time.Duration)
+}
+
+// ErrNoBackend is returned by Send when no notifier is installed.
+var ErrNoBackend = errors.New("no notifier available")
+
+// Send shows n with the first notifier found on PATH.
+func Send(n Notification, opts Options) error {
+	switch runtime.GOOS {
+	case "darwin":
+		if path, err := exec.LookPath("terminal-notifier"); err == nil {
+			return TerminalNotifier(path, n, opts)
+		}
+		if path, err := exec.LookPath("osascript"); err == nil {
+			return Osascript(path, n, opts)
+		}
+	case "linux":
+		if path, err := exec.LookPath("notify-send"); err == nil {
+			return NotifySend(path, n, opts)
+		}
+	default:
+		return fmt.Errorf("unsupported OS: %s (macOS and Linux only)", runtime.GOOS)
+	}
+	return ErrNoBackend
+}
+
+// TerminalNotifier shows n with the terminal-notifier at path.
+func TerminalNotifier(path string, n Notification, opts Options) error {
+	cmd := exec.Command(path, terminalNotifierArgs(n, opts)...)
+	if opts.PrivateArgv {
+		// terminal-notifier reads the message from stdin when -message is
+		// absent.
+		cmd.Stdin = strings.NewReader(n.Message)
+	}
+	return run(cmd, opts)
+}
+
+func terminalNotifierArgs(n Notification, opts Options) []string {
+	args := []string{"-title", n.Title}
+	if n.Group != "" {
+		args = append(args, "-group", n.Group)
+	}
+	if !opts.PrivateArgv {
+		args = append(args, "-message", n.Message)
+	}
+	if n.ExecuteOnClick != "" {
+		args = append(args, "-execute", n.ExecuteOnClick)
+	}
+	if n.Activate != "" {
+		args = append(args, "-activate", n.Activate)
+	}
+	if n.Image != "" {
+		args = append(args, "-contentImage", n.Image)
+	}
+	if n.Sound != "" {
+		args = append(args, "-sound", n.Sound)
+	}
+	return args
+}
+
+// Osascript shows n with AppleScript's `display notification`, which cannot
+// run anything on click.
+func Osascript(path string, n Notification, opts Options) error {
+	script := osascriptScript(n)
+	cmd := exec.Command(path, "-e", script)
+	if opts.PrivateArgv {
+		// osascript reads the script from stdin when no -e or file is given.
+		cmd = exec.Command(path)
+		cmd.Stdin = strings.NewReader(script)
+	}
+	return run(cmd, opts)
+}
+
+func osascriptScript(n Notification) string {
+	script := fmt.Sprintf(`display notification "%s" with title "%s"`, EscapeAppleScript(n.Message), EscapeAppleScript(n.Title))
+	if n.Sound != "" {
+		script += fmt.Sprintf(` sound name "%s"`, EscapeAppleScript(n.Sound))
+	}
+	return script
+}
+
+// NotifySend shows n with the notify-send at path, without actions.
+func NotifySend(path string, n Notification, opts Options) error {
+	return run(exec.Command(path, notifySendArgs(n, opts)...), opts)
+}
+
+func notifySendArgs(n Notification, opts Options) []string {
+	var args []string
+	if opts.AppName != "" {
+		args = append(args, "--app-name="+opts.AppName)
+	}
+	args = append(args, NotifySendIdentityArgs(n)...)
+	return append(args, "--", n.Title, n.Message)
+}
+
+// NotifySendIdentityArgs are the notify-send flags for n's icon and sound.
+func NotifySendIdentityArgs(n Notification) []string {
+	var args []string
+	if n.Icon != "" {
+		args = append(args, "--icon="+n.Icon)
+	}
+	switch {
+	case strings.HasPrefix(n.Sound, "/"):
+		args = append(args, "--hint=string:sound-file:"+n.Sound)
+	case n.Sound != "":
+		args = append(args, "--hint=string:sound-name:"+n.Sound)
+	}
+	return args
+}
+
+// EscapeAppleScript escapes s for an AppleScript string literal.
+func EscapeAppleScript(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+func run(cmd *exec.Cmd, opts Options) error {
+	start := time.Now()
+	out, err := cmd.CombinedOutput()
+	if opts.Trace != nil {
+		opts.Trace(cmd, err, time.Since(start))
+	}
+	if err != nil {
+		return fmt.Errorf("%w (%s)", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
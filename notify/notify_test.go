@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerminalNotifierArgs(t *testing.T) {
+	n := Notification{Title: "Codex", Message: "done", Group: "g", ExecuteOnClick: "open .", Sound: "Glass"}
+	want := []string{"-title", "Codex", "-group", "g", "-message", "done", "-execute", "open .", "-sound", "Glass"}
+	if got := terminalNotifierArgs(n, Options{}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("terminalNotifierArgs() = %q, want %q", got, want)
+	}
+	// The message goes over stdin instead.
+	for _, arg := range terminalNotifierArgs(n, Options{PrivateArgv: true}) {
+		if arg == "done" {
+			t.Fatal("private argv put the message on the command line")
+		}
+	}
+}
+
+func TestOsascriptScriptEscapes(t *testing.T) {
+	got := osascriptScript(Notification{Title: `say "hi"`, Message: `C:\tmp`})
+	want := `display notification "C:\\tmp" with title "say \"hi\""`
+	if got != want {
+		t.Fatalf("osascriptScript() = %s, want %s", got, want)
+	}
+}
+
+func TestNotifySendArgs(t *testing.T) {
+	got := notifySendArgs(Notification{Title: "-t", Message: "m", Icon: "dialog-information"}, Options{AppName: "codex-notify"})
+	want := []string{"--app-name=codex-notify", "--icon=dialog-information", "--", "-t", "m"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("notifySendArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestNotifySendIdentityArgsSoundFile(t *testing.T) {
+	got := NotifySendIdentityArgs(Notification{Sound: "/home/me/sounds/done.oga"})
+	want := []string{"--hint=string:sound-file:/home/me/sounds/done.oga"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("NotifySendIdentityArgs() = %q, want %q", got, want)
+	}
+}
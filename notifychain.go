@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// chainFlag is the hook flag carrying the notify command codex-notify
+// passes each payload on to, as a JSON array of argv. `init --chain` used
+// to write it into the Codex config; it now sets chain in config.toml, and
+// the flag is still read so those hooks keep working.
+const chainFlag = "--then"
+
+// parseChainedCommand decodes a --then value.
+func parseChainedCommand(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var argv []string
+	if err := json.Unmarshal([]byte(raw), &argv); err != nil || len(argv) == 0 || strings.TrimSpace(argv[0]) == "" {
+		return nil, fmt.Errorf("%s must be a JSON array naming a command, e.g. '[\"my-notifier\"]'", chainFlag)
+	}
+	return argv, nil
+}
+
+// startChainedNotify runs the chained notify command with the payload as
+// its last argument, the way Codex would have, or on stdin in private argv
+// mode. It is not waited for: a slow notifier must not hold up the hook,
+// and its failure is its own.
+func startChainedNotify(argv []string, payloadRaw string) {
+	if len(argv) == 0 || benchDryRun() {
+		return
+	}
+	cmd := exec.Command(argv[0], append(argv[1:], payloadRaw)...)
+	if privateArgvEnabled() {
+		stdin, err := unlinkedFile([]byte(payloadRaw))
+		if err != nil {
+			logErrorf("chained notify %s: %v", argv[0], err)
+			return
+		}
+		defer stdin.Close()
+		cmd = exec.Command(argv[0], argv[1:]...)
+		cmd.Stdin = stdin
+	}
+	cmd.Env = hookEnviron()
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	err := cmd.Start()
+	logExecStart(cmd, err)
+	if err != nil {
+		logErrorf("chained notify %s: %v", argv[0], err)
+		return
+	}
+	_ = cmd.Process.Release()
+}
+
+// notifyArgv parses the array of a `notify = [...]` line.
+func notifyArgv(line string) ([]string, error) {
+	_, rhs, ok := strings.Cut(stripTOMLComment(strings.TrimSpace(line)), "=")
+	if !ok {
+		return nil, errors.New("not a notify line")
+	}
+	value, err := parseTOMLValue(strings.TrimSpace(rhs))
+	if err != nil {
+		return nil, err
+	}
+	items, ok := value.([]any)
+	if !ok || len(items) == 0 {
+		return nil, errors.New("notify must be a non-empty array of strings")
+	}
+	argv := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, errors.New("notify must be a non-empty array of strings")
+		}
+		argv = append(argv, s)
+	}
+	return argv, nil
+}
+
+// hookChain returns the command a codex-notify notify array chains to.
+func hookChain(argv []string) []string {
+	for i := 0; i+1 < len(argv); i++ {
+		if argv[i] == chainFlag {
+			chain, _ := parseChainedCommand(argv[i+1])
+			return chain
+		}
+	}
+	return nil
+}
+
+// chainManagedNotifyBlock replaces a root notify that runs another program
+// with the managed codex-notify block, and returns that program for
+// config.toml's chain. A codex-notify line chaining with --then gives up its
+// chain the same way. Content without such a line is returned unchanged.
+func chainManagedNotifyBlock(content []byte) ([]byte, []string, error) {
+	lines := splitLines(content)
+	rootEnd := firstTableHeaderIndex(lines)
+	for i := 0; i < rootEnd; i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(lines[i], " ") || strings.HasPrefix(lines[i], "\t") || !isRootNotifyLine(trimmed) {
+			continue
+		}
+		argv, err := notifyArgv(trimmed)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot chain the existing notify (%v); rerun with --replace instead", err)
+		}
+		replacement := managedBlock(defaultNotifyLine)
+		if isCodexNotifyHookLine(trimmed) {
+			if argv = hookChain(argv); len(argv) == 0 {
+				continue
+			}
+			replacement = []string{defaultNotifyLine}
+		}
+		out := append([]string{}, lines[:i]...)
+		out = append(out, replacement...)
+		out = append(out, lines[i+1:]...)
+		return joinConfigLines(out), argv, nil
+	}
+	return content, nil, nil
+}
+
+// setUserConfigChain sets chain in config.toml to argv. It refuses to
+// replace a different chain, which would silently drop a notifier.
+func setUserConfigChain(argv []string) (string, bool, error) {
+	cfgPath, err := userConfigPath()
+	if err != nil {
+		return "", false, err
+	}
+	existing, err := readFileMaybe(cfgPath)
+	if err != nil {
+		return "", false, err
+	}
+	cfg, err := parseUserConfig(existing)
+	if err != nil {
+		return "", false, fmt.Errorf("parse %s: %w", cfgPath, err)
+	}
+	if strings.Join(cfg.Chain, "\x00") == strings.Join(argv, "\x00") {
+		return cfgPath, false, nil
+	}
+	if len(cfg.Chain) > 0 {
+		return "", false, fmt.Errorf("%s already chains to %s; remove chain there to chain %s instead", cfgPath, cfg.Chain[0], argv[0])
+	}
+	items := make([]any, len(argv))
+	for i, arg := range argv {
+		items[i] = arg
+	}
+	content := setTopLevelTOMLKey(existing, "chain", tomlLiteral(items))
+	if err := os.MkdirAll(filepath.Dir(cfgPath), 0o755); err != nil {
+		return "", false, fmt.Errorf("create config dir: %w", err)
+	}
+	if err := writeConfigChecked(cfgPath, existing, content); err != nil {
+		return "", false, fmt.Errorf("write %s: %w", cfgPath, err)
+	}
+	return cfgPath, true, nil
+}
+
+// addChainDoctorCheck reports the chain from config.toml.
+func addChainDoctorCheck(report *doctorReport, cfg userConfig) {
+	if len(cfg.Chain) == 0 {
+		return
+	}
+	detail := "then " + strings.Join(cfg.Chain, " ") + " (config.toml)"
+	if _, err := exec.LookPath(cfg.Chain[0]); err != nil {
+		report.add(doctorCodex, checkWarn, "notify chain", detail+", but "+cfg.Chain[0]+" is not on PATH", false)
+		return
+	}
+	report.add(doctorCodex, checkOK, "notify chain", detail, false)
+}
+
+// addNotifyConflictChecks looks for notify settings that fight codex-notify:
+// a duplicate root key, which Codex refuses to load, a foreign program in
+// its place, or profiles with their own notify.
+func addNotifyConflictChecks(report *doctorReport, cfgPath string, content []byte) {
+	lines := splitLines(content)
+	rootEnd := firstTableHeaderIndex(lines)
+	var root []string
+	table := ""
+	for i, line := range lines {
+		if isTableHeader(line) {
+			table = tableHeaderName(line)
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if !isRootNotifyLine(trimmed) {
+			continue
+		}
+		if i < rootEnd {
+			root = append(root, trimmed)
+			continue
+		}
+		if strings.HasPrefix(table, "profiles.") {
+			report.add(doctorCodex, checkWarn, "notify conflict", fmt.Sprintf("[%s] sets its own notify, which replaces codex-notify when Codex runs with that profile (%s)", table, cfgPath), false)
+		}
+	}
+
+	if len(root) > 1 {
+		report.add(doctorCodex, checkFail, "notify conflict", fmt.Sprintf("notify is set %d times; Codex rejects a duplicate key, keep one line (%s)", len(root), cfgPath), true)
+		return
+	}
+	if len(root) == 0 {
+		return
+	}
+	argv, err := notifyArgv(root[0])
+	if err != nil {
+		return
+	}
+	if !isCodexNotifyHookLine(root[0]) {
+		report.add(doctorCodex, checkWarn, "notify conflict", fmt.Sprintf("notify runs %s instead of codex-notify; `%s init --chain` runs both (%s)", argv[0], appName, cfgPath), false)
+		return
+	}
+	if chain := hookChain(argv); len(chain) > 0 {
+		report.add(doctorCodex, checkWarn, "notify chain", fmt.Sprintf("then %s through the hook's %s, which wins over chain in config.toml; `%s init --chain` moves it there (%s)", strings.Join(chain, " "), chainFlag, appName, cfgPath), false)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChainManagedNotifyBlock(t *testing.T) {
+	in := []byte(`notify = ["my-notifier", "--say", "it's done"]` + "\nmodel = \"o3\"\n")
+	got, chain, err := chainManagedNotifyBlock(in)
+	if err != nil || strings.Join(chain, "|") != "my-notifier|--say|it's done" {
+		t.Fatalf("chainManagedNotifyBlock() chain %q, err %v", chain, err)
+	}
+	lines := splitLines(got)
+	if lines[0] != managedBlockBegin || lines[1] != defaultNotifyLine || lines[2] != managedBlockEnd || lines[3] != `model = "o3"` {
+		t.Fatalf("got:\n%s", got)
+	}
+	if same, chain, _ := chainManagedNotifyBlock([]byte(defaultNotifyLine + "\n")); chain != nil || string(same) != defaultNotifyLine+"\n" {
+		t.Fatal("chainManagedNotifyBlock() touched a codex-notify line")
+	}
+
+	// A hook chaining with --then gives its chain up to config.toml.
+	legacy := managedBlockBegin + "\n" + legacyChainLine + "\n" + managedBlockEnd + "\n"
+	if again, changed, err := setManagedNotifyBlock([]byte(legacy), false); err != nil || changed || string(again) != legacy {
+		t.Fatalf("setManagedNotifyBlock() dropped the chain: changed %v, err %v", changed, err)
+	}
+	got, chain, err = chainManagedNotifyBlock([]byte(legacy))
+	if err != nil || strings.Join(chain, " ") != "sh -c true" || string(got) != managedBlockBegin+"\n"+defaultNotifyLine+"\n"+managedBlockEnd+"\n" {
+		t.Fatalf("chainManagedNotifyBlock(legacy) = %q, %q, %v", got, chain, err)
+	}
+}
+
+// legacyChainLine is a notify line as older `init --chain` wrote it.
+const legacyChainLine = `notify = ["codex-notify", "hook", "--then", '["sh","-c","true"]']`
+
+func TestInitChainMovesNotifierToUserConfig(t *testing.T) {
+	dir := t.TempDir()
+	userCfg := filepath.Join(dir, "codex-notify.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", userCfg)
+	codexCfg := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(codexCfg, []byte(`notify = ["my-notifier", "--quiet"]`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out := commandOutput{mode: outputQuiet}
+	if _, err := initCodexConfig(codexCfg, false, true, false, out); err != nil {
+		t.Fatalf("initCodexConfig(--chain): %v", err)
+	}
+	if content, _ := os.ReadFile(codexCfg); strings.Contains(string(content), "my-notifier") {
+		t.Fatalf("Codex config still runs the notifier:\n%s", content)
+	}
+	if cfg, err := loadUserConfig(); err != nil || strings.Join(cfg.Chain, " ") != "my-notifier --quiet" {
+		t.Fatalf("chain = %q, %v", cfg.Chain, err)
+	}
+
+	// A different chain is never replaced.
+	if err := os.WriteFile(codexCfg, []byte(`notify = ["other-notifier"]`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := initCodexConfig(codexCfg, false, true, false, out); err == nil {
+		t.Fatal("initCodexConfig(--chain) replaced a different chain")
+	}
+	if content, _ := os.ReadFile(codexCfg); !strings.Contains(string(content), "other-notifier") {
+		t.Fatal("a failed chain still rewrote the Codex config")
+	}
+}
+
+func TestNotifyConflictChecks(t *testing.T) {
+	tests := []struct {
+		name, content, status string
+	}{
+		{"foreign", `notify = ["my-notifier"]` + "\n", checkWarn},
+		{"duplicate", defaultNotifyLine + "\n" + `notify = ["my-notifier"]` + "\n", checkFail},
+		{"profile", defaultNotifyLine + "\n[profiles.work]\nnotify = [\"other\"]\n", checkWarn},
+		{"chain", legacyChainLine + "\n", checkWarn},
+	}
+	for _, tt := range tests {
+		var report doctorReport
+		addNotifyConflictChecks(&report, "config.toml", []byte(tt.content))
+		if len(report.Checks) != 1 || report.Checks[0].Status != tt.status {
+			t.Errorf("%s: checks = %+v", tt.name, report.Checks)
+		}
+	}
+	var report doctorReport
+	addNotifyConflictChecks(&report, "config.toml", []byte(defaultNotifyLine+"\n"))
+	if len(report.Checks) != 0 {
+		t.Errorf("plain config: checks = %+v", report.Checks)
+	}
+}
+
+func TestStartChainedNotify(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "payload")
+	chain, err := parseChainedCommand(`["sh", "-c", "printf '%s' \"$1\" > ` + out + `", "sh"]`)
+	if err != nil {
+		t.Fatalf("parseChainedCommand(): %v", err)
+	}
+	startChainedNotify(chain, `{"type":"agent-turn-complete"}`)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if got, _ := os.ReadFile(out); string(got) == `{"type":"agent-turn-complete"}` {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("chained command did not receive the payload")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := parseChainedCommand(`"my-notifier"`); err == nil {
+		t.Fatal("parseChainedCommand() accepted a bare string")
+	}
+}
+
+func TestParseUserConfigChain(t *testing.T) {
+	cfg, err := parseUserConfig([]byte(`chain = ["my-notifier", "--quiet"]` + "\n"))
+	if err != nil {
+		t.Fatalf("parseUserConfig(): %v", err)
+	}
+	if strings.Join(cfg.Chain, " ") != "my-notifier --quiet" {
+		t.Fatalf("Chain = %q", cfg.Chain)
+	}
+	for _, in := range []string{`chain = "my-notifier"`, `chain = []`, `chain = ["my-notifier", 1]`, `chain = [""]`} {
+		if _, err := parseUserConfig([]byte(in + "\n")); err == nil {
+			t.Errorf("parseUserConfig(%s) succeeded", in)
+		}
+	}
+}
+
+func TestChainedNotifyKeepsPrivatePayloadOffArgv(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "chained")
+	argv := []string{"sh", "-c", `{ echo "$#"; cat; } > "$0.tmp" && mv "$0.tmp" "$0"`, out}
+	read := func() string {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if got, err := os.ReadFile(out); err == nil {
+				_ = os.Remove(out)
+				return string(got)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatal("the chained command never ran")
+		return ""
+	}
+
+	t.Setenv("CODEX_NOTIFY_PRIVATE_ARGV", "")
+	startChainedNotify(argv, `{"type":"agent-turn-complete"}`)
+	if got := read(); got != "1\n" {
+		t.Fatalf("chained command got %q, want the payload as its one argument", got)
+	}
+	t.Setenv("CODEX_NOTIFY_PRIVATE_ARGV", "1")
+	startChainedNotify(argv, `{"type":"agent-turn-complete"}`)
+	if got := read(); got != "0\n"+`{"type":"agent-turn-complete"}` {
+		t.Fatalf("chained command got %q, want no arguments and the payload on stdin", got)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/MiUPa/codex-notify/notify"
+)
+
+// hostOS is the platform codex-notify behaves as. It is a variable so tests
+// can exercise the macOS and Linux paths on either host.
+var hostOS = runtime.GOOS
+
+var errKeystrokesUnsupported = errors.New("key injection needs macOS, or xdotool (X11) or wtype (Wayland) and CODEX_NOTIFY_TERMINAL_WM_CLASS on linux")
+
+// notifySendBackend shows freedesktop notifications through libnotify's
+// notify-send. With action support (libnotify 0.7.9+), a detached shell waits
+// for the user's choice and runs the matching `action` command.
+type notifySendBackend struct{}
+
+func (notifySendBackend) Name() string { return "notify-send" }
+
+func (notifySendBackend) Capabilities() backendCapabilities {
+	actions := notifySendSupportsActions()
+	return backendCapabilities{Click: actions, Actions: actions, Format: formatHTML}
+}
+
+func (notifySendBackend) Available() bool {
+	if hostOS != "linux" {
+		return false
+	}
+	_, ok := lookupCmd("notify-send")
+	return ok
+}
+
+func (notifySendBackend) Send(req notificationRequest) error {
+	path, ok := lookupCmd("notify-send")
+	if !ok {
+		return errors.New("notify-send not found")
+	}
+
+	choices := []approvalChoice{}
+	if req.ExecuteOnClick != "" || len(req.ExtraChoices) > 0 {
+		choices = popupChoicesForRequest(req)
+	}
+	if len(choices) == 0 {
+		n := notify.Notification{Title: req.Title, Message: req.Message, Icon: req.Icon, Sound: req.Sound}
+		return notify.NotifySend(path, n, notify.Options{AppName: appName, Trace: logExec})
+	}
+
+	// The title and message reach the shell through its environment, so
+	// they never need quoting into the script.
+	cmd := exec.Command("/bin/sh", "-c", notifySendScript(path, choices, notifySendIdentityArgs(req)))
+	cmd.Env = append(hookEnviron(),
+		"CODEX_NOTIFY_TITLE="+req.Title,
+		"CODEX_NOTIFY_MESSAGE="+req.Message,
+	)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	err := cmd.Start()
+	logExecStart(cmd, err)
+	if err != nil {
+		return fmt.Errorf("start notify-send: %w", err)
+	}
+	return cmd.Process.Release()
+}
+
+// notifySendScript waits for a notify-send action and runs its command.
+// Clicking the notification body ("default") runs the first choice.
+func notifySendScript(notifySendPath string, choices []approvalChoice, extra []string) string {
+	args := []string{shellQuote(notifySendPath), "--app-name=" + appName, "--wait", shellQuote("--action=default=" + choices[0].Label)}
+	for _, arg := range extra {
+		args = append(args, shellQuote(arg))
+	}
+	for i, c := range choices {
+		args = append(args, shellQuote("--action="+strconv.Itoa(i)+"="+c.Label))
+	}
+	args = append(args, "--", `"$CODEX_NOTIFY_TITLE"`, `"$CODEX_NOTIFY_MESSAGE"`)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "choice=$(%s) || exit 0\n", strings.Join(args, " "))
+	b.WriteString("case \"$choice\" in\n")
+	for i, c := range choices {
+		if strings.TrimSpace(c.Command) == "" {
+			continue
+		}
+		keys := strconv.Itoa(i)
+		if i == 0 {
+			keys = "default|0"
+		}
+		fmt.Fprintf(&b, "%s) exec /bin/sh -c %s ;;\n", keys, shellQuote(c.Command))
+	}
+	b.WriteString("esac\n")
+	return b.String()
+}
+
+// notifySendIdentityArgs passes an identity's icon and sound; the sound is
+// a freedesktop sound theme name, played by servers that support it.
+func notifySendIdentityArgs(req notificationRequest) []string {
+	return notify.NotifySendIdentityArgs(notify.Notification{Icon: req.Icon, Sound: req.Sound})
+}
+
+// notifySendSupportsActions reports whether the installed notify-send has
+// --action and --wait; older libnotify releases only show plain text.
+func notifySendSupportsActions() bool {
+	path, ok := lookupCmd("notify-send")
+	if !ok {
+		return false
+	}
+	out, _ := combinedOutputLogged(exec.Command(path, "--help"))
+	return strings.Contains(string(out), "--action") && strings.Contains(string(out), "--wait")
+}
+
+// activateLinuxTerminal raises the terminal window by WM class with wmctrl.
+// Linux has no bundle IDs, so the class comes from
+// CODEX_NOTIFY_TERMINAL_WM_CLASS.
+func activateLinuxTerminal() error {
+	class := strings.TrimSpace(getenv("CODEX_NOTIFY_TERMINAL_WM_CLASS"))
+	if class == "" {
+		return errors.New("set CODEX_NOTIFY_TERMINAL_WM_CLASS to the terminal's window class to activate it on linux")
+	}
+	path, ok := lookupCmd("wmctrl")
+	if !ok {
+		return errors.New("wmctrl not found (needed to activate the terminal on linux)")
+	}
+	if out, err := combinedOutputLogged(exec.Command(path, "-x", "-a", class)); err != nil {
+		return fmt.Errorf("activate window failed: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func addLinuxDoctorChecks(report *doctorReport) {
+	notifySendPath, ok := lookupCmd("notify-send")
+	switch {
+	case !ok:
+		report.add(doctorPlatform, checkFail, "notify-send", "not found; install libnotify (e.g. libnotify-bin)", true)
+	case notifySendSupportsActions():
+		report.add(doctorPlatform, checkOK, "notify-send", notifySendPath+" (actions supported)", false)
+	default:
+		report.add(doctorPlatform, checkWarn, "notify-send", notifySendPath+" (no --action support; notifications are not clickable)", false)
+	}
+
+	if strings.TrimSpace(getenv("CODEX_NOTIFY_TERMINAL_WM_CLASS")) == "" {
+		report.add(doctorPlatform, checkWarn, "terminal window", "CODEX_NOTIFY_TERMINAL_WM_CLASS not set; Open cannot raise the terminal", false)
+	} else if _, ok := lookupCmd("wmctrl"); !ok {
+		report.add(doctorPlatform, checkWarn, "terminal window", "wmctrl not found; Open cannot raise the terminal", false)
+	} else {
+		report.add(doctorPlatform, checkOK, "terminal window", getenv("CODEX_NOTIFY_TERMINAL_WM_CLASS"), false)
+	}
+
+	if tool, path := linuxKeyTool(); path == "" {
+		report.add(doctorPlatform, checkWarn, "keystrokes", tool+" not found; Approve, Reject, and submit presets are not offered", false)
+	} else {
+		report.add(doctorPlatform, checkOK, "keystrokes", path, false)
+	}
+}
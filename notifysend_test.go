@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNotificationBackendsOnLinux(t *testing.T) {
+	useHostOS(t, "linux")
+
+	backends := notificationBackends()
+	if len(backends) != 1 || backends[0].Name() != "notify-send" {
+		names := []string{}
+		for _, b := range backends {
+			names = append(names, b.Name())
+		}
+		t.Fatalf("notificationBackends() = %v, want [notify-send]", names)
+	}
+	if (popupBackend{}).Available() {
+		t.Fatalf("popupBackend.Available() on linux = true, want false")
+	}
+}
+
+func TestNotifySendScriptMapsActionsToCommands(t *testing.T) {
+	script := notifySendScript("/usr/bin/notify-send", []approvalChoice{
+		{Label: "Open", Command: "codex-notify action 'open' --thread-id 't1'"},
+		{Label: "Mute project 1h", Command: "codex-notify action 'mute-project' --cwd '/src/app'"},
+	}, nil)
+
+	for _, want := range []string{
+		`'/usr/bin/notify-send' --app-name=codex-notify --wait '--action=default=Open' '--action=0=Open' '--action=1=Mute project 1h' -- "$CODEX_NOTIFY_TITLE" "$CODEX_NOTIFY_MESSAGE"`,
+		`default|0) exec /bin/sh -c 'codex-notify action '"'"'open'"'"' --thread-id '"'"'t1'"'"'' ;;`,
+		`1) exec /bin/sh -c 'codex-notify action '"'"'mute-project'"'"' --cwd '"'"'/src/app'"'"'' ;;`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("notifySendScript() missing %q:\n%s", want, script)
+		}
+	}
+}
+
+func TestLinuxDisablesKeystrokeActions(t *testing.T) {
+	useHostOS(t, "linux")
+	t.Setenv("CODEX_NOTIFY_SANDBOX", "")
+	t.Setenv("CODEX_NOTIFY_ENABLE_APPROVAL_ACTIONS", "1")
+	t.Setenv("CODEX_NOTIFY_TERMINAL_WM_CLASS", "kitty")
+	t.Setenv("PATH", t.TempDir())
+
+	if approvalActionsEnabled() {
+		t.Fatalf("approvalActionsEnabled() on linux without xdotool = true, want false")
+	}
+	if err := sendKeySequence([]string{"y"}, "t1"); !errors.Is(err, errKeystrokesUnsupported) {
+		t.Fatalf("sendKeySequence() error = %v, want errKeystrokesUnsupported", err)
+	}
+}
+
+func TestLinuxKeystrokes(t *testing.T) {
+	useHostOS(t, "linux")
+	bin := t.TempDir()
+	log := filepath.Join(bin, "calls")
+	for _, tool := range []string{"xdotool", "wtype"} {
+		script := "#!/bin/sh\necho " + tool + " \"$@\" >> " + log + "\n"
+		if err := os.WriteFile(filepath.Join(bin, tool), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin)
+	t.Setenv("CODEX_NOTIFY_TERMINAL_WM_CLASS", "kitty")
+	t.Setenv("CODEX_NOTIFY_ENABLE_APPROVAL_ACTIONS", "1")
+	t.Setenv("WAYLAND_DISPLAY", "")
+
+	if !approvalActionsEnabled() {
+		t.Fatalf("approvalActionsEnabled() with xdotool = false, want true")
+	}
+	if err := sendKeySequence([]string{"y", "ctrl+l", "enter"}, "t1"); err != nil {
+		t.Fatalf("sendKeySequence() under X11 error = %v", err)
+	}
+	t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+	if err := sendKeySequence([]string{"cmd+shift+end", "hi"}, "t1"); err != nil {
+		t.Fatalf("sendKeySequence() under Wayland error = %v", err)
+	}
+
+	calls, _ := os.ReadFile(log)
+	want := "xdotool type --clearmodifiers --delay 0 -- y\n" +
+		"xdotool key --clearmodifiers ctrl+l\n" +
+		"xdotool key --clearmodifiers Return\n" +
+		"wtype -M super -M shift -k End -m super -m shift\n" +
+		"wtype -- hi\n"
+	if string(calls) != want {
+		t.Fatalf("key tool calls:\n%s\nwant:\n%s", calls, want)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultNtfyServer = "https://ntfy.sh"
+	ntfyTokenEnv      = "CODEX_NOTIFY_NTFY_TOKEN"
+)
+
+// defaultNtfyEvents are the events worth a phone push: Codex is waiting on
+// the user.
+var defaultNtfyEvents = []string{"agent-turn-complete", "approval-requested"}
+
+// ntfyConfig is the [ntfy] table of config.toml. The sink is off until a
+// topic is set.
+type ntfyConfig struct {
+	Server string
+	Topic  string
+	// Token is sent as a bearer token; CODEX_NOTIFY_NTFY_TOKEN overrides it,
+	// so the secret can stay out of the config file.
+	Token  string
+	Events []string
+	// ReplyTopic turns on Approve/Reject buttons on approval pushes. They
+	// publish to this topic, and a running daemon answers Codex from it.
+	// The buttons carry the approval's nonce, so they are only offered
+	// with a token: on an open topic anyone subscribed could answer.
+	ReplyTopic string
+	// Watch sends the compact watch variant instead of the desktop text,
+	// and WatchURL is what tapping the push opens.
+	Watch    bool
+	WatchURL string
+}
+
+func (c ntfyConfig) enabled() bool {
+	return c.Topic != ""
+}
+
+func (c ntfyConfig) wants(event string) bool {
+	events := c.Events
+	if events == nil {
+		events = defaultNtfyEvents
+	}
+	return containsString(events, event) || containsString(events, "all")
+}
+
+func (c ntfyConfig) server() string {
+	if c.Server == "" {
+		return defaultNtfyServer
+	}
+	return strings.TrimRight(c.Server, "/")
+}
+
+func (c ntfyConfig) token() string {
+	if token := strings.TrimSpace(getenv(ntfyTokenEnv)); token != "" {
+		return secretValue(token)
+	}
+	return secretValue(c.Token)
+}
+
+// repliesEnabled reports whether approval pushes get Approve/Reject
+// buttons and the daemon listens for them: a reply topic, a token to keep
+// others off it, and the ntfy_replies feature.
+func (c ntfyConfig) repliesEnabled() bool {
+	return c.ReplyTopic != "" && c.token() != "" && featureEnabled("ntfy_replies")
+}
+
+// set parses one `ntfy.<key>` entry.
+func (c *ntfyConfig) set(key string, value any) error {
+	if ok, err := parseWatchSetting("ntfy", key, value, &c.Watch, &c.WatchURL); ok {
+		return err
+	}
+	if key == "events" {
+		events, err := parseEventList("ntfy.events", value)
+		if err != nil {
+			return err
+		}
+		c.Events = events
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("ntfy.%s must be a string", key)
+	}
+	s = strings.TrimSpace(s)
+	switch key {
+	case "server":
+		if !strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://") {
+			return errors.New("ntfy.server must be an http(s) URL")
+		}
+		c.Server = s
+	case "topic":
+		if s == "" || strings.ContainsAny(s, "/ ") {
+			return errors.New("ntfy.topic must be a non-empty name without slashes or spaces")
+		}
+		c.Topic = s
+	case "token":
+		c.Token = s
+	case "reply_topic":
+		if s == "" || strings.ContainsAny(s, "/ ") {
+			return errors.New("ntfy.reply_topic must be a non-empty name without slashes or spaces")
+		}
+		c.ReplyTopic = s
+	default:
+		return unknownSetting("ntfy." + key)
+	}
+	return nil
+}
+
+// ntfyMessage is ntfy's JSON publish format.
+type ntfyMessage struct {
+	Topic    string       `json:"topic"`
+	Title    string       `json:"title,omitempty"`
+	Message  string       `json:"message"`
+	Tags     []string     `json:"tags,omitempty"`
+	Priority int          `json:"priority,omitempty"`
+	Actions  []ntfyAction `json:"actions,omitempty"`
+	Click    string       `json:"click,omitempty"`
+}
+
+// ntfyAction is an http action button: tapping it makes the phone send the
+// request, here a publish to the reply topic.
+type ntfyAction struct {
+	Action  string            `json:"action"`
+	Label   string            `json:"label"`
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+	Clear   bool              `json:"clear"`
+}
+
+func buildNtfyMessage(cfg ntfyConfig, payload map[string]any) ntfyMessage {
+	title, message := renderPayloadMessage(payload)
+	msg := ntfyMessage{Topic: cfg.Topic, Title: title, Message: renderMessage(message, formatPlain)}
+	if cfg.Watch {
+		msg.Title, msg.Message = watchText(payload)
+		msg.Click = expandWatchURL(cfg.WatchURL, payload)
+	} else if project, ok := projectIdentityForCwd(payloadCwd(payload)); ok {
+		msg.Message = project.prefix(msg.Message)
+	}
+	switch payloadEventName(payload) {
+	case "approval-requested":
+		msg.Tags, msg.Priority = []string{"warning"}, 4
+		if pending, ok := lookupPendingApproval(payloadThreadID(payload)); ok && cfg.repliesEnabled() {
+			msg.Actions = ntfyApprovalActions(cfg, pending)
+		}
+	case "agent-error":
+		msg.Tags, msg.Priority = []string{"rotating_light"}, 4
+	case "agent-turn-complete":
+		msg.Tags = []string{"white_check_mark"}
+	}
+	return msg
+}
+
+func publishNtfy(cfg ntfyConfig, msg ntfyMessage) error {
+	req, err := jsonRequest(cfg.server(), msg)
+	if err != nil {
+		return err
+	}
+	if token := cfg.token(); token != "" {
+		req.Headers["Authorization"] = "Bearer " + token
+	}
+	return publishWebhook(sinkHTTPClient, "ntfy", req)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseUserConfigNtfy(t *testing.T) {
+	cfg, err := parseUserConfig([]byte(`
+[ntfy]
+topic = "codex-runs"
+server = "https://ntfy.example.com/"
+token = "tk_secret"
+events = ["approval-requested"]
+`))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	n := cfg.Ntfy
+	if !n.enabled() || n.Topic != "codex-runs" || n.server() != "https://ntfy.example.com" || n.Token != "tk_secret" {
+		t.Fatalf("ntfy config = %+v", n)
+	}
+	if n.wants("agent-turn-complete") || !n.wants("approval-requested") {
+		t.Fatalf("events filter = %v", n.Events)
+	}
+
+	if _, err := parseUserConfig([]byte("[ntfy]\ntopic = \"a/b\"\n")); err == nil {
+		t.Fatal("expected an error for a topic with a slash")
+	}
+	if _, err := parseUserConfig([]byte("[ntfy]\npriority = \"high\"\n")); err == nil {
+		t.Fatal("expected an error for an unknown ntfy key")
+	}
+	if (ntfyConfig{Topic: "t"}).wants("agent-error") {
+		t.Fatal("default events should not include agent-error")
+	}
+}
+
+func TestPublishNtfy(t *testing.T) {
+	t.Setenv(ntfyTokenEnv, "tk_env")
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "0")
+
+	var got ntfyMessage
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := ntfyConfig{Server: server.URL, Topic: "codex-runs", Token: "tk_file"}
+	payload := map[string]any{
+		"type":                   "agent-turn-complete",
+		"last-assistant-message": "All **tests** pass.",
+	}
+	if err := publishNtfy(cfg, buildNtfyMessage(cfg, payload)); err != nil {
+		t.Fatalf("publishNtfy() error = %v", err)
+	}
+	if auth != "Bearer tk_env" {
+		t.Fatalf("Authorization = %q, want the env token", auth)
+	}
+	if got.Topic != "codex-runs" || got.Title != "Codex: ✅ Turn Complete" || got.Message != "All tests pass." {
+		t.Fatalf("published %+v", got)
+	}
+}
+
+func TestRedactConfigSecrets(t *testing.T) {
+	in := "ntfy.token = \"a\"\n[ntfy]\ntopic = \"t\"\ntoken = \"b\"\n[slack]\nwebhook_url = \"https://hooks.example/c\"\n[click]\ntoken = \"kept\"\n"
+	out := redactConfigSecrets(in)
+	if strings.Contains(out, `"a"`) || strings.Contains(out, `"b"`) || strings.Contains(out, "hooks.example") {
+		t.Fatalf("token not redacted:\n%s", out)
+	}
+	if !strings.Contains(out, `topic = "t"`) || !strings.Contains(out, `token = "kept"`) {
+		t.Fatalf("redacted too much:\n%s", out)
+	}
+	if !strings.Contains(out, "# token = (not exported; set it again or use "+ntfyTokenEnv+")") {
+		t.Fatalf("redacted ntfy token does not name %s:\n%s", ntfyTokenEnv, out)
+	}
+	if _, err := parseUserConfig([]byte(out)); err != nil {
+		t.Fatalf("redacted config does not parse: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// approvalReply is the body an ntfy action button publishes to the reply
+// topic. Nonce ties it to one pending approval.
+type approvalReply struct {
+	Action string `json:"action"`
+	Thread string `json:"thread_id"`
+	Nonce  string `json:"nonce"`
+}
+
+func ntfyApprovalActions(cfg ntfyConfig, pending pendingApproval) []ntfyAction {
+	headers := map[string]string{}
+	if token := cfg.token(); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	actions := []ntfyAction{}
+	for _, choice := range []struct{ label, action string }{{"Approve", "approve"}, {"Reject", "reject"}} {
+		body, _ := json.Marshal(approvalReply{Action: choice.action, Thread: pending.Thread, Nonce: pending.Nonce})
+		actions = append(actions, ntfyAction{
+			Action:  "http",
+			Label:   choice.label,
+			URL:     cfg.server() + "/" + cfg.ReplyTopic,
+			Method:  http.MethodPost,
+			Headers: headers,
+			Body:    string(body),
+			Clear:   true,
+		})
+	}
+	return actions
+}
+
+// handleApprovalReply answers Codex for a reply from the phone, then
+// withdraws the prompt from this Mac. A reply that matches no pending
+// approval (already answered, expired, or forged) sends nothing.
+func handleApprovalReply(body string, now time.Time) error {
+	var reply approvalReply
+	if err := json.Unmarshal([]byte(body), &reply); err != nil {
+		return fmt.Errorf("ignoring reply that is not an approval: %w", err)
+	}
+	return answerApprovalReply(reply, "from ntfy", now)
+}
+
+// answerApprovalReply answers Codex for a reply that came from off this
+// Mac, the phone or a peer; detail says which in the thread's timeline.
+func answerApprovalReply(reply approvalReply, detail string, now time.Time) error {
+	if reply.Action != "approve" && reply.Action != "reject" {
+		return fmt.Errorf("ignoring reply with action %q", reply.Action)
+	}
+	if reply.Thread == "" || reply.Nonce == "" {
+		return errors.New("ignoring reply without thread_id and nonce")
+	}
+	pending, ok := claimApproval(reply.Thread, reply.Nonce, now)
+	if !ok {
+		return fmt.Errorf("ignoring %s for thread %s: no matching pending approval", reply.Action, reply.Thread)
+	}
+	if err := dispatchAction(reply.Action, pending.Thread, "", pending.Cwd, 0); err != nil {
+		reportPermissionFailure(reply.Action, err)
+		return err
+	}
+	clearPermissionProblem()
+	resolveWidgetApproval(pending.Thread)
+	recordActionEvent(reply.Action, pending.Thread, pending.Cwd, detail, "ok")
+	// The daemon keeps running, so the withdrawals finish without holding
+	// up the reply.
+	withdrawApproval(pending.Thread, answeredRemote)
+	return nil
+}
+
+// ntfyStreamIdleTimeout is how long the reply stream may go without a line,
+// keepalives included, before it is reopened.
+const ntfyStreamIdleTimeout = 2 * time.Minute
+
+// ntfyStreamClient subscribes to the reply topic. The stream stays open, so
+// sinkHTTPClient's overall timeout does not apply; connecting and the
+// response headers are bounded instead, and streamNtfyReplies watches for
+// a stream that stops sending.
+var ntfyStreamClient = &http.Client{Transport: &http.Transport{
+	Proxy:                 http.ProxyFromEnvironment,
+	DialContext:           (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ResponseHeaderTimeout: 30 * time.Second,
+	IdleConnTimeout:       90 * time.Second,
+}}
+
+// ntfyStreamEvent is one line of ntfy's JSON subscription stream.
+type ntfyStreamEvent struct {
+	Event   string `json:"event"`
+	Message string `json:"message"`
+}
+
+// startNtfyReplyListener subscribes to the reply topic until the returned
+// function is called, reconnecting after errors.
+func startNtfyReplyListener(cfg ntfyConfig) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for ctx.Err() == nil {
+			if err := streamNtfyReplies(ctx, cfg); err != nil && ctx.Err() == nil {
+				logErrorf("ntfy replies: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}()
+	return cancel
+}
+
+func streamNtfyReplies(ctx context.Context, cfg ntfyConfig) error {
+	stream, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(stream, http.MethodGet, cfg.server()+"/"+cfg.ReplyTopic+"/json", nil)
+	if err != nil {
+		return err
+	}
+	if token := cfg.token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ntfyStreamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("subscribe returned %s", resp.Status)
+	}
+
+	// ntfy sends a keepalive event every 45 seconds, so a stream that
+	// stays silent for longer has died without closing.
+	idle := time.AfterFunc(ntfyStreamIdleTimeout, cancel)
+	defer idle.Stop()
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var ev ntfyStreamEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) == nil && ev.Event == "message" {
+			var err error
+			inDaemonWork(func() { err = handleApprovalReply(ev.Message, time.Now()) })
+			if err != nil {
+				logErrorf("ntfy replies: %v", err)
+			}
+		}
+		idle.Reset(ntfyStreamIdleTimeout)
+	}
+	if stream.Err() != nil && ctx.Err() == nil {
+		return fmt.Errorf("no data for %s; reconnecting", ntfyStreamIdleTimeout)
+	}
+	return scanner.Err()
+}
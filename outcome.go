@@ -0,0 +1,84 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// turnOutcome classifies a finished turn from the final assistant message,
+// so the notification says whether Codex is done or stuck.
+type turnOutcome string
+
+const (
+	outcomeUnknown     turnOutcome = ""
+	outcomeSucceeded   turnOutcome = "succeeded"
+	outcomeNeedsInput  turnOutcome = "needs-input"
+	outcomeTestsFailed turnOutcome = "tests-failed"
+	outcomeBlocked     turnOutcome = "blocked"
+	outcomeFailed      turnOutcome = "failed"
+)
+
+var (
+	testsFailedRE = regexp.MustCompile(`(?i)\b(tests?|specs?|checks?|builds?)\b[^.!?\n]{0,40}\b(fail(ed|ing|s)?|broke|broken)\b|\bfail(ed|ing)\s+(tests?|specs?)\b|\b[1-9]\d*\s+(failures?|failed|failing)\b|テスト.{0,10}(失敗|落ち)`)
+	// noFailuresRE catches "no tests failed" and "0 failures".
+	noFailuresRE = regexp.MustCompile(`(?i)\b(no|none|zero|0)\s+(tests?\s+)?(fail(ed|ures?|ing)?)\b`)
+	blockedRE    = regexp.MustCompile(`(?i)\bi\s+(can(no|')t|couldn't|could not|was unable|am unable)\b|\bunable to\b|\bblocked (by|on)\b|\bpermission denied\b|\bdon't have (access|permission)\b|できません|権限がありません`)
+	needsInputRE = regexp.MustCompile(`(?i)\b(should i|shall i|would you like|do you want|let me know|please confirm|which (one|option) (do|would|should))\b|しますか|でしょうか|教えてください`)
+)
+
+func turnOutcomesEnabled() bool {
+	switch strings.TrimSpace(strings.ToLower(getenv("CODEX_NOTIFY_TURN_OUTCOMES"))) {
+	case "0", "false", "no", "off":
+		return false
+	default:
+		return true
+	}
+}
+
+// classifyTurn looks only at the assistant's final message; a turn without
+// one (or a payload that is not a turn) is unknown. An explicit payload
+// status that says the turn failed or succeeded wins over the text.
+func classifyTurn(payload map[string]any) turnOutcome {
+	if payloadEventName(payload) != "agent-turn-complete" || !turnOutcomesEnabled() {
+		return outcomeUnknown
+	}
+	switch strings.ToLower(getStringAny(payload, "status", "outcome")) {
+	case "failed", "failure", "error", "errored":
+		return outcomeFailed
+	case "succeeded", "success", "completed", "complete", "ok":
+		return outcomeSucceeded
+	}
+
+	msg := getStringAny(payload, "last-assistant-message", "last_assistant_message")
+	if msg == "" {
+		return outcomeUnknown
+	}
+	switch {
+	case testsFailedRE.MatchString(msg) && !noFailuresRE.MatchString(msg):
+		return outcomeTestsFailed
+	case blockedRE.MatchString(msg):
+		return outcomeBlocked
+	case needsInputRE.MatchString(msg) || strings.HasSuffix(msg, "?") || strings.HasSuffix(msg, "？"):
+		return outcomeNeedsInput
+	default:
+		return outcomeSucceeded
+	}
+}
+
+// turnTitle is the completion title for outcome, with an emoji to triage by.
+func turnTitle(text notificationStrings, outcome turnOutcome) string {
+	switch outcome {
+	case outcomeSucceeded:
+		return text.SucceededTitle
+	case outcomeNeedsInput:
+		return text.NeedsInputTitle
+	case outcomeTestsFailed:
+		return text.TestsFailedTitle
+	case outcomeBlocked:
+		return text.BlockedTitle
+	case outcomeFailed:
+		return text.FailedTitle
+	default:
+		return text.TurnCompleteTitle
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestClassifyTurn(t *testing.T) {
+	tests := []struct {
+		message string
+		want    turnOutcome
+	}{
+		{"Added 4 tests for POST /login. All tests pass.", outcomeSucceeded},
+		{"Ran go test: 0 failures.", outcomeSucceeded},
+		{"Fixed the parser, but 2 tests failed in pkg/api.", outcomeTestsFailed},
+		{"The build is broken after the upgrade.", outcomeTestsFailed},
+		{"テストが2件失敗しました。", outcomeTestsFailed},
+		{"I couldn't push because permission denied on origin.", outcomeBlocked},
+		{"Should I also update the README?", outcomeNeedsInput},
+		{"Two options are possible; let me know which you prefer.", outcomeNeedsInput},
+		{"どちらの方式で実装しますか", outcomeNeedsInput},
+	}
+	for _, tt := range tests {
+		payload := map[string]any{"type": "agent-turn-complete", "last-assistant-message": tt.message}
+		if got := classifyTurn(payload); got != tt.want {
+			t.Errorf("classifyTurn(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+
+	if got := classifyTurn(map[string]any{"type": "agent-turn-complete", "input-messages": []any{"fix it?"}}); got != outcomeUnknown {
+		t.Errorf("turn without an assistant message = %q, want unknown", got)
+	}
+	if got := classifyTurn(map[string]any{"type": "approval-requested", "message": "Allow?"}); got != outcomeUnknown {
+		t.Errorf("approval = %q, want unknown", got)
+	}
+
+	for _, tt := range []struct {
+		status, message string
+		want            turnOutcome
+	}{
+		{"failed", "All tests pass.", outcomeFailed},
+		{"error", "", outcomeFailed},
+		{"Failure", "Done.", outcomeFailed},
+		{"success", "Should I also update the README?", outcomeSucceeded},
+		{"completed", "", outcomeSucceeded},
+		{"running", "2 tests failed.", outcomeTestsFailed},
+	} {
+		payload := map[string]any{"type": "agent-turn-complete", "status": tt.status, "last-assistant-message": tt.message}
+		if got := classifyTurn(payload); got != tt.want {
+			t.Errorf("classifyTurn(status %q, %q) = %q, want %q", tt.status, tt.message, got, tt.want)
+		}
+	}
+	if got := turnTitle(englishStrings, outcomeFailed); got != "Codex: ❌ Turn Failed" {
+		t.Errorf("failed title = %q", got)
+	}
+
+	t.Setenv("CODEX_NOTIFY_TURN_OUTCOMES", "0")
+	if got := classifyTurn(map[string]any{"type": "agent-turn-complete", "last-assistant-message": "Done."}); got != outcomeUnknown {
+		t.Errorf("disabled classification = %q, want unknown", got)
+	}
+}
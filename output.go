@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type outputMode int
+
+const (
+	outputHuman outputMode = iota
+	outputQuiet
+	outputJSON
+)
+
+// outputEnv sets the output mode for commands run without --json or
+// --quiet: "json", "quiet", or "human".
+const outputEnv = "CODEX_NOTIFY_OUTPUT"
+
+type outputFlags struct {
+	fs        *flag.FlagSet
+	quiet     *bool
+	json      *bool
+	porcelain *bool
+	verbose   *bool
+}
+
+// addOutputFlags registers the shared --quiet / --json / --porcelain flags,
+// and --verbose, which logs debug lines on stderr.
+// --porcelain is an alias of --json: both print exactly one JSON document.
+func addOutputFlags(fs *flag.FlagSet) outputFlags {
+	return outputFlags{
+		fs:        fs,
+		quiet:     fs.Bool("quiet", false, "suppress non-error output"),
+		json:      fs.Bool("json", false, "print a single JSON result"),
+		porcelain: fs.Bool("porcelain", false, "alias of --json"),
+		verbose:   fs.Bool("verbose", false, "log debug lines, including every command run, on stderr"),
+	}
+}
+
+func (f outputFlags) output() commandOutput {
+	if *f.verbose {
+		_ = os.Setenv(verboseEnv, "1")
+	}
+	mode := envOutputMode()
+	if f.explicit() {
+		mode = outputHuman
+	}
+	switch {
+	case *f.json || *f.porcelain:
+		mode = outputJSON
+	case *f.quiet:
+		mode = outputQuiet
+	}
+	return commandOutput{mode: mode, w: os.Stdout}
+}
+
+// explicit reports whether an output flag was given, if only as
+// --json=false, which then keeps CODEX_NOTIFY_OUTPUT from applying.
+func (f outputFlags) explicit() bool {
+	given := false
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "quiet", "json", "porcelain":
+			given = true
+		}
+	})
+	return given
+}
+
+func envOutputMode() outputMode {
+	switch strings.TrimSpace(strings.ToLower(getenv(outputEnv))) {
+	case "json", "porcelain":
+		return outputJSON
+	case "quiet":
+		return outputQuiet
+	}
+	return outputHuman
+}
+
+// applyGlobalOutputFlags takes --json, --porcelain, and --quiet given before
+// the command, as in `codex-notify --json status`, and passes them on
+// through CODEX_NOTIFY_OUTPUT; --verbose likewise sets CODEX_NOTIFY_VERBOSE.
+// It returns the arguments without them.
+func applyGlobalOutputFlags(args []string) []string {
+	for len(args) > 0 {
+		switch args[0] {
+		case "--json", "--porcelain", "-json", "-porcelain":
+			_ = os.Setenv(outputEnv, "json")
+		case "--quiet", "-quiet":
+			_ = os.Setenv(outputEnv, "quiet")
+		case "--verbose", "-verbose", "-v":
+			_ = os.Setenv(verboseEnv, "1")
+		default:
+			return args
+		}
+		args = args[1:]
+	}
+	return args
+}
+
+// commandOutput routes human-oriented text and machine-readable results so
+// each subcommand can describe its outcome once and support every mode.
+type commandOutput struct {
+	mode outputMode
+	w    io.Writer
+}
+
+func (o commandOutput) Printf(format string, args ...any) {
+	if o.mode != outputHuman {
+		return
+	}
+	fmt.Fprintf(o.w, format, args...)
+}
+
+func (o commandOutput) Println(args ...any) {
+	if o.mode != outputHuman {
+		return
+	}
+	fmt.Fprintln(o.w, args...)
+}
+
+// Result prints v as JSON in --json mode and is a no-op otherwise.
+func (o commandOutput) Result(v any) error {
+	if o.mode != outputJSON {
+		return nil
+	}
+	enc := json.NewEncoder(o.w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode json output: %w", err)
+	}
+	return nil
+}
+
+// commandResult is the stable JSON shape shared by init, uninstall, test,
+// hook, and action.
+type commandResult struct {
+	Command string `json:"command"`
+	Status  string `json:"status"`
+	Config  string `json:"config,omitempty"`
+	Backup  string `json:"backup,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Action  string `json:"action,omitempty"`
+	Thread  string `json:"thread_id,omitempty"`
+	Count   int    `json:"count,omitempty"`
+	// Rule is the routing rule that kept a hook event off the desktop.
+	Rule  string `json:"rule,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Targets holds one result per Codex config when a command manages
+	// several (codex_configs in the codex-notify config).
+	Targets []commandResult `json:"targets,omitempty"`
+}
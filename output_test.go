@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"testing"
+)
+
+func parseOutputFlagsForTest(t *testing.T, args ...string) commandOutput {
+	t.Helper()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags := addOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse(%v): %v", args, err)
+	}
+	return flags.output()
+}
+
+func TestCommandOutputModes(t *testing.T) {
+	t.Run("human prints text only", func(t *testing.T) {
+		var buf bytes.Buffer
+		out := parseOutputFlagsForTest(t)
+		out.w = &buf
+
+		out.Printf("updated %s\n", "config.toml")
+		if err := out.Result(commandResult{Command: "init", Status: "updated"}); err != nil {
+			t.Fatalf("Result() error = %v", err)
+		}
+		if got := buf.String(); got != "updated config.toml\n" {
+			t.Fatalf("output = %q, want human text only", got)
+		}
+	})
+
+	t.Run("quiet prints nothing", func(t *testing.T) {
+		var buf bytes.Buffer
+		out := parseOutputFlagsForTest(t, "--quiet")
+		out.w = &buf
+
+		out.Println("hello")
+		_ = out.Result(commandResult{Command: "init", Status: "updated"})
+		if buf.Len() != 0 {
+			t.Fatalf("output = %q, want empty", buf.String())
+		}
+	})
+
+	for _, flagName := range []string{"--json", "--porcelain"} {
+		t.Run(flagName+" prints one document", func(t *testing.T) {
+			var buf bytes.Buffer
+			out := parseOutputFlagsForTest(t, flagName)
+			out.w = &buf
+
+			out.Println("hello")
+			if err := out.Result(commandResult{Command: "init", Status: "updated", Config: "/tmp/config.toml"}); err != nil {
+				t.Fatalf("Result() error = %v", err)
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("output %q is not JSON: %v", buf.String(), err)
+			}
+			if got["command"] != "init" || got["status"] != "updated" || got["config"] != "/tmp/config.toml" {
+				t.Fatalf("result = %v, want init/updated/config", got)
+			}
+		})
+	}
+}
+
+func TestOutputEnvAndGlobalFlags(t *testing.T) {
+	t.Setenv(outputEnv, "json")
+	if out := parseOutputFlagsForTest(t); out.mode != outputJSON {
+		t.Fatalf("mode with %s=json = %v, want JSON", outputEnv, out.mode)
+	}
+	if out := parseOutputFlagsForTest(t, "--json=false"); out.mode != outputHuman {
+		t.Fatalf("mode with --json=false = %v, want human", out.mode)
+	}
+	if out := parseOutputFlagsForTest(t, "--quiet"); out.mode != outputQuiet {
+		t.Fatalf("mode with --quiet = %v, want quiet", out.mode)
+	}
+
+	t.Setenv(outputEnv, "")
+	rest := applyGlobalOutputFlags([]string{"--quiet", "--json", "status", "--json"})
+	if len(rest) != 2 || rest[0] != "status" {
+		t.Fatalf("applyGlobalOutputFlags() = %v, want [status --json]", rest)
+	}
+	if out := parseOutputFlagsForTest(t); out.mode != outputJSON {
+		t.Fatalf("mode after global --json = %v, want JSON", out.mode)
+	}
+}
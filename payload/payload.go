@@ -0,0 +1,109 @@
+// Package payload reads the JSON payloads Codex passes to its notify
+// command. Codex has used several spellings for the same field over time
+// (thread-id, thread_id, threadId), and the accessors accept all of them.
+package payload
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// previewLimit is the longest Preview returns, in characters.
+const previewLimit = 180
+
+// Payload is one decoded notify payload.
+type Payload map[string]any
+
+// Parse decodes a payload. Blank input is an empty payload, since Codex
+// may run the command without one.
+func Parse(raw []byte) (Payload, error) {
+	p := Payload{}
+	if strings.TrimSpace(string(raw)) == "" {
+		return p, nil
+	}
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, fmt.Errorf("parse payload json: %w", err)
+	}
+	return p, nil
+}
+
+// Event is the event name, such as "agent-turn-complete".
+func (p Payload) Event() string {
+	return p.String("event", "type")
+}
+
+// ThreadID identifies the Codex session the event belongs to.
+func (p Payload) ThreadID() string {
+	return p.String("thread-id", "thread_id", "threadId")
+}
+
+// TurnID identifies one turn within the thread.
+func (p Payload) TurnID() string {
+	return p.String("turn-id", "turn_id", "turnId")
+}
+
+// Cwd is the working directory Codex ran in.
+func (p Payload) Cwd() string {
+	return p.String("cwd", "working-directory", "working_directory")
+}
+
+// Preview is the assistant message, or the input messages without one, on
+// one line and cut to 180 characters. The cut never splits a UTF-8
+// sequence.
+func (p Payload) Preview() string {
+	msg := p.String(
+		"last-assistant-message",
+		"last_assistant_message",
+		"message",
+		"text",
+	)
+	if msg == "" {
+		if msgs := p.Strings("input-messages", "input_messages"); len(msgs) > 0 {
+			msg = strings.Join(msgs, " ")
+		}
+	}
+	msg = strings.Join(strings.Fields(msg), " ")
+	if runes := []rune(msg); len(runes) > previewLimit {
+		msg = string(runes[:previewLimit-3]) + "..."
+	}
+	return msg
+}
+
+// String returns the first of keys holding a non-blank string, trimmed.
+func (p Payload) String(keys ...string) string {
+	for _, key := range keys {
+		if s, ok := p[key].(string); ok {
+			if s = strings.TrimSpace(s); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// Strings returns the first of keys holding a list with non-blank items,
+// trimmed. Non-string items are formatted with %v.
+func (p Payload) Strings(keys ...string) []string {
+	for _, key := range keys {
+		var out []string
+		switch typed := p[key].(type) {
+		case []string:
+			for _, item := range typed {
+				if item = strings.TrimSpace(item); item != "" {
+					out = append(out, item)
+				}
+			}
+		case []any:
+			for _, item := range typed {
+				if s := strings.TrimSpace(fmt.Sprintf("%v", item)); s != "" {
+					out = append(out, s)
+				}
+			}
+		}
+		if len(out) > 0 {
+			return out
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package payload
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(`{"type":"agent-turn-complete","thread_id":" t1 ","turnId":"3","working-directory":"/src/app","input-messages":["fix", " the ", "tests"]}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if p.Event() != "agent-turn-complete" || p.ThreadID() != "t1" || p.TurnID() != "3" || p.Cwd() != "/src/app" {
+		t.Fatalf("accessors = %q %q %q %q", p.Event(), p.ThreadID(), p.TurnID(), p.Cwd())
+	}
+	if got := p.Preview(); got != "fix the tests" {
+		t.Fatalf("Preview() = %q", got)
+	}
+	if p, err := Parse([]byte("  \n")); err != nil || len(p) != 0 {
+		t.Fatalf("Parse(blank) = %v, %v", p, err)
+	}
+	if _, err := Parse([]byte("{")); err == nil {
+		t.Fatal("Parse() accepted broken JSON")
+	}
+}
+
+func TestPreviewPrefersAssistantMessageAndTruncates(t *testing.T) {
+	p := Payload{"last-assistant-message": strings.Repeat("word ", 60), "input-messages": []any{"ignored"}}
+	got := p.Preview()
+	if len(got) != previewLimit || !strings.HasSuffix(got, "...") || !strings.HasPrefix(got, "word word") {
+		t.Fatalf("Preview() = %q (%d bytes)", got, len(got))
+	}
+	got = Payload{"message": strings.Repeat("完了", 100)}.Preview()
+	if !utf8.ValidString(got) || utf8.RuneCountInString(got) != previewLimit {
+		t.Fatalf("Preview() of Japanese text = %q (%d characters)", got, utf8.RuneCountInString(got))
+	}
+	if got := (Payload{"event": 3, "type": "approval-requested"}).Event(); got != "approval-requested" {
+		t.Fatalf("Event() skipped a non-string field wrongly: %q", got)
+	}
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	peerTokenEnv    = "CODEX_NOTIFY_PEER_TOKEN"
+	peerTokenSecret = "peer-token"
+
+	// peerMaxSkew is how far a signed peer request's timestamp may be from
+	// the receiver's clock; older ones are taken for replays.
+	peerMaxSkew = 5 * time.Minute
+	// peerBodyLimit bounds what the listener reads from one request.
+	peerBodyLimit = 1 << 20
+)
+
+// defaultPeerEvents are mirrored when [peer] has no events list: the ones
+// that want a person, wherever they sit.
+var defaultPeerEvents = []string{"agent-turn-complete", "approval-requested", "agent-error"}
+
+// peerConfig is the [peer] table of config.toml. Two machines that run the
+// daemon point url at each other and share token; each mirrors its events
+// to the other, and approvals can be answered from either.
+type peerConfig struct {
+	// URL is the other machine's listener, e.g. http://laptop.local:7788.
+	URL string
+	// Listen is the address this daemon accepts the other's requests on.
+	Listen string
+	// Token signs every request; CODEX_NOTIFY_PEER_TOKEN overrides it.
+	Token string
+	// Name labels this machine's notifications on the other one; the host
+	// name by default.
+	Name   string
+	Events []string
+}
+
+func (c peerConfig) enabled() bool {
+	return c.URL != "" && c.token() != ""
+}
+
+func (c peerConfig) listening() bool {
+	return c.Listen != "" && c.token() != ""
+}
+
+func (c peerConfig) wants(event string) bool {
+	events := c.Events
+	if events == nil {
+		events = defaultPeerEvents
+	}
+	return containsString(events, event) || containsString(events, "all")
+}
+
+func (c peerConfig) token() string {
+	if token := strings.TrimSpace(getenv(peerTokenEnv)); token != "" {
+		return secretValue(token)
+	}
+	return secretValue(c.Token)
+}
+
+func (c peerConfig) name() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "peer"
+	}
+	return strings.TrimSuffix(host, ".local")
+}
+
+// set parses one `peer.<key>` entry.
+func (c *peerConfig) set(key string, value any) error {
+	if key == "events" {
+		events, err := parseEventList("peer.events", value)
+		if err != nil {
+			return err
+		}
+		c.Events = events
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("peer.%s must be a string", key)
+	}
+	s = strings.TrimSpace(s)
+	switch key {
+	case "url":
+		if !strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://") {
+			return errors.New("peer.url must be an http(s) URL")
+		}
+		c.URL = strings.TrimRight(s, "/")
+	case "listen":
+		if _, _, err := net.SplitHostPort(s); err != nil {
+			return fmt.Errorf("peer.listen must be host:port or :port: %w", err)
+		}
+		c.Listen = s
+	case "token":
+		c.Token = s
+	case "name":
+		c.Name = s
+	default:
+		return unknownSetting("peer." + key)
+	}
+	return nil
+}
+
+// peerMessage is what one daemon posts to the other's /notify. Nonce is
+// the pending approval's, which the Approve and Reject buttons send back.
+type peerMessage struct {
+	From    string         `json:"from"`
+	Payload map[string]any `json:"payload"`
+	Nonce   string         `json:"nonce,omitempty"`
+}
+
+// peerWithdrawal tells the other machine to close its copy of a thread's
+// approval, answered here.
+type peerWithdrawal struct {
+	Thread string `json:"thread_id"`
+}
+
+func buildPeerMessage(cfg peerConfig, payload map[string]any) peerMessage {
+	msg := peerMessage{From: cfg.name(), Payload: payload}
+	if payloadEventName(payload) == "approval-requested" {
+		if pending, ok := lookupPendingApproval(payloadThreadID(payload)); ok {
+			msg.Nonce = pending.Nonce
+		}
+	}
+	return msg
+}
+
+// postPeer sends a signed request to the other machine's listener.
+func postPeer(cfg peerConfig, path string, v any) error {
+	req, err := jsonRequest(cfg.URL+path, v)
+	if err != nil {
+		return err
+	}
+	req.Headers[webhookTimestampHeader], req.Headers[webhookSignatureHeader] = signPeerRequest(cfg.token(), http.MethodPost, path, req.Body, time.Now())
+	return publishWebhook(sinkHTTPClient, "peer", req)
+}
+
+// signPeerRequest signs a request like a signed webhook, with the method and
+// path signed along with the body, so a body captured on its way to one
+// endpoint cannot be replayed to another.
+func signPeerRequest(token, method, path, body string, now time.Time) (timestamp, signature string) {
+	return signWebhookBody(token, method+" "+path+"\n"+body, now)
+}
+
+// verifyPeerRequest checks the signature and age of a request to path.
+func verifyPeerRequest(token, method, path string, header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get(webhookTimestampHeader)
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("missing or malformed timestamp")
+	}
+	if skew := now.Sub(time.Unix(sec, 0)); skew > peerMaxSkew || skew < -peerMaxSkew {
+		return fmt.Errorf("timestamp is %s off", skew.Round(time.Second))
+	}
+	_, want := signPeerRequest(token, method, path, string(body), time.Unix(sec, 0))
+	if !hmac.Equal([]byte(header.Get(webhookSignatureHeader)), []byte(want)) {
+		return errors.New("bad signature")
+	}
+	return nil
+}
+
+// peerHandler serves the other machine's requests: /notify shows its
+// events here, /reply answers one of its approvals from here, /withdraw
+// closes a mirrored approval answered there, and /ping reports the name.
+func peerHandler(cfg peerConfig) http.Handler {
+	mux := http.NewServeMux()
+	handle := func(path string, fn func(body []byte) error) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "POST only", http.StatusMethodNotAllowed)
+				return
+			}
+			body, err := io.ReadAll(io.LimitReader(r.Body, peerBodyLimit))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := verifyPeerRequest(cfg.token(), r.Method, r.URL.Path, r.Header, body, time.Now()); err != nil {
+				logErrorf("peer: rejected %s from %s: %v", path, r.RemoteAddr, err)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if err := fn(body); err != nil {
+				logErrorf("peer: %s: %v", path, err)
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]string{"name": cfg.name()})
+		})
+	}
+	handle("/notify", func(body []byte) error {
+		var msg peerMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return err
+		}
+		return showPeerNotification(msg)
+	})
+	handle("/reply", func(body []byte) error {
+		var reply approvalReply
+		if err := json.Unmarshal(body, &reply); err != nil {
+			return err
+		}
+		return answerApprovalReply(reply, "from peer", time.Now())
+	})
+	handle("/withdraw", func(body []byte) error {
+		var w peerWithdrawal
+		if err := json.Unmarshal(body, &w); err != nil {
+			return err
+		}
+		withdrawLocalGroups([]string{peerGroup(w.Thread)})
+		return nil
+	})
+	handle("/ping", func([]byte) error { return nil })
+	return mux
+}
+
+func peerGroup(thread string) string {
+	return notificationGroup("peer", thread)
+}
+
+// showPeerNotification shows an event from the other machine, unless it is
+// muted here or a rule keeps it off the desktop. Only its approvals get
+// buttons, which answer over there; a click does nothing here, where the
+// session is not.
+func showPeerNotification(msg peerMessage) error {
+	if msg.Payload == nil {
+		return errors.New("message has no payload")
+	}
+	now := time.Now()
+	if state, err := loadState(); err == nil && (isProjectMuted(state, payloadCwd(msg.Payload), now) || isGloballyMuted(state, now)) {
+		return nil
+	}
+	if desktop, _ := routesToDesktop(msg.Payload, now); !desktop {
+		return nil
+	}
+	title, message := renderPayloadMessage(msg.Payload)
+	thread := payloadThreadID(msg.Payload)
+	req := notificationRequest{
+		Title:   msg.From + " · " + title,
+		Message: renderMessage(message, formatPlain),
+		Group:   peerGroup(thread),
+	}
+	if project, ok := projectIdentityForCwd(payloadCwd(msg.Payload)); ok {
+		req.Message = project.prefix(req.Message)
+		req.AccentColor = project.Color
+	}
+	if payloadEventName(msg.Payload) == "approval-requested" && msg.Nonce != "" && thread != "" {
+		req.ExtraChoices = []approvalChoice{
+			{Label: "Approve", Command: buildPeerReplyCommand("approve", thread, msg.Nonce)},
+			{Label: "Reject", Command: buildPeerReplyCommand("reject", thread, msg.Nonce)},
+		}
+	}
+	return sendNotification(req)
+}
+
+func buildPeerReplyCommand(action, thread, nonce string) string {
+	executable := appName
+	if path, err := os.Executable(); err == nil && strings.TrimSpace(path) != "" {
+		executable = path
+	}
+
+	parts := []string{
+		shellQuote(executable),
+		"peer",
+		"reply",
+		shellQuote(action),
+		"--thread-id",
+		shellQuote(thread),
+		"--nonce",
+		shellQuote(nonce),
+		"--quiet",
+	}
+	return strings.Join(parts, " ")
+}
+
+// withdrawPeerApproval closes the other machine's copy of the thread's
+// approval.
+func withdrawPeerApproval(cfg peerConfig, thread string) error {
+	return postPeer(cfg, "/withdraw", peerWithdrawal{Thread: thread})
+}
+
+// startPeerListener serves the other machine until the returned function
+// is called.
+func startPeerListener(cfg peerConfig) (func(), error) {
+	ln, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return nil, err
+	}
+	handler := peerHandler(cfg)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inDaemonWork(func() { handler.ServeHTTP(w, r) })
+	}), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logErrorf("peer: %v", err)
+		}
+	}()
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}, nil
+}
+
+// runPeer is `peer pair`, `peer status`, and `peer reply`, which the
+// buttons on a mirrored approval run.
+func runPeer(args []string) error {
+	if len(args) == 0 {
+		return usageError(errors.New("peer requires one of: pair, status, reply"))
+	}
+	command, args := args[0], args[1:]
+	fs := flag.NewFlagSet("peer "+command, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	token := fs.String("token", "", "the token the other machine printed (peer pair)")
+	threadID := fs.String("thread-id", "", "the approval's thread (peer reply)")
+	nonce := fs.String("nonce", "", "the approval's nonce (peer reply)")
+	outFlags := addOutputFlags(fs)
+	// The reply action may come before or after the flags.
+	var positional []string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		positional, args = args[:1], args[1:]
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	positional = append(positional, fs.Args()...)
+	out := outFlags.output()
+
+	switch command {
+	case "pair":
+		if len(positional) > 0 {
+			return usageError(errors.New("peer pair takes no arguments"))
+		}
+		return pairPeer(*token, out)
+	case "status":
+		if len(positional) > 0 {
+			return usageError(errors.New("peer status takes no arguments"))
+		}
+		cfg, err := loadUserConfig()
+		if err != nil {
+			return configError(err)
+		}
+		if !cfg.Peer.enabled() {
+			return configError(errors.New("no peer configured: set [peer] url and token"))
+		}
+		if err := postPeer(cfg.Peer, "/ping", struct{}{}); err != nil {
+			return backendError(fmt.Errorf("peer %s: %w", cfg.Peer.URL, err))
+		}
+		out.Printf("peer %s reachable; the token matches\n", cfg.Peer.URL)
+		return out.Result(commandResult{Command: "peer", Status: "ok", Path: cfg.Peer.URL})
+	case "reply":
+		if len(positional) != 1 || positional[0] != "approve" && positional[0] != "reject" {
+			return usageError(errors.New("peer reply requires approve or reject"))
+		}
+		if *threadID == "" || *nonce == "" {
+			return usageError(errors.New("peer reply requires --thread-id and --nonce"))
+		}
+		cfg, err := loadUserConfig()
+		if err != nil {
+			return configError(err)
+		}
+		if !cfg.Peer.enabled() {
+			return configError(errors.New("no peer configured: set [peer] url and token"))
+		}
+		if err := postPeer(cfg.Peer, "/reply", approvalReply{Action: positional[0], Thread: *threadID, Nonce: *nonce}); err != nil {
+			return backendError(fmt.Errorf("peer %s: %w", cfg.Peer.URL, err))
+		}
+		withdrawLocalGroups([]string{peerGroup(*threadID)})
+		out.Printf("sent %s for thread %s to %s\n", positional[0], *threadID, cfg.Peer.URL)
+		return out.Result(commandResult{Command: "peer", Status: "sent", Action: positional[0], Thread: *threadID})
+	default:
+		return usageError(fmt.Errorf("unknown peer command: %s", command))
+	}
+}
+
+// pairPeer stores the shared token, generating one when none is given, and
+// prints what the other machine needs.
+func pairPeer(token string, out commandOutput) error {
+	generated := token == ""
+	if generated {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		token = hex.EncodeToString(buf)
+	}
+	store, err := defaultSecretStore()
+	if err == nil {
+		err = store.Set(peerTokenSecret, token)
+	}
+	if err != nil {
+		return fmt.Errorf("store the peer token: %w (set %s instead)", err, peerTokenEnv)
+	}
+	out.Printf("stored the peer token as %s%s\n", secretRefPrefix, peerTokenSecret)
+	if generated {
+		out.Printf("\non the other machine, run:\n  %s peer pair --token %s\n", appName, token)
+	}
+	out.Printf("\nthen on both, add to config.toml with the other machine's address:\n  [peer]\n  url = \"https://other-mac.example.ts.net\"\n  listen = \"127.0.0.1:7788\"\n  token = \"%s%s\"\n\nThe listener speaks plain HTTP, so point url at an https tunnel to the\nother machine's listen port (such as `tailscale serve 7788`), or use\nhttp://other-mac.local:7788 with listen = \":7788\" only on a network you trust.\n\nthen run `%s features enable peer` and restart the daemon.\n", secretRefPrefix, peerTokenSecret, appName)
+	return out.Result(commandResult{Command: "peer", Status: "paired", Source: secretRefPrefix + peerTokenSecret})
+}
+
+func addPeerDoctorCheck(report *doctorReport, cfg peerConfig) {
+	if cfg.URL == "" && cfg.Listen == "" {
+		return
+	}
+	if cfg.token() == "" {
+		report.add(doctorSinks, checkWarn, "peer", "[peer] has no token; run `"+appName+" peer pair`", false)
+		return
+	}
+	if !featureEnabled("peer") {
+		report.add(doctorSinks, checkWarn, "peer", "configured but off (enable it with `"+appName+" features enable peer`)", false)
+		return
+	}
+	events := cfg.Events
+	if events == nil {
+		events = defaultPeerEvents
+	}
+	var parts []string
+	if cfg.URL != "" {
+		parts = append(parts, fmt.Sprintf("mirrors %s to %s", strings.Join(events, ", "), cfg.URL))
+	}
+	if cfg.Listen != "" {
+		parts = append(parts, "listens on "+cfg.Listen+" (needs the daemon)")
+	}
+	report.add(doctorSinks, checkOK, "peer", strings.Join(parts, "; "), false)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVerifyPeerRequest(t *testing.T) {
+	now := time.Now()
+	body := `{"thread_id":"t1"}`
+	header := func(token string, at time.Time) http.Header {
+		timestamp, signature := signPeerRequest(token, http.MethodPost, "/notify", body, at)
+		h := http.Header{}
+		h.Set(webhookTimestampHeader, timestamp)
+		h.Set(webhookSignatureHeader, signature)
+		return h
+	}
+	if err := verifyPeerRequest("shared", http.MethodPost, "/notify", header("shared", now), []byte(body), now); err != nil {
+		t.Fatalf("good request rejected: %v", err)
+	}
+	for name, h := range map[string]http.Header{
+		"wrong token": header("other", now),
+		"replayed":    header("shared", now.Add(-10*time.Minute)),
+		"unsigned":    {},
+	} {
+		if err := verifyPeerRequest("shared", http.MethodPost, "/notify", h, []byte(body), now); err == nil {
+			t.Errorf("%s request accepted", name)
+		}
+	}
+	if err := verifyPeerRequest("shared", http.MethodPost, "/notify", header("shared", now), []byte(`{"thread_id":"t2"}`), now); err == nil {
+		t.Error("tampered body accepted")
+	}
+	if err := verifyPeerRequest("shared", http.MethodPost, "/withdraw", header("shared", now), []byte(body), now); err == nil {
+		t.Error("a /notify body replayed to /withdraw accepted")
+	}
+}
+
+func TestPeerMirrorsApprovals(t *testing.T) {
+	_, dir := useTempHome(t)
+	t.Setenv(peerTokenEnv, "")
+
+	server := httptest.NewServer(peerHandler(peerConfig{Name: "laptop", Token: "shared"}))
+	defer server.Close()
+	cfg := peerConfig{URL: server.URL, Name: "desktop", Token: "shared"}
+
+	payload := map[string]any{"type": "approval-requested", "thread-id": "t1", "cwd": "/src/api"}
+	pending, _ := trackApproval(payload, time.Now())
+	msg := buildPeerMessage(cfg, payload)
+	if msg.Nonce != pending.Nonce || msg.From != "desktop" {
+		t.Fatalf("peer message = %+v, want the pending nonce from desktop", msg)
+	}
+	if err := postPeer(cfg, "/notify", msg); err != nil {
+		t.Fatalf("postPeer(/notify): %v", err)
+	}
+	captured := readCaptured(t, dir)
+	if len(captured) != 1 {
+		t.Fatalf("captured %d notifications, want one", len(captured))
+	}
+	n := captured[0]
+	if !strings.HasPrefix(n.Title, "desktop · ") || n.Group != peerGroup("t1") || n.ExecuteOnClick != "" {
+		t.Fatalf("mirrored notification = %+v", n)
+	}
+	if len(n.Choices) != 2 || n.Choices[0].Command != buildPeerReplyCommand("approve", "t1", pending.Nonce) {
+		t.Fatalf("choices = %+v, want Approve and Reject replying with the nonce", n.Choices)
+	}
+
+	// A reply with a spent or wrong nonce is refused by the listener.
+	if err := postPeer(cfg, "/reply", approvalReply{Action: "approve", Thread: "t1", Nonce: "0000"}); err == nil {
+		t.Fatal("reply with the wrong nonce accepted")
+	}
+	if err := postPeer(cfg, "/ping", struct{}{}); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if err := postPeer(peerConfig{URL: server.URL, Token: "other"}, "/ping", struct{}{}); err == nil {
+		t.Fatal("ping with the wrong token accepted")
+	}
+}
+
+func TestPeerConfig(t *testing.T) {
+	var cfg peerConfig
+	for key, value := range map[string]any{"url": "http://laptop.local:7788/", "listen": ":7788", "events": []any{"all"}} {
+		if err := cfg.set(key, value); err != nil {
+			t.Fatalf("set(%s): %v", key, err)
+		}
+	}
+	if cfg.URL != "http://laptop.local:7788" || !cfg.wants("agent-turn-complete") {
+		t.Fatalf("cfg = %+v", cfg)
+	}
+	if err := cfg.set("listen", "7788"); err == nil {
+		t.Error("listen without a colon accepted")
+	}
+	if err := cfg.set("url", "laptop:7788"); err == nil {
+		t.Error("url without a scheme accepted")
+	}
+}
+
+func TestPeerNotificationsHonourMutesAndRules(t *testing.T) {
+	home, dir := useTempHome(t)
+	path := filepath.Join(home, "config.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", path)
+	if err := os.WriteFile(path, []byte("[rules.quiet]\nevents = [\"agent-turn-complete\"]\nsinks = []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateState(func(s *notifyState) {
+		s.ProjectMutes = map[string]int64{normalizeProjectPath("/src/docs"): time.Now().Add(time.Hour).Unix()}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, payload := range []map[string]any{
+		{"type": "agent-error", "thread-id": "t1", "cwd": "/src/docs"},
+		{"type": "agent-turn-complete", "thread-id": "t2", "cwd": "/src/api"},
+		{"type": "agent-error", "thread-id": "t3", "cwd": "/src/api"},
+	} {
+		if err := showPeerNotification(peerMessage{From: "laptop", Payload: payload}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if captured := readCaptured(t, dir); len(captured) != 1 || captured[0].Group != peerGroup("t3") {
+		t.Fatalf("captured = %+v, want only t3's error", captured)
+	}
+}
+
+func TestPeerWithdrawalIsQueuedWhenThePeerIsDown(t *testing.T) {
+	home, _ := useTempHome(t)
+	// Remote sinks stay quiet in capture mode.
+	t.Setenv(backendEnv, "")
+	t.Setenv(peerTokenEnv, "")
+	t.Setenv(featuresEnv, "peer=on")
+	var up atomic.Bool
+	withdrawn := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		withdrawn <- r.URL.Path
+	}))
+	defer server.Close()
+	path := filepath.Join(home, "config.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", path)
+	if err := os.WriteFile(path, []byte("[peer]\nurl = \""+server.URL+"\"\ntoken = \"shared\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	withdrawRemoteApproval("t1")()
+	state, _ := loadState()
+	if len(state.SinkQueue) != 1 || state.SinkQueue[0].Sink != "peer" || payloadEventName(state.SinkQueue[0].Payload) != approvalWithdrawnEvent {
+		t.Fatalf("queue = %+v, want the peer withdrawal", state.SinkQueue)
+	}
+
+	up.Store(true)
+	cfg, err := loadUserConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := drainSinkQueue(cfg, time.Now().Add(time.Hour)); n != 1 {
+		t.Fatalf("drainSinkQueue() delivered %d, want the withdrawal", n)
+	}
+	if got := <-withdrawn; got != "/withdraw" {
+		t.Fatalf("retry posted to %s", got)
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// openTurnRetention is how long a turn that never reported its end is kept;
+// a machine off for longer than that has nothing worth offering back.
+const openTurnRetention = 7 * 24 * time.Hour
+
+// openTurn is a thread whose last hook event did not end its turn: Codex
+// was still working, or waiting for an approval, when it was last heard
+// from. Since is the first event of the turn, Updated the latest.
+type openTurn struct {
+	Cwd              string `json:"cwd,omitempty"`
+	Event            string `json:"event"`
+	Since            int64  `json:"since"`
+	Updated          int64  `json:"updated"`
+	AwaitingApproval bool   `json:"awaiting_approval,omitempty"`
+}
+
+// bootTimePattern finds the seconds in sysctl's kern.boottime, which
+// looks like "{ sec = 1712345678, usec = 123456 } Fri Apr  5 ...".
+var bootTimePattern = regexp.MustCompile(`sec = (\d+)`)
+
+// bootTime is when the machine last started, zero when it cannot be told.
+// Tests move it to put a turn before or after a restart.
+var bootTime = func() time.Time {
+	switch hostOS {
+	case "darwin":
+		sysctl, ok := lookupCmd("sysctl")
+		if !ok {
+			return time.Time{}
+		}
+		raw, err := outputLogged(exec.Command(sysctl, "-n", "kern.boottime"))
+		if err != nil {
+			return time.Time{}
+		}
+		if m := bootTimePattern.FindSubmatch(raw); m != nil {
+			if sec, err := strconv.ParseInt(string(m[1]), 10, 64); err == nil {
+				return time.Unix(sec, 0)
+			}
+		}
+	case "linux":
+		content, err := os.ReadFile("/proc/stat")
+		if err != nil {
+			return time.Time{}
+		}
+		for _, line := range splitLines(content) {
+			if rest, ok := strings.CutPrefix(line, "btime "); ok {
+				if sec, err := strconv.ParseInt(strings.TrimSpace(rest), 10, 64); err == nil {
+					return time.Unix(sec, 0)
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// trackTurn keeps the thread's open turn up to date with a hook event: a
+// turn-ending event closes it, anything else opens or extends it.
+func trackTurn(payload map[string]any, now time.Time) {
+	thread := payloadThreadID(payload)
+	if thread == "" {
+		return
+	}
+	event := payloadEventName(payload)
+	_ = updateState(func(s *notifyState) {
+		pruneOpenTurns(s, now)
+		if turnEnded(event) {
+			delete(s.OpenTurns, thread)
+			return
+		}
+		if s.OpenTurns == nil {
+			s.OpenTurns = map[string]openTurn{}
+		}
+		turn, ok := s.OpenTurns[thread]
+		if !ok {
+			turn.Since = now.Unix()
+		}
+		turn.Cwd, turn.Event, turn.Updated = payloadCwd(payload), event, now.Unix()
+		turn.AwaitingApproval = event == "approval-requested"
+		s.OpenTurns[thread] = turn
+	})
+}
+
+// forgetOpenTurn drops the thread's open turn, for a Codex process that is
+// known to be gone.
+func forgetOpenTurn(thread string) {
+	if thread == "" {
+		return
+	}
+	_ = updateState(func(s *notifyState) { delete(s.OpenTurns, thread) })
+}
+
+func pruneOpenTurns(s *notifyState, now time.Time) {
+	for thread, turn := range s.OpenTurns {
+		if now.Sub(time.Unix(turn.Updated, 0)) >= openTurnRetention {
+			delete(s.OpenTurns, thread)
+		}
+	}
+}
+
+// pendingSession is one entry of `codex-notify pending`. Stale ones were
+// last heard from before the machine restarted, so no Codex process is
+// left to finish them.
+type pendingSession struct {
+	Thread           string     `json:"thread_id"`
+	Cwd              string     `json:"cwd,omitempty"`
+	Event            string     `json:"event"`
+	Since            time.Time  `json:"since"`
+	Updated          time.Time  `json:"updated"`
+	AwaitingApproval bool       `json:"awaiting_approval"`
+	SnoozedUntil     *time.Time `json:"snoozed_until,omitempty"`
+	Stale            bool       `json:"stale"`
+}
+
+type pendingResult struct {
+	commandResult
+	Boot     *time.Time       `json:"boot,omitempty"`
+	Sessions []pendingSession `json:"sessions"`
+}
+
+// pendingSessions lists the open turns, newest first.
+func pendingSessions(state notifyState, boot, now time.Time) []pendingSession {
+	sessions := []pendingSession{}
+	for thread, turn := range state.OpenTurns {
+		if now.Sub(time.Unix(turn.Updated, 0)) >= openTurnRetention {
+			continue
+		}
+		s := pendingSession{
+			Thread:           thread,
+			Cwd:              turn.Cwd,
+			Event:            turn.Event,
+			Since:            time.Unix(turn.Since, 0),
+			Updated:          time.Unix(turn.Updated, 0),
+			AwaitingApproval: turn.AwaitingApproval,
+			Stale:            !boot.IsZero() && turn.Updated < boot.Unix(),
+		}
+		if p, ok := state.PendingApprovals[thread]; ok && p.SnoozedUntil > now.Unix() {
+			until := time.Unix(p.SnoozedUntil, 0)
+			s.SnoozedUntil = &until
+		}
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		a, b := sessions[i], sessions[j]
+		if !a.Updated.Equal(b.Updated) {
+			return a.Updated.After(b.Updated)
+		}
+		return a.Thread < b.Thread
+	})
+	return sessions
+}
+
+func staleSessions(sessions []pendingSession) []pendingSession {
+	var stale []pendingSession
+	for _, s := range sessions {
+		if s.Stale {
+			stale = append(stale, s)
+		}
+	}
+	return stale
+}
+
+func formatPending(sessions []pendingSession, style timeStyle, now time.Time) string {
+	if len(sessions) == 0 {
+		return "no open sessions\n"
+	}
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "THREAD\tPROJECT\tSTATE\tLAST EVENT")
+	for _, s := range sessions {
+		project := "-"
+		if s.Cwd != "" {
+			project = filepath.Base(s.Cwd)
+		}
+		state := "running"
+		if s.AwaitingApproval {
+			state = "waiting for approval"
+		}
+		if s.SnoozedUntil != nil {
+			state += " (snoozed)"
+		}
+		if s.Stale {
+			state += " (stale)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s %s\n", s.Thread, project, state, formatEventTime(s.Updated.Format(time.RFC3339), style, now), s.Event)
+	}
+	_ = tw.Flush()
+	if stale := len(staleSessions(sessions)); stale > 0 {
+		fmt.Fprintf(&b, "\n%d from before the last restart; `%s pending clear --stale` drops them\n", stale, appName)
+	}
+	return b.String()
+}
+
+// runPending lists the sessions that were working or waiting when last
+// heard from, and `pending clear` and `pending recheck` deal with them.
+func runPending(args []string) error {
+	command := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("pending "+command, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	staleOnly := fs.Bool("stale", false, "only sessions from before the last restart")
+	threadID := fs.String("thread-id", "", "only this thread (pending clear)")
+	utc := fs.Bool("utc", false, "print full UTC timestamps")
+	relative := fs.Bool("relative", false, "print times relative to now, e.g. 3m ago")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+	if *threadID != "" && command != "clear" {
+		return usageError(errors.New("--thread-id only applies to pending clear"))
+	}
+	style := timeLocal
+	switch {
+	case *utc && *relative:
+		return usageError(errors.New("--utc and --relative cannot be combined"))
+	case *utc:
+		style = timeUTC
+	case *relative:
+		style = timeRelative
+	}
+	out := outFlags.output()
+
+	now := time.Now()
+	boot := bootTime()
+	state, err := loadState()
+	if err != nil {
+		return err
+	}
+	sessions := pendingSessions(state, boot, now)
+	if *staleOnly || command == "recheck" {
+		sessions = staleSessions(sessions)
+	}
+	if *threadID != "" {
+		var matched []pendingSession
+		for _, s := range sessions {
+			if s.Thread == *threadID {
+				matched = append(matched, s)
+			}
+		}
+		sessions = matched
+	}
+	if sessions == nil {
+		sessions = []pendingSession{}
+	}
+	result := pendingResult{commandResult: commandResult{Command: "pending", Status: "ok", Thread: *threadID, Count: len(sessions)}, Sessions: sessions}
+	if !boot.IsZero() {
+		result.Boot = &boot
+	}
+
+	switch command {
+	case "list":
+		out.Printf("%s", formatPending(sessions, style, now))
+	case "clear":
+		if err := clearSessions(sessions); err != nil {
+			return err
+		}
+		result.Status = "cleared"
+		out.Printf("cleared %d sessions\n", len(sessions))
+	case "recheck":
+		for _, s := range sessions {
+			recheckSession(s, now)
+		}
+		result.Status = "rechecked"
+		out.Printf("showed %d sessions from before the restart again\n", len(sessions))
+	default:
+		return usageError(fmt.Errorf("unknown pending command: %s", command))
+	}
+	return out.Result(result)
+}
+
+// clearSessions forgets the sessions' open turns and any approval they
+// were waiting on, so nothing offers to answer them anymore.
+func clearSessions(sessions []pendingSession) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+	return updateState(func(s *notifyState) {
+		for _, session := range sessions {
+			delete(s.OpenTurns, session.Thread)
+			delete(s.PendingApprovals, session.Thread)
+		}
+	})
+}
+
+// recheckSession shows a stale session again; its click opens the terminal
+// so the session can be looked at, and Codex resumed there.
+func recheckSession(s pendingSession, now time.Time) {
+	message := fmt.Sprintf("was running when the machine restarted (last %s, %s)", s.Event, relativeTime(s.Updated, now))
+	if s.AwaitingApproval {
+		message = fmt.Sprintf("was waiting for approval when the machine restarted (%s)", relativeTime(s.Updated, now))
+	}
+	notifyLifecycle(notificationGroup("pending", s.Thread), s.Cwd, "Codex: Session Still Open?", message)
+}
+
+// offerStaleSessions is run once when the daemon starts: after a restart it
+// shows one notification for the sessions left open before it, with
+// buttons to show each again or clear them all.
+func offerStaleSessions(now time.Time) {
+	boot := bootTime()
+	if boot.IsZero() {
+		return
+	}
+	state, err := loadState()
+	if err != nil || state.StaleSessionsOffered >= boot.Unix() {
+		return
+	}
+	stale := staleSessions(pendingSessions(state, boot, now))
+	_ = updateState(func(s *notifyState) { s.StaleSessionsOffered = boot.Unix() })
+	if len(stale) == 0 {
+		return
+	}
+	var projects []string
+	for _, s := range stale {
+		if s.Cwd != "" && !containsString(projects, filepath.Base(s.Cwd)) {
+			projects = append(projects, filepath.Base(s.Cwd))
+		}
+	}
+	message := fmt.Sprintf("%d Codex sessions were still open before the restart", len(stale))
+	if len(stale) == 1 {
+		message = "1 Codex session was still open before the restart"
+	}
+	if len(projects) > 0 {
+		message += " (" + strings.Join(projects, ", ") + ")"
+	}
+	req := notificationRequest{
+		Title:          "Codex: Sessions From Before Restart",
+		Message:        message,
+		Group:          notificationGroup("pending", "stale"),
+		ExecuteOnClick: buildActionCommand("open", ""),
+		ExtraChoices: []approvalChoice{
+			{Label: "Re-check", Command: buildPendingCommand("recheck")},
+			{Label: "Clear all", Command: buildPendingCommand("clear", "--stale")},
+		},
+	}
+	if err := sendNotification(req); err != nil {
+		logErrorf("%v", err)
+	}
+}
+
+func buildPendingCommand(command string, flags ...string) string {
+	executable := appName
+	if path, err := os.Executable(); err == nil && strings.TrimSpace(path) != "" {
+		executable = path
+	}
+
+	parts := []string{shellQuote(executable), "pending", command}
+	parts = append(parts, flags...)
+	return strings.Join(append(parts, "--quiet"), " ")
+}
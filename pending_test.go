@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPendingSessionsAcrossRestart(t *testing.T) {
+	useTempHome(t)
+	now := time.Now()
+
+	trackTurn(map[string]any{"type": "approval-requested", "thread-id": "old", "cwd": "/src/api"}, now.Add(-3*time.Hour))
+	trackTurn(map[string]any{"type": "approval-requested", "thread-id": "new", "cwd": "/src/web"}, now.Add(-time.Minute))
+	trackTurn(map[string]any{"type": "approval-requested", "thread-id": "done"}, now.Add(-2*time.Minute))
+	trackTurn(map[string]any{"type": "agent-turn-complete", "thread-id": "done"}, now.Add(-time.Minute))
+
+	boot := now.Add(-time.Hour)
+	stub(t, &bootTime, func() time.Time { return boot })
+
+	state, err := loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := pendingSessions(state, boot, now)
+	if len(sessions) != 2 || sessions[0].Thread != "new" || sessions[0].Stale || sessions[1].Thread != "old" || !sessions[1].Stale {
+		t.Fatalf("sessions = %+v, want new then stale old", sessions)
+	}
+	if !sessions[0].AwaitingApproval {
+		t.Fatalf("new = %+v, want awaiting approval", sessions[0])
+	}
+	text := formatPending(sessions, timeLocal, now)
+	if !strings.Contains(text, "waiting for approval (stale)") || !strings.Contains(text, "1 from before the last restart") {
+		t.Fatalf("formatPending() = %q", text)
+	}
+
+	if err := runPending([]string{"clear", "--stale", "--quiet"}); err != nil {
+		t.Fatalf("pending clear --stale: %v", err)
+	}
+	state, _ = loadState()
+	if _, ok := state.OpenTurns["old"]; ok {
+		t.Fatal("stale session survived pending clear --stale")
+	}
+	if _, ok := state.OpenTurns["new"]; !ok {
+		t.Fatal("pending clear --stale dropped a current session")
+	}
+
+	// Answering the approval leaves the turn open but no longer waiting.
+	trackApproval(map[string]any{"type": "approval-requested", "thread-id": "new"}, now)
+	claimApproval("new", "", now)
+	state, _ = loadState()
+	if turn := state.OpenTurns["new"]; turn.AwaitingApproval {
+		t.Fatalf("turn after answer = %+v, want not awaiting approval", turn)
+	}
+}
+
+func TestOfferStaleSessionsOncePerBoot(t *testing.T) {
+	_, dir := useTempHome(t)
+	now := time.Now()
+	trackTurn(map[string]any{"type": "approval-requested", "thread-id": "t1", "cwd": "/src/api"}, now.Add(-2*time.Hour))
+
+	stub(t, &bootTime, func() time.Time { return now.Add(-time.Hour) })
+
+	offerStaleSessions(now)
+	offerStaleSessions(now)
+	captured := readCaptured(t, dir)
+	if len(captured) != 1 {
+		t.Fatalf("captured %d notifications, want one", len(captured))
+	}
+	n := captured[0]
+	if !strings.Contains(n.Message, "1 Codex session was still open before the restart (api)") {
+		t.Fatalf("message = %q", n.Message)
+	}
+	if len(n.Choices) != 2 || !strings.Contains(n.Choices[1].Command, "pending clear --stale") {
+		t.Fatalf("choices = %+v, want Re-check and Clear all", n.Choices)
+	}
+}
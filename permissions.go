@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	permissionAccessibility = "Accessibility"
+	permissionAutomation    = "Automation"
+
+	// permissionRenotifyAfter keeps a burst of failing clicks to one
+	// notification per permission.
+	permissionRenotifyAfter = 10 * time.Minute
+)
+
+// permissionSettingsURLs open the matching Privacy & Security pane.
+var permissionSettingsURLs = map[string]string{
+	permissionAccessibility: "x-apple.systempreferences:com.apple.preference.security?Privacy_Accessibility",
+	permissionAutomation:    "x-apple.systempreferences:com.apple.preference.security?Privacy_Automation",
+}
+
+// applePermissionDenied is an osascript failure caused by a missing
+// privacy grant. Permission names the System Settings list to fix.
+type applePermissionDenied struct {
+	Permission string
+	Err        error
+}
+
+func (e *applePermissionDenied) Error() string { return e.Err.Error() }
+func (e *applePermissionDenied) Unwrap() error { return e.Err }
+
+// applePermissionFor tells the two grants apart: -1743 is Automation
+// (sending Apple Events to System Events), the others are Accessibility
+// (posting keystrokes).
+func applePermissionFor(output string) string {
+	out := strings.ToLower(output)
+	if strings.Contains(out, "(-1743)") || strings.Contains(out, "not authorized to send apple events") {
+		return permissionAutomation
+	}
+	return permissionAccessibility
+}
+
+// applePermissionError classifies an osascript failure whose output shows
+// a privacy denial.
+func applePermissionError(err error, output string) error {
+	return permissionError(&applePermissionDenied{Permission: applePermissionFor(output), Err: err})
+}
+
+// permissionProblem is the last action that failed on a revoked grant. It
+// stays in state until an action succeeds, so doctor keeps pointing at it.
+type permissionProblem struct {
+	Permission string `json:"permission"`
+	Action     string `json:"action"`
+	Time       int64  `json:"time"`
+	Notified   int64  `json:"notified,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// reportPermissionFailure records a permission denial and raises one
+// notification that opens the right Settings pane. Actions run from
+// notification clicks, where an error on stderr is never seen.
+func reportPermissionFailure(action string, err error) {
+	var denied *applePermissionDenied
+	if !errors.As(err, &denied) {
+		return
+	}
+	now := time.Now()
+	notify := true
+	_ = updateState(func(s *notifyState) {
+		prev := s.PermissionProblem
+		if prev != nil && prev.Permission == denied.Permission && now.Sub(time.Unix(prev.Notified, 0)) < permissionRenotifyAfter {
+			notify = false
+		}
+		p := &permissionProblem{Permission: denied.Permission, Action: action, Time: now.Unix(), Detail: denied.Err.Error()}
+		if notify {
+			p.Notified = now.Unix()
+		} else {
+			p.Notified = prev.Notified
+		}
+		s.PermissionProblem = p
+	})
+	if notify {
+		_ = sendNotification(permissionNotification(denied.Permission, action))
+	}
+}
+
+func permissionNotification(permission, action string) notificationRequest {
+	return notificationRequest{
+		Title:             fmt.Sprintf("Codex Notify: %s permission needed", permission),
+		Message:           fmt.Sprintf("%q could not reach the terminal because macOS denied %s access. Click to open System Settings and turn it back on for your terminal app.", action, permission),
+		Group:             "codex-notify-permission",
+		ExecuteOnClick:    "open " + shellQuote(permissionSettingsURLs[permission]),
+		PopupPrimaryLabel: "Open Settings",
+	}
+}
+
+// clearPermissionProblem forgets a recorded denial once an action works
+// again.
+func clearPermissionProblem() {
+	if state, err := loadState(); err != nil || state.PermissionProblem == nil {
+		return
+	}
+	_ = updateState(func(s *notifyState) { s.PermissionProblem = nil })
+}
+
+func addPermissionDoctorCheck(report *doctorReport) {
+	state, err := loadState()
+	if err != nil || state.PermissionProblem == nil {
+		return
+	}
+	p := state.PermissionProblem
+	report.add(doctorPermissions, checkFail, "permission "+strings.ToLower(p.Permission), fmt.Sprintf(
+		"%s was denied when %q ran at %s; re-grant it in System Settings > Privacy & Security > %s (open %s)",
+		p.Permission, p.Action, time.Unix(p.Time, 0).Local().Format("2006-01-02 15:04"), p.Permission, permissionSettingsURLs[p.Permission],
+	), true)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestApplePermissionFor(t *testing.T) {
+	tests := map[string]string{
+		"execution error: Not authorized to send Apple events to System Events. (-1743)":   permissionAutomation,
+		"System Events got an error: osascript is not allowed to send keystrokes. (-1719)": permissionAccessibility,
+		"osascript is not allowed assistive access. (-25211)":                              permissionAccessibility,
+	}
+	for output, want := range tests {
+		if got := applePermissionFor(output); got != want {
+			t.Errorf("applePermissionFor(%q) = %q, want %q", output, got, want)
+		}
+	}
+	err := applePermissionError(errors.New("exit status 1"), "(-1743)")
+	if exitCodeFor(err) != exitPermission {
+		t.Fatalf("exitCodeFor() = %d, want %d", exitCodeFor(err), exitPermission)
+	}
+}
+
+func TestReportPermissionFailure(t *testing.T) {
+	_, dir := useTempHome(t)
+
+	// Unrelated failures are left alone.
+	reportPermissionFailure("approve", errors.New("no terminal"))
+	if state, _ := loadState(); state.PermissionProblem != nil {
+		t.Fatalf("recorded %+v for a non-permission error", state.PermissionProblem)
+	}
+
+	err := applePermissionError(errors.New("exit status 1"), "not allowed assistive access (-25211)")
+	reportPermissionFailure("approve", err)
+	reportPermissionFailure("reject", err)
+	captured := readCaptured(t, dir)
+	if len(captured) != 1 {
+		t.Fatalf("captured %d notifications, want 1 per burst", len(captured))
+	}
+	if !strings.Contains(captured[0].Title, "Accessibility") || !strings.Contains(captured[0].ExecuteOnClick, "Privacy_Accessibility") {
+		t.Fatalf("notification = %+v", captured[0])
+	}
+
+	state, _ := loadState()
+	if p := state.PermissionProblem; p == nil || p.Permission != permissionAccessibility || p.Action != "reject" {
+		t.Fatalf("PermissionProblem = %+v", p)
+	}
+	var report doctorReport
+	addPermissionDoctorCheck(&report)
+	if len(report.Checks) != 1 || report.Checks[0].Status != checkFail || report.Problems != 1 {
+		t.Fatalf("doctor = %+v", report)
+	}
+
+	clearPermissionProblem()
+	if state, _ := loadState(); state.PermissionProblem != nil {
+		t.Fatal("problem not cleared after a successful action")
+	}
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPushoverURL = "https://api.pushover.net/1/messages.json"
+	pushoverTokenEnv   = "CODEX_NOTIFY_PUSHOVER_TOKEN"
+	pushoverUserEnv    = "CODEX_NOTIFY_PUSHOVER_USER"
+
+	defaultBarkServer = "https://api.day.app"
+	barkKeyEnv        = "CODEX_NOTIFY_BARK_KEY"
+
+	// pushoverEmergency repeats until acknowledged, every Retry seconds for
+	// up to Expire seconds. Pushover's limits: retry >= 30, expire <= 10800.
+	pushoverEmergency     = 2
+	defaultPushoverRetry  = 60
+	defaultPushoverExpire = 3600
+)
+
+// defaultPhoneEvents are the events that need someone at the keyboard.
+var defaultPhoneEvents = []string{"approval-requested", "agent-error"}
+
+var barkLevels = []string{"active", "timeSensitive", "passive", "critical"}
+
+// pushoverConfig is the [pushover] table of config.toml. The sink is off
+// until both the application token and the user key are set.
+type pushoverConfig struct {
+	// Token and User are credentials; CODEX_NOTIFY_PUSHOVER_TOKEN and
+	// CODEX_NOTIFY_PUSHOVER_USER override them.
+	Token  string
+	User   string
+	Device string
+	Sound  string
+	// Priority is for most events and ApprovalPriority for approvals,
+	// which default to high (1). Unset is nil so 0 can be chosen.
+	Priority         *int64
+	ApprovalPriority *int64
+	Retry, Expire    int64
+	Events           []string
+	// URL is for test servers.
+	URL string
+	// Watch sends the compact watch variant; WatchURL is the supplementary
+	// URL Pushover shows with it.
+	Watch    bool
+	WatchURL string
+}
+
+func (c pushoverConfig) token() string {
+	if v := strings.TrimSpace(getenv(pushoverTokenEnv)); v != "" {
+		return secretValue(v)
+	}
+	return secretValue(c.Token)
+}
+
+func (c pushoverConfig) user() string {
+	if v := strings.TrimSpace(getenv(pushoverUserEnv)); v != "" {
+		return secretValue(v)
+	}
+	return secretValue(c.User)
+}
+
+func (c pushoverConfig) enabled() bool {
+	return c.token() != "" && c.user() != ""
+}
+
+func (c pushoverConfig) wants(event string) bool {
+	return phoneWants(c.Events, event)
+}
+
+func (c pushoverConfig) priorityFor(event string) int64 {
+	if event == "approval-requested" {
+		if c.ApprovalPriority != nil {
+			return *c.ApprovalPriority
+		}
+		return 1
+	}
+	if c.Priority != nil {
+		return *c.Priority
+	}
+	return 0
+}
+
+func (c pushoverConfig) url() string {
+	if c.URL == "" {
+		return defaultPushoverURL
+	}
+	return c.URL
+}
+
+// set parses one `pushover.<key>` entry.
+func (c *pushoverConfig) set(key string, value any) error {
+	if ok, err := parseWatchSetting("pushover", key, value, &c.Watch, &c.WatchURL); ok {
+		return err
+	}
+	switch key {
+	case "events":
+		events, err := parseEventList("pushover.events", value)
+		if err != nil {
+			return err
+		}
+		c.Events = events
+		return nil
+	case "priority", "approval_priority", "retry", "expire":
+		n, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("pushover.%s must be an integer", key)
+		}
+		switch key {
+		case "priority", "approval_priority":
+			if n < -2 || n > 2 {
+				return fmt.Errorf("pushover.%s must be between -2 and 2", key)
+			}
+			if key == "priority" {
+				c.Priority = &n
+			} else {
+				c.ApprovalPriority = &n
+			}
+		case "retry":
+			if n < 30 {
+				return errors.New("pushover.retry must be at least 30 seconds")
+			}
+			c.Retry = n
+		case "expire":
+			if n < 1 || n > 10800 {
+				return errors.New("pushover.expire must be between 1 and 10800 seconds")
+			}
+			c.Expire = n
+		}
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("pushover.%s must be a string", key)
+	}
+	s = strings.TrimSpace(s)
+	switch key {
+	case "token":
+		c.Token = s
+	case "user":
+		c.User = s
+	case "device":
+		c.Device = s
+	case "sound":
+		c.Sound = s
+	case "url":
+		if !strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://") {
+			return errors.New("pushover.url must be an http(s) URL")
+		}
+		c.URL = s
+	default:
+		return unknownSetting("pushover." + key)
+	}
+	return nil
+}
+
+// barkConfig is the [bark] table: pushes through a Bark server to its iOS
+// app. The sink is off until a device key is set.
+type barkConfig struct {
+	Server string
+	// DeviceKey is the credential; CODEX_NOTIFY_BARK_KEY overrides it.
+	DeviceKey string
+	Sound     string
+	// Level is for most events and ApprovalLevel for approvals, which
+	// default to timeSensitive so they break through Focus.
+	Level         string
+	ApprovalLevel string
+	Events        []string
+}
+
+func (c barkConfig) deviceKey() string {
+	if v := strings.TrimSpace(getenv(barkKeyEnv)); v != "" {
+		return secretValue(v)
+	}
+	return secretValue(c.DeviceKey)
+}
+
+func (c barkConfig) enabled() bool {
+	return c.deviceKey() != ""
+}
+
+func (c barkConfig) wants(event string) bool {
+	return phoneWants(c.Events, event)
+}
+
+func (c barkConfig) server() string {
+	if c.Server == "" {
+		return defaultBarkServer
+	}
+	return strings.TrimRight(c.Server, "/")
+}
+
+func (c barkConfig) levelFor(event string) string {
+	if event == "approval-requested" {
+		if c.ApprovalLevel != "" {
+			return c.ApprovalLevel
+		}
+		return "timeSensitive"
+	}
+	if c.Level != "" {
+		return c.Level
+	}
+	return "active"
+}
+
+// set parses one `bark.<key>` entry.
+func (c *barkConfig) set(key string, value any) error {
+	if key == "events" {
+		events, err := parseEventList("bark.events", value)
+		if err != nil {
+			return err
+		}
+		c.Events = events
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("bark.%s must be a string", key)
+	}
+	s = strings.TrimSpace(s)
+	switch key {
+	case "server":
+		if !strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://") {
+			return errors.New("bark.server must be an http(s) URL")
+		}
+		c.Server = s
+	case "device_key":
+		c.DeviceKey = s
+	case "sound":
+		c.Sound = s
+	case "level", "approval_level":
+		if !containsString(barkLevels, s) {
+			return fmt.Errorf("bark.%s must be one of %s", key, strings.Join(barkLevels, ", "))
+		}
+		if key == "level" {
+			c.Level = s
+		} else {
+			c.ApprovalLevel = s
+		}
+	default:
+		return unknownSetting("bark." + key)
+	}
+	return nil
+}
+
+func phoneWants(events []string, event string) bool {
+	if events == nil {
+		events = defaultPhoneEvents
+	}
+	return containsString(events, event) || containsString(events, "all")
+}
+
+// phoneText is the title and plain-text body for a phone push, with the
+// project in front like the desktop notification.
+func phoneText(payload map[string]any) (title, message string) {
+	title, message = renderPayloadMessage(payload)
+	message = renderMessage(message, formatPlain)
+	if project, ok := projectIdentityForCwd(payloadCwd(payload)); ok {
+		message = project.prefix(message)
+	}
+	return title, message
+}
+
+// buildPushoverForm returns the form fields of a Pushover message.
+func buildPushoverForm(cfg pushoverConfig, payload map[string]any) url.Values {
+	title, message := phoneText(payload)
+	if cfg.Watch {
+		title, message = watchText(payload)
+	}
+	form := url.Values{
+		"token":   {cfg.token()},
+		"user":    {cfg.user()},
+		"title":   {title},
+		"message": {message},
+	}
+	priority := cfg.priorityFor(payloadEventName(payload))
+	if priority != 0 {
+		form.Set("priority", strconv.FormatInt(priority, 10))
+	}
+	if priority == pushoverEmergency {
+		retry, expire := cfg.Retry, cfg.Expire
+		if retry == 0 {
+			retry = defaultPushoverRetry
+		}
+		if expire == 0 {
+			expire = defaultPushoverExpire
+		}
+		form.Set("retry", strconv.FormatInt(retry, 10))
+		form.Set("expire", strconv.FormatInt(expire, 10))
+		// The tag lets an answer on the Mac stop the repeats.
+		if thread := payloadThreadID(payload); thread != "" && payloadEventName(payload) == "approval-requested" {
+			form.Set("tags", approvalTag(thread))
+		}
+	}
+	if link := expandWatchURL(cfg.WatchURL, payload); cfg.Watch && link != "" {
+		form.Set("url", link)
+	}
+	if cfg.Device != "" {
+		form.Set("device", cfg.Device)
+	}
+	if cfg.Sound != "" {
+		form.Set("sound", cfg.Sound)
+	}
+	return form
+}
+
+func publishPushover(cfg pushoverConfig, form url.Values) error {
+	return publishWebhook(sinkHTTPClient, "Pushover", pushoverRequest(cfg.url(), form))
+}
+
+func pushoverRequest(url string, form url.Values) webhookRequest {
+	return webhookRequest{Method: http.MethodPost, URL: url, ContentType: "application/x-www-form-urlencoded", Body: form.Encode()}
+}
+
+// cancelPushoverEmergency stops the retries of emergency messages tagged
+// tag, once the approval they announce was answered elsewhere.
+func cancelPushoverEmergency(cfg pushoverConfig, tag string) error {
+	base := strings.TrimSuffix(cfg.url(), "/messages.json")
+	req := pushoverRequest(base+"/receipts/cancel_by_tag/"+url.PathEscape(tag)+".json", url.Values{"token": {cfg.token()}})
+	return publishWebhook(sinkHTTPClient, "Pushover cancel", req)
+}
+
+// barkMessage is the JSON body of Bark's /push endpoint.
+type barkMessage struct {
+	DeviceKey string `json:"device_key"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Group     string `json:"group,omitempty"`
+	Level     string `json:"level,omitempty"`
+	Sound     string `json:"sound,omitempty"`
+}
+
+func buildBarkMessage(cfg barkConfig, payload map[string]any) barkMessage {
+	title, message := phoneText(payload)
+	msg := barkMessage{
+		DeviceKey: cfg.deviceKey(),
+		Title:     title,
+		Body:      message,
+		Group:     appName,
+		Level:     cfg.levelFor(payloadEventName(payload)),
+		Sound:     cfg.Sound,
+	}
+	if project, ok := projectIdentityForCwd(payloadCwd(payload)); ok {
+		msg.Group = project.Name
+	}
+	return msg
+}
+
+func publishBark(cfg barkConfig, msg barkMessage) error {
+	req, err := jsonRequest(cfg.server()+"/push", msg)
+	if err != nil {
+		return err
+	}
+	return publishWebhook(sinkHTTPClient, "Bark", req)
+}
+
+// addPhoneDoctorChecks reports the configured phone push sinks.
+func addPhoneDoctorChecks(report *doctorReport, cfg userConfig) {
+	events := func(list []string) string {
+		if list == nil {
+			list = defaultPhoneEvents
+		}
+		return strings.Join(list, ", ")
+	}
+	if cfg.Pushover.enabled() {
+		report.add(doctorSinks, checkOK, "pushover", fmt.Sprintf("approval priority %d (%s)", cfg.Pushover.priorityFor("approval-requested"), events(cfg.Pushover.Events)), false)
+	}
+	if cfg.Bark.enabled() {
+		report.add(doctorSinks, checkOK, "bark", fmt.Sprintf("%s, approval level %s (%s)", cfg.Bark.server(), cfg.Bark.levelFor("approval-requested"), events(cfg.Bark.Events)), false)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBuildPushoverForm(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "0")
+	t.Setenv(pushoverTokenEnv, "")
+	t.Setenv(pushoverUserEnv, "")
+	cfg, err := parseUserConfig([]byte("[pushover]\ntoken = \"app\"\nuser = \"me\"\napproval_priority = 2\nretry = 45\nsound = \"siren\"\n"))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+
+	form := buildPushoverForm(cfg.Pushover, map[string]any{"type": "approval-requested", "message": "Run **make deploy**?"})
+	want := url.Values{
+		"token":    {"app"},
+		"user":     {"me"},
+		"title":    {"Codex: Approval Requested"},
+		"message":  {"Run make deploy?"},
+		"priority": {"2"},
+		"retry":    {"45"},
+		"expire":   {"3600"},
+		"sound":    {"siren"},
+	}
+	if form.Encode() != want.Encode() {
+		t.Fatalf("form = %v, want %v", form, want)
+	}
+
+	// Emergency approvals are tagged so answering on the Mac can cancel them.
+	form = buildPushoverForm(cfg.Pushover, map[string]any{"type": "approval-requested", "thread-id": "t-1"})
+	if form.Get("tags") != "codex-notify-t-1" {
+		t.Fatalf("tags = %q", form.Get("tags"))
+	}
+
+	// Other events use the normal priority, which leaves the field out.
+	form = buildPushoverForm(cfg.Pushover, map[string]any{"type": "agent-error"})
+	if form.Has("priority") || form.Has("retry") {
+		t.Fatalf("agent-error form = %v", form)
+	}
+
+	for _, bad := range []string{
+		"[pushover]\napproval_priority = 3\n",
+		"[pushover]\nretry = 10\n",
+		"[pushover]\nexpire = 20000\n",
+		"[pushover]\npriority = \"high\"\n",
+	} {
+		if _, err := parseUserConfig([]byte(bad)); err == nil {
+			t.Errorf("parseUserConfig(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestPhonePushSinksPublish(t *testing.T) {
+	var gotForm url.Values
+	var gotBark barkMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/1/messages.json":
+			if err := r.ParseForm(); err != nil {
+				t.Errorf("parse form: %v", err)
+			}
+			gotForm = r.PostForm
+		case "/push":
+			if err := json.NewDecoder(r.Body).Decode(&gotBark); err != nil {
+				t.Errorf("decode body: %v", err)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv(pushoverTokenEnv, "app")
+	t.Setenv(pushoverUserEnv, "me")
+	t.Setenv(barkKeyEnv, "")
+	cfg, err := parseUserConfig([]byte("[pushover]\nurl = \"" + server.URL + "/1/messages.json\"\n[bark]\nserver = \"" + server.URL + "/\"\ndevice_key = \"dev\"\n"))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	if len(remoteSinks(cfg, map[string]any{"type": "agent-turn-complete"})) != 0 {
+		t.Fatal("phone sinks fired for a finished turn by default")
+	}
+
+	sinks := remoteSinks(cfg, map[string]any{"type": "approval-requested", "message": "Allow?"})
+	if len(sinks) != 2 || sinks[0].Name != "pushover" || sinks[1].Name != "bark" {
+		t.Fatalf("remoteSinks() = %+v", sinks)
+	}
+	for _, sink := range sinks {
+		if err := sink.Publish(); err != nil {
+			t.Fatalf("%s Publish() error = %v", sink.Name, err)
+		}
+	}
+	if gotForm.Get("token") != "app" || gotForm.Get("priority") != "1" {
+		t.Fatalf("pushover got %v", gotForm)
+	}
+	if gotBark.DeviceKey != "dev" || gotBark.Level != "timeSensitive" || gotBark.Body != "Allow?" {
+		t.Fatalf("bark got %+v", gotBark)
+	}
+}
+
+func TestWatchVariant(t *testing.T) {
+	t.Setenv(pushoverTokenEnv, "")
+	t.Setenv(pushoverUserEnv, "")
+	cfg, err := parseUserConfig([]byte("[ntfy]\ntopic = \"runs\"\nwatch = true\nwatch_url = \"https://codex.example/t/{thread}\"\n\n[pushover]\ntoken = \"app\"\nuser = \"me\"\nwatch = true\nwatch_url = \"https://codex.example/t/{thread}\"\n"))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	long := "Run the migration\n\nfor every tenant — " + strings.Repeat("é", 120)
+	payload := map[string]any{"type": "approval-requested", "thread-id": "t 1", "cwd": "/src/app", "message": long}
+
+	msg := buildNtfyMessage(cfg.Ntfy, payload)
+	if msg.Title != "app: Approval Requested" || msg.Click != "https://codex.example/t/t%201" {
+		t.Fatalf("ntfy watch message = %+v", msg)
+	}
+	if n := utf8.RuneCountInString(msg.Message); n != watchMessageLimit || !utf8.ValidString(msg.Message) || !strings.HasPrefix(msg.Message, "Run the migration for every tenant") {
+		t.Fatalf("ntfy watch message body = %q (%d characters)", msg.Message, n)
+	}
+	form := buildPushoverForm(cfg.Pushover, payload)
+	if form.Get("title") != msg.Title || form.Get("message") != msg.Message || form.Get("url") != msg.Click {
+		t.Fatalf("pushover watch form = %v", form)
+	}
+
+	if _, err := parseUserConfig([]byte("[ntfy]\ntopic = \"runs\"\nwatch = \"yes\"\n")); err == nil || !strings.Contains(err.Error(), "ntfy.watch must be true or false") {
+		t.Fatalf("parseUserConfig(watch = \"yes\") error = %v", err)
+	}
+}
@@ -0,0 +1,527 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type popupSettings struct {
+	PopupTimeoutSeconds int `json:"popup_timeout_seconds,omitempty"`
+}
+
+func approvalActionsEnabled() bool {
+	if !keystrokesSupported() {
+		return false
+	}
+	return currentSettings().ApprovalActions
+}
+
+func approvalUIStyle() string {
+	return currentSettings().ApprovalUI
+}
+
+func notificationUIStyle() string {
+	return currentSettings().NotificationUI
+}
+
+func shouldUseNativeApprovalNotification(payload map[string]any) bool {
+	if notificationUIStyle() == notificationUISystem {
+		return false
+	}
+	if !featureEnabled("native_popup") {
+		return false
+	}
+	if powerSaverActive() {
+		return false
+	}
+	if payloadEventName(payload) != "approval-requested" {
+		return false
+	}
+	if !approvalActionsEnabled() {
+		return false
+	}
+	if approvalUIStyle() == approvalUIMulti {
+		return false
+	}
+	return currentSettings().PopupApprovalActions
+}
+
+// buildNativeApprovalContent returns what the approval popup shows for payload.
+func buildNativeApprovalContent(payload map[string]any) notificationRequest {
+	threadID := payloadThreadID(payload)
+	req := notificationRequest{Group: notificationGroup("approval-native", threadID)}
+	req.Title, req.Message = renderPayloadMessage(payload)
+	if project, ok := projectIdentityForCwd(payloadCwd(payload)); ok {
+		req.Message = project.prefix(req.Message)
+		req.AccentColor = project.Color
+	}
+	cfg, _ := loadUserConfig()
+	if identity, ok := cfg.identityFor(payload); ok {
+		identity.apply(&req)
+	}
+	if req.Sound == "" {
+		req.Sound = themeSoundFor(payloadEventName(payload))
+	}
+	req.ExtraChoices = approvalChoicesFromPayload(payload, threadID)
+	if len(req.ExtraChoices) == 0 {
+		req.ExtraChoices = defaultApprovalChoices(threadID)
+	}
+	now := time.Now()
+	if deadline, ok := payloadApprovalDeadline(payload, now); ok {
+		req.ExtraChoices = expiringChoices(req.ExtraChoices, threadID, deadline, now)
+	}
+	if choice, ok := snoozeChoice(payload, threadID, now); ok {
+		req.ExtraChoices = append(req.ExtraChoices, choice)
+	}
+	return req
+}
+
+func sendNativeApprovalNotification(payload map[string]any) error {
+	if benchDryRun() {
+		return nil
+	}
+	dir, capture := captureDir()
+	if capture {
+		if err := injectedFailure("capture"); err != nil {
+			return err
+		}
+		return captureApproval(dir, payload)
+	}
+	if err := injectedFailure("popup"); err != nil {
+		return err
+	}
+	return startApprovalPopup(payload, false)
+}
+
+// startApprovalPopup shows payload's approval in the popup helper; with
+// remember, as the chooser, offering to remember the answer per project.
+func startApprovalPopup(payload map[string]any, remember bool) error {
+	helperPath, err := ensureApprovalActionHelper()
+	if err != nil {
+		return err
+	}
+
+	content := buildNativeApprovalContent(payload)
+	rememberLabel := ""
+	if remember {
+		content.ExtraChoices, rememberLabel = withRememberCommands(content.ExtraChoices, payloadThreadID(payload), payloadCwd(payload))
+	}
+	lockPath, err := approvalInteractionLockPath()
+	if err != nil {
+		return err
+	}
+	timeoutSeconds := approvalActionTimeoutSeconds()
+	if deadline, ok := payloadApprovalDeadline(payload, time.Now()); ok {
+		timeoutSeconds = capTimeoutToDeadline(timeoutSeconds, deadline, time.Now())
+	}
+	if err := writeApprovalInteractionLock(lockPath, timeoutSeconds); err != nil {
+		return err
+	}
+
+	req := helperRequest{
+		Title:                     content.Title,
+		Message:                   content.Message,
+		Identifier:                content.Group,
+		Category:                  payloadEventName(payload),
+		TimeoutSeconds:            timeoutSeconds,
+		DismissOnActivateBundleID: terminalBundleID(),
+		InteractionLockFile:       lockPath,
+		AccentColor:               content.AccentColor,
+		Icon:                      content.Icon,
+		Sound:                     content.Sound,
+		Choices:                   content.ExtraChoices,
+		RememberLabel:             rememberLabel,
+	}
+	if receiptPath, err := receiptsPath(); err == nil {
+		req.ReceiptFile = receiptPath
+	}
+	withExpiryCountdown(&req, payload)
+
+	if err := startPopupHelper(helperPath, req); err != nil {
+		clearApprovalInteractionLock(lockPath)
+		return fmt.Errorf("start native approval notifier: %w", err)
+	}
+	recordDelivered(req.Identifier, "popup")
+	return nil
+}
+
+func sendNativePopupNotification(req notificationRequest, title, message, group string) error {
+	helperPath, err := ensureApprovalActionHelper()
+	if err != nil {
+		return err
+	}
+
+	helperReq := helperRequest{
+		Title:                     title,
+		Message:                   message,
+		Identifier:                group,
+		TimeoutSeconds:            popupTimeoutSeconds(),
+		DismissOnActivateBundleID: terminalBundleID(),
+		AccentColor:               req.AccentColor,
+		Icon:                      req.Icon,
+		Sound:                     req.Sound,
+		Choices:                   popupChoicesForRequest(req),
+	}
+	if receiptPath, err := receiptsPath(); err == nil {
+		helperReq.ReceiptFile = receiptPath
+	}
+
+	if err := startPopupHelper(helperPath, helperReq); err != nil {
+		return fmt.Errorf("start native popup notifier: %w", err)
+	}
+	return nil
+}
+
+// helperRequest is the JSON document the popup helper reads from stdin.
+// Keep field names in sync with HelperRequest in approval_action_notifier.swift.
+type helperRequest struct {
+	Title                     string           `json:"title"`
+	Message                   string           `json:"message"`
+	Identifier                string           `json:"identifier"`
+	Category                  string           `json:"category,omitempty"`
+	TimeoutSeconds            int              `json:"timeout_seconds"`
+	DismissOnActivateBundleID string           `json:"dismiss_on_activate_bundle_id,omitempty"`
+	InteractionLockFile       string           `json:"interaction_lock_file,omitempty"`
+	ReceiptFile               string           `json:"receipt_file,omitempty"`
+	AccentColor               string           `json:"accent_color,omitempty"`
+	WithdrawFile              string           `json:"withdraw_file,omitempty"`
+	Icon                      string           `json:"icon,omitempty"`
+	Sound                     string           `json:"sound,omitempty"`
+	Choices                   []approvalChoice `json:"choices"`
+	RememberLabel             string           `json:"remember_label,omitempty"`
+	// Env is the environment for choice commands, set when the daemon's
+	// warm helper shows the popup instead of a helper started for it.
+	Env map[string]string `json:"env,omitempty"`
+	// ExpiresAt is the approval's deadline in unix seconds. The message
+	// then ends in its expiry line, which the helper counts down with
+	// ExpiresInFormat and replaces with ExpiredText at the deadline.
+	ExpiresAt       int64  `json:"expires_at,omitempty"`
+	ExpiresInFormat string `json:"expires_in_format,omitempty"`
+	ExpiredText     string `json:"expired_text,omitempty"`
+}
+
+// startPopupHelper launches the helper without waiting for it. The request
+// goes over stdin, so no notification text or command appears in `ps` and
+// argv length or quoting limits do not apply.
+func startPopupHelper(helperPath string, req helperRequest) error {
+	// A marker left for an earlier popup with this identifier must not
+	// close the new one.
+	if path, err := withdrawMarkerPath(req.Identifier); err == nil && req.Identifier != "" {
+		_ = os.Remove(path)
+		req.WithdrawFile = path
+	}
+	if warmPopup != nil && warmPopup.show(helperPath, req) {
+		return nil
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("encode helper request: %w", err)
+	}
+
+	cmd := exec.Command(helperPath)
+	cmd.Env = hookEnviron()
+	cmd.Stdout = io.Discard
+	cmd.Stderr = io.Discard
+	err = startWithStdin(cmd, body)
+	logExecStart(cmd, err)
+	if err == nil {
+		// Only a process that outlives the popup, like the daemon on the
+		// cold path, gets to log the exit.
+		go func() {
+			logInfof("popup helper pid %d exited: %s", cmd.Process.Pid, exitStatus(cmd.Wait()))
+		}()
+	}
+	return err
+}
+
+func popupChoicesForRequest(req notificationRequest) []approvalChoice {
+	command := strings.TrimSpace(req.ExecuteOnClick)
+	label := strings.TrimSpace(req.PopupPrimaryLabel)
+	if label == "" {
+		label = inferPopupLabelFromCommand(command)
+	}
+	if label == "" {
+		if command == "" {
+			label = "Close"
+		} else {
+			label = "Open"
+		}
+	}
+
+	choices := []approvalChoice{
+		{Label: label, Command: command},
+	}
+	return append(choices, req.ExtraChoices...)
+}
+
+func inferPopupLabelFromCommand(command string) string {
+	if strings.TrimSpace(command) == "" {
+		return ""
+	}
+
+	switch commandAction(command) {
+	case "approve":
+		return "Approve"
+	case "reject-with-reason":
+		return "Reject with reason…"
+	case "reject":
+		return "Reject"
+	case "choose":
+		return "Choose"
+	case "submit":
+		return "Submit"
+	default:
+		return "Open"
+	}
+}
+
+// commandAction reads the action out of a command buildActionCommand made,
+// such as 'codex-notify' action 'approve' --thread-id 't1', quoted or not.
+func commandAction(command string) string {
+	_, rest, ok := strings.Cut(command, " action ")
+	if !ok {
+		return ""
+	}
+	action, _, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	return strings.ToLower(strings.Trim(action, "'"))
+}
+
+func approvalActionTimeoutSeconds() int {
+	return popupTimeoutSecondsForEnv(
+		"CODEX_NOTIFY_APPROVAL_TIMEOUT_SECONDS",
+		"CODEX_NOTIFY_POPUP_TIMEOUT_SECONDS",
+	)
+}
+
+func popupTimeoutSeconds() int {
+	return popupTimeoutSecondsForEnv(
+		"CODEX_NOTIFY_POPUP_TIMEOUT_SECONDS",
+		"CODEX_NOTIFY_APPROVAL_TIMEOUT_SECONDS",
+	)
+}
+
+func popupTimeoutSecondsForEnv(keys ...string) int {
+	for _, key := range keys {
+		raw := strings.TrimSpace(getenv(key))
+		if raw == "" {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			continue
+		}
+		return clampPopupTimeoutSeconds(parsed)
+	}
+
+	if fromSettings := popupTimeoutSecondsFromSettings(); fromSettings > 0 {
+		return fromSettings
+	}
+
+	return defaultPopupTimeoutSeconds
+}
+
+func popupTimeoutSecondsFromSettings() int {
+	settings, err := readPopupSettings()
+	if err != nil {
+		return 0
+	}
+	if settings.PopupTimeoutSeconds <= 0 {
+		return 0
+	}
+	return clampPopupTimeoutSeconds(settings.PopupTimeoutSeconds)
+}
+
+func clampPopupTimeoutSeconds(v int) int {
+	if v < minPopupTimeoutSeconds {
+		return minPopupTimeoutSeconds
+	}
+	if v > maxPopupTimeoutSeconds {
+		return maxPopupTimeoutSeconds
+	}
+	return v
+}
+
+func popupSettingsPath() (string, error) {
+	configDir, err := userConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve user config dir: %w", err)
+	}
+	configDir = strings.TrimSpace(configDir)
+	if configDir == "" {
+		return "", errors.New("resolve user config dir: empty path")
+	}
+	return filepath.Join(configDir, appName, popupSettingsFilename), nil
+}
+
+func readPopupSettings() (popupSettings, error) {
+	settingsPath, err := popupSettingsPath()
+	if err != nil {
+		return popupSettings{}, err
+	}
+
+	content, err := readFileMaybe(settingsPath)
+	if err != nil {
+		return popupSettings{}, err
+	}
+	if len(content) == 0 {
+		return popupSettings{}, nil
+	}
+
+	var settings popupSettings
+	if err := json.Unmarshal(content, &settings); err != nil {
+		return popupSettings{}, fmt.Errorf("parse popup settings: %w", err)
+	}
+	return settings, nil
+}
+
+func approvalInteractionLockPath() (string, error) {
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, interactionLockName), nil
+}
+
+func writeApprovalInteractionLock(path string, timeoutSeconds int) error {
+	expiresAt := time.Now().Add(time.Duration(timeoutSeconds+interactionLockGraceSeconds) * time.Second).Unix()
+	content := fmt.Sprintf("%d\n", expiresAt)
+	if err := writeFileAtomic(path, []byte(content), privateFileMode); err != nil {
+		return fmt.Errorf("write approval lock: %w", err)
+	}
+	return nil
+}
+
+func clearApprovalInteractionLock(path string) {
+	if strings.TrimSpace(path) == "" {
+		return
+	}
+	_ = os.Remove(path)
+}
+
+func isApprovalInteractionLockActive() bool {
+	lockPath, err := approvalInteractionLockPath()
+	if err != nil {
+		return false
+	}
+
+	raw, err := os.ReadFile(lockPath)
+	if err != nil {
+		return false
+	}
+
+	expiresAt, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+	if err != nil {
+		clearApprovalInteractionLock(lockPath)
+		return false
+	}
+
+	if time.Now().Unix() > expiresAt {
+		clearApprovalInteractionLock(lockPath)
+		return false
+	}
+	return true
+}
+
+type approvalChoice struct {
+	Label   string `json:"label"`
+	Command string `json:"command"`
+	// RememberCommand runs instead of Command when the chooser's remember
+	// box is ticked.
+	RememberCommand string `json:"remember_command,omitempty"`
+}
+
+func defaultApprovalChoices(threadID string) []approvalChoice {
+	choices := []approvalChoice{
+		{Label: "Open", Command: buildActionCommand("open", threadID)},
+		{Label: "Approve", Command: buildActionCommand("approve", threadID)},
+		{Label: "Reject", Command: buildActionCommand("reject", threadID)},
+	}
+	if hostOS == "darwin" {
+		// The reason prompt is a macOS dialog.
+		choices = append(choices, rejectWithReasonChoice(threadID))
+	}
+	return choices
+}
+
+func rejectWithReasonChoice(threadID string) approvalChoice {
+	return approvalChoice{Label: "Reject with reason…", Command: buildActionCommand("reject-with-reason", threadID)}
+}
+
+func approvalChoicesFromPayload(payload map[string]any, threadID string) []approvalChoice {
+	options := payloadApprovalOptions(payload)
+	if len(options) == 0 {
+		return nil
+	}
+
+	choices := make([]approvalChoice, 0, len(options)+1)
+	hasReject := false
+	for i, option := range options {
+		label := strings.TrimSpace(option)
+		if label == "" {
+			continue
+		}
+
+		action := actionForApprovalOption(label, i, len(options))
+		command := buildSubmitActionCommand(label, threadID)
+		if action != "" {
+			command = buildActionCommand(action, threadID)
+		}
+		choices = append(choices, approvalChoice{
+			Label:   label,
+			Command: command,
+		})
+		if action == "reject" {
+			hasReject = true
+		}
+	}
+	if hasReject && hostOS == "darwin" {
+		choices = append(choices, rejectWithReasonChoice(threadID))
+	}
+	return choices
+}
+
+func payloadApprovalOptions(payload map[string]any) []string {
+	return getStringSliceAny(
+		payload,
+		"approval-options",
+		"approval_options",
+		"options",
+		"choices",
+		"actions",
+	)
+}
+
+func actionForApprovalOption(label string, idx, total int) string {
+	norm := strings.ToLower(strings.TrimSpace(label))
+	norm = strings.ReplaceAll(norm, " ", "")
+	norm = strings.ReplaceAll(norm, "-", "")
+	norm = strings.ReplaceAll(norm, "_", "")
+
+	switch norm {
+	case "open", "show", "focus":
+		return "open"
+	case "approve", "approved", "allow", "yes", "y", "ok":
+		return "approve"
+	case "reject", "denied", "deny", "no", "n", "cancel":
+		return "reject"
+	}
+
+	// Common approval UX is binary yes/no; map by position if labels are unknown.
+	if total == 2 {
+		if idx == 0 {
+			return "approve"
+		}
+		return "reject"
+	}
+
+	return ""
+}
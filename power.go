@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	powerSaverOff  = "off"
+	powerSaverAuto = "auto"
+	powerSaverOn   = "on"
+)
+
+// powerStatus describes what `pmset` reports about the current power source.
+type powerStatus struct {
+	OnBattery    bool
+	LowPowerMode bool
+}
+
+var readPowerStatus = func() powerStatus {
+	if hostOS != "darwin" {
+		return powerStatus{}
+	}
+	path, ok := lookupCmd("pmset")
+	if !ok {
+		return powerStatus{}
+	}
+
+	status := powerStatus{}
+	if out, err := outputLogged(exec.Command(path, "-g", "batt")); err == nil {
+		status.OnBattery = parsePmsetOnBattery(string(out))
+	}
+	if out, err := outputLogged(exec.Command(path, "-g")); err == nil {
+		status.LowPowerMode = parsePmsetLowPowerMode(string(out))
+	}
+	return status
+}
+
+func parsePmsetOnBattery(out string) bool {
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "drawing from") {
+			return strings.Contains(line, "'Battery Power'")
+		}
+	}
+	return false
+}
+
+func parsePmsetLowPowerMode(out string) bool {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.EqualFold(fields[0], "lowpowermode") {
+			return fields[1] == "1"
+		}
+	}
+	return false
+}
+
+func powerSaverMode() string {
+	return currentSettings().PowerSaver
+}
+
+// powerSaverActive reports whether notifications should avoid the popup helper
+// (and its swiftc compile step) in favor of the cheapest available notifier.
+func powerSaverActive() bool {
+	switch powerSaverMode() {
+	case powerSaverOn:
+		return true
+	case powerSaverAuto:
+		status := currentPowerStatus(time.Now())
+		return status.OnBattery || status.LowPowerMode
+	default:
+		return false
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestParsePmset(t *testing.T) {
+	battery := "Now drawing from 'Battery Power'\n -InternalBattery-0 (id=1234)\t80%; discharging; 5:12 remaining present: true\n"
+	ac := "Now drawing from 'AC Power'\n -InternalBattery-0 (id=1234)\t100%; charged; 0:00 remaining present: true\n"
+
+	if !parsePmsetOnBattery(battery) {
+		t.Fatalf("parsePmsetOnBattery(battery) = false, want true")
+	}
+	if parsePmsetOnBattery(ac) {
+		t.Fatalf("parsePmsetOnBattery(ac) = true, want false")
+	}
+
+	settings := "System-wide power settings:\nCurrently in use:\n standby              1\n lowpowermode         1\n"
+	if !parsePmsetLowPowerMode(settings) {
+		t.Fatalf("parsePmsetLowPowerMode() = false, want true")
+	}
+	if parsePmsetLowPowerMode(" lowpowermode         0\n") {
+		t.Fatalf("parsePmsetLowPowerMode(0) = true, want false")
+	}
+}
+
+func TestPowerSaverActive(t *testing.T) {
+	stub(t, &readPowerStatus, func() powerStatus { return powerStatus{OnBattery: true} })
+	resetPowerStatusCache()
+	t.Cleanup(resetPowerStatusCache)
+
+	t.Setenv("CODEX_NOTIFY_POWER_SAVER", "")
+	if powerSaverActive() {
+		t.Fatalf("powerSaverActive() with default mode = true, want false")
+	}
+
+	t.Setenv("CODEX_NOTIFY_POWER_SAVER", "auto")
+	if !powerSaverActive() {
+		t.Fatalf("powerSaverActive() on battery in auto mode = false, want true")
+	}
+
+	readPowerStatus = func() powerStatus { return powerStatus{} }
+	if !powerSaverActive() {
+		t.Fatalf("powerSaverActive() within the cache TTL = false, want the cached battery status")
+	}
+	resetPowerStatusCache()
+	if powerSaverActive() {
+		t.Fatalf("powerSaverActive() on AC in auto mode = true, want false")
+	}
+
+	t.Setenv("CODEX_NOTIFY_POWER_SAVER", "on")
+	if !powerSaverActive() {
+		t.Fatalf("powerSaverActive() in on mode = false, want true")
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// installPrebuiltHelper copies the embedded helper to binaryPath when it was
+// built from the current Swift source, verifies the copy, and checks that it
+// runs here. Any failure leaves the caller to compile with swiftc.
+func installPrebuiltHelper(binaryPath, sourceHash string) error {
+	if len(prebuiltHelper) == 0 {
+		return errors.New("no prebuilt helper in this build")
+	}
+	if strings.TrimSpace(prebuiltHelperSourceHash) != sourceHash {
+		return errors.New("prebuilt helper does not match the helper source")
+	}
+
+	tmpPath := binaryPath + ".tmp"
+	_ = os.Remove(tmpPath)
+	if err := os.WriteFile(tmpPath, prebuiltHelper, 0o700); err != nil {
+		return fmt.Errorf("write prebuilt helper: %w", err)
+	}
+	written, err := os.ReadFile(tmpPath)
+	if err != nil || sha256.Sum256(written) != sha256.Sum256(prebuiltHelper) {
+		_ = os.Remove(tmpPath)
+		return errors.New("prebuilt helper copy does not match the embedded binary")
+	}
+	if err := probeHelper(tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, binaryPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("install helper: %w", err)
+	}
+	return nil
+}
+
+// probeHelper runs `helper --probe`, which exits before any UI appears.
+var probeHelper = func(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := outputLogged(exec.CommandContext(ctx, path, "--probe"))
+	if err != nil || strings.TrimSpace(string(out)) != "ok" {
+		return fmt.Errorf("prebuilt helper cannot run here: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build prebuilthelper && darwin
+
+package main
+
+import _ "embed"
+
+// Release builds embed a universal helper compiled on macOS from the Swift
+// source, plus the source hash it was compiled from. Only macOS builds carry
+// it; elsewhere it could never run.
+//
+//go:embed internal/swift/bin/approval_action_notifier
+var prebuiltHelper []byte
+
+//go:embed internal/swift/bin/approval_action_notifier.source.sha256
+var prebuiltHelperSourceHash string
@@ -0,0 +1,11 @@
+//go:build !prebuilthelper || !darwin
+
+package main
+
+// Builds without the prebuilthelper tag, and builds for other systems, carry
+// no helper binary; on macOS they compile the Swift source with swiftc on
+// first use.
+var (
+	prebuiltHelper           []byte
+	prebuiltHelperSourceHash string
+)
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func usePrebuiltHelper(t *testing.T, binary []byte, sourceHash string, probe func(string) error) {
+	t.Helper()
+
+	prevBinary, prevHash, prevProbe := prebuiltHelper, prebuiltHelperSourceHash, probeHelper
+	prebuiltHelper, prebuiltHelperSourceHash, probeHelper = binary, sourceHash, probe
+	t.Cleanup(func() {
+		prebuiltHelper, prebuiltHelperSourceHash, probeHelper = prevBinary, prevHash, prevProbe
+	})
+}
+
+func TestInstallPrebuiltHelper(t *testing.T) {
+	okProbe := func(string) error { return nil }
+
+	t.Run("installs matching helper", func(t *testing.T) {
+		usePrebuiltHelper(t, []byte("helper"), "abc\n", okProbe)
+		path := filepath.Join(t.TempDir(), helperBinaryName)
+		if err := installPrebuiltHelper(path, "abc"); err != nil {
+			t.Fatalf("installPrebuiltHelper: %v", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat helper: %v", err)
+		}
+		if info.Mode().Perm() != 0o700 {
+			t.Fatalf("mode = %v, want 0700", info.Mode().Perm())
+		}
+	})
+
+	t.Run("rejects stale helper", func(t *testing.T) {
+		usePrebuiltHelper(t, []byte("helper"), "old", okProbe)
+		path := filepath.Join(t.TempDir(), helperBinaryName)
+		if err := installPrebuiltHelper(path, "new"); err == nil {
+			t.Fatal("expected a source hash mismatch")
+		}
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("helper should not be installed: %v", err)
+		}
+	})
+
+	t.Run("falls back when probe fails", func(t *testing.T) {
+		usePrebuiltHelper(t, []byte("helper"), "abc", func(string) error { return errors.New("bad cpu type") })
+		dir := t.TempDir()
+		path := filepath.Join(dir, helperBinaryName)
+		if err := installPrebuiltHelper(path, "abc"); err == nil {
+			t.Fatal("expected probe failure")
+		}
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != 0 {
+			t.Fatalf("left files behind: %v", entries)
+		}
+	})
+
+	t.Run("no embedded helper", func(t *testing.T) {
+		usePrebuiltHelper(t, nil, "", okProbe)
+		if err := installPrebuiltHelper(filepath.Join(t.TempDir(), helperBinaryName), ""); err == nil {
+			t.Fatal("expected an error without an embedded helper")
+		}
+	})
+}
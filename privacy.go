@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const privateRelayEnv = "CODEX_NOTIFY_PRIVATE_RELAY"
+
+// privateArgvEnabled reports whether payload text should be kept out of
+// process arguments. Codex always passes the payload as argv, so the best the
+// hook can do is hand it to a fresh process over stdin and exit right away,
+// and feed child processes their message text over stdin as well.
+func privateArgvEnabled() bool {
+	v := strings.TrimSpace(strings.ToLower(getenv("CODEX_NOTIFY_PRIVATE_ARGV")))
+	return v == "1" || v == "true" || v == "yes" || v == "on"
+}
+
+// relayHookPayloadPrivately re-runs hook with the payload on stdin and returns
+// without waiting, which ends the argv-visible process within milliseconds.
+// flagArgs are the original non-positional hook arguments.
+func relayHookPayloadPrivately(payload string, flagArgs []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("resolve executable: %w", err)
+	}
+
+	args := append([]string{"hook", "--payload-file", "-"}, flagArgs...)
+	cmd := exec.Command(executable, args...)
+	cmd.Env = append(os.Environ(), privateRelayEnv+"=1")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := startWithStdin(cmd, []byte(payload)); err != nil {
+		return fmt.Errorf("relay hook payload: %w", err)
+	}
+	return nil
+}
+
+// unlinkedFile returns a file holding data, read from the start and already
+// removed from the file system. As a child's stdin it never blocks the
+// parent the way a pipe to a child that does not read would.
+func unlinkedFile(data []byte) (*os.File, error) {
+	f, err := os.CreateTemp("", appName+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("create stdin file: %w", err)
+	}
+	_ = os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("write stdin file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("rewind stdin file: %w", err)
+	}
+	return f, nil
+}
+
+// startWithStdin starts cmd and writes data to its stdin before returning.
+// Unlike assigning cmd.Stdin a reader, the write does not depend on a
+// goroutine that would die with this short-lived process.
+func startWithStdin(cmd *exec.Cmd, data []byte) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("create stdin pipe: %w", err)
+	}
+	cmd.Stdin = r
+
+	if err := cmd.Start(); err != nil {
+		_ = r.Close()
+		_ = w.Close()
+		return err
+	}
+	_ = r.Close()
+
+	_, writeErr := w.Write(data)
+	closeErr := w.Close()
+	if writeErr != nil {
+		return fmt.Errorf("write stdin: %w", writeErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("close stdin: %w", closeErr)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+)
+
+func TestStartWithStdin(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not available")
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command("cat")
+	cmd.Stdout = &out
+	if err := startWithStdin(cmd, []byte(`{"type":"agent-turn-complete"}`)); err != nil {
+		t.Fatalf("startWithStdin() error = %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if got := out.String(); got != `{"type":"agent-turn-complete"}` {
+		t.Fatalf("child stdin = %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// projectSwatch pairs an emoji used in message prefixes with the popup accent
+// color (hex RGB) shown for the same project.
+type projectSwatch struct {
+	Emoji string
+	Color string
+}
+
+var projectPalette = []projectSwatch{
+	{Emoji: "🔴", Color: "#FF3B30"},
+	{Emoji: "🟠", Color: "#FF9500"},
+	{Emoji: "🟡", Color: "#FFCC00"},
+	{Emoji: "🟢", Color: "#34C759"},
+	{Emoji: "🔵", Color: "#007AFF"},
+	{Emoji: "🟣", Color: "#AF52DE"},
+	{Emoji: "🟤", Color: "#A2845E"},
+}
+
+type projectIdentity struct {
+	Name  string
+	Emoji string
+	Color string
+}
+
+// projectColorsEnabled reports whether titles get the project prefix. It is
+// opt-in, so existing titles stay as they were.
+func projectColorsEnabled() bool {
+	v := strings.TrimSpace(strings.ToLower(getenv("CODEX_NOTIFY_PROJECT_COLORS")))
+	return v == "1" || v == "true" || v == "yes" || v == "on"
+}
+
+// projectIdentityForCwd derives a stable color and emoji from the repository
+// name, so every session in the same repo looks the same regardless of which
+// subdirectory Codex runs in.
+func projectIdentityForCwd(cwd string) (projectIdentity, bool) {
+	if !projectColorsEnabled() {
+		return projectIdentity{}, false
+	}
+	name := projectName(cwd)
+	if name == "" {
+		return projectIdentity{}, false
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	swatch := projectPalette[h.Sum32()%uint32(len(projectPalette))]
+	return projectIdentity{Name: name, Emoji: swatch.Emoji, Color: swatch.Color}, true
+}
+
+func projectName(cwd string) string {
+	cwd = normalizeProjectPath(cwd)
+	if cwd == "" || cwd == string(filepath.Separator) {
+		return ""
+	}
+	if root := repoRoot(cwd); root != "" {
+		return filepath.Base(root)
+	}
+	return filepath.Base(cwd)
+}
+
+func repoRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func (p projectIdentity) prefix(message string) string {
+	return p.Emoji + " " + p.Name + " · " + message
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProjectIdentityIsStablePerRepository(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "1")
+
+	root := filepath.Join(t.TempDir(), "demo-repo")
+	sub := filepath.Join(root, "internal", "pkg")
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir .git: %v", err)
+	}
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatalf("mkdir sub: %v", err)
+	}
+
+	atRoot, ok := projectIdentityForCwd(root)
+	if !ok {
+		t.Fatalf("projectIdentityForCwd(root) ok = false, want true")
+	}
+	inSub, ok := projectIdentityForCwd(sub)
+	if !ok {
+		t.Fatalf("projectIdentityForCwd(sub) ok = false, want true")
+	}
+	if atRoot != inSub {
+		t.Fatalf("identity differs between repo root and subdirectory: %+v vs %+v", atRoot, inSub)
+	}
+	if atRoot.Name != "demo-repo" {
+		t.Fatalf("Name = %q, want demo-repo", atRoot.Name)
+	}
+	if got := atRoot.prefix("done"); !strings.HasSuffix(got, "demo-repo · done") || !strings.HasPrefix(got, atRoot.Emoji) {
+		t.Fatalf("prefix() = %q", got)
+	}
+}
+
+func TestProjectIdentityDisabled(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "0")
+	if _, ok := projectIdentityForCwd(t.TempDir()); ok {
+		t.Fatalf("projectIdentityForCwd() ok = true with colors disabled, want false")
+	}
+
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "1")
+	if _, ok := projectIdentityForCwd(""); ok {
+		t.Fatalf("projectIdentityForCwd(\"\") ok = true, want false")
+	}
+}
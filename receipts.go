@@ -0,0 +1,54 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+)
+
+const (
+	receiptsFilename = "receipts.jsonl"
+
+	receiptDelivered = "delivered"
+	receiptClicked   = "clicked"
+	receiptDismissed = "dismissed"
+	receiptExpired   = "expired"
+	// receiptWithdrawn is a popup closed because its approval was answered
+	// on another device.
+	receiptWithdrawn = "withdrawn"
+)
+
+// deliveryReceipt is one line of the receipts log. The popup helper appends
+// clicked/dismissed/expired/withdrawn lines to the same file with identical fields.
+type deliveryReceipt struct {
+	Time    string `json:"time"`
+	ID      string `json:"id"`
+	Backend string `json:"backend,omitempty"`
+	Status  string `json:"status"`
+	Choice  string `json:"choice,omitempty"`
+}
+
+func receiptsPath() (string, error) {
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, receiptsFilename), nil
+}
+
+func appendReceipt(r deliveryReceipt) error {
+	path, err := receiptsPath()
+	if err != nil {
+		return err
+	}
+	if r.Time == "" {
+		r.Time = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	return appendJSONLine(path, r)
+}
+
+// recordDelivered notes that a backend accepted a notification. Receipts are
+// best effort and never fail the notification itself.
+func recordDelivered(id, backend string) {
+	_ = appendReceipt(deliveryReceipt{ID: id, Backend: backend, Status: receiptDelivered})
+}
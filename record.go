@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	codexpayload "github.com/MiUPa/codex-notify/payload"
+)
+
+// recordPayload writes raw, exactly as the hook received it, to
+// <UTC time>-<event>.json in dir, so a listing is in arrival order and any
+// file can be given to `codex-notify replay`. Payloads that do not parse
+// are kept too, as <time>-payload.json.
+func recordPayload(dir, raw string, now time.Time) (string, error) {
+	dir, err := expandUserPath(dir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	event := "payload"
+	if payload, err := codexpayload.Parse([]byte(raw)); err == nil && payloadEventName(payload) != "" {
+		event = payloadEventName(payload)
+	}
+	stamp := now.UTC().Format("20060102T150405.000000000Z")
+	for attempt := 1; attempt <= 100; attempt++ {
+		name := fmt.Sprintf("%s-%s.json", stamp, sanitizeID(event))
+		if attempt > 1 {
+			name = fmt.Sprintf("%s-%s-%d.json", stamp, sanitizeID(event), attempt)
+		}
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, privateFileMode)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, werr := f.WriteString(raw + "\n")
+		if cerr := f.Close(); werr == nil {
+			werr = cerr
+		}
+		return path, werr
+	}
+	return "", fmt.Errorf("record payload: no free file name in %s", dir)
+}
+
+// replayCaptureDirName is where replay captures notifications, under the
+// runtime state dir, when CODEX_NOTIFY_BACKEND names no capture dir.
+const replayCaptureDirName = "replay"
+
+// runReplay runs saved payloads through the hook pipeline again, in this
+// process rather than the daemon, so --verbose shows every step. The
+// notifications go to the capture backend unless --live puts them on the
+// desktop. Either way a replay acts for nothing: remote sinks are skipped,
+// approvals are not tracked, and buttons only open the terminal. The
+// duplicate check forgets each payload first.
+func runReplay(args []string) error {
+	var files []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		files, args = append(files, args[0]), args[1:]
+	}
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	live := fs.Bool("live", false, "show the notifications instead of capturing them")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	files = append(files, fs.Args()...)
+	if len(files) == 0 {
+		return usageError(errors.New("replay requires a payload file"))
+	}
+	if err := checkBackendEnv(); err != nil {
+		return err
+	}
+	out := outFlags.output()
+
+	env := forwardedEnv()
+	result := commandResult{Command: "replay"}
+	if dir, capture := captureDir(); capture {
+		result.Path = dir
+	} else if !*live {
+		stateDir, err := runtimeStateDir()
+		if err != nil {
+			return err
+		}
+		result.Path = filepath.Join(stateDir, replayCaptureDirName)
+		env[backendEnv] = capturePrefix + result.Path
+	}
+
+	var replayErr error
+	withHookEnv(env, func() {
+		for _, file := range files {
+			raw, err := os.ReadFile(file)
+			if err != nil {
+				replayErr = usageError(fmt.Errorf("read payload file: %w", err))
+				return
+			}
+			payload, err := codexpayload.Parse(raw)
+			if err != nil {
+				replayErr = usageError(fmt.Errorf("%s: %w", file, err))
+				return
+			}
+			forgetEvent(eventDedupKey(payload))
+			delivered, err := deliverPayload(payload, hookDelivery{replay: true})
+			if err != nil {
+				replayErr = err
+				return
+			}
+			out.Printf("%s: %s %s\n", filepath.Base(file), payloadEventName(payload), delivered.Status)
+			result.Status, result.Thread, result.Rule = delivered.Status, delivered.Thread, delivered.Rule
+			result.Count += delivered.Count
+		}
+	})
+	if replayErr != nil {
+		return replayErr
+	}
+	if result.Path != "" {
+		out.Printf("captured in %s\n", result.Path)
+	}
+	return out.Result(result)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordPayloadNamesFilesByTimeAndEvent(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "payloads")
+	now := time.Date(2026, 10, 14, 9, 12, 3, 418000000, time.UTC)
+	raw := `{"type":"approval-requested","thread-id":"t1","turn-id":"4"}`
+
+	first, err := recordPayload(dir, raw, now)
+	if err != nil {
+		t.Fatalf("recordPayload() error = %v", err)
+	}
+	if want := filepath.Join(dir, "20261014T091203.418000000Z-approval-requested.json"); first != want {
+		t.Fatalf("recordPayload() = %q, want %q", first, want)
+	}
+	second, err := recordPayload(dir, raw, now)
+	if err != nil || !strings.HasSuffix(second, "-approval-requested-2.json") {
+		t.Fatalf("second recordPayload() at the same time = %q, %v", second, err)
+	}
+	if content, err := os.ReadFile(first); err != nil || string(content) != raw+"\n" {
+		t.Fatalf("recorded %q, %v; want the payload as received", content, err)
+	}
+	broken, err := recordPayload(dir, "{not json", now)
+	if err != nil || !strings.HasSuffix(broken, "-payload.json") {
+		t.Fatalf("recordPayload() of a broken payload = %q, %v", broken, err)
+	}
+}
+
+func TestReplayRunsRecordedPayloadThroughHookAgain(t *testing.T) {
+	home, captured := useTempHome(t)
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "0")
+
+	path, err := recordPayload(filepath.Join(home, "payloads"), `{"type":"agent-turn-complete","thread-id":"t1","turn-id":"7","last-assistant-message":"Done"}`, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := map[string]any{"type": "agent-turn-complete", "thread-id": "t1", "turn-id": "7"}
+	if first, err := claimEvent(eventDedupKey(payload), time.Now()); err != nil || !first {
+		t.Fatalf("claimEvent() = %v, %v", first, err)
+	}
+
+	// The live hook already claimed the event; replaying it twice still
+	// delivers it both times.
+	for i := 0; i < 2; i++ {
+		if err := runReplay([]string{path, "--quiet"}); err != nil {
+			t.Fatalf("runReplay() error = %v", err)
+		}
+	}
+	notes := readCaptured(t, captured)
+	if len(notes) != 2 || !strings.Contains(notes[0].Message, "Done") || notes[0].Group != notificationGroup("agent-turn-complete", "t1") {
+		t.Fatalf("captured = %+v, want the turn notification twice", notes)
+	}
+
+	if err := runReplay(nil); exitCodeFor(err) != exitUsage {
+		t.Fatalf("runReplay() without a file = %v, want a usage error", err)
+	}
+}
+
+func TestReplayCapturesApprovalsWithoutTrackingThem(t *testing.T) {
+	home, _ := useTempHome(t)
+	t.Setenv(backendEnv, "")
+	t.Setenv("CODEX_NOTIFY_ENABLE_APPROVAL_ACTIONS", "1")
+	t.Setenv("CODEX_NOTIFY_APPROVAL_UI", "multi")
+
+	path, err := recordPayload(filepath.Join(home, "payloads"), `{"type":"approval-requested","thread-id":"t1","turn-id":"3"}`, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runReplay([]string{path, "--quiet"}); err != nil {
+		t.Fatalf("runReplay() error = %v", err)
+	}
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := readCaptured(t, filepath.Join(stateDir, replayCaptureDirName))
+	if len(notes) != 1 || notes[0].ExecuteOnClick != buildActionCommand("open", "t1") || len(notes[0].Choices) > 1 {
+		t.Fatalf("captured = %+v, want one notification that only opens the terminal", notes)
+	}
+	if _, ok := lookupPendingApproval("t1"); ok {
+		t.Fatal("a replayed approval became pending")
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultRemindMax     = 3
+	defaultSnoozeSeconds = 300
+	// remindEscalateAfter is the reminder from which the approval also goes
+	// to CODEX_NOTIFY_REMIND_ESCALATE: the desktop was tried twice by then.
+	remindEscalateAfter = 2
+)
+
+// remindInterval is CODEX_NOTIFY_REMIND_SECONDS: an approval nobody
+// answered is shown again that often. Zero turns reminders off.
+func remindInterval() time.Duration {
+	raw := strings.TrimSpace(getenv("CODEX_NOTIFY_REMIND_SECONDS"))
+	if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return 0
+}
+
+// remindMax is CODEX_NOTIFY_REMIND_MAX, how many reminders an approval gets.
+func remindMax() int {
+	raw := strings.TrimSpace(getenv("CODEX_NOTIFY_REMIND_MAX"))
+	if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+		return n
+	}
+	return defaultRemindMax
+}
+
+// snoozeInterval is CODEX_NOTIFY_SNOOZE_SECONDS, how long the approval
+// popup's Snooze button hides an approval. Zero removes the button.
+func snoozeInterval() time.Duration {
+	raw := strings.TrimSpace(getenv("CODEX_NOTIFY_SNOOZE_SECONDS"))
+	if raw == "" {
+		return defaultSnoozeSeconds * time.Second
+	}
+	if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return 0
+}
+
+// remindEscalateSinks is CODEX_NOTIFY_REMIND_ESCALATE, the remote sinks a
+// reminder also goes to from the second one on, whether or not their events
+// lists have approvals.
+func remindEscalateSinks() []string {
+	var sinks []string
+	for _, name := range strings.Split(getenv("CODEX_NOTIFY_REMIND_ESCALATE"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			sinks = append(sinks, name)
+		}
+	}
+	return sinks
+}
+
+// nextReminder is when pending is due to be shown again: at the end of its
+// snooze, or an interval after it was last shown while it has reminders
+// left.
+func nextReminder(pending pendingApproval, interval time.Duration, limit int) (time.Time, bool) {
+	if pending.Payload == nil {
+		return time.Time{}, false
+	}
+	if pending.SnoozedUntil != 0 {
+		return time.Unix(pending.SnoozedUntil, 0), true
+	}
+	if interval == 0 || pending.Reminders >= limit {
+		return time.Time{}, false
+	}
+	last := pending.RemindedAt
+	if last == 0 {
+		last = pending.Since
+	}
+	return time.Unix(last, 0).Add(interval), true
+}
+
+// remindPendingApprovals shows approvals again that have waited an interval
+// since they were last shown or whose snooze is over, and returns how many
+// it showed. Nothing is counted while notifications could not be seen
+// anyway: muted, locked, or with an approval popup open.
+func remindPendingApprovals(now time.Time) int {
+	interval := remindInterval()
+	if interval == 0 && snoozeInterval() == 0 {
+		return 0
+	}
+	state, err := loadState()
+	if err != nil || len(state.PendingApprovals) == 0 || isGloballyMuted(state, now) {
+		return 0
+	}
+	if isApprovalInteractionLockActive() || screenLockQueueActive() {
+		return 0
+	}
+
+	limit := remindMax()
+	var due []pendingApproval
+	// A snooze ending is not a reminder: it neither counts nor escalates.
+	woke := map[string]bool{}
+	if err := updateState(func(s *notifyState) {
+		due, woke = nil, map[string]bool{}
+		for thread, pending := range s.PendingApprovals {
+			at, ok := nextReminder(pending, interval, limit)
+			if !ok || now.Before(at) || isProjectMuted(*s, pending.Cwd, now) {
+				continue
+			}
+			if pending.SnoozedUntil != 0 {
+				pending.SnoozedUntil = 0
+				woke[thread] = true
+			} else {
+				pending.Reminders++
+			}
+			pending.RemindedAt = now.Unix()
+			s.PendingApprovals[thread] = pending
+			due = append(due, pending)
+		}
+	}); err != nil {
+		return 0
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Since < due[j].Since })
+
+	cfg, _ := loadUserConfig()
+	escalate := remindEscalateSinks()
+	var wg sync.WaitGroup
+	for _, pending := range due {
+		redeliverNotification(pending.Payload)
+		recordHookEvent(pending.Payload, "reminded")
+		if woke[pending.Thread] || pending.Local || pending.Reminders < remindEscalateAfter || len(escalate) == 0 || benchDryRun() {
+			continue
+		}
+		for _, sink := range namedRemoteSinks(cfg, pending.Payload, escalate) {
+			wg.Add(1)
+			go func(sink remoteSink) {
+				defer wg.Done()
+				if err := sink.Publish(); err != nil {
+					logErrorf("%s: %v", sink.Name, err)
+				}
+			}(sink)
+		}
+	}
+	wg.Wait()
+	return len(due)
+}
+
+func addRemindDoctorCheck(report *doctorReport, cfg userConfig) {
+	interval := remindInterval()
+	if interval == 0 {
+		return
+	}
+	detail := fmt.Sprintf("every %s, up to %d times", interval, remindMax())
+	if escalate := remindEscalateSinks(); len(escalate) > 0 {
+		var missing []string
+		for _, name := range escalate {
+			if len(namedRemoteSinks(cfg, map[string]any{"type": "approval-requested"}, []string{name})) == 0 {
+				missing = append(missing, name)
+			}
+		}
+		detail += fmt.Sprintf("; from reminder %d also to %s", remindEscalateAfter, strings.Join(escalate, ", "))
+		if len(missing) > 0 {
+			report.add(doctorSettings, checkWarn, "approval reminders", detail+", but "+strings.Join(missing, ", ")+" is not configured", false)
+			return
+		}
+	}
+	report.add(doctorSettings, checkOK, "approval reminders", detail, false)
+}
+
+// snoozeApproval hides thread's pending approval for the snooze interval
+// and returns when it comes back; ok is false when nothing is pending.
+func snoozeApproval(thread string, now time.Time) (time.Time, bool) {
+	until := now.Add(snoozeInterval())
+	found := false
+	err := updateState(func(s *notifyState) {
+		pending, ok := s.PendingApprovals[thread]
+		found = ok && pending.Payload != nil
+		if !found {
+			return
+		}
+		pending.SnoozedUntil = until.Unix()
+		s.PendingApprovals[thread] = pending
+	})
+	return until, err == nil && found
+}
+
+// runSnoozeAction is `action snooze`, the popup's Snooze button. Withdrawn
+// notifications stay gone; the approval comes back once the snooze is over.
+func runSnoozeAction(out commandOutput, thread string) error {
+	if thread == "" {
+		return usageError(errors.New("snooze action requires --thread-id"))
+	}
+	if snoozeInterval() == 0 {
+		return usageError(errors.New("snoozing is off (CODEX_NOTIFY_SNOOZE_SECONDS=0)"))
+	}
+	until, ok := snoozeApproval(thread, time.Now())
+	if !ok {
+		out.Printf("no pending approval for thread %s\n", thread)
+		return out.Result(commandResult{Command: "action", Status: "not-found", Action: "snooze", Thread: thread})
+	}
+	withdrawLocalGroups(approvalGroups(thread))
+	if pending, ok := lookupPendingApproval(thread); ok {
+		recordActionEvent("snooze", thread, pending.Cwd, "", "ok")
+	}
+	startReminderProcess(thread)
+	out.Printf("snoozed until %s\n", until.Local().Format("15:04"))
+	return out.Result(commandResult{Command: "action", Status: "snoozed", Action: "snooze", Thread: thread})
+}
+
+// snoozeChoice is the popup's Snooze button, left out when snoozing is off
+// or the approval would expire before the snooze ends.
+func snoozeChoice(payload map[string]any, thread string, now time.Time) (approvalChoice, bool) {
+	interval := snoozeInterval()
+	if interval == 0 || thread == "" {
+		return approvalChoice{}, false
+	}
+	if deadline, ok := payloadApprovalDeadline(payload, now); ok && !now.Add(interval).Before(deadline) {
+		return approvalChoice{}, false
+	}
+	return approvalChoice{Label: "Snooze " + formatSnooze(interval), Command: buildActionCommand("snooze", thread)}, true
+}
+
+// formatSnooze is the button's duration, e.g. "5m" or "1h30m".
+func formatSnooze(d time.Duration) string {
+	s := d.Round(time.Second).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// startReminderProcess starts `remind` in the background for thread, which
+// shows its approval again when a reminder or the end of a snooze is due.
+func startReminderProcess(thread string) {
+	if thread == "" || benchDryRun() {
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	cmd := exec.Command(exe, "remind", "--thread-id", thread)
+	cmd.Env = hookEnviron()
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	err = cmd.Start()
+	logExecStart(cmd, err)
+	if err != nil {
+		logErrorf("start reminders: %v", err)
+		return
+	}
+	_ = cmd.Process.Release()
+}
+
+// runRemind waits out thread's approval, sending reminders as they fall
+// due, until it is answered or has had every reminder. hook starts it when
+// no daemon is running; other processes claiming the same reminder first
+// is harmless.
+func runRemind(args []string) error {
+	fs := flag.NewFlagSet("remind", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	thread := fs.String("thread-id", "", "thread whose approval to wait for")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *thread == "" {
+		return usageError(errors.New("remind requires --thread-id"))
+	}
+	deadline := time.Now().Add(widgetApprovalWindow)
+	for time.Now().Before(deadline) {
+		pending, ok := lookupPendingApproval(*thread)
+		if !ok {
+			return nil
+		}
+		at, ok := nextReminder(pending, remindInterval(), remindMax())
+		if !ok {
+			return nil
+		}
+		wait := time.Until(at)
+		if wait <= 0 {
+			// Due but held back, by a mute, a lock, or an open popup.
+			wait = lockQueuePollInterval
+		}
+		time.Sleep(wait)
+		remindPendingApprovals(time.Now())
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRemindPendingApprovals(t *testing.T) {
+	home, captured := useTempHome(t)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("CODEX_NOTIFY_REMIND_SECONDS", "60")
+	t.Setenv("CODEX_NOTIFY_REMIND_MAX", "2")
+	t.Setenv("CODEX_NOTIFY_REMIND_ESCALATE", "ntfy")
+	stub(t, &readScreenLocked, func() bool { return false })
+
+	var pushes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { pushes.Add(1) }))
+	defer server.Close()
+	cfgPath, err := userConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cfgPath), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	// ntfy only wants errors, so approvals reach it by escalation alone.
+	if err := os.WriteFile(cfgPath, []byte("[ntfy]\nserver = \""+server.URL+"\"\ntopic = \"t\"\nevents = [\"agent-error\"]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if _, ok := trackApproval(map[string]any{"type": "approval-requested", "thread-id": "t1", "cwd": "/src/app"}, now); !ok {
+		t.Fatal("trackApproval failed")
+	}
+	if n := remindPendingApprovals(now.Add(30 * time.Second)); n != 0 {
+		t.Fatalf("reminded %d before the interval", n)
+	}
+	if n := remindPendingApprovals(now.Add(61 * time.Second)); n != 1 || pushes.Load() != 0 {
+		t.Fatalf("first reminder: reminded %d, %d pushes", n, pushes.Load())
+	}
+	if n := remindPendingApprovals(now.Add(90 * time.Second)); n != 0 {
+		t.Fatal("reminded again before another interval")
+	}
+	if n := remindPendingApprovals(now.Add(122 * time.Second)); n != 1 || pushes.Load() != 1 {
+		t.Fatalf("second reminder: reminded %d, %d pushes; want one escalation", n, pushes.Load())
+	}
+	if n := remindPendingApprovals(now.Add(10 * time.Minute)); n != 0 {
+		t.Fatal("reminded past CODEX_NOTIFY_REMIND_MAX")
+	}
+	if got := len(readCaptured(t, captured)); got != 2 {
+		t.Fatalf("captured %d reminders, want 2", got)
+	}
+
+	// An answered approval is not reminded.
+	if _, ok := trackApproval(map[string]any{"type": "approval-requested", "thread-id": "t2"}, now); !ok {
+		t.Fatal("trackApproval failed")
+	}
+	settleApproval("t2", answeredCodex)()
+	if n := remindPendingApprovals(now.Add(2 * time.Minute)); n != 0 {
+		t.Fatal("reminded an answered approval")
+	}
+}
+
+func TestSnoozeApproval(t *testing.T) {
+	home, captured := useTempHome(t)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("CODEX_NOTIFY_REMIND_SECONDS", "")
+	t.Setenv("CODEX_NOTIFY_SNOOZE_SECONDS", "")
+	stub(t, &readScreenLocked, func() bool { return false })
+
+	if err := runAction([]string{"snooze", "--thread-id", "t1", "--quiet"}); err != nil {
+		t.Fatalf("snoozing without a pending approval: %v", err)
+	}
+
+	now := time.Now()
+	payload := map[string]any{"type": "approval-requested", "thread-id": "t1"}
+	if _, ok := trackApproval(payload, now); !ok {
+		t.Fatal("trackApproval failed")
+	}
+	if choice, ok := snoozeChoice(payload, "t1", now); !ok || choice.Label != "Snooze 5m" {
+		t.Fatalf("snoozeChoice = %+v, %v", choice, ok)
+	}
+	until, ok := snoozeApproval("t1", now)
+	if !ok || !until.Equal(now.Add(5*time.Minute)) {
+		t.Fatalf("snoozeApproval = %s, %v", until, ok)
+	}
+	if n := remindPendingApprovals(now.Add(4 * time.Minute)); n != 0 {
+		t.Fatal("shown again before the snooze ended")
+	}
+	if n := remindPendingApprovals(now.Add(301 * time.Second)); n != 1 {
+		t.Fatal("not shown again after the snooze")
+	}
+	if n := remindPendingApprovals(now.Add(time.Hour)); n != 0 {
+		t.Fatal("a snooze ending started reminders")
+	}
+	if got := len(readCaptured(t, captured)); got != 1 {
+		t.Fatalf("captured %d notifications, want 1", got)
+	}
+
+	t.Setenv("CODEX_NOTIFY_SNOOZE_SECONDS", "0")
+	if _, ok := snoozeChoice(payload, "t1", now); ok {
+		t.Fatal("Snooze offered with snooze_seconds = 0")
+	}
+	t.Setenv("CODEX_NOTIFY_SNOOZE_SECONDS", "5400")
+	if choice, _ := snoozeChoice(payload, "t1", now); choice.Label != "Snooze 1h30m" {
+		t.Fatalf("label = %q", choice.Label)
+	}
+}
+
+func TestTestApprovalsStayLocal(t *testing.T) {
+	home, _ := useTempHome(t)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("CODEX_NOTIFY_REMIND_SECONDS", "60")
+	t.Setenv("CODEX_NOTIFY_REMIND_MAX", "3")
+	t.Setenv("CODEX_NOTIFY_REMIND_ESCALATE", "ntfy,pushover,peer")
+	t.Setenv("CODEX_NOTIFY_SANDBOX", "")
+	t.Setenv("CODEX_NOTIFY_REVEAL_KEYS", "")
+	t.Setenv("CODEX_NOTIFY_APPROVE_KEYS", "")
+	t.Setenv("CODEX_NOTIFY_TERMINAL_BUNDLE_ID", "com.apple.Terminal")
+	t.Setenv(pushoverTokenEnv, "")
+	t.Setenv(pushoverUserEnv, "")
+	t.Setenv(peerTokenEnv, "")
+	t.Setenv(featuresEnv, "peer=on")
+	stub(t, &readScreenLocked, func() bool { return false })
+	stub(t, &frontmostApp, func() string { return "com.tinyspeck.slackmacgap" })
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		t.Errorf("a test approval reached %s", r.URL.Path)
+	}))
+	defer server.Close()
+	cfgPath, err := userConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cfgPath), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	config := "[ntfy]\nserver = \"" + server.URL + "\"\ntopic = \"t\"\n" +
+		"[pushover]\ntoken = \"app\"\nuser = \"me\"\nurl = \"" + server.URL + "\"\n" +
+		"[peer]\nurl = \"" + server.URL + "\"\ntoken = \"shared\"\n"
+	if err := os.WriteFile(cfgPath, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	payload := testEventPayload("approval-requested", "t1", "rm -rf?", "", home, now)
+	if _, err := deliverPayload(payload, hookDelivery{test: true}); err != nil {
+		t.Fatalf("test approval: %v", err)
+	}
+	if state, _ := loadState(); !state.PendingApprovals["t1"].Local {
+		t.Fatalf("pending = %+v, want the test approval marked local", state.PendingApprovals["t1"])
+	}
+
+	// Out of capture mode, a withdrawal would reach the sinks.
+	t.Setenv(backendEnv, "")
+	bin := t.TempDir()
+	writeFakeNotifier(t, bin, "osascript", filepath.Join(home, "ran.log"))
+	t.Setenv("PATH", bin)
+	for i := 1; i <= 3; i++ {
+		if n := remindPendingApprovals(now.Add(time.Duration(i) * 61 * time.Second)); n != 1 {
+			t.Fatalf("reminder %d: reminded %d, want 1", i, n)
+		}
+	}
+	if err := runAction([]string{"approve", "--thread-id", "t1", "--quiet"}); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if state, _ := loadState(); len(state.PendingApprovals) != 0 || len(state.SinkQueue) != 0 {
+		t.Fatalf("state = %+v, want the approval settled and nothing queued", state)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("%d sink or peer requests for a test approval", n)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	codexpayload "github.com/MiUPa/codex-notify/payload"
+)
+
+// Anonymized Codex payloads used by `render --fixture` and the golden tests.
+//
+//go:embed internal/fixtures/payloads/*.json
+var payloadFixtures embed.FS
+
+const payloadFixturesDir = "internal/fixtures/payloads"
+
+// renderResult is the output of `render`: the notifications hook would send
+// for one payload with the current environment and config.
+type renderResult struct {
+	Event         string                 `json:"event"`
+	Path          string                 `json:"path"`
+	Notifications []renderedNotification `json:"notifications"`
+}
+
+type renderedNotification struct {
+	Title       string           `json:"title"`
+	Message     string           `json:"message"`
+	Group       string           `json:"group"`
+	Click       string           `json:"click,omitempty"`
+	AccentColor string           `json:"accent_color,omitempty"`
+	Icon        string           `json:"icon,omitempty"`
+	Sound       string           `json:"sound,omitempty"`
+	Choices     []approvalChoice `json:"choices,omitempty"`
+}
+
+func runRender(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fixture := fs.String("fixture", "", "render a bundled payload fixture")
+	list := fs.Bool("list", false, "list bundled payload fixtures")
+	payloadFile := fs.String("payload-file", "", "read the payload from a file (- for stdin)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *list {
+		names, err := payloadFixtureNames()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Fprintln(os.Stdout, name)
+		}
+		return nil
+	}
+
+	var raw string
+	if *fixture != "" {
+		content, err := payloadFixture(*fixture)
+		if err != nil {
+			return usageError(err)
+		}
+		raw = string(content)
+	} else {
+		var err error
+		raw, err = resolveHookPayload(fs.Args(), *payloadFile, -1)
+		if err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(raw) == "" {
+		return usageError(errors.New("render requires --fixture, --payload-file, or a json payload"))
+	}
+
+	payload, err := codexpayload.Parse([]byte(raw))
+	if err != nil {
+		return usageError(err)
+	}
+
+	result, err := renderHookPayload(payload)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode render result: %w", err)
+	}
+	fmt.Fprintln(os.Stdout, string(out))
+	return nil
+}
+
+// renderHookPayload mirrors the delivery decisions of runHook without
+// sending anything. Mutes, tmux suppression, and the approval interaction
+// lock are runtime state and do not affect the rendering.
+func renderHookPayload(payload map[string]any) (renderResult, error) {
+	result := renderResult{Event: payloadEventName(payload)}
+
+	if shouldUseNativeApprovalNotification(payload) {
+		req := buildNativeApprovalContent(payload)
+		result.Path = "approval-popup"
+		result.Notifications = []renderedNotification{{
+			Title:       req.Title,
+			Message:     req.Message,
+			Group:       req.Group,
+			AccentColor: req.AccentColor,
+			Icon:        req.Icon,
+			Sound:       req.Sound,
+			Choices:     stableChoices(req.ExtraChoices),
+		}}
+		return result, nil
+	}
+
+	requests, err := buildHookNotifications(payload)
+	if err != nil {
+		return renderResult{}, err
+	}
+	backend := firstAvailableBackend()
+	_, popup := backend.(popupBackend)
+	result.Path = "system"
+	if popup {
+		result.Path = "popup"
+	}
+	for _, req := range requests {
+		n := renderedNotification{
+			Title:   req.Title,
+			Message: req.Message,
+			Group:   req.Group,
+			Click:   stableCommand(req.ExecuteOnClick),
+			Icon:    req.Icon,
+			Sound:   req.Sound,
+		}
+		if popup {
+			n.AccentColor = req.AccentColor
+		}
+		if backend != nil {
+			n.Message = renderMessage(req.Message, backend.Capabilities().Format)
+		}
+		if backend != nil && backend.Capabilities().Actions {
+			n.Choices = stableChoices(popupChoicesForRequest(adaptForBackend(req, backend.Capabilities())))
+		}
+		result.Notifications = append(result.Notifications, n)
+	}
+	return result, nil
+}
+
+// notificationPreview is one fixture rendered for `doctor --preview`.
+type notificationPreview struct {
+	Fixture string `json:"fixture"`
+	Backend string `json:"backend"`
+	renderResult
+}
+
+// previewNotifications renders every bundled fixture with the current
+// environment and config, and names the backend that would show it.
+func previewNotifications() ([]notificationPreview, error) {
+	names, err := payloadFixtureNames()
+	if err != nil {
+		return nil, err
+	}
+	backend := "none"
+	if b := firstAvailableBackend(); b != nil {
+		backend = b.Name()
+	}
+
+	previews := []notificationPreview{}
+	for _, name := range names {
+		content, err := payloadFixture(name)
+		if err != nil {
+			return nil, err
+		}
+		payload, err := codexpayload.Parse(content)
+		if err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", name, err)
+		}
+		result, err := renderHookPayload(payload)
+		if err != nil {
+			return nil, fmt.Errorf("render fixture %s: %w", name, err)
+		}
+		p := notificationPreview{Fixture: name, Backend: backend, renderResult: result}
+		if result.Path == "approval-popup" {
+			p.Backend = "popup"
+		}
+		previews = append(previews, p)
+	}
+	return previews, nil
+}
+
+func printNotificationPreviews(out commandOutput, previews []notificationPreview) {
+	out.Println("")
+	out.Println("notification preview")
+	out.Println("--------------------")
+	for _, p := range previews {
+		out.Printf("%s (%s via %s)\n", p.Fixture, p.Event, p.Backend)
+		if len(p.Notifications) == 0 {
+			out.Println("  (no notification)")
+		}
+		for _, n := range p.Notifications {
+			out.Printf("  title:   %s\n", n.Title)
+			out.Printf("  message: %s\n", strings.ReplaceAll(n.Message, "\n", "\n           "))
+			if len(n.Choices) > 0 {
+				labels := make([]string, 0, len(n.Choices))
+				for _, c := range n.Choices {
+					labels = append(labels, "["+c.Label+"]")
+				}
+				out.Printf("  buttons: %s\n", strings.Join(labels, " "))
+			}
+			if n.Click != "" {
+				out.Printf("  click:   %s\n", n.Click)
+			}
+		}
+	}
+}
+
+// stableCommand replaces the absolute executable path in generated action
+// commands with the bare program name, so renders compare across machines.
+func stableCommand(command string) string {
+	executable, err := os.Executable()
+	if err != nil || strings.TrimSpace(executable) == "" {
+		return command
+	}
+	return strings.ReplaceAll(command, shellQuote(executable), appName)
+}
+
+func stableChoices(choices []approvalChoice) []approvalChoice {
+	out := make([]approvalChoice, 0, len(choices))
+	for _, c := range choices {
+		out = append(out, approvalChoice{Label: c.Label, Command: stableCommand(c.Command)})
+	}
+	return out
+}
+
+func payloadFixtureNames() ([]string, error) {
+	entries, err := fs.ReadDir(payloadFixtures, payloadFixturesDir)
+	if err != nil {
+		return nil, fmt.Errorf("read fixtures: %w", err)
+	}
+	names := []string{}
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".json"); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func payloadFixture(name string) ([]byte, error) {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".json")
+	content, err := payloadFixtures.ReadFile(path.Join(payloadFixturesDir, name+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("unknown fixture: %s (see render --list)", name)
+	}
+	return content, err
+}
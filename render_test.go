@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden render outputs")
+
+func TestRenderFixturesMatchGolden(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", filepath.Join(t.TempDir(), "missing.toml"))
+	for _, key := range []string{
+		"CODEX_NOTIFY_NOTIFICATION_UI",
+		"CODEX_NOTIFY_APPROVAL_UI",
+		"CODEX_NOTIFY_ENABLE_APPROVAL_ACTIONS",
+		"CODEX_NOTIFY_ENABLE_POPUP_APPROVAL_ACTIONS",
+		"CODEX_NOTIFY_ENABLE_NATIVE_APPROVAL_ACTIONS",
+		"CODEX_NOTIFY_POWER_SAVER",
+		"CODEX_NOTIFY_PROJECT_COLORS",
+		"CODEX_NOTIFY_SANDBOX",
+		"CODEX_NOTIFY_LANGUAGE",
+		"CODEX_NOTIFY_TURN_OUTCOMES",
+	} {
+		t.Setenv(key, "")
+	}
+
+	names, err := payloadFixtureNames()
+	if err != nil {
+		t.Fatalf("payloadFixtureNames() error = %v", err)
+	}
+	if len(names) == 0 {
+		t.Fatalf("no payload fixtures found")
+	}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			content, err := payloadFixture(name)
+			if err != nil {
+				t.Fatalf("payloadFixture() error = %v", err)
+			}
+			var payload map[string]any
+			if err := json.Unmarshal(content, &payload); err != nil {
+				t.Fatalf("parse fixture: %v", err)
+			}
+			result, err := renderHookPayload(payload)
+			if err != nil {
+				t.Fatalf("renderHookPayload() error = %v", err)
+			}
+			got, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			got = append(got, '\n')
+
+			goldenPath := filepath.Join("internal", "fixtures", "golden", name+".json")
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatalf("write golden: %v", err)
+				}
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("read golden (run go test -run TestRenderFixtures -update): %v", err)
+			}
+			if string(got) != string(want) {
+				t.Fatalf("render %s mismatch\n--- got\n%s\n--- want\n%s", name, got, want)
+			}
+		})
+	}
+}
+
+func TestPayloadFixtureUnknown(t *testing.T) {
+	if _, err := payloadFixture("does-not-exist"); err == nil {
+		t.Fatalf("payloadFixture(unknown) error = nil, want error")
+	}
+}
+
+func TestPreviewNotificationsCoversFixtures(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", filepath.Join(t.TempDir(), "missing.toml"))
+	t.Setenv("CODEX_NOTIFY_NOTIFICATION_UI", "system")
+	t.Setenv("CODEX_NOTIFY_SANDBOX", "")
+	t.Setenv("PATH", t.TempDir())
+
+	names, err := payloadFixtureNames()
+	if err != nil {
+		t.Fatalf("payloadFixtureNames() error = %v", err)
+	}
+	previews, err := previewNotifications()
+	if err != nil {
+		t.Fatalf("previewNotifications() error = %v", err)
+	}
+	if len(previews) != len(names) {
+		t.Fatalf("previewNotifications() = %d previews, want %d", len(previews), len(names))
+	}
+	for _, p := range previews {
+		if p.Backend != "none" {
+			t.Fatalf("preview %s backend = %q, want none with no notifier on PATH", p.Fixture, p.Backend)
+		}
+		if len(p.Notifications) == 0 || p.Notifications[0].Title == "" {
+			t.Fatalf("preview %s has no rendered notification: %+v", p.Fixture, p)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// routeSinkNames are the sink names a rule can list, besides
+// "webhooks.<name>" for one webhook and "all" for every configured sink.
+var routeSinkNames = []string{"desktop", "ntfy", "slack", "pushover", "bark", "pagerduty", "oncall", "email", "peer", "webhooks"}
+
+// routingRule is one [rules.<name>] table. Rules are tried in file order
+// and the first whose matchers all hold decides which sinks get the event;
+// the sinks' own events lists are not consulted then. An event no rule
+// matches goes where those lists send it, as without rules.
+type routingRule struct {
+	Name   string
+	Events []string
+	// Thread is a glob on the thread ID.
+	Thread string
+	// Cwd is a glob on the working directory, which also matches anything
+	// below a matching directory.
+	Cwd string
+	// Hours is a local time window like "22:00-07:00"; it may wrap past
+	// midnight.
+	Hours *clockWindow
+	// ScreenShared, when set, matches only while the screen is (or is
+	// not) being shared.
+	ScreenShared *bool
+	Sinks        []string
+}
+
+// clockWindow holds minutes after midnight; Start == End is all day.
+type clockWindow struct {
+	Start, End int
+}
+
+func (w clockWindow) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.Start <= w.End {
+		return w.Start == w.End || m >= w.Start && m < w.End
+	}
+	return m >= w.Start || m < w.End
+}
+
+func (w clockWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+}
+
+func parseClockWindow(s string) (clockWindow, error) {
+	start, end, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return clockWindow{}, errors.New("must look like 22:00-07:00")
+	}
+	var w clockWindow
+	for i, part := range []string{start, end} {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return clockWindow{}, errors.New("must look like 22:00-07:00")
+		}
+		if i == 0 {
+			w.Start = t.Hour()*60 + t.Minute()
+		} else {
+			w.End = t.Hour()*60 + t.Minute()
+		}
+	}
+	return w, nil
+}
+
+// setRule parses one `rules.<name>.<key>` entry.
+func (cfg *userConfig) setRule(key string, value any) error {
+	return setNamedTable("rules", key, value, &cfg.Rules, func(name string) routingRule { return routingRule{Name: name} })
+}
+
+func (r routingRule) tableName() string {
+	return r.Name
+}
+
+func (r *routingRule) set(key string, value any) error {
+	prefix := "rules." + r.Name + "."
+	switch key {
+	case "events":
+		events, err := parseEventList(prefix+"events", value)
+		if err != nil {
+			return err
+		}
+		r.Events = events
+		return nil
+	case "screen_shared":
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("%sscreen_shared must be true or false", prefix)
+		}
+		r.ScreenShared = &b
+		return nil
+	case "sinks":
+		// An empty list is allowed: it drops the event everywhere.
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%ssinks must be an array of sink names", prefix)
+		}
+		r.Sinks = []string{}
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("%ssinks entries must be strings", prefix)
+			}
+			r.Sinks = append(r.Sinks, strings.TrimSpace(s))
+		}
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%s%s must be a string", prefix, key)
+	}
+	s = strings.TrimSpace(s)
+	switch key {
+	case "thread":
+		if _, err := path.Match(s, ""); err != nil {
+			return fmt.Errorf("%sthread: %w", prefix, err)
+		}
+		r.Thread = s
+	case "cwd":
+		expanded, err := expandUserPath(s)
+		if err != nil {
+			return fmt.Errorf("%scwd: %w", prefix, err)
+		}
+		if _, err := filepath.Match(expanded, ""); err != nil {
+			return fmt.Errorf("%scwd: %w", prefix, err)
+		}
+		r.Cwd = filepath.Clean(expanded)
+	case "hours":
+		w, err := parseClockWindow(s)
+		if err != nil {
+			return fmt.Errorf("%shours %w", prefix, err)
+		}
+		r.Hours = &w
+	default:
+		return unknownSetting(prefix + key)
+	}
+	return nil
+}
+
+// validateRules checks that every rule says where its events go and only
+// names sinks that exist in the file.
+func validateRules(cfg userConfig) error {
+	for _, r := range cfg.Rules {
+		if r.Sinks == nil {
+			return fmt.Errorf("rules.%s.sinks is required", r.Name)
+		}
+		for _, sink := range r.Sinks {
+			if sink == "all" || containsString(routeSinkNames, sink) {
+				continue
+			}
+			if name, ok := strings.CutPrefix(sink, "webhooks."); ok && cfg.webhook(name) {
+				continue
+			}
+			return fmt.Errorf("rules.%s.sinks: unknown sink %q", r.Name, sink)
+		}
+	}
+	return nil
+}
+
+func (cfg userConfig) webhook(name string) bool {
+	for _, hook := range cfg.Webhooks {
+		if hook.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r routingRule) matches(payload map[string]any, now time.Time) bool {
+	if r.Events != nil {
+		event := payloadEventName(payload)
+		if !containsString(r.Events, event) && !containsString(r.Events, "all") {
+			return false
+		}
+	}
+	if r.Thread != "" {
+		if ok, _ := path.Match(r.Thread, payloadThreadID(payload)); !ok {
+			return false
+		}
+	}
+	if r.Cwd != "" && !cwdMatches(r.Cwd, payloadCwd(payload)) {
+		return false
+	}
+	if r.Hours != nil && !r.Hours.contains(now) {
+		return false
+	}
+	if r.ScreenShared != nil {
+		if _, sharing := screenSharing(now); sharing != *r.ScreenShared {
+			return false
+		}
+	}
+	return true
+}
+
+// cwdMatches reports whether cwd, or a directory above it, matches pattern.
+func cwdMatches(pattern, cwd string) bool {
+	if cwd == "" {
+		return false
+	}
+	for dir := filepath.Clean(cwd); ; dir = filepath.Dir(dir) {
+		if ok, _ := filepath.Match(pattern, dir); ok {
+			return true
+		}
+		if dir == filepath.Dir(dir) {
+			return false
+		}
+	}
+}
+
+// eventRoute is the first rule matching payload, if any.
+func (cfg userConfig) eventRoute(payload map[string]any, now time.Time) (routingRule, bool) {
+	for _, r := range cfg.Rules {
+		if r.matches(payload, now) {
+			return r, true
+		}
+	}
+	return routingRule{}, false
+}
+
+// routes reports whether the rule sends its events to sink. A webhook is
+// "webhooks.<name>", which "webhooks" also covers.
+func (r routingRule) routes(sink string) bool {
+	for _, s := range r.Sinks {
+		if s == "all" || s == sink || s == "webhooks" && strings.HasPrefix(sink, "webhooks.") {
+			return true
+		}
+	}
+	return false
+}
+
+// routesToDesktop reports whether the desktop notification should be shown.
+// A config that fails to load never hides it.
+func routesToDesktop(payload map[string]any, now time.Time) (bool, string) {
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return true, ""
+	}
+	rule, ok := cfg.eventRoute(payload, now)
+	if !ok || rule.routes("desktop") {
+		return true, rule.Name
+	}
+	return false, rule.Name
+}
+
+// sinkEnabled reports whether a sink name in a rule is configured.
+func (cfg userConfig) sinkEnabled(sink string) bool {
+	switch sink {
+	case "all", "desktop":
+		return true
+	case "ntfy":
+		return cfg.Ntfy.enabled()
+	case "slack":
+		return cfg.Slack.enabled()
+	case "pushover":
+		return cfg.Pushover.enabled()
+	case "bark":
+		return cfg.Bark.enabled()
+	case "pagerduty":
+		return cfg.PagerDuty.enabled()
+	case "oncall":
+		return cfg.OnCall.enabled()
+	case "email":
+		return cfg.Email.enabled()
+	case "peer":
+		return cfg.Peer.enabled()
+	case "webhooks":
+		return len(cfg.Webhooks) > 0
+	}
+	name, _ := strings.CutPrefix(sink, "webhooks.")
+	return cfg.webhook(name)
+}
+
+func (r routingRule) describe() string {
+	var match []string
+	if r.Events != nil {
+		match = append(match, strings.Join(r.Events, ", "))
+	}
+	if r.Thread != "" {
+		match = append(match, "thread "+r.Thread)
+	}
+	if r.Cwd != "" {
+		match = append(match, "in "+r.Cwd)
+	}
+	if r.Hours != nil {
+		match = append(match, r.Hours.String())
+	}
+	if r.ScreenShared != nil {
+		if *r.ScreenShared {
+			match = append(match, "while sharing the screen")
+		} else {
+			match = append(match, "while not sharing the screen")
+		}
+	}
+	if len(match) == 0 {
+		match = append(match, "every event")
+	}
+	sinks := "nowhere"
+	if len(r.Sinks) > 0 {
+		sinks = strings.Join(r.Sinks, ", ")
+	}
+	return strings.Join(match, "; ") + " → " + sinks
+}
+
+// addRuleDoctorChecks lists the rules in order and flags sinks they name
+// that are not configured, which would silently drop those events.
+func addRuleDoctorChecks(report *doctorReport, cfg userConfig) {
+	for _, r := range cfg.Rules {
+		var missing []string
+		for _, sink := range r.Sinks {
+			if !cfg.sinkEnabled(sink) {
+				missing = append(missing, sink)
+			}
+		}
+		if len(missing) > 0 {
+			report.add(doctorSettings, checkWarn, "rule "+r.Name, r.describe()+" ("+strings.Join(missing, ", ")+" not configured)", false)
+			continue
+		}
+		report.add(doctorSettings, checkOK, "rule "+r.Name, r.describe(), false)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRoutingRulesPickSinks(t *testing.T) {
+	t.Setenv(ntfyTokenEnv, "")
+	t.Setenv(pagerDutyKeyEnv, "")
+	cfg, err := parseUserConfig([]byte(`
+[ntfy]
+topic = "runs"
+[pagerduty]
+routing_key = "R"
+[webhooks.log]
+url = "https://example.com/log"
+
+[rules.approvals]
+events = ["approval-requested"]
+sinks = ["desktop", "ntfy"]
+
+[rules.errors]
+events = ["agent-error"]
+sinks = ["all"]
+
+[rules.scratch]
+cwd = "/tmp/*"
+sinks = []
+`))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	names := func(payload map[string]any) []string {
+		var got []string
+		for _, sink := range remoteSinks(cfg, payload) {
+			got = append(got, sink.Name)
+		}
+		return got
+	}
+
+	// The rule wins over the webhook's "every event" default.
+	if got := names(map[string]any{"type": "approval-requested"}); len(got) != 1 || got[0] != "ntfy" {
+		t.Fatalf("approval sinks = %v", got)
+	}
+	// pagerduty only listens for errors, but "all" covers every configured sink.
+	if got := names(map[string]any{"type": "agent-error"}); len(got) != 3 {
+		t.Fatalf("error sinks = %v", got)
+	}
+	if got := names(map[string]any{"type": "agent-turn-complete", "cwd": "/tmp/x/y"}); len(got) != 0 {
+		t.Fatalf("scratch sinks = %v", got)
+	}
+	// No rule matches: each sink's events list applies.
+	if got := names(map[string]any{"type": "agent-turn-complete", "cwd": "/src/app"}); len(got) != 2 || got[0] != "ntfy" || got[1] != "webhook log" {
+		t.Fatalf("unrouted sinks = %v", got)
+	}
+}
+
+func TestRoutingRuleMatchers(t *testing.T) {
+	cfg, err := parseUserConfig([]byte("[rules.night]\nthread = \"batch-*\"\nhours = \"22:00-07:00\"\nsinks = [\"email\"]\n"))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	rule := cfg.Rules[0]
+	batch := map[string]any{"type": "agent-error", "thread-id": "batch-42"}
+	for _, tc := range []struct {
+		payload map[string]any
+		hour    int
+		want    bool
+	}{
+		{batch, 23, true},
+		{batch, 3, true},
+		{batch, 7, false},
+		{batch, 12, false},
+		{map[string]any{"thread-id": "chat-1"}, 23, false},
+	} {
+		now := time.Date(2026, 3, 1, tc.hour, 0, 0, 0, time.Local)
+		if got := rule.matches(tc.payload, now); got != tc.want {
+			t.Errorf("matches(%v at %02d:00) = %v, want %v", tc.payload, tc.hour, got, tc.want)
+		}
+	}
+	if !rule.routes("email") || rule.routes("desktop") {
+		t.Fatalf("routes: %+v", rule.Sinks)
+	}
+
+	if !cwdMatches(filepath.Clean("/src/*"), "/src/app/pkg") || cwdMatches("/src/*", "/other/app") {
+		t.Fatal("cwdMatches mismatch")
+	}
+
+	for _, bad := range []string{
+		"[rules.a]\nevents = [\"agent-error\"]\n",
+		"[rules.a]\nsinks = [\"fax\"]\n",
+		"[rules.a]\nsinks = [\"webhooks.missing\"]\n",
+		"[rules.a]\nhours = \"late\"\nsinks = []\n",
+		"[rules.a]\nthread = \"[\"\nsinks = []\n",
+	} {
+		if _, err := parseUserConfig([]byte(bad)); err == nil {
+			t.Errorf("parseUserConfig(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestRoutingRuleKeepsEventOffDesktop(t *testing.T) {
+	home, dir := useTempHome(t)
+	path := filepath.Join(home, "config.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", path)
+	if err := os.WriteFile(path, []byte("[rules.quiet]\nevents = [\"agent-turn-complete\"]\nsinks = []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := deliverHookPayload(map[string]any{"type": "agent-turn-complete", "thread-id": "t1"})
+	if err != nil || result.Status != "routed" || result.Rule != "quiet" {
+		t.Fatalf("deliverHookPayload() = %+v, %v", result, err)
+	}
+	if _, err := deliverHookPayload(map[string]any{"type": "agent-error", "thread-id": "t1"}); err != nil {
+		t.Fatal(err)
+	}
+	if captured := readCaptured(t, dir); len(captured) != 1 {
+		t.Fatalf("captured = %+v, want only the error", captured)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var errSandboxKeystrokes = errors.New("key injection is disabled in sandbox mode (CODEX_NOTIFY_SANDBOX)")
+
+// sandboxModeEnabled reports whether codex-notify must avoid osascript and
+// System Events entirely, for machines where MDM/TCC policy blocks Apple
+// Events. Notifications then go through the popup helper or
+// terminal-notifier, Open uses LaunchServices, and no keystrokes are sent.
+func sandboxModeEnabled() bool {
+	v := strings.TrimSpace(strings.ToLower(getenv("CODEX_NOTIFY_SANDBOX")))
+	return v == "1" || v == "true" || v == "yes" || v == "on"
+}
+
+// activateWithLaunchServices brings the terminal forward via `open -b`, which
+// does not send Apple Events and needs no Automation permission.
+func activateWithLaunchServices(bundleID string) error {
+	path, ok := lookupCmd("open")
+	if !ok {
+		return errors.New("open not found")
+	}
+	if out, err := combinedOutputLogged(exec.Command(path, "-b", bundleID)); err != nil {
+		return fmt.Errorf("activate app failed: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// probeSystemEvents runs a harmless System Events query so doctor can tell
+// whether Apple Events are blocked. Tests answer it with each osascript
+// error doctor knows.
+var probeSystemEvents = func(osascriptPath string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := combinedOutputLogged(exec.CommandContext(ctx, osascriptPath, "-e", `tell application "System Events" to count processes`))
+	return string(out), err
+}
+
+// sandboxModeRequired interprets a System Events probe: a permission denial
+// or a probe that hangs until the timeout both mean the user should enable
+// sandbox mode.
+func sandboxModeRequired(output string, err error) bool {
+	if err == nil {
+		return false
+	}
+	return isAppleEventsPermissionDenied(output) || errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "signal: killed")
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSandboxModeDisablesKeystrokes(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_SANDBOX", "1")
+	t.Setenv("CODEX_NOTIFY_ENABLE_APPROVAL_ACTIONS", "1")
+
+	if approvalActionsEnabled() {
+		t.Fatalf("approvalActionsEnabled() = true in sandbox mode, want false")
+	}
+
+	err := sendKeySequence([]string{"y", "enter"}, "")
+	if !errors.Is(err, errSandboxKeystrokes) {
+		t.Fatalf("sendKeySequence() error = %v, want errSandboxKeystrokes", err)
+	}
+	if got := exitCodeFor(err); got != exitPermission {
+		t.Fatalf("exitCodeFor() = %d, want %d", got, exitPermission)
+	}
+}
+
+func TestSandboxModeRequired(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		err    error
+		want   bool
+	}{
+		{name: "allowed", output: "312\n", err: nil, want: false},
+		{name: "denied", output: "execution error: Not authorized to send Apple events to System Events. (-1743)", err: errors.New("exit status 1"), want: true},
+		{name: "timeout", err: context.DeadlineExceeded, want: true},
+		{name: "other failure", output: "syntax error", err: errors.New("exit status 1"), want: false},
+	}
+	for _, tt := range tests {
+		if got := sandboxModeRequired(tt.output, tt.err); got != tt.want {
+			t.Fatalf("%s: sandboxModeRequired() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
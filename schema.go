@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// schemaVersion is the version of the embedded schema. Adding an optional
+// field keeps it; removing or retyping one raises it.
+const schemaVersion = 1
+
+//go:embed internal/schema/codex-notify.schema.json
+var schemaDocument []byte
+
+var (
+	schemaOnce sync.Once
+	schemaDefs map[string]any
+)
+
+// schemaDef returns one of the schema's $defs.
+func schemaDef(name string) (map[string]any, bool) {
+	schemaOnce.Do(func() {
+		var doc struct {
+			Defs map[string]any `json:"$defs"`
+		}
+		if err := json.Unmarshal(schemaDocument, &doc); err != nil {
+			panic(fmt.Sprintf("embedded schema: %v", err))
+		}
+		schemaDefs = doc.Defs
+	})
+	def, ok := schemaDefs[name].(map[string]any)
+	return def, ok
+}
+
+// validateSchema checks data against the named definition. It supports the
+// keywords the embedded schema uses: type, properties, required,
+// additionalProperties, items, minimum, enum, and local $refs.
+func validateSchema(name string, data []byte) error {
+	def, ok := schemaDef(name)
+	if !ok {
+		return fmt.Errorf("schema has no definition %q", name)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := validateValue(def, value, name); err != nil {
+		return fmt.Errorf("does not match schema v%d: %w", schemaVersion, err)
+	}
+	return nil
+}
+
+func validateValue(def map[string]any, value any, path string) error {
+	if ref, ok := def["$ref"].(string); ok {
+		name, found := strings.CutPrefix(ref, "#/$defs/")
+		target, ok := schemaDef(name)
+		if !found || !ok {
+			return fmt.Errorf("%s: unknown $ref %s", path, ref)
+		}
+		return validateValue(target, value, path)
+	}
+	if types := schemaTypes(def["type"]); len(types) > 0 && !matchesSchemaType(types, value) {
+		return fmt.Errorf("%s: is %s, want %s", path, jsonTypeName(value), strings.Join(types, " or "))
+	}
+	if choices, ok := def["enum"].([]any); ok {
+		matched := false
+		for _, choice := range choices {
+			if fmt.Sprint(choice) == fmt.Sprint(value) {
+				matched = true
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: %v is not one of %v", path, value, choices)
+		}
+	}
+	if minimum, ok := def["minimum"].(float64); ok {
+		if n, isNumber := value.(json.Number); isNumber {
+			if f, err := n.Float64(); err == nil && f < minimum {
+				return fmt.Errorf("%s: %v is below %v", path, n, minimum)
+			}
+		}
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		return validateObject(def, v, path)
+	case []any:
+		if items, ok := def["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateValue(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateObject(def map[string]any, object map[string]any, path string) error {
+	if required, ok := def["required"].([]any); ok {
+		for _, key := range required {
+			if _, present := object[key.(string)]; !present {
+				return fmt.Errorf("%s: missing %s", path, key)
+			}
+		}
+	}
+	properties, _ := def["properties"].(map[string]any)
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if prop, ok := properties[key].(map[string]any); ok {
+			if err := validateValue(prop, object[key], path+"."+key); err != nil {
+				return err
+			}
+			continue
+		}
+		switch extra := def["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				return fmt.Errorf("%s: unknown field %s", path, key)
+			}
+		case map[string]any:
+			if err := validateValue(extra, object[key], path+"."+key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func schemaTypes(raw any) []string {
+	switch t := raw.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var types []string
+		for _, name := range t {
+			types = append(types, fmt.Sprint(name))
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesSchemaType(types []string, value any) bool {
+	name := jsonTypeName(value)
+	for _, t := range types {
+		if t == name || (t == "number" && name == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTypeName(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) && !strings.ContainsAny(v.String(), ".eE") {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// validateDaemonRequest checks a forwarded hook, and the payload it
+// carries, before the daemon acts on it.
+func validateDaemonRequest(data []byte) (daemonRequest, error) {
+	var req daemonRequest
+	if err := validateSchema("daemon_request", data); err != nil {
+		return req, err
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, err
+	}
+	if req.Schema > schemaVersion {
+		return req, fmt.Errorf("schema v%d is newer than this daemon's v%d; restart the daemon after upgrading", req.Schema, schemaVersion)
+	}
+	if strings.TrimSpace(req.Payload) != "" {
+		if err := validateSchema("payload", []byte(req.Payload)); err != nil {
+			return req, fmt.Errorf("payload %w", err)
+		}
+	}
+	return req, nil
+}
+
+// runSchema prints the embedded schema, the contract for the event log,
+// capture files, --json results, and the daemon socket.
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	version := fs.Bool("version", false, "print only the schema version")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(errors.New("schema takes no arguments"))
+	}
+	if *version {
+		fmt.Println(schemaVersion)
+		return nil
+	}
+	_, err := os.Stdout.Write(schemaDocument)
+	return err
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// jsonFieldNames lists the JSON names of a struct's fields, following
+// embedded structs the way encoding/json does.
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			names = append(names, jsonFieldNames(field.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSchemaMatchesGoTypes(t *testing.T) {
+	for name, value := range map[string]any{
+		"event":           eventRecord{},
+		"notification":    capturedNotification{},
+		"choice":          approvalChoice{},
+		"command_result":  commandResult{},
+		"daemon_request":  daemonRequest{},
+		"daemon_response": daemonResponse{},
+	} {
+		def, ok := schemaDef(name)
+		if !ok {
+			t.Fatalf("schema has no %s definition", name)
+		}
+		properties, _ := def["properties"].(map[string]any)
+		var documented []string
+		for key := range properties {
+			documented = append(documented, key)
+		}
+		sort.Strings(documented)
+		if fields := jsonFieldNames(reflect.TypeOf(value)); !reflect.DeepEqual(fields, documented) {
+			t.Errorf("%s: schema properties = %v, Go fields = %v", name, documented, fields)
+		}
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	event, _ := json.Marshal(eventRecord{Time: "2026-10-14T09:12:03.418000000Z", Event: "approval-requested", Thread: "t1", Status: "sent"})
+	result, _ := json.Marshal(daemonResponse{Schema: schemaVersion, Result: &commandResult{Command: "hook", Status: "sent", Count: 1}})
+	for name, data := range map[string]string{
+		"event":           string(event),
+		"daemon_response": string(result),
+		"notification":    `{"title":"Codex","message":"m","group":"g","choices":[{"label":"Approve","command":"x"}],"time":"t"}`,
+		"payload":         `{"type":"agent-turn-complete","thread-id":"t1","extra":{"any":[1,2]}}`,
+	} {
+		if err := validateSchema(name, []byte(data)); err != nil {
+			t.Errorf("validateSchema(%s, %s) error = %v", name, data, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name, data, want string
+	}{
+		{"event", `{"time":"t","event":"e"}`, "event: missing status"},
+		{"event", `{"time":"t","event":"e","status":"sent","color":"red"}`, "event: unknown field color"},
+		{"daemon_request", `{"payload":5}`, "daemon_request.payload: is integer, want string"},
+		{"daemon_request", `{"payload":"","env":{"TMUX":1}}`, "daemon_request.env.TMUX: is integer, want string"},
+		{"daemon_response", `{"result":{"command":"hook","status":"sent","count":-1}}`, "daemon_response.result.count: -1 is below 0"},
+		{"notification", `{"title":"","message":"","group":"","time":"","choices":[{"label":"x"}]}`, "notification.choices[0]: missing command"},
+		{"payload", `[1]`, "payload: is array, want object"},
+	} {
+		err := validateSchema(tc.name, []byte(tc.data))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("validateSchema(%s, %s) error = %v, want %q", tc.name, tc.data, err, tc.want)
+		}
+	}
+}
+
+func TestDaemonRejectsRequestsOutsideSchema(t *testing.T) {
+	handled := 0
+	path := startTestDaemon(t, func(_ daemonRequest, reply func(daemonResponse)) {
+		handled++
+		reply(daemonResponse{Result: &commandResult{Command: "hook", Status: "sent"}})
+	})
+
+	for request, want := range map[string]string{
+		`{"payload":"{}","extra":true}`:        "unknown field extra",
+		`{"payload":"[1]"}`:                    "payload does not match schema",
+		`{"schema":99,"payload":"{}"}`:         "schema v99 is newer",
+		`{"payload":"{}","env":{"TMUX":null}}`: "is null, want string",
+	} {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Write([]byte(request + "\n")); err != nil {
+			t.Fatal(err)
+		}
+		var resp daemonResponse
+		if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		if resp.ExitCode != exitUsage || !strings.Contains(resp.Error, want) || resp.Schema != schemaVersion {
+			t.Errorf("request %s: response = %+v, want a usage error with %q", request, resp, want)
+		}
+	}
+	if handled != 0 {
+		t.Fatalf("daemon handled %d invalid requests", handled)
+	}
+
+	if _, forwarded, err := forwardHookToSocket(path, `{"type":"agent-turn-complete"}`); !forwarded || err != nil || handled != 1 {
+		t.Fatalf("forwardHookToSocket() = %v, %v after %d handled, want a valid request through", forwarded, err, handled)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	screenShareOff      = "off"
+	screenShareSuppress = "suppress"
+
+	// screenShareCacheTTL keeps a burst of events, or a daemon handling
+	// them, from listing processes once per notification.
+	screenShareCacheTTL = 5 * time.Second
+	// screenMirrorCacheTTL is the same for system_profiler, which takes
+	// most of a second. Displays are mirrored well before a talk starts.
+	screenMirrorCacheTTL = time.Minute
+)
+
+// defaultScreenShareProcesses are processes that only run while the screen
+// is being shared: Zoom's share host and macOS Screen Sharing / Remote
+// Management serving a viewer.
+var defaultScreenShareProcesses = []string{"CptHost", "screensharingd"}
+
+// screenShareMode is CODEX_NOTIFY_SCREEN_SHARE: "suppress" holds desktop
+// notifications back while sharing. Rules can also match screen_shared.
+func screenShareMode() string {
+	if strings.TrimSpace(strings.ToLower(getenv("CODEX_NOTIFY_SCREEN_SHARE"))) == screenShareSuppress {
+		return screenShareSuppress
+	}
+	return screenShareOff
+}
+
+// screenShareProcesses is CODEX_NOTIFY_SCREEN_SHARE_PROCESSES, a
+// comma-separated list replacing the default process names.
+func screenShareProcesses() []string {
+	raw := strings.TrimSpace(getenv("CODEX_NOTIFY_SCREEN_SHARE_PROCESSES"))
+	if raw == "" {
+		return defaultScreenShareProcesses
+	}
+	var names []string
+	for _, name := range strings.Split(raw, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// readSharingProcess reports which of screenShareProcesses is running. It is
+// a variable so tests can share a screen without starting Zoom.
+var readSharingProcess = func() (string, bool) {
+	if hostOS != "darwin" {
+		return "", false
+	}
+	path, ok := lookupCmd("ps")
+	if !ok {
+		return "", false
+	}
+	out, err := outputLogged(exec.Command(path, "-axco", "comm"))
+	if err != nil {
+		return "", false
+	}
+	return sharingProcess(string(out), screenShareProcesses())
+}
+
+// readDisplaysMirrored reports whether system_profiler lists a mirrored
+// display. Tests stub it, as system_profiler does not exist off macOS.
+var readDisplaysMirrored = func() bool {
+	if hostOS != "darwin" {
+		return false
+	}
+	path, ok := lookupCmd("system_profiler")
+	if !ok {
+		return false
+	}
+	out, err := outputLogged(exec.Command(path, "SPDisplaysDataType"))
+	return err == nil && displaysMirrored(string(out))
+}
+
+// sharingProcess finds the first of names in `ps -axco comm` output.
+func sharingProcess(out string, names []string) (string, bool) {
+	for _, line := range strings.Split(out, "\n") {
+		comm := filepath.Base(strings.TrimSpace(line))
+		for _, name := range names {
+			if comm == name {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// displaysMirrored looks for `Mirror: On` in system_profiler's display
+// report.
+func displaysMirrored(out string) bool {
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if ok && key == "Mirror" && strings.TrimSpace(value) == "On" {
+			return true
+		}
+	}
+	return false
+}
+
+// screenShareSample is the last screen sharing check, kept in state.json so
+// hooks in separate processes share it.
+type screenShareSample struct {
+	// Process is the sharing process found at ProcessCheckedAt, and
+	// Mirrored whether a display was mirrored at MirrorCheckedAt.
+	Process          string `json:"process,omitempty"`
+	ProcessCheckedAt int64  `json:"process_checked_at"`
+	Mirrored         bool   `json:"mirrored,omitempty"`
+	MirrorCheckedAt  int64  `json:"mirror_checked_at"`
+}
+
+// sampleFresh reports whether a check made at unix time at still holds.
+func sampleFresh(at int64, ttl time.Duration, now time.Time) bool {
+	checked := time.Unix(at, 0)
+	return at > 0 && !now.Before(checked) && now.Sub(checked) <= ttl
+}
+
+// screenSharing reports whether the screen is visible to others, and why: a
+// sharing process is running or a display is mirrored. Processes are
+// listed at most every screenShareCacheTTL and displays read at most every
+// screenMirrorCacheTTL, across hooks.
+func screenSharing(now time.Time) (string, bool) {
+	var sample screenShareSample
+	if state, err := loadState(); err == nil && state.ScreenShare != nil {
+		sample = *state.ScreenShare
+	}
+	changed := false
+	if !sampleFresh(sample.ProcessCheckedAt, screenShareCacheTTL, now) {
+		sample.Process, _ = readSharingProcess()
+		sample.ProcessCheckedAt = now.Unix()
+		changed = true
+	}
+	if sample.Process == "" && !sampleFresh(sample.MirrorCheckedAt, screenMirrorCacheTTL, now) {
+		sample.Mirrored = readDisplaysMirrored()
+		sample.MirrorCheckedAt = now.Unix()
+		changed = true
+	}
+	if changed {
+		_ = updateState(func(s *notifyState) { s.ScreenShare = &sample })
+	}
+	switch {
+	case sample.Process != "":
+		return sample.Process + " is running", true
+	case sample.Mirrored:
+		return "a display is mirrored", true
+	}
+	return "", false
+}
+
+func screenShareSuppressActive(now time.Time) bool {
+	if screenShareMode() != screenShareSuppress {
+		return false
+	}
+	_, sharing := screenSharing(now)
+	return sharing
+}
+
+func addScreenShareDoctorCheck(report *doctorReport, cfg userConfig) {
+	usedByRule := false
+	for _, r := range cfg.Rules {
+		usedByRule = usedByRule || r.ScreenShared != nil
+	}
+	if screenShareMode() == screenShareOff && !usedByRule {
+		return
+	}
+	detail := "not sharing now"
+	if reason, sharing := screenSharing(time.Now()); sharing {
+		detail = "sharing now: " + reason
+	}
+	report.add(doctorSettings, checkOK, "screen sharing", fmt.Sprintf("%s (%s; watching %s and mirrored displays)",
+		screenShareMode(), detail, strings.Join(screenShareProcesses(), ", ")), false)
+}
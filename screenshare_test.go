@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScreenShareDetection(t *testing.T) {
+	ps := "COMM\nlaunchd\n/Applications/zoom.us.app/Contents/Frameworks/CptHost.app/Contents/MacOS/CptHost\nGhostty\n"
+	if name, ok := sharingProcess(ps, defaultScreenShareProcesses); !ok || name != "CptHost" {
+		t.Fatalf("sharingProcess() = %q, %v", name, ok)
+	}
+	if _, ok := sharingProcess("launchd\nGhostty\n", defaultScreenShareProcesses); ok {
+		t.Fatal("sharingProcess() matched without a sharing process")
+	}
+	if !displaysMirrored("      Color LCD:\n          Mirror: On\n") || displaysMirrored("          Mirror: Off\n") {
+		t.Fatal("displaysMirrored() misread the report")
+	}
+	t.Setenv("CODEX_NOTIFY_SCREEN_SHARE_PROCESSES", "Webex, Teams")
+	if got := screenShareProcesses(); len(got) != 2 || got[1] != "Teams" {
+		t.Fatalf("screenShareProcesses() = %q", got)
+	}
+}
+
+func TestScreenShareGating(t *testing.T) {
+	useTempHome(t)
+	sharing, mirrorReads := true, 0
+	stub(t, &readSharingProcess, func() (string, bool) {
+		if sharing {
+			return "CptHost", true
+		}
+		return "", false
+	})
+	stub(t, &readDisplaysMirrored, func() bool { mirrorReads++; return false })
+
+	t.Setenv("CODEX_NOTIFY_SCREEN_SHARE", "suppress")
+	if result, err := deliverHookPayload(map[string]any{"type": "agent-turn-complete", "thread-id": "t1", "turn-id": "1"}); err != nil || result.Status != "sharing" {
+		t.Fatalf("deliverHookPayload() while sharing = %+v, %v", result, err)
+	}
+
+	t.Setenv("CODEX_NOTIFY_SCREEN_SHARE", "")
+	cfg, err := parseUserConfig([]byte("[rules.meeting]\nscreen_shared = true\nsinks = [\"ntfy\"]\n"))
+	if err != nil {
+		t.Fatalf("parseUserConfig(): %v", err)
+	}
+	payload := map[string]any{"type": "agent-turn-complete"}
+	if rule, ok := cfg.eventRoute(payload, time.Now()); !ok || rule.routes("desktop") {
+		t.Fatalf("eventRoute() while sharing = %+v, %v", rule, ok)
+	}
+	// Another hook within the TTL reuses the check from state.json.
+	sharing = false
+	if _, ok := screenSharing(time.Now()); !ok {
+		t.Fatal("screenSharing() probed again within the TTL")
+	}
+	later := time.Now().Add(2 * screenShareCacheTTL)
+	if _, ok := cfg.eventRoute(payload, later); ok {
+		t.Fatal("screen_shared rule matched while not sharing")
+	}
+	if _, ok := screenSharing(later.Add(2 * screenShareCacheTTL)); ok || mirrorReads != 1 {
+		t.Fatalf("system_profiler read %d times within its TTL", mirrorReads)
+	}
+	if _, err := parseUserConfig([]byte("[rules.meeting]\nscreen_shared = \"yes\"\nsinks = []\n")); err == nil {
+		t.Fatal("parseUserConfig() accepted a string screen_shared")
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	scriptAppleScript = "applescript"
+	scriptJXA         = "jxa"
+
+	// clickScriptPrefix marks a [click] value that runs a [scripts] entry.
+	clickScriptPrefix = "script:"
+)
+
+// scriptVariables are the placeholders a script may use. Each is replaced
+// by a string literal in the script's language, never spliced as code, so
+// a directory or thread id cannot break out of it.
+var scriptVariables = []string{"cwd", "thread_id", "event", "project"}
+
+// scriptAction is one [scripts.<name>] table: an AppleScript or JXA
+// snippet run by osascript when a notification using it is clicked.
+type scriptAction struct {
+	Name     string
+	Language string
+	// Source is the snippet itself, or the contents of File.
+	Source string
+	File   string
+}
+
+// setScript parses one `scripts.<name>.<key>` entry.
+func (cfg *userConfig) setScript(key string, value any) error {
+	return setNamedTable("scripts", key, value, &cfg.Scripts, func(name string) scriptAction { return scriptAction{Name: name} })
+}
+
+func (s scriptAction) tableName() string {
+	return s.Name
+}
+
+func (s *scriptAction) set(key string, value any) error {
+	prefix := "scripts." + s.Name + "."
+	v, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%s%s must be a string", prefix, key)
+	}
+	switch key {
+	case "language":
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v != scriptAppleScript && v != scriptJXA {
+			return fmt.Errorf("%slanguage must be %s or %s", prefix, scriptAppleScript, scriptJXA)
+		}
+		s.Language = v
+	case "source":
+		s.Source = v
+	case "file":
+		path, err := expandUserPath(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("%sfile: %w", prefix, err)
+		}
+		s.File = path
+	default:
+		return unknownSetting(prefix + key)
+	}
+	return nil
+}
+
+// validateScripts checks each script has exactly one body and that every
+// script: click value names a script.
+func validateScripts(cfg userConfig) error {
+	for _, s := range cfg.Scripts {
+		if (s.Source == "") == (s.File == "") {
+			return fmt.Errorf("scripts.%s needs either source or file", s.Name)
+		}
+	}
+	for event, action := range cfg.Click {
+		if name, ok := strings.CutPrefix(action, clickScriptPrefix); ok {
+			if _, found := cfg.script(name); !found {
+				return fmt.Errorf("click.%s: unknown script %q", event, name)
+			}
+		}
+	}
+	return nil
+}
+
+func (c userConfig) script(name string) (scriptAction, bool) {
+	for _, s := range c.Scripts {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return scriptAction{}, false
+}
+
+// language defaults to AppleScript, or JXA for a .js file.
+func (s scriptAction) language() string {
+	switch {
+	case s.Language != "":
+		return s.Language
+	case strings.HasSuffix(s.File, ".js"):
+		return scriptJXA
+	}
+	return scriptAppleScript
+}
+
+// scriptLiteral quotes value as a string literal of language.
+func scriptLiteral(language, value string) string {
+	if language == scriptJXA {
+		quoted, _ := json.Marshal(value)
+		return string(quoted)
+	}
+	// AppleScript strings have no escape for newlines; keep them as the
+	// linefeed constant so the literal stays on one line.
+	parts := strings.Split(value, "\n")
+	for i, part := range parts {
+		parts[i] = `"` + escapeAppleScript(part) + `"`
+	}
+	return strings.Join(parts, " & linefeed & ")
+}
+
+// render loads the script body and substitutes the variables.
+func (s scriptAction) render(vars map[string]string) (string, error) {
+	source := s.Source
+	if s.File != "" {
+		content, err := os.ReadFile(s.File)
+		if err != nil {
+			return "", configError(fmt.Errorf("scripts.%s: %w", s.Name, err))
+		}
+		source = string(content)
+	}
+	pairs := make([]string, 0, 2*len(scriptVariables))
+	for _, name := range scriptVariables {
+		pairs = append(pairs, "{"+name+"}", scriptLiteral(s.language(), vars[name]))
+	}
+	return strings.NewReplacer(pairs...).Replace(source), nil
+}
+
+// buildScriptActionCommand is the click command for a script: the script
+// is looked up by name when clicked, so an edit applies to notifications
+// already on screen.
+func buildScriptActionCommand(name string, payload map[string]any) string {
+	parts := []string{buildActionCommand("script", payloadThreadID(payload)), "--script", shellQuote(name)}
+	if cwd := payloadCwd(payload); cwd != "" {
+		parts = append(parts, "--cwd", shellQuote(cwd))
+	}
+	if event := payloadEventName(payload); event != "" {
+		parts = append(parts, "--event", shellQuote(event))
+	}
+	return strings.Join(parts, " ")
+}
+
+// runScriptAction runs the named script through osascript.
+func runScriptAction(name, threadID, cwd, event string) error {
+	if strings.TrimSpace(name) == "" {
+		return usageError(errors.New("script action requires --script"))
+	}
+	if hostOS != "darwin" {
+		return errors.New("script actions need macOS (osascript)")
+	}
+	if sandboxModeEnabled() {
+		return permissionError(errors.New("sandbox mode: script actions need osascript"))
+	}
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return configError(err)
+	}
+	s, ok := cfg.script(name)
+	if !ok {
+		return configError(fmt.Errorf("unknown script: %s", name))
+	}
+	source, err := s.render(map[string]string{
+		"cwd":       cwd,
+		"thread_id": threadID,
+		"event":     event,
+		"project":   projectName(cwd),
+	})
+	if err != nil {
+		return err
+	}
+
+	path, ok := lookupCmd("osascript")
+	if !ok {
+		return errors.New("osascript not found")
+	}
+	args := []string{"-e", source}
+	if s.language() == scriptJXA {
+		args = append([]string{"-l", "JavaScript"}, args...)
+	}
+	if out, err := combinedOutputLogged(exec.Command(path, args...)); err != nil {
+		err = fmt.Errorf("script %s failed: %w (%s)", name, err, strings.TrimSpace(string(out)))
+		if isAppleEventsPermissionDenied(string(out)) {
+			return applePermissionError(err, string(out))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScriptLiteral(t *testing.T) {
+	evil := `/tmp/a" & (do shell script "rm -rf ~") & "`
+	if got, want := scriptLiteral(scriptAppleScript, evil), `"/tmp/a\" & (do shell script \"rm -rf ~\") & \""`; got != want {
+		t.Fatalf("applescript = %s, want %s", got, want)
+	}
+	if got, want := scriptLiteral(scriptAppleScript, "a\nb"), `"a" & linefeed & "b"`; got != want {
+		t.Fatalf("applescript newline = %s, want %s", got, want)
+	}
+	if got, want := scriptLiteral(scriptJXA, "it's \"x\"\n"), `"it's \"x\"\n"`; got != want {
+		t.Fatalf("jxa = %s, want %s", got, want)
+	}
+}
+
+func TestScriptConfig(t *testing.T) {
+	cfg, err := parseUserConfig([]byte(`[click]
+agent-turn-complete = "script:tile"
+
+[scripts.tile]
+source = "display dialog {project} & \" in \" & {cwd}"
+
+[scripts.lights]
+file = "/tmp/lights.js"
+`))
+	if err != nil {
+		t.Fatalf("parseUserConfig(): %v", err)
+	}
+	tile, ok := cfg.script("tile")
+	if !ok || tile.language() != scriptAppleScript {
+		t.Fatalf("tile = %+v", tile)
+	}
+	if lights, _ := cfg.script("lights"); lights.language() != scriptJXA {
+		t.Fatalf("lights language = %q, want jxa from .js", lights.language())
+	}
+	got, err := tile.render(map[string]string{"cwd": "/src/app", "project": "app"})
+	if err != nil || got != `display dialog "app" & " in " & "/src/app"` {
+		t.Fatalf("render() = %q, %v", got, err)
+	}
+
+	payload := map[string]any{"type": "agent-turn-complete", "thread-id": "t1", "cwd": "/src/app"}
+	command := buildClickCommand("script:tile", payload)
+	if want := "action 'script' --thread-id 't1' --script 'tile' --cwd '/src/app' --event 'agent-turn-complete'"; !strings.HasSuffix(command, want) {
+		t.Fatalf("click command = %q, want suffix %q", command, want)
+	}
+	if clickActionLabel("script:tile") != "Run" {
+		t.Fatal("script click label is not Run")
+	}
+
+	for _, bad := range []string{
+		"[click]\ndefault = \"script:missing\"\n",
+		"[scripts.x]\nlanguage = \"python\"\nsource = \"1\"\n",
+		"[scripts.x]\nlanguage = \"jxa\"\n",
+	} {
+		if _, err := parseUserConfig([]byte(bad)); err == nil {
+			t.Errorf("parseUserConfig(%q) accepted", bad)
+		}
+	}
+}
+
+func TestRunScriptAction(t *testing.T) {
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	fake := "#!/bin/sh\nfor a in \"$@\"; do printf '%s\\n' \"$a\"; done > " + argsFile + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "osascript"), []byte(fake), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	t.Setenv("CODEX_NOTIFY_SANDBOX", "")
+	cfgPath := filepath.Join(dir, "config.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", cfgPath)
+	if err := os.WriteFile(cfgPath, []byte("[scripts.say]\nlanguage = \"jxa\"\nsource = \"Application('Finder').say({thread_id})\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runScriptAction("say", "t'1", "", ""); err != nil {
+		t.Fatalf("runScriptAction(): %v", err)
+	}
+	got, _ := os.ReadFile(argsFile)
+	if want := "-l\nJavaScript\n-e\nApplication('Finder').say(\"t'1\")\n"; string(got) != want {
+		t.Fatalf("osascript args = %q, want %q", got, want)
+	}
+	if err := runScriptAction("nope", "", "", ""); exitCodeFor(err) != exitConfig {
+		t.Fatalf("unknown script: %v", err)
+	}
+}
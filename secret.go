@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretRefPrefix marks a config value that names a stored secret instead
+// of holding it: token = "secret:ntfy-token".
+const secretRefPrefix = "secret:"
+
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// secretStore keeps sink credentials out of config.toml: the login
+// Keychain on macOS and the Secret Service (libsecret) on Linux.
+type secretStore interface {
+	Name() string
+	Set(name, value string) error
+	Get(name string) (string, error)
+	Delete(name string) error
+}
+
+// errSecretNotFound is returned by Get and Delete for an unknown name.
+var errSecretNotFound = errors.New("no such secret")
+
+// defaultSecretStore picks the OS keyring: the Keychain on macOS, libsecret
+// on linux. Tests put an in-memory store in its place.
+var defaultSecretStore = func() (secretStore, error) {
+	switch hostOS {
+	case "darwin":
+		path, ok := lookupCmd("security")
+		if !ok {
+			return nil, errors.New("security not found")
+		}
+		return keychainStore{security: path}, nil
+	case "linux":
+		path, ok := lookupCmd("secret-tool")
+		if !ok {
+			return nil, errors.New("secret-tool not found (install libsecret-tools)")
+		}
+		return libsecretStore{secretTool: path}, nil
+	}
+	return nil, fmt.Errorf("no secret store on %s", hostOS)
+}
+
+// keychainStore keeps secrets as generic passwords with service
+// codex-notify and the secret name as the account.
+type keychainStore struct {
+	security string
+}
+
+func (keychainStore) Name() string { return "Keychain" }
+
+// Set feeds the command to `security -i` on stdin, with the value in hex,
+// so it never appears in argv.
+func (k keychainStore) Set(name, value string) error {
+	cmd := exec.Command(k.security, "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -l %s -X %s\n", appName, name, appName+"-"+name, hex.EncodeToString([]byte(value))))
+	// `security -i` exits 0 even when a command fails; it is silent on success.
+	if out, err := combinedOutputLogged(cmd); err != nil || strings.TrimSpace(string(out)) != "" {
+		return fmt.Errorf("security add-generic-password failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (k keychainStore) Get(name string) (string, error) {
+	out, err := outputLogged(exec.Command(k.security, "find-generic-password", "-s", appName, "-a", name, "-w"))
+	if err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && exit.ExitCode() == 44 {
+			return "", errSecretNotFound
+		}
+		return "", fmt.Errorf("security find-generic-password: %w", err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (k keychainStore) Delete(name string) error {
+	out, err := combinedOutputLogged(exec.Command(k.security, "delete-generic-password", "-s", appName, "-a", name))
+	if err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && exit.ExitCode() == 44 {
+			return errSecretNotFound
+		}
+		return fmt.Errorf("security delete-generic-password: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// libsecretStore uses secret-tool, which reads the value from stdin.
+type libsecretStore struct {
+	secretTool string
+}
+
+func (libsecretStore) Name() string { return "Secret Service" }
+
+func (l libsecretStore) Set(name, value string) error {
+	cmd := exec.Command(l.secretTool, "store", "--label", appName+": "+name, "service", appName, "account", name)
+	cmd.Stdin = strings.NewReader(value)
+	if out, err := combinedOutputLogged(cmd); err != nil {
+		return fmt.Errorf("secret-tool store: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (l libsecretStore) Get(name string) (string, error) {
+	out, err := outputLogged(exec.Command(l.secretTool, "lookup", "service", appName, "account", name))
+	if err != nil {
+		// lookup exits 1 with no output for a missing item.
+		return "", errSecretNotFound
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (l libsecretStore) Delete(name string) error {
+	if _, err := l.Get(name); err != nil {
+		return err
+	}
+	if out, err := combinedOutputLogged(exec.Command(l.secretTool, "clear", "service", appName, "account", name)); err != nil {
+		return fmt.Errorf("secret-tool clear: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func isSecretRef(v string) bool {
+	return strings.HasPrefix(strings.TrimSpace(v), secretRefPrefix)
+}
+
+// secretCacheTTL is how long a looked-up secret is reused. A hook reads
+// the config several times per event; the daemon, which runs for days,
+// picks up a rotated secret once the entry is this old.
+const secretCacheTTL = time.Minute
+
+// cachedSecret is a store lookup, or the error it failed with.
+type cachedSecret struct {
+	value string
+	err   error
+	at    time.Time
+}
+
+var (
+	secretCacheMu sync.Mutex
+	secretCache   = map[string]cachedSecret{}
+)
+
+// lookupSecret returns the stored secret, asking the store at most once
+// per secretCacheTTL.
+func lookupSecret(name string) (string, error) {
+	secretCacheMu.Lock()
+	defer secretCacheMu.Unlock()
+	now := time.Now()
+	if c, ok := secretCache[name]; ok && now.Sub(c.at) < secretCacheTTL {
+		return c.value, c.err
+	}
+	store, err := defaultSecretStore()
+	var v string
+	if err == nil {
+		v, err = store.Get(name)
+	}
+	if err != nil {
+		err = fmt.Errorf("secret %q: %w", name, err)
+	}
+	secretCache[name] = cachedSecret{value: v, err: err, at: now}
+	return v, err
+}
+
+// resolveSecret returns v, or the secret it names when it is a
+// secret:<name> reference.
+func resolveSecret(v string) (string, error) {
+	if !isSecretRef(v) {
+		return v, nil
+	}
+	return lookupSecret(strings.TrimPrefix(strings.TrimSpace(v), secretRefPrefix))
+}
+
+var reportedSecretErrors sync.Map
+
+// secretValue is resolveSecret for sink credentials. A reference that
+// cannot be resolved reads as unset, which turns the sink off; the reason
+// is reported once on stderr and in doctor.
+func secretValue(v string) string {
+	resolved, err := resolveSecret(v)
+	if err != nil {
+		if _, seen := reportedSecretErrors.LoadOrStore(err.Error(), true); !seen {
+			logErrorf("%v", err)
+		}
+		return ""
+	}
+	return resolved
+}
+
+// configSecretRefs lists the secret:<name> references in the config file.
+func configSecretRefs(content []byte) []string {
+	entries, err := parseTOMLEntries(content)
+	if err != nil {
+		return nil
+	}
+	var refs []string
+	for _, e := range entries {
+		s, ok := e.Value.(string)
+		if !ok {
+			continue
+		}
+		if name, ok := strings.CutPrefix(strings.TrimSpace(s), secretRefPrefix); ok && !containsString(refs, name) {
+			refs = append(refs, name)
+		}
+		for _, m := range secretTemplatePattern.FindAllStringSubmatch(s, -1) {
+			if !containsString(refs, m[1]) {
+				refs = append(refs, m[1])
+			}
+		}
+	}
+	return refs
+}
+
+// secretTemplatePattern finds {{secret "name"}} in webhook templates.
+var secretTemplatePattern = regexp.MustCompile(`\{\{-?\s*secret\s+"([^"]+)"`)
+
+// addSecretDoctorChecks checks that every secret the config refers to
+// can be read.
+func addSecretDoctorChecks(report *doctorReport, content []byte) {
+	for _, name := range configSecretRefs(content) {
+		if _, err := lookupSecret(name); err != nil {
+			report.add(doctorSinks, checkFail, "secret "+name, fmt.Sprintf("%v (store it with `%s secret set %s`)", errors.Unwrap(err), appName, name), true)
+			continue
+		}
+		report.add(doctorSinks, checkOK, "secret "+name, "readable", false)
+	}
+}
+
+func runSecret(args []string) error {
+	if len(args) < 2 {
+		return usageError(errors.New("secret requires one of: set, get, delete, followed by a name"))
+	}
+	command, name := args[0], args[1]
+	fs := flag.NewFlagSet("secret "+command, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args[2:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+	out := outFlags.output()
+	if !secretNamePattern.MatchString(name) {
+		return usageError(fmt.Errorf("invalid secret name %q (letters, digits, '.', '_', '-')", name))
+	}
+	store, err := defaultSecretStore()
+	if err != nil {
+		return backendError(err)
+	}
+
+	switch command {
+	case "set":
+		value, err := readSecretValue(name)
+		if err != nil {
+			return err
+		}
+		if err := store.Set(name, value); err != nil {
+			return backendError(err)
+		}
+		out.Printf("stored %s in the %s; refer to it as \"%s%s\"\n", name, store.Name(), secretRefPrefix, name)
+		return out.Result(commandResult{Command: "secret", Status: "stored", Source: store.Name()})
+	case "get":
+		value, err := store.Get(name)
+		if errors.Is(err, errSecretNotFound) {
+			return configError(fmt.Errorf("no secret named %s", name))
+		}
+		if err != nil {
+			return backendError(err)
+		}
+		fmt.Fprintln(os.Stdout, value)
+		return nil
+	case "delete":
+		err := store.Delete(name)
+		if errors.Is(err, errSecretNotFound) {
+			return configError(fmt.Errorf("no secret named %s", name))
+		}
+		if err != nil {
+			return backendError(err)
+		}
+		out.Printf("deleted %s from the %s\n", name, store.Name())
+		return out.Result(commandResult{Command: "secret", Status: "deleted", Source: store.Name()})
+	default:
+		return usageError(fmt.Errorf("unknown secret command: %s", command))
+	}
+}
+
+// readSecretValue reads the value from stdin, never argv. A terminal gets
+// a prompt with echo turned off.
+func readSecretValue(name string) (string, error) {
+	var raw []byte
+	var err error
+	if isTerminal(os.Stdin) {
+		fmt.Fprintf(os.Stderr, "Value for %s: ", name)
+		raw, err = readLineNoEcho()
+		fmt.Fprintln(os.Stderr)
+	} else {
+		raw, err = io.ReadAll(io.LimitReader(os.Stdin, 64<<10))
+	}
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimRight(string(raw), "\r\n")
+	if value == "" {
+		return "", usageError(errors.New("empty secret value"))
+	}
+	return value, nil
+}
+
+func readLineNoEcho() ([]byte, error) {
+	if stty, ok := lookupCmd("stty"); ok {
+		off := exec.Command(stty, "-echo")
+		off.Stdin = os.Stdin
+		if off.Run() == nil {
+			defer func() {
+				on := exec.Command(stty, "echo")
+				on.Stdin = os.Stdin
+				_ = on.Run()
+			}()
+		}
+	}
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 1 {
+			if buf[0] == '\n' {
+				return line, nil
+			}
+			line = append(line, buf[0])
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return line, nil
+			}
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type memorySecretStore map[string]string
+
+func (memorySecretStore) Name() string { return "test store" }
+
+func (m memorySecretStore) Set(name, value string) error {
+	m[name] = value
+	return nil
+}
+
+func (m memorySecretStore) Get(name string) (string, error) {
+	v, ok := m[name]
+	if !ok {
+		return "", errSecretNotFound
+	}
+	return v, nil
+}
+
+func (m memorySecretStore) Delete(name string) error {
+	if _, ok := m[name]; !ok {
+		return errSecretNotFound
+	}
+	delete(m, name)
+	return nil
+}
+
+func useMemorySecretStore(t *testing.T, secrets map[string]string) memorySecretStore {
+	t.Helper()
+	store := memorySecretStore(secrets)
+	stub(t, &defaultSecretStore, func() (secretStore, error) { return store, nil })
+	reset := func() {
+		secretCacheMu.Lock()
+		secretCache = map[string]cachedSecret{}
+		secretCacheMu.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+	return store
+}
+
+func TestSinkConfigsResolveSecretRefs(t *testing.T) {
+	useMemorySecretStore(t, map[string]string{"ntfy": "tk_stored", "hook": "s3cret"})
+	t.Setenv(ntfyTokenEnv, "")
+	t.Setenv(pushoverTokenEnv, "")
+	t.Setenv(pushoverUserEnv, "")
+	cfg, err := parseUserConfig([]byte(`
+[ntfy]
+topic = "runs"
+token = "secret:ntfy"
+[pushover]
+token = "secret:missing"
+user = "me"
+[webhooks.home]
+url = "secret:hook-url"
+secret = '{{secret "hook"}}'
+`))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	if got := cfg.Ntfy.token(); got != "tk_stored" {
+		t.Fatalf("ntfy token = %q", got)
+	}
+	// An unresolvable reference turns the sink off instead of sending the name.
+	if cfg.Pushover.enabled() {
+		t.Fatal("pushover enabled with a missing secret")
+	}
+
+	if _, err := buildWebhookRequest(cfg.Webhooks[0], map[string]any{}, time.Now()); err == nil || !strings.Contains(err.Error(), "hook-url") {
+		t.Fatalf("buildWebhookRequest() error = %v, want the missing url secret", err)
+	}
+	cfg.Webhooks[0].URL = "https://example.com"
+	req, err := buildWebhookRequest(cfg.Webhooks[0], map[string]any{}, time.Unix(1_700_000_000, 0))
+	if err != nil {
+		t.Fatalf("buildWebhookRequest() error = %v", err)
+	}
+	_, want := signWebhookBody("s3cret", req.Body, time.Unix(1_700_000_000, 0))
+	if req.Headers[webhookSignatureHeader] != want {
+		t.Fatalf("signature = %q, want %q", req.Headers[webhookSignatureHeader], want)
+	}
+}
+
+func TestSecretRefsAreExportedAndChecked(t *testing.T) {
+	useMemorySecretStore(t, map[string]string{"slack": "https://hooks.example/x"})
+	content := "[slack]\nwebhook_url = \"secret:slack\"\n[ntfy]\ntopic = \"t\"\ntoken = \"plain\"\n[webhooks.a]\nurl = \"https://example.com\"\n[webhooks.a.headers]\nAuthorization = 'Bearer {{secret \"a-token\"}}'\n"
+
+	out := redactConfigSecrets(content)
+	if !strings.Contains(out, `webhook_url = "secret:slack"`) || strings.Contains(out, "plain") {
+		t.Fatalf("redactConfigSecrets() =\n%s", out)
+	}
+	if refs := configSecretRefs([]byte(content)); strings.Join(refs, ",") != "slack,a-token" {
+		t.Fatalf("configSecretRefs() = %v", refs)
+	}
+
+	var report doctorReport
+	addSecretDoctorChecks(&report, []byte(content))
+	if len(report.Checks) != 2 || report.Checks[0].Status != checkOK || report.Checks[1].Status != checkFail {
+		t.Fatalf("checks = %+v", report.Checks)
+	}
+}
+
+func TestSecretCommand(t *testing.T) {
+	store := useMemorySecretStore(t, map[string]string{})
+	stdin, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin.WriteString("tk_value\n")
+	stdin.Seek(0, 0)
+	stub(t, &os.Stdin, stdin)
+
+	if err := runSecret([]string{"set", "ntfy", "--quiet"}); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	if store["ntfy"] != "tk_value" {
+		t.Fatalf("stored %q", store["ntfy"])
+	}
+	if err := runSecret([]string{"delete", "ntfy", "--quiet"}); err != nil {
+		t.Fatalf("secret delete: %v", err)
+	}
+	if err := runSecret([]string{"delete", "ntfy"}); exitCodeFor(err) != exitConfig {
+		t.Fatalf("deleting a missing secret: %v", err)
+	}
+	if err := runSecret([]string{"set", "../x"}); exitCodeFor(err) != exitUsage {
+		t.Fatalf("invalid name: %v", err)
+	}
+}
+
+func TestLookupSecretExpires(t *testing.T) {
+	store := useMemorySecretStore(t, map[string]string{"ntfy": "old"})
+	if v, err := lookupSecret("ntfy"); err != nil || v != "old" {
+		t.Fatalf("lookupSecret() = %q, %v", v, err)
+	}
+	store["ntfy"] = "rotated"
+	if v, _ := lookupSecret("ntfy"); v != "old" {
+		t.Fatalf("fresh entry = %q, want the cached value", v)
+	}
+
+	secretCacheMu.Lock()
+	c := secretCache["ntfy"]
+	c.at = c.at.Add(-secretCacheTTL)
+	secretCache["ntfy"] = c
+	secretCacheMu.Unlock()
+	if v, _ := lookupSecret("ntfy"); v != "rotated" {
+		t.Fatalf("expired entry = %q, want the rotated secret", v)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// serviceEnv is the environment `service plist` bakes into the service: the
+// codex-notify settings exported in this shell, the config file and Codex
+// home they point at, and PATH, which launchd would otherwise cut down to
+// the system directories. Credentials are never settings, so they stay out.
+func serviceEnv() map[string]string {
+	names := []string{"CODEX_NOTIFY_CONFIG_FILE", "CODEX_HOME", "PATH"}
+	for _, spec := range userConfigSettings {
+		if spec.Env != "" {
+			names = append(names, spec.Env)
+		}
+	}
+	env := map[string]string{}
+	for _, name := range names {
+		if v, ok := lookupEnv(name); ok && strings.TrimSpace(v) != "" {
+			env[name] = v
+		}
+	}
+	return env
+}
+
+// brewServiceBlock renders the `service do` block of a Homebrew formula
+// that runs the daemon the way the LaunchAgent does, for a tap's formula.
+func brewServiceBlock(env map[string]string) string {
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `#`, `\#`)
+	var b strings.Builder
+	b.WriteString("service do\n")
+	fmt.Fprintf(&b, "  run [opt_bin/%q, \"daemon\"]\n", appName)
+	b.WriteString("  keep_alive true\n")
+	b.WriteString("  process_type :interactive\n")
+	fmt.Fprintf(&b, "  error_log_path var/\"log/%s.log\"\n", appName)
+	if len(env) > 0 {
+		keys := make([]string, 0, len(env))
+		for key := range env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			pairs = append(pairs, fmt.Sprintf("%s: \"%s\"", key, quote.Replace(env[key])))
+		}
+		fmt.Fprintf(&b, "  environment_variables %s\n", strings.Join(pairs, ", "))
+	}
+	b.WriteString("end\n")
+	return b.String()
+}
+
+// runService generates service definitions for the daemon:
+// `service plist` writes the LaunchAgent without loading it, --print
+// prints it instead, and --brew prints a Homebrew `service do` block.
+func runService(args []string) error {
+	if len(args) == 0 || args[0] != "plist" {
+		return usageError(errors.New("service requires: plist"))
+	}
+	fs := flag.NewFlagSet("service plist", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	printPlist := fs.Bool("print", false, "print the plist instead of writing it")
+	brew := fs.Bool("brew", false, "print a Homebrew formula service block")
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+	if *printPlist && *brew {
+		return usageError(errors.New("--print and --brew cannot be combined"))
+	}
+	out := outFlags.output()
+
+	env := serviceEnv()
+	if *brew {
+		_, err := io.WriteString(os.Stdout, brewServiceBlock(env))
+		return err
+	}
+	executable, err := daemonExecutable()
+	if err != nil {
+		return err
+	}
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		return err
+	}
+	content := launchAgentPlist(executable, filepath.Join(stateDir, "daemon.log"), env)
+	if *printPlist {
+		_, err := io.WriteString(os.Stdout, content)
+		return err
+	}
+
+	path, err := launchAgentPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create LaunchAgents dir: %w", err)
+	}
+	if err := writeFileAtomic(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	out.Printf("wrote LaunchAgent: %s\nload it with: launchctl bootstrap %s %s\n", path, launchctlDomain(), path)
+	return out.Result(commandResult{Command: "service", Status: "written", Path: path})
+}
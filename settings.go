@@ -0,0 +1,199 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// powerStatusCacheTTL keeps a hook, which asks about the power source from
+// several places, to one pmset run, and the daemon to one per burst.
+const powerStatusCacheTTL = 5 * time.Second
+
+// settings are the environment-derived values the hot path reads. They are
+// resolved once and reused until one of the variables changes, as it does
+// when config.toml is reloaded or a test sets another value.
+type settings struct {
+	TerminalBundleID string
+	ApproveKeys      []string
+	RejectKeys       []string
+	OpenKeys         []string
+	ApprovalUI       string
+	NotificationUI   string
+	PowerSaver       string
+	// ApprovalActions and PopupApprovalActions are the switches alone;
+	// approvalActionsEnabled also needs keystrokes to work here.
+	ApprovalActions      bool
+	PopupApprovalActions bool
+}
+
+// settingsEnv lists every variable resolveSettings reads.
+var settingsEnv = []string{
+	"CODEX_NOTIFY_TERMINAL_BUNDLE_ID",
+	"CODEX_NOTIFY_APPROVE_KEYS",
+	"CODEX_NOTIFY_REJECT_KEYS",
+	"CODEX_NOTIFY_OPEN_KEYS",
+	"CODEX_NOTIFY_APPROVAL_UI",
+	"CODEX_NOTIFY_NOTIFICATION_UI",
+	"CODEX_NOTIFY_POWER_SAVER",
+	"CODEX_NOTIFY_ENABLE_APPROVAL_ACTIONS",
+	"CODEX_NOTIFY_ENABLE_POPUP_APPROVAL_ACTIONS",
+	"CODEX_NOTIFY_ENABLE_NATIVE_APPROVAL_ACTIONS",
+}
+
+// resolveSettings computes settings from getenv, so tests can resolve them
+// from a map instead of the process environment.
+func resolveSettings(getenv func(string) string) settings {
+	lower := func(key string) string { return strings.TrimSpace(strings.ToLower(getenv(key))) }
+	s := settings{
+		TerminalBundleID: strings.TrimSpace(getenv("CODEX_NOTIFY_TERMINAL_BUNDLE_ID")),
+		ApproveKeys:      keySequenceFrom(getenv("CODEX_NOTIFY_APPROVE_KEYS"), defaultApproveSeq),
+		RejectKeys:       keySequenceFrom(getenv("CODEX_NOTIFY_REJECT_KEYS"), defaultRejectSeq),
+		OpenKeys:         keySequenceFrom(getenv("CODEX_NOTIFY_OPEN_KEYS"), ""),
+		ApprovalUI:       approvalUIPopup,
+		NotificationUI:   notificationUIPopup,
+		PowerSaver:       powerSaverOff,
+		ApprovalActions:  switchOn(lower("CODEX_NOTIFY_ENABLE_APPROVAL_ACTIONS")),
+	}
+	if s.TerminalBundleID == "" {
+		s.TerminalBundleID = defaultTerminalID
+	}
+	if lower("CODEX_NOTIFY_APPROVAL_UI") == approvalUIMulti {
+		s.ApprovalUI = approvalUIMulti
+	}
+	if lower("CODEX_NOTIFY_NOTIFICATION_UI") == notificationUISystem {
+		s.NotificationUI = notificationUISystem
+	}
+	switch lower("CODEX_NOTIFY_POWER_SAVER") {
+	case powerSaverAuto:
+		s.PowerSaver = powerSaverAuto
+	case powerSaverOn, "1", "true", "yes":
+		s.PowerSaver = powerSaverOn
+	}
+	popup := lower("CODEX_NOTIFY_ENABLE_POPUP_APPROVAL_ACTIONS")
+	if popup == "" {
+		popup = lower("CODEX_NOTIFY_ENABLE_NATIVE_APPROVAL_ACTIONS")
+	}
+	s.PopupApprovalActions = switchOn(popup)
+	return s
+}
+
+// switchOn reads an on-by-default switch: unset or a true value is on.
+func switchOn(v string) bool {
+	return v == "" || v == "1" || v == "true" || v == "yes" || v == "on"
+}
+
+var settingsCache struct {
+	sync.Mutex
+	key    string
+	cached bool
+	value  settings
+}
+
+// currentSettings returns the settings as getenv sees them, so a forwarded
+// hook gets its own.
+func currentSettings() settings {
+	var key strings.Builder
+	for _, name := range settingsEnv {
+		key.WriteString(getenv(name))
+		key.WriteByte(0)
+	}
+	settingsCache.Lock()
+	defer settingsCache.Unlock()
+	if !settingsCache.cached || settingsCache.key != key.String() {
+		settingsCache.value = resolveSettings(getenv)
+		settingsCache.key, settingsCache.cached = key.String(), true
+	}
+	return settingsCache.value
+}
+
+var powerStatusCache struct {
+	sync.Mutex
+	at     time.Time
+	status powerStatus
+}
+
+// currentPowerStatus is readPowerStatus, cached for powerStatusCacheTTL.
+func currentPowerStatus(now time.Time) powerStatus {
+	powerStatusCache.Lock()
+	defer powerStatusCache.Unlock()
+	if powerStatusCache.at.IsZero() || now.Sub(powerStatusCache.at) > powerStatusCacheTTL || now.Before(powerStatusCache.at) {
+		powerStatusCache.status = readPowerStatus()
+		powerStatusCache.at = now
+	}
+	return powerStatusCache.status
+}
+
+// resetPowerStatusCache makes the next currentPowerStatus ask pmset again.
+func resetPowerStatusCache() {
+	powerStatusCache.Lock()
+	powerStatusCache.at = time.Time{}
+	powerStatusCache.Unlock()
+}
+
+var cmdCache struct {
+	sync.Mutex
+	pathEnv string
+	entries map[string]string
+}
+
+// lookupCmd finds name on PATH. A found path is kept until PATH changes or
+// the file is gone; a miss is looked up again, so a notifier installed
+// while the daemon runs is picked up.
+func lookupCmd(name string) (string, bool) {
+	pathEnv := getenv("PATH")
+	cmdCache.Lock()
+	if cmdCache.pathEnv != pathEnv || cmdCache.entries == nil {
+		cmdCache.pathEnv, cmdCache.entries = pathEnv, map[string]string{}
+	}
+	cached, ok := cmdCache.entries[name]
+	cmdCache.Unlock()
+	if ok {
+		if _, err := os.Stat(cached); err == nil {
+			return cached, true
+		}
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", false
+	}
+	cmdCache.Lock()
+	if cmdCache.pathEnv == pathEnv {
+		cmdCache.entries[name] = path
+	}
+	cmdCache.Unlock()
+	return path, true
+}
+
+type userConfigEntry struct {
+	path    string
+	modTime time.Time
+	size    int64
+	cfg     userConfig
+}
+
+var userConfigCache struct {
+	sync.Mutex
+	entry *userConfigEntry
+}
+
+// cachedUserConfig returns the parsed config for path if the file has not
+// changed since it was parsed.
+func cachedUserConfig(path string, info os.FileInfo) (userConfig, bool) {
+	userConfigCache.Lock()
+	defer userConfigCache.Unlock()
+	e := userConfigCache.entry
+	if e == nil || e.path != path || !e.modTime.Equal(info.ModTime()) || e.size != info.Size() {
+		return userConfig{}, false
+	}
+	return e.cfg, true
+}
+
+func storeUserConfig(path string, info os.FileInfo, cfg userConfig) {
+	userConfigCache.Lock()
+	userConfigCache.entry = &userConfigEntry{path: path, modTime: info.ModTime(), size: info.Size(), cfg: cfg}
+	userConfigCache.Unlock()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveSettings(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+
+	s := resolveSettings(getenv)
+	if s.TerminalBundleID != defaultTerminalID || s.ApprovalUI != approvalUIPopup || s.NotificationUI != notificationUIPopup || s.PowerSaver != powerSaverOff {
+		t.Fatalf("defaults = %+v", s)
+	}
+	if !reflect.DeepEqual(s.ApproveKeys, []string{"y", "enter"}) || len(s.OpenKeys) != 0 || !s.ApprovalActions || !s.PopupApprovalActions {
+		t.Fatalf("defaults = %+v", s)
+	}
+
+	env = map[string]string{
+		"CODEX_NOTIFY_TERMINAL_BUNDLE_ID":             " com.googlecode.iterm2 ",
+		"CODEX_NOTIFY_APPROVE_KEYS":                   "1, enter",
+		"CODEX_NOTIFY_APPROVAL_UI":                    "MULTI",
+		"CODEX_NOTIFY_NOTIFICATION_UI":                "system",
+		"CODEX_NOTIFY_POWER_SAVER":                    "true",
+		"CODEX_NOTIFY_ENABLE_APPROVAL_ACTIONS":        "0",
+		"CODEX_NOTIFY_ENABLE_NATIVE_APPROVAL_ACTIONS": "no",
+	}
+	s = resolveSettings(getenv)
+	if s.TerminalBundleID != "com.googlecode.iterm2" || s.ApprovalUI != approvalUIMulti || s.NotificationUI != notificationUISystem || s.PowerSaver != powerSaverOn {
+		t.Fatalf("resolved = %+v", s)
+	}
+	if !reflect.DeepEqual(s.ApproveKeys, []string{"1", "enter"}) || s.ApprovalActions || s.PopupApprovalActions {
+		t.Fatalf("resolved = %+v", s)
+	}
+}
+
+func TestCurrentSettingsFollowsEnvironment(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_APPROVAL_UI", "")
+	if approvalUIStyle() != approvalUIPopup {
+		t.Fatal("default approval UI not popup")
+	}
+	t.Setenv("CODEX_NOTIFY_APPROVAL_UI", "multi")
+	if approvalUIStyle() != approvalUIMulti {
+		t.Fatal("cached settings outlived an environment change")
+	}
+	// Callers may modify the sequence they get.
+	keys := approveKeySequence()
+	keys[0] = "changed"
+	if approveKeySequence()[0] == "changed" {
+		t.Fatal("key sequence shares the cached slice")
+	}
+}
+
+func TestCurrentSettingsFollowsForwardedHook(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_APPROVAL_UI", "")
+	var got string
+	withHookEnv(map[string]string{"CODEX_NOTIFY_APPROVAL_UI": "multi"}, func() { got = approvalUIStyle() })
+	if got != approvalUIMulti {
+		t.Fatalf("forwarded approval UI = %q, want the hook's %q", got, approvalUIMulti)
+	}
+	if approvalUIStyle() != approvalUIPopup {
+		t.Fatal("the hook's setting outlived it")
+	}
+}
+
+func TestLoadUserConfigRereadsChangedFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	path, err := userConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`chain = ["a"]`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err := loadUserConfig(); err != nil || cfg.Chain[0] != "a" {
+		t.Fatalf("loadUserConfig() = %+v, %v", cfg.Chain, err)
+	}
+	if err := os.WriteFile(path, []byte(`chain = ["bb"]`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err := loadUserConfig(); err != nil || cfg.Chain[0] != "bb" {
+		t.Fatalf("loadUserConfig() after a change = %+v, %v", cfg.Chain, err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err := loadUserConfig(); err != nil || len(cfg.Chain) != 0 {
+		t.Fatalf("loadUserConfig() after removal = %+v, %v", cfg.Chain, err)
+	}
+}
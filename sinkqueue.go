@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// A failed delivery is retried after 30s, 1m, 2m, ... up to an hour
+	// apart, and given up after sinkQueueMaxAttempts tries or a day.
+	sinkRetryBase        = 30 * time.Second
+	sinkRetryMax         = time.Hour
+	sinkQueueMaxAttempts = 10
+	sinkQueueMaxAge      = 24 * time.Hour
+	// sinkQueueMaxLen bounds the queue (and state.json) during a long
+	// outage; the oldest deliveries go first.
+	sinkQueueMaxLen = 100
+
+	sinkQueueDrainInterval = 30 * time.Second
+)
+
+// transientError marks a sink failure worth retrying: the network was down
+// or the service was overloaded, rather than the request being wrong.
+type transientError struct {
+	err error
+}
+
+func (e transientError) Error() string { return e.err.Error() }
+func (e transientError) Unwrap() error { return e.err }
+
+func transient(err error) error {
+	return transientError{err: err}
+}
+
+func isTransient(err error) bool {
+	var t transientError
+	return errors.As(err, &t)
+}
+
+// httpStatusError reports a non-2xx response; 429 and 5xx are transient.
+func httpStatusError(code int, err error) error {
+	if code == 429 || code >= 500 {
+		return transient(err)
+	}
+	return err
+}
+
+// queuedDelivery is a remote sink delivery waiting to be retried. The
+// payload is kept rather than the built message so the retry uses the
+// current config.
+type queuedDelivery struct {
+	Sink      string         `json:"sink"`
+	Payload   map[string]any `json:"payload"`
+	Queued    int64          `json:"queued"`
+	Attempts  int            `json:"attempts"`
+	Next      int64          `json:"next"`
+	LastError string         `json:"last_error,omitempty"`
+}
+
+func sinkRetryDelay(attempts int) time.Duration {
+	delay := sinkRetryBase
+	for i := 1; i < attempts && delay < sinkRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, sinkRetryMax)
+}
+
+// enqueueDelivery records a failed delivery for a later retry.
+func enqueueDelivery(d queuedDelivery, err error, now time.Time) error {
+	d.Attempts++
+	d.LastError = err.Error()
+	d.Next = now.Add(sinkRetryDelay(d.Attempts)).Unix()
+	if d.Queued == 0 {
+		d.Queued = now.Unix()
+	}
+	if d.Attempts >= sinkQueueMaxAttempts || now.Sub(time.Unix(d.Queued, 0)) >= sinkQueueMaxAge {
+		return fmt.Errorf("giving up after %d attempts: %w", d.Attempts, err)
+	}
+	return updateState(func(s *notifyState) {
+		s.SinkQueue = append(s.SinkQueue, d)
+		if over := len(s.SinkQueue) - sinkQueueMaxLen; over > 0 {
+			s.SinkQueue = s.SinkQueue[over:]
+		}
+	})
+}
+
+// claimDueDeliveries removes and returns the deliveries whose retry time
+// has come, so a hook and the daemon draining at once never both send one.
+// Approvals that were answered, withdrawn, or expired since are dropped.
+func claimDueDeliveries(now time.Time) []queuedDelivery {
+	var due []queuedDelivery
+	_ = updateState(func(s *notifyState) {
+		kept := s.SinkQueue[:0]
+		for _, d := range s.SinkQueue {
+			if approvalDeliverySettled(s, d, now) {
+				logInfof("%s: approval for thread %s settled; dropped from the retry queue", d.Sink, payloadThreadID(d.Payload))
+				continue
+			}
+			if d.Next <= now.Unix() {
+				due = append(due, d)
+			} else {
+				kept = append(kept, d)
+			}
+		}
+		s.SinkQueue = kept
+	})
+	return due
+}
+
+// approvalDeliverySettled reports whether d announces an approval that is
+// no longer waiting: its thread has no pending approval, or only a newer
+// one that got its own deliveries.
+func approvalDeliverySettled(s *notifyState, d queuedDelivery, now time.Time) bool {
+	thread := payloadThreadID(d.Payload)
+	if payloadEventName(d.Payload) != "approval-requested" || thread == "" {
+		return false
+	}
+	pending, ok := s.PendingApprovals[thread]
+	return !ok || pending.Since > d.Queued || now.Sub(time.Unix(pending.Since, 0)) >= widgetApprovalWindow
+}
+
+// requeueDeliveries puts claimed deliveries back untouched.
+func requeueDeliveries(ds []queuedDelivery) {
+	if len(ds) == 0 {
+		return
+	}
+	_ = updateState(func(s *notifyState) {
+		s.SinkQueue = append(ds, s.SinkQueue...)
+	})
+}
+
+// drainSinkQueue retries the deliveries that are due, one at a time. The
+// first one that fails again suggests the network is still down, so the
+// rest wait for the next drain instead of each timing out.
+func drainSinkQueue(cfg userConfig, now time.Time) int {
+	due := claimDueDeliveries(now)
+	delivered := 0
+	for i, d := range due {
+		var sink *remoteSink
+		for _, s := range deliverySinks(cfg, d.Payload) {
+			if s.Name == d.Sink {
+				sink = &s
+				break
+			}
+		}
+		if sink == nil {
+			// The sink was removed or no longer wants the event.
+			continue
+		}
+		err := sink.Publish()
+		if err == nil {
+			delivered++
+			continue
+		}
+		if !isTransient(err) {
+			logErrorf("%s: %v (dropped from the retry queue)", d.Sink, err)
+			continue
+		}
+		if err := enqueueDelivery(d, err, now); err != nil {
+			logErrorf("%s: %v", d.Sink, err)
+		}
+		requeueDeliveries(due[i+1:])
+		break
+	}
+	return delivered
+}
+
+// startSinkQueueDrainer retries queued deliveries in the background for
+// the daemon's lifetime.
+func startSinkQueueDrainer() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(sinkQueueDrainInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				inDaemonWork(func() {
+					if cfg, err := loadUserConfig(); err == nil {
+						drainSinkQueue(cfg, now)
+					}
+				})
+			}
+		}
+	}()
+	return cancel
+}
+
+// addSinkQueueDoctorCheck reports deliveries still waiting for a retry.
+func addSinkQueueDoctorCheck(report *doctorReport, now time.Time) {
+	state, err := loadState()
+	if err != nil || len(state.SinkQueue) == 0 {
+		return
+	}
+	oldest := state.SinkQueue[0]
+	for _, d := range state.SinkQueue {
+		if d.Queued < oldest.Queued {
+			oldest = d
+		}
+	}
+	age := now.Sub(time.Unix(oldest.Queued, 0)).Round(time.Second)
+	report.add(doctorSinks, checkWarn, "retry queue", fmt.Sprintf("%d deliveries waiting, oldest %s ago (%s: %s)", len(state.SinkQueue), age, oldest.Sink, oldest.LastError), false)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSinkRetryDelay(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		4:  4 * time.Minute,
+		8:  sinkRetryMax,
+		20: sinkRetryMax,
+	} {
+		if got := sinkRetryDelay(attempts); got != want {
+			t.Errorf("sinkRetryDelay(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestOfflineDeliveriesAreQueuedAndRetried(t *testing.T) {
+	home, _ := useTempHome(t)
+	// Remote sinks stay quiet in capture mode.
+	t.Setenv(backendEnv, "")
+	t.Setenv(ntfyTokenEnv, "")
+
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(home, "config.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", path)
+	if err := os.WriteFile(path, []byte("[ntfy]\ntopic = \"runs\"\nserver = \""+server.URL+"\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadUserConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	startRemoteSinks(map[string]any{"type": "agent-turn-complete", "thread-id": "t1"})()
+	state, _ := loadState()
+	if len(state.SinkQueue) != 1 || state.SinkQueue[0].Sink != "ntfy" || state.SinkQueue[0].Attempts != 1 {
+		t.Fatalf("queue after a 503 = %+v", state.SinkQueue)
+	}
+
+	// Not due yet: nothing is sent.
+	now := time.Now()
+	if n := drainSinkQueue(cfg, now); n != 0 || hits.Load() != 1 {
+		t.Fatalf("early drain delivered %d (hits %d)", n, hits.Load())
+	}
+
+	// Still failing: the attempt count and backoff grow.
+	later := now.Add(time.Minute)
+	drainSinkQueue(cfg, later)
+	state, _ = loadState()
+	if len(state.SinkQueue) != 1 || state.SinkQueue[0].Attempts != 2 || state.SinkQueue[0].Next != later.Add(time.Minute).Unix() {
+		t.Fatalf("queue after a second 503 = %+v", state.SinkQueue)
+	}
+
+	status.Store(http.StatusOK)
+	if n := drainSinkQueue(cfg, later.Add(2*time.Minute)); n != 1 {
+		t.Fatalf("drain delivered %d, want 1", n)
+	}
+	if state, _ = loadState(); len(state.SinkQueue) != 0 {
+		t.Fatalf("queue after delivery = %+v", state.SinkQueue)
+	}
+
+	// A rejected request will never succeed, so it is not queued.
+	status.Store(http.StatusBadRequest)
+	startRemoteSinks(map[string]any{"type": "agent-turn-complete", "thread-id": "t2"})()
+	if state, _ = loadState(); len(state.SinkQueue) != 0 {
+		t.Fatalf("queue after a 400 = %+v", state.SinkQueue)
+	}
+}
+
+func TestEnqueueDeliveryGivesUp(t *testing.T) {
+	useTempHome(t)
+	// Remote sinks stay quiet in capture mode.
+	t.Setenv(backendEnv, "")
+	now := time.Now()
+
+	old := queuedDelivery{Sink: "ntfy", Queued: now.Add(-sinkQueueMaxAge).Unix()}
+	if err := enqueueDelivery(old, transient(os.ErrDeadlineExceeded), now); err == nil {
+		t.Fatal("a day-old delivery was queued again")
+	}
+	tired := queuedDelivery{Sink: "ntfy", Attempts: sinkQueueMaxAttempts - 1}
+	if err := enqueueDelivery(tired, transient(os.ErrDeadlineExceeded), now); err == nil {
+		t.Fatal("a delivery past its attempts was queued again")
+	}
+	if state, _ := loadState(); len(state.SinkQueue) != 0 {
+		t.Fatalf("queue = %+v", state.SinkQueue)
+	}
+}
+
+func TestSettledApprovalsLeaveTheRetryQueue(t *testing.T) {
+	useTempHome(t)
+	// Remote sinks stay quiet in capture mode.
+	t.Setenv(backendEnv, "")
+	now := time.Now()
+
+	payload := map[string]any{"type": "approval-requested", "thread-id": "t1"}
+	if _, ok := trackApproval(payload, now); !ok {
+		t.Fatal("trackApproval failed")
+	}
+	if err := enqueueDelivery(queuedDelivery{Sink: "ntfy", Payload: payload}, transient(os.ErrDeadlineExceeded), now); err != nil {
+		t.Fatal(err)
+	}
+	if due := claimDueDeliveries(now); len(due) != 0 {
+		t.Fatalf("claimed %+v before the retry time", due)
+	}
+	if state, _ := loadState(); len(state.SinkQueue) != 1 {
+		t.Fatalf("pending approval dropped from the queue: %+v", state.SinkQueue)
+	}
+
+	settleApproval("t1", answeredLocal)()
+	if due := claimDueDeliveries(now.Add(time.Hour)); len(due) != 0 {
+		t.Fatalf("claimed %+v for an answered approval", due)
+	}
+	if state, _ := loadState(); len(state.SinkQueue) != 0 {
+		t.Fatalf("answered approval still queued: %+v", state.SinkQueue)
+	}
+}
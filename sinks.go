@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sinkHTTPClient bounds how long a slow remote sink can hold up the hook.
+var sinkHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+// remoteSink is one configured destination besides the desktop
+// notification.
+type remoteSink struct {
+	Name    string
+	Publish func() error
+}
+
+// parseEventList reads a sink's `events` array.
+func parseEventList(key string, value any) ([]string, error) {
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of event names", key)
+	}
+	events := []string{}
+	for _, item := range items {
+		event, ok := item.(string)
+		if !ok || strings.TrimSpace(event) == "" {
+			return nil, errors.New(key + " entries must be non-empty strings")
+		}
+		events = append(events, strings.TrimSpace(event))
+	}
+	return events, nil
+}
+
+// remoteSinks returns the sinks that want this event: the ones the first
+// matching rule names, or without one, those whose events list has it.
+func remoteSinks(cfg userConfig, payload map[string]any) []remoteSink {
+	rule, routed := cfg.eventRoute(payload, time.Now())
+	return buildRemoteSinks(cfg, payload, func(sink string, own bool) bool {
+		if routed {
+			return rule.routes(sink)
+		}
+		return own
+	})
+}
+
+// namedRemoteSinks returns the configured sinks among names, whatever
+// their events lists say.
+func namedRemoteSinks(cfg userConfig, payload map[string]any, names []string) []remoteSink {
+	return buildRemoteSinks(cfg, payload, func(sink string, _ bool) bool {
+		return containsString(names, sink)
+	})
+}
+
+// deliverySinks is remoteSinks for a queued delivery: a withdrawal goes to
+// the sinks that take approvals back rather than to the event's sinks.
+func deliverySinks(cfg userConfig, payload map[string]any) []remoteSink {
+	if payloadEventName(payload) == approvalWithdrawnEvent {
+		return withdrawalSinks(cfg, payloadThreadID(payload))
+	}
+	return remoteSinks(cfg, payload)
+}
+
+// buildRemoteSinks returns the enabled sinks wants accepts; own is whether
+// the sink's events list has the event.
+func buildRemoteSinks(cfg userConfig, payload map[string]any, wants func(sink string, own bool) bool) []remoteSink {
+	event := payloadEventName(payload)
+	sinks := []remoteSink{}
+	if cfg.Ntfy.enabled() && wants("ntfy", cfg.Ntfy.wants(event)) {
+		msg := buildNtfyMessage(cfg.Ntfy, payload)
+		sinks = append(sinks, remoteSink{Name: "ntfy", Publish: func() error { return publishNtfy(cfg.Ntfy, msg) }})
+	}
+	if cfg.Slack.enabled() && wants("slack", cfg.Slack.wants(event)) {
+		msg := buildSlackMessage(payload)
+		sinks = append(sinks, remoteSink{Name: "slack", Publish: func() error { return publishSlack(cfg.Slack, msg) }})
+	}
+	if cfg.Pushover.enabled() && wants("pushover", cfg.Pushover.wants(event)) {
+		form := buildPushoverForm(cfg.Pushover, payload)
+		sinks = append(sinks, remoteSink{Name: "pushover", Publish: func() error { return publishPushover(cfg.Pushover, form) }})
+	}
+	if cfg.Bark.enabled() && wants("bark", cfg.Bark.wants(event)) {
+		msg := buildBarkMessage(cfg.Bark, payload)
+		sinks = append(sinks, remoteSink{Name: "bark", Publish: func() error { return publishBark(cfg.Bark, msg) }})
+	}
+	if cfg.PagerDuty.enabled() && wants("pagerduty", cfg.PagerDuty.wants(event)) {
+		ev := buildPagerDutyEvent(cfg.PagerDuty, payload)
+		sinks = append(sinks, remoteSink{Name: "pagerduty", Publish: func() error { return publishPagerDuty(cfg.PagerDuty, ev) }})
+	}
+	if cfg.OnCall.enabled() && wants("oncall", cfg.OnCall.wants(event)) {
+		alert := buildOnCallAlert(payload)
+		sinks = append(sinks, remoteSink{Name: "oncall", Publish: func() error { return publishOnCall(cfg.OnCall, alert) }})
+	}
+	if cfg.Email.enabled() && wants("email", cfg.Email.wants(event)) {
+		msg := buildEmailMessage(cfg.Email, payload, time.Now())
+		sinks = append(sinks, remoteSink{Name: "email", Publish: func() error { return sendEmail(cfg.Email, msg) }})
+	}
+	if cfg.Peer.enabled() && featureEnabled("peer") && wants("peer", cfg.Peer.wants(event)) {
+		msg := buildPeerMessage(cfg.Peer, payload)
+		sinks = append(sinks, remoteSink{Name: "peer", Publish: func() error { return postPeer(cfg.Peer, "/notify", msg) }})
+	}
+	for _, hook := range cfg.Webhooks {
+		if !wants("webhooks."+hook.Name, hook.wants(event)) {
+			continue
+		}
+		sinks = append(sinks, remoteSink{Name: "webhook " + hook.Name, Publish: func() error {
+			req, err := buildWebhookRequest(hook, payload, time.Now())
+			if err != nil {
+				return err
+			}
+			client, err := webhookHTTPClient(hook)
+			if err != nil {
+				return err
+			}
+			return publishWebhook(client, "webhook", req)
+		}})
+	}
+	for i, sink := range sinks {
+		publish := sink.Publish
+		sinks[i].Publish = func() error {
+			if err := injectedFailure(sink.Name); err != nil {
+				return err
+			}
+			return publish()
+		}
+	}
+	return sinks
+}
+
+// startRemoteSinks publishes the event to the remote sinks while the desktop
+// notification is delivered, and returns a function that waits for them.
+// Failures are reported on stderr and never fail the hook; transient ones
+// are queued, and deliveries queued earlier are retried alongside.
+func startRemoteSinks(payload map[string]any) func() {
+	if _, capture := captureDir(); capture || benchDryRun() {
+		return func() {}
+	}
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return func() {}
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		drainSinkQueue(cfg, time.Now())
+	}()
+	publish := publishRemoteSinks(remoteSinks(cfg, payload), payload)
+	return func() {
+		wg.Wait()
+		publish()
+	}
+}
+
+// publishRemoteSinks publishes to sinks concurrently, queueing transient
+// failures for a retry with payload. The returned function waits for them.
+func publishRemoteSinks(sinks []remoteSink, payload map[string]any) func() {
+	var wg sync.WaitGroup
+	for _, sink := range sinks {
+		wg.Add(1)
+		go func(sink remoteSink) {
+			defer wg.Done()
+			err := sink.Publish()
+			if err == nil {
+				return
+			}
+			if isTransient(err) {
+				if qerr := enqueueDelivery(queuedDelivery{Sink: sink.Name, Payload: payload}, err, time.Now()); qerr == nil {
+					logErrorf("%s: %v (queued for retry)", sink.Name, err)
+					return
+				}
+			}
+			logErrorf("%s: %v", sink.Name, err)
+		}(sink)
+	}
+	return wg.Wait
+}
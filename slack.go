@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+const slackWebhookEnv = "CODEX_NOTIFY_SLACK_WEBHOOK"
+
+// defaultSlackEvents are the events that need someone to look: Codex is
+// blocked on an approval or failed.
+var defaultSlackEvents = []string{"approval-requested", "agent-error"}
+
+// slackConfig is the [slack] table of config.toml.
+type slackConfig struct {
+	// WebhookURL is a Slack incoming webhook; CODEX_NOTIFY_SLACK_WEBHOOK
+	// overrides it, so the secret can stay out of the config file.
+	WebhookURL string
+	Events     []string
+}
+
+func (c slackConfig) webhook() string {
+	if url := strings.TrimSpace(getenv(slackWebhookEnv)); url != "" {
+		return secretValue(url)
+	}
+	return secretValue(c.WebhookURL)
+}
+
+func (c slackConfig) enabled() bool {
+	return c.webhook() != ""
+}
+
+func (c slackConfig) wants(event string) bool {
+	events := c.Events
+	if events == nil {
+		events = defaultSlackEvents
+	}
+	return containsString(events, event) || containsString(events, "all")
+}
+
+// set parses one `slack.<key>` entry.
+func (c *slackConfig) set(key string, value any) error {
+	switch key {
+	case "events":
+		events, err := parseEventList("slack.events", value)
+		if err != nil {
+			return err
+		}
+		c.Events = events
+	case "webhook_url":
+		s, ok := value.(string)
+		if !ok || !strings.HasPrefix(strings.TrimSpace(s), "https://") && !isSecretRef(s) {
+			return errors.New("slack.webhook_url must be an https URL or a secret: reference")
+		}
+		c.WebhookURL = strings.TrimSpace(s)
+	default:
+		return unknownSetting("slack." + key)
+	}
+	return nil
+}
+
+// slackMessage is an incoming-webhook body. Text is the fallback shown in
+// notifications and by clients that do not render blocks.
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string       `json:"type"`
+	Text     *slackText   `json:"text,omitempty"`
+	Elements []*slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func buildSlackMessage(payload map[string]any) slackMessage {
+	title, message := renderPayloadMessage(payload)
+	body := renderMessage(message, formatSlack)
+
+	context := []string{}
+	if project, ok := projectIdentityForCwd(payloadCwd(payload)); ok {
+		context = append(context, project.Emoji+" "+htmlTextEscape.Replace(project.Name))
+	}
+	if thread := payloadThreadID(payload); thread != "" {
+		context = append(context, "thread `"+htmlTextEscape.Replace(thread)+"`")
+	}
+
+	msg := slackMessage{
+		Text: title + ": " + renderMessage(message, formatPlain),
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: title}},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: body}},
+		},
+	}
+	if len(context) > 0 {
+		msg.Blocks = append(msg.Blocks, slackBlock{
+			Type:     "context",
+			Elements: []*slackText{{Type: "mrkdwn", Text: strings.Join(context, "  ·  ")}},
+		})
+	}
+	return msg
+}
+
+func publishSlack(cfg slackConfig, msg slackMessage) error {
+	req, err := jsonRequest(cfg.webhook(), msg)
+	if err != nil {
+		return err
+	}
+	return publishWebhook(sinkHTTPClient, "Slack webhook", req)
+}
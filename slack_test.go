@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderMessageSlack(t *testing.T) {
+	in := "Run **rm -rf build** in *this* repo? See [docs](https://example.com/a?b=1&c=2) or `x<y`."
+	want := "Run *rm -rf build* in _this_ repo? See <https://example.com/a?b=1&amp;c=2|docs> or `x&lt;y`."
+	if got := renderMessage(in, formatSlack); got != want {
+		t.Fatalf("renderMessage(slack) = %q, want %q", got, want)
+	}
+}
+
+func TestBuildSlackMessage(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "0")
+	msg := buildSlackMessage(map[string]any{
+		"type":      "approval-requested",
+		"thread-id": "t-123",
+		"message":   "Allow command: **go test ./...**",
+	})
+
+	if msg.Text != "Codex: Approval Requested: Allow command: go test ./..." {
+		t.Fatalf("fallback text = %q", msg.Text)
+	}
+	if len(msg.Blocks) != 3 || msg.Blocks[0].Type != "header" || msg.Blocks[1].Text.Text != "Allow command: *go test ./...*" {
+		t.Fatalf("blocks = %+v", msg.Blocks)
+	}
+	if got := msg.Blocks[2].Elements[0].Text; got != "thread `t-123`" {
+		t.Fatalf("context = %q", got)
+	}
+}
+
+func TestSlackSinkConfigAndPublish(t *testing.T) {
+	var got slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv(slackWebhookEnv, "")
+	cfg, err := parseUserConfig([]byte("[slack]\nwebhook_url = \"https://hooks.slack.com/services/x\"\nevents = [\"agent-error\"]\n"))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	if !cfg.Slack.enabled() || cfg.Slack.wants("approval-requested") || !cfg.Slack.wants("agent-error") {
+		t.Fatalf("slack config = %+v", cfg.Slack)
+	}
+	if (slackConfig{}).enabled() {
+		t.Fatal("slack sink enabled without a webhook")
+	}
+	if _, err := parseUserConfig([]byte("[slack]\nwebhook_url = \"http://insecure\"\n")); err == nil {
+		t.Fatal("expected an error for a non-https webhook")
+	}
+
+	// The environment variable wins over the file, which keeps the webhook
+	// out of config.toml.
+	t.Setenv(slackWebhookEnv, server.URL)
+	sinks := remoteSinks(cfg, map[string]any{"type": "agent-error", "message": "stream disconnected"})
+	if len(sinks) != 1 || sinks[0].Name != "slack" {
+		t.Fatalf("remoteSinks() = %+v", sinks)
+	}
+	if err := sinks[0].Publish(); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if !strings.Contains(got.Text, "stream disconnected") {
+		t.Fatalf("posted %+v", got)
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	soundThemeEnv     = "CODEX_NOTIFY_SOUND_THEME"
+	soundThemesDir    = "sounds"
+	soundThemeDefault = "default"
+	soundThemeBuiltIn = "built-in"
+)
+
+// soundFileExts are the audio files a theme directory may hold.
+var soundFileExts = map[string]bool{
+	".aiff": true, ".aif": true, ".wav": true, ".mp3": true, ".m4a": true,
+	".caf": true, ".ogg": true, ".oga": true, ".flac": true,
+}
+
+// soundTheme maps events to sounds: a system sound name, or an absolute
+// path to an audio file. The "default" entry covers events it leaves out.
+type soundTheme struct {
+	Name string `json:"name"`
+	// Source is "built-in" or the theme's directory.
+	Source string            `json:"source"`
+	Sounds map[string]string `json:"sounds"`
+}
+
+// builtInSoundThemes are shipped with codex-notify, per OS: macOS system
+// sound names and freedesktop sound theme names.
+var builtInSoundThemes = map[string]map[string]map[string]string{
+	"darwin": {
+		"subtle": {"approval-requested": "Tink", "agent-turn-complete": "Pop", "agent-error": "Basso"},
+		"bright": {"approval-requested": "Glass", "agent-turn-complete": "Hero", "agent-error": "Sosumi"},
+		"retro":  {"approval-requested": "Ping", "agent-turn-complete": "Submarine", "agent-error": "Funk"},
+	},
+	"linux": {
+		"subtle": {"approval-requested": "message-new-instant", "agent-turn-complete": "complete", "agent-error": "dialog-error"},
+		"bright": {"approval-requested": "bell", "agent-turn-complete": "complete", "agent-error": "dialog-warning"},
+		"retro":  {"approval-requested": "window-attention", "agent-turn-complete": "service-login", "agent-error": "suspend-error"},
+	},
+}
+
+func soundThemesPath() (string, error) {
+	configDir, err := userConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve user config dir: %w", err)
+	}
+	return filepath.Join(configDir, appName, soundThemesDir), nil
+}
+
+// loadSoundThemes returns the built-in themes and those under the themes
+// dir, by name. A directory theme replaces a built-in one of the same name.
+func loadSoundThemes() (map[string]soundTheme, error) {
+	themes := map[string]soundTheme{}
+	for name, sounds := range builtInSoundThemes[hostOS] {
+		themes[name] = soundTheme{Name: name, Source: soundThemeBuiltIn, Sounds: sounds}
+	}
+	root, err := soundThemesPath()
+	if err != nil {
+		return themes, err
+	}
+	dirs, err := os.ReadDir(root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return themes, nil
+		}
+		return themes, err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		theme := soundTheme{Name: dir.Name(), Source: filepath.Join(root, dir.Name()), Sounds: map[string]string{}}
+		files, err := os.ReadDir(theme.Source)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			ext := strings.ToLower(filepath.Ext(f.Name()))
+			if f.IsDir() || !soundFileExts[ext] {
+				continue
+			}
+			theme.Sounds[strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))] = filepath.Join(theme.Source, f.Name())
+		}
+		themes[theme.Name] = theme
+	}
+	return themes, nil
+}
+
+func (t soundTheme) soundFor(event string) string {
+	if sound, ok := t.Sounds[event]; ok {
+		return sound
+	}
+	return t.Sounds[soundThemeDefault]
+}
+
+// events lists the theme's events, default last.
+func (t soundTheme) events() []string {
+	var events []string
+	for event := range t.Sounds {
+		if event != soundThemeDefault {
+			events = append(events, event)
+		}
+	}
+	sort.Strings(events)
+	if _, ok := t.Sounds[soundThemeDefault]; ok {
+		events = append(events, soundThemeDefault)
+	}
+	return events
+}
+
+// themeSoundFor is the sound the selected theme gives event, or "" without
+// a theme. An unknown theme is silent here; doctor reports it.
+func themeSoundFor(event string) string {
+	name := strings.TrimSpace(getenv(soundThemeEnv))
+	if name == "" {
+		return ""
+	}
+	themes, _ := loadSoundThemes()
+	theme, ok := themes[name]
+	if !ok {
+		return ""
+	}
+	return theme.soundFor(event)
+}
+
+// soundIsFile tells an audio file from a system sound name.
+func soundIsFile(sound string) bool {
+	return strings.HasPrefix(sound, "/")
+}
+
+// soundPlayer is the command line that plays sound, or nil when nothing
+// here can.
+func soundPlayer(sound string) []string {
+	switch hostOS {
+	case "darwin":
+		if !soundIsFile(sound) {
+			sound = systemSoundFile(sound)
+		}
+		if path, ok := lookupCmd("afplay"); ok && sound != "" {
+			return []string{path, sound}
+		}
+	case "linux":
+		if path, ok := lookupCmd("canberra-gtk-play"); ok {
+			if soundIsFile(sound) {
+				return []string{path, "-f", sound}
+			}
+			return []string{path, "-i", sound}
+		}
+		if path, ok := lookupCmd("paplay"); ok && soundIsFile(sound) {
+			return []string{path, sound}
+		}
+	}
+	return nil
+}
+
+// systemSoundFile finds a macOS sound name in the folders NSSound and
+// terminal-notifier search.
+func systemSoundFile(name string) string {
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, "Library", "Sounds"))
+	}
+	dirs = append(dirs, "/Library/Sounds", "/System/Library/Sounds")
+	for _, dir := range dirs {
+		for _, ext := range []string{".aiff", ".aif", ".wav", ".caf", ".m4a", ".mp3"} {
+			path := filepath.Join(dir, name+ext)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// playSound plays sound, waiting for it to finish when wait is set. Tests
+// record the sounds through it instead of playing them.
+var playSound = func(sound string, wait bool) error {
+	argv := soundPlayer(sound)
+	if argv == nil {
+		return fmt.Errorf("no player for sound %s", sound)
+	}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	if wait {
+		return runLogged(cmd)
+	}
+	err := cmd.Start()
+	logExecStart(cmd, err)
+	if err != nil {
+		return err
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
+
+// backendSound is what to hand a backend that only takes sound names: ""
+// for a file sound, which playFileSound plays once the notification is up.
+func backendSound(sound string) string {
+	if soundIsFile(sound) {
+		return ""
+	}
+	return sound
+}
+
+// playFileSound plays sound if it is an audio file, for the backends
+// backendSound left it out of.
+func playFileSound(sound string) {
+	if soundIsFile(sound) {
+		_ = playSound(sound, false)
+	}
+}
+
+type soundsResult struct {
+	commandResult
+	Themes []soundTheme `json:"themes,omitempty"`
+}
+
+// runSounds lists sound themes or plays one: `sounds list`,
+// `sounds preview [theme] [event]`.
+func runSounds(args []string) error {
+	if len(args) == 0 {
+		return usageError(errors.New("sounds requires one of: list, preview"))
+	}
+	command, args := args[0], args[1:]
+	fs := flag.NewFlagSet("sounds "+command, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	outFlags := addOutputFlags(fs)
+	// The theme and event may come before or after the flags.
+	var positional []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		positional, args = append(positional, args[0]), args[1:]
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	positional = append(positional, fs.Args()...)
+	out := outFlags.output()
+	themes, err := loadSoundThemes()
+	if err != nil {
+		return err
+	}
+	current := strings.TrimSpace(getenv(soundThemeEnv))
+
+	switch command {
+	case "list":
+		if len(positional) > 0 {
+			return usageError(errors.New("sounds list takes no arguments"))
+		}
+		names := make([]string, 0, len(themes))
+		for name := range themes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		list := make([]soundTheme, 0, len(names))
+		var b strings.Builder
+		w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "THEME\tSOURCE\tSOUNDS")
+		for _, name := range names {
+			theme := themes[name]
+			list = append(list, theme)
+			marker := ""
+			if name == current {
+				marker = " *"
+			}
+			var sounds []string
+			for _, event := range theme.events() {
+				sounds = append(sounds, event+"="+filepath.Base(theme.Sounds[event]))
+			}
+			fmt.Fprintf(w, "%s%s\t%s\t%s\n", name, marker, theme.Source, strings.Join(sounds, " "))
+		}
+		_ = w.Flush()
+		out.Printf("%s", b.String())
+		if current != "" {
+			if _, ok := themes[current]; !ok {
+				out.Printf("sound_theme %q is not installed\n", current)
+			}
+		}
+		return out.Result(soundsResult{commandResult: commandResult{Command: "sounds", Status: "ok", Count: len(list)}, Themes: list})
+
+	case "preview":
+		if len(positional) > 2 {
+			return usageError(errors.New("sounds preview takes a theme and an optional event"))
+		}
+		name := current
+		if len(positional) > 0 {
+			name = positional[0]
+		}
+		if name == "" {
+			return usageError(errors.New("sounds preview needs a theme name, or sound_theme set"))
+		}
+		theme, ok := themes[name]
+		if !ok {
+			return usageError(fmt.Errorf("unknown sound theme %q (see `%s sounds list`)", name, appName))
+		}
+		events := theme.events()
+		if len(positional) > 1 {
+			event := positional[1]
+			if theme.soundFor(event) == "" {
+				return usageError(fmt.Errorf("theme %s has no sound for %s", name, event))
+			}
+			events = []string{event}
+		}
+		for i, event := range events {
+			if i > 0 {
+				time.Sleep(300 * time.Millisecond)
+			}
+			sound := theme.soundFor(event)
+			out.Printf("%s: %s\n", event, sound)
+			if err := playSound(sound, true); err != nil {
+				return backendError(fmt.Errorf("play %s: %w", sound, err))
+			}
+		}
+		return out.Result(commandResult{Command: "sounds", Status: "played", Count: len(events)})
+	}
+	return usageError(fmt.Errorf("unknown sounds command: %s", command))
+}
+
+func addSoundThemeDoctorCheck(report *doctorReport) {
+	name := strings.TrimSpace(getenv(soundThemeEnv))
+	if name == "" {
+		return
+	}
+	themes, _ := loadSoundThemes()
+	theme, ok := themes[name]
+	if !ok {
+		report.add(doctorSettings, checkWarn, "sound theme", fmt.Sprintf("%q is not installed; notifications keep their own sounds (see `%s sounds list`)", name, appName), false)
+		return
+	}
+	detail := fmt.Sprintf("%s (%s), %d sounds", name, theme.Source, len(theme.Sounds))
+	for _, sound := range theme.Sounds {
+		// terminal-notifier and osascript take names only; files need afplay.
+		if hostOS == "darwin" && soundIsFile(sound) && soundPlayer(sound) == nil {
+			report.add(doctorSettings, checkWarn, "sound theme", detail+"; afplay not found, so banners play no sound files", false)
+			return
+		}
+	}
+	report.add(doctorSettings, checkOK, "sound theme", detail, false)
+}
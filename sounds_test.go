@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSoundThemes(t *testing.T) {
+	configDir := useTempUserConfigDir(t)
+	themeDir := filepath.Join(configDir, appName, soundThemesDir, "studio")
+	if err := os.MkdirAll(themeDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"approval-requested.aiff", "default.wav", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(themeDir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	themes, err := loadSoundThemes()
+	if err != nil {
+		t.Fatalf("loadSoundThemes() error = %v", err)
+	}
+	if themes["bright"].Source != soundThemeBuiltIn || themes["bright"].soundFor("agent-turn-complete") != "Hero" {
+		t.Fatalf("bright = %+v", themes["bright"])
+	}
+	studio := themes["studio"]
+	if len(studio.Sounds) != 2 || studio.soundFor("approval-requested") != filepath.Join(themeDir, "approval-requested.aiff") {
+		t.Fatalf("studio = %+v", studio)
+	}
+	if got := studio.soundFor("agent-error"); got != filepath.Join(themeDir, "default.wav") {
+		t.Fatalf("studio falls back to %q, want default.wav", got)
+	}
+
+	t.Setenv(soundThemeEnv, "bright")
+	requests, err := buildHookNotifications(map[string]any{"type": "agent-turn-complete", "thread-id": "t1"})
+	if err != nil || len(requests) == 0 || requests[0].Sound != "Hero" {
+		t.Fatalf("buildHookNotifications sound = %+v, %v; want Hero", requests, err)
+	}
+	t.Setenv(soundThemeEnv, "missing")
+	if got := themeSoundFor("agent-turn-complete"); got != "" {
+		t.Fatalf("unknown theme gave %q", got)
+	}
+}
+
+func TestSoundsPreview(t *testing.T) {
+	useTempUserConfigDir(t)
+	t.Setenv(soundThemeEnv, "")
+	var played []string
+	stub(t, &playSound, func(sound string, wait bool) error {
+		played = append(played, sound)
+		return nil
+	})
+
+	if err := runSounds([]string{"preview", "retro", "agent-error", "--quiet"}); err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if len(played) != 1 || played[0] != "Funk" {
+		t.Fatalf("played %v, want Funk", played)
+	}
+	if err := runSounds([]string{"preview", "--quiet"}); exitCodeFor(err) != exitUsage {
+		t.Fatalf("preview without a theme: err = %v, want a usage error", err)
+	}
+	if err := runSounds([]string{"preview", "nope", "--quiet"}); exitCodeFor(err) != exitUsage {
+		t.Fatalf("preview of an unknown theme: err = %v, want a usage error", err)
+	}
+}
+
+func TestBackendSoundIsPure(t *testing.T) {
+	stub(t, &playSound, func(sound string, wait bool) error {
+		t.Fatalf("backendSound played %s", sound)
+		return nil
+	})
+	if got := backendSound("/Users/me/sounds/done.aiff"); got != "" {
+		t.Fatalf("backendSound(file) = %q, want it left to playFileSound", got)
+	}
+	if got := backendSound("Glass"); got != "Glass" {
+		t.Fatalf("backendSound(name) = %q", got)
+	}
+}
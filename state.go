@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	stateFilename              = "state.json"
+	stateLockFilename          = "state.lock"
+	defaultProjectMuteDuration = time.Hour
+
+	// recentEventWindow is how long a delivered event key is remembered for
+	// deduplication.
+	recentEventWindow = 10 * time.Minute
+)
+
+// notifyState is the small persistent state shared between hook and action
+// invocations. It lives next to the compiled helper in the runtime state dir.
+type notifyState struct {
+	// ProjectMutes maps an absolute working directory to the unix time at
+	// which its mute expires.
+	ProjectMutes map[string]int64 `json:"project_mutes,omitempty"`
+	// RecentEvents maps an event dedup key to the unix time it was first
+	// seen, so the same turn is never notified twice.
+	RecentEvents map[string]int64 `json:"recent_events,omitempty"`
+	// PendingApprovals maps a thread id to its unanswered approval, so an
+	// answer on one device withdraws the prompt from the others.
+	PendingApprovals map[string]pendingApproval `json:"pending_approvals,omitempty"`
+	// AnsweredApprovals maps a thread id to the unix time its approval was
+	// answered, so a late click on a banner left behind is not typed into
+	// the session.
+	AnsweredApprovals map[string]int64 `json:"answered_approvals,omitempty"`
+	// Paused silences every notification until `resume`; MutedUntil does
+	// the same until a unix time.
+	Paused     bool  `json:"paused,omitempty"`
+	MutedUntil int64 `json:"muted_until,omitempty"`
+	// PermissionProblem is the last action that failed on a revoked
+	// Accessibility or Automation grant, until one succeeds again.
+	PermissionProblem *permissionProblem `json:"permission_problem,omitempty"`
+	// LockQueue holds hook payloads that arrived while the screen was
+	// locked, oldest first.
+	LockQueue []lockedEvent `json:"lock_queue,omitempty"`
+	// NoiseScores counts shown and acted-on notifications per class, and
+	// NoiseThreads the class of each thread's last notification.
+	NoiseScores  map[string]noiseScore  `json:"noise_scores,omitempty"`
+	NoiseThreads map[string]noiseThread `json:"noise_threads,omitempty"`
+	// Digest holds events of downgraded classes until they are summarized.
+	Digest []lockedEvent `json:"digest,omitempty"`
+	// RecentNotifications maps a sent notification's group and message to
+	// the unix time it was sent, and SentTimes lists recent send times, for
+	// CODEX_NOTIFY_DEDUPE_SECONDS and CODEX_NOTIFY_RATE_LIMIT.
+	RecentNotifications map[string]int64 `json:"recent_notifications,omitempty"`
+	SentTimes           []int64          `json:"sent_times,omitempty"`
+	// RememberedChoices maps a project directory to the answer `action
+	// choose` gives there without asking.
+	RememberedChoices map[string]string `json:"remembered_choices,omitempty"`
+	// SinkQueue holds remote sink deliveries that failed transiently, in
+	// the order they are retried.
+	SinkQueue []queuedDelivery `json:"sink_queue,omitempty"`
+	// OpenTurns maps a thread id to its turn while it has not ended, and
+	// StaleSessionsOffered is the boot time the daemon last offered the
+	// ones left from before a restart for.
+	OpenTurns            map[string]openTurn `json:"open_turns,omitempty"`
+	StaleSessionsOffered int64               `json:"stale_sessions_offered,omitempty"`
+	// ScreenShare is the last screen sharing check, reused until it is
+	// stale.
+	ScreenShare *screenShareSample `json:"screen_share,omitempty"`
+}
+
+func statePath() (string, error) {
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, stateFilename), nil
+}
+
+func loadState() (notifyState, error) {
+	path, err := statePath()
+	if err != nil {
+		return notifyState{}, err
+	}
+
+	content, err := readFileMaybe(path)
+	if err != nil {
+		return notifyState{}, err
+	}
+	if len(content) == 0 {
+		return notifyState{}, nil
+	}
+
+	var state notifyState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return notifyState{}, fmt.Errorf("parse state: %w", err)
+	}
+	return state, nil
+}
+
+func saveState(state notifyState) error {
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode state: %w", err)
+	}
+	if err := writeFileAtomic(path, append(content, '\n'), privateFileMode); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	return nil
+}
+
+// updateState applies fn under an exclusive lock, so concurrent hook
+// invocations never lose each other's writes.
+func updateState(fn func(*notifyState)) error {
+	unlock, err := lockState()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := loadState()
+	if err != nil {
+		return err
+	}
+	fn(&state)
+	return saveState(state)
+}
+
+func lockState() (func(), error) {
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(stateDir, stateLockFilename), os.O_CREATE|os.O_RDWR, privateFileMode)
+	if err != nil {
+		return nil, fmt.Errorf("open state lock: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("lock state: %w", err)
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+	}, nil
+}
+
+// eventDedupKey identifies one notification-worthy occurrence across every
+// source that may report it. Events without a turn id (such as approval
+// requests) have no stable identity and are never deduplicated.
+func eventDedupKey(payload map[string]any) string {
+	thread := payloadThreadID(payload)
+	turn := payloadTurnID(payload)
+	if thread == "" || turn == "" {
+		return ""
+	}
+	return payloadEventName(payload) + "|" + thread + "|" + turn
+}
+
+// claimEvent records key as seen and reports whether this caller is the
+// first to see it within recentEventWindow.
+func claimEvent(key string, now time.Time) (bool, error) {
+	if key == "" {
+		return true, nil
+	}
+	first := false
+	err := updateState(func(s *notifyState) {
+		if s.RecentEvents == nil {
+			s.RecentEvents = map[string]int64{}
+		}
+		for k, seenAt := range s.RecentEvents {
+			if now.Sub(time.Unix(seenAt, 0)) > recentEventWindow {
+				delete(s.RecentEvents, k)
+			}
+		}
+		if _, seen := s.RecentEvents[key]; !seen {
+			s.RecentEvents[key] = now.Unix()
+			first = true
+		}
+	})
+	return first, err
+}
+
+// forgetEvent drops key from the recently seen events, so the next claim of
+// it is first again.
+func forgetEvent(key string) {
+	if key == "" {
+		return
+	}
+	_ = updateState(func(s *notifyState) { delete(s.RecentEvents, key) })
+}
+
+func muteProject(cwd string, d time.Duration) error {
+	cwd = normalizeProjectPath(cwd)
+	if cwd == "" {
+		return errors.New("mute project requires a working directory")
+	}
+	now := time.Now()
+	return updateState(func(s *notifyState) {
+		if s.ProjectMutes == nil {
+			s.ProjectMutes = map[string]int64{}
+		}
+		for dir, expiresAt := range s.ProjectMutes {
+			if now.Unix() > expiresAt {
+				delete(s.ProjectMutes, dir)
+			}
+		}
+		s.ProjectMutes[cwd] = now.Add(d).Unix()
+	})
+}
+
+func isProjectMuted(state notifyState, cwd string, now time.Time) bool {
+	cwd = normalizeProjectPath(cwd)
+	if cwd == "" {
+		return false
+	}
+	for dir, expiresAt := range state.ProjectMutes {
+		if now.Unix() > expiresAt {
+			continue
+		}
+		if cwd == dir || strings.HasPrefix(cwd, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeProjectPath(cwd string) string {
+	cwd = strings.TrimSpace(cwd)
+	if cwd == "" {
+		return ""
+	}
+	return filepath.Clean(cwd)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// statusReport is `codex-notify status`: what a status bar or launcher
+// needs at a glance. Status is "paused", "muted", "pending" while
+// approvals wait, and "idle" otherwise.
+type statusReport struct {
+	commandResult
+	Paused           bool             `json:"paused"`
+	MutedUntil       *time.Time       `json:"muted_until,omitempty"`
+	ProjectMutes     []statusMute     `json:"project_mutes"`
+	Daemon           bool             `json:"daemon"`
+	PendingApprovals []statusApproval `json:"pending_approvals"`
+	LastEvent        *historyEntry    `json:"last_event,omitempty"`
+}
+
+type statusMute struct {
+	Cwd   string    `json:"cwd"`
+	Until time.Time `json:"until"`
+}
+
+type statusApproval struct {
+	Thread       string     `json:"thread_id"`
+	Cwd          string     `json:"cwd,omitempty"`
+	Since        time.Time  `json:"since"`
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+}
+
+func buildStatusReport(now time.Time) (statusReport, error) {
+	report := statusReport{
+		commandResult:    commandResult{Command: "status", Status: "idle"},
+		ProjectMutes:     []statusMute{},
+		PendingApprovals: []statusApproval{},
+		Daemon:           daemonListening(),
+	}
+	state, err := loadState()
+	if err != nil {
+		return report, err
+	}
+	for dir, until := range state.ProjectMutes {
+		if now.Unix() < until {
+			report.ProjectMutes = append(report.ProjectMutes, statusMute{Cwd: dir, Until: time.Unix(until, 0)})
+		}
+	}
+	sort.Slice(report.ProjectMutes, func(i, j int) bool { return report.ProjectMutes[i].Cwd < report.ProjectMutes[j].Cwd })
+	for _, p := range state.PendingApprovals {
+		if now.Sub(time.Unix(p.Since, 0)) >= widgetApprovalWindow {
+			continue
+		}
+		a := statusApproval{Thread: p.Thread, Cwd: p.Cwd, Since: time.Unix(p.Since, 0)}
+		if p.SnoozedUntil > now.Unix() {
+			until := time.Unix(p.SnoozedUntil, 0)
+			a.SnoozedUntil = &until
+		}
+		report.PendingApprovals = append(report.PendingApprovals, a)
+	}
+	// Newest first, as `action --latest` picks them.
+	sort.Slice(report.PendingApprovals, func(i, j int) bool {
+		a, b := report.PendingApprovals[i], report.PendingApprovals[j]
+		if !a.Since.Equal(b.Since) {
+			return a.Since.After(b.Since)
+		}
+		return a.Thread < b.Thread
+	})
+	report.Count = len(report.PendingApprovals)
+
+	if until, muted := globalMute(state, now); muted {
+		report.Paused = until.IsZero()
+		if !report.Paused {
+			report.MutedUntil = &until
+		}
+	}
+	switch {
+	case report.Paused:
+		report.Status = "paused"
+	case report.MutedUntil != nil:
+		report.Status = "muted"
+	case len(report.PendingApprovals) > 0:
+		report.Status = "pending"
+	}
+
+	if entries, err := loadHistory(time.Time{}); err == nil {
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].Event != "action" {
+				report.LastEvent = &entries[i]
+				break
+			}
+		}
+	}
+	return report, nil
+}
+
+func formatStatus(report statusReport, now time.Time) string {
+	var b strings.Builder
+	switch {
+	case report.Paused:
+		b.WriteString("notifications: paused\n")
+	case report.MutedUntil != nil:
+		fmt.Fprintf(&b, "notifications: %s\n", describeGlobalMute(*report.MutedUntil, now))
+	default:
+		b.WriteString("notifications: on\n")
+	}
+	for _, m := range report.ProjectMutes {
+		fmt.Fprintf(&b, "  %s muted for %s\n", filepath.Base(m.Cwd), formatCountdown(m.Until.Sub(now)))
+	}
+	if report.Daemon {
+		b.WriteString("daemon: running\n")
+	} else {
+		b.WriteString("daemon: not running\n")
+	}
+	fmt.Fprintf(&b, "pending approvals: %d\n", len(report.PendingApprovals))
+	for _, a := range report.PendingApprovals {
+		project := "-"
+		if a.Cwd != "" {
+			project = filepath.Base(a.Cwd)
+		}
+		line := fmt.Sprintf("  %s  %s  waiting %s", a.Thread, project, formatCountdown(now.Sub(a.Since)))
+		if a.SnoozedUntil != nil {
+			line += " (snoozed until " + a.SnoozedUntil.Local().Format("15:04") + ")"
+		}
+		b.WriteString(line + "\n")
+	}
+	if e := report.LastEvent; e != nil {
+		project := ""
+		if e.Cwd != "" {
+			project = " " + filepath.Base(e.Cwd)
+		}
+		fmt.Fprintf(&b, "last event: %s %s%s (%s)\n", relativeTime(e.Time, now), e.Event, project, historyOutcome(*e))
+	}
+	return b.String()
+}
+
+// runStatus prints whether notifications are on, the daemon, pending
+// approvals, and the last event.
+func runStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	outFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+	out := outFlags.output()
+
+	now := time.Now()
+	report, err := buildStatusReport(now)
+	if err != nil {
+		return err
+	}
+	out.Printf("%s", formatStatus(report, now))
+	return out.Result(report)
+}
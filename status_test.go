@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildStatusReport(t *testing.T) {
+	useTempHome(t)
+	now := time.Now()
+
+	report, err := buildStatusReport(now)
+	if err != nil {
+		t.Fatalf("buildStatusReport() error = %v", err)
+	}
+	if report.Status != "idle" || report.Daemon || report.LastEvent != nil || len(report.PendingApprovals) != 0 {
+		t.Fatalf("empty report = %+v, want idle", report)
+	}
+
+	path, err := eventsPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := appendEvent(path, &eventRecord{Event: "agent-turn-complete", Thread: "t-1", Cwd: "/src/api", Status: "sent"}, now.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateState(func(s *notifyState) {
+		s.PendingApprovals = map[string]pendingApproval{
+			"old":   {Thread: "old", Since: now.Add(-10 * time.Minute).Unix()},
+			"new":   {Thread: "new", Cwd: "/src/web", Since: now.Add(-time.Minute).Unix(), SnoozedUntil: now.Add(4 * time.Minute).Unix()},
+			"stale": {Thread: "stale", Since: now.Add(-2 * widgetApprovalWindow).Unix()},
+		}
+		s.ProjectMutes = map[string]int64{"/src/api": now.Add(time.Hour).Unix()}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = buildStatusReport(now)
+	if err != nil {
+		t.Fatalf("buildStatusReport() error = %v", err)
+	}
+	if report.Status != "pending" || report.Count != 2 {
+		t.Fatalf("status = %s with %d pending, want pending with 2", report.Status, report.Count)
+	}
+	if report.PendingApprovals[0].Thread != "new" || report.PendingApprovals[0].SnoozedUntil == nil || report.PendingApprovals[1].Thread != "old" {
+		t.Fatalf("pending = %+v, want new (snoozed) then old", report.PendingApprovals)
+	}
+	if len(report.ProjectMutes) != 1 || report.ProjectMutes[0].Cwd != "/src/api" {
+		t.Fatalf("project mutes = %+v", report.ProjectMutes)
+	}
+	if report.LastEvent == nil || report.LastEvent.Thread != "t-1" {
+		t.Fatalf("last event = %+v, want t-1", report.LastEvent)
+	}
+	text := formatStatus(report, now)
+	for _, want := range []string{"notifications: on", "api muted for", "daemon: not running", "pending approvals: 2", "snoozed until", "last event: "} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("formatStatus() = %q, missing %q", text, want)
+		}
+	}
+
+	if err := updateState(func(s *notifyState) { s.Paused = true }); err != nil {
+		t.Fatal(err)
+	}
+	if report, _ = buildStatusReport(now); report.Status != "paused" || !report.Paused {
+		t.Fatalf("status while paused = %s", report.Status)
+	}
+}
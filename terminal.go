@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+)
+
+// terminalProfile is what codex-notify knows about a terminal app: the keys
+// that scroll its scrollback to the bottom, where Codex's prompt is.
+type terminalProfile struct {
+	BundleID   string
+	Name       string
+	RevealKeys []string
+}
+
+var terminalProfiles = []terminalProfile{
+	{BundleID: "com.mitchellh.ghostty", Name: "Ghostty", RevealKeys: []string{"cmd+end"}},
+	{BundleID: "com.apple.Terminal", Name: "Terminal", RevealKeys: []string{"cmd+end"}},
+	{BundleID: "com.googlecode.iterm2", Name: "iTerm2", RevealKeys: []string{"cmd+end"}},
+	{BundleID: "net.kovidgoyal.kitty", Name: "kitty", RevealKeys: []string{"cmd+end"}},
+}
+
+// currentTerminalProfile returns the profile for the configured terminal,
+// or a bare one without reveal keys for terminals not listed.
+func currentTerminalProfile() terminalProfile {
+	bundleID := terminalBundleID()
+	for _, p := range terminalProfiles {
+		if strings.EqualFold(p.BundleID, bundleID) {
+			return p
+		}
+	}
+	return terminalProfile{BundleID: bundleID, Name: bundleID}
+}
+
+// revealKeySequence is sent after codex-notify activates the terminal for
+// an action, so a long scrollback does not hide the approval prompt. It is
+// off by default; CODEX_NOTIFY_REVEAL_KEYS=auto uses the terminal profile.
+func revealKeySequence() []string {
+	raw := strings.TrimSpace(getenv("CODEX_NOTIFY_REVEAL_KEYS"))
+	if strings.EqualFold(raw, "auto") {
+		return currentTerminalProfile().RevealKeys
+	}
+	if raw == "" {
+		return nil
+	}
+	return keySequenceFromEnv("CODEX_NOTIFY_REVEAL_KEYS", "")
+}
+
+func addTerminalDoctorCheck(report *doctorReport) {
+	profile := currentTerminalProfile()
+	detail := profile.BundleID
+	if profile.Name != profile.BundleID {
+		detail = profile.Name + " (" + profile.BundleID + ")"
+	}
+	if reveal := revealKeySequence(); len(reveal) > 0 {
+		detail += ", reveal keys " + strings.Join(reveal, ",")
+	}
+	report.add(doctorPlatform, checkOK, "terminal", detail, false)
+}
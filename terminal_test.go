@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRevealKeySequence(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_TERMINAL_BUNDLE_ID", "com.googlecode.iterm2")
+
+	t.Setenv("CODEX_NOTIFY_REVEAL_KEYS", "")
+	if got := revealKeySequence(); got != nil {
+		t.Fatalf("reveal keys are on by default: %v", got)
+	}
+
+	t.Setenv("CODEX_NOTIFY_REVEAL_KEYS", "auto")
+	if got := revealKeySequence(); !reflect.DeepEqual(got, []string{"cmd+end"}) {
+		t.Fatalf("auto reveal keys = %v, want the iTerm2 profile", got)
+	}
+
+	t.Setenv("CODEX_NOTIFY_REVEAL_KEYS", "end, ctrl+l")
+	if got := revealKeySequence(); !reflect.DeepEqual(got, []string{"end", "ctrl+l"}) {
+		t.Fatalf("explicit reveal keys = %v", got)
+	}
+
+	t.Setenv("CODEX_NOTIFY_TERMINAL_BUNDLE_ID", "org.example.unknown")
+	t.Setenv("CODEX_NOTIFY_REVEAL_KEYS", "auto")
+	if got := revealKeySequence(); len(got) != 0 {
+		t.Fatalf("auto reveal keys for an unknown terminal = %v, want none", got)
+	}
+}
+
+func TestKeyScript(t *testing.T) {
+	tests := map[string]string{
+		"enter":         `tell application "System Events" to key code 36`,
+		"cmd+end":       `tell application "System Events" to key code 119 using {command down}`,
+		"ctrl+l":        `tell application "System Events" to keystroke "l" using {control down}`,
+		"cmd+shift+end": `tell application "System Events" to key code 119 using {command down, shift down}`,
+		"c++":           `tell application "System Events" to keystroke "c++"`,
+		"a+b":           `tell application "System Events" to keystroke "a+b"`,
+	}
+	for token, want := range tests {
+		if got := keyScript(token); got != want {
+			t.Errorf("keyScript(%q) = %q, want %q", token, got, want)
+		}
+	}
+}
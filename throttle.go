@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dedupeSkip   = "skip"
+	dedupeUpdate = "update"
+
+	// rateLimitWindow is the period CODEX_NOTIFY_RATE_LIMIT counts over.
+	rateLimitWindow = time.Minute
+)
+
+// dedupeWindow is CODEX_NOTIFY_DEDUPE_SECONDS: a notification with the
+// same group and message as one sent that recently is a repeat. Zero turns
+// it off.
+func dedupeWindow() time.Duration {
+	raw := strings.TrimSpace(getenv("CODEX_NOTIFY_DEDUPE_SECONDS"))
+	if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return 0
+}
+
+// dedupeMode is CODEX_NOTIFY_DEDUPE: "skip" drops a repeat, "update"
+// resends it so a notifier that replaces by group refreshes the banner in
+// place instead of stacking a new one.
+func dedupeMode() string {
+	if strings.TrimSpace(strings.ToLower(getenv("CODEX_NOTIFY_DEDUPE"))) == dedupeUpdate {
+		return dedupeUpdate
+	}
+	return dedupeSkip
+}
+
+// rateLimit is CODEX_NOTIFY_RATE_LIMIT, the most desktop notifications sent
+// per minute. Zero turns it off.
+func rateLimit() int {
+	raw := strings.TrimSpace(getenv("CODEX_NOTIFY_RATE_LIMIT"))
+	if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+		return n
+	}
+	return 0
+}
+
+// updatesInPlace reports whether the notifier that would show a request
+// replaces an earlier one from the same group.
+func updatesInPlace() bool {
+	for _, backend := range notificationBackends() {
+		if backend.Available() {
+			return backend.Capabilities().Updates
+		}
+	}
+	return false
+}
+
+func notificationContentKey(req notificationRequest) string {
+	return req.Group + "|" + req.Message
+}
+
+// throttleNotifications drops repeats and anything over the rate limit from
+// requests. Approvals block Codex, so they are never dropped, though they
+// count toward the limit. The status names why everything was dropped:
+// "duplicate" or "limited". Nothing is recorded until recordNotificationSent,
+// so a notification that fails to send does not hold back its retry.
+func throttleNotifications(payload map[string]any, requests []notificationRequest, now time.Time) ([]notificationRequest, string) {
+	window, limit := dedupeWindow(), rateLimit()
+	if window == 0 && limit == 0 {
+		return requests, ""
+	}
+	exempt := payloadEventName(payload) == "approval-requested"
+	resendRepeats := dedupeMode() == dedupeUpdate && updatesInPlace()
+
+	var kept []notificationRequest
+	status := ""
+	// A state failure must not cost the notification.
+	if err := updateState(func(s *notifyState) {
+		kept, status = nil, ""
+		pruneThrottleState(s, window, now)
+		seen := map[string]bool{}
+		for key := range s.RecentNotifications {
+			seen[key] = true
+		}
+		sent := len(s.SentTimes)
+		for _, req := range requests {
+			key := notificationContentKey(req)
+			if seen[key] && window > 0 && !exempt && !resendRepeats {
+				status = "duplicate"
+				continue
+			}
+			if limit > 0 && sent >= limit && !exempt {
+				status = "limited"
+				continue
+			}
+			seen[key] = true
+			sent++
+			kept = append(kept, req)
+		}
+	}); err != nil {
+		return requests, ""
+	}
+	return kept, status
+}
+
+// recordNotificationSent counts req, which was just sent, toward the
+// repeat window and the rate limit.
+func recordNotificationSent(req notificationRequest, now time.Time) {
+	window, limit := dedupeWindow(), rateLimit()
+	if window == 0 && limit == 0 {
+		return
+	}
+	_ = updateState(func(s *notifyState) {
+		pruneThrottleState(s, window, now)
+		if window > 0 {
+			if s.RecentNotifications == nil {
+				s.RecentNotifications = map[string]int64{}
+			}
+			s.RecentNotifications[notificationContentKey(req)] = now.Unix()
+		}
+		if limit > 0 {
+			s.SentTimes = append(s.SentTimes, now.Unix())
+		}
+	})
+}
+
+// pruneThrottleState forgets sends older than the repeat window and the
+// rate limit's minute.
+func pruneThrottleState(s *notifyState, window time.Duration, now time.Time) {
+	for k, sentAt := range s.RecentNotifications {
+		if window == 0 || now.Sub(time.Unix(sentAt, 0)) > window {
+			delete(s.RecentNotifications, k)
+		}
+	}
+	recent := s.SentTimes[:0]
+	for _, sentAt := range s.SentTimes {
+		if now.Sub(time.Unix(sentAt, 0)) < rateLimitWindow {
+			recent = append(recent, sentAt)
+		}
+	}
+	s.SentTimes = recent
+}
+
+func addThrottleDoctorCheck(report *doctorReport) {
+	window, limit := dedupeWindow(), rateLimit()
+	if window == 0 && limit == 0 {
+		return
+	}
+	var parts []string
+	if window > 0 {
+		parts = append(parts, fmt.Sprintf("repeats within %s: %s", window, dedupeMode()))
+	}
+	if limit > 0 {
+		sent := 0
+		if state, err := loadState(); err == nil {
+			for _, sentAt := range state.SentTimes {
+				if time.Since(time.Unix(sentAt, 0)) < rateLimitWindow {
+					sent++
+				}
+			}
+		}
+		parts = append(parts, fmt.Sprintf("at most %d per minute (%d in the last minute)", limit, sent))
+	}
+	report.add(doctorSettings, checkOK, "throttle", strings.Join(parts, "; "), false)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleNotifications(t *testing.T) {
+	useTempHome(t)
+	t.Setenv("CODEX_NOTIFY_DEDUPE_SECONDS", "30")
+	t.Setenv("CODEX_NOTIFY_RATE_LIMIT", "2")
+	turn := map[string]any{"type": "agent-turn-complete"}
+	req := func(msg string) []notificationRequest {
+		return []notificationRequest{{Group: "g", Message: msg}}
+	}
+	now := time.Unix(1_700_000_000, 0)
+	send := func(payload map[string]any, reqs []notificationRequest, at time.Time) ([]notificationRequest, string) {
+		kept, status := throttleNotifications(payload, reqs, at)
+		for _, r := range kept {
+			recordNotificationSent(r, at)
+		}
+		return kept, status
+	}
+
+	// A send that failed is not recorded, so its retry goes out.
+	if kept, _ := throttleNotifications(turn, req("a"), now); len(kept) != 1 {
+		t.Fatal("first attempt was dropped")
+	}
+	if kept, status := send(turn, req("a"), now); len(kept) != 1 || status != "" {
+		t.Fatalf("retry = %v, %q", kept, status)
+	}
+	if kept, status := send(turn, req("a"), now.Add(10*time.Second)); len(kept) != 0 || status != "duplicate" {
+		t.Fatalf("repeat = %v, %q", kept, status)
+	}
+	if kept, _ := send(turn, req("a"), now.Add(40*time.Second)); len(kept) != 1 {
+		t.Fatal("repeat after the window was dropped")
+	}
+	if kept, status := send(turn, req("b"), now.Add(50*time.Second)); len(kept) != 0 || status != "limited" {
+		t.Fatalf("over the limit = %v, %q", kept, status)
+	}
+	approval := map[string]any{"type": "approval-requested"}
+	if kept, _ := send(approval, req("a"), now.Add(50*time.Second)); len(kept) != 1 {
+		t.Fatal("approval was dropped")
+	}
+	if kept, _ := send(turn, req("c"), now.Add(2*time.Minute)); len(kept) != 1 {
+		t.Fatal("limit did not reset after a minute")
+	}
+}
+
+func TestThrottleUpdateModeNeedsUpdatingNotifier(t *testing.T) {
+	useTempHome(t)
+	t.Setenv("CODEX_NOTIFY_DEDUPE_SECONDS", "30")
+	t.Setenv("CODEX_NOTIFY_DEDUPE", "update")
+	turn := map[string]any{"type": "agent-turn-complete"}
+	reqs := []notificationRequest{{Group: "g", Message: "a"}}
+	now := time.Unix(1_700_000_000, 0)
+	recordNotificationSent(reqs[0], now)
+	// The capture backend cannot replace a banner, so a repeat would stack.
+	if kept, status := throttleNotifications(turn, reqs, now.Add(time.Second)); len(kept) != 0 || status != "duplicate" {
+		t.Fatalf("repeat = %v, %q", kept, status)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// timelineGap is the quiet stretch worth calling out: a run that waits this
+// long was most likely stuck on someone.
+const timelineGap = 5 * time.Minute
+
+// recordActionEvent logs a click or reply action next to the hook events,
+// so a thread's timeline shows who answered and when. The action also
+// counts as acting on the thread's last notification for its noise score.
+func recordActionEvent(action, thread, cwd, via, status string) {
+	recordNoiseActed(thread, time.Now())
+	path, err := eventsPath()
+	if err != nil {
+		return
+	}
+	message := action
+	if via != "" {
+		message += " (" + via + ")"
+	}
+	_ = appendEvent(path, &eventRecord{
+		Event:   "action",
+		Thread:  thread,
+		Cwd:     cwd,
+		Message: message,
+		Status:  status,
+	}, time.Now())
+}
+
+// timelineEntry is one line of `codex-notify thread`, from the event log or
+// the receipts log.
+type timelineEntry struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Kind   string    `json:"kind"`
+	Status string    `json:"status,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// threadTimeline merges everything logged about thread, oldest first. The
+// wrap session that ran the thread contributes its start and exit.
+func threadTimeline(thread string) ([]timelineEntry, error) {
+	entries := []timelineEntry{}
+	sessions := map[string]bool{}
+	var records []eventRecord
+	var kinds []string
+
+	path, err := eventsPath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := readFileMaybe(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range splitLines(content) {
+		var rec eventRecord
+		if json.Unmarshal([]byte(line), &rec) != nil {
+			continue
+		}
+		records = append(records, rec)
+		if rec.Thread == thread && rec.Session != "" {
+			sessions[rec.Session] = true
+		}
+	}
+	for _, rec := range records {
+		if rec.Thread != thread && !(rec.Thread == "" && sessions[rec.Session]) {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, rec.Time)
+		if err != nil {
+			continue
+		}
+		detail := rec.Message
+		if detail == "" {
+			detail = rec.Title
+		}
+		entries = append(entries, timelineEntry{Time: t, Source: "event", Kind: rec.Event, Status: rec.Status, Detail: detail})
+		if !containsString(kinds, rec.Event) {
+			kinds = append(kinds, rec.Event)
+		}
+	}
+
+	if path, err := receiptsPath(); err == nil {
+		content, err := readFileMaybe(path)
+		if err != nil {
+			return nil, err
+		}
+		// Receipt IDs are notification groups; matching them exactly keeps
+		// thread "1" from picking up thread "t-1".
+		groups := map[string]string{}
+		for _, kind := range append([]string{"approval-native", "approve", "reject"}, kinds...) {
+			groups[notificationGroup(kind, thread)] = kind
+		}
+		for _, line := range splitLines(content) {
+			var r deliveryReceipt
+			if json.Unmarshal([]byte(line), &r) != nil {
+				continue
+			}
+			kind, ok := groups[r.ID]
+			if !ok {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339Nano, r.Time)
+			if err != nil {
+				continue
+			}
+			detail := r.Backend
+			if r.Choice != "" {
+				detail = r.Choice
+			}
+			entries = append(entries, timelineEntry{Time: t, Source: "receipt", Kind: kind, Status: r.Status, Detail: detail})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, nil
+}
+
+// formatTimeline renders entries with the gaps between them, then a
+// summary naming the longest wait.
+func formatTimeline(thread string, entries []timelineEntry, style timeStyle, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "thread %s\n", thread)
+	var longest time.Duration
+	var longestFrom, longestTo string
+	for i, e := range entries {
+		if i > 0 {
+			gap := e.Time.Sub(entries[i-1].Time)
+			if gap >= timelineGap {
+				fmt.Fprintf(&b, "  ⋮ %s later\n", formatCountdown(gap))
+			}
+			if gap > longest {
+				longest, longestFrom, longestTo = gap, entries[i-1].Kind, e.Kind
+			}
+		}
+		parts := []string{formatEventTime(e.Time.Format(time.RFC3339Nano), style, now), fmt.Sprintf("%-20s", e.Kind)}
+		if e.Status != "" && e.Status != "sent" {
+			parts = append(parts, "("+e.Status+")")
+		}
+		if e.Detail != "" {
+			parts = append(parts, e.Detail)
+		}
+		fmt.Fprintf(&b, "  %s\n", strings.TrimRight(strings.Join(parts, "  "), " "))
+	}
+	if len(entries) > 1 {
+		span := entries[len(entries)-1].Time.Sub(entries[0].Time)
+		fmt.Fprintf(&b, "%d entries over %s", len(entries), formatCountdown(span))
+		if longest >= timelineGap {
+			fmt.Fprintf(&b, "; longest wait %s, from %s to %s", formatCountdown(longest), longestFrom, longestTo)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+type timelineResult struct {
+	commandResult
+	Entries []timelineEntry `json:"entries"`
+}
+
+func runThread(args []string) error {
+	fs := flag.NewFlagSet("thread", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	raw := fs.Bool("raw", false, "print entries as NDJSON")
+	utc := fs.Bool("utc", false, "print full UTC timestamps")
+	relative := fs.Bool("relative", false, "print times relative to now, e.g. 3m ago")
+	outFlags := addOutputFlags(fs)
+	// The thread id may come before or after the flags.
+	var thread string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		thread, args = args[0], args[1:]
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if thread == "" && fs.NArg() == 1 {
+		thread = fs.Arg(0)
+	} else if fs.NArg() > 0 || thread == "" {
+		return usageError(errors.New("thread requires exactly one thread id"))
+	}
+	style := timeLocal
+	switch {
+	case *utc && *relative:
+		return usageError(errors.New("--utc and --relative cannot be combined"))
+	case *utc:
+		style = timeUTC
+	case *relative:
+		style = timeRelative
+	}
+
+	entries, err := threadTimeline(thread)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("nothing recorded for thread %s", thread)
+	}
+	if *raw {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	out := outFlags.output()
+	out.Printf("%s", formatTimeline(thread, entries, style, time.Now()))
+	return out.Result(timelineResult{
+		commandResult: commandResult{Command: "thread", Status: "ok", Thread: thread, Count: len(entries)},
+		Entries:       entries,
+	})
+}
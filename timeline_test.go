@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThreadTimeline(t *testing.T) {
+	useTempHome(t)
+	path, err := eventsPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	events := []struct {
+		at  time.Duration
+		rec eventRecord
+	}{
+		{0, eventRecord{Event: "wrap-start", Session: "s1"}},
+		{time.Minute, eventRecord{Event: "approval-requested", Thread: "t-1", Session: "s1", Message: "Run make?", Status: "sent"}},
+		{2 * time.Minute, eventRecord{Event: "approval-requested", Thread: "1", Message: "other thread"}},
+		{20 * time.Minute, eventRecord{Event: "action", Thread: "t-1", Message: "approve", Status: "ok"}},
+		{21 * time.Minute, eventRecord{Event: "agent-turn-complete", Thread: "t-1", Session: "s1", Status: "sent"}},
+	}
+	for _, e := range events {
+		if err := appendEvent(path, &e.rec, start.Add(e.at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, r := range []deliveryReceipt{
+		{Time: start.Add(90 * time.Second).Format(time.RFC3339), ID: notificationGroup("approval-requested", "t-1"), Backend: "popup", Status: receiptDelivered},
+		{Time: start.Add(20 * time.Minute).Format(time.RFC3339), ID: notificationGroup("approval-requested", "t-1"), Status: receiptClicked, Choice: "Approve"},
+		{Time: start.Add(3 * time.Minute).Format(time.RFC3339), ID: notificationGroup("approval-requested", "1"), Status: receiptDelivered},
+	} {
+		if err := appendReceipt(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := threadTimeline("t-1")
+	if err != nil {
+		t.Fatalf("threadTimeline() error = %v", err)
+	}
+	var kinds []string
+	for _, e := range entries {
+		kinds = append(kinds, e.Source+":"+e.Kind)
+	}
+	want := "event:wrap-start,event:approval-requested,receipt:approval-requested,event:action,receipt:approval-requested,event:agent-turn-complete"
+	if strings.Join(kinds, ",") != want {
+		t.Fatalf("timeline = %v, want %s", kinds, want)
+	}
+
+	out := formatTimeline("t-1", entries, timeUTC, start)
+	for _, s := range []string{"⋮ 18m30s later", "(clicked)  Approve", "6 entries over 21m; longest wait 18m30s, from approval-requested to action"} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("timeline output missing %q:\n%s", s, out)
+		}
+	}
+	if strings.Contains(out, "other thread") {
+		t.Fatalf("timeline includes another thread:\n%s", out)
+	}
+
+	if err := runThread([]string{"nope"}); err == nil {
+		t.Fatal("runThread() for an unknown thread succeeded")
+	}
+}
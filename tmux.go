@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultTmuxActivitySeconds = 120
+
+// tmuxPaneStatus describes the tmux session a hook was launched from.
+type tmuxPaneStatus struct {
+	Session      string
+	Attached     bool
+	WindowActive bool
+	// LastActivity is the most recent input from any attached client.
+	LastActivity time.Time
+}
+
+func tmuxSuppressEnabled() bool {
+	v := strings.TrimSpace(strings.ToLower(getenv("CODEX_NOTIFY_TMUX_SUPPRESS")))
+	return v == "1" || v == "true" || v == "yes" || v == "on"
+}
+
+func tmuxActivityWindow() time.Duration {
+	raw := strings.TrimSpace(getenv("CODEX_NOTIFY_TMUX_ACTIVITY_SECONDS"))
+	if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return defaultTmuxActivitySeconds * time.Second
+}
+
+// readTmuxPaneStatus queries tmux about pane. Tests describe a pane through
+// it without a tmux server.
+var readTmuxPaneStatus = func(pane string) (tmuxPaneStatus, error) {
+	path, ok := lookupCmd("tmux")
+	if !ok {
+		return tmuxPaneStatus{}, errors.New("tmux not found")
+	}
+
+	out, err := outputLogged(exec.Command(path, "display-message", "-p", "-t", pane, "#{session_name}\t#{session_attached}\t#{window_active}"))
+	if err != nil {
+		return tmuxPaneStatus{}, fmt.Errorf("tmux display-message: %w", err)
+	}
+	status, err := parseTmuxPaneStatus(string(out))
+	if err != nil {
+		return tmuxPaneStatus{}, err
+	}
+	if !status.Attached {
+		return status, nil
+	}
+
+	clients, err := outputLogged(exec.Command(path, "list-clients", "-t", status.Session, "-F", "#{client_activity}"))
+	if err != nil {
+		return tmuxPaneStatus{}, fmt.Errorf("tmux list-clients: %w", err)
+	}
+	status.LastActivity = latestTmuxClientActivity(string(clients))
+	return status, nil
+}
+
+func parseTmuxPaneStatus(out string) (tmuxPaneStatus, error) {
+	fields := strings.Split(strings.TrimSpace(out), "\t")
+	if len(fields) != 3 {
+		return tmuxPaneStatus{}, fmt.Errorf("unexpected tmux output: %q", out)
+	}
+	attached, _ := strconv.Atoi(fields[1])
+	return tmuxPaneStatus{
+		Session:      fields[0],
+		Attached:     attached > 0,
+		WindowActive: fields[2] == "1",
+	}, nil
+}
+
+func latestTmuxClientActivity(out string) time.Time {
+	var latest int64
+	for _, line := range strings.Split(out, "\n") {
+		if ts, err := strconv.ParseInt(strings.TrimSpace(line), 10, 64); err == nil && ts > latest {
+			latest = ts
+		}
+	}
+	if latest == 0 {
+		return time.Time{}
+	}
+	return time.Unix(latest, 0)
+}
+
+// tmuxSessionWatched reports whether the hook's tmux session is attached,
+// showing the Codex window, and has had client input within the activity
+// window. Session output does not count: Codex itself just produced some.
+func tmuxSessionWatched(now time.Time) bool {
+	if !tmuxSuppressEnabled() {
+		return false
+	}
+	pane := strings.TrimSpace(getenv("TMUX_PANE"))
+	if getenv("TMUX") == "" || pane == "" {
+		return false
+	}
+	status, err := readTmuxPaneStatus(pane)
+	if err != nil {
+		return false
+	}
+	return status.Attached && status.WindowActive && now.Sub(status.LastActivity) <= tmuxActivityWindow()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTmuxPaneStatus(t *testing.T) {
+	status, err := parseTmuxPaneStatus("work\t1\t1\n")
+	if err != nil {
+		t.Fatalf("parseTmuxPaneStatus() error = %v", err)
+	}
+	if status.Session != "work" || !status.Attached || !status.WindowActive {
+		t.Fatalf("parseTmuxPaneStatus() = %+v", status)
+	}
+
+	if _, err := parseTmuxPaneStatus("garbage"); err == nil {
+		t.Fatalf("parseTmuxPaneStatus(garbage) error = nil, want error")
+	}
+
+	if got := latestTmuxClientActivity("1700000000\n1700000500\n\n"); !got.Equal(time.Unix(1700000500, 0)) {
+		t.Fatalf("latestTmuxClientActivity() = %v", got)
+	}
+}
+
+func TestTmuxSessionWatched(t *testing.T) {
+	now := time.Unix(1700001000, 0)
+	status := tmuxPaneStatus{Session: "work", Attached: true, WindowActive: true, LastActivity: now.Add(-30 * time.Second)}
+	stub(t, &readTmuxPaneStatus, func(string) (tmuxPaneStatus, error) { return status, nil })
+
+	t.Setenv("TMUX", "/tmp/tmux-501/default,123,0")
+	t.Setenv("TMUX_PANE", "%1")
+	t.Setenv("CODEX_NOTIFY_TMUX_ACTIVITY_SECONDS", "")
+
+	t.Setenv("CODEX_NOTIFY_TMUX_SUPPRESS", "")
+	if tmuxSessionWatched(now) {
+		t.Fatalf("tmuxSessionWatched() with suppression off = true, want false")
+	}
+
+	t.Setenv("CODEX_NOTIFY_TMUX_SUPPRESS", "1")
+	if !tmuxSessionWatched(now) {
+		t.Fatalf("tmuxSessionWatched() for an active session = false, want true")
+	}
+
+	status.LastActivity = now.Add(-10 * time.Minute)
+	if tmuxSessionWatched(now) {
+		t.Fatalf("tmuxSessionWatched() for an idle session = true, want false")
+	}
+
+	status.LastActivity = now
+	status.WindowActive = false
+	if tmuxSessionWatched(now) {
+		t.Fatalf("tmuxSessionWatched() for a background window = true, want false")
+	}
+
+	status.WindowActive = true
+	t.Setenv("TMUX", "")
+	if tmuxSessionWatched(now) {
+		t.Fatalf("tmuxSessionWatched() outside tmux = true, want false")
+	}
+}
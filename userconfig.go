@@ -0,0 +1,767 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const userConfigFilename = "config.toml"
+
+// userConfig is codex-notify's own optional config file. It lives next to
+// settings.json and only grows keys that environment variables cannot
+// express well.
+type userConfig struct {
+	Presets []submitPreset
+	// Click maps an event name (or "default") to what clicking its
+	// notification does: a built-in action name, "none", or a shell command.
+	Click map[string]string
+	// Events turns desktop notifications on or off per event name, with
+	// "default" for the rest.
+	Events map[string]bool
+	// Chain is a notify command run with every payload after codex-notify
+	// handled it. `init --chain` sets it; a hook --then left by older
+	// versions takes precedence.
+	Chain []string
+	// CodexConfigs lists the Codex config files (or CODEX_HOME directories)
+	// that init, doctor, and uninstall manage when --config is not given.
+	CodexConfigs []string
+	// Ntfy configures pushes to an ntfy server alongside the desktop
+	// notification.
+	Ntfy ntfyConfig
+	// Slack posts to an incoming webhook alongside the desktop notification.
+	Slack slackConfig
+	// PagerDuty and OnCall page for failures, agent-error by default.
+	PagerDuty pagerDutyConfig
+	OnCall    onCallConfig
+	// Pushover and Bark push to a phone, approvals at high priority.
+	Pushover pushoverConfig
+	Bark     barkConfig
+	// Email sends mail over SMTP, agent-error by default.
+	Email emailConfig
+	// Peer mirrors notifications to a second machine and takes answers to
+	// approvals from it.
+	Peer peerConfig
+	// Webhooks are generic HTTP sinks with templated bodies, in file order.
+	Webhooks []webhookConfig
+	// Rules route events to sinks, first match wins, in file order.
+	Rules []routingRule
+	// Features holds [features] settings: on, off, or a stage name.
+	Features map[string]string
+	// Scripts are AppleScript or JXA snippets that [click] entries run
+	// as script:<name>.
+	Scripts []scriptAction
+	// Identities restyle notifications per Codex profile or model, first
+	// match wins, in file order.
+	Identities []notificationIdentity
+	// Settings holds top-level keys as the CODEX_NOTIFY_* variables they
+	// stand for, with values already in environment form.
+	Settings map[string]string
+}
+
+// settingKind is the TOML shape a top-level setting accepts.
+type settingKind int
+
+const (
+	settingString settingKind = iota
+	settingKeys
+	settingInt
+	settingBool
+)
+
+// settingSpec describes one top-level config.toml key and the
+// CODEX_NOTIFY_* variable it defaults.
+type settingSpec struct {
+	Env  string
+	Kind settingKind
+	// Choices restricts a string setting to fixed values.
+	Choices []string
+	// Min and Max bound an integer setting when Max is non-zero.
+	Min, Max int64
+}
+
+// userConfigSettings maps top-level config.toml keys to the environment
+// variables they default. A variable that is set always wins.
+var userConfigSettings = map[string]settingSpec{
+	"terminal_bundle_id":       {Env: "CODEX_NOTIFY_TERMINAL_BUNDLE_ID", Kind: settingString},
+	"terminal_wm_class":        {Env: "CODEX_NOTIFY_TERMINAL_WM_CLASS", Kind: settingString},
+	"approve_keys":             {Env: "CODEX_NOTIFY_APPROVE_KEYS", Kind: settingKeys},
+	"reject_keys":              {Env: "CODEX_NOTIFY_REJECT_KEYS", Kind: settingKeys},
+	"open_keys":                {Env: "CODEX_NOTIFY_OPEN_KEYS", Kind: settingKeys},
+	"reveal_keys":              {Env: "CODEX_NOTIFY_REVEAL_KEYS", Kind: settingKeys},
+	"notification_ui":          {Env: "CODEX_NOTIFY_NOTIFICATION_UI", Kind: settingString, Choices: []string{notificationUIPopup, notificationUISystem}},
+	"chooser":                  {Env: "CODEX_NOTIFY_CHOOSER", Kind: settingString, Choices: []string{chooserPopup, chooserDialog}},
+	"sound_theme":              {Env: "CODEX_NOTIFY_SOUND_THEME", Kind: settingString},
+	"hotkey_approve":           {Env: "CODEX_NOTIFY_HOTKEY_APPROVE", Kind: settingString},
+	"hotkey_reject":            {Env: "CODEX_NOTIFY_HOTKEY_REJECT", Kind: settingString},
+	"hotkey_open":              {Env: "CODEX_NOTIFY_HOTKEY_OPEN", Kind: settingString},
+	"approval_ui":              {Env: "CODEX_NOTIFY_APPROVAL_UI", Kind: settingString, Choices: []string{approvalUIPopup, approvalUISingle, approvalUIMulti}},
+	"popup_timeout_seconds":    {Env: "CODEX_NOTIFY_POPUP_TIMEOUT_SECONDS", Kind: settingInt, Min: minPopupTimeoutSeconds, Max: maxPopupTimeoutSeconds},
+	"approval_timeout_seconds": {Env: "CODEX_NOTIFY_APPROVAL_TIMEOUT_SECONDS", Kind: settingInt, Min: minPopupTimeoutSeconds, Max: maxPopupTimeoutSeconds},
+	"enable_approval_actions":  {Env: "CODEX_NOTIFY_ENABLE_APPROVAL_ACTIONS", Kind: settingBool},
+	"sandbox":                  {Env: "CODEX_NOTIFY_SANDBOX", Kind: settingBool},
+	"private_argv":             {Env: "CODEX_NOTIFY_PRIVATE_ARGV", Kind: settingBool},
+	"power_saver":              {Env: "CODEX_NOTIFY_POWER_SAVER", Kind: settingString, Choices: []string{powerSaverOff, powerSaverAuto, powerSaverOn}},
+	"project_colors":           {Env: "CODEX_NOTIFY_PROJECT_COLORS", Kind: settingBool},
+	"turn_outcomes":            {Env: "CODEX_NOTIFY_TURN_OUTCOMES", Kind: settingBool},
+	"tmux_suppress":            {Env: "CODEX_NOTIFY_TMUX_SUPPRESS", Kind: settingBool},
+	"tmux_activity_seconds":    {Env: "CODEX_NOTIFY_TMUX_ACTIVITY_SECONDS", Kind: settingInt, Min: 1, Max: 86400},
+	"adaptive_notifications":   {Env: "CODEX_NOTIFY_ADAPTIVE", Kind: settingBool},
+	"screen_share":             {Env: "CODEX_NOTIFY_SCREEN_SHARE", Kind: settingString, Choices: []string{screenShareOff, screenShareSuppress}},
+	"screen_share_processes":   {Env: "CODEX_NOTIFY_SCREEN_SHARE_PROCESSES", Kind: settingKeys},
+	"idle_seconds":             {Env: "CODEX_NOTIFY_IDLE_SECONDS", Kind: settingInt, Min: 1, Max: 86400},
+	"remind_seconds":           {Env: "CODEX_NOTIFY_REMIND_SECONDS", Kind: settingInt, Min: 10, Max: 86400},
+	"remind_max":               {Env: "CODEX_NOTIFY_REMIND_MAX", Kind: settingInt, Min: 1, Max: 20},
+	"snooze_seconds":           {Env: "CODEX_NOTIFY_SNOOZE_SECONDS", Kind: settingInt, Min: 0, Max: 86400},
+	"remind_escalate":          {Env: "CODEX_NOTIFY_REMIND_ESCALATE", Kind: settingKeys},
+	"dedupe_seconds":           {Env: "CODEX_NOTIFY_DEDUPE_SECONDS", Kind: settingInt, Min: 1, Max: 86400},
+	"dedupe":                   {Env: "CODEX_NOTIFY_DEDUPE", Kind: settingString, Choices: []string{dedupeSkip, dedupeUpdate}},
+	"rate_limit":               {Env: "CODEX_NOTIFY_RATE_LIMIT", Kind: settingInt, Min: 1, Max: 1000},
+	"daemon":                   {Env: "CODEX_NOTIFY_DAEMON", Kind: settingBool},
+	"terminal_bell":            {Env: "CODEX_NOTIFY_TERMINAL_BELL", Kind: settingString, Choices: []string{terminalBellOff, terminalBellBell, terminalBellOSC777}},
+	"language":                 {Env: "CODEX_NOTIFY_LANGUAGE", Kind: settingString, Choices: []string{languageAuto, languageEn, languageJa, languageMixed}},
+	"log_level":                {Env: logLevelEnv, Kind: settingString, Choices: []string{"error", "info", "debug"}},
+	"log_file":                 {Env: logFileEnv, Kind: settingString},
+}
+
+// envValue checks a parsed TOML value against the spec and renders it the
+// way the environment variable spells it; key arrays become the
+// comma-separated sequences.
+func (spec settingSpec) envValue(value any) (string, error) {
+	switch spec.Kind {
+	case settingString:
+		s, ok := value.(string)
+		if !ok {
+			return "", errors.New("must be a string")
+		}
+		if len(spec.Choices) > 0 && !containsString(spec.Choices, s) {
+			return "", fmt.Errorf("must be one of %s", strings.Join(spec.Choices, ", "))
+		}
+		return s, nil
+	case settingKeys:
+		items, ok := value.([]any)
+		if !ok {
+			return "", errors.New("must be an array of key names")
+		}
+		keys := make([]string, 0, len(items))
+		for _, item := range items {
+			key, ok := item.(string)
+			if !ok || strings.TrimSpace(key) == "" || strings.Contains(key, ",") {
+				return "", errors.New("keys must be non-empty strings without commas")
+			}
+			keys = append(keys, key)
+		}
+		return strings.Join(keys, ","), nil
+	case settingInt:
+		n, ok := value.(int64)
+		if !ok {
+			return "", errors.New("must be an integer")
+		}
+		if spec.Max != 0 && (n < spec.Min || n > spec.Max) {
+			return "", fmt.Errorf("must be between %d and %d", spec.Min, spec.Max)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case settingBool:
+		b, ok := value.(bool)
+		if !ok {
+			return "", errors.New("must be true or false")
+		}
+		return strconv.FormatBool(b), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", value)
+}
+
+// parseArg reads a command-line value for the setting into the same shape
+// the TOML parser produces: "y,enter" for keys, yes/no for booleans.
+func (spec settingSpec) parseArg(raw string) (any, error) {
+	raw = strings.TrimSpace(raw)
+	switch spec.Kind {
+	case settingKeys:
+		keys := []any{}
+		for _, key := range strings.Split(raw, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		return keys, nil
+	case settingInt:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return n, nil
+	case settingBool:
+		switch strings.ToLower(raw) {
+		case "1", "true", "yes", "on":
+			return true, nil
+		case "0", "false", "no", "off":
+			return false, nil
+		}
+		return nil, errors.New("must be true or false")
+	}
+	return raw, nil
+}
+
+// tomlLiteral formats a value produced by parseTOMLValue back as TOML.
+func tomlLiteral(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, tomlLiteral(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(value)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// submitPreset is a named follow-up message offered as a popup choice and
+// accepted by `action submit --preset <name>`.
+type submitPreset struct {
+	Name string
+	Text string
+}
+
+func (c userConfig) preset(name string) (submitPreset, bool) {
+	for _, p := range c.Presets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return submitPreset{}, false
+}
+
+func userConfigPath() (string, error) {
+	if path := strings.TrimSpace(getenv("CODEX_NOTIFY_CONFIG_FILE")); path != "" {
+		return path, nil
+	}
+	configDir, err := userConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve user config dir: %w", err)
+	}
+	return filepath.Join(configDir, appName, userConfigFilename), nil
+}
+
+// loadUserConfig reads the user config file. A missing file is an empty
+// config, not an error.
+func loadUserConfig() (userConfig, error) {
+	path, err := userConfigPath()
+	if err != nil {
+		return userConfig{}, err
+	}
+	info, statErr := os.Stat(path)
+	if statErr == nil {
+		if cfg, ok := cachedUserConfig(path, info); ok {
+			return cfg, nil
+		}
+	}
+	content, err := readFileMaybe(path)
+	if err != nil {
+		return userConfig{}, err
+	}
+	cfg, err := parseUserConfig(content)
+	if err != nil {
+		return userConfig{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if statErr == nil {
+		storeUserConfig(path, info, cfg)
+	}
+	return cfg, nil
+}
+
+func parseUserConfig(content []byte) (userConfig, error) {
+	entries, err := parseTOMLEntries(content)
+	if err != nil {
+		return userConfig{}, err
+	}
+
+	var cfg userConfig
+	for _, e := range entries {
+		switch {
+		case strings.HasPrefix(e.Key, "presets."):
+			name := strings.TrimPrefix(e.Key, "presets.")
+			text, ok := e.Value.(string)
+			if !ok {
+				return userConfig{}, fmt.Errorf("line %d: preset %q must be a string", e.Line, name)
+			}
+			if strings.TrimSpace(text) == "" {
+				return userConfig{}, fmt.Errorf("line %d: preset %q is empty", e.Line, name)
+			}
+			cfg.Presets = append(cfg.Presets, submitPreset{Name: name, Text: text})
+		case e.Key == "codex_configs":
+			items, ok := e.Value.([]any)
+			if !ok {
+				return userConfig{}, fmt.Errorf("line %d: codex_configs must be an array of strings", e.Line)
+			}
+			for _, item := range items {
+				path, ok := item.(string)
+				if !ok || strings.TrimSpace(path) == "" {
+					return userConfig{}, fmt.Errorf("line %d: codex_configs entries must be non-empty strings", e.Line)
+				}
+				cfg.CodexConfigs = append(cfg.CodexConfigs, strings.TrimSpace(path))
+			}
+		case e.Key == "chain":
+			items, ok := e.Value.([]any)
+			if !ok || len(items) == 0 {
+				return userConfig{}, fmt.Errorf("line %d: chain must be a non-empty array of strings, e.g. [\"my-notifier\"]", e.Line)
+			}
+			for _, item := range items {
+				arg, ok := item.(string)
+				if !ok {
+					return userConfig{}, fmt.Errorf("line %d: chain entries must be strings", e.Line)
+				}
+				cfg.Chain = append(cfg.Chain, arg)
+			}
+			if strings.TrimSpace(cfg.Chain[0]) == "" {
+				return userConfig{}, fmt.Errorf("line %d: chain must start with a command", e.Line)
+			}
+		case strings.HasPrefix(e.Key, "click."):
+			event := strings.TrimPrefix(e.Key, "click.")
+			action, ok := e.Value.(string)
+			if !ok || strings.TrimSpace(action) == "" {
+				return userConfig{}, fmt.Errorf("line %d: click action for %q must be a non-empty string", e.Line, event)
+			}
+			if cfg.Click == nil {
+				cfg.Click = map[string]string{}
+			}
+			cfg.Click[event] = strings.TrimSpace(action)
+		case strings.HasPrefix(e.Key, "events."):
+			event := strings.TrimPrefix(e.Key, "events.")
+			enabled, ok := e.Value.(bool)
+			if !ok {
+				return userConfig{}, fmt.Errorf("line %d: events.%s must be true or false", e.Line, event)
+			}
+			if cfg.Events == nil {
+				cfg.Events = map[string]bool{}
+			}
+			cfg.Events[event] = enabled
+		case strings.HasPrefix(e.Key, "ntfy."):
+			if err := cfg.Ntfy.set(strings.TrimPrefix(e.Key, "ntfy."), e.Value); err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, err)
+			}
+		case strings.HasPrefix(e.Key, "slack."):
+			if err := cfg.Slack.set(strings.TrimPrefix(e.Key, "slack."), e.Value); err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, err)
+			}
+		case strings.HasPrefix(e.Key, "pagerduty."):
+			if err := cfg.PagerDuty.set(strings.TrimPrefix(e.Key, "pagerduty."), e.Value); err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, err)
+			}
+		case strings.HasPrefix(e.Key, "oncall."):
+			if err := cfg.OnCall.set(strings.TrimPrefix(e.Key, "oncall."), e.Value); err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, err)
+			}
+		case strings.HasPrefix(e.Key, "pushover."):
+			if err := cfg.Pushover.set(strings.TrimPrefix(e.Key, "pushover."), e.Value); err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, err)
+			}
+		case strings.HasPrefix(e.Key, "bark."):
+			if err := cfg.Bark.set(strings.TrimPrefix(e.Key, "bark."), e.Value); err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, err)
+			}
+		case strings.HasPrefix(e.Key, "email."):
+			if err := cfg.Email.set(strings.TrimPrefix(e.Key, "email."), e.Value); err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, err)
+			}
+		case strings.HasPrefix(e.Key, "peer."):
+			if err := cfg.Peer.set(strings.TrimPrefix(e.Key, "peer."), e.Value); err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, err)
+			}
+		case strings.HasPrefix(e.Key, "features."):
+			name := strings.TrimPrefix(e.Key, "features.")
+			setting, err := parseFeatureSetting(name, e.Value)
+			if err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, err)
+			}
+			if cfg.Features == nil {
+				cfg.Features = map[string]string{}
+			}
+			cfg.Features[name] = setting
+		case strings.HasPrefix(e.Key, "identities."):
+			if err := cfg.setIdentity(strings.TrimPrefix(e.Key, "identities."), e.Value); err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, err)
+			}
+		case strings.HasPrefix(e.Key, "scripts."):
+			if err := cfg.setScript(strings.TrimPrefix(e.Key, "scripts."), e.Value); err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, err)
+			}
+		case strings.HasPrefix(e.Key, "rules."):
+			if err := cfg.setRule(strings.TrimPrefix(e.Key, "rules."), e.Value); err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, err)
+			}
+		case strings.HasPrefix(e.Key, "webhooks."):
+			if err := cfg.setWebhook(strings.TrimPrefix(e.Key, "webhooks."), e.Value); err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, err)
+			}
+		case !strings.Contains(e.Key, "."):
+			spec, ok := userConfigSettings[e.Key]
+			if !ok {
+				return userConfig{}, fmt.Errorf("line %d: %w", e.Line, unknownSetting(e.Key))
+			}
+			value, err := spec.envValue(e.Value)
+			if err != nil {
+				return userConfig{}, fmt.Errorf("line %d: %s %w", e.Line, e.Key, err)
+			}
+			if cfg.Settings == nil {
+				cfg.Settings = map[string]string{}
+			}
+			cfg.Settings[spec.Env] = value
+		}
+	}
+	if err := validateWebhooks(cfg.Webhooks); err != nil {
+		return userConfig{}, err
+	}
+	if err := validateEmail(cfg.Email); err != nil {
+		return userConfig{}, err
+	}
+	if err := validateRules(cfg); err != nil {
+		return userConfig{}, err
+	}
+	if err := validateScripts(cfg); err != nil {
+		return userConfig{}, err
+	}
+	return cfg, nil
+}
+
+// namedTable is one table of a section of named tables, such as
+// [webhooks.<name>].
+type namedTable interface {
+	tableName() string
+	set(key string, value any) error
+}
+
+// setNamedTable parses one `<section>.<name>.<key>` entry into the table
+// called name, adding it to tables on its first key.
+func setNamedTable[T any, P interface {
+	*T
+	namedTable
+}](section, key string, value any, tables *[]T, create func(name string) T) error {
+	name, field, ok := strings.Cut(key, ".")
+	if !ok {
+		return fmt.Errorf("%s.%s must be a table", section, key)
+	}
+	i := 0
+	for i < len(*tables) && P(&(*tables)[i]).tableName() != name {
+		i++
+	}
+	if i == len(*tables) {
+		*tables = append(*tables, create(name))
+	}
+	return P(&(*tables)[i]).set(field, value)
+}
+
+// unknownSetting is the error for a config file key codex-notify does not
+// know.
+func unknownSetting(key string) error {
+	return fmt.Errorf("unknown setting %q", key)
+}
+
+// configExportedEnv is what applyUserConfigSettings last exported, so a
+// daemon reload can take back settings the file no longer has.
+var configExportedEnv = map[string]string{}
+
+// applyUserConfigSettings exports the config file's settings as
+// environment variables for this process and its children, leaving any
+// variable the caller already set alone. Called again, as on a daemon
+// reload, it first drops what the previous call exported. Errors leave the
+// environment as is.
+func applyUserConfigSettings() error {
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return err
+	}
+	for env, value := range configExportedEnv {
+		if current, _ := os.LookupEnv(env); current == value {
+			_ = os.Unsetenv(env)
+		}
+		delete(configExportedEnv, env)
+	}
+	for env, value := range cfg.Settings {
+		if _, set := lookupEnv(env); set {
+			continue
+		}
+		if err := os.Setenv(env, value); err != nil {
+			return err
+		}
+		configExportedEnv[env] = value
+	}
+	return nil
+}
+
+const userConfigScaffold = `# codex-notify settings. Environment variables (CODEX_NOTIFY_*) override
+# the values here. Uncomment what you need.
+
+# terminal_bundle_id = "com.mitchellh.ghostty"
+# approve_keys = ["y", "enter"]
+# reject_keys = ["n", "enter"]
+# open_keys = []
+# reveal_keys = ["auto"]           # scroll to the prompt first; "auto" uses the terminal's keys
+# notification_ui = "popup"        # popup or system
+# popup_timeout_seconds = 45
+# enable_approval_actions = true
+# project_colors = false
+# power_saver = "off"              # off, auto, or on
+# language = "auto"               # auto (follow the message), en, ja, or mixed
+# chain = ["my-notifier"]         # your old notify script; gets each payload after codex-notify
+
+# [presets]
+# tests = "Run the tests and fix any failures."
+
+# [click]
+# agent-turn-complete = "open"
+
+# [events]                         # desktop notifications per event
+# agent-turn-complete = false
+
+# [ntfy]                           # push to your phone as well
+# topic = "my-codex-runs"
+# server = "https://ntfy.sh"
+# events = ["agent-turn-complete", "approval-requested"]
+# reply_topic = "my-codex-replies" # Approve/Reject buttons; needs token, the daemon and ntfy_replies
+# watch = true                     # short title and message for Apple Watch
+
+# [pushover]                       # approvals on your iPhone at high priority
+# token = "..."
+# user = "..."
+# approval_priority = 1            # 2 = emergency, repeats every retry seconds until expire
+
+# [pagerduty]                      # page on agent-error during unattended runs
+# routing_key = "..."
+# severity = "error"
+
+# [email]                          # mail failed overnight runs (agent-error by default)
+# host = "smtp.example.com"
+# username = "me@example.com"      # password from CODEX_NOTIFY_SMTP_PASSWORD
+# from = "codex-notify <me@example.com>"
+# to = "me@example.com"
+
+# [peer]                           # mirror to a second Mac; see codex-notify peer pair
+# url = "http://laptop.local:7788"
+# listen = ":7788"
+# token = "secret:peer-token"
+
+# [features]                       # beta subsystems, see: codex-notify features list
+# ntfy_replies = "beta"
+# peer = "beta"
+
+# [identities.review]              # restyle runs of a Codex profile or model
+# model = "gpt-5*"
+# title_prefix = "[review]"
+# sound = "Glass"
+
+# [scripts.tile]                   # click = "script:tile" runs it with osascript
+# language = "applescript"         # or "jxa"; {cwd}, {thread_id}, {event}, {project} become quoted strings
+# source = "tell application \"Finder\" to open POSIX file {cwd}"
+
+# [rules.approvals]                # first matching rule picks the sinks
+# events = ["approval-requested"]
+# sinks = ["desktop", "ntfy"]
+
+# [webhooks.home]                  # POST each event to any URL
+# url = "https://example.com/hooks/codex"
+# body = '{"text": {{json .Title}}, "project": {{json .Project}}}'
+# events = ["approval-requested"]
+# [webhooks.home.headers]
+# Authorization = 'Bearer {{env "HOME_TOKEN"}}'
+`
+
+// scaffoldUserConfig writes a commented config.toml when none exists and
+// returns its path, or "" when a config was already there.
+func scaffoldUserConfig() (string, error) {
+	path, err := userConfigPath()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err == nil || !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("create config dir: %w", err)
+	}
+	if err := writeFileAtomic(path, []byte(userConfigScaffold), 0o644); err != nil {
+		return "", fmt.Errorf("write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// tomlEntry is one key/value pair with its fully qualified dotted key, in
+// file order.
+type tomlEntry struct {
+	Key   string
+	Value any
+	Line  int
+}
+
+// parseTOMLEntries parses the small TOML subset the user config needs:
+// [table] headers, dotted and quoted keys, basic and literal strings,
+// integers, booleans, and single-line arrays of those.
+func parseTOMLEntries(content []byte) ([]tomlEntry, error) {
+	var (
+		entries []tomlEntry
+		table   string
+		seen    = map[string]int{}
+	)
+	for i, raw := range splitLines(content) {
+		lineNo := i + 1
+		line := strings.TrimSpace(stripTOMLComment(strings.TrimSpace(raw)))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: unsupported table header %q", lineNo, line)
+			}
+			name, err := parseTOMLKey(strings.TrimSpace(line[1 : len(line)-1]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			table = name
+			continue
+		}
+
+		eq := indexOutsideQuotes(line, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key, err := parseTOMLKey(strings.TrimSpace(line[:eq]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if table != "" {
+			key = table + "." + key
+		}
+		value, err := parseTOMLValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if prev, dup := seen[key]; dup {
+			return nil, fmt.Errorf("line %d: %s already defined on line %d", lineNo, key, prev)
+		}
+		seen[key] = lineNo
+		entries = append(entries, tomlEntry{Key: key, Value: value, Line: lineNo})
+	}
+	return entries, nil
+}
+
+func parseTOMLKey(raw string) (string, error) {
+	if raw == "" {
+		return "", errors.New("empty key")
+	}
+	parts := []string{}
+	for raw != "" {
+		var part string
+		switch raw[0] {
+		case '"', '\'':
+			end := strings.IndexByte(raw[1:], raw[0])
+			if end < 0 {
+				return "", fmt.Errorf("unterminated quoted key %q", raw)
+			}
+			part, raw = raw[1:end+1], strings.TrimSpace(raw[end+2:])
+		default:
+			end := strings.IndexByte(raw, '.')
+			if end < 0 {
+				end = len(raw)
+			}
+			part, raw = strings.TrimSpace(raw[:end]), raw[end:]
+			for _, r := range part {
+				if !(r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+					return "", fmt.Errorf("invalid bare key %q", part)
+				}
+			}
+		}
+		if part == "" {
+			return "", errors.New("empty key")
+		}
+		parts = append(parts, part)
+		if raw == "" {
+			break
+		}
+		if raw[0] != '.' {
+			return "", fmt.Errorf("unexpected %q in key", raw)
+		}
+		raw = strings.TrimSpace(raw[1:])
+		if raw == "" {
+			return "", errors.New("key ends with '.'")
+		}
+	}
+	return strings.Join(parts, "."), nil
+}
+
+func parseTOMLValue(raw string) (any, error) {
+	switch {
+	case raw == "":
+		return nil, errors.New("missing value")
+	case strings.HasPrefix(raw, "["):
+		if !strings.HasSuffix(raw, "]") {
+			return nil, fmt.Errorf("arrays must be on one line: %s", raw)
+		}
+		return parseTOMLArray(strings.TrimSpace(raw[1 : len(raw)-1]))
+	case raw == "true":
+		return true, nil
+	case raw == "false":
+		return false, nil
+	case strings.HasPrefix(raw, `"`):
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", raw)
+		}
+		return s, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") || strings.Contains(raw[1:len(raw)-1], "'") {
+			return nil, fmt.Errorf("invalid literal string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(raw, "_", ""), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported value %s", raw)
+	}
+	return n, nil
+}
+
+func parseTOMLArray(body string) ([]any, error) {
+	items := []any{}
+	for body != "" {
+		end := indexOutsideQuotes(body, ',')
+		if end < 0 {
+			end = len(body)
+		}
+		item := strings.TrimSpace(body[:end])
+		if item == "" {
+			return nil, errors.New("empty array element")
+		}
+		if strings.HasPrefix(item, "[") {
+			return nil, errors.New("nested arrays are not supported")
+		}
+		value, err := parseTOMLValue(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+		if end == len(body) {
+			break
+		}
+		// A trailing comma is allowed.
+		body = strings.TrimSpace(body[end+1:])
+	}
+	return items, nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseUserConfigPresets(t *testing.T) {
+	content := []byte(`# follow-ups
+presets.continue = "続けて"
+
+[presets]
+tests = 'run the tests' # literal string
+"fix lint" = "fix \"lint\" errors"
+`)
+	cfg, err := parseUserConfig(content)
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+
+	want := []submitPreset{
+		{Name: "continue", Text: "続けて"},
+		{Name: "tests", Text: "run the tests"},
+		{Name: "fix lint", Text: `fix "lint" errors`},
+	}
+	if len(cfg.Presets) != len(want) {
+		t.Fatalf("Presets = %+v, want %+v", cfg.Presets, want)
+	}
+	for i := range want {
+		if cfg.Presets[i] != want[i] {
+			t.Fatalf("Presets[%d] = %+v, want %+v", i, cfg.Presets[i], want[i])
+		}
+	}
+}
+
+func TestParseUserConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "not a string", content: "presets.tests = 3\n", want: "must be a string"},
+		{name: "duplicate", content: "presets.a = \"x\"\n[presets]\na = \"y\"\n", want: "already defined on line 1"},
+		{name: "missing value", content: "presets.a =\n", want: "line 1: missing value"},
+		{name: "bad key", content: "pre sets.a = \"x\"\n", want: "invalid bare key"},
+		{name: "unknown setting", content: "notification_uii = \"system\"\n", want: `unknown setting "notification_uii"`},
+		{name: "unknown table key", content: "[scripts.tidy]\nsauce = \"x\"\n", want: `unknown setting "scripts.tidy.sauce"`},
+		{name: "table without a name", content: "identities.work = \"x\"\n", want: "identities.work must be a table"},
+	}
+	for _, tt := range tests {
+		_, err := parseUserConfig([]byte(tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: parseUserConfig() error = %v, want containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestBuildHookNotificationsAddsPresetChoices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("[presets]\ntests = \"run the tests\"\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", path)
+	t.Setenv("CODEX_NOTIFY_SANDBOX", "")
+
+	reqs, err := buildHookNotifications(map[string]any{"type": "agent-turn-complete", "thread-id": "t1"})
+	if err != nil {
+		t.Fatalf("buildHookNotifications() error = %v", err)
+	}
+	choices := reqs[0].ExtraChoices
+	if len(choices) == 0 || choices[0].Label != "tests" {
+		t.Fatalf("ExtraChoices = %+v, want a tests preset first", choices)
+	}
+	if !strings.Contains(choices[0].Command, "submit --preset 'tests' --thread-id 't1'") {
+		t.Fatalf("preset command = %q", choices[0].Command)
+	}
+}
+
+func TestClickActionConfig(t *testing.T) {
+	cfg, err := parseUserConfig([]byte(`[click]
+default = "open"
+agent-turn-complete = "code {cwd}"
+agent-error = "none"
+approval-requested = "choose"
+`))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+
+	payload := map[string]any{"cwd": "/tmp/my repo", "thread-id": "t1"}
+	tests := []struct {
+		event     string
+		wantCmd   string
+		wantLabel string
+	}{
+		{event: "agent-turn-complete", wantCmd: "code '/tmp/my repo'", wantLabel: "Open"},
+		{event: "agent-error", wantCmd: "", wantLabel: "Close"},
+		{event: "approval-requested", wantCmd: "action 'choose' --thread-id 't1'", wantLabel: "Choose"},
+		{event: "something-new", wantCmd: "action 'open' --thread-id 't1'", wantLabel: "Open"},
+	}
+	for _, tt := range tests {
+		action, ok := cfg.clickAction(tt.event)
+		if !ok {
+			t.Fatalf("clickAction(%q) ok = false", tt.event)
+		}
+		cmd := buildClickCommand(action, payload)
+		if (tt.wantCmd == "" && cmd != "") || !strings.HasSuffix(cmd, tt.wantCmd) {
+			t.Fatalf("buildClickCommand(%q) = %q, want suffix %q", action, cmd, tt.wantCmd)
+		}
+		if got := clickActionLabel(action); got != tt.wantLabel {
+			t.Fatalf("clickActionLabel(%q) = %q, want %q", action, got, tt.wantLabel)
+		}
+	}
+
+	if _, ok := (userConfig{}).clickAction("agent-turn-complete"); ok {
+		t.Fatalf("clickAction() on empty config ok = true, want false")
+	}
+}
+
+func TestUserConfigSettingsDefaultEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	content := "terminal_bundle_id = \"com.googlecode.iterm2\"\napprove_keys = [\"1\", \"enter\"]\npopup_timeout_seconds = 20\nsandbox = true\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", path)
+	t.Setenv("CODEX_NOTIFY_TERMINAL_BUNDLE_ID", "")
+	os.Unsetenv("CODEX_NOTIFY_TERMINAL_BUNDLE_ID")
+	t.Setenv("CODEX_NOTIFY_APPROVE_KEYS", "")
+	os.Unsetenv("CODEX_NOTIFY_APPROVE_KEYS")
+	t.Setenv("CODEX_NOTIFY_SANDBOX", "")
+	os.Unsetenv("CODEX_NOTIFY_SANDBOX")
+	// Set variables override the file.
+	t.Setenv("CODEX_NOTIFY_POPUP_TIMEOUT_SECONDS", "60")
+
+	if err := applyUserConfigSettings(); err != nil {
+		t.Fatalf("applyUserConfigSettings() error = %v", err)
+	}
+	if got := terminalBundleID(); got != "com.googlecode.iterm2" {
+		t.Fatalf("terminalBundleID() = %q, want com.googlecode.iterm2", got)
+	}
+	if got := strings.Join(approveKeySequence(), ","); got != "1,enter" {
+		t.Fatalf("approveKeySequence() = %q, want 1,enter", got)
+	}
+	if !sandboxModeEnabled() {
+		t.Fatalf("sandboxModeEnabled() = false, want true from config")
+	}
+	if got := os.Getenv("CODEX_NOTIFY_POPUP_TIMEOUT_SECONDS"); got != "60" {
+		t.Fatalf("CODEX_NOTIFY_POPUP_TIMEOUT_SECONDS = %q, want the environment's 60", got)
+	}
+}
+
+func TestScaffoldUserConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "codex-notify", "config.toml")
+	t.Setenv("CODEX_NOTIFY_CONFIG_FILE", path)
+
+	created, err := scaffoldUserConfig()
+	if err != nil || created != path {
+		t.Fatalf("scaffoldUserConfig() = %q, %v, want %q", created, err, path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if _, err := parseUserConfig(content); err != nil {
+		t.Fatalf("scaffold does not parse: %v", err)
+	}
+	// Uncommenting the sample settings must give a valid config too.
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		if sample, ok := strings.CutPrefix(line, "# "); ok && (strings.Contains(sample, " = ") || strings.HasPrefix(sample, "[")) {
+			lines[i] = sample
+		}
+	}
+	if _, err := parseUserConfig([]byte(strings.Join(lines, "\n"))); err != nil {
+		t.Fatalf("uncommented scaffold does not parse: %v", err)
+	}
+
+	if created, err := scaffoldUserConfig(); err != nil || created != "" {
+		t.Fatalf("second scaffoldUserConfig() = %q, %v, want no-op", created, err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// privateDirMode is used for every directory codex-notify creates for its
+// runtime state; files inside it are written with privateFileMode.
+const (
+	privateDirMode  os.FileMode = 0o700
+	privateFileMode os.FileMode = 0o600
+)
+
+// sharedTempDirName scopes the temp-dir fallback to the current user. The
+// temp dir is shared on many machines (notably /tmp on CI and build Macs),
+// so a plain "codex-notify" directory would collide between users.
+func sharedTempDirName() string {
+	return fmt.Sprintf("%s-%d", appName, os.Getuid())
+}
+
+// ensurePrivateDir creates dir with privateDirMode, or verifies that an
+// existing dir is a real directory owned by the current user. Group and other
+// permission bits on an owned directory are stripped; a directory owned by
+// someone else is rejected so the caller can fall back to another candidate.
+func ensurePrivateDir(dir string) error {
+	if err := os.MkdirAll(dir, privateDirMode); err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return errors.New("is a symlink")
+	}
+	if !info.IsDir() {
+		return errors.New("not a directory")
+	}
+	if uid, ok := fileOwnerUID(info); ok && uid != os.Getuid() {
+		return fmt.Errorf("owned by uid %d, not %d", uid, os.Getuid())
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		if err := os.Chmod(dir, privateDirMode); err != nil {
+			return fmt.Errorf("restrict permissions: %w", err)
+		}
+	}
+	return nil
+}
+
+func fileOwnerUID(info os.FileInfo) (int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(stat.Uid), true
+}
+
+func runtimeStateDir() (string, error) {
+	candidates := []string{}
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		cacheDir = strings.TrimSpace(cacheDir)
+		if cacheDir != "" {
+			candidates = append(candidates, filepath.Join(cacheDir, appName))
+		}
+	}
+
+	tempDir := strings.TrimSpace(os.TempDir())
+	if tempDir != "" {
+		candidates = append(candidates, filepath.Join(tempDir, sharedTempDirName()))
+	}
+
+	seen := map[string]struct{}{}
+	failures := []string{}
+	for _, dir := range candidates {
+		if dir == "" {
+			continue
+		}
+		if _, ok := seen[dir]; ok {
+			continue
+		}
+		seen[dir] = struct{}{}
+
+		if err := ensureWritableDir(dir); err == nil {
+			return dir, nil
+		} else {
+			failures = append(failures, fmt.Sprintf("%s: %v", dir, err))
+		}
+	}
+
+	if len(failures) == 0 {
+		return "", errors.New("resolve runtime state dir: no candidate directories")
+	}
+	return "", fmt.Errorf("resolve runtime state dir failed (%s)", strings.Join(failures, "; "))
+}
+
+func ensureWritableDir(dir string) error {
+	if err := ensurePrivateDir(dir); err != nil {
+		return err
+	}
+
+	probe, err := os.CreateTemp(dir, ".write-test-*")
+	if err != nil {
+		return err
+	}
+	probePath := probe.Name()
+	if err := probe.Close(); err != nil {
+		_ = os.Remove(probePath)
+		return err
+	}
+	if err := os.Remove(probePath); err != nil {
+		return err
+	}
+	return nil
+}
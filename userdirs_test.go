@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnsurePrivateDirRestrictsPermissions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if err := ensurePrivateDir(dir); err != nil {
+		t.Fatalf("ensurePrivateDir() error = %v", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if got := info.Mode().Perm(); got != privateDirMode {
+		t.Fatalf("mode = %o, want %o", got, privateDirMode)
+	}
+}
+
+func TestEnsurePrivateDirRejectsSymlink(t *testing.T) {
+	base := t.TempDir()
+	target := filepath.Join(base, "target")
+	link := filepath.Join(base, "link")
+	if err := os.Mkdir(target, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	if err := ensurePrivateDir(link); err == nil {
+		t.Fatalf("ensurePrivateDir(symlink) error = nil, want error")
+	}
+}
+
+func TestSharedTempDirNameIsPerUser(t *testing.T) {
+	name := sharedTempDirName()
+	if !strings.HasPrefix(name, appName+"-") || name == appName+"-" {
+		t.Fatalf("sharedTempDirName() = %q, want %s-<uid>", name, appName)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// warmHelperArg starts the popup helper as a server that shows one popup
+// per line of stdin, for the daemon's warm path.
+const warmHelperArg = "--serve"
+
+// warmHelperReadyTimeout bounds the wait for a new server's "ready". A
+// helper built before --serve existed opens a popup instead and never
+// answers.
+const warmHelperReadyTimeout = 2 * time.Second
+
+// warmPopup is the popup helper the daemon keeps running. It is nil outside
+// the daemon, where every popup starts its own helper.
+var warmPopup *warmHelper
+
+// warmHelper hands popups to one long-running helper process, so the
+// daemon posts them without a process launch. It starts the helper on the
+// first popup and again after the helper exits.
+type warmHelper struct {
+	mu    sync.Mutex
+	path  string
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	// unsupported is set once a helper failed to start serving; popups
+	// then take the cold path for the life of the daemon.
+	unsupported bool
+}
+
+// show sends req to the running helper with the hook's environment for
+// its choice commands. false means the caller starts a helper itself.
+func (w *warmHelper) show(helperPath string, req helperRequest) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.unsupported {
+		return false
+	}
+	if w.cmd != nil && w.path != helperPath {
+		w.stopLocked()
+	}
+	if w.cmd == nil {
+		if err := w.startLocked(helperPath); err != nil {
+			logErrorf("warm popup helper: %v; starting a helper per popup", err)
+			w.unsupported = true
+			return false
+		}
+	}
+
+	req.Env = map[string]string{}
+	for _, kv := range hookEnviron() {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			req.Env[key] = value
+		}
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return false
+	}
+	if _, err := w.stdin.Write(append(body, '\n')); err != nil {
+		logErrorf("warm popup helper: %v", err)
+		w.stopLocked()
+		return false
+	}
+	return true
+}
+
+func (w *warmHelper) startLocked(helperPath string) error {
+	cmd := exec.Command(helperPath, warmHelperArg)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	err = cmd.Start()
+	logExecStart(cmd, err)
+	if err != nil {
+		return err
+	}
+
+	ready := make(chan string, 1)
+	reader := bufio.NewReader(stdout)
+	go func() {
+		line, _ := reader.ReadString('\n')
+		ready <- strings.TrimSpace(line)
+	}()
+	var answer string
+	select {
+	case answer = <-ready:
+	case <-time.After(warmHelperReadyTimeout):
+	}
+	if answer != "ready" {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return errors.New("helper does not support " + warmHelperArg)
+	}
+
+	w.path, w.cmd, w.stdin = helperPath, cmd, stdin
+	go func() {
+		_, _ = io.Copy(io.Discard, reader)
+		err := cmd.Wait()
+		logInfof("warm popup helper pid %d exited: %s", cmd.Process.Pid, exitStatus(err))
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.cmd == cmd {
+			w.cmd, w.stdin = nil, nil
+		}
+	}()
+	return nil
+}
+
+// stopLocked closes the helper's stdin. It exits once its open popups
+// close, so none are cut short.
+func (w *warmHelper) stopLocked() {
+	if w.stdin != nil {
+		_ = w.stdin.Close()
+	}
+	w.cmd, w.stdin = nil, nil
+}
+
+func (w *warmHelper) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopLocked()
+}
+
+// exitStatus describes how a helper process ended, for the log.
+func exitStatus(err error) string {
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return "exit 0"
+	case errors.As(err, &exitErr):
+		return exitErr.ProcessState.String()
+	default:
+		return fmt.Sprintf("wait: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFakeHelper(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "helper")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestWarmHelperServesPopups(t *testing.T) {
+	got := filepath.Join(t.TempDir(), "requests")
+	helper := writeFakeHelper(t, `[ "$1" = --serve ] || exit 2
+echo ready
+while read -r line; do echo "$line" >> '`+got+`'; done
+`)
+	w := &warmHelper{}
+	defer w.stop()
+
+	withHookEnv(map[string]string{"CODEX_NOTIFY_TERMINAL_BUNDLE_ID": "com.googlecode.iterm2"}, func() {
+		for _, title := range []string{"first", "second"} {
+			if !w.show(helper, helperRequest{Title: title, Identifier: "t-1"}) {
+				t.Fatalf("show(%s) = false, want the warm helper to take it", title)
+			}
+		}
+	})
+
+	var lines []string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		data, _ := os.ReadFile(got)
+		if lines = strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) == 2 {
+			break
+		}
+	}
+	if len(lines) != 2 || !strings.Contains(lines[0], `"title":"first"`) || !strings.Contains(lines[1], `"title":"second"`) {
+		t.Fatalf("helper received %q, want both requests on one process", lines)
+	}
+	if !strings.Contains(lines[0], `"CODEX_NOTIFY_TERMINAL_BUNDLE_ID":"com.googlecode.iterm2"`) {
+		t.Fatalf("request %s is missing the hook's environment", lines[0])
+	}
+}
+
+func TestWarmHelperFallsBackForOldHelper(t *testing.T) {
+	// A helper without --serve reads one request and shows it.
+	helper := writeFakeHelper(t, "cat >/dev/null\n")
+	w := &warmHelper{}
+	defer w.stop()
+
+	if w.show(helper, helperRequest{Title: "first"}) {
+		t.Fatalf("show() = true for a helper that never said ready")
+	}
+	if !w.unsupported || w.show(helper, helperRequest{Title: "second"}) {
+		t.Fatalf("warm helper retried an unsupported helper")
+	}
+}
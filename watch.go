@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// A watch shows about two lines of a push before it has to scroll, so the
+// compact variant keeps the title and message to these many characters.
+const (
+	watchTitleLimit   = 32
+	watchMessageLimit = 100
+)
+
+// watchText is the compact variant of phoneText, for ntfy and Pushover
+// sinks with watch = true: the project and the short event title, and the
+// message on one line.
+func watchText(payload map[string]any) (title, message string) {
+	title, message = renderPayloadMessage(payload)
+	title = strings.TrimPrefix(title, "Codex: ")
+	if project := projectName(payloadCwd(payload)); project != "" {
+		title = project + ": " + title
+	}
+	message = strings.Join(strings.Fields(renderMessage(message, formatPlain)), " ")
+	return truncateRunes(title, watchTitleLimit), truncateRunes(message, watchMessageLimit)
+}
+
+// expandWatchURL fills a watch_url template for payload: {thread} and
+// {project} become the URL-escaped thread ID and project name.
+func expandWatchURL(template string, payload map[string]any) string {
+	if template == "" {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{thread}", url.PathEscape(payloadThreadID(payload)),
+		"{project}", url.PathEscape(projectName(payloadCwd(payload))),
+	).Replace(template)
+}
+
+// parseWatchSetting reads the watch and watch_url keys a push sink's table
+// shares. It reports false for any other key.
+func parseWatchSetting(table, key string, value any, watch *bool, watchURL *string) (bool, error) {
+	switch key {
+	case "watch":
+		b, ok := value.(bool)
+		if !ok {
+			return true, fmt.Errorf("%s.watch must be true or false", table)
+		}
+		*watch = b
+	case "watch_url":
+		s, ok := value.(string)
+		if s = strings.TrimSpace(s); !ok || (!strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://")) {
+			return true, errors.New(table + ".watch_url must be an http(s) URL")
+		}
+		*watchURL = s
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// truncateRunes cuts s to at most limit characters, ending in an ellipsis
+// when anything was cut. It never splits a UTF-8 sequence.
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultWebhookBody is sent when a webhook sets no body template.
+const defaultWebhookBody = `{"event":{{json .Event}},"title":{{json .Title}},"message":{{json .Message}},"project":{{json .Project}},"cwd":{{json .Cwd}},"thread_id":{{json .Thread}}}`
+
+// webhookConfig is one [webhooks.<name>] table of config.toml: a POST of
+// the event to an arbitrary URL, for services without a dedicated sink.
+type webhookConfig struct {
+	Name   string
+	URL    string
+	Method string
+	// ContentType defaults to application/json, in which case the rendered
+	// body must be valid JSON.
+	ContentType string
+	// Body and Headers are text/template sources over webhookEvent.
+	Body    string
+	Headers map[string]string
+	// Events defaults to every event.
+	Events []string
+	// Secret, a template like Headers, turns on HMAC-SHA256 signing.
+	Secret string
+	// ClientCert and ClientKey are PEM files for mutual TLS; CACert
+	// verifies a server certificate from a private CA.
+	ClientCert string
+	ClientKey  string
+	CACert     string
+}
+
+func (c webhookConfig) wants(event string) bool {
+	if c.Events == nil {
+		return true
+	}
+	return containsString(c.Events, event) || containsString(c.Events, "all")
+}
+
+func (c webhookConfig) method() string {
+	if c.Method == "" {
+		return http.MethodPost
+	}
+	return c.Method
+}
+
+func (c webhookConfig) contentType() string {
+	if c.ContentType == "" {
+		return "application/json"
+	}
+	return c.ContentType
+}
+
+// host is what doctor shows: enough to recognize the endpoint without
+// printing a key embedded in the path or query.
+func (c webhookConfig) host() string {
+	if isSecretRef(c.URL) {
+		return "(" + strings.TrimSpace(c.URL) + ")"
+	}
+	if u, err := url.Parse(c.URL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "(invalid URL)"
+}
+
+// setWebhook parses one `webhooks.<name>.<key>` entry, adding the webhook
+// the first time its name appears.
+func (cfg *userConfig) setWebhook(key string, value any) error {
+	return setNamedTable("webhooks", key, value, &cfg.Webhooks, func(name string) webhookConfig { return webhookConfig{Name: name} })
+}
+
+func (c webhookConfig) tableName() string {
+	return c.Name
+}
+
+func (c *webhookConfig) set(key string, value any) error {
+	prefix := "webhooks." + c.Name + "."
+	if key == "events" {
+		events, err := parseEventList(prefix+"events", value)
+		if err != nil {
+			return err
+		}
+		c.Events = events
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%s%s must be a string", prefix, key)
+	}
+	if header, ok := strings.CutPrefix(key, "headers."); ok {
+		if _, err := newWebhookTemplate(header).Parse(s); err != nil {
+			return fmt.Errorf("%s%s: %w", prefix, key, err)
+		}
+		if c.Headers == nil {
+			c.Headers = map[string]string{}
+		}
+		c.Headers[header] = s
+		return nil
+	}
+	switch key {
+	case "url":
+		s = strings.TrimSpace(s)
+		if !strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://") && !isSecretRef(s) {
+			return fmt.Errorf("%surl must be an http(s) URL or a secret: reference", prefix)
+		}
+		c.URL = s
+	case "method":
+		switch m := strings.ToUpper(strings.TrimSpace(s)); m {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			c.Method = m
+		default:
+			return fmt.Errorf("%smethod must be POST, PUT, or PATCH", prefix)
+		}
+	case "content_type":
+		c.ContentType = strings.TrimSpace(s)
+	case "body":
+		if _, err := newWebhookTemplate("body").Parse(s); err != nil {
+			return fmt.Errorf("%sbody: %w", prefix, err)
+		}
+		c.Body = s
+	case "secret":
+		if _, err := newWebhookTemplate("secret").Parse(s); err != nil {
+			return fmt.Errorf("%ssecret: %w", prefix, err)
+		}
+		c.Secret = s
+	case "client_cert":
+		c.ClientCert = strings.TrimSpace(s)
+	case "client_key":
+		c.ClientKey = strings.TrimSpace(s)
+	case "ca_cert":
+		c.CACert = strings.TrimSpace(s)
+	default:
+		return unknownSetting(prefix + key)
+	}
+	return nil
+}
+
+// validateWebhooks reports webhooks missing a URL once the whole file is
+// parsed, since the url line may come after the others.
+func validateWebhooks(webhooks []webhookConfig) error {
+	for _, w := range webhooks {
+		if w.URL == "" {
+			return fmt.Errorf("webhooks.%s.url is required", w.Name)
+		}
+		if err := validateWebhookTLS(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// webhookEvent is what body and header templates see. Payload is the raw
+// hook payload, for fields codex-notify does not lift out.
+type webhookEvent struct {
+	Event   string
+	Title   string
+	Message string
+	Project string
+	Cwd     string
+	Thread  string
+	Turn    string
+	Payload map[string]any
+}
+
+func newWebhookEvent(payload map[string]any) webhookEvent {
+	title, message := renderPayloadMessage(payload)
+	ev := webhookEvent{
+		Event:   payloadEventName(payload),
+		Title:   title,
+		Message: renderMessage(message, formatPlain),
+		Cwd:     payloadCwd(payload),
+		Thread:  payloadThreadID(payload),
+		Turn:    payloadTurnID(payload),
+		Payload: payload,
+	}
+	if project, ok := projectIdentityForCwd(ev.Cwd); ok {
+		ev.Project = project.Name
+	}
+	return ev
+}
+
+// newWebhookTemplate adds json, which quotes a value for a JSON body, and
+// env, which keeps tokens in the environment instead of config.toml.
+func newWebhookTemplate(name string) *template.Template {
+	return template.New(name).Option("missingkey=zero").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"env": getenv,
+		"secret": func(name string) (string, error) {
+			return lookupSecret(name)
+		},
+	})
+}
+
+func renderWebhookTemplate(name, src string, ev webhookEvent) (string, error) {
+	tmpl, err := newWebhookTemplate(name).Parse(src)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, ev); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// webhookRequest is a rendered webhook, ready to send.
+type webhookRequest struct {
+	Method      string
+	URL         string
+	ContentType string
+	Headers     map[string]string
+	Body        string
+}
+
+func buildWebhookRequest(cfg webhookConfig, payload map[string]any, now time.Time) (webhookRequest, error) {
+	ev := newWebhookEvent(payload)
+	src := cfg.Body
+	if src == "" {
+		src = defaultWebhookBody
+	}
+	body, err := renderWebhookTemplate("body", src, ev)
+	if err != nil {
+		return webhookRequest{}, fmt.Errorf("render body: %w", err)
+	}
+	if strings.HasPrefix(cfg.contentType(), "application/json") && !json.Valid([]byte(body)) {
+		return webhookRequest{}, errors.New("body template did not render valid JSON (quote values with {{json .Field}})")
+	}
+
+	url, err := resolveSecret(cfg.URL)
+	if err != nil {
+		return webhookRequest{}, fmt.Errorf("url: %w", err)
+	}
+	req := webhookRequest{Method: cfg.method(), URL: url, ContentType: cfg.contentType(), Body: body, Headers: map[string]string{}}
+	names := make([]string, 0, len(cfg.Headers))
+	for name := range cfg.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := renderWebhookTemplate(name, cfg.Headers[name], ev)
+		if err != nil {
+			return webhookRequest{}, fmt.Errorf("render header %s: %w", name, err)
+		}
+		req.Headers[name] = value
+	}
+	if cfg.Secret != "" {
+		secret, err := renderWebhookTemplate("secret", cfg.Secret, ev)
+		if err != nil {
+			return webhookRequest{}, fmt.Errorf("render secret: %w", err)
+		}
+		if secret == "" {
+			// Sending unsigned would silently defeat the receiver's check.
+			return webhookRequest{}, errors.New("secret rendered empty")
+		}
+		req.Headers[webhookTimestampHeader], req.Headers[webhookSignatureHeader] = signWebhookBody(secret, body, now)
+	}
+	return req, nil
+}
+
+// jsonRequest is a POST of v as JSON to url, for the sinks with a fixed
+// JSON API.
+func jsonRequest(url string, v any) (webhookRequest, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return webhookRequest{}, err
+	}
+	return webhookRequest{Method: http.MethodPost, URL: url, ContentType: "application/json", Body: string(body), Headers: map[string]string{}}, nil
+}
+
+// publishWebhook sends req for every HTTP sink. The URL and headers may
+// carry keys, so errors name only service.
+func publishWebhook(client *http.Client, service string, req webhookRequest) error {
+	httpReq, err := http.NewRequest(req.Method, req.URL, bytes.NewReader([]byte(req.Body)))
+	if err != nil {
+		return errors.New("invalid " + service + " request")
+	}
+	httpReq.Header.Set("Content-Type", req.ContentType)
+	for name, value := range req.Headers {
+		httpReq.Header.Set(name, value)
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return transient(errors.New(service + " request failed"))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return httpStatusError(resp.StatusCode, fmt.Errorf("%s returned %s (%s)", service, resp.Status, strings.TrimSpace(string(detail))))
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookSinkConfigAndPublish(t *testing.T) {
+	var gotMethod, gotBody, gotAuth, gotType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotAuth, gotType = r.Method, r.Header.Get("Authorization"), r.Header.Get("Content-Type")
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+	}))
+	defer server.Close()
+
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "0")
+	t.Setenv("HOME_TOKEN", "s3cret")
+	content := "[webhooks.home]\n" +
+		"url = \"" + server.URL + "\"\n" +
+		"method = \"put\"\n" +
+		"body = '{\"text\": {{json .Title}}, \"thread\": {{json .Thread}}, \"raw\": {{json .Payload.extra}}}'\n" +
+		"events = [\"approval-requested\"]\n" +
+		"[webhooks.home.headers]\n" +
+		"Authorization = 'Bearer {{env \"HOME_TOKEN\"}}'\n"
+	cfg, err := parseUserConfig([]byte(content))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	if len(cfg.Webhooks) != 1 || cfg.Webhooks[0].wants("agent-turn-complete") {
+		t.Fatalf("webhooks = %+v", cfg.Webhooks)
+	}
+
+	sinks := remoteSinks(cfg, map[string]any{"type": "approval-requested", "thread-id": "t1", "extra": "x\"y"})
+	if len(sinks) != 1 || sinks[0].Name != "webhook home" {
+		t.Fatalf("remoteSinks() = %+v", sinks)
+	}
+	if err := sinks[0].Publish(); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if gotMethod != http.MethodPut || gotAuth != "Bearer s3cret" || gotType != "application/json" {
+		t.Fatalf("request = %s, auth %q, type %q", gotMethod, gotAuth, gotType)
+	}
+	if want := `{"text": "Codex: Approval Requested", "thread": "t1", "raw": "x\"y"}`; gotBody != want {
+		t.Fatalf("body = %s, want %s", gotBody, want)
+	}
+}
+
+func TestBuildWebhookRequestDefaultsAndErrors(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "0")
+	payload := map[string]any{"type": "agent-error", "message": "stream disconnected"}
+
+	req, err := buildWebhookRequest(webhookConfig{Name: "x", URL: "https://example.com"}, payload, time.Now())
+	if err != nil {
+		t.Fatalf("buildWebhookRequest() error = %v", err)
+	}
+	if req.Method != http.MethodPost || !strings.Contains(req.Body, `"event":"agent-error"`) || !strings.Contains(req.Body, `"message":"stream disconnected"`) {
+		t.Fatalf("default request = %+v", req)
+	}
+
+	// An unquoted value breaks the JSON body; that is caught before sending.
+	if _, err := buildWebhookRequest(webhookConfig{Name: "x", URL: "https://example.com", Body: `{"text": {{.Message}}}`}, payload, time.Now()); err == nil {
+		t.Fatal("expected an error for a body that is not JSON")
+	}
+	req, err = buildWebhookRequest(webhookConfig{Name: "x", URL: "https://example.com", ContentType: "text/plain", Body: "{{.Event}}: {{.Message}}"}, payload, time.Now())
+	if err != nil || req.Body != "agent-error: stream disconnected" {
+		t.Fatalf("plain body = %q, %v", req.Body, err)
+	}
+}
+
+func TestParseWebhookConfigErrors(t *testing.T) {
+	for _, content := range []string{
+		"[webhooks.a]\nmethod = \"POST\"\n",
+		"[webhooks.a]\nurl = \"ftp://example.com\"\n",
+		"[webhooks.a]\nurl = \"https://example.com\"\nmethod = \"DELETE\"\n",
+		"[webhooks.a]\nurl = \"https://example.com\"\nbody = \"{{.Title\"\n",
+		"[webhooks.a]\nurl = \"https://example.com\"\nretries = \"3\"\n",
+		"webhooks = \"https://example.com\"\n",
+	} {
+		if _, err := parseUserConfig([]byte(content)); err == nil {
+			t.Errorf("parseUserConfig(%q) succeeded, want an error", content)
+		}
+	}
+}
+
+func TestRedactConfigSecretsWebhooks(t *testing.T) {
+	in := "[webhooks.home]\nurl = \"https://maker.ifttt.com/trigger/x/with/key/abc\"\nsecret = \"abc\"\nevents = [\"all\"]\n[webhooks.home.headers]\n\"X-Api-Key\" = \"abc\"\n"
+	out := redactConfigSecrets(in)
+	if strings.Contains(out, "abc") {
+		t.Fatalf("secret left in export:\n%s", out)
+	}
+	if !strings.Contains(out, "events = [\"all\"]") {
+		t.Fatalf("non-secret setting dropped:\n%s", out)
+	}
+}
+
+func TestSignWebhookBody(t *testing.T) {
+	timestamp, signature := signWebhookBody("key", `{"a":1}`, time.Unix(1_700_000_000, 0))
+	if timestamp != "1700000000" {
+		t.Fatalf("timestamp = %q", timestamp)
+	}
+	// printf '1700000000.{"a":1}' | openssl dgst -sha256 -hmac key
+	if want := "sha256=a438e398bfafc57e4396bb7fc2304422f0f768e965d073ca313cb52e22e6ad03"; signature != want {
+		t.Fatalf("signature = %q, want %q", signature, want)
+	}
+
+	t.Setenv("HOOK_SECRET", "key")
+	req, err := buildWebhookRequest(webhookConfig{Name: "x", URL: "https://example.com", Body: `{"a":1}`, Secret: `{{env "HOOK_SECRET"}}`}, map[string]any{}, time.Unix(1_700_000_000, 0))
+	if err != nil {
+		t.Fatalf("buildWebhookRequest() error = %v", err)
+	}
+	if req.Headers[webhookTimestampHeader] != timestamp || req.Headers[webhookSignatureHeader] != signature {
+		t.Fatalf("headers = %+v", req.Headers)
+	}
+
+	t.Setenv("HOOK_SECRET", "")
+	if _, err := buildWebhookRequest(webhookConfig{Name: "x", URL: "https://example.com", Secret: `{{env "HOOK_SECRET"}}`}, map[string]any{}, time.Now()); err == nil {
+		t.Fatal("expected an error when the secret renders empty")
+	}
+}
+
+func TestWebhookMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestClientCert(t, dir)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "codex-notify-test" {
+			http.Error(w, "no client certificate", http.StatusForbidden)
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+	caPath := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	content := "[webhooks.internal]\n" +
+		"url = \"" + server.URL + "\"\n" +
+		"client_cert = \"" + certPath + "\"\n" +
+		"client_key = \"" + keyPath + "\"\n" +
+		"ca_cert = \"" + caPath + "\"\n"
+	cfg, err := parseUserConfig([]byte(content))
+	if err != nil {
+		t.Fatalf("parseUserConfig() error = %v", err)
+	}
+	if detail, err := webhookSecurityDetail(cfg.Webhooks[0]); err != nil || detail != "mTLS, custom CA" {
+		t.Fatalf("webhookSecurityDetail() = %q, %v", detail, err)
+	}
+	sinks := remoteSinks(cfg, map[string]any{"type": "agent-error"})
+	if err := sinks[0].Publish(); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	// Without the client certificate the server refuses the handshake.
+	cfg.Webhooks[0].ClientCert, cfg.Webhooks[0].ClientKey = "", ""
+	if err := remoteSinks(cfg, map[string]any{"type": "agent-error"})[0].Publish(); err == nil {
+		t.Fatal("expected the handshake to fail without a client certificate")
+	}
+
+	for _, bad := range []string{
+		"[webhooks.a]\nurl = \"https://example.com\"\nclient_cert = \"c.pem\"\n",
+		"[webhooks.a]\nurl = \"http://example.com\"\nca_cert = \"ca.pem\"\n",
+	} {
+		if _, err := parseUserConfig([]byte(bad)); err == nil {
+			t.Errorf("parseUserConfig(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func writeTestClientCert(t *testing.T, dir string) (certPath, keyPath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "codex-notify-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath, keyPath = filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
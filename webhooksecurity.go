@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signed webhooks carry the signing time and an HMAC-SHA256 over
+// "<timestamp>.<body>", so a receiver can reject forged and replayed calls.
+const (
+	webhookTimestampHeader = "X-Codex-Notify-Timestamp"
+	webhookSignatureHeader = "X-Codex-Notify-Signature"
+)
+
+// signWebhookBody returns the timestamp and signature header values.
+func signWebhookBody(secret, body string, now time.Time) (timestamp, signature string) {
+	timestamp = strconv.FormatInt(now.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + body))
+	return timestamp, "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// usesTLSFiles reports whether the webhook needs its own TLS settings.
+func (c webhookConfig) usesTLSFiles() bool {
+	return c.ClientCert != "" || c.CACert != ""
+}
+
+// validateWebhookTLS checks the pairing rules that do not need the files.
+func validateWebhookTLS(w webhookConfig) error {
+	if (w.ClientCert == "") != (w.ClientKey == "") {
+		return fmt.Errorf("webhooks.%s.client_cert and client_key must be set together", w.Name)
+	}
+	if w.usesTLSFiles() && !strings.HasPrefix(w.URL, "https://") && !isSecretRef(w.URL) {
+		return fmt.Errorf("webhooks.%s uses TLS files but its url is not https", w.Name)
+	}
+	return nil
+}
+
+// webhookTLSConfig loads the client certificate for mutual TLS and the CA
+// that signs the server's certificate.
+func webhookTLSConfig(c webhookConfig) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.ClientCert != "" {
+		certPath, err := expandUserPath(c.ClientCert)
+		if err != nil {
+			return nil, err
+		}
+		keyPath, err := expandUserPath(c.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if c.CACert != "" {
+		path, err := expandUserPath(c.CACert)
+		if err != nil {
+			return nil, err
+		}
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s has no PEM certificates", path)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// webhookHTTPClient is sinkHTTPClient unless the webhook brings its own
+// certificates.
+func webhookHTTPClient(c webhookConfig) (*http.Client, error) {
+	if !c.usesTLSFiles() {
+		return sinkHTTPClient, nil
+	}
+	tlsConfig, err := webhookTLSConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Timeout: sinkHTTPClient.Timeout, Transport: transport}, nil
+}
+
+func expandUserPath(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("resolve home: %w", err)
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	if path == "" {
+		return "", errors.New("empty path")
+	}
+	return path, nil
+}
+
+// webhookSecurityDetail is the doctor summary of how a webhook is protected.
+// A certificate that does not load is a problem, since every send would fail.
+func webhookSecurityDetail(c webhookConfig) (string, error) {
+	parts := []string{}
+	if c.Secret != "" {
+		parts = append(parts, "signed")
+	}
+	if c.usesTLSFiles() {
+		if _, err := webhookTLSConfig(c); err != nil {
+			return "", err
+		}
+		if c.ClientCert != "" {
+			parts = append(parts, "mTLS")
+		}
+		if c.CACert != "" {
+			parts = append(parts, "custom CA")
+		}
+	}
+	return strings.Join(parts, ", "), nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	widgetFilename = "widget.json"
+
+	// widgetApprovalWindow drops approvals nobody answered through
+	// codex-notify, so the widget does not show stale requests forever.
+	widgetApprovalWindow = time.Hour
+	widgetMaxProjects    = 20
+)
+
+// widgetSnapshot is the status feed for desktop widgets and menu bar tools:
+// a small JSON file in the runtime state dir, rewritten after every hook
+// event, whether hook ran in-process or in the daemon.
+type widgetSnapshot struct {
+	UpdatedAt        string           `json:"updated_at"`
+	PendingApprovals []widgetApproval `json:"pending_approvals"`
+	Projects         []widgetProject  `json:"projects"`
+}
+
+type widgetApproval struct {
+	Thread  string `json:"thread_id,omitempty"`
+	Project string `json:"project,omitempty"`
+	Cwd     string `json:"cwd,omitempty"`
+	Message string `json:"message,omitempty"`
+	Since   string `json:"since"`
+}
+
+// widgetProject is the last event seen for one project.
+type widgetProject struct {
+	Name    string `json:"name"`
+	Cwd     string `json:"cwd"`
+	Color   string `json:"color,omitempty"`
+	Event   string `json:"event"`
+	Status  string `json:"status"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message,omitempty"`
+	Time    string `json:"time"`
+}
+
+func widgetPath() (string, error) {
+	stateDir, err := runtimeStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, widgetFilename), nil
+}
+
+// updateWidget applies fn to the snapshot under the state lock. Like the
+// event log it is best effort.
+func updateWidget(now time.Time, fn func(*widgetSnapshot)) {
+	path, err := widgetPath()
+	if err != nil {
+		return
+	}
+	unlock, err := lockState()
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	var snap widgetSnapshot
+	if content, err := readFileMaybe(path); err == nil && len(content) > 0 {
+		_ = json.Unmarshal(content, &snap)
+	}
+	fn(&snap)
+	pruneWidgetSnapshot(&snap, now)
+	snap.UpdatedAt = now.UTC().Format(time.RFC3339)
+
+	content, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = writeFileAtomic(path, append(content, '\n'), privateFileMode)
+}
+
+func recordWidgetEvent(rec eventRecord, now time.Time) {
+	// A reminder is the same approval again, still waiting since the first.
+	if rec.Status == "duplicate" || rec.Status == "reminded" {
+		return
+	}
+	updateWidget(now, func(snap *widgetSnapshot) {
+		applyWidgetEvent(snap, rec)
+	})
+}
+
+// resolveWidgetApproval clears a thread's pending approval once the user
+// answered it through an action.
+func resolveWidgetApproval(threadID string) {
+	if threadID == "" {
+		return
+	}
+	updateWidget(time.Now(), func(snap *widgetSnapshot) {
+		removeWidgetApproval(snap, threadID, "")
+	})
+}
+
+func applyWidgetEvent(snap *widgetSnapshot, rec eventRecord) {
+	if rec.Event == "approval-requested" {
+		removeWidgetApproval(snap, rec.Thread, rec.Cwd)
+		snap.PendingApprovals = append(snap.PendingApprovals, widgetApproval{
+			Thread:  rec.Thread,
+			Project: projectName(rec.Cwd),
+			Cwd:     rec.Cwd,
+			Message: rec.Message,
+			Since:   rec.Time,
+		})
+	} else if rec.Thread != "" {
+		// Any later event from the thread means Codex moved past the prompt.
+		removeWidgetApproval(snap, rec.Thread, "")
+	}
+
+	name := projectName(rec.Cwd)
+	if name == "" {
+		return
+	}
+	project := widgetProject{
+		Name:    name,
+		Cwd:     rec.Cwd,
+		Event:   rec.Event,
+		Status:  rec.Status,
+		Title:   rec.Title,
+		Message: rec.Message,
+		Time:    rec.Time,
+	}
+	if identity, ok := projectIdentityForCwd(rec.Cwd); ok {
+		project.Color = identity.Color
+	}
+	projects := []widgetProject{project}
+	for _, p := range snap.Projects {
+		if p.Cwd != rec.Cwd {
+			projects = append(projects, p)
+		}
+	}
+	snap.Projects = projects
+}
+
+// removeWidgetApproval drops approvals for threadID, or for cwd when the
+// approval has no thread.
+func removeWidgetApproval(snap *widgetSnapshot, threadID, cwd string) {
+	kept := snap.PendingApprovals[:0]
+	for _, a := range snap.PendingApprovals {
+		same := threadID != "" && a.Thread == threadID || threadID == "" && a.Thread == "" && a.Cwd == cwd
+		if !same {
+			kept = append(kept, a)
+		}
+	}
+	snap.PendingApprovals = kept
+}
+
+func pruneWidgetSnapshot(snap *widgetSnapshot, now time.Time) {
+	kept := []widgetApproval{}
+	for _, a := range snap.PendingApprovals {
+		if since, err := time.Parse(time.RFC3339, a.Since); err == nil && now.Sub(since) < widgetApprovalWindow {
+			kept = append(kept, a)
+		}
+	}
+	snap.PendingApprovals = kept
+
+	sort.SliceStable(snap.Projects, func(i, j int) bool { return snap.Projects[i].Time > snap.Projects[j].Time })
+	if len(snap.Projects) > widgetMaxProjects {
+		snap.Projects = snap.Projects[:widgetMaxProjects]
+	}
+	if snap.Projects == nil {
+		snap.Projects = []widgetProject{}
+	}
+}
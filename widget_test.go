@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyWidgetEvent(t *testing.T) {
+	t.Setenv("CODEX_NOTIFY_PROJECT_COLORS", "1")
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) string { return now.Add(d).Format(time.RFC3339) }
+
+	snap := widgetSnapshot{}
+	applyWidgetEvent(&snap, eventRecord{Time: at(-3 * time.Minute), Event: "agent-turn-complete", Thread: "t1", Cwd: "/src/api", Status: "sent"})
+	applyWidgetEvent(&snap, eventRecord{Time: at(-2 * time.Minute), Event: "approval-requested", Thread: "t2", Cwd: "/src/web", Message: "Allow command: npm test", Status: "sent"})
+	applyWidgetEvent(&snap, eventRecord{Time: at(-1 * time.Minute), Event: "approval-requested", Thread: "t1", Cwd: "/src/api", Status: "sent"})
+	pruneWidgetSnapshot(&snap, now)
+
+	if len(snap.PendingApprovals) != 2 {
+		t.Fatalf("PendingApprovals = %+v, want t2 and t1", snap.PendingApprovals)
+	}
+	if len(snap.Projects) != 2 || snap.Projects[0].Name != "api" || snap.Projects[0].Event != "approval-requested" || snap.Projects[1].Name != "web" {
+		t.Fatalf("Projects = %+v, want api's approval first, then web", snap.Projects)
+	}
+	if snap.Projects[0].Color == "" {
+		t.Fatalf("Projects[0].Color is empty, want the project accent")
+	}
+
+	// A later event from the thread resolves its approval.
+	applyWidgetEvent(&snap, eventRecord{Time: at(0), Event: "agent-turn-complete", Thread: "t1", Cwd: "/src/api", Status: "sent"})
+	if len(snap.PendingApprovals) != 1 || snap.PendingApprovals[0].Thread != "t2" {
+		t.Fatalf("PendingApprovals after t1 finished = %+v, want only t2", snap.PendingApprovals)
+	}
+
+	removeWidgetApproval(&snap, "t2", "")
+	if len(snap.PendingApprovals) != 0 {
+		t.Fatalf("PendingApprovals after answering t2 = %+v, want none", snap.PendingApprovals)
+	}
+}
+
+func TestPruneWidgetSnapshotDropsStaleApprovals(t *testing.T) {
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	snap := widgetSnapshot{PendingApprovals: []widgetApproval{
+		{Thread: "old", Since: now.Add(-2 * widgetApprovalWindow).Format(time.RFC3339)},
+		{Thread: "new", Since: now.Add(-time.Minute).Format(time.RFC3339)},
+	}}
+	pruneWidgetSnapshot(&snap, now)
+	if len(snap.PendingApprovals) != 1 || snap.PendingApprovals[0].Thread != "new" {
+		t.Fatalf("PendingApprovals = %+v, want only new", snap.PendingApprovals)
+	}
+	if snap.Projects == nil {
+		t.Fatalf("Projects = nil, want an empty list for widget decoders")
+	}
+}
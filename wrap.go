@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// wrapSessionEnv is set for the wrapped Codex process. Hooks it triggers
+// inherit it and tag their event log lines, which is how wrap knows whether
+// Codex already notified on its own.
+const wrapSessionEnv = "CODEX_NOTIFY_WRAP_SESSION"
+
+func runWrap(args []string) error {
+	fs := flag.NewFlagSet("wrap", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	notifyStart := fs.Bool("start", false, "also notify when the command starts")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError(errors.New("wrap requires a command, e.g. wrap -- codex"))
+	}
+
+	session := fmt.Sprintf("wrap-%d-%d", os.Getpid(), time.Now().UnixNano())
+	cwd, _ := os.Getwd()
+	command := strings.Join(fs.Args(), " ")
+
+	cmd := exec.Command(fs.Arg(0), fs.Args()[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), wrapSessionEnv+"="+session)
+
+	// Ctrl-C reaches Codex through the terminal's process group; wrap stays
+	// alive to report how it ended and passes on signals sent to it alone.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	started := time.Now()
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start %s: %w", fs.Arg(0), err)
+	}
+	recordWrapEvent("wrap-start", session, cwd, "Codex: Started", command, "started")
+	if *notifyStart {
+		notifyLifecycle(notificationGroup("wrap", session), cwd, "Codex: Started", command)
+	}
+
+	go func() {
+		for sig := range signals {
+			if sig != syscall.SIGINT {
+				_ = cmd.Process.Signal(sig)
+			}
+		}
+	}()
+
+	waitErr := cmd.Wait()
+	elapsed := time.Since(started).Round(time.Second)
+	code, how := wrapExitStatus(cmd, waitErr)
+	reason, crashed := wrapCrashReason(cmd.ProcessState, lastSessionHookEvent(session))
+
+	switch {
+	case crashed:
+		message := fmt.Sprintf("agent exited unexpectedly (%s) after %s", reason, elapsed)
+		recordWrapEvent("wrap-exit", session, cwd, "Codex: Exited Unexpectedly", message, notifyLifecycle(notificationGroup("wrap", session), cwd, "Codex: Exited Unexpectedly", message))
+	case code != 0:
+		message := fmt.Sprintf("%s after %s", how, elapsed)
+		recordWrapEvent("wrap-exit", session, cwd, "Codex: Exited With Error", message, notifyLifecycle(notificationGroup("wrap", session), cwd, "Codex: Exited With Error", message))
+	case sessionHookEvents(session) > 0:
+		// Codex's own hook already reported this session.
+		recordWrapEvent("wrap-exit", session, cwd, "Codex: Finished", elapsed.String(), "merged")
+	default:
+		message := fmt.Sprintf("finished after %s", elapsed)
+		recordWrapEvent("wrap-exit", session, cwd, "Codex: Finished", message, notifyLifecycle(notificationGroup("wrap", session), cwd, "Codex: Finished", message))
+	}
+
+	if code != 0 {
+		return withExitCode(code, fmt.Errorf("%s %s", fs.Arg(0), how))
+	}
+	return nil
+}
+
+// wrapExitStatus turns the child's wait result into an exit code for wrap
+// and a short description for the notification.
+func wrapExitStatus(cmd *exec.Cmd, waitErr error) (int, string) {
+	state := cmd.ProcessState
+	if state == nil {
+		return exitFailure, waitErr.Error()
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal()), "killed by " + ws.Signal().String()
+	}
+	if code := state.ExitCode(); code != 0 {
+		return code, fmt.Sprintf("exited with status %d", code)
+	}
+	return 0, "exited"
+}
+
+// wrapCrashReason reports whether Codex died rather than quit: killed by a
+// signal the user did not send through the terminal, or failing while a
+// turn was still open, with lastEvent the session's latest hook event.
+func wrapCrashReason(state *os.ProcessState, lastEvent string) (string, bool) {
+	if state == nil {
+		return "", false
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		switch ws.Signal() {
+		case syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP:
+			return "", false
+		}
+		return fmt.Sprintf("signal %d", int(ws.Signal())), true
+	}
+	if code := state.ExitCode(); code != 0 && !turnEnded(lastEvent) {
+		return fmt.Sprintf("status %d", code), true
+	}
+	return "", false
+}
+
+// notifyLifecycle sends a notification about the Codex process itself
+// unless the project is muted, and returns the event log status.
+func notifyLifecycle(group, cwd, title, message string) string {
+	if state, err := loadState(); err == nil && isProjectMuted(state, cwd, time.Now()) {
+		return "muted"
+	}
+	req := notificationRequest{
+		Title:          title,
+		Message:        message,
+		Group:          group,
+		ExecuteOnClick: buildActionCommand("open", ""),
+	}
+	if project, ok := projectIdentityForCwd(cwd); ok {
+		req.Message = project.prefix(req.Message)
+		req.AccentColor = project.Color
+	}
+	if err := sendNotification(req); err != nil {
+		logErrorf("%v", err)
+		return "failed"
+	}
+	return "sent"
+}
+
+func recordWrapEvent(event, session, cwd, title, message, status string) {
+	path, err := eventsPath()
+	if err != nil {
+		return
+	}
+	_ = appendEvent(path, &eventRecord{
+		Event:   event,
+		Session: session,
+		Cwd:     cwd,
+		Title:   title,
+		Message: message,
+		Status:  status,
+	}, time.Now())
+}
+
+// lastSessionHookEvent is the latest hook event logged for a wrap session.
+func lastSessionHookEvent(session string) string {
+	path, err := eventsPath()
+	if err != nil {
+		return ""
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	last := ""
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec eventRecord
+		if json.Unmarshal(scanner.Bytes(), &rec) == nil && rec.Session == session && !strings.HasPrefix(rec.Event, "wrap-") {
+			last = rec.Event
+		}
+	}
+	return last
+}
+
+// sessionHookEvents counts hook events logged for a wrap session.
+func sessionHookEvents(session string) int {
+	path, err := eventsPath()
+	if err != nil {
+		return 0
+	}
+	return countSessionHookEvents(path, session)
+}
+
+func countSessionHookEvents(path, session string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec eventRecord
+		if json.Unmarshal(scanner.Bytes(), &rec) != nil || rec.Session != session {
+			continue
+		}
+		if !strings.HasPrefix(rec.Event, "wrap-") {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestCountSessionHookEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), eventsFilename)
+	for _, rec := range []eventRecord{
+		{Event: "wrap-start", Session: "s1", Status: "started"},
+		{Event: "agent-turn-complete", Session: "s1", Status: "sent"},
+		{Event: "agent-turn-complete", Session: "s2", Status: "sent"},
+		{Event: "agent-turn-complete", Status: "sent"},
+		{Event: "wrap-exit", Session: "s1", Status: "merged"},
+	} {
+		if err := appendJSONLine(path, rec); err != nil {
+			t.Fatalf("appendJSONLine: %v", err)
+		}
+	}
+
+	if got := countSessionHookEvents(path, "s1"); got != 1 {
+		t.Fatalf("countSessionHookEvents(s1) = %d, want 1", got)
+	}
+	if got := countSessionHookEvents(path, "s3"); got != 0 {
+		t.Fatalf("countSessionHookEvents(s3) = %d, want 0", got)
+	}
+	if got := countSessionHookEvents(filepath.Join(t.TempDir(), "missing"), "s1"); got != 0 {
+		t.Fatalf("countSessionHookEvents(missing) = %d, want 0", got)
+	}
+}
+
+func TestWrapExitStatus(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+	tests := []struct {
+		script string
+		code   int
+		how    string
+	}{
+		{script: "exit 0", code: 0, how: "exited"},
+		{script: "exit 3", code: 3, how: "exited with status 3"},
+		{script: "kill -TERM $$", code: 143, how: "killed by terminated"},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command(sh, "-c", tt.script)
+		cmd.Stderr = os.Stderr
+		code, how := wrapExitStatus(cmd, cmd.Run())
+		if code != tt.code || how != tt.how {
+			t.Fatalf("wrapExitStatus(%q) = %d, %q, want %d, %q", tt.script, code, how, tt.code, tt.how)
+		}
+	}
+}
+
+func TestWrapCrashReason(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+	tests := []struct {
+		script    string
+		lastEvent string
+		reason    string
+		crashed   bool
+	}{
+		{script: "kill -KILL $$", lastEvent: "agent-turn-complete", reason: "signal 9", crashed: true},
+		{script: "kill -TERM $$", reason: "", crashed: false},
+		{script: "exit 2", lastEvent: "approval-requested", reason: "status 2", crashed: true},
+		{script: "exit 2", lastEvent: "agent-turn-complete", reason: "", crashed: false},
+		{script: "exit 0", reason: "", crashed: false},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command(sh, "-c", tt.script)
+		_ = cmd.Run()
+		reason, crashed := wrapCrashReason(cmd.ProcessState, tt.lastEvent)
+		if reason != tt.reason || crashed != tt.crashed {
+			t.Errorf("wrapCrashReason(%q, %q) = %q, %v, want %q, %v", tt.script, tt.lastEvent, reason, crashed, tt.reason, tt.crashed)
+		}
+	}
+}